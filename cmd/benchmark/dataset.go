@@ -0,0 +1,135 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/namespace/definition"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketodataset"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const (
+	// relation is the only relation of the synthetic namespace.
+	relation = "members"
+	// writeBatchSize is how many relation tuples are written per transaction.
+	writeBatchSize = 500
+)
+
+// dataset describes the synthetic relation tuples. Documents and groups are
+// objects of the same namespace, generated by ketodataset with one level of
+// groups, so that checks and expands also resolve one level of indirection.
+type dataset struct {
+	Namespace       string `json:"namespace"`
+	Documents       int    `json:"documents"`
+	Groups          int    `json:"groups"`
+	Users           int    `json:"users"`
+	TuplesPerObject int    `json:"tuples_per_object"`
+	Seed            int64  `json:"seed"`
+}
+
+// config returns the configuration of the generator. Users are written as
+// subject IDs, their namespace is only used as the type of the members.
+func (d *dataset) config() ketodataset.Config {
+	return ketodataset.Config{
+		Seed:              d.Seed,
+		Documents:         d.Documents,
+		Users:             d.Users,
+		Groups:            d.Groups,
+		FanOut:            d.TuplesPerObject,
+		DocumentNamespace: d.Namespace,
+		GroupNamespace:    d.Namespace,
+		UserNamespace:     d.Namespace + "User",
+		ViewersRelation:   relation,
+		MembersRelation:   relation,
+	}
+}
+
+// generate generates the namespaces and relation tuples of the dataset. The
+// same flags and seed always result in the same relation tuples.
+func (d *dataset) generate() (*ketodataset.Dataset, error) {
+	return ketodataset.Generate(d.config())
+}
+
+// createNamespaces creates the synthetic model through the namespace
+// administration API. Namespaces that already exist are kept as they are.
+func (d *dataset) createNamespaces(cmd *cobra.Command) error {
+	data, err := d.generate()
+	if err != nil {
+		return err
+	}
+	for _, n := range data.Namespaces {
+		body, err := json.Marshal(&ketoapi.NamespaceDefinition{Name: n.Name, OPL: n.OPL})
+		if err != nil {
+			return err
+		}
+		u := client.GetAdminURL(cmd)
+		u.Path = definition.RouteBase
+		req, err := client.NewRequest(cmd, http.MethodPost, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+			err = fmt.Errorf("could not create the namespace %s: %w", n.Name, client.ErrorFromResponse(resp))
+		}
+		_ = resp.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write replaces all relation tuples of the namespace with the dataset, so
+// that repeated runs start from the same state. It returns the number of
+// written relation tuples.
+func (d *dataset) write(cmd *cobra.Command, cl rts.WriteServiceClient) (int, error) {
+	ctx := cmd.Context()
+	data, err := d.generate()
+	if err != nil {
+		return 0, err
+	}
+	tuples := make([]*rts.RelationTuple, len(data.Tuples))
+	for i, t := range data.Tuples {
+		tuples[i] = t.ToProto()
+	}
+
+	if _, err := cl.DeleteRelationTuples(ctx, &rts.DeleteRelationTuplesRequest{
+		RelationQuery: &rts.RelationQuery{Namespace: &d.Namespace},
+	}); err != nil {
+		return 0, fmt.Errorf("could not delete the relation tuples of the namespace %s: %w", d.Namespace, err)
+	}
+
+	for start := 0; start < len(tuples); start += writeBatchSize {
+		end := start + writeBatchSize
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+		if _, err := cl.TransactRelationTuples(ctx, &rts.TransactRelationTuplesRequest{
+			RelationTupleDeltas: rts.RelationTupleToDeltas(tuples[start:end], rts.RelationTupleDelta_ACTION_INSERT),
+		}); err != nil {
+			return start, fmt.Errorf("could not write the relation tuples: %w", err)
+		}
+	}
+	return len(tuples), nil
+}
+
+// cleanup deletes all relation tuples of the namespace.
+func (d *dataset) cleanup(cmd *cobra.Command, cl rts.WriteServiceClient) error {
+	_, err := cl.DeleteRelationTuples(cmd.Context(), &rts.DeleteRelationTuplesRequest{
+		RelationQuery: &rts.RelationQuery{Namespace: &d.Namespace},
+	})
+	return err
+}
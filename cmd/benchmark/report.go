@@ -0,0 +1,82 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type (
+	// workloadStats are the latencies of one workload. Durations are encoded
+	// as nanoseconds.
+	workloadStats struct {
+		Workload   string        `json:"workload"`
+		Requests   int           `json:"requests"`
+		Errors     int           `json:"errors"`
+		ErrorRate  float64       `json:"error_rate"`
+		Mean       time.Duration `json:"mean_ns"`
+		P50        time.Duration `json:"p50_ns"`
+		P90        time.Duration `json:"p90_ns"`
+		P99        time.Duration `json:"p99_ns"`
+		Max        time.Duration `json:"max_ns"`
+		Throughput float64       `json:"requests_per_second"`
+	}
+	report struct {
+		Dataset     *dataset         `json:"dataset"`
+		Concurrency int              `json:"concurrency"`
+		Duration    time.Duration    `json:"duration_ns"`
+		Workloads   []*workloadStats `json:"workloads"`
+		Total       *workloadStats   `json:"total"`
+	}
+)
+
+func newReport(r *run, results map[string]*samples, elapsed time.Duration) *report {
+	rep := &report{Dataset: r.data, Concurrency: r.concurrency, Duration: elapsed}
+	all := &samples{}
+	for _, w := range workloads {
+		s := results[w]
+		if r.mix[w] == 0 && len(s.latencies) == 0 {
+			continue
+		}
+		rep.Workloads = append(rep.Workloads, s.stats(w, elapsed))
+		all.latencies = append(all.latencies, s.latencies...)
+		all.errors += s.errors
+	}
+	rep.Total = all.stats("total", elapsed)
+	return rep
+}
+
+func (r *report) Header() []string {
+	return []string{"WORKLOAD", "REQUESTS", "ERRORS", "ERROR RATE", "MEAN", "P50", "P90", "P99", "MAX", "REQ/S"}
+}
+
+func (r *report) Table() [][]string {
+	data := make([][]string, 0, len(r.Workloads)+1)
+	for _, s := range append(r.Workloads, r.Total) {
+		data = append(data, []string{
+			s.Workload,
+			strconv.Itoa(s.Requests),
+			strconv.Itoa(s.Errors),
+			fmt.Sprintf("%.2f%%", s.ErrorRate*100),
+			formatLatency(s.Mean),
+			formatLatency(s.P50),
+			formatLatency(s.P90),
+			formatLatency(s.P99),
+			formatLatency(s.Max),
+			fmt.Sprintf("%.1f", s.Throughput),
+		})
+	}
+	return data
+}
+
+func (r *report) Interface() interface{} {
+	return r
+}
+
+func (r *report) Len() int {
+	return len(r.Workloads) + 1
+}
+
+func formatLatency(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,183 @@
+package benchmark
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const (
+	FlagNamespace       = "namespace"
+	FlagCreateNamespace = "create-namespace"
+	FlagDocuments       = "documents"
+	FlagGroups          = "groups"
+	FlagUsers           = "users"
+	FlagTuplesPerObject = "tuples-per-object"
+	FlagSeed            = "seed"
+	FlagSkipSetup       = "skip-setup"
+	FlagCleanup         = "cleanup"
+	FlagWorkload        = "workload"
+	FlagConcurrency     = "concurrency"
+	FlagRequests        = "requests"
+	FlagDuration        = "duration"
+)
+
+func newBenchmarkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Measure the latency of a Keto server",
+		Long: `Write a synthetic dataset and measure the latency of check, list, and expand requests against a Keto server.
+
+The dataset consists of documents and groups in one namespace. Every group has --tuples-per-object users as members, every document has --tuples-per-object members that are either users or the subject sets of groups.
+The same flags and --seed always result in the same relation tuples and requests, so that runs against differently tuned servers are comparable.
+
+Before the run, ALL relation tuples of the namespace are deleted and replaced by the dataset. Use a dedicated namespace, or --skip-setup to run against existing data that was written by an earlier run.
+The namespace has to be configured on the server. With --create-namespace, it is created through the namespace administration API instead.
+
+--workload sets the weight of each workload, e.g. check=8,list=1,expand=1 sends 80% check requests.
+The run ends after --requests requests, or after --duration if it is set.`,
+		Example: `keto benchmark --namespace Benchmark --requests 10000 --concurrency 20
+keto benchmark --workload check=1 --duration 30s --skip-setup --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			d, r, err := parseFlags(cmd)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			ctx := cmd.Context()
+
+			skipSetup, _ := cmd.Flags().GetBool(FlagSkipSetup)
+			cleanup, _ := cmd.Flags().GetBool(FlagCleanup)
+			var wcl rts.WriteServiceClient
+			if !skipSetup || cleanup {
+				conn, err := client.GetWriteConn(cmd)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				wcl = rts.NewWriteServiceClient(conn)
+			}
+
+			if !skipSetup {
+				if create, _ := cmd.Flags().GetBool(FlagCreateNamespace); create {
+					if err := d.createNamespaces(cmd); err != nil {
+						_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+						return cmdx.FailSilently(cmd)
+					}
+				}
+				start := time.Now()
+				n, err := d.write(cmd, wcl)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+					return cmdx.FailSilently(cmd)
+				}
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d relation tuples in %s.\n", n, time.Since(start).Round(time.Millisecond))
+			}
+
+			conn, err := client.GetReadConn(cmd)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			r.clients = clients{
+				check:  rts.NewCheckServiceClient(conn),
+				read:   rts.NewReadServiceClient(conn),
+				expand: rts.NewExpandServiceClient(conn),
+			}
+
+			results, elapsed := r.exec(ctx)
+			rep := newReport(r, results, elapsed)
+
+			for _, w := range workloads {
+				if err := results[w].firstErr; err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The first %s request error was: %s\n", w, err)
+				}
+			}
+			if cleanup {
+				if err := d.cleanup(cmd, wcl); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not delete the relation tuples of the namespace %s: %s\n", d.Namespace, err)
+					return cmdx.FailSilently(cmd)
+				}
+			}
+
+			cmdx.PrintTable(cmd, rep)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(FlagNamespace, "Benchmark", "The namespace of the synthetic dataset. All its relation tuples are replaced.")
+	cmd.Flags().Bool(FlagCreateNamespace, false, "Create the namespace of the synthetic dataset through the namespace administration API.")
+	cmd.Flags().Int(FlagDocuments, 100, "The number of documents.")
+	cmd.Flags().Int(FlagGroups, 10, "The number of groups, every document has one group as member.")
+	cmd.Flags().Int(FlagUsers, 1000, "The number of users the members are chosen from.")
+	cmd.Flags().Int(FlagTuplesPerObject, 10, "The number of members of every document and group.")
+	cmd.Flags().Int64(FlagSeed, 1, "The seed of the dataset and the requests.")
+	cmd.Flags().Bool(FlagSkipSetup, false, "Do not write the dataset, use the relation tuples written by an earlier run.")
+	cmd.Flags().Bool(FlagCleanup, false, "Delete the relation tuples of the namespace after the run.")
+	cmd.Flags().String(FlagWorkload, "check=8,list=1,expand=1", "The weights of the check, list, and expand workloads.")
+	cmd.Flags().Int(FlagConcurrency, 10, "The number of concurrent requests.")
+	cmd.Flags().Int(FlagRequests, 1000, "The total number of requests. Ignored if --duration is set.")
+	cmd.Flags().Duration(FlagDuration, 0, "Send requests for this long instead of a fixed number of requests.")
+
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+	cmdx.RegisterFormatFlags(cmd.Flags())
+
+	return cmd
+}
+
+func parseFlags(cmd *cobra.Command) (*dataset, *run, error) {
+	f := cmd.Flags()
+	d := &dataset{}
+	d.Namespace, _ = f.GetString(FlagNamespace)
+	d.Documents, _ = f.GetInt(FlagDocuments)
+	d.Groups, _ = f.GetInt(FlagGroups)
+	d.Users, _ = f.GetInt(FlagUsers)
+	d.TuplesPerObject, _ = f.GetInt(FlagTuplesPerObject)
+	d.Seed, _ = f.GetInt64(FlagSeed)
+
+	switch {
+	case d.Namespace == "":
+		return nil, nil, fmt.Errorf("--%s must not be empty", FlagNamespace)
+	case d.Documents < 1:
+		return nil, nil, fmt.Errorf("--%s must be at least 1", FlagDocuments)
+	case d.Users < 1:
+		return nil, nil, fmt.Errorf("--%s must be at least 1", FlagUsers)
+	case d.Groups < 0:
+		return nil, nil, fmt.Errorf("--%s must not be negative", FlagGroups)
+	case d.TuplesPerObject < 1:
+		return nil, nil, fmt.Errorf("--%s must be at least 1", FlagTuplesPerObject)
+	}
+
+	raw, _ := f.GetString(FlagWorkload)
+	m, err := parseMix(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := &run{data: d, mix: m}
+	r.concurrency, _ = f.GetInt(FlagConcurrency)
+	if r.concurrency < 1 {
+		return nil, nil, fmt.Errorf("--%s must be at least 1", FlagConcurrency)
+	}
+	r.duration, _ = f.GetDuration(FlagDuration)
+	if r.duration > 0 {
+		return d, r, nil
+	}
+	requests, _ := f.GetInt(FlagRequests)
+	if requests < 1 {
+		return nil, nil, fmt.Errorf("either --%s or --%s has to be positive", FlagRequests, FlagDuration)
+	}
+	r.requests = int64(requests)
+	return d, r, nil
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command) {
+	parent.AddCommand(newBenchmarkCmd())
+}
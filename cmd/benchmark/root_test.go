@@ -0,0 +1,107 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ory/x/cmdx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+func TestBenchmark(t *testing.T) {
+	ts := client.NewTestServer(t, client.WriteServer, []*namespace.Namespace{{Name: "Benchmark"}}, newBenchmarkCmd)
+	defer ts.Shutdown(t)
+
+	// The benchmark also needs the read API.
+	read := ts.Reg.ReadGRPCServer(context.Background())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	eg := &errgroup.Group{}
+	eg.Go(func() error { return read.Serve(l) })
+	defer func() {
+		read.GracefulStop()
+		require.NoError(t, eg.Wait())
+	}()
+
+	dataFlags := []string{
+		"--" + client.FlagReadRemote, l.Addr().String(),
+		"--" + FlagDocuments, "5", "--" + FlagGroups, "2", "--" + FlagUsers, "20", "--" + FlagTuplesPerObject, "3",
+		"--" + cmdx.FlagFormat, string(cmdx.FormatJSON),
+	}
+	bench := func(t *testing.T, args ...string) *report {
+		stdOut, stdErr, err := ts.Cmd.Exec(nil, append(args, dataFlags...)...)
+		require.NoError(t, err, stdErr)
+
+		var rep report
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &rep), stdOut)
+		return &rep
+	}
+	// The generator can draw the same subject twice for an object, which is
+	// only stored once.
+	data, err := (&dataset{Namespace: "Benchmark", Documents: 5, Groups: 2, Users: 20, TuplesPerObject: 3, Seed: 1}).generate()
+	require.NoError(t, err)
+	distinct := make(map[string]struct{}, len(data.Tuples))
+	for _, tuple := range data.Tuples {
+		distinct[tuple.String()] = struct{}{}
+	}
+	countTuples := func(t *testing.T) int {
+		tuples, _, err := ts.Reg.RelationTupleManager().GetRelationTuples(context.Background(), &relationtuple.RelationQuery{})
+		require.NoError(t, err)
+		return len(tuples)
+	}
+
+	t.Run("case=writes the dataset and runs all workloads", func(t *testing.T) {
+		rep := bench(t, "--"+FlagRequests, "60", "--"+FlagConcurrency, "3")
+
+		assert.Equal(t, len(distinct), countTuples(t))
+		require.Len(t, rep.Workloads, 3)
+		assert.Equal(t, 60, rep.Total.Requests)
+		assert.Zero(t, rep.Total.Errors)
+		for _, w := range rep.Workloads {
+			assert.Positive(t, w.P50, w.Workload)
+			assert.LessOrEqual(t, w.P50, w.P99, w.Workload)
+			assert.LessOrEqual(t, w.P99, w.Max, w.Workload)
+		}
+	})
+
+	t.Run("case=repeated runs replace the dataset", func(t *testing.T) {
+		rep := bench(t, "--"+FlagRequests, "10", "--"+FlagWorkload, "check=1")
+
+		assert.Equal(t, len(distinct), countTuples(t))
+		require.Len(t, rep.Workloads, 1)
+		assert.Equal(t, WorkloadCheck, rep.Workloads[0].Workload)
+		assert.Equal(t, 10, rep.Workloads[0].Requests)
+	})
+
+	t.Run("case=cleanup", func(t *testing.T) {
+		bench(t, "--"+FlagRequests, "5", "--"+FlagSkipSetup, "--"+FlagCleanup)
+		assert.Zero(t, countTuples(t))
+	})
+
+	t.Run("case=invalid workload", func(t *testing.T) {
+		_, stdErr, err := ts.Cmd.Exec(nil, "--"+FlagWorkload, "check=1,write=1")
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdErr, `unknown workload "write"`)
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i + 1)
+	}
+	assert.Equal(t, time.Duration(50), percentile(sorted, 50))
+	assert.Equal(t, time.Duration(99), percentile(sorted, 99))
+	assert.Equal(t, time.Duration(100), percentile(sorted, 100))
+	assert.Equal(t, time.Duration(1), percentile(sorted[:1], 99))
+	assert.Zero(t, percentile(nil, 50))
+}
@@ -0,0 +1,240 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ory/keto/ketodataset"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const (
+	WorkloadCheck  = "check"
+	WorkloadList   = "list"
+	WorkloadExpand = "expand"
+
+	// expandMaxDepth is deep enough to expand the group of a document.
+	expandMaxDepth = 3
+)
+
+var workloads = []string{WorkloadCheck, WorkloadList, WorkloadExpand}
+
+type (
+	// mix is the weight of each workload, the share of the requests that are
+	// sent to it.
+	mix map[string]int
+
+	clients struct {
+		check  rts.CheckServiceClient
+		read   rts.ReadServiceClient
+		expand rts.ExpandServiceClient
+	}
+
+	// run sends requests until the number of requests is reached, or for the
+	// duration if the number of requests is not positive.
+	run struct {
+		data        *dataset
+		mix         mix
+		requests    int64
+		duration    time.Duration
+		concurrency int
+		clients     clients
+	}
+
+	// samples are the results of the requests of one workload.
+	samples struct {
+		latencies []time.Duration
+		errors    int
+		firstErr  error
+	}
+)
+
+// parseMix parses a list like "check=8,list=1,expand=1". A workload without
+// a weight has the weight 1.
+func parseMix(raw string) (mix, error) {
+	m := make(mix)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weight, hasWeight := strings.Cut(part, "=")
+		if !isWorkload(name) {
+			return nil, fmt.Errorf("unknown workload %q, expected one of %s", name, strings.Join(workloads, ", "))
+		}
+		w := 1
+		if hasWeight {
+			var err error
+			if w, err = strconv.Atoi(weight); err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight %q of workload %s, expected a non-negative integer", weight, name)
+			}
+		}
+		m[name] += w
+	}
+	if m.total() == 0 {
+		return nil, fmt.Errorf("the workload %q has no requests", raw)
+	}
+	return m, nil
+}
+
+func isWorkload(name string) bool {
+	for _, w := range workloads {
+		if w == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m mix) total() int {
+	t := 0
+	for _, w := range m {
+		t += w
+	}
+	return t
+}
+
+// pick chooses a workload with a probability proportional to its weight.
+func (m mix) pick(r *rand.Rand) string {
+	n := r.Intn(m.total())
+	for _, w := range workloads {
+		if n < m[w] {
+			return w
+		}
+		n -= m[w]
+	}
+	return workloads[len(workloads)-1]
+}
+
+// exec runs the benchmark. Every worker has its own random source derived
+// from the seed, so that the requests only depend on the flags.
+func (r *run) exec(ctx context.Context) (map[string]*samples, time.Duration) {
+	var (
+		sent    int64
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]*samples)
+	)
+	for _, w := range workloads {
+		results[w] = &samples{}
+	}
+
+	start := time.Now()
+	deadline := start.Add(r.duration)
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(r.data.Seed + int64(worker) + 1))
+			local := make(map[string]*samples)
+			for _, w := range workloads {
+				local[w] = &samples{}
+			}
+
+			for ctx.Err() == nil {
+				if r.requests > 0 {
+					if atomic.AddInt64(&sent, 1) > r.requests {
+						break
+					}
+				} else if time.Now().After(deadline) {
+					break
+				}
+
+				w := r.mix.pick(rnd)
+				reqStart := time.Now()
+				err := r.request(ctx, rnd, w)
+				s := local[w]
+				s.latencies = append(s.latencies, time.Since(reqStart))
+				if err != nil {
+					s.errors++
+					if s.firstErr == nil {
+						s.firstErr = err
+					}
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for w, s := range local {
+				results[w].latencies = append(results[w].latencies, s.latencies...)
+				results[w].errors += s.errors
+				if results[w].firstErr == nil {
+					results[w].firstErr = s.firstErr
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results, time.Since(start)
+}
+
+func (r *run) request(ctx context.Context, rnd *rand.Rand, workload string) error {
+	d := r.data
+	object := ketodataset.Document(rnd.Intn(d.Documents))
+
+	var err error
+	switch workload {
+	case WorkloadCheck:
+		_, err = r.clients.check.Check(ctx, &rts.CheckRequest{
+			Namespace: d.Namespace,
+			Object:    object,
+			Relation:  relation,
+			Subject:   rts.NewSubjectID(ketodataset.User(rnd.Intn(d.Users))),
+		})
+	case WorkloadList:
+		_, err = r.clients.read.ListRelationTuples(ctx, &rts.ListRelationTuplesRequest{
+			RelationQuery: &rts.RelationQuery{Namespace: &d.Namespace, Object: &object},
+		})
+	case WorkloadExpand:
+		_, err = r.clients.expand.Expand(ctx, &rts.ExpandRequest{
+			Subject:  rts.NewSubjectSet(d.Namespace, object, relation),
+			MaxDepth: expandMaxDepth,
+		})
+	}
+	return err
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func (s *samples) stats(name string, elapsed time.Duration) *workloadStats {
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+
+	st := &workloadStats{Workload: name, Requests: len(s.latencies), Errors: s.errors}
+	if st.Requests == 0 {
+		return st
+	}
+	var sum time.Duration
+	for _, l := range s.latencies {
+		sum += l
+	}
+	st.ErrorRate = float64(s.errors) / float64(st.Requests)
+	st.Mean = sum / time.Duration(st.Requests)
+	st.P50 = percentile(s.latencies, 50)
+	st.P90 = percentile(s.latencies, 90)
+	st.P99 = percentile(s.latencies, 99)
+	st.Max = s.latencies[len(s.latencies)-1]
+	if elapsed > 0 {
+		st.Throughput = float64(st.Requests) / elapsed.Seconds()
+	}
+	return st
+}
@@ -0,0 +1,178 @@
+package check
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/ketoapi"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const FlagBatch = "batch"
+
+type (
+	// BatchCheck is one line of a batch file and its result.
+	BatchCheck struct {
+		Line     int                    `json:"line"`
+		Tuple    *ketoapi.RelationTuple `json:"tuple"`
+		Expected bool                   `json:"expected"`
+		Allowed  bool                   `json:"allowed"`
+		Error    string                 `json:"error,omitempty"`
+	}
+	BatchSummary struct {
+		Total  int `json:"total"`
+		Passed int `json:"passed"`
+		Failed int `json:"failed"`
+		Errors int `json:"errors"`
+	}
+	// BatchOutput are the results of all checks of a batch file.
+	BatchOutput struct {
+		Results []*BatchCheck `json:"results"`
+		Summary BatchSummary  `json:"summary"`
+	}
+)
+
+func NewBatchOutput(checks []*BatchCheck) *BatchOutput {
+	return &BatchOutput{Results: checks, Summary: BatchSummary{Total: len(checks)}}
+}
+
+// Record sets the result of the check.
+func (o *BatchOutput) Record(c *BatchCheck, allowed bool, err error) {
+	switch {
+	case err != nil:
+		c.Error = err.Error()
+		o.Summary.Errors++
+	case allowed == c.Expected:
+		c.Allowed = allowed
+		o.Summary.Passed++
+	default:
+		c.Allowed = allowed
+		o.Summary.Failed++
+	}
+}
+
+// Print prints the results and the summary, and fails if any check did not
+// have the expected result.
+func (o *BatchOutput) Print(cmd *cobra.Command) error {
+	cmdx.PrintTable(cmd, o)
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d checks: %d passed, %d failed, %d errors\n", o.Summary.Total, o.Summary.Passed, o.Summary.Failed, o.Summary.Errors)
+
+	if o.Summary.Passed != o.Summary.Total {
+		return cmdx.FailSilently(cmd)
+	}
+	return nil
+}
+
+func (c *BatchCheck) passed() bool {
+	return c.Error == "" && c.Allowed == c.Expected
+}
+
+func (o *BatchOutput) Header() []string {
+	return []string{"LINE", "TUPLE", "EXPECTED", "RESULT", "PASSED"}
+}
+
+func (o *BatchOutput) Table() [][]string {
+	data := make([][]string, len(o.Results))
+	for i, c := range o.Results {
+		result := allowedString(c.Allowed)
+		if c.Error != "" {
+			result = "Error: " + c.Error
+		}
+		data[i] = []string{strconv.Itoa(c.Line), c.Tuple.String(), allowedString(c.Expected), result, strconv.FormatBool(c.passed())}
+	}
+	return data
+}
+
+func (o *BatchOutput) Interface() interface{} {
+	return o
+}
+
+func (o *BatchOutput) Len() int {
+	return len(o.Results)
+}
+
+func allowedString(allowed bool) string {
+	if allowed {
+		return "Allowed"
+	}
+	return "Denied"
+}
+
+// ParseBatch reads one relation tuple per line. A line that starts with "!"
+// is expected to be denied, all other lines are expected to be allowed.
+// Comments (starting with "//") and blank lines are ignored.
+func ParseBatch(r io.Reader, fn string) ([]*BatchCheck, error) {
+	var checks []*BatchCheck
+	s := bufio.NewScanner(r)
+	for i := 1; s.Scan(); i++ {
+		row := strings.TrimSpace(s.Text())
+		if row == "" || strings.HasPrefix(row, "//") {
+			continue
+		}
+
+		c := &BatchCheck{Line: i, Expected: true}
+		if strings.HasPrefix(row, "!") {
+			c.Expected = false
+			row = strings.TrimSpace(row[1:])
+		}
+		rt, err := (&ketoapi.RelationTuple{}).FromString(row)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s:%d\n  %s\n\n%w", fn, i, row, err)
+		}
+		c.Tuple = rt
+		checks = append(checks, c)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", fn, err)
+	}
+	return checks, nil
+}
+
+// runBatch checks every line of the batch file, and fails if any check did
+// not have the expected result.
+func runBatch(cmd *cobra.Command, fn string, maxDepth int32) error {
+	var f io.Reader
+	if fn == "-" {
+		fn = "stdin"
+		f = cmd.InOrStdin()
+	} else {
+		ff, err := os.Open(fn)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not open file %s: %v\n", fn, err)
+			return cmdx.FailSilently(cmd)
+		}
+		defer ff.Close()
+		f = ff
+	}
+
+	checks, err := ParseBatch(f, fn)
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+
+	conn, err := client.GetReadConn(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cl := rts.NewCheckServiceClient(conn)
+
+	out := NewBatchOutput(checks)
+	for _, c := range checks {
+		resp, err := cl.Check(cmd.Context(), &rts.CheckRequest{
+			Tuple:    c.Tuple.ToProto(),
+			MaxDepth: maxDepth,
+		})
+		out.Record(c, resp.GetAllowed(), err)
+	}
+	return out.Print(cmd)
+}
@@ -28,19 +28,35 @@ func newCheckCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "check <subject> <relation> <namespace> <object>",
 		Short: "Check whether a subject has a relation on an object",
-		Long:  "Check whether a subject has a relation on an object. This method resolves subject sets and subject set rewrites.",
-		Args:  cobra.ExactArgs(4),
+		Long: "Check whether a subject has a relation on an object. This method resolves subject sets and subject set rewrites.\n\n" +
+			"With --batch, the relation tuples are read from a file (or stdin with -), one per line in the form namespace:object#relation@subject. " +
+			"Every line is expected to be allowed, unless it starts with !, e.g. !namespace:object#relation@subject. " +
+			"Comments (starting with //) and blank lines are ignored. " +
+			"The result of every line and a summary are printed, and the command fails if any check does not have the expected result.",
+		Example: `keto check --batch smoke-tests.txt
+echo 'files:readme#view@alice' | keto check --batch - --format json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if batch, _ := cmd.Flags().GetString(FlagBatch); batch != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(4)(cmd, args)
+		},
+		ValidArgsFunction: client.CompletePositional(nil, client.CompleteRelations(nil), client.CompleteNamespaces),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			conn, err := client.GetReadConn(cmd)
+			maxDepth, err := cmd.Flags().GetInt32(FlagMaxDepth)
 			if err != nil {
 				return err
 			}
-			defer conn.Close()
 
-			maxDepth, err := cmd.Flags().GetInt32(FlagMaxDepth)
+			if batch, _ := cmd.Flags().GetString(FlagBatch); batch != "" {
+				return runBatch(cmd, batch, maxDepth)
+			}
+
+			conn, err := client.GetReadConn(cmd)
 			if err != nil {
 				return err
 			}
+			defer conn.Close()
 
 			cl := rts.NewCheckServiceClient(conn)
 			resp, err := cl.Check(cmd.Context(), &rts.CheckRequest{
@@ -62,6 +78,7 @@ func newCheckCmd() *cobra.Command {
 
 	client.RegisterRemoteURLFlags(cmd.Flags())
 	cmdx.RegisterFormatFlags(cmd.Flags())
+	cmd.Flags().String(FlagBatch, "", "Check the relation tuples of the file, one per line, or of stdin with -.")
 	cmd.Flags().Int32P(FlagMaxDepth, "d", 0, "Maximum depth of the search tree. If the value is less than 1 or greater than the global max-depth then the global max-depth will be used instead.")
 
 	return cmd
@@ -1,12 +1,23 @@
 package check
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/ory/x/cmdx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/driver"
 	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
 )
 
 func TestCheckCommand(t *testing.T) {
@@ -17,3 +28,59 @@ func TestCheckCommand(t *testing.T) {
 	stdOut := ts.Cmd.ExecNoErr(t, "subject", "access", nspace.Name, "object")
 	assert.Equal(t, "Denied\n", stdOut)
 }
+
+func TestCheckBatch(t *testing.T) {
+	nspace := &namespace.Namespace{Name: t.Name()}
+	ts := client.NewTestServer(t, client.ReadServer, []*namespace.Namespace{nspace}, newCheckCmd)
+	defer ts.Shutdown(t)
+
+	relationtuple.MapAndWriteTuples(t, ts.Reg.(*driver.RegistryDefault), &ketoapi.RelationTuple{Namespace: nspace.Name, Object: "readme", Relation: "view", SubjectID: x.Ptr("alice")})
+
+	t.Run("case=all checks pass", func(t *testing.T) {
+		stdin := strings.NewReader(fmt.Sprintf(`// smoke tests
+%[1]s:readme#view@alice
+
+!%[1]s:readme#view@bob
+`, nspace.Name))
+		stdOut, stdErr, err := ts.Cmd.Exec(stdin, "--"+FlagBatch, "-", "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))
+		require.NoError(t, err, stdErr)
+		assert.Equal(t, "2 checks: 2 passed, 0 failed, 0 errors\n", stdErr)
+
+		var out BatchOutput
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &out))
+		require.Len(t, out.Results, 2)
+		assert.Equal(t, 2, out.Results[0].Line)
+		assert.True(t, out.Results[0].Allowed)
+		assert.Equal(t, 4, out.Results[1].Line)
+		assert.False(t, out.Results[1].Expected)
+		assert.False(t, out.Results[1].Allowed)
+	})
+
+	t.Run("case=fails if a check does not have the expected result", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "checks.txt")
+		require.NoError(t, os.WriteFile(fn, []byte(fmt.Sprintf("%[1]s:readme#view@alice\n%[1]s:readme#view@bob\n", nspace.Name)), 0600))
+
+		stdOut, stdErr, err := ts.Cmd.Exec(nil, "--"+FlagBatch, fn)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, "2 checks: 1 passed, 1 failed, 0 errors\n", stdErr)
+		assert.Contains(t, stdOut, nspace.Name+":readme#view@bob")
+		assert.Regexp(t, `view@bob\s+Allowed\s+Denied\s+false`, stdOut)
+	})
+
+	t.Run("case=empty batch", func(t *testing.T) {
+		_, stdErr, err := ts.Cmd.Exec(strings.NewReader("// nothing to check\n"), "--"+FlagBatch, "-")
+		require.NoError(t, err)
+		assert.Equal(t, "0 checks: 0 passed, 0 failed, 0 errors\n", stdErr)
+	})
+
+	t.Run("case=rejects invalid lines", func(t *testing.T) {
+		_, stdErr, err := ts.Cmd.Exec(strings.NewReader("not a tuple"), "--"+FlagBatch, "-")
+		require.Error(t, err)
+		assert.Contains(t, stdErr, "stdin:1")
+	})
+
+	t.Run("case=does not accept arguments", func(t *testing.T) {
+		_, _, err := ts.Cmd.Exec(nil, "--"+FlagBatch, "-", "subject")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,73 @@
+package cleanup
+
+import (
+	"fmt"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoctx"
+)
+
+const (
+	FlagDryRun    = "dry-run"
+	FlagBatchSize = "batch-size"
+)
+
+func newCleanupCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete the UUID mappings that are not referenced anymore",
+		Long: fmt.Sprintf(`Delete the mappings of subject IDs and objects that no relation tuple references anymore.
+
+A mapping is first marked as orphaned, and deleted by a later run once it was orphaned for %s.
+Run this command periodically, or set %s to collect the mappings in the background.`, config.KeyUUIDMappingGCGracePeriod, config.KeyUUIDMappingGCInterval),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), false, opts...)
+			if err != nil {
+				return err
+			}
+			gm := reg.MappingGCManager()
+			if gm == nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "The UUID mappings can only be cleaned up if the relation tuples are stored in the primary SQL database.")
+				return cmdx.FailSilently(cmd)
+			}
+
+			gc := reg.Config(ctx).UUIDMappingGC()
+			gcOpts := relationtuple.MappingGCOptions{GracePeriod: gc.GracePeriod, BatchSize: gc.BatchSize}
+			if cmd.Flags().Changed(FlagBatchSize) {
+				if gcOpts.BatchSize, err = cmd.Flags().GetInt(FlagBatchSize); err != nil {
+					return err
+				}
+			}
+			if gcOpts.DryRun, err = cmd.Flags().GetBool(FlagDryRun); err != nil {
+				return err
+			}
+
+			res, err := gm.CollectOrphanedMappings(ctx, gcOpts)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not clean up the UUID mappings: %+v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			if gcOpts.DryRun {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Would mark %d, unmark %d, and delete %d UUID mappings.\n", res.Marked, res.Unmarked, res.Deleted)
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Marked %d, unmarked %d, and deleted %d UUID mappings.\n", res.Marked, res.Unmarked, res.Deleted)
+			return nil
+		},
+	}
+	cmd.Flags().Bool(FlagDryRun, false, "Only count the UUID mappings that would be changed.")
+	cmd.Flags().Int(FlagBatchSize, 1000, fmt.Sprintf("How many UUID mappings are checked at once, overrides %s.", config.KeyUUIDMappingGCBatchSize))
+	return cmd
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command, opts []ketoctx.Option) {
+	parent.AddCommand(newCleanupCmd(opts))
+}
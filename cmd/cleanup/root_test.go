@@ -0,0 +1,37 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestCleanup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cf := dbx.ConfigFile(t, map[string]interface{}{
+		config.KeyDSN:        "memory",
+		config.KeyNamespaces: []*namespace.Namespace{},
+	})
+	cmd := &cmdx.CommandExecuter{
+		New: func() *cobra.Command {
+			cmd := newCleanupCmd(nil)
+			configx.RegisterFlags(cmd.PersistentFlags())
+			return cmd
+		},
+		Ctx:            ctx,
+		PersistentArgs: []string{"-c", cf},
+	}
+
+	assert.Equal(t, "Would mark 0, unmark 0, and delete 0 UUID mappings.\n", cmd.ExecNoErr(t, "--"+FlagDryRun))
+	assert.Equal(t, "Marked 0, unmarked 0, and deleted 0 UUID mappings.\n", cmd.ExecNoErr(t, "--"+FlagBatchSize, "10"))
+}
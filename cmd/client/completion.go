@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+)
+
+// completionTimeout bounds the request for the namespaces, so that the shell
+// does not hang if the server is not reachable.
+const completionTimeout = 2 * time.Second
+
+// CompletionFunc completes an argument or a flag value.
+type CompletionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// CompletePositional completes every positional argument with the function
+// at its position. Arguments without a function are not completed.
+func CompletePositional(fns ...CompletionFunc) CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(fns) || fns[len(args)] == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fns[len(args)](cmd, args, toComplete)
+	}
+}
+
+// CompleteNamespaces completes the names of the namespaces of the server.
+func CompleteNamespaces(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	nn, err := effectiveNamespaces(cmd)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	names := make([]string, 0, len(nn))
+	for _, n := range nn {
+		names = append(names, n.Name)
+	}
+	return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteRelations completes the relations and permissions of the namespace
+// that namespace returns, or of all namespaces if namespace is nil or returns
+// the empty string.
+func CompleteRelations(namespace func(cmd *cobra.Command, args []string) string) CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		nn, err := effectiveNamespaces(cmd)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), false)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var ns string
+		if namespace != nil {
+			ns = namespace(cmd, args)
+		}
+		seen := make(map[string]struct{})
+		var relations []string
+		for _, n := range nn {
+			if ns != "" && n.Name != ns {
+				continue
+			}
+			for _, r := range n.Relations {
+				if _, ok := seen[r.Name]; !ok {
+					seen[r.Name] = struct{}{}
+					relations = append(relations, r.Name)
+				}
+			}
+		}
+		return filterCompletions(relations, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// NamespaceFromFlag returns the value of the flag, for CompleteRelations.
+func NamespaceFromFlag(flag string) func(cmd *cobra.Command, _ []string) string {
+	return func(cmd *cobra.Command, _ []string) string {
+		if f := cmd.Flags().Lookup(flag); f != nil {
+			return f.Value.String()
+		}
+		return ""
+	}
+}
+
+func filterCompletions(candidates []string, toComplete string) []string {
+	res := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if strings.HasPrefix(c, toComplete) {
+			res = append(res, c)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// effectiveNamespaces gets the namespaces the server uses from the admin API.
+func effectiveNamespaces(cmd *cobra.Command) ([]*namespacehandler.EffectiveNamespace, error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	u := GetAdminURL(cmd)
+	u.Path = namespacehandler.RouteBase
+	u.RawQuery = url.Values{"format": {namespacehandler.FormatJSON}}.Encode()
+	req, err := NewRequest(cmd, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrorFromResponse(resp)
+	}
+
+	var nn namespacehandler.EffectiveNamespaces
+	if err := json.NewDecoder(resp.Body).Decode(&nn); err != nil {
+		return nil, err
+	}
+	return nn.Namespaces, nil
+}
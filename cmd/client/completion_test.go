@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/check"
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/cmd/relationtuple"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+)
+
+func TestCompletion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, namespacehandler.RouteBase, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(&namespacehandler.EffectiveNamespaces{Namespaces: []*namespacehandler.EffectiveNamespace{
+			{Name: "documents", Relations: []ast.Relation{{Name: "viewers"}, {Name: "view"}}},
+			{Name: "groups", Relations: []ast.Relation{{Name: "members"}}},
+			{Name: "directories", Relations: []ast.Relation{{Name: "viewers"}, {Name: "parent"}}},
+		}})
+	}))
+	t.Cleanup(ts.Close)
+	remote := strings.TrimPrefix(ts.URL, "http://")
+
+	complete := func(t *testing.T, args ...string) []string {
+		root := &cobra.Command{Use: "keto"}
+		check.RegisterCommandsRecursive(root)
+		relationtuple.RegisterCommandsRecursive(root)
+
+		stdOut, stdErr, err := cmdx.Exec(t, root, nil, append([]string{cobra.ShellCompRequestCmd}, args...)...)
+		require.NoError(t, err, stdErr)
+		lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+		// The last line is the directive.
+		return lines[:len(lines)-1]
+	}
+
+	t.Run("case=namespaces of check", func(t *testing.T) {
+		assert.Equal(t, []string{"directories", "documents"}, complete(t, "check", "--"+client.FlagWriteRemote, remote, "alice", "view", "d"))
+	})
+
+	t.Run("case=relations of all namespaces", func(t *testing.T) {
+		assert.Equal(t, []string{"view", "viewers"}, complete(t, "check", "--"+client.FlagWriteRemote, remote, "alice", "vi"))
+	})
+
+	t.Run("case=relations of the namespace flag", func(t *testing.T) {
+		assert.Equal(t, []string{"parent", "viewers"}, complete(t, "relation-tuple", "get", "--"+client.FlagWriteRemote, remote, "--namespace", "directories", "--relation", ""))
+	})
+
+	t.Run("case=no completions of other arguments", func(t *testing.T) {
+		assert.Empty(t, complete(t, "check", "--"+client.FlagWriteRemote, remote, ""))
+		assert.Empty(t, complete(t, "check", "--"+client.FlagWriteRemote, remote, "alice", "view", "documents", ""))
+	})
+
+	t.Run("case=unreachable server", func(t *testing.T) {
+		assert.Empty(t, complete(t, "check", "--"+client.FlagWriteRemote, "127.0.0.1:1", "alice", "view", "d"))
+	})
+}
@@ -22,9 +22,13 @@ type contextKeys string
 const (
 	FlagReadRemote  = "read-remote"
 	FlagWriteRemote = "write-remote"
+	FlagAdminRemote = "admin-remote"
+	FlagBearerToken = "bearer-token"
 
 	EnvReadRemote  = "KETO_READ_REMOTE"
 	EnvWriteRemote = "KETO_WRITE_REMOTE"
+	EnvAdminRemote = "KETO_ADMIN_REMOTE"
+	EnvBearerToken = "KETO_BEARER_TOKEN"
 
 	ContextKeyTimeout contextKeys = "timeout"
 )
@@ -49,14 +53,16 @@ func getRemote(cmd *cobra.Command, flagRemote, envRemote string) (remote string)
 }
 
 func GetReadConn(cmd *cobra.Command) (*grpc.ClientConn, error) {
-	return Conn(cmd.Context(), getRemote(cmd, FlagReadRemote, EnvReadRemote))
+	remote := getRemote(cmd, FlagReadRemote, EnvReadRemote)
+	return Conn(cmd.Context(), remote, bearerTokenOptions(cmd, remote)...)
 }
 
 func GetWriteConn(cmd *cobra.Command) (*grpc.ClientConn, error) {
-	return Conn(cmd.Context(), getRemote(cmd, FlagWriteRemote, EnvWriteRemote))
+	remote := getRemote(cmd, FlagWriteRemote, EnvWriteRemote)
+	return Conn(cmd.Context(), remote, bearerTokenOptions(cmd, remote)...)
 }
 
-func Conn(ctx context.Context, remote string) (*grpc.ClientConn, error) {
+func Conn(ctx context.Context, remote string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	timeout := 3 * time.Second
 	if d, ok := ctx.Value(ContextKeyTimeout).(time.Duration); ok {
 		timeout = d
@@ -64,15 +70,50 @@ func Conn(ctx context.Context, remote string) (*grpc.ClientConn, error) {
 
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	return grpc.DialContext(ctx, remote,
+	return grpc.DialContext(ctx, remote, append([]grpc.DialOption{
 		grpc.WithTransportCredentials(transportCredentials(remote)),
 		grpc.WithBlock(),
-		grpc.WithDisableHealthCheck())
+		grpc.WithDisableHealthCheck(),
+	}, opts...)...)
 }
 
-func transportCredentials(remote string) credentials.TransportCredentials {
+// getBearerToken returns the token the client authenticates with, if any.
+func getBearerToken(cmd *cobra.Command) string {
+	if f := cmd.Flags().Lookup(FlagBearerToken); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	return os.Getenv(EnvBearerToken)
+}
+
+// bearerToken sends the token as authorization metadata with every call.
+type bearerToken struct {
+	token  string
+	secure bool
+}
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+func bearerTokenOptions(cmd *cobra.Command, remote string) []grpc.DialOption {
+	token := getBearerToken(cmd)
+	if token == "" {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithPerRPCCredentials(bearerToken{token: token, secure: !isLocalRemote(remote)})}
+}
+
+func isLocalRemote(remote string) bool {
 	host, _, err := net.SplitHostPort(remote)
-	if err == nil && (host == "127.0.0.1" || host == "localhost") {
+	return err == nil && (host == "127.0.0.1" || host == "localhost")
+}
+
+func transportCredentials(remote string) credentials.TransportCredentials {
+	if isLocalRemote(remote) {
 		return insecure.NewCredentials()
 	}
 
@@ -83,4 +124,17 @@ func transportCredentials(remote string) credentials.TransportCredentials {
 func RegisterRemoteURLFlags(flags *pflag.FlagSet) {
 	flags.String(FlagReadRemote, "127.0.0.1:4466", "Remote address of the read API endpoint.")
 	flags.String(FlagWriteRemote, "127.0.0.1:4467", "Remote address of the write API endpoint.")
+	RegisterBearerTokenFlag(flags)
+}
+
+// RegisterBearerTokenFlag registers the flag of the bearer token, for commands
+// that do not use the default remote addresses.
+func RegisterBearerTokenFlag(flags *pflag.FlagSet) {
+	flags.String(FlagBearerToken, "", fmt.Sprintf("Bearer token to authenticate with, if the server requires authentication. Prefer the %s environment variable, as flags are visible to other users of the system.", EnvBearerToken))
+}
+
+// RegisterAdminRemoteURLFlag registers the flag of the admin API endpoint, for
+// commands that use the schema and operational endpoints.
+func RegisterAdminRemoteURLFlag(flags *pflag.FlagSet) {
+	flags.String(FlagAdminRemote, "", "Remote address of the admin API endpoint. Defaults to the write API endpoint, which serves the admin endpoints unless the server has a separate admin API.")
 }
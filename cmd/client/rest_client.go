@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/ory/herodot"
+	"github.com/spf13/cobra"
+)
+
+// GetWriteURL returns the base URL of the REST endpoints of the write API. They
+// are served on the same address as the gRPC endpoints.
+func GetWriteURL(cmd *cobra.Command) *url.URL {
+	return RemoteURL(getRemote(cmd, FlagWriteRemote, EnvWriteRemote))
+}
+
+// GetAdminURL returns the base URL of the schema and operational endpoints.
+// They are served on the write API, unless the server has a separate admin
+// API.
+func GetAdminURL(cmd *cobra.Command) *url.URL {
+	if f := cmd.Flags().Lookup(FlagAdminRemote); f != nil && f.Changed {
+		return RemoteURL(f.Value.String())
+	} else if remote, isSet := os.LookupEnv(EnvAdminRemote); isSet {
+		return RemoteURL(remote)
+	}
+	return GetWriteURL(cmd)
+}
+
+// GetReadURL returns the base URL of the REST endpoints of the read API.
+func GetReadURL(cmd *cobra.Command) *url.URL {
+	return RemoteURL(getRemote(cmd, FlagReadRemote, EnvReadRemote))
+}
+
+// NewRequest returns a request of the command that authenticates with the
+// bearer token, if there is one.
+func NewRequest(cmd *cobra.Command, method, rawURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(cmd.Context(), method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if token := getBearerToken(cmd); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+func RemoteURL(remote string) *url.URL {
+	u := &url.URL{Scheme: "https", Host: remote}
+	if isLocalRemote(remote) {
+		u.Scheme = "http"
+	}
+	return u
+}
+
+// ErrorFromResponse converts a non-successful response into an error,
+// preferring the message of the JSON error body if there is one.
+func ErrorFromResponse(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var e struct {
+		Error herodot.DefaultError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err == nil && e.Error.Error() != "" {
+		msg := e.Error.Error()
+		if reason := e.Error.Reason(); reason != "" {
+			msg += ": " + reason
+		}
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, msg)
+	}
+	return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+}
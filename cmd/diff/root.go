@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/ketoapi"
+)
+
+const FlagIncludeNamespaces = "include-namespaces"
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+type (
+	// result are the differences from the source to the target.
+	result struct {
+		Added      []*ketoapi.RelationTuple `json:"added"`
+		Removed    []*ketoapi.RelationTuple `json:"removed"`
+		Namespaces *namespaceChanges        `json:"namespaces,omitempty"`
+	}
+	namespaceChanges struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+		Changed []string `json:"changed"`
+	}
+)
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <source> <target>",
+		Short: "Compare the relation tuples of two servers or snapshots",
+		Long: `Compare the relation tuples of two servers or snapshot files, and print the relation tuples that were added to or removed from the source to get the target.
+
+Every argument is a snapshot file of ` + "`keto relation-tuple export`" + ` or an NDJSON export, or the address of the admin API of a server if there is no such file.
+Pass the special filename ` + "`-`" + ` to read one of them from STD_IN. The snapshot of a server is read from one consistent snapshot of its database.
+
+With --include-namespaces, the namespace configurations are compared as well. Snapshot files have to be exported with --include-namespaces for that.
+The command fails if there are any differences, so that migrations and blue/green cutovers can be verified in scripts.`,
+		Example: `keto diff blue.example.com:4467 green.example.com:4467
+keto diff snapshot.ndjson 127.0.0.1:4467 --include-namespaces
+keto relation-tuple export - | keto diff - snapshot.ndjson --format json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == "-" && args[1] == "-" {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Only one of the arguments can be read from STD_IN.")
+				return cmdx.FailSilently(cmd)
+			}
+			includeNamespaces, _ := cmd.Flags().GetBool(FlagIncludeNamespaces)
+
+			var snapshots [2]*snapshot
+			for i, source := range args {
+				s, err := readSnapshot(cmd, source, includeNamespaces)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+					return cmdx.FailSilently(cmd)
+				}
+				if includeNamespaces && s.namespaces == nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The snapshot %s does not include the namespaces, export it with --%s.\n", source, FlagIncludeNamespaces)
+					return cmdx.FailSilently(cmd)
+				}
+				snapshots[i] = s
+			}
+
+			res := compare(snapshots[0], snapshots[1], includeNamespaces)
+			cmdx.PrintTable(cmd, res)
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d relation tuples added, %d removed.\n", len(res.Added), len(res.Removed))
+			if res.Namespaces != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d namespaces added, %d removed, %d changed.\n", len(res.Namespaces.Added), len(res.Namespaces.Removed), len(res.Namespaces.Changed))
+			}
+
+			if res.Len() > 0 {
+				return cmdx.FailSilently(cmd)
+			}
+			return nil
+		},
+	}
+
+	client.RegisterBearerTokenFlag(cmd.Flags())
+	cmd.Flags().Bool(FlagIncludeNamespaces, false, "Also compare the namespace configurations.")
+	cmdx.RegisterFormatFlags(cmd.Flags())
+
+	return cmd
+}
+
+// compare returns the differences sorted by namespace name or relation tuple.
+func compare(source, target *snapshot, includeNamespaces bool) *result {
+	res := &result{Added: []*ketoapi.RelationTuple{}, Removed: []*ketoapi.RelationTuple{}}
+	for _, k := range sortedKeys(target.tuples) {
+		if _, ok := source.tuples[k]; !ok {
+			res.Added = append(res.Added, target.tuples[k])
+		}
+	}
+	for _, k := range sortedKeys(source.tuples) {
+		if _, ok := target.tuples[k]; !ok {
+			res.Removed = append(res.Removed, source.tuples[k])
+		}
+	}
+	if !includeNamespaces {
+		return res
+	}
+
+	res.Namespaces = &namespaceChanges{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for _, n := range sortedKeys(target.namespaces) {
+		if config, ok := source.namespaces[n]; !ok {
+			res.Namespaces.Added = append(res.Namespaces.Added, n)
+		} else if !equalJSON(config, target.namespaces[n]) {
+			res.Namespaces.Changed = append(res.Namespaces.Changed, n)
+		}
+	}
+	for _, n := range sortedKeys(source.namespaces) {
+		if _, ok := target.namespaces[n]; !ok {
+			res.Namespaces.Removed = append(res.Namespaces.Removed, n)
+		}
+	}
+	return res
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// equalJSON compares the values independent of the formatting and the order
+// of the keys.
+func equalJSON(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return string(a) == string(b)
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func (r *result) Header() []string {
+	return []string{"CHANGE", "KIND", "VALUE"}
+}
+
+func (r *result) Table() [][]string {
+	data := make([][]string, 0, r.Len())
+	if r.Namespaces != nil {
+		for _, c := range []struct {
+			change string
+			names  []string
+		}{{changeAdded, r.Namespaces.Added}, {changeRemoved, r.Namespaces.Removed}, {changeChanged, r.Namespaces.Changed}} {
+			for _, n := range c.names {
+				data = append(data, []string{c.change, "namespace", n})
+			}
+		}
+	}
+	for _, t := range r.Added {
+		data = append(data, []string{changeAdded, "relation tuple", t.String()})
+	}
+	for _, t := range r.Removed {
+		data = append(data, []string{changeRemoved, "relation tuple", t.String()})
+	}
+	return data
+}
+
+func (r *result) Interface() interface{} {
+	return r
+}
+
+func (r *result) Len() int {
+	l := len(r.Added) + len(r.Removed)
+	if r.Namespaces != nil {
+		l += len(r.Namespaces.Added) + len(r.Namespaces.Removed) + len(r.Namespaces.Changed)
+	}
+	return l
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command) {
+	parent.AddCommand(newDiffCmd())
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/x/cmdx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestDiff(t *testing.T) {
+	reg := driver.NewSqliteTestRegistry(t, false)
+	ctx := context.Background()
+	require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "files"}, {Name: "groups"}}))
+
+	r := &x.AdminRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterAdminRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	server := strings.TrimPrefix(ts.URL, "http://")
+
+	alice := &ketoapi.RelationTuple{Namespace: "files", Object: "a", Relation: "viewer", SubjectID: x.Ptr("alice")}
+	bob := &ketoapi.RelationTuple{Namespace: "files", Object: "b", Relation: "viewer", SubjectID: x.Ptr("bob")}
+	carol := &ketoapi.RelationTuple{Namespace: "groups", Object: "dev", Relation: "member", SubjectSet: &ketoapi.SubjectSet{Namespace: "groups", Object: "ops", Relation: "member"}}
+	relationtuple.MapAndWriteTuples(t, reg, bob, carol)
+
+	dir := t.TempDir()
+	write := func(t *testing.T, name string, lines ...interface{}) string {
+		var sb strings.Builder
+		enc := json.NewEncoder(&sb)
+		var sum *ketoapi.SnapshotChecksum
+		for _, l := range lines {
+			require.NoError(t, enc.Encode(l))
+			if _, ok := l.(*ketoapi.SnapshotHeader); ok {
+				sum = ketoapi.NewSnapshotChecksum()
+			} else if sum != nil {
+				line, err := json.Marshal(l)
+				require.NoError(t, err)
+				sum.Add(line)
+			}
+		}
+		// Snapshots end with a trailer.
+		if sum != nil {
+			trailer, err := ketoapi.EncodeSnapshotTrailer(sum.Trailer())
+			require.NoError(t, err)
+			sb.Write(append(trailer, '\n'))
+		}
+		fn := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fn, []byte(sb.String()), 0600))
+		return fn
+	}
+	header := func(namespaces ...*ketoapi.SnapshotNamespace) *ketoapi.SnapshotHeader {
+		return &ketoapi.SnapshotHeader{Version: ketoapi.SnapshotFormatVersion, Namespaces: namespaces}
+	}
+
+	t.Run("case=snapshot file and server", func(t *testing.T) {
+		fn := write(t, "snapshot.ndjson", header(), alice, bob)
+
+		stdOut, stdErr, err := cmdx.ExecCtx(ctx, newDiffCmd(), nil, fn, server, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, "1 relation tuples added, 1 removed.\n", stdErr)
+
+		var res result
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &res))
+		assert.Equal(t, []*ketoapi.RelationTuple{carol}, res.Added)
+		assert.Equal(t, []*ketoapi.RelationTuple{alice}, res.Removed)
+		assert.Nil(t, res.Namespaces)
+	})
+
+	t.Run("case=truncated snapshot file", func(t *testing.T) {
+		fn := write(t, "truncated.ndjson", header(), alice, bob)
+		content, err := os.ReadFile(fn)
+		require.NoError(t, err)
+		lines := strings.SplitAfter(string(content), "\n")
+		require.NoError(t, os.WriteFile(fn, []byte(strings.Join(lines[:2], "")), 0600))
+
+		_, stdErr, err := cmdx.ExecCtx(ctx, newDiffCmd(), nil, fn, server)
+		require.Error(t, err)
+		assert.Contains(t, stdErr, "the snapshot ends without a trailer")
+	})
+
+	t.Run("case=no differences", func(t *testing.T) {
+		stdOut, stdErr, err := cmdx.ExecCtx(ctx, newDiffCmd(), strings.NewReader(`{"namespace":"files","object":"b","relation":"viewer","subject_id":"bob"}
+{"namespace":"groups","object":"dev","relation":"member","subject_set":{"namespace":"groups","object":"ops","relation":"member"}}
+`), "-", server)
+		require.NoError(t, err, stdErr)
+		assert.NotContains(t, stdOut, "relation tuple\t")
+		assert.Equal(t, "0 relation tuples added, 0 removed.\n", stdErr)
+	})
+
+	t.Run("case=namespaces", func(t *testing.T) {
+		fn := write(t, "namespaces.ndjson", header(
+			&ketoapi.SnapshotNamespace{Name: "files"},
+			&ketoapi.SnapshotNamespace{Name: "old"},
+		), bob, carol)
+		changed := write(t, "changed.ndjson", header(
+			&ketoapi.SnapshotNamespace{Name: "files", Config: json.RawMessage(`{"max_depth": 3}`)},
+			&ketoapi.SnapshotNamespace{Name: "old"},
+		), bob, carol)
+
+		stdOut, stdErr, err := cmdx.ExecCtx(ctx, newDiffCmd(), nil, fn, server, "--"+FlagIncludeNamespaces, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdErr, "1 namespaces added, 1 removed, 0 changed.\n")
+		var res result
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &res))
+		assert.Equal(t, &namespaceChanges{Added: []string{"groups"}, Removed: []string{"old"}, Changed: []string{}}, res.Namespaces)
+		assert.Empty(t, res.Added)
+		assert.Empty(t, res.Removed)
+
+		stdOut, _, err = cmdx.ExecCtx(ctx, newDiffCmd(), nil, fn, changed, "--"+FlagIncludeNamespaces)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Regexp(t, `changed\s+namespace\s+files`, stdOut)
+	})
+
+	t.Run("case=snapshot without namespaces", func(t *testing.T) {
+		fn := write(t, "tuples.ndjson", bob, carol)
+
+		_, stdErr, err := cmdx.ExecCtx(ctx, newDiffCmd(), nil, fn, server, "--"+FlagIncludeNamespaces)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, "The snapshot "+fn+" does not include the namespaces, export it with --include-namespaces.\n", stdErr)
+	})
+}
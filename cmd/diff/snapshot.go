@@ -0,0 +1,132 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+// snapshot is the state of one side of the comparison.
+type snapshot struct {
+	// tuples are the relation tuples by their string representation.
+	tuples map[string]*ketoapi.RelationTuple
+	// namespaces are the configurations by namespace name. It is nil if the
+	// snapshot does not include the namespaces.
+	namespaces map[string]json.RawMessage
+}
+
+// readSnapshot reads the snapshot of a file, of STD_IN with -, or of the
+// server at the address if there is no such file.
+func readSnapshot(cmd *cobra.Command, source string, includeNamespaces bool) (*snapshot, error) {
+	if source == "-" {
+		return decodeSnapshot(cmd.InOrStdin())
+	}
+	if info, err := os.Stat(source); err == nil && !info.IsDir() {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("could not open file %s: %w", source, err)
+		}
+		defer f.Close()
+		s, err := decodeSnapshot(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode file %s: %w", source, err)
+		}
+		return s, nil
+	}
+
+	u := client.RemoteURL(source)
+	u.Path = relationtuple.SnapshotRoute
+	u.RawQuery = url.Values{"include_namespaces": {strconv.FormatBool(includeNamespaces)}}.Encode()
+	req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a file, and could not export the snapshot of the server: %w", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not export the snapshot of %s: %w", source, client.ErrorFromResponse(resp))
+	}
+
+	s, err := decodeSnapshot(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the snapshot of %s: %w", source, err)
+	}
+	if includeNamespaces && s.namespaces == nil {
+		// The server has no namespaces, they are left out of the header.
+		s.namespaces = map[string]json.RawMessage{}
+	}
+	return s, nil
+}
+
+// decodeSnapshot decodes a snapshot of `keto relation-tuple export`, or
+// relation tuples as NDJSON without the snapshot header. Snapshots with a
+// header have to end with a matching trailer.
+func decodeSnapshot(r io.Reader) (*snapshot, error) {
+	s := &snapshot{tuples: make(map[string]*ketoapi.RelationTuple)}
+	dec := json.NewDecoder(r)
+	var sum *ketoapi.SnapshotChecksum
+	for first := true; ; first = false {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			if sum != nil {
+				return nil, ketoapi.ErrSnapshotTruncated
+			}
+			return s, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if sum != nil {
+			if trailer, ok := ketoapi.DecodeSnapshotTrailer(raw); ok {
+				if err := sum.Verify(trailer); err != nil {
+					return nil, err
+				}
+				return s, nil
+			}
+			sum.Add(raw)
+		}
+
+		if first {
+			var probe struct {
+				Version *int `json:"version"`
+			}
+			if err := json.Unmarshal(raw, &probe); err == nil && probe.Version != nil {
+				var header ketoapi.SnapshotHeader
+				if err := json.Unmarshal(raw, &header); err != nil {
+					return nil, fmt.Errorf("could not decode the snapshot header: %w", err)
+				}
+				if header.Version != ketoapi.SnapshotFormatVersion {
+					return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", header.Version, ketoapi.SnapshotFormatVersion)
+				}
+				sum = ketoapi.NewSnapshotChecksum()
+				if header.Namespaces != nil {
+					s.namespaces = make(map[string]json.RawMessage, len(header.Namespaces))
+					for _, n := range header.Namespaces {
+						s.namespaces[n.Name] = n.Config
+					}
+				}
+				continue
+			}
+		}
+
+		var t ketoapi.RelationTuple
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, fmt.Errorf("could not decode relation tuple %s: %w", raw, err)
+		}
+		s.tuples[t.String()] = &t
+	}
+}
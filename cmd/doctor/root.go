@@ -0,0 +1,191 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/ketoctx"
+)
+
+// ANSI escape sequences of the report colors.
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorGray   = "\x1b[90m"
+)
+
+type report struct {
+	checks []driver.DoctorCheck
+	color  bool
+}
+
+func newDoctorCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the configuration and the database",
+		Long: `Diagnose common problems of an Ory Keto deployment, and explain how to fix them.
+The following is checked:
+
+  config       the configuration files are valid
+  database     the database is reachable, and how long a query takes
+  migrations   no migrations are pending and the databases are not dirty
+  namespaces   the namespaces can be loaded
+  indexes      the relation tuple table has all indexes
+  clock skew   the clocks of this host and the database server agree
+
+Run the command with the same configuration and environment as the server.
+It exits with a non-zero code if any check failed, warnings do not fail it.`,
+		Example: `keto doctor -c keto.yml
+keto doctor -c keto.yml --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			checks := []driver.DoctorCheck{checkConfig(cmd)}
+			if reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), true, opts...); err != nil {
+				checks = append(checks, driver.DoctorCheck{
+					Name:    "initialization",
+					Status:  driver.DoctorStatusFailed,
+					Message: err.Error(),
+					Hint:    "Fix the configuration, the other checks run once Ory Keto can be initialized.",
+				})
+			} else {
+				checks = append(checks, reg.Doctor(ctx)...)
+			}
+
+			cmdx.PrintJSONAble(cmd, &report{checks: checks, color: useColor(cmd)})
+			for _, c := range checks {
+				if c.Status == driver.DoctorStatusFailed {
+					return cmdx.FailSilently(cmd)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmdx.RegisterJSONFormatFlags(cmd.Flags())
+
+	return cmd
+}
+
+// checkConfig validates the configuration files of the --config flag. The
+// default file is only validated if it exists.
+func checkConfig(cmd *cobra.Command) driver.DoctorCheck {
+	const name = "config"
+
+	files, err := cmd.Flags().GetStringSlice(configx.FlagConfig)
+	if err != nil {
+		return driver.DoctorCheck{Name: name, Status: driver.DoctorStatusFailed, Message: err.Error()}
+	}
+	if !cmd.Flags().Changed(configx.FlagConfig) {
+		existing := files[:0:0]
+		for _, fn := range files {
+			if _, err := os.Stat(fn); err == nil {
+				existing = append(existing, fn)
+			}
+		}
+		files = existing
+	}
+	if len(files) == 0 {
+		return driver.DoctorCheck{
+			Name:    name,
+			Status:  driver.DoctorStatusSkipped,
+			Message: "Skipped because no configuration file was given, only environment variables are used.",
+		}
+	}
+
+	names := strings.Join(files, ", ")
+	problems, err := config.ValidateFiles(cmd.Context(), files...)
+	if err != nil {
+		return driver.DoctorCheck{
+			Name:    name,
+			Status:  driver.DoctorStatusFailed,
+			Message: fmt.Sprintf("Could not load %s: %s", names, err),
+		}
+	}
+	if len(problems) > 0 {
+		pp := make([]string, len(problems))
+		for i, p := range problems {
+			pp[i] = p.Message
+			if p.Key != "" {
+				pp[i] = p.Key + ": " + p.Message
+			}
+		}
+		return driver.DoctorCheck{
+			Name:    name,
+			Status:  driver.DoctorStatusFailed,
+			Message: fmt.Sprintf("Found %d problem(s) in %s: %s", len(problems), names, strings.Join(pp, "; ")),
+			Hint:    fmt.Sprintf("Run `keto validate config %s` to see how to fix them.", strings.Join(files, " ")),
+		}
+	}
+	return driver.DoctorCheck{
+		Name:    name,
+		Status:  driver.DoctorStatusOK,
+		Message: fmt.Sprintf("The configuration in %s is valid.", names),
+	}
+}
+
+// useColor reports whether the output is a terminal, and colors are not
+// disabled with NO_COLOR, see https://no-color.org.
+func useColor(cmd *cobra.Command) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := cmd.OutOrStdout().(*os.File)
+	return ok && isatty.IsTerminal(f.Fd())
+}
+
+func (r *report) label(s driver.DoctorStatus) string {
+	label, color := "[ ok ]", colorGreen
+	switch s {
+	case driver.DoctorStatusWarning:
+		label, color = "[warn]", colorYellow
+	case driver.DoctorStatusFailed:
+		label, color = "[fail]", colorRed
+	case driver.DoctorStatusSkipped:
+		label, color = "[skip]", colorGray
+	}
+	if !r.color {
+		return label
+	}
+	return color + label + colorReset
+}
+
+func (r *report) String() string {
+	var (
+		b      strings.Builder
+		counts = make(map[driver.DoctorStatus]int)
+	)
+	for _, c := range r.checks {
+		counts[c.Status]++
+		b.WriteString(fmt.Sprintf("%s %-14s %s\n", r.label(c.Status), c.Name, c.Message))
+		if c.Hint != "" {
+			b.WriteString(fmt.Sprintf("%s %-14s Hint: %s\n", strings.Repeat(" ", 6), "", c.Hint))
+		}
+	}
+
+	b.WriteString(fmt.Sprintf("\n%d ok, %d warning(s), %d failed, %d skipped\n",
+		counts[driver.DoctorStatusOK], counts[driver.DoctorStatusWarning], counts[driver.DoctorStatusFailed], counts[driver.DoctorStatusSkipped]))
+	return b.String()
+}
+
+// MarshalJSON encodes only the checks. The YAML format is converted from
+// JSON, so it is covered as well.
+func (r *report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.checks)
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command, opts []ketoctx.Option) {
+	parent.AddCommand(newDoctorCmd(opts))
+}
@@ -0,0 +1,107 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestDoctor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	// The file name is derived from the test name, so it has to be created
+	// outside of the subtests.
+	unmigrated := dbx.GetSqlite(t, dbx.SQLiteFile).Conn
+
+	newCmd := func(cf string) *cmdx.CommandExecuter {
+		return &cmdx.CommandExecuter{
+			New: func() *cobra.Command {
+				cmd := newDoctorCmd(nil)
+				configx.RegisterFlags(cmd.PersistentFlags())
+				return cmd
+			},
+			Ctx:            ctx,
+			PersistentArgs: []string{"-c", cf},
+		}
+	}
+	statuses := func(t *testing.T, out string) map[string]driver.DoctorStatus {
+		var checks []driver.DoctorCheck
+		require.NoError(t, json.Unmarshal([]byte(out), &checks), out)
+		res := make(map[string]driver.DoctorStatus, len(checks))
+		for _, c := range checks {
+			res[c.Name] = c.Status
+		}
+		return res
+	}
+
+	t.Run("case=healthy deployment", func(t *testing.T) {
+		cf := dbx.ConfigFile(t, map[string]interface{}{
+			config.KeyDSN:        "memory",
+			config.KeyNamespaces: []*namespace.Namespace{{Name: "files"}},
+		})
+		cmd := newCmd(cf)
+
+		out := cmd.ExecNoErr(t)
+		assert.Contains(t, out, "[ ok ] config")
+		assert.Contains(t, out, "[ ok ] indexes")
+		assert.Contains(t, out, "[skip] clock skew")
+		assert.Contains(t, out, "5 ok, 0 warning(s), 0 failed, 1 skipped")
+		assert.NotContains(t, out, "\x1b[", "colors are only used on terminals")
+
+		assert.Equal(t, map[string]driver.DoctorStatus{
+			"config":     driver.DoctorStatusOK,
+			"database":   driver.DoctorStatusOK,
+			"migrations": driver.DoctorStatusOK,
+			"namespaces": driver.DoctorStatusOK,
+			"indexes":    driver.DoctorStatusOK,
+			"clock skew": driver.DoctorStatusSkipped,
+		}, statuses(t, cmd.ExecNoErr(t, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))))
+	})
+
+	t.Run("case=pending migrations and no namespaces", func(t *testing.T) {
+		cf := dbx.ConfigFile(t, map[string]interface{}{
+			config.KeyDSN:        unmigrated,
+			config.KeyNamespaces: []*namespace.Namespace{},
+		})
+		cmd := newCmd(cf)
+
+		stdout, _, err := cmd.Exec(nil, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, map[string]driver.DoctorStatus{
+			"config":     driver.DoctorStatusOK,
+			"database":   driver.DoctorStatusOK,
+			"migrations": driver.DoctorStatusFailed,
+			"namespaces": driver.DoctorStatusWarning,
+			"indexes":    driver.DoctorStatusSkipped,
+			"clock skew": driver.DoctorStatusSkipped,
+		}, statuses(t, stdout))
+
+		stdout, _, err = cmd.Exec(nil)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdout, "Hint: Run `keto migrate up`.")
+	})
+
+	t.Run("case=invalid config", func(t *testing.T) {
+		cf := dbx.ConfigFile(t, map[string]interface{}{
+			config.KeyDSN:        "memory",
+			config.KeyNamespaces: []*namespace.Namespace{{Name: "files"}, {Name: "files"}},
+		})
+		cmd := newCmd(cf)
+
+		stdout, _, err := cmd.Exec(nil)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdout, "[fail] config")
+		assert.Contains(t, stdout, "keto validate config")
+	})
+}
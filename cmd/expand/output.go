@@ -0,0 +1,106 @@
+package expand
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	FormatTree = "tree"
+	FormatDOT  = "dot"
+)
+
+type (
+	expandTree = ketoapi.Tree[*ketoapi.RelationTuple]
+	subjects   []string
+)
+
+func (s subjects) String() string {
+	return strings.Join(s, "\n")
+}
+
+// leafSubjects returns the sorted and deduplicated subjects of all leaves of
+// the tree.
+func leafSubjects(t *expandTree) subjects {
+	seen := make(map[string]struct{})
+	var walk func(t *expandTree)
+	walk = func(t *expandTree) {
+		if t == nil {
+			return
+		}
+		if t.Type == ketoapi.TreeNodeLeaf && t.Tuple != nil {
+			seen[subjectString(t.Tuple)] = struct{}{}
+		}
+		for _, c := range t.Children {
+			walk(c)
+		}
+	}
+	walk(t)
+
+	res := make(subjects, 0, len(seen))
+	for s := range seen {
+		res = append(res, s)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func subjectString(t *ketoapi.RelationTuple) string {
+	if t.SubjectID != nil {
+		return *t.SubjectID
+	}
+	if t.SubjectSet != nil {
+		return t.SubjectSet.String()
+	}
+	return (&ketoapi.SubjectSet{Namespace: t.Namespace, Object: t.Object, Relation: t.Relation}).String()
+}
+
+func nodeLabel(t *expandTree) string {
+	if t.Tuple == nil {
+		return string(t.Type)
+	}
+	if t.Type == ketoapi.TreeNodeLeaf || t.Type == ketoapi.TreeNodeUnion {
+		return subjectString(t.Tuple)
+	}
+	return fmt.Sprintf("%s\n%s", t.Type, subjectString(t.Tuple))
+}
+
+// writeDOT renders the tree as a Graphviz digraph. Subject sets are drawn as
+// boxes and subjects as ellipses. If leavesOnly is set, the leaves are
+// connected directly to the root.
+func writeDOT(w io.Writer, t *expandTree, leavesOnly bool) error {
+	var b strings.Builder
+	b.WriteString("digraph expand {\n")
+	if t != nil {
+		b.WriteString(fmt.Sprintf("  n0 [label=%s, shape=box];\n", strconv.Quote(nodeLabel(t))))
+		if leavesOnly {
+			for i, s := range leafSubjects(t) {
+				b.WriteString(fmt.Sprintf("  n%d [label=%s];\n  n0 -> n%d;\n", i+1, strconv.Quote(s), i+1))
+			}
+		} else {
+			id := 0
+			var walk func(parent int, t *expandTree)
+			walk = func(parent int, t *expandTree) {
+				for _, c := range t.Children {
+					id++
+					shape := "box"
+					if c.Type == ketoapi.TreeNodeLeaf {
+						shape = "ellipse"
+					}
+					b.WriteString(fmt.Sprintf("  n%d [label=%s, shape=%s];\n  n%d -> n%d;\n", id, strconv.Quote(nodeLabel(c)), shape, parent, id))
+					walk(id, c)
+				}
+			}
+			walk(0, t)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
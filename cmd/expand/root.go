@@ -15,14 +15,24 @@ import (
 	"github.com/ory/keto/cmd/client"
 )
 
-const FlagMaxDepth = "max-depth"
+const (
+	FlagMaxDepth   = "max-depth"
+	FlagLeavesOnly = "leaves-only"
+)
 
 func NewExpandCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "expand <relation> <namespace> <object>",
 		Short: "Expand a subject set",
-		Long:  "Expand a subject set into a tree of subjects.",
-		Args:  cobra.ExactArgs(3),
+		Long: "Expand a subject set into a tree of subjects.\n" +
+			"The tree can be printed as text, as JSON, or as a Graphviz DOT graph. " +
+			"With --leaves-only, only the subjects at the leaves of the tree are printed.",
+		Example: `keto expand view files /photos/beach.jpg --format tree
+keto expand view files /photos/beach.jpg --format json-pretty --max-depth 3
+keto expand view files /photos/beach.jpg --format dot | dot -Tsvg > tree.svg
+keto expand view files /photos/beach.jpg --leaves-only`,
+		Args:              cobra.ExactArgs(3),
+		ValidArgsFunction: client.CompletePositional(client.CompleteRelations(nil), client.CompleteNamespaces),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			conn, err := client.GetReadConn(cmd)
 			if err != nil {
@@ -50,9 +60,18 @@ func NewExpandCmd() *cobra.Command {
 				tree = ketoapi.TreeFromProto[*ketoapi.RelationTuple](resp.Tree)
 			}
 
-			cmdx.PrintJSONAble(cmd, tree)
-			switch flagx.MustGetString(cmd, cmdx.FlagFormat) {
-			case string(cmdx.FormatDefault), "":
+			format := flagx.MustGetString(cmd, cmdx.FlagFormat)
+			leavesOnly := flagx.MustGetBool(cmd, FlagLeavesOnly)
+			switch {
+			case format == FormatDOT:
+				return writeDOT(cmd.OutOrStdout(), tree, leavesOnly)
+			case leavesOnly && tree != nil:
+				cmdx.PrintJSONAble(cmd, leafSubjects(tree))
+			default:
+				cmdx.PrintJSONAble(cmd, tree)
+			}
+			switch format {
+			case string(cmdx.FormatDefault), FormatTree, "":
 				if tree == nil && !flagx.MustGetBool(cmd, cmdx.FlagQuiet) {
 					_, _ = fmt.Fprint(cmd.OutOrStdout(), "Got an empty tree. This probably means that the requested relation tuple is not present in Keto.")
 				}
@@ -64,8 +83,10 @@ func NewExpandCmd() *cobra.Command {
 
 	client.RegisterRemoteURLFlags(cmd.Flags())
 	cmdx.RegisterJSONFormatFlags(cmd.Flags())
+	cmd.Flags().Lookup(cmdx.FlagFormat).Usage = fmt.Sprintf("Set the output format. One of %s, %s, %s, %s, %s, and %s.", cmdx.FormatDefault, FormatTree, cmdx.FormatJSON, cmdx.FormatJSONPretty, cmdx.FormatYAML, FormatDOT)
 	cmdx.RegisterNoiseFlags(cmd.Flags())
 	cmd.Flags().Int32P(FlagMaxDepth, "d", 0, "Maximum depth of the tree to be returned. If the value is less than 1 or greater than the global max-depth then the global max-depth will be used instead.")
+	cmd.Flags().Bool(FlagLeavesOnly, false, "Only print the subjects at the leaves of the tree.")
 
 	return cmd
 }
@@ -1,13 +1,20 @@
 package expand
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/ory/x/cmdx"
+	"github.com/ory/x/pointerx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/driver"
 	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
 )
 
 func TestExpandCommand(t *testing.T) {
@@ -30,4 +37,54 @@ func TestExpandCommand(t *testing.T) {
 			assert.Contains(t, stdOut, "empty tree")
 		})
 	})
+
+	t.Run("case=known tuple", func(t *testing.T) {
+		relationtuple.MapAndWriteTuples(t, ts.Reg.(*driver.RegistryDefault),
+			&ketoapi.RelationTuple{Namespace: nspace.Name, Object: "doc", Relation: "view", SubjectID: pointerx.String("alice")},
+			&ketoapi.RelationTuple{Namespace: nspace.Name, Object: "doc", Relation: "view", SubjectSet: &ketoapi.SubjectSet{Namespace: nspace.Name, Object: "group", Relation: "member"}},
+			&ketoapi.RelationTuple{Namespace: nspace.Name, Object: "group", Relation: "member", SubjectID: pointerx.String("bob")},
+			&ketoapi.RelationTuple{Namespace: nspace.Name, Object: "group", Relation: "member", SubjectID: pointerx.String("alice")},
+		)
+		expand := func(t *testing.T, args ...string) string {
+			return ts.Cmd.ExecNoErr(t, append([]string{"view", nspace.Name, "doc"}, args...)...)
+		}
+
+		t.Run("format=tree", func(t *testing.T) {
+			stdOut := expand(t, "--"+cmdx.FlagFormat, FormatTree)
+			assert.Contains(t, stdOut, "@("+nspace.Name+":group#member)")
+			assert.Contains(t, stdOut, "∋ :#@bob")
+			assert.NotContains(t, stdOut, "empty tree")
+		})
+
+		t.Run("format=JSON", func(t *testing.T) {
+			var tree ketoapi.Tree[*ketoapi.RelationTuple]
+			require.NoError(t, json.Unmarshal([]byte(expand(t, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))), &tree))
+			assert.Equal(t, ketoapi.TreeNodeUnion, tree.Type)
+			assert.Len(t, tree.Children, 2)
+		})
+
+		t.Run("format=DOT", func(t *testing.T) {
+			stdOut := expand(t, "--"+cmdx.FlagFormat, FormatDOT)
+			assert.True(t, strings.HasPrefix(stdOut, "digraph expand {\n"), stdOut)
+			assert.Contains(t, stdOut, `n0 [label="`+nspace.Name+`:doc#view", shape=box];`)
+			assert.Contains(t, stdOut, `[label="bob", shape=ellipse];`)
+			assert.Equal(t, 4, strings.Count(stdOut, "->"), stdOut)
+		})
+
+		t.Run("format=DOT with max depth", func(t *testing.T) {
+			stdOut := expand(t, "--"+cmdx.FlagFormat, FormatDOT, "--"+FlagMaxDepth, "1")
+			assert.NotContains(t, stdOut, "bob")
+		})
+
+		t.Run("case=leaves only", func(t *testing.T) {
+			assert.Equal(t, "alice\nbob\n", expand(t, "--"+FlagLeavesOnly))
+
+			var leaves []string
+			require.NoError(t, json.Unmarshal([]byte(expand(t, "--"+FlagLeavesOnly, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))), &leaves))
+			assert.Equal(t, []string{"alice", "bob"}, leaves)
+
+			stdOut := expand(t, "--"+FlagLeavesOnly, "--"+cmdx.FlagFormat, FormatDOT)
+			assert.Equal(t, 2, strings.Count(stdOut, "->"), stdOut)
+		})
+	})
 }
@@ -0,0 +1,144 @@
+package fixtures
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/ketodataset"
+)
+
+const (
+	FlagObjects       = "objects"
+	FlagUsers         = "users"
+	FlagGroups        = "groups"
+	FlagDepth         = "depth"
+	FlagFanOut        = "fan-out"
+	FlagWildcardRatio = "wildcard-ratio"
+	FlagSeed          = "seed"
+	FlagAssertions    = "assertions"
+	FlagOPL           = "opl"
+)
+
+func newGenerateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a synthetic dataset and the expected check results",
+		Long: `Generate a synthetic dataset of documents, nested groups, and users, and print its relation tuples as NDJSON.
+
+Every group has --fan-out users as members. Groups up to --depth levels below the --groups top-level groups also have --fan-out nested groups as members.
+Every document has --fan-out viewers, each one either a user or the members of a group.
+Keto has no wildcard subjects, so the documents that are visible to everyone (--wildcard-ratio) have the members of the group "everyone" as viewers, which contains all users.
+
+With --assertions, one allowed and one denied check per document are written in the format of ` + "`keto check --batch`" + `. With --opl, the namespaces of the dataset are written as an Ory Permission Language file.
+The same flags and --seed always result in the same fixtures, so that performance tests are reproducible.`,
+		Example: `keto fixtures generate --objects 1000 --depth 3 --opl namespaces.keto.ts --assertions assertions.txt > tuples.ndjson
+keto relation-tuple create tuples.ndjson && keto check --batch assertions.txt
+keto simulate --opl namespaces.keto.ts --tuples tuples.ndjson --assertions assertions.txt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := parseFlags(cmd)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			d, err := ketodataset.Generate(*c)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			if fn, _ := cmd.Flags().GetString(FlagOPL); fn != "" {
+				if err := os.WriteFile(fn, []byte(d.OPL()), 0600); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not write file %s: %v\n", fn, err)
+					return cmdx.FailSilently(cmd)
+				}
+			}
+			if fn, _ := cmd.Flags().GetString(FlagAssertions); fn != "" {
+				if err := writeAssertions(fn, d.Checks); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not write file %s: %v\n", fn, err)
+					return cmdx.FailSilently(cmd)
+				}
+			}
+
+			w := bufio.NewWriter(cmd.OutOrStdout())
+			enc := json.NewEncoder(w)
+			for _, t := range d.Tuples {
+				if err := enc.Encode(t); err != nil {
+					return err
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Generated %d relation tuples and %d assertions.\n", len(d.Tuples), len(d.Checks))
+			return nil
+		},
+	}
+
+	cmd.Flags().Int(FlagObjects, 100, "The number of documents.")
+	cmd.Flags().Int(FlagUsers, 100, "The number of users the members and viewers are chosen from.")
+	cmd.Flags().Int(FlagGroups, 10, "The number of top-level groups.")
+	cmd.Flags().Int(FlagDepth, 2, "The number of levels of nested groups below every top-level group.")
+	cmd.Flags().Int(FlagFanOut, 3, "The number of users and nested groups of every group, and of viewers of every document.")
+	cmd.Flags().Float64(FlagWildcardRatio, 0.1, "The share of documents that are visible to everyone, between 0 and 1.")
+	cmd.Flags().Int64(FlagSeed, 1, "The seed of the dataset.")
+	cmd.Flags().String(FlagAssertions, "", "Write the expected check results to this file.")
+	cmd.Flags().String(FlagOPL, "", "Write the Ory Permission Language file of the dataset to this file.")
+
+	return cmd
+}
+
+func parseFlags(cmd *cobra.Command) (*ketodataset.Config, error) {
+	f := cmd.Flags()
+	c := &ketodataset.Config{}
+	c.Documents, _ = f.GetInt(FlagObjects)
+	c.Users, _ = f.GetInt(FlagUsers)
+	c.Groups, _ = f.GetInt(FlagGroups)
+	c.Depth, _ = f.GetInt(FlagDepth)
+	c.FanOut, _ = f.GetInt(FlagFanOut)
+	c.WildcardRatio, _ = f.GetFloat64(FlagWildcardRatio)
+	c.Seed, _ = f.GetInt64(FlagSeed)
+
+	switch {
+	case c.Documents < 1:
+		return nil, fmt.Errorf("--%s must be at least 1", FlagObjects)
+	case c.Users < 1:
+		return nil, fmt.Errorf("--%s must be at least 1", FlagUsers)
+	case c.Groups < 0:
+		return nil, fmt.Errorf("--%s must not be negative", FlagGroups)
+	case c.Depth < 0:
+		return nil, fmt.Errorf("--%s must not be negative", FlagDepth)
+	case c.FanOut < 1:
+		return nil, fmt.Errorf("--%s must be at least 1", FlagFanOut)
+	case c.WildcardRatio < 0 || c.WildcardRatio > 1:
+		return nil, fmt.Errorf("--%s must be between 0 and 1", FlagWildcardRatio)
+	}
+	return c, nil
+}
+
+func writeAssertions(fn string, checks []*ketodataset.Check) (err error) {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	for _, c := range checks {
+		if !c.Allowed {
+			_, _ = io.WriteString(w, "!")
+		}
+		_, _ = fmt.Fprintln(w, c.Tuple)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,72 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/simulate"
+	"github.com/ory/keto/ketodataset"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	generate := func(t *testing.T, args ...string) (tuples string, assertions string) {
+		fn := filepath.Join(t.TempDir(), "assertions.txt")
+		stdOut, stdErr, err := cmdx.ExecCtx(ctx, newGenerateCmd(), nil, append(args, "--"+FlagAssertions, fn)...)
+		require.NoError(t, err, stdErr)
+		raw, err := os.ReadFile(fn)
+		require.NoError(t, err)
+		return stdOut, string(raw)
+	}
+
+	t.Run("case=dataset size", func(t *testing.T) {
+		tuples, assertions := generate(t, "--"+FlagObjects, "3", "--"+FlagGroups, "2", "--"+FlagDepth, "1", "--"+FlagFanOut, "2", "--"+FlagWildcardRatio, "0")
+		// Every top-level group has 2 users and 2 nested groups, which have
+		// 2 users each. Every document has 2 viewers.
+		assert.Len(t, strings.Split(strings.TrimSpace(tuples), "\n"), 2*(4+2*2)+3*2)
+		assert.Len(t, strings.Split(strings.TrimSpace(assertions), "\n"), 3*2)
+		assert.NotContains(t, tuples, ketodataset.Everyone)
+	})
+
+	t.Run("case=same seed results in the same fixtures", func(t *testing.T) {
+		tuples, assertions := generate(t, "--"+FlagSeed, "42")
+		again, againAssertions := generate(t, "--"+FlagSeed, "42")
+		assert.Equal(t, tuples, again)
+		assert.Equal(t, assertions, againAssertions)
+
+		other, _ := generate(t, "--"+FlagSeed, "43")
+		assert.NotEqual(t, tuples, other)
+	})
+
+	t.Run("case=assertions hold", func(t *testing.T) {
+		dir := t.TempDir()
+		opl, tuplesFile, assertions := filepath.Join(dir, "namespaces.keto.ts"), filepath.Join(dir, "tuples.ndjson"), filepath.Join(dir, "assertions.txt")
+		tuples, _, err := cmdx.ExecCtx(ctx, newGenerateCmd(), nil,
+			"--"+FlagObjects, "20", "--"+FlagUsers, "30", "--"+FlagGroups, "3", "--"+FlagWildcardRatio, "0.3",
+			"--"+FlagOPL, opl, "--"+FlagAssertions, assertions)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(tuplesFile, []byte(tuples), 0600))
+
+		root := &cobra.Command{Use: "keto"}
+		simulate.RegisterCommandsRecursive(root, nil)
+		_, stdErr, err := cmdx.ExecCtx(ctx, root, nil, "simulate", "--"+simulate.FlagOPL, opl, "--"+simulate.FlagTuples, tuplesFile, "--"+simulate.FlagAssertions, assertions)
+		require.NoError(t, err, stdErr)
+		assert.Contains(t, stdErr, "0 failed, 0 errors")
+	})
+
+	t.Run("case=invalid flags", func(t *testing.T) {
+		_, stdErr, err := cmdx.ExecCtx(ctx, newGenerateCmd(), nil, "--"+FlagWildcardRatio, "2")
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, "--wildcard-ratio must be between 0 and 1\n", stdErr)
+	})
+}
@@ -0,0 +1,20 @@
+package fixtures
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newFixturesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "fixtures",
+		Short: "Generate relation tuple fixtures",
+	}
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command) {
+	fixturesCmd := newFixturesCmd()
+
+	parent.AddCommand(fixturesCmd)
+
+	fixturesCmd.AddCommand(newGenerateCmd())
+}
@@ -0,0 +1,45 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/ketoctx"
+)
+
+func newClosureCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "closure",
+		Short: "Rebuild the membership closures",
+		Long: `Recompute the materialized transitive closures of the relations that are configured with "closure" in the namespace configuration.
+
+Writes keep the closures up to date, but they have to be rebuilt after relations were added to or removed from the configuration.
+The closures are rebuilt in one transaction, which blocks writes until it completes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), false, opts...)
+			if err != nil {
+				return err
+			}
+			cm := reg.RelationTupleClosureManager()
+			if cm == nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "The relation tuples are stored without closures. They are not supported with sharding, namespace storage, or other stores than SQL databases.")
+				return cmdx.FailSilently(cmd)
+			}
+
+			n, err := cm.RebuildClosure(ctx)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not rebuild the closures: %+v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Rebuilt the closures with %d entries.\n", n)
+			return nil
+		},
+	}
+	return cmd
+}
@@ -18,7 +18,8 @@ func newDownCmd(opts []ketoctx.Option) *cobra.Command {
 		Use:   "down <steps>",
 		Short: "Migrate the database down",
 		Long: "Migrate the database down a specific amount of steps.\n" +
-			"Pass 0 steps to fully migrate down.",
+			"Pass 0 steps to fully migrate down. The migrations that will be rolled back are listed before asking for confirmation, " +
+			"use `keto migrate plan --down <steps>` to review their SQL.",
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			steps, err := strconv.ParseInt(args[0], 0, 0)
@@ -55,13 +56,25 @@ func BoxDown(cmd *cobra.Command, mb *popx.MigrationBox, steps int) error {
 	}
 	cmdx.PrintTable(cmd, s)
 
+	plan, err := planDown(cmd.Context(), mb, steps)
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not plan the migrations: %+v\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+	if len(plan.Migrations) == 0 {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No migrations are applied, there is nothing to do.")
+		return nil
+	}
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), "The following migrations will be rolled back:")
+	printPlan(cmd, plan)
+
 	if !flagx.MustGetBool(cmd, FlagYes) && !cmdx.AskForConfirmation("Do you really want to migrate down? This will delete data.", cmd.InOrStdin(), cmd.OutOrStdout()) {
 		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Migration aborted.")
 		return nil
 	}
 
 	if err := mb.Down(cmd.Context(), steps); err != nil {
-		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could apply down migrations: %+v\n", err)
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not apply down migrations: %+v\n", err)
 		return cmdx.FailSilently(cmd)
 	}
 
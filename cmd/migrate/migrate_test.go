@@ -3,10 +3,13 @@ package migrate
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/ory/x/dbal"
+	"github.com/ory/x/popx"
 
 	"github.com/ory/keto/internal/x/dbx"
 
@@ -77,6 +80,20 @@ func TestMigrate(t *testing.T) {
 
 					out := cmd.ExecNoErr(t, "up", "--"+FlagYes)
 					assert.Contains(t, out, "All migrations are already applied, there is nothing to do.")
+
+					out = cmd.ExecNoErr(t, "up", "--"+FlagYes, "--"+FlagPhase, string(driver.MigrationPhaseExpand))
+					assert.Contains(t, out, "All migrations are already applied, there is nothing to do.")
+					out = cmd.ExecNoErr(t, "up", "--"+FlagYes, "--"+FlagPhase, string(driver.MigrationPhaseContract))
+					assert.Contains(t, out, "There are no migrations in this phase, there is nothing to do.")
+				})
+
+				t.Run("case=maintenance commands initialize the persister", func(t *testing.T) {
+					cmd := newCmd(context.Background(), "-c", dbx.ConfigFile(t, map[string]interface{}{
+						config.KeyDSN:        dsn.Conn,
+						config.KeyNamespaces: nspaces,
+					}))
+					assert.Contains(t, cmd.ExecNoErr(t, "closure"), "Rebuilt the closures with 0 entries.")
+					assert.Contains(t, cmd.ExecNoErr(t, "reencrypt"), "Updated 0 identifiers.")
 				})
 			})
 		} else {
@@ -120,6 +137,52 @@ func TestMigrate(t *testing.T) {
 					assertAllApplied(t, parts[1])
 				})
 
+				t.Run("case=plans and migrates to a version", func(t *testing.T) {
+					t.Cleanup(func() {
+						t.Logf("cleanup:\n%s\n", cmd.ExecNoErr(t, "down", "0", "--"+FlagYes))
+					})
+					status := func(t *testing.T) (s popx.MigrationStatuses) {
+						require.NoError(t, json.Unmarshal([]byte(cmd.ExecNoErr(t, "status", "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))), &s))
+						return s
+					}
+					initial := status(t)
+					require.Greater(t, len(initial), 4)
+					target, earlier := initial[len(initial)/2], initial[1]
+
+					plan := cmd.ExecNoErr(t, "plan")
+					assert.Contains(t, plan, fmt.Sprintf("-- Migrating up %d migrations", len(initial)))
+					assert.Contains(t, plan, "CREATE TABLE")
+
+					plan = cmd.ExecNoErr(t, "plan", target.Version)
+					assert.Contains(t, plan, fmt.Sprintf("-- Migrating up %d migrations", len(initial)/2+1))
+					assert.Contains(t, plan, "-- "+target.Version+"_"+target.Name+" (up)")
+
+					out := cmd.ExecNoErr(t, "to", target.Version, "--"+FlagYes)
+					assert.Contains(t, out, "Successfully migrated up to version "+target.Version)
+					for i, m := range status(t) {
+						assert.Equal(t, i <= len(initial)/2, m.State == popx.Applied, "%s %s", m.Version, m.State)
+					}
+					assert.Contains(t, cmd.ExecNoErr(t, "to", target.Version, "--"+FlagYes), "there is nothing to do")
+
+					plan = cmd.ExecNoErr(t, "plan", "--"+FlagDown, "1")
+					assert.Contains(t, plan, "-- Migrating down 1 migrations")
+					assert.Contains(t, plan, "-- "+target.Version+"_"+target.Name+" (down)")
+
+					out, stdErr, err := cmd.Exec(bytes.NewBufferString("n\n"), "to", earlier.Version)
+					require.NoError(t, err, stdErr)
+					assert.Contains(t, out, "The following migrations will run:")
+					assert.Contains(t, out, "down "+target.Version+"_"+target.Name)
+					assert.Contains(t, out, "Migration aborted.")
+
+					cmd.ExecNoErr(t, "to", earlier.Version, "--"+FlagYes)
+					for i, m := range status(t) {
+						assert.Equal(t, i <= 1, m.State == popx.Applied, "%s %s", m.Version, m.State)
+					}
+
+					stdErr = cmd.ExecExpectedErr(t, "to", "1")
+					assert.Contains(t, stdErr, `unknown migration version "1"`)
+				})
+
 				t.Run("case=applies on yes flag", func(t *testing.T) {
 					out := cmd.ExecNoErr(t, "up", "--"+FlagYes)
 
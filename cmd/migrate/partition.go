@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/ketoctx"
+)
+
+const (
+	FlagPartitionBy = "by"
+	FlagPartitions  = "partitions"
+	FlagPrint       = "print"
+)
+
+func newPartitionCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "partition",
+		Short: "Partition the relation tuple table",
+		Long: `Convert the relation tuple table into a hash partitioned table on PostgreSQL, so that vacuum and index maintenance work on smaller tables.
+
+Partitioning by "namespace" lets queries with a namespace read only one partition, but large namespaces result in large partitions.
+Partitioning by "shard_id" spreads the relation tuples evenly over the partitions.
+
+The conversion copies all relation tuples in one transaction, which blocks writes until it completes.
+Use --print to review or adapt the statements and run them yourself.
+
+### WARNING ###
+
+Before running this command on an existing database, create a back up!
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), true, opts...)
+			if err != nil {
+				return err
+			}
+			p, ok := reg.Persister().(persistence.Partitioner)
+			if !ok {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "The persister does not support partitioning.")
+				return cmdx.FailSilently(cmd)
+			}
+
+			key := persistence.PartitionKey(flagx.MustGetString(cmd, FlagPartitionBy))
+			partitions := flagx.MustGetInt(cmd, FlagPartitions)
+			stmts, err := p.PartitionRelationTuplesPlan(ctx, key, partitions)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not plan the partitioning: %+v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			for _, stmt := range stmts {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s;\n", stmt)
+			}
+			if flagx.MustGetBool(cmd, FlagPrint) {
+				return nil
+			}
+
+			if !flagx.MustGetBool(cmd, FlagYes) && !cmdx.AskForConfirmation("Do you really want to partition the relation tuple table?", cmd.InOrStdin(), cmd.OutOrStdout()) {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Partitioning aborted.")
+				return nil
+			}
+			if err := p.PartitionRelationTuples(ctx, key, partitions); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not partition the relation tuple table: %+v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "The relation tuple table is partitioned by %s into %d partitions.\n", key, partitions)
+			return nil
+		},
+	}
+
+	RegisterYesFlag(cmd.Flags())
+	cmd.Flags().String(FlagPartitionBy, string(persistence.PartitionByNamespace), `The column the relation tuples are partitioned by, "namespace" or "shard_id". Partitioning by "shard_id" is not possible once the unique indexes of the relation tuples exist.`)
+	cmd.Flags().Int(FlagPartitions, 16, "The number of partitions.")
+	cmd.Flags().Bool(FlagPrint, false, "Only print the statements instead of executing them.")
+
+	return cmd
+}
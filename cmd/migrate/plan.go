@@ -0,0 +1,216 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/popx"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/ketoctx"
+)
+
+const FlagDown = "down"
+
+const (
+	directionUp   = "up"
+	directionDown = "down"
+)
+
+// migrationPlan are the migrations that a migration command would run, in
+// the order they would run.
+type migrationPlan struct {
+	Direction  string
+	Migrations popx.Migrations
+}
+
+func newPlanCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan [<version>]",
+		Short: "Print the SQL that a migration would run",
+		Long: "Print the SQL that a migration would run, without changing the database.\n" +
+			"Without arguments, the SQL of `keto migrate up` is printed. Pass a version to print the SQL of `keto migrate to <version>`, " +
+			"or --down <steps> for the SQL of `keto migrate down <steps>`.",
+		Example: `keto migrate plan
+keto migrate plan 20220513200300000000
+keto migrate plan --down 2`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), true, opts...)
+			if err != nil {
+				return err
+			}
+			mb, err := reg.MigrationBox(ctx)
+			if err != nil {
+				return err
+			}
+
+			var plan *migrationPlan
+			switch {
+			case cmd.Flags().Changed(FlagDown) && len(args) > 0:
+				return fmt.Errorf("pass either a version or --%s", FlagDown)
+			case cmd.Flags().Changed(FlagDown):
+				plan, err = planDown(ctx, mb, flagx.MustGetInt(cmd, FlagDown))
+			case len(args) > 0:
+				plan, err = planTo(ctx, mb, args[0])
+			default:
+				plan, err = planUp(ctx, mb)
+			}
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not plan the migrations: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			if err := writePlanSQL(cmd.OutOrStdout(), mb, plan); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not render the migrations: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int(FlagDown, 0, "Print the SQL of migrating down this many steps, 0 for all steps.")
+
+	return cmd
+}
+
+// planUp plans applying all pending migrations.
+func planUp(ctx context.Context, mb *popx.MigrationBox) (*migrationPlan, error) {
+	return planUpTo(ctx, mb, "")
+}
+
+// planUpTo plans applying the pending migrations up to and including the
+// version, or all pending migrations if the version is empty.
+func planUpTo(ctx context.Context, mb *popx.MigrationBox, version string) (*migrationPlan, error) {
+	s, err := mb.Status(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pending := make(map[string]bool)
+	for _, m := range s {
+		if m.State == popx.Pending && (version == "" || m.Version <= version) {
+			pending[m.Version] = true
+		}
+	}
+
+	plan := &migrationPlan{Direction: directionUp}
+	for _, mi := range mb.Migrations[directionUp].SortAndFilter(mb.Connection.Dialect.Name()) {
+		if pending[mi.Version] {
+			plan.Migrations = append(plan.Migrations, mi)
+		}
+	}
+	return plan, nil
+}
+
+// planDown plans rolling back the last steps applied migrations, or all
+// applied migrations if steps is not positive. Like `popx.Migrator.Down`, it
+// assumes that the migrations were applied in order.
+func planDown(ctx context.Context, mb *popx.MigrationBox, steps int) (*migrationPlan, error) {
+	s, err := mb.Status(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var applied []string
+	for _, m := range s {
+		if m.State == popx.Applied {
+			applied = append(applied, m.Version)
+		}
+	}
+	if steps > 0 && steps < len(applied) {
+		applied = applied[len(applied)-steps:]
+	}
+	rollback := make(map[string]bool, len(applied))
+	for _, v := range applied {
+		rollback[v] = true
+	}
+
+	plan := &migrationPlan{Direction: directionDown}
+	for _, mi := range mb.Migrations[directionDown].SortAndFilter(mb.Connection.Dialect.Name(), sort.Reverse) {
+		if rollback[mi.Version] {
+			plan.Migrations = append(plan.Migrations, mi)
+		}
+	}
+	return plan, nil
+}
+
+// planTo plans migrating up or down, so that the migration with the version
+// is the last applied migration.
+func planTo(ctx context.Context, mb *popx.MigrationBox, version string) (*migrationPlan, error) {
+	s, err := mb.Status(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var (
+		found bool
+		after int
+	)
+	for _, m := range s {
+		if m.Version == version {
+			found = true
+		} else if m.Version > version && m.State == popx.Applied {
+			after++
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("unknown migration version %q, see `keto migrate status` for all versions", version)
+	}
+
+	if after > 0 {
+		return planDown(ctx, mb, after)
+	}
+	return planUpTo(ctx, mb, version)
+}
+
+// writePlanSQL writes the SQL of the planned migrations, as it would be run
+// against the database.
+func writePlanSQL(w io.Writer, mb *popx.MigrationBox, plan *migrationPlan) error {
+	if len(plan.Migrations) == 0 {
+		_, err := fmt.Fprintln(w, "-- There are no migrations to run.")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "-- Migrating %s %d migrations on %s.\n", plan.Direction, len(plan.Migrations), mb.Connection.Dialect.Name()); err != nil {
+		return err
+	}
+	render := popx.ParameterizedMigrationContent(nil)
+	for _, mi := range plan.Migrations {
+		if _, err := fmt.Fprintf(w, "\n-- %s_%s (%s)\n", mi.Version, mi.Name, mi.Direction); err != nil {
+			return err
+		}
+
+		if mi.Type == "go" {
+			if _, err := fmt.Fprintln(w, "-- This migration is implemented in Go, its statements depend on the data."); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, err := fs.ReadFile(mb.Dir, mi.Path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		content, err := render(mi, mb.Connection, raw, true)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPlan lists the planned migrations.
+func printPlan(cmd *cobra.Command, plan *migrationPlan) {
+	for _, mi := range plan.Migrations {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "  %-4s %s_%s\n", plan.Direction, mi.Version, mi.Name)
+	}
+}
@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/ketoctx"
+)
+
+func newReencryptCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reencrypt",
+		Short: "Encrypt the stored identifiers with the current secret",
+		Long: `Encrypt the stored subject IDs and objects with the first secret of "secrets.cipher".
+
+Run this command after enabling encryption, to encrypt the identifiers that were written before,
+and after prepending a new secret, so that the old one can be removed afterwards. The last secret
+derives the UUIDs of the identifiers and has to be kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), false, opts...)
+			if err != nil {
+				return err
+			}
+			cm := reg.MappingCipherManager()
+			if cm == nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "The identifiers can only be encrypted if the relation tuples are stored in SQL databases.")
+				return cmdx.FailSilently(cmd)
+			}
+
+			n, err := cm.ReencryptMappings(ctx)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not encrypt the identifiers: %+v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Updated %d identifiers.\n", n)
+			return nil
+		},
+	}
+	return cmd
+}
@@ -17,6 +17,11 @@ func newMigrateCmd(opts []ketoctx.Option) *cobra.Command {
 		newStatusCmd(opts),
 		newUpCmd(opts),
 		newDownCmd(opts),
+		newToCmd(opts),
+		newPlanCmd(opts),
+		newPartitionCmd(opts),
+		newClosureCmd(opts),
+		newReencryptCmd(opts),
 	)
 	return cmd
 }
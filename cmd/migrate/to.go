@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/popx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/ketoctx"
+)
+
+func newToCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "to <version>",
+		Short: "Migrate the database up or down to a version",
+		Long: "Migrate the database up or down, so that the migration with the version is the last applied migration.\n" +
+			"Use `keto migrate status` to list all versions, and `keto migrate plan <version>` to review the SQL before migrating.\n\n" +
+			"### WARNING ###\n\n" +
+			"Migrating down deletes data. Before running this command on an existing database, create a back up!",
+		Example: "keto migrate to 20220513200300000000",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			reg, err := driver.NewDefaultRegistry(ctx, cmd.Flags(), true, opts...)
+			if err != nil {
+				return err
+			}
+			mb, err := reg.MigrationBox(ctx)
+			if err != nil {
+				return err
+			}
+
+			return BoxTo(cmd, mb, args[0])
+		},
+	}
+
+	RegisterYesFlag(cmd.Flags())
+	cmdx.RegisterFormatFlags(cmd.Flags())
+
+	return cmd
+}
+
+func BoxTo(cmd *cobra.Command, mb *popx.MigrationBox, version string) error {
+	ctx := cmd.Context()
+
+	plan, err := planTo(ctx, mb, version)
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not plan the migrations: %s\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+
+	if err := BoxStatus(cmd, mb, ""); err != nil {
+		return err
+	}
+	if len(plan.Migrations) == 0 {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "The database is already at version %s, there is nothing to do.\n", version)
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "The following migrations will run:\n")
+	printPlan(cmd, plan)
+
+	question := "Are you sure that you want to apply these migrations? Make sure to check the CHANGELOG.md for breaking changes beforehand."
+	if plan.Direction == directionDown {
+		question = "Do you really want to migrate down? This will delete data."
+	}
+	if !flagx.MustGetBool(cmd, FlagYes) && !cmdx.AskForConfirmation(question, cmd.InOrStdin(), cmd.OutOrStdout()) {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Migration aborted.")
+		return nil
+	}
+
+	if plan.Direction == directionDown {
+		err = mb.Down(ctx, len(plan.Migrations))
+	} else {
+		_, err = mb.UpTo(ctx, len(plan.Migrations))
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not apply the migrations: %+v\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully migrated %s to version %s:\n", plan.Direction, version)
+	return BoxStatus(cmd, mb, "")
+}
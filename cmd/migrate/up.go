@@ -14,7 +14,8 @@ import (
 )
 
 const (
-	FlagYes = "yes"
+	FlagYes   = "yes"
+	FlagPhase = "phase"
 )
 
 func newUpCmd(opts []ketoctx.Option) *cobra.Command {
@@ -29,6 +30,17 @@ This decreases risk of failure and decreases time required.
 ### WARNING ###
 
 Before running this command on an existing database, create a back up!
+
+### Zero-downtime rollouts ###
+
+The migrations are split into an expand and a contract phase. The expand phase only adds to the schema,
+so that the running and the new version of Ory Keto both work with it. Roll out the new version with
+
+1. keto migrate up --phase expand
+2. rolling out the new version
+3. keto migrate up --phase contract, once the previous version is no longer running
+
+The default phase "all" applies both phases at once.
 `,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
@@ -43,6 +55,15 @@ Before running this command on an existing database, create a back up!
 			if err != nil {
 				return err
 			}
+			mb, err = driver.PhaseMigrationBox(ctx, mb, driver.MigrationPhase(flagx.MustGetString(cmd, FlagPhase)))
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not select the migrations: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			if len(mb.Migrations["up"].SortAndFilter(mb.Connection.Dialect.Name())) == 0 {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "There are no migrations in this phase, there is nothing to do.")
+				return nil
+			}
 
 			if err := BoxUp(cmd, mb); err != nil {
 				return err
@@ -53,6 +74,7 @@ Before running this command on an existing database, create a back up!
 	}
 
 	RegisterYesFlag(cmd.Flags())
+	cmd.Flags().String(FlagPhase, string(driver.MigrationPhaseAll), fmt.Sprintf("the phase of the migrations to apply, one of %q, %q, or %q", driver.MigrationPhaseAll, driver.MigrationPhaseExpand, driver.MigrationPhaseContract))
 
 	cmdx.RegisterFormatFlags(cmd.Flags())
 
@@ -0,0 +1,130 @@
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/logrusx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+)
+
+func NewConvertCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert <config.yml|namespace.yml> [<namespace2.yml> ...]",
+		Short: "Convert legacy namespaces to the Ory Permission Language",
+		Long: `Convert the legacy namespaces of configuration files or namespace files to one Ory Permission Language model, and print it.
+Configuration files can list the namespaces under the "namespaces" key, or point to a directory of namespace files.
+
+Relations are converted from the "relations" key of the namespace config, written in the JSON form that "keto namespace export --ast" prints:
+
+  namespaces:
+    - id: 0
+      name: Document
+      config:
+        relations:
+          - name: viewers
+            types: [{ namespace: User }]
+          - name: view
+            rewrite:
+              operator: or
+              children: [{ relation: viewers }]
+
+Namespace IDs are not part of the Ory Permission Language and are dropped.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var legacy []*namespace.Namespace
+			for _, fn := range args {
+				nn, err := readLegacyNamespaces(cmd, fn)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read the namespaces of %q: %s\n", fn, err)
+					return cmdx.FailSilently(cmd)
+				}
+				legacy = append(legacy, nn...)
+			}
+
+			opl, errs := schema.ConvertLegacy(legacy)
+			if len(errs) > 0 {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Could not convert the namespaces:")
+				for _, err := range errs {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprint(cmd.OutOrStdout(), opl)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// readLegacyNamespaces reads the namespaces of a configuration file, or the
+// namespace of a namespace file.
+func readLegacyNamespaces(cmd *cobra.Command, fn string) ([]*namespace.Namespace, error) {
+	fc, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	parse, err := config.GetParser(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	var val map[string]interface{}
+	if err := parse(fc, &val); err != nil {
+		return nil, err
+	}
+	ns, ok := val["namespaces"]
+	if !ok {
+		n, err := decodeLegacyNamespace(val)
+		if err != nil {
+			return nil, err
+		}
+		return []*namespace.Namespace{n}, nil
+	}
+
+	switch t := ns.(type) {
+	case string:
+		cw, err := config.NewNamespaceWatcher(cmd.Context(), logrusx.New("cmd", "0"), t)
+		if err != nil {
+			return nil, err
+		}
+		var nn []*namespace.Namespace
+		for _, file := range cw.NamespaceFiles() {
+			n := &namespace.Namespace{}
+			if err := file.Parser(file.Contents, n); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+			nn = append(nn, n)
+		}
+		return nn, nil
+	case []interface{}, []map[string]interface{}:
+		// Depending on the format, the list is decoded differently.
+		var nn []*namespace.Namespace
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &nn); err != nil {
+			return nil, err
+		}
+		return nn, nil
+	default:
+		return nil, fmt.Errorf("unknown type %T for key 'namespaces'", t)
+	}
+}
+
+func decodeLegacyNamespace(obj interface{}) (*namespace.Namespace, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	n := &namespace.Namespace{}
+	return n, json.Unmarshal(raw, n)
+}
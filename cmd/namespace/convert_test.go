@@ -0,0 +1,103 @@
+package namespace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertCmd(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: func() *cobra.Command {
+		root := &cobra.Command{Use: "keto"}
+		root.AddCommand(NewConvertCmd())
+		return root
+	}}
+
+	writeFile := func(t *testing.T, dir, name, content string) string {
+		fn := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fn, []byte(content), fileMode))
+		return fn
+	}
+
+	const expected = `class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: (User | SubjectSet<Document, "owners">)[]
+  }
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      this.related.owners.includes(ctx.subject),
+  }
+}
+`
+
+	t.Run("case=embedded namespaces", func(t *testing.T) {
+		fn := writeFile(t, t.TempDir(), "keto.yml", `
+dsn: memory
+namespaces:
+  - id: 0
+    name: User
+  - id: 1
+    name: Document
+    config:
+      relations:
+        - name: owners
+          types: [{ namespace: User }]
+        - name: viewers
+          types: [{ namespace: User }, { namespace: Document, relation: owners }]
+        - name: view
+          rewrite:
+            operator: or
+            children: [{ relation: viewers }, { relation: owners }]
+`)
+		assert.Equal(t, expected, cmd.ExecNoErr(t, "convert", fn))
+	})
+
+	t.Run("case=namespace files", func(t *testing.T) {
+		dir := t.TempDir()
+		user := writeFile(t, dir, "user.json", `{"id": 0, "name": "User"}`)
+		document := writeFile(t, dir, "document.toml", `
+id = 1
+name = "Document"
+
+[[config.relations]]
+name = "owners"
+types = [{ namespace = "User" }]
+
+[[config.relations]]
+name = "viewers"
+types = [{ namespace = "User" }, { namespace = "Document", relation = "owners" }]
+
+[[config.relations]]
+name = "view"
+rewrite = { operator = "or", children = [{ relation = "viewers" }, { relation = "owners" }] }
+`)
+		assert.Equal(t, expected, cmd.ExecNoErr(t, "convert", user, document))
+	})
+
+	t.Run("case=namespace directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "user.yml", "id: 0\nname: User")
+		config := writeFile(t, t.TempDir(), "keto.yml", "namespaces: file://"+dir)
+
+		assert.Equal(t, "class User implements Namespace {}\n", cmd.ExecNoErr(t, "convert", config))
+	})
+
+	t.Run("case=fails on invalid namespaces", func(t *testing.T) {
+		fn := writeFile(t, t.TempDir(), "keto.yml", `
+namespaces:
+  - id: 0
+    name: videos-v2
+`)
+		stdErr := cmd.ExecExpectedErr(t, "convert", fn)
+		assert.Contains(t, stdErr, `namespace "videos-v2": the name is not a valid identifier`)
+	})
+}
@@ -0,0 +1,151 @@
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	FlagAllowBreaking = "allow-breaking"
+	FlagCheckTuples   = "check-tuples"
+)
+
+type changes []schema.Change
+
+func NewDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <old.ts> <new.ts>",
+		Short: "Compare two versions of an Ory Permission Language file",
+		Long: `Compare two versions of an Ory Permission Language file and classify the changes as breaking or safe.
+Removing namespaces, relations, permissions or subject types, restricting the number of subjects of a relation, and adding condition parameters are breaking changes.
+With --check-tuples, removed namespaces and relations are only breaking if the server still stores relation tuples for them.
+
+The command fails if there are breaking changes, so that it can be used to gate deployments.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			old, errs := schema.ParseLocalFiles(args[0])
+			if len(errs) > 0 {
+				return printParseErrors(cmd, args[0], errs)
+			}
+			new, errs := schema.ParseLocalFiles(args[1])
+			if len(errs) > 0 {
+				return printParseErrors(cmd, args[1], errs)
+			}
+
+			cc := changes(schema.Diff(old, new))
+
+			checkTuples, err := cmd.Flags().GetBool(FlagCheckTuples)
+			if err != nil {
+				return err
+			}
+			if checkTuples {
+				if err := schema.ClassifyWithTuples(cc, countTuples(cmd)); err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not count the relation tuples: %s\n", err)
+					return cmdx.FailSilently(cmd)
+				}
+			}
+
+			cmdx.PrintTable(cmd, cc)
+
+			allowBreaking, err := cmd.Flags().GetBool(FlagAllowBreaking)
+			if err != nil {
+				return err
+			}
+			for _, c := range cc {
+				if c.Breaking && !allowBreaking {
+					return cmdx.FailSilently(cmd)
+				}
+			}
+			return nil
+		},
+	}
+
+	registerPackageFlags(cmd.Flags())
+	cmd.Flags().Bool(FlagAllowBreaking, false, "Do not fail if there are breaking changes.")
+	cmd.Flags().Bool(FlagCheckTuples, false, "Only classify removed namespaces and relations as breaking if the server stores relation tuples for them.")
+
+	return cmd
+}
+
+func printParseErrors(cmd *cobra.Command, fn string, errs []error) error {
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the Ory Permission Language file %s:\n", fn)
+	for _, err := range errs {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+	}
+	return cmdx.FailSilently(cmd)
+}
+
+// countTuples counts the relation tuples using the read API. Namespaces that
+// are unknown to the server have no relation tuples.
+func countTuples(cmd *cobra.Command) schema.TupleCounter {
+	return func(namespace, relation string) (int, error) {
+		u := client.GetReadURL(cmd)
+		u.Path = relationtuple.CountRoute
+		q := url.Values{"namespace": {namespace}}
+		if relation != "" {
+			q.Set("relation", relation)
+		}
+		u.RawQuery = q.Encode()
+
+		req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			return 0, nil
+		default:
+			return 0, client.ErrorFromResponse(resp)
+		}
+
+		var res ketoapi.CountResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return 0, err
+		}
+		return res.Count, nil
+	}
+}
+
+func (cc changes) Header() []string {
+	return []string{"BREAKING", "KIND", "NAMESPACE", "RELATION", "MESSAGE"}
+}
+
+func (cc changes) Table() [][]string {
+	rows := make([][]string, len(cc))
+	for i, c := range cc {
+		breaking := "no"
+		if c.Breaking {
+			breaking = "yes"
+		}
+		rows[i] = []string{breaking, string(c.Kind), c.Namespace, c.Relation, c.Message}
+	}
+	return rows
+}
+
+func (cc changes) Interface() interface{} {
+	if cc == nil {
+		return []schema.Change{}
+	}
+	return []schema.Change(cc)
+}
+
+func (cc changes) Len() int {
+	return len(cc)
+}
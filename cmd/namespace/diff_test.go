@@ -0,0 +1,103 @@
+package namespace
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestDiffCmd(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: func() *cobra.Command {
+		root := &cobra.Command{Use: "keto"}
+		root.AddCommand(NewDiffCmd())
+		return root
+	}}
+
+	writeFile := func(t *testing.T, content string) string {
+		fn := filepath.Join(t.TempDir(), "namespaces.keto.ts")
+		require.NoError(t, os.WriteFile(fn, []byte(content), fileMode))
+		return fn
+	}
+
+	old := writeFile(t, `
+class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: User[]
+  }
+}`)
+	safe := writeFile(t, `
+class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: User[]
+    editors: User[]
+  }
+}`)
+	breaking := writeFile(t, `
+class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+  }
+}`)
+
+	t.Run("case=prints changes as JSON", func(t *testing.T) {
+		var cc []schema.Change
+		require.NoError(t, json.Unmarshal([]byte(cmd.ExecNoErr(t, "diff", "--format", "json", old, safe)), &cc))
+		require.Len(t, cc, 1)
+		assert.Equal(t, schema.ChangeRelationAdded, cc[0].Kind)
+		assert.False(t, cc[0].Breaking)
+	})
+
+	t.Run("case=fails on breaking changes", func(t *testing.T) {
+		stdOut, _, err := cmd.Exec(nil, "diff", old, breaking)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdOut, "relation-removed")
+
+		cmd.ExecNoErr(t, "diff", "--allow-breaking", old, breaking)
+	})
+
+	t.Run("case=removals without relation tuples are safe", func(t *testing.T) {
+		counts := map[string]int{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, relationtuple.CountRoute, r.URL.Path)
+			q := r.URL.Query()
+			_ = json.NewEncoder(w).Encode(&ketoapi.CountResponse{Count: counts[q.Get("namespace")+"#"+q.Get("relation")]})
+		}))
+		t.Cleanup(ts.Close)
+		remote := strings.TrimPrefix(ts.URL, "http://")
+
+		stdOut := cmd.ExecNoErr(t, "diff", "--check-tuples", "--"+client.FlagReadRemote, remote, old, breaking)
+		assert.Contains(t, stdOut, "no relation tuples exist")
+
+		counts["Document#viewers"] = 2
+		stdOut, _, err := cmd.Exec(nil, "diff", "--check-tuples", "--"+client.FlagReadRemote, remote, old, breaking)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdOut, "but 2 relation tuples exist")
+	})
+
+	t.Run("case=fails on parse errors", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "diff", old, writeFile(t, `class Document implements Namespace { related: { owners: User[] } }`))
+		assert.Contains(t, stdErr, `namespace "User" was not declared`)
+	})
+}
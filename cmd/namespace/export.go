@@ -0,0 +1,82 @@
+package namespace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+)
+
+const FlagAST = "ast"
+
+func NewExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the namespaces a running server uses",
+		Long: "Print the namespaces a running server uses as an Ory Permission Language model, including the namespaces managed through the namespace administration API.\n" +
+			"Use this to verify that the server runs the namespace configuration you expect.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ast, err := cmd.Flags().GetBool(FlagAST)
+			if err != nil {
+				return err
+			}
+			format := namespacehandler.FormatOPL
+			if ast {
+				format = namespacehandler.FormatJSON
+			}
+
+			body, err := getEffectiveNamespaces(cmd, format)
+			if err != nil {
+				return err
+			}
+			if ast {
+				var indented bytes.Buffer
+				if err := json.Indent(&indented, body, "", "  "); err != nil {
+					return err
+				}
+				indented.WriteByte('\n')
+				body = indented.Bytes()
+			}
+			_, _ = cmd.OutOrStdout().Write(body)
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+	cmd.Flags().Bool(FlagAST, false, "Print the JSON syntax tree of the namespaces instead of the Ory Permission Language model.")
+
+	return cmd
+}
+
+// getEffectiveNamespaces returns the namespaces of the running server in the
+// given format.
+func getEffectiveNamespaces(cmd *cobra.Command, format string) ([]byte, error) {
+	u := client.GetAdminURL(cmd)
+	u.Path = namespacehandler.RouteBase
+	u.RawQuery = url.Values{"format": {format}}.Encode()
+	req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not get the namespaces: %s\n", err)
+		return nil, cmdx.FailSilently(cmd)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not get the namespaces: %s\n", client.ErrorFromResponse(resp))
+		return nil, cmdx.FailSilently(cmd)
+	}
+
+	return io.ReadAll(resp.Body)
+}
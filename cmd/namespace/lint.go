@@ -0,0 +1,290 @@
+package namespace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+	"github.com/ory/keto/internal/schema"
+)
+
+const (
+	FlagFailOn = "fail-on"
+	FlagRemote = "remote"
+
+	FormatSARIF = "sarif"
+
+	// RuleParseError is the rule of diagnostics from the parser and type
+	// checker.
+	RuleParseError = "parse-error"
+
+	// remoteFile is the name of the model of a running server in
+	// diagnostics.
+	remoteFile = "namespaces.keto.ts"
+)
+
+type (
+	position struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	}
+	// diagnostic is a parse error or lint finding. The location is known
+	// for all parse errors, but only on a best-effort basis for findings.
+	diagnostic struct {
+		schema.Finding
+		File  string    `json:"file,omitempty"`
+		Start *position `json:"start,omitempty"`
+		End   *position `json:"end,omitempty"`
+		Hint  string    `json:"hint,omitempty"`
+	}
+	diagnostics []diagnostic
+)
+
+func NewLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint <namespaces.keto.ts> [<file.ts> ...] | lint --remote",
+		Short: "Lint Ory Permission Language files",
+		Long: `Lint Ory Permission Language files for semantic problems that are not reported by the parser and type checker.
+The files and all files they import are checked as one model. With --remote, the model of a running server is checked instead.
+Parse and type errors are reported with the rule ` + RuleParseError + `. The following rules are checked:
+
+` + ruleList() + `
+Use --format json or --format sarif to process the diagnostics in automation, e.g. to upload them to a code scanning service.`,
+		Example: `keto namespace lint namespaces.keto.ts
+keto namespace lint namespaces.keto.ts --fail-on warning --format sarif > lint.sarif
+keto namespace lint --remote --admin-remote keto.example.com:4467`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if flagx.MustGetBool(cmd, FlagRemote) {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			failOn, err := cmd.Flags().GetString(FlagFailOn)
+			if err != nil {
+				return err
+			}
+			threshold := schema.Severity(failOn)
+			if !isSeverity(threshold) {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unknown severity %q for --%s, expected one of %s.\n", failOn, FlagFailOn, severities())
+				return cmdx.FailSilently(cmd)
+			}
+
+			var (
+				nn      []namespace.Namespace
+				errs    []error
+				sources = make(map[string]string)
+			)
+			if flagx.MustGetBool(cmd, FlagRemote) {
+				body, err := getEffectiveNamespaces(cmd, namespacehandler.FormatOPL)
+				if err != nil {
+					return err
+				}
+				sources[remoteFile] = string(body)
+				nn, errs = schema.ParseContents(sources)
+			} else {
+				for _, fn := range args {
+					if content, err := os.ReadFile(fn); err == nil {
+						sources[fn] = string(content)
+					}
+				}
+				nn, errs = schema.ParseLocalFiles(args...)
+			}
+
+			var dd diagnostics
+			for _, err := range errs {
+				dd = append(dd, parseDiagnostic(err))
+			}
+			if len(errs) == 0 {
+				for _, f := range schema.Lint(nn) {
+					dd = append(dd, findingDiagnostic(f, sources))
+				}
+			}
+
+			switch format := flagx.MustGetString(cmd, cmdx.FlagFormat); {
+			case format == FormatSARIF:
+				if err := writeSARIF(cmd.OutOrStdout(), dd); err != nil {
+					return err
+				}
+			case len(errs) > 0 && (format == string(cmdx.FormatDefault) || format == string(cmdx.FormatTable)):
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Could not parse the Ory Permission Language files:")
+				for _, err := range errs {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+			default:
+				cmdx.PrintTable(cmd, dd)
+			}
+
+			for _, d := range dd {
+				if d.Severity.AtLeast(threshold) {
+					return cmdx.FailSilently(cmd)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmdx.RegisterFormatFlags(cmd.Flags())
+	cmd.Flags().Lookup(cmdx.FlagFormat).Usage = fmt.Sprintf("Set the output format. One of %s, %s, %s, %s, and %s.", cmdx.FormatTable, cmdx.FormatJSON, cmdx.FormatYAML, cmdx.FormatJSONPretty, FormatSARIF)
+	cmd.Flags().String(FlagFailOn, string(schema.SeverityError), fmt.Sprintf("Fail if there are findings of at least this severity, one of %s.", severities()))
+	cmd.Flags().Bool(FlagRemote, false, "Lint the namespaces of a running server instead of local files.")
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+
+	return cmd
+}
+
+func ruleList() string {
+	var b strings.Builder
+	for _, r := range schema.Rules {
+		b.WriteString(fmt.Sprintf("  %-24s %s\n", r.ID, r.Description))
+	}
+	return b.String()
+}
+
+// parseDiagnostic converts an error of the parser or type checker. A
+// suggestion in the message is reported as the hint.
+func parseDiagnostic(err error) diagnostic {
+	d := diagnostic{Finding: schema.Finding{
+		Severity: schema.SeverityError,
+		Rule:     RuleParseError,
+		Message:  err.Error(),
+	}}
+
+	var perr *schema.ParseError
+	if !errors.As(err, &perr) {
+		return d
+	}
+	d.Message = perr.Message()
+	if msg, suggestion, ok := strings.Cut(d.Message, ", did you mean "); ok {
+		d.Message, d.Hint = msg, "Did you mean "+suggestion
+	}
+	if perr.File() != "" && perr.File() != "input" {
+		d.File = localPath(perr.File())
+	}
+	start, end := perr.Start(), perr.End()
+	d.Start = &position{Line: start.Line, Column: start.Column}
+	d.End = &position{Line: end.Line, Column: end.Column}
+	return d
+}
+
+// localPath converts the name of a parsed file back to a path relative to the
+// working directory, if possible.
+func localPath(name string) string {
+	if name == remoteFile {
+		return name
+	}
+	abs := filepath.FromSlash("/" + name)
+	if _, err := os.Stat(abs); err != nil {
+		return name
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return abs
+}
+
+// findingDiagnostic adds the hint of the rule to the finding, and locates the
+// declaration of the namespace or relation in the sources.
+func findingDiagnostic(f schema.Finding, sources map[string]string) diagnostic {
+	d := diagnostic{Finding: f}
+	if r, ok := schema.RuleByID(f.Rule); ok {
+		d.Hint = r.Hint
+	}
+
+	class := regexp.MustCompile(`\bclass\s+` + regexp.QuoteMeta(f.Namespace) + `\s+implements\s+Namespace\b`)
+	files := make([]string, 0, len(sources))
+	for fn := range sources {
+		files = append(files, fn)
+	}
+	sort.Strings(files)
+	for _, fn := range files {
+		src := sources[fn]
+		loc := class.FindStringIndex(src)
+		if loc == nil {
+			continue
+		}
+		offset := loc[0]
+		if f.Relation != "" {
+			relation := regexp.MustCompile(`\b` + regexp.QuoteMeta(f.Relation) + `\s*:`)
+			if rel := relation.FindStringIndex(src[loc[1]:]); rel != nil {
+				offset = loc[1] + rel[0]
+			}
+		}
+		d.File = fn
+		d.Start = sourcePosition(src, offset)
+		break
+	}
+	return d
+}
+
+func sourcePosition(src string, offset int) *position {
+	line := strings.Count(src[:offset], "\n") + 1
+	column := len([]rune(src[strings.LastIndex(src[:offset], "\n")+1 : offset]))
+	return &position{Line: line, Column: column + 1}
+}
+
+func isSeverity(s schema.Severity) bool {
+	for _, sev := range schema.Severities {
+		if sev == s {
+			return true
+		}
+	}
+	return false
+}
+
+func severities() string {
+	s := make([]string, len(schema.Severities))
+	for i, sev := range schema.Severities {
+		s[i] = string(sev)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (d diagnostic) location() string {
+	if d.File == "" {
+		return ""
+	}
+	if d.Start == nil {
+		return d.File
+	}
+	return fmt.Sprintf("%s:%d:%d", d.File, d.Start.Line, d.Start.Column)
+}
+
+func (dd diagnostics) Header() []string {
+	return []string{"SEVERITY", "RULE", "NAMESPACE", "RELATION", "LOCATION", "MESSAGE", "HINT"}
+}
+
+func (dd diagnostics) Table() [][]string {
+	rows := make([][]string, len(dd))
+	for i, d := range dd {
+		rows[i] = []string{string(d.Severity), d.Rule, d.Namespace, d.Relation, d.location(), d.Message, d.Hint}
+	}
+	return rows
+}
+
+func (dd diagnostics) Interface() interface{} {
+	if dd == nil {
+		return []diagnostic{}
+	}
+	return []diagnostic(dd)
+}
+
+func (dd diagnostics) Len() int {
+	return len(dd)
+}
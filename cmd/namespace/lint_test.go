@@ -0,0 +1,161 @@
+package namespace
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+	"github.com/ory/keto/internal/schema"
+)
+
+func TestLintCmd(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: func() *cobra.Command {
+		root := &cobra.Command{Use: "keto"}
+		root.AddCommand(NewLintCmd())
+		return root
+	}}
+
+	writeFile := func(t *testing.T, content string) string {
+		fn := filepath.Join(t.TempDir(), "namespaces.keto.ts")
+		require.NoError(t, os.WriteFile(fn, []byte(content), fileMode))
+		return fn
+	}
+
+	unused := writeFile(t, `
+class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+  }
+}`)
+
+	t.Run("case=prints findings as JSON", func(t *testing.T) {
+		var ff []schema.Finding
+		require.NoError(t, json.Unmarshal([]byte(cmd.ExecNoErr(t, "lint", "--format", "json", unused)), &ff))
+		require.Len(t, ff, 1)
+		assert.Equal(t, schema.RuleUnusedRelation, ff[0].Rule)
+		assert.Equal(t, "owners", ff[0].Relation)
+	})
+
+	t.Run("case=fails on findings of the given severity", func(t *testing.T) {
+		stdOut, _, err := cmd.Exec(nil, "lint", "--fail-on", "warning", unused)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdOut, "unused-relation")
+	})
+
+	t.Run("case=fails on unreachable permissions", func(t *testing.T) {
+		fn := writeFile(t, `
+class Folder implements Namespace {
+  related: {
+    parents: Folder[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.parents.traverse((p) => p.permits.view(ctx)),
+  }
+}`)
+		stdOut, _, err := cmd.Exec(nil, "lint", fn)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdOut, "unreachable-permission")
+	})
+
+	t.Run("case=fails on parse errors", func(t *testing.T) {
+		fn := writeFile(t, `class Document implements Namespace { related: { owners: User[] } }`)
+		stdErr := cmd.ExecExpectedErr(t, "lint", fn)
+		assert.Contains(t, stdErr, `namespace "User" was not declared`)
+	})
+
+	t.Run("case=rejects unknown severities", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "lint", "--fail-on", "fatal", unused)
+		assert.Contains(t, stdErr, "Unknown severity")
+	})
+
+	t.Run("case=adds hints and locations to findings", func(t *testing.T) {
+		var dd []diagnostic
+		require.NoError(t, json.Unmarshal([]byte(cmd.ExecNoErr(t, "lint", "--format", "json", unused)), &dd))
+		require.Len(t, dd, 1)
+		assert.Equal(t, unused, dd[0].File)
+		assert.Equal(t, &position{Line: 6, Column: 5}, dd[0].Start)
+		rule, ok := schema.RuleByID(schema.RuleUnusedRelation)
+		require.True(t, ok)
+		assert.Equal(t, rule.Hint, dd[0].Hint)
+	})
+
+	t.Run("case=prints parse errors as JSON", func(t *testing.T) {
+		fn := writeFile(t, `class User implements Namespace {}
+class Document implements Namespace { related: { owners: Usr[] } }`)
+		stdOut, _, err := cmd.Exec(nil, "lint", "--format", "json", fn)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+
+		var dd []diagnostic
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &dd))
+		require.Len(t, dd, 1)
+		assert.Equal(t, RuleParseError, dd[0].Rule)
+		assert.Equal(t, schema.SeverityError, dd[0].Severity)
+		assert.Equal(t, `namespace "Usr" was not declared`, dd[0].Message)
+		assert.Equal(t, `Did you mean "User"?`, dd[0].Hint)
+		assert.Equal(t, &position{Line: 2, Column: 58}, dd[0].Start)
+		assert.Equal(t, "namespaces.keto.ts", filepath.Base(dd[0].File))
+	})
+
+	t.Run("case=prints SARIF", func(t *testing.T) {
+		stdOut, _, err := cmd.Exec(nil, "lint", "--format", FormatSARIF, "--fail-on", "warning", unused)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+
+		var log sarifLog
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &log))
+		assert.Equal(t, "2.1.0", log.Version)
+		require.Len(t, log.Runs, 1)
+		assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(schema.Rules)+1)
+		require.Len(t, log.Runs[0].Results, 1)
+
+		result := log.Runs[0].Results[0]
+		assert.Equal(t, schema.RuleUnusedRelation, result.RuleID)
+		assert.Equal(t, "warning", result.Level)
+		require.Len(t, result.Locations, 1)
+		assert.Equal(t, filepath.ToSlash(unused), result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		assert.Equal(t, 6, result.Locations[0].PhysicalLocation.Region.StartLine)
+		assert.Equal(t, []sarifLogicalLocation{{FullyQualifiedName: "Document.owners", Kind: "member"}}, result.Locations[0].LogicalLocations)
+	})
+
+	t.Run("case=lints the namespaces of a running server", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, namespacehandler.RouteBase, r.URL.Path)
+			require.Equal(t, namespacehandler.FormatOPL, r.URL.Query().Get("format"))
+			_, _ = w.Write([]byte(`class User implements Namespace {}
+class Bot implements Namespace {}`))
+		}))
+		t.Cleanup(ts.Close)
+
+		var dd []diagnostic
+		stdOut := cmd.ExecNoErr(t, "lint", "--remote", "--format", "json", "--"+client.FlagAdminRemote, strings.TrimPrefix(ts.URL, "http://"))
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &dd))
+		require.Len(t, dd, 2)
+		assert.Equal(t, schema.RuleUnusedNamespace, dd[0].Rule)
+		assert.Equal(t, remoteFile, dd[0].File)
+		assert.Equal(t, &position{Line: 1, Column: 1}, dd[0].Start)
+		assert.Equal(t, &position{Line: 2, Column: 1}, dd[1].Start)
+	})
+
+	t.Run("case=rejects files with --remote", func(t *testing.T) {
+		_, _, err := cmd.Exec(nil, "lint", "--remote", unused)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,114 @@
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+)
+
+const FlagModel = "model"
+
+func NewOpenFGACmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "openfga",
+		Short: "Import OpenFGA authorization models and relation tuples",
+	}
+	cmd.AddCommand(newOpenFGAImportCmd(), newOpenFGATuplesCmd())
+	return cmd
+}
+
+func newOpenFGAImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <model.fga|model.json>",
+		Short: "Translate an OpenFGA authorization model to the Ory Permission Language",
+		Long: `Translate an OpenFGA authorization model, written in the DSL or in JSON, to an Ory Permission Language model, and print it.
+Types become namespaces, directly related user types become relations, and usersets become permissions.
+A relation that is both directly assignable and computed, e.g. "define viewer: [user] or owner", is split into the relation "viewer_direct" that stores the relation tuples, and the permission "viewer".
+Constructs without an equivalent, such as wildcards and conditions, are dropped and reported.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			model, err := os.ReadFile(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read the OpenFGA model: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			nn, untranslatable, errs := schema.ImportOpenFGA(model)
+			if len(errs) > 0 {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Could not translate the OpenFGA model:")
+				for _, err := range errs {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+				return cmdx.FailSilently(cmd)
+			}
+			if err := reportUntranslatable(cmd, untranslatable); err != nil {
+				return err
+			}
+
+			translated := make([]*namespace.Namespace, len(nn))
+			for i := range nn {
+				translated[i] = &nn[i]
+			}
+			opl, err := schema.Print(translated)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(cmd.OutOrStdout(), opl)
+			return nil
+		},
+	}
+	cmd.Flags().Bool(FlagStrict, false, "Fail if a construct can not be translated.")
+
+	return cmd
+}
+
+func newOpenFGATuplesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tuples --model <model.fga|model.json> <tuples.json>",
+		Short: "Translate OpenFGA relation tuples",
+		Long: `Translate OpenFGA relation tuples to the relation tuples of the namespaces that "keto namespace openfga import" translates the model to, and print them as JSON.
+The tuples are read as a JSON list of tuple keys, or as an object with the key "tuple_keys", as returned by "fga tuple read".
+The output can be written with "keto relation-tuple create -".`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modelFile, err := cmd.Flags().GetString(FlagModel)
+			if err != nil {
+				return err
+			}
+			model, err := os.ReadFile(modelFile)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read the OpenFGA model: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			tuples, err := os.ReadFile(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read the OpenFGA tuples: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			translated, untranslatable, err := schema.ImportOpenFGATuples(model, tuples)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not translate the OpenFGA tuples: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			if err := reportUntranslatable(cmd, untranslatable); err != nil {
+				return err
+			}
+
+			e := json.NewEncoder(cmd.OutOrStdout())
+			e.SetIndent("", "  ")
+			return e.Encode(translated)
+		},
+	}
+	cmd.Flags().String(FlagModel, "", "The OpenFGA model the tuples belong to.")
+	cmd.Flags().Bool(FlagStrict, false, "Fail if a tuple can not be translated.")
+	_ = cmd.MarkFlagRequired(FlagModel)
+
+	return cmd
+}
@@ -0,0 +1,81 @@
+package namespace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestOpenFGACmd(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: func() *cobra.Command {
+		root := &cobra.Command{Use: "keto"}
+		root.AddCommand(NewOpenFGACmd())
+		return root
+	}}
+
+	writeFile := func(t *testing.T, name, content string) string {
+		fn := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(fn, []byte(content), fileMode))
+		return fn
+	}
+
+	model := writeFile(t, "model.fga", `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, user:*] or owner
+`)
+
+	t.Run("case=import", func(t *testing.T) {
+		stdOut, stdErr, err := cmd.Exec(nil, "openfga", "import", model)
+		require.NoError(t, err)
+		assert.Equal(t, `class user implements Namespace {}
+
+class document implements Namespace {
+  related: {
+    owner: user[]
+    viewer_direct: user[]
+  }
+  permits = {
+    viewer: (ctx: Context): boolean =>
+      this.related.viewer_direct.includes(ctx.subject) ||
+      this.related.owner.includes(ctx.subject),
+  }
+}
+`, stdOut)
+		assert.Contains(t, stdErr, "untranslatable: document.viewer: the wildcard user:* was dropped")
+
+		_, _, err = cmd.Exec(nil, "openfga", "import", "--"+FlagStrict, model)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+	})
+
+	t.Run("case=tuples", func(t *testing.T) {
+		tuples := writeFile(t, "tuples.json", `[{"user": "user:anne", "relation": "viewer", "object": "document:readme"}]`)
+
+		var translated []*ketoapi.RelationTuple
+		require.NoError(t, json.Unmarshal([]byte(cmd.ExecNoErr(t, "openfga", "tuples", "--"+FlagModel, model, tuples)), &translated))
+		assert.Equal(t, []*ketoapi.RelationTuple{{
+			Namespace:  "document",
+			Object:     "readme",
+			Relation:   "viewer_direct",
+			SubjectSet: &ketoapi.SubjectSet{Namespace: "user", Object: "anne"},
+		}}, translated)
+	})
+
+	t.Run("case=fails on invalid models", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "openfga", "import", writeFile(t, "model.fga", "model\n  schema 1.0\n"))
+		assert.Contains(t, stdErr, "only models with schema 1.1 can be translated")
+	})
+}
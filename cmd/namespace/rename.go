@@ -0,0 +1,224 @@
+package namespace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+const FlagBatchSize = "batch-size"
+
+func NewRenameCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename",
+		Short: "Rename a namespace",
+		Long: "Rename a namespace without breaking checks on the way.\n" +
+			"While the relation tuples are rewritten, the old name is an alias of the new one. " +
+			"Once all clients use the new name, finish the rename to remove the alias.",
+	}
+	cmd.AddCommand(newRenameStartCmd(), newRenameRewriteCmd(), newRenameFinishCmd(), newRenameStatusCmd())
+	return cmd
+}
+
+func newRenameStartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start <from> <to>",
+		Short: "Start renaming a namespace and rewrite its relation tuples",
+		Long: "Start renaming a namespace and rewrite its relation tuples in batches.\n" +
+			"A namespace definition managed through the namespace administration API is renamed right away. " +
+			"Namespaces from the configuration have to be declared with the new name before starting the rename.\n" +
+			"The relation tuples are rewritten once all Keto instances know the alias, " +
+			`which takes up to the namespace refresh interval. An interrupted rewrite is continued with "keto namespace rename rewrite".`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := json.Marshal(&ketoapi.StartNamespaceRename{From: args[0], To: args[1]})
+			if err != nil {
+				return err
+			}
+
+			u := client.GetAdminURL(cmd)
+			u.Path = relationtuple.NamespaceRenameRouteBase
+			req, err := client.NewRequest(cmd, http.MethodPost, u.String(), bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			var rename ketoapi.NamespaceRename
+			if err := doJSON(req, &rename); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not start the rename: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Started renaming namespace %s to %s\n", rename.From, rename.To)
+			if rename.DefinitionRenamed {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), "The managed namespace definition was renamed.")
+			}
+
+			return rewriteNamespace(cmd, &rename)
+		},
+	}
+	registerRenameBatchFlags(cmd)
+
+	return cmd
+}
+
+func newRenameRewriteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rewrite <from>",
+		Short: "Continue rewriting the relation tuples of a namespace rename",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rename, err := getNamespaceRename(cmd, args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not get the rename: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			return rewriteNamespace(cmd, rename)
+		},
+	}
+	registerRenameBatchFlags(cmd)
+
+	return cmd
+}
+
+func newRenameFinishCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "finish <from>",
+		Short: "Finish a namespace rename and remove the old name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			u := client.GetAdminURL(cmd)
+			u.Path = strings.Replace(relationtuple.NamespaceRenameFinishRoute, ":from", url.PathEscape(args[0]), 1)
+			req, err := client.NewRequest(cmd, http.MethodPost, u.String(), nil)
+			if err != nil {
+				return err
+			}
+
+			var rename ketoapi.NamespaceRename
+			if err := doJSON(req, &rename); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not finish the rename: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Renamed namespace %s to %s\n", rename.From, rename.To)
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+
+	return cmd
+}
+
+func newRenameStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "List the namespace renames and their progress",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			renames, err := listNamespaceRenames(cmd)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not list the renames: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			for _, r := range renames {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s -> %s\t%s\t%d relation tuples rewritten\t%s\n", r.From, r.To, r.State, r.Rewritten, r.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+
+	return cmd
+}
+
+func registerRenameBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Int(FlagBatchSize, 100, "The maximum number of relation tuples to rewrite per request.")
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+}
+
+// rewriteNamespace waits until batches are allowed, and rewrites batches of
+// relation tuples until none reference the old name anymore.
+func rewriteNamespace(cmd *cobra.Command, rename *ketoapi.NamespaceRename) error {
+	batchSize, err := cmd.Flags().GetInt(FlagBatchSize)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(&ketoapi.NamespaceRenameBatch{BatchSize: batchSize})
+	if err != nil {
+		return err
+	}
+
+	if wait := time.Until(rename.BatchesAllowedAt); wait > 0 {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Waiting %s until all instances know the alias...\n", wait.Round(time.Second))
+		select {
+		case <-time.After(wait):
+		case <-cmd.Context().Done():
+			return cmd.Context().Err()
+		}
+	}
+
+	u := client.GetAdminURL(cmd)
+	u.Path = strings.Replace(relationtuple.NamespaceRenameBatchRoute, ":from", url.PathEscape(rename.From), 1)
+	for rename.State == ketoapi.NamespaceRenameRewriting {
+		req, err := client.NewRequest(cmd, http.MethodPost, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		if err := doJSON(req, rename); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not rewrite the relation tuples: %s\n", err)
+			return cmdx.FailSilently(cmd)
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%d relation tuples rewritten\n", rename.Rewritten)
+	}
+
+	if rename.State == ketoapi.NamespaceRenameRewritten {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(),
+			"All relation tuples use the name %s. Run \"keto namespace rename finish %s\" once all clients use the new name.\n",
+			rename.To, rename.From)
+	}
+	return nil
+}
+
+func getNamespaceRename(cmd *cobra.Command, from string) (*ketoapi.NamespaceRename, error) {
+	renames, err := listNamespaceRenames(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range renames {
+		if r.From == from && r.State != ketoapi.NamespaceRenameCompleted {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("there is no active rename of namespace %q", from)
+}
+
+func listNamespaceRenames(cmd *cobra.Command) ([]*ketoapi.NamespaceRename, error) {
+	u := client.GetAdminURL(cmd)
+	u.Path = relationtuple.NamespaceRenameRouteBase
+	req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res ketoapi.ListNamespaceRenamesResponse
+	if err := doJSON(req, &res); err != nil {
+		return nil, err
+	}
+	return res.Renames, nil
+}
@@ -29,7 +29,7 @@ func RegisterCommandsRecursive(parent *cobra.Command, _ []ketoctx.Option) {
 	migrateCmd := NewMigrateCmd()
 	migrateCmd.AddCommand(NewMigrateUpCmd(), NewMigrateDownCmd(), NewMigrateStatusCmd())
 
-	rootCmd.AddCommand(migrateCmd, NewValidateCmd())
+	rootCmd.AddCommand(migrateCmd, NewValidateCmd(), NewLintCmd(), NewDiffCmd(), NewExportCmd(), NewConvertCmd(), NewSpiceDBCmd(), NewOpenFGACmd(), NewSchemaCmd(), NewRenameCmd())
 
 	parent.AddCommand(rootCmd)
 }
@@ -0,0 +1,145 @@
+package namespace
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/schema"
+)
+
+// The subset of the SARIF 2.1.0 format that is needed to report diagnostics,
+// see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+		Help             sarifMessage `json:"help"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+		LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine,omitempty"`
+		EndColumn   int `json:"endColumn,omitempty"`
+	}
+	sarifLogicalLocation struct {
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
+
+func writeSARIF(w io.Writer, dd diagnostics) error {
+	rules := []sarifRule{{
+		ID:               RuleParseError,
+		ShortDescription: sarifMessage{Text: "the model could not be parsed or type checked"},
+		Help:             sarifMessage{Text: "Fix the syntax or type error at the reported location."},
+	}}
+	for _, r := range schema.Rules {
+		rules = append(rules, sarifRule{
+			ID:               r.ID,
+			ShortDescription: sarifMessage{Text: r.Description},
+			Help:             sarifMessage{Text: r.Hint},
+		})
+	}
+
+	results := make([]sarifResult, len(dd))
+	for i, d := range dd {
+		message := d.Message
+		if d.Hint != "" {
+			message += ". " + d.Hint
+		}
+		results[i] = sarifResult{
+			RuleID:  d.Rule,
+			Level:   sarifLevel(d.Severity),
+			Message: sarifMessage{Text: message},
+		}
+
+		var loc sarifLocation
+		if d.File != "" {
+			loc.PhysicalLocation = &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.File)},
+			}
+			if d.Start != nil {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: d.Start.Line, StartColumn: d.Start.Column}
+				if d.End != nil {
+					loc.PhysicalLocation.Region.EndLine, loc.PhysicalLocation.Region.EndColumn = d.End.Line, d.End.Column
+				}
+			}
+		}
+		if d.Namespace != "" {
+			name, kind := d.Namespace, "type"
+			if d.Relation != "" {
+				name, kind = d.Namespace+"."+d.Relation, "member"
+			}
+			loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: name, Kind: kind}}
+		}
+		if loc.PhysicalLocation != nil || loc.LogicalLocations != nil {
+			results[i].Locations = []sarifLocation{loc}
+		}
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(&sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "keto",
+				Version:        config.Version,
+				InformationURI: "https://www.ory.sh/keto",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	})
+}
+
+func sarifLevel(s schema.Severity) string {
+	switch s {
+	case schema.SeverityError:
+		return "error"
+	case schema.SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
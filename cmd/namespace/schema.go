@@ -0,0 +1,142 @@
+package namespace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+func NewSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Evolve the schema of a namespace",
+		Long: "Rewrite the relation tuples of a namespace after a relation was renamed or split.\n" +
+			"Every applied migration increments the schema version of the namespace.",
+	}
+	cmd.AddCommand(newSchemaMigrateCmd(true), newSchemaMigrateCmd(false), newSchemaVersionsCmd())
+	return cmd
+}
+
+func newSchemaMigrateCmd(dryRun bool) *cobra.Command {
+	use, short, route := "apply", "Apply a schema migration", relationtuple.SchemaMigrationApplyRoute
+	if dryRun {
+		use, short, route = "plan", "Preview a schema migration without changing any relation tuples", relationtuple.SchemaMigrationPlanRoute
+	}
+
+	cmd := &cobra.Command{
+		Use:   use + " <migration-file>",
+		Short: short,
+		Long: short + ".\n" +
+			"The migration file is written in YAML or JSON, for example:\n\n" +
+			"  namespace: Document\n" +
+			"  from_version: 0\n" +
+			"  description: split viewers by subject type\n" +
+			"  steps:\n" +
+			"    - rename: {from: owner, to: owners}\n" +
+			"    - split:\n" +
+			"        from: viewers\n" +
+			"        into:\n" +
+			"          - {relation: user_viewers, subject_ids: true}\n" +
+			"          - {relation: group_viewers, subject_set_namespace: Group}\n",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := os.ReadFile(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read file %s: %s\n", args[0], err)
+				return cmdx.FailSilently(cmd)
+			}
+			body, err := yaml.YAMLToJSON(raw)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse migration file %s: %s\n", args[0], err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			u := client.GetAdminURL(cmd)
+			u.Path = route
+			req, err := client.NewRequest(cmd, http.MethodPost, u.String(), bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			var plan ketoapi.SchemaMigrationPlan
+			if err := doJSON(req, &plan); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not %s the migration: %s\n", use, err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			verb := "would be"
+			if plan.Applied {
+				verb = "were"
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Namespace %s: schema version %d -> %d\n", plan.Namespace, plan.FromVersion, plan.ToVersion)
+			for _, s := range plan.Steps {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "  %s: %d relation tuples %s rewritten, %d unmatched\n", s.Description, s.Rewritten, verb, s.Unmatched)
+			}
+			for _, w := range plan.Warnings {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", w)
+			}
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+
+	return cmd
+}
+
+func newSchemaVersionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions <namespace>",
+		Short: "List the schema versions of a namespace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			u := client.GetAdminURL(cmd)
+			u.Path = relationtuple.SchemaVersionsRoute
+			u.RawQuery = url.Values{"namespace": {args[0]}}.Encode()
+			req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return err
+			}
+
+			var res ketoapi.ListSchemaVersionsResponse
+			if err := doJSON(req, &res); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not list the schema versions: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Namespace %s is at schema version %d\n", args[0], res.Current)
+			for _, v := range res.Versions {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "  %d\t%s\t%s\n", v.Version, v.AppliedAt.Format("2006-01-02 15:04:05"), v.Description)
+			}
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+
+	return cmd
+}
+
+func doJSON(req *http.Request, v interface{}) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return client.ErrorFromResponse(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,112 @@
+package namespace
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+)
+
+const FlagStrict = "strict"
+
+func NewSpiceDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spicedb",
+		Short: "Translate between SpiceDB schemas and the Ory Permission Language",
+	}
+	cmd.AddCommand(newSpiceDBImportCmd(), newSpiceDBExportCmd())
+	return cmd
+}
+
+func newSpiceDBImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <schema.zed>",
+		Short: "Translate a SpiceDB schema to the Ory Permission Language",
+		Long: `Translate a SpiceDB schema to an Ory Permission Language model, and print it.
+Unions, intersections, exclusions, and arrows are translated. Constructs without an equivalent, such as wildcards, caveats, and expiration, are dropped and reported.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := os.ReadFile(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read the SpiceDB schema: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			nn, untranslatable, errs := schema.ImportSpiceDB(string(input))
+			if len(errs) > 0 {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Could not translate the SpiceDB schema:")
+				for _, err := range errs {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+				return cmdx.FailSilently(cmd)
+			}
+			if err := reportUntranslatable(cmd, untranslatable); err != nil {
+				return err
+			}
+
+			translated := make([]*namespace.Namespace, len(nn))
+			for i := range nn {
+				translated[i] = &nn[i]
+			}
+			opl, err := schema.Print(translated)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(cmd.OutOrStdout(), opl)
+			return nil
+		},
+	}
+	cmd.Flags().Bool(FlagStrict, false, "Fail if a construct can not be translated.")
+
+	return cmd
+}
+
+func newSpiceDBExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <namespaces.keto.ts> [<file.ts> ...]",
+		Short: "Translate Ory Permission Language files to a SpiceDB schema",
+		Long: `Translate Ory Permission Language files to a SpiceDB schema, and print it.
+Names are converted to snake case, as SpiceDB only allows lowercase names. Constructs without an equivalent, such as conditions and cardinalities, are dropped and reported.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nn, errs := schema.ParseLocalFiles(args...)
+			if len(errs) > 0 {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Could not parse the Ory Permission Language files:")
+				for _, err := range errs {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+				return cmdx.FailSilently(cmd)
+			}
+
+			zed, untranslatable := schema.ExportSpiceDB(nn)
+			if err := reportUntranslatable(cmd, untranslatable); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(cmd.OutOrStdout(), zed)
+			return nil
+		},
+	}
+	cmd.Flags().Bool(FlagStrict, false, "Fail if a construct can not be translated.")
+
+	return cmd
+}
+
+// reportUntranslatable prints the untranslatable constructs to stderr, and
+// fails if the strict flag is set.
+func reportUntranslatable(cmd *cobra.Command, untranslatable []schema.Untranslatable) error {
+	for _, u := range untranslatable {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "untranslatable: %s\n", u)
+	}
+	strict, err := cmd.Flags().GetBool(FlagStrict)
+	if err != nil {
+		return err
+	}
+	if strict && len(untranslatable) > 0 {
+		return cmdx.FailSilently(cmd)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package namespace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpiceDBCmd(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: func() *cobra.Command {
+		root := &cobra.Command{Use: "keto"}
+		root.AddCommand(NewSpiceDBCmd())
+		return root
+	}}
+
+	writeFile := func(t *testing.T, name, content string) string {
+		fn := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(fn, []byte(content), fileMode))
+		return fn
+	}
+
+	zed := writeFile(t, "schema.zed", `definition user {}
+
+definition document {
+	relation viewer: user | user:*
+	relation owner: user
+
+	permission view = viewer + owner
+}
+`)
+
+	t.Run("case=import", func(t *testing.T) {
+		stdOut, stdErr, err := cmd.Exec(nil, "spicedb", "import", zed)
+		require.NoError(t, err)
+		assert.Equal(t, `class user implements Namespace {}
+
+class document implements Namespace {
+  related: {
+    viewer: user[]
+    owner: user[]
+  }
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewer.includes(ctx.subject) ||
+      this.related.owner.includes(ctx.subject),
+  }
+}
+`, stdOut)
+		assert.Contains(t, stdErr, "untranslatable: document.viewer: the wildcard user:* was dropped")
+
+		_, _, err = cmd.Exec(nil, "spicedb", "import", "--"+FlagStrict, zed)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+	})
+
+	t.Run("case=export", func(t *testing.T) {
+		opl := writeFile(t, "namespaces.keto.ts", `
+class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    viewers: User[]
+  }
+}`)
+		stdOut, stdErr, err := cmd.Exec(nil, "spicedb", "export", opl)
+		require.NoError(t, err)
+		assert.Equal(t, "definition user {}\n\ndefinition document {\n\trelation viewers: user\n}\n", stdOut)
+		assert.Contains(t, stdErr, `untranslatable: User: renamed to "user"`)
+	})
+
+	t.Run("case=fails on syntax errors", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "spicedb", "import", writeFile(t, "schema.zed", "definition user {"))
+		assert.Contains(t, stdErr, `1:18: expected "relation", "permission", or "}", got end of input`)
+	})
+}
@@ -0,0 +1,105 @@
+package opl
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/schema"
+)
+
+const (
+	FlagWrite = "write"
+	FlagCheck = "check"
+)
+
+func NewFmtCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fmt [<file.ts> ...]",
+		Short: "Format Ory Permission Language files",
+		Long: `Format Ory Permission Language files canonically.
+Without arguments, the input is read from stdin. The formatted files are written to stdout, unless --write or --check is set.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			write, err := cmd.Flags().GetBool(FlagWrite)
+			if err != nil {
+				return err
+			}
+			check, err := cmd.Flags().GetBool(FlagCheck)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				if write {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "--%s requires files as arguments\n", FlagWrite)
+					return cmdx.FailSilently(cmd)
+				}
+				raw, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read from stdin: %+v\n", err)
+					return cmdx.FailSilently(cmd)
+				}
+				return formatFile(cmd, "stdin", raw, false, check)
+			}
+
+			failed := false
+			for _, fn := range args {
+				raw, err := os.ReadFile(fn)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not read file \"%s\": %+v\n", fn, err)
+					return cmdx.FailSilently(cmd)
+				}
+				if err := formatFile(cmd, fn, raw, write, check); err != nil {
+					failed = true
+				}
+			}
+			if failed {
+				return cmdx.FailSilently(cmd)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP(FlagWrite, "w", false, "Write the formatted result back to the files.")
+	cmd.Flags().Bool(FlagCheck, false, "Only list the files that are not formatted, and fail if there are any.")
+
+	return cmd
+}
+
+func formatFile(cmd *cobra.Command, name string, raw []byte, write, check bool) error {
+	formatted, errs := schema.Format(string(raw))
+	if len(errs) > 0 {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not format \"%s\":\n", name)
+		for _, err := range errs {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+		}
+		return cmdx.FailSilently(cmd)
+	}
+
+	switch {
+	case check:
+		if formatted != string(raw) {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), name)
+			return cmdx.FailSilently(cmd)
+		}
+	case write:
+		if formatted == string(raw) {
+			return nil
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not stat file \"%s\": %+v\n", name, err)
+			return cmdx.FailSilently(cmd)
+		}
+		if err := os.WriteFile(name, []byte(formatted), info.Mode().Perm()); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not write file \"%s\": %+v\n", name, err)
+			return cmdx.FailSilently(cmd)
+		}
+	default:
+		_, _ = fmt.Fprint(cmd.OutOrStdout(), formatted)
+	}
+	return nil
+}
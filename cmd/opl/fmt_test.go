@@ -0,0 +1,66 @@
+package opl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFmtCmd(t *testing.T) {
+	const (
+		unformatted = "class User implements Namespace{}\nclass Group implements Namespace { related: { members: User[] } }"
+		formatted   = "class User implements Namespace {}\n\nclass Group implements Namespace {\n  related: {\n    members: User[]\n  }\n}\n"
+	)
+
+	cmd := cmdx.CommandExecuter{New: func() *cobra.Command {
+		root := &cobra.Command{}
+		RegisterCommandsRecursive(root)
+		return root
+	}}
+
+	writeFile := func(t *testing.T, content string) string {
+		fn := filepath.Join(t.TempDir(), "namespaces.keto.ts")
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0600))
+		return fn
+	}
+
+	t.Run("case=prints formatted file", func(t *testing.T) {
+		fn := writeFile(t, unformatted)
+		assert.Equal(t, formatted, cmd.ExecNoErr(t, "opl", "fmt", fn))
+	})
+
+	t.Run("case=formats stdin", func(t *testing.T) {
+		stdOut, stdErr, err := cmd.Exec(strings.NewReader(unformatted), "opl", "fmt")
+		require.NoError(t, err, stdErr)
+		assert.Equal(t, formatted, stdOut)
+	})
+
+	t.Run("case=writes files", func(t *testing.T) {
+		fn := writeFile(t, unformatted)
+		assert.Empty(t, cmd.ExecNoErr(t, "opl", "fmt", "--write", fn))
+		actual, err := os.ReadFile(fn)
+		require.NoError(t, err)
+		assert.Equal(t, formatted, string(actual))
+	})
+
+	t.Run("case=checks files", func(t *testing.T) {
+		good, bad := writeFile(t, formatted), writeFile(t, unformatted)
+		assert.Empty(t, cmd.ExecNoErr(t, "opl", "fmt", "--check", good))
+
+		stdOut, _, err := cmd.Exec(nil, "opl", "fmt", "--check", good, bad)
+		assert.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, bad+"\n", stdOut)
+	})
+
+	t.Run("case=fails on invalid files", func(t *testing.T) {
+		fn := writeFile(t, "class User implements Namespace {")
+		stdErr := cmd.ExecExpectedErr(t, "opl", "fmt", fn)
+		assert.Contains(t, stdErr, "Could not format")
+	})
+}
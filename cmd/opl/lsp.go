@@ -0,0 +1,23 @@
+package opl
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/schema/lsp"
+)
+
+func NewLSPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Start a language server for Ory Permission Language files",
+		Long: `Start a language server for Ory Permission Language files.
+The server communicates with the editor through the Language Server Protocol over stdin and stdout. It reports parse, type, and lint errors as diagnostics, and supports hover information, go to definition, and completion of namespaces and relations.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return lsp.NewServer().Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+	// Editors commonly pass --stdio to language servers.
+	cmd.Flags().Bool("stdio", true, "Communicate over stdin and stdout. This is the only supported transport.")
+	return cmd
+}
@@ -0,0 +1,19 @@
+package opl
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewOPLCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "opl",
+		Short: "Work with Ory Permission Language files",
+	}
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command) {
+	rootCmd := NewOPLCmd()
+	rootCmd.AddCommand(NewFmtCmd(), NewLSPCmd())
+
+	parent.AddCommand(rootCmd)
+}
@@ -10,8 +10,6 @@ import (
 
 	"github.com/ory/keto/ketoapi"
 
-	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
-
 	"github.com/spf13/cobra"
 
 	"github.com/ory/x/cmdx"
@@ -20,14 +18,22 @@ import (
 func newCreateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "create <relation-tuple.json> [<relation-tuple-dir>]",
-		Short: "Create relation tuples from JSON files",
-		Long: "Create relation tuples from JSON files.\n" +
+		Short: "Create relation tuples from JSON, CSV, or NDJSON files",
+		Long: "Create relation tuples from JSON, CSV, or NDJSON files.\n" +
 			"A directory will be traversed and all relation tuples will be created.\n" +
-			"Pass the special filename `-` to read from STD_IN.",
+			"Pass the special filename `-` to read from STD_IN.\n\n" +
+			"The relation tuples of JSON files are created in one transaction. CSV and NDJSON files are streamed and created in batches, " +
+			"rows that are rejected are reported and do not stop the import. CSV files need a header row. " +
+			"The columns, or the keys of the NDJSON objects, are mapped to the fields of the relation tuples with --columns. " +
+			"Without --columns, NDJSON lines are relation tuples as in the JSON format.",
+		Example: `keto relation-tuple create tuples.json
+keto relation-tuple create memberships.csv --columns object=group,subject_id=user --error-report rejected.ndjson
+cat tuples.ndjson | keto relation-tuple create - --input-format ndjson`,
 		Args: cobra.MinimumNArgs(1),
-		RunE: transactRelationTuples(rts.RelationTupleDelta_ACTION_INSERT),
+		RunE: createRelationTuples,
 	}
 	registerPackageFlags(cmd.Flags())
+	registerStreamFlags(cmd.Flags())
 
 	return cmd
 }
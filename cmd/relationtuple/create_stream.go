@@ -0,0 +1,572 @@
+package relationtuple
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/ketoapi"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const (
+	FlagInputFormat = "input-format"
+	FlagColumns     = "columns"
+	FlagBatchSize   = "batch-size"
+	FlagErrorReport = "error-report"
+
+	InputFormatJSON   = "json"
+	InputFormatCSV    = "csv"
+	InputFormatNDJSON = "ndjson"
+
+	// maxReportedRows is the number of rejected rows printed to stderr, all
+	// rejected rows are written to the error report.
+	maxReportedRows = 20
+)
+
+// recordFields are the fields of a relation tuple that can be mapped to the
+// columns of a CSV file or the keys of an NDJSON object. The subject is either
+// given as a string like in `keto relation-tuple parse`, or by its parts.
+var recordFields = []string{
+	"namespace", "object", "relation",
+	"subject", "subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation",
+}
+
+type (
+	// record is a row of a CSV or NDJSON file.
+	record struct {
+		file   int
+		source string
+		line   int
+		raw    string
+		tuple  *ketoapi.RelationTuple
+		err    error
+	}
+	rejectedRow struct {
+		file   int
+		Source string `json:"source"`
+		Line   int    `json:"line"`
+		Row    string `json:"row"`
+		Error  string `json:"error"`
+	}
+	rejectedRows  []*rejectedRow
+	importSummary struct {
+		Created  int `json:"created"`
+		Rejected int `json:"rejected"`
+	}
+	importer struct {
+		cmd       *cobra.Command
+		cl        rts.WriteServiceClient
+		batchSize int
+		batch     []*record
+		file      int
+		summary   importSummary
+		rejected  rejectedRows
+		progress  *progress
+	}
+	// progress renders a progress bar on stderr if it is a terminal.
+	progress struct {
+		w       io.Writer
+		read    *countingReader
+		total   int64
+		summary *importSummary
+	}
+	countingReader struct {
+		io.Reader
+		n int64
+	}
+)
+
+func registerStreamFlags(flags *pflag.FlagSet) {
+	flags.String(FlagInputFormat, "", fmt.Sprintf("The format of the files, one of %s, %s, or %s. Defaults to the file extension (.csv, .ndjson, or .jsonl), or %s.", InputFormatJSON, InputFormatCSV, InputFormatNDJSON, InputFormatJSON))
+	flags.StringToString(FlagColumns, nil, fmt.Sprintf("Map the fields of the relation tuples to the CSV columns or NDJSON keys, e.g. object=document_id. The fields are %s. Unmapped fields use a column of the same name.", strings.Join(recordFields, ", ")))
+	flags.Int(FlagBatchSize, 100, "The number of relation tuples of CSV and NDJSON files created per request.")
+	flags.String(FlagErrorReport, "", "Write the rejected rows of CSV and NDJSON files to this file as NDJSON.")
+}
+
+// inputFormat returns the format of the file, either as set by the flag or
+// as derived from the file extension.
+func inputFormat(cmd *cobra.Command, fn string) (string, error) {
+	if f := flagx.MustGetString(cmd, FlagInputFormat); f != "" {
+		switch f {
+		case InputFormatJSON, InputFormatCSV, InputFormatNDJSON:
+			return f, nil
+		}
+		return "", fmt.Errorf("unknown input format %q, expected one of %s, %s, or %s", f, InputFormatJSON, InputFormatCSV, InputFormatNDJSON)
+	}
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".csv":
+		return InputFormatCSV, nil
+	case ".ndjson", ".jsonl":
+		return InputFormatNDJSON, nil
+	}
+	return InputFormatJSON, nil
+}
+
+func createRelationTuples(cmd *cobra.Command, args []string) error {
+	stream := false
+	for _, fn := range args {
+		f, err := inputFormat(cmd, fn)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+			return cmdx.FailSilently(cmd)
+		}
+		stream = stream || f != InputFormatJSON
+	}
+	if !stream {
+		return transactRelationTuples(rts.RelationTupleDelta_ACTION_INSERT)(cmd, args)
+	}
+	return streamRelationTuples(cmd, args)
+}
+
+// streamRelationTuples creates the relation tuples of the files in batches.
+// The rows of a batch that was rejected are created one by one, so that only
+// the invalid rows are rejected.
+func streamRelationTuples(cmd *cobra.Command, args []string) error {
+	columns, err := cmd.Flags().GetStringToString(FlagColumns)
+	if err != nil {
+		return err
+	}
+	for field := range columns {
+		if !isRecordField(field) {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unknown field %q in --%s, expected one of %s.\n", field, FlagColumns, strings.Join(recordFields, ", "))
+			return cmdx.FailSilently(cmd)
+		}
+	}
+	batchSize := flagx.MustGetInt(cmd, FlagBatchSize)
+	if batchSize < 1 {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The --%s has to be at least 1.\n", FlagBatchSize)
+		return cmdx.FailSilently(cmd)
+	}
+
+	conn, err := client.GetWriteConn(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	im := &importer{cmd: cmd, cl: rts.NewWriteServiceClient(conn), batchSize: batchSize}
+	for i, fn := range args {
+		im.file = i
+		if err := im.importFile(fn, columns); errors.Is(err, cmdx.ErrNoPrintButFail) {
+			return err
+		} else if err != nil {
+			im.progress.done()
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+			return cmdx.FailSilently(cmd)
+		}
+	}
+	if err := im.flush(); err != nil {
+		im.progress.done()
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+	im.progress.done()
+
+	cmdx.PrintRow(cmd, &im.summary)
+	if len(im.rejected) == 0 {
+		return nil
+	}
+	im.rejected.sort()
+
+	if fn := flagx.MustGetString(cmd, FlagErrorReport); fn != "" {
+		if err := im.rejected.writeReport(fn); err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not write the error report %s: %s\n", fn, err)
+		}
+	}
+	im.rejected.print(cmd.ErrOrStderr())
+	return cmdx.FailSilently(cmd)
+}
+
+func (im *importer) importFile(fn string, columns map[string]string) error {
+	format, err := inputFormat(im.cmd, fn)
+	if err != nil {
+		return err
+	}
+	if format == InputFormatJSON {
+		tuples, err := readTuplesFromArg(im.cmd, fn)
+		if err != nil {
+			return err
+		}
+		for i, t := range tuples {
+			if err := im.add(&record{file: im.file, source: fn, line: i + 1, raw: t.String(), tuple: t}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		in   io.Reader = im.cmd.InOrStdin()
+		size int64
+	)
+	if fn == "-" {
+		fn = "stdin"
+	} else {
+		f, err := os.Open(fn)
+		if err != nil {
+			return fmt.Errorf("could not open file %s: %w", fn, err)
+		}
+		defer f.Close()
+		if stat, err := f.Stat(); err == nil {
+			size = stat.Size()
+		}
+		in = f
+	}
+	cr := &countingReader{Reader: in}
+	im.progress = newProgress(im.cmd.ErrOrStderr(), cr, size, &im.summary)
+
+	add := func(r *record) error {
+		r.file = im.file
+		return im.add(r)
+	}
+	if format == InputFormatCSV {
+		return readCSV(cr, fn, columns, add)
+	}
+	return readNDJSON(cr, fn, columns, add)
+}
+
+func (im *importer) add(r *record) error {
+	if r.err != nil {
+		im.reject(r, r.err.Error())
+		return nil
+	}
+	im.batch = append(im.batch, r)
+	if len(im.batch) < im.batchSize {
+		return nil
+	}
+	return im.flush()
+}
+
+func (im *importer) flush() error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+	defer func() {
+		im.batch = im.batch[:0]
+		im.progress.render()
+	}()
+
+	if err := im.transact(im.batch...); err == nil {
+		im.summary.Created += len(im.batch)
+		return nil
+	} else if fatal(err) {
+		return fmt.Errorf("could not create the relation tuples: %w", err)
+	}
+
+	for _, r := range im.batch {
+		err := im.transact(r)
+		switch {
+		case err == nil:
+			im.summary.Created++
+		case fatal(err):
+			return fmt.Errorf("could not create the relation tuples: %w", err)
+		default:
+			im.reject(r, status.Convert(err).Message())
+		}
+	}
+	return nil
+}
+
+func (im *importer) transact(rs ...*record) error {
+	deltas := make([]*rts.RelationTupleDelta, len(rs))
+	for i, r := range rs {
+		deltas[i] = &rts.RelationTupleDelta{
+			Action:        rts.RelationTupleDelta_ACTION_INSERT,
+			RelationTuple: r.tuple.ToProto(),
+		}
+	}
+	_, err := im.cl.TransactRelationTuples(im.cmd.Context(), &rts.TransactRelationTuplesRequest{RelationTupleDeltas: deltas})
+	return err
+}
+
+func (im *importer) reject(r *record, msg string) {
+	im.summary.Rejected++
+	im.rejected = append(im.rejected, &rejectedRow{file: r.file, Source: r.source, Line: r.line, Row: r.raw, Error: msg})
+}
+
+// fatal returns whether the error would reject every row, so that the import
+// is aborted.
+func fatal(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Unauthenticated, codes.PermissionDenied, codes.ResourceExhausted, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+func isRecordField(field string) bool {
+	for _, f := range recordFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func columnOf(columns map[string]string, field string) string {
+	if c, ok := columns[field]; ok {
+		return c
+	}
+	return field
+}
+
+// readCSV reads the rows of a CSV file with a header row.
+func readCSV(in io.Reader, fn string, columns map[string]string, add func(*record) error) error {
+	cr := csv.NewReader(in)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not read the header of %s: %w", fn, err)
+	}
+	index := make(map[string]int, len(recordFields))
+	for i, name := range header {
+		for _, field := range recordFields {
+			if columnOf(columns, field) == strings.TrimSpace(name) {
+				index[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"namespace", "object", "relation"} {
+		if _, ok := index[field]; !ok {
+			return fmt.Errorf("the header of %s has no column %q for the %s", fn, columnOf(columns, field), field)
+		}
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		r := &record{source: fn}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			r.line, r.err = parseErr.Line, parseErr.Err
+			if err := add(r); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return fmt.Errorf("could not read %s: %w", fn, err)
+		}
+
+		r.line, _ = cr.FieldPos(0)
+		r.raw = strings.Join(row, ",")
+		fields := make(map[string]string, len(index))
+		for field, i := range index {
+			if i < len(row) {
+				fields[field] = strings.TrimSpace(row[i])
+			}
+		}
+		r.tuple, r.err = tupleFromFields(fields)
+		if err := add(r); err != nil {
+			return err
+		}
+	}
+}
+
+// readNDJSON reads one JSON object per line. Without column mapping, the
+// objects are relation tuples as in the JSON format.
+func readNDJSON(in io.Reader, fn string, columns map[string]string, add func(*record) error) error {
+	s := bufio.NewScanner(in)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; s.Scan(); line++ {
+		raw := bytes.TrimSpace(s.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		r := &record{source: fn, line: line, raw: string(raw)}
+
+		if len(columns) == 0 {
+			r.tuple = &ketoapi.RelationTuple{}
+			if err := json.Unmarshal(raw, r.tuple); err != nil {
+				r.err = err
+			} else if r.tuple.SubjectID == nil && r.tuple.SubjectSet == nil {
+				r.err = ketoapi.ErrNilSubject
+			}
+		} else {
+			var obj map[string]interface{}
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			if err := dec.Decode(&obj); err != nil {
+				r.err = err
+			} else {
+				fields := make(map[string]string, len(recordFields))
+				for _, field := range recordFields {
+					if v, ok := obj[columnOf(columns, field)]; ok && v != nil {
+						fields[field] = fmt.Sprint(v)
+					}
+				}
+				r.tuple, r.err = tupleFromFields(fields)
+			}
+		}
+
+		if err := add(r); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("could not read %s: %w", fn, err)
+	}
+	return nil
+}
+
+func tupleFromFields(fields map[string]string) (*ketoapi.RelationTuple, error) {
+	t := &ketoapi.RelationTuple{
+		Namespace: fields["namespace"],
+		Object:    fields["object"],
+		Relation:  fields["relation"],
+	}
+	for _, field := range []string{"namespace", "object", "relation"} {
+		if fields[field] == "" {
+			return nil, fmt.Errorf("the %s is empty", field)
+		}
+	}
+
+	setParts := fields["subject_set_namespace"] != "" || fields["subject_set_object"] != "" || fields["subject_set_relation"] != ""
+	switch {
+	case fields["subject"] != "":
+		if fields["subject_id"] != "" || setParts {
+			return nil, ketoapi.ErrDuplicateSubject
+		}
+		if subject := strings.Trim(fields["subject"], "()"); strings.Contains(subject, "#") {
+			set, err := (&ketoapi.SubjectSet{}).FromString(subject)
+			if err != nil {
+				return nil, err
+			}
+			t.SubjectSet = set
+		} else {
+			t.SubjectID = &subject
+		}
+	case fields["subject_id"] != "":
+		if setParts {
+			return nil, ketoapi.ErrDuplicateSubject
+		}
+		id := fields["subject_id"]
+		t.SubjectID = &id
+	case setParts:
+		t.SubjectSet = &ketoapi.SubjectSet{
+			Namespace: fields["subject_set_namespace"],
+			Object:    fields["subject_set_object"],
+			Relation:  fields["subject_set_relation"],
+		}
+		if t.SubjectSet.Namespace == "" || t.SubjectSet.Object == "" || t.SubjectSet.Relation == "" {
+			return nil, errors.New("the subject set has to have a namespace, object, and relation")
+		}
+	default:
+		return nil, ketoapi.ErrNilSubject
+	}
+	return t, nil
+}
+
+func (s *importSummary) Header() []string {
+	return []string{"CREATED", "REJECTED"}
+}
+
+func (s *importSummary) Columns() []string {
+	return []string{strconv.Itoa(s.Created), strconv.Itoa(s.Rejected)}
+}
+
+func (s *importSummary) Interface() interface{} {
+	return s
+}
+
+// sort orders the rows as in the files, because rows that are rejected by
+// the server are only known once their batch was created.
+func (rr rejectedRows) sort() {
+	sort.SliceStable(rr, func(i, j int) bool {
+		if rr[i].file != rr[j].file {
+			return rr[i].file < rr[j].file
+		}
+		return rr[i].Line < rr[j].Line
+	})
+}
+
+func (rr rejectedRows) print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Rejected %d rows:\n", len(rr))
+	for i, r := range rr {
+		if i == maxReportedRows {
+			_, _ = fmt.Fprintf(w, "  ... and %d more, use --%s to write all rejected rows to a file.\n", len(rr)-maxReportedRows, FlagErrorReport)
+			break
+		}
+		_, _ = fmt.Fprintf(w, "  %s:%d: %s\n    %s\n", r.Source, r.Line, r.Error, r.Row)
+	}
+}
+
+func (rr rejectedRows) writeReport(fn string) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, r := range rr {
+		if err := enc.Encode(r); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func newProgress(w io.Writer, read *countingReader, total int64, summary *importSummary) *progress {
+	if f, ok := w.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+		return nil
+	}
+	return &progress{w: w, read: read, total: total, summary: summary}
+}
+
+func (p *progress) render() {
+	if p == nil {
+		return
+	}
+	if p.total <= 0 {
+		_, _ = fmt.Fprintf(p.w, "\r%d created, %d rejected", p.summary.Created, p.summary.Rejected)
+		return
+	}
+
+	const width = 30
+	done := float64(p.read.n) / float64(p.total)
+	if done > 1 {
+		done = 1
+	}
+	filled := int(done * width)
+	_, _ = fmt.Fprintf(p.w, "\r[%s%s] %3.0f%% %d created, %d rejected",
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled), done*100, p.summary.Created, p.summary.Rejected)
+}
+
+func (p *progress) done() {
+	if p == nil {
+		return
+	}
+	p.render()
+	_, _ = fmt.Fprintln(p.w)
+}
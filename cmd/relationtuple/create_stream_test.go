@@ -0,0 +1,151 @@
+package relationtuple
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+)
+
+func TestCreateStream(t *testing.T) {
+	ctx := context.Background()
+	ts := client.NewTestServer(t, client.WriteServer, []*namespace.Namespace{{Name: "docs"}, {Name: "groups"}}, newCreateCmd)
+	defer ts.Shutdown(t)
+
+	count := func(t *testing.T, nspace string) int {
+		n, _, err := ts.Reg.RelationTupleManager().CountRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &nspace}, false)
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("case=csv with column mapping", func(t *testing.T) {
+		dir := t.TempDir()
+		fn := filepath.Join(dir, "memberships.csv")
+		require.NoError(t, os.WriteFile(fn, []byte(`type,id,rel,who
+docs,readme,view,alice
+docs,readme,view,groups:dev#member
+unknown,readme,view,bob
+docs,readme,,carol
+docs,guide,view,"(groups:dev#member)"
+`), 0600))
+		report := filepath.Join(dir, "rejected.ndjson")
+
+		stdOut, stdErr, err := ts.Cmd.Exec(nil, fn,
+			"--"+FlagColumns, "namespace=type,object=id,relation=rel,subject=who",
+			"--"+FlagBatchSize, "2",
+			"--"+FlagErrorReport, report,
+			"--"+cmdx.FlagFormat, string(cmdx.FormatJSON),
+		)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+
+		var summary importSummary
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &summary))
+		assert.Equal(t, importSummary{Created: 3, Rejected: 2}, summary)
+		assert.Equal(t, 3, count(t, "docs"))
+		assert.Contains(t, stdErr, "Rejected 2 rows:")
+		assert.Contains(t, stdErr, fn+":4:")
+		assert.Contains(t, stdErr, fn+":5: the relation is empty")
+
+		raw, err := os.ReadFile(report)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+		require.Len(t, lines, 2)
+		var rejected []rejectedRow
+		for _, l := range lines {
+			var r rejectedRow
+			require.NoError(t, json.Unmarshal([]byte(l), &r))
+			rejected = append(rejected, r)
+		}
+		assert.Equal(t, 4, rejected[0].Line, "the rows rejected by the server are sorted by line")
+		assert.Equal(t, "unknown,readme,view,bob", rejected[0].Row)
+		assert.Equal(t, 5, rejected[1].Line)
+	})
+
+	t.Run("case=ndjson from stdin", func(t *testing.T) {
+		stdin := strings.NewReader(`{"namespace":"groups","object":"dev","relation":"member","subject_id":"alice"}
+
+{"namespace":"groups","object":"dev","relation":"member","subject_set":{"namespace":"groups","object":"ops","relation":"member"}}
+`)
+		stdOut, stdErr, err := ts.Cmd.Exec(stdin, "-", "--"+FlagInputFormat, InputFormatNDJSON)
+		require.NoError(t, err, stdErr)
+		assert.Regexp(t, `CREATED\s+2\s+REJECTED\s+0`, stdOut)
+		assert.Equal(t, 2, count(t, "groups"))
+	})
+
+	t.Run("case=ndjson with column mapping", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "tuples.jsonl")
+		require.NoError(t, os.WriteFile(fn, []byte(`{"ns":"groups","obj":"qa","rel":"member","user":42}`+"\n"), 0600))
+
+		_, stdErr, err := ts.Cmd.Exec(nil, fn, "--"+FlagColumns, "namespace=ns,object=obj,relation=rel,subject_id=user")
+		require.NoError(t, err, stdErr)
+
+		res, _, err := ts.Reg.RelationTupleManager().GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("groups")})
+		require.NoError(t, err)
+		assert.Len(t, res, 3)
+	})
+
+	t.Run("case=invalid flags and headers", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "tuples.csv")
+		require.NoError(t, os.WriteFile(fn, []byte("namespace,object\ndocs,readme\n"), 0600))
+
+		stdErr := ts.Cmd.ExecExpectedErr(t, fn, "--"+FlagColumns, "owner=user")
+		assert.Contains(t, stdErr, `Unknown field "owner"`)
+
+		stdErr = ts.Cmd.ExecExpectedErr(t, fn)
+		assert.Contains(t, stdErr, `no column "relation"`)
+
+		stdErr = ts.Cmd.ExecExpectedErr(t, fn, "--"+FlagInputFormat, "xml")
+		assert.Contains(t, stdErr, `unknown input format "xml"`)
+	})
+}
+
+func TestTupleFromFields(t *testing.T) {
+	base := map[string]string{"namespace": "n", "object": "o", "relation": "r"}
+	with := func(kv ...string) map[string]string {
+		m := map[string]string{}
+		for k, v := range base {
+			m[k] = v
+		}
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+
+	for _, tc := range []struct {
+		name   string
+		fields map[string]string
+		tuple  string
+		err    bool
+	}{
+		{name: "subject id", fields: with("subject", "alice"), tuple: "n:o#r@alice"},
+		{name: "subject set string", fields: with("subject", "g:dev#member"), tuple: "n:o#r@(g:dev#member)"},
+		{name: "subject id column", fields: with("subject_id", "alice"), tuple: "n:o#r@alice"},
+		{name: "subject set columns", fields: with("subject_set_namespace", "g", "subject_set_object", "dev", "subject_set_relation", "member"), tuple: "n:o#r@(g:dev#member)"},
+		{name: "no subject", fields: with(), err: true},
+		{name: "two subjects", fields: with("subject", "alice", "subject_id", "bob"), err: true},
+		{name: "partial subject set", fields: with("subject_set_namespace", "g"), err: true},
+		{name: "empty object", fields: with("object", "", "subject", "alice"), err: true},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			tuple, err := tupleFromFields(tc.fields)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.tuple, tuple.String())
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package relationtuple
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/ory/keto/internal/x"
 
@@ -14,10 +16,13 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
 )
 
 const (
-	FlagForce = "force"
+	FlagForce   = "force"
+	FlagBatched = "batched"
 )
 
 func newDeleteAllCmd() *cobra.Command {
@@ -25,13 +30,16 @@ func newDeleteAllCmd() *cobra.Command {
 		Use:   "delete-all",
 		Short: "Delete ALL relation tuples matching the relation query.",
 		Long: "Delete all relation tuples matching the relation query.\n" +
-			"It is recommended to first run the command without the `--force` flag to verify that the operation is safe.",
+			"It is recommended to first run the command without the `--force` flag to verify that the operation is safe.\n" +
+			"Use `--batched` to delete large amounts of relation tuples in batches. If interrupted, the deletion can be resumed with the printed page token.",
 		Args: cobra.ExactArgs(0),
 		RunE: deleteRelationTuplesFromQuery,
 	}
 	registerPackageFlags(cmd.Flags())
-	registerRelationTupleFlags(cmd.Flags())
+	registerRelationTupleFlags(cmd)
 	cmd.Flags().Bool(FlagForce, false, "Force the deletion of relation tuples")
+	cmd.Flags().Bool(FlagBatched, false, "Delete the relation tuples in batches through the REST API")
+	cmd.Flags().String(FlagPageToken, "", "Resume a batched deletion with the page token it printed last")
 
 	return cmd
 }
@@ -46,6 +54,10 @@ func deleteRelationTuplesFromQuery(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("usage of --%s is not supported anymore, use --%s or --%s respectively", FlagSubject, FlagSubjectID, FlagSubjectSet)
 	}
 
+	if flagx.MustGetBool(cmd, FlagBatched) {
+		return deleteRelationTuplesInBatches(cmd)
+	}
+
 	query, err := readQueryFromFlags(cmd)
 	if err != nil {
 		return err
@@ -67,3 +79,55 @@ func deleteRelationTuplesFromQuery(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func deleteRelationTuplesInBatches(cmd *cobra.Command) error {
+	query, err := readAPIQueryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	u := client.GetWriteURL(cmd)
+	u.Path = relationtuple.BulkDeleteRoute
+	params := query.ToURLQuery()
+	pageToken := flagx.MustGetString(cmd, FlagPageToken)
+
+	for {
+		params.Set("page_token", pageToken)
+		u.RawQuery = params.Encode()
+
+		req, err := client.NewRequest(cmd, http.MethodPost, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not make request: %s\n", err)
+			return failWithPageToken(cmd, pageToken)
+		}
+
+		var res ketoapi.BulkDeleteResponse
+		if resp.StatusCode != http.StatusOK {
+			err = client.ErrorFromResponse(resp)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&res)
+		}
+		_ = resp.Body.Close()
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not delete relation tuples: %s\n", err)
+			return failWithPageToken(cmd, pageToken)
+		}
+
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Deleted %d relation tuples so far.\n", res.TotalDeleted)
+		if res.NextPageToken == "" {
+			return nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func failWithPageToken(cmd *cobra.Command, pageToken string) error {
+	if pageToken != "" {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Resume the deletion with --%s %s\n", FlagPageToken, pageToken)
+	}
+	return cmdx.FailSilently(cmd)
+}
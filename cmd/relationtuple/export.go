@@ -0,0 +1,297 @@
+package relationtuple
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/ketoapi"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const (
+	FlagExportFormat = "format"
+	FlagResume       = "resume"
+
+	ExportFormatSnapshot = "snapshot"
+
+	exportStateSuffix = ".export-state"
+)
+
+// csvHeader are the columns of exported CSV files, which can be imported
+// again with `keto relation-tuple create` without column mapping.
+var csvHeader = []string{"namespace", "object", "relation", "subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation"}
+
+type (
+	// exportState is written next to the export file after every page, so
+	// that an interrupted export can be resumed.
+	exportState struct {
+		Format        string                 `json:"format"`
+		Query         *ketoapi.RelationQuery `json:"query"`
+		NextPageToken string                 `json:"next_page_token"`
+		// Offset is the size of the export file after the last complete
+		// page.
+		Offset int64 `json:"offset"`
+		Count  int   `json:"count"`
+	}
+	tupleWriter interface {
+		begin() error
+		write(t *ketoapi.RelationTuple, first bool) error
+		end() error
+	}
+	ndjsonWriter struct{ w io.Writer }
+	jsonWriter   struct{ w io.Writer }
+	csvWriter    struct{ w *csv.Writer }
+)
+
+func newTupleWriter(format string, w io.Writer) (tupleWriter, error) {
+	switch format {
+	case InputFormatNDJSON:
+		return &ndjsonWriter{w: w}, nil
+	case InputFormatJSON:
+		return &jsonWriter{w: w}, nil
+	case InputFormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, expected one of %s, %s, %s, or %s", format, ExportFormatSnapshot, InputFormatNDJSON, InputFormatCSV, InputFormatJSON)
+}
+
+// exportRelationTuples pages through the relation tuples matching the query
+// and writes them to the file. The relation tuples are not read from one
+// consistent snapshot, use the snapshot format for that.
+func exportRelationTuples(cmd *cobra.Command, fn, format string) error {
+	var out io.Writer = cmd.OutOrStdout()
+	buf := bufio.NewWriter(out)
+	tw, err := newTupleWriter(format, buf)
+	if err != nil {
+		return err
+	}
+
+	query, err := readAPIQueryFromFlags(cmd)
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the query: %s\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+	resume := flagx.MustGetBool(cmd, FlagResume)
+	if resume && fn == "-" {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Only exports to a file can be resumed.\n")
+		return cmdx.FailSilently(cmd)
+	}
+
+	pageSize, err := cmd.Flags().GetInt32(FlagPageSize)
+	if err != nil {
+		return err
+	}
+
+	state := &exportState{Format: format, Query: query}
+	if fn != "-" {
+		f, err := openExportFile(fn, state, resume)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+			return cmdx.FailSilently(cmd)
+		}
+		defer f.Close()
+		out = f
+		buf.Reset(f)
+	}
+
+	conn, err := client.GetReadConn(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cl := rts.NewReadServiceClient(conn)
+
+	if state.Offset == 0 {
+		if err := tw.begin(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		resp, err := cl.ListRelationTuples(cmd.Context(), &rts.ListRelationTuplesRequest{
+			RelationQuery: query.ToProto(),
+			PageSize:      pageSize,
+			PageToken:     state.NextPageToken,
+		})
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not export the relation tuples: %s\n", err)
+			if fn != "-" {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Run the command again with --%s to continue after the last complete page.\n", FlagResume)
+			}
+			return cmdx.FailSilently(cmd)
+		}
+
+		for _, rt := range resp.RelationTuples {
+			t, err := (&ketoapi.RelationTuple{}).FromDataProvider(rt)
+			if err != nil {
+				return err
+			}
+			if err := tw.write(t, state.Count == 0); err != nil {
+				return err
+			}
+			state.Count++
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+
+		state.NextPageToken = resp.NextPageToken
+		if fn != "-" {
+			if err := checkpoint(fn, buf, out.(*os.File), state); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not save the export state: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+		}
+	}
+
+	if err := tw.end(); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not write the export: %s\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+	if fn != "-" {
+		if err := os.Remove(fn + exportStateSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not remove the export state: %s\n", err)
+		}
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d relation tuples.\n", state.Count)
+	return nil
+}
+
+// openExportFile creates the export file, or opens it at the end of the last
+// complete page if the export is resumed.
+func openExportFile(fn string, state *exportState, resume bool) (*os.File, error) {
+	if !resume {
+		_ = os.Remove(fn + exportStateSuffix)
+		f, err := os.Create(fn)
+		if err != nil {
+			return nil, fmt.Errorf("could not create file %s: %w", fn, err)
+		}
+		return f, nil
+	}
+
+	raw, err := os.ReadFile(fn + exportStateSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("there is no interrupted export of %s to resume", fn)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read the export state: %w", err)
+	}
+	var saved exportState
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return nil, fmt.Errorf("could not decode the export state: %w", err)
+	}
+	if saved.Format != state.Format || !reflect.DeepEqual(saved.Query, state.Query) {
+		return nil, fmt.Errorf("the export of %s was started with a different format or query", fn)
+	}
+
+	f, err := os.OpenFile(fn, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file %s: %w", fn, err)
+	}
+	// Drop the relation tuples written after the last complete page.
+	if err := f.Truncate(saved.Offset); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("could not truncate file %s: %w", fn, err)
+	}
+	if _, err := f.Seek(saved.Offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("could not seek file %s: %w", fn, err)
+	}
+	*state = saved
+	return f, nil
+}
+
+func checkpoint(fn string, buf *bufio.Writer, f *os.File, state *exportState) error {
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	state.Offset = offset
+
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := fn + exportStateSuffix + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn+exportStateSuffix)
+}
+
+func (w *ndjsonWriter) begin() error { return nil }
+
+func (w *ndjsonWriter) write(t *ketoapi.RelationTuple, _ bool) error {
+	return json.NewEncoder(w.w).Encode(t)
+}
+
+func (w *ndjsonWriter) end() error { return nil }
+
+func (w *jsonWriter) begin() error {
+	_, err := io.WriteString(w.w, "[")
+	return err
+}
+
+func (w *jsonWriter) write(t *ketoapi.RelationTuple, first bool) error {
+	if !first {
+		if _, err := io.WriteString(w.w, ","); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w.w, "\n"); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(raw)
+	return err
+}
+
+func (w *jsonWriter) end() error {
+	_, err := io.WriteString(w.w, "\n]\n")
+	return err
+}
+
+func (w *csvWriter) begin() error {
+	if err := w.w.Write(csvHeader); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) write(t *ketoapi.RelationTuple, _ bool) error {
+	row := []string{t.Namespace, t.Object, t.Relation, "", "", "", ""}
+	if t.SubjectID != nil {
+		row[3] = *t.SubjectID
+	} else if t.SubjectSet != nil {
+		row[4], row[5], row[6] = t.SubjectSet.Namespace, t.SubjectSet.Object, t.SubjectSet.Relation
+	}
+	if err := w.w.Write(row); err != nil {
+		return err
+	}
+	// The rows are flushed to the buffered writer, so that checkpoints
+	// include all rows of the page.
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) end() error { return nil }
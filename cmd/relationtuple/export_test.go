@@ -0,0 +1,134 @@
+package relationtuple
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/pointerx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestExport(t *testing.T) {
+	ts := client.NewTestServer(t, client.ReadServer, []*namespace.Namespace{{Name: "doc"}, {Name: "group"}}, newExportCmd)
+	defer ts.Shutdown(t)
+
+	tuples := []*ketoapi.RelationTuple{
+		{Namespace: "doc", Object: "a", Relation: "viewer", SubjectID: pointerx.String("alice")},
+		{Namespace: "doc", Object: "b", Relation: "viewer", SubjectID: pointerx.String("bob")},
+		{Namespace: "doc", Object: "c", Relation: "viewer", SubjectSet: &ketoapi.SubjectSet{Namespace: "group", Object: "dev", Relation: "member"}},
+		{Namespace: "doc", Object: "a", Relation: "owner", SubjectID: pointerx.String("carol")},
+		{Namespace: "group", Object: "dev", Relation: "member", SubjectID: pointerx.String("dave")},
+	}
+	relationtuple.MapAndWriteTuples(t, ts.Reg.(*driver.RegistryDefault), tuples...)
+	viewers := tuples[:3]
+
+	filter := []string{"--" + FlagNamespace, "doc", "--" + FlagRelation, "viewer", "--" + FlagPageSize, "2"}
+	export := func(t *testing.T, args ...string) (string, string) {
+		stdOut, stdErr, err := ts.Cmd.Exec(nil, append(args, filter...)...)
+		require.NoError(t, err, stdErr)
+		return stdOut, stdErr
+	}
+
+	t.Run("format=ndjson", func(t *testing.T) {
+		stdOut, stdErr := export(t, "-", "--"+FlagExportFormat, InputFormatNDJSON)
+		assert.Contains(t, stdErr, "Exported 3 relation tuples.")
+
+		lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+		actual := make([]*ketoapi.RelationTuple, len(lines))
+		for i, l := range lines {
+			actual[i] = &ketoapi.RelationTuple{}
+			require.NoError(t, json.Unmarshal([]byte(l), actual[i]))
+		}
+		assert.ElementsMatch(t, viewers, actual)
+	})
+
+	t.Run("format=json", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "viewers.json")
+		export(t, fn, "--"+FlagExportFormat, InputFormatJSON)
+
+		raw, err := os.ReadFile(fn)
+		require.NoError(t, err)
+		var actual []*ketoapi.RelationTuple
+		require.NoError(t, json.Unmarshal(raw, &actual))
+		assert.ElementsMatch(t, viewers, actual)
+		assert.NoFileExists(t, fn+exportStateSuffix)
+	})
+
+	t.Run("format=csv", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "viewers.csv")
+		export(t, fn, "--"+FlagExportFormat, InputFormatCSV)
+
+		raw, err := os.ReadFile(fn)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+		require.Len(t, lines, 4)
+		assert.Equal(t, strings.Join(csvHeader, ","), lines[0])
+		assert.ElementsMatch(t, []string{
+			"doc,a,viewer,alice,,,",
+			"doc,b,viewer,bob,,,",
+			"doc,c,viewer,,group,dev,member",
+		}, lines[1:])
+	})
+
+	t.Run("case=resumes after the last complete page", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "viewers.ndjson")
+		export(t, fn, "--"+FlagExportFormat, InputFormatNDJSON)
+		complete, err := os.ReadFile(fn)
+		require.NoError(t, err)
+
+		// Simulate an export that was interrupted in the middle of the
+		// first page.
+		require.NoError(t, os.WriteFile(fn, []byte(`{"namespace":"doc","obj`), 0600))
+		query := &ketoapi.RelationQuery{Namespace: pointerx.String("doc"), Relation: pointerx.String("viewer")}
+		state, err := json.Marshal(&exportState{Format: InputFormatNDJSON, Query: query})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(fn+exportStateSuffix, state, 0600))
+
+		_, stdErr := export(t, fn, "--"+FlagExportFormat, InputFormatNDJSON, "--"+FlagResume)
+		assert.Contains(t, stdErr, "Exported 3 relation tuples.")
+
+		resumed, err := os.ReadFile(fn)
+		require.NoError(t, err)
+		assert.Equal(t, string(complete), string(resumed))
+		assert.NoFileExists(t, fn+exportStateSuffix)
+	})
+
+	t.Run("case=resume fails with a different query", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "viewers.ndjson")
+		state, err := json.Marshal(&exportState{Format: InputFormatNDJSON, Query: &ketoapi.RelationQuery{Namespace: pointerx.String("group")}})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(fn, nil, 0600))
+		require.NoError(t, os.WriteFile(fn+exportStateSuffix, state, 0600))
+
+		stdErr := ts.Cmd.ExecExpectedErr(t, append([]string{fn, "--" + FlagExportFormat, InputFormatNDJSON, "--" + FlagResume}, filter...)...)
+		assert.Contains(t, stdErr, "different format or query")
+	})
+
+	t.Run("case=resume fails without an interrupted export", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "viewers.ndjson")
+		stdErr := ts.Cmd.ExecExpectedErr(t, fn, "--"+FlagExportFormat, InputFormatNDJSON, "--"+FlagResume)
+		assert.Contains(t, stdErr, "no interrupted export")
+	})
+
+	t.Run("case=filters are not supported by snapshots", func(t *testing.T) {
+		stdErr := ts.Cmd.ExecExpectedErr(t, "-", "--"+FlagNamespace, "doc")
+		assert.Contains(t, stdErr, "--namespace flag is not supported by the snapshot format")
+	})
+
+	t.Run("case=unknown format", func(t *testing.T) {
+		_, _, err := ts.Cmd.Exec(nil, "-", "--"+FlagExportFormat, "xml")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, cmdx.ErrNoPrintButFail)
+	})
+}
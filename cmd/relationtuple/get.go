@@ -11,8 +11,6 @@ import (
 
 	"github.com/ory/x/flagx"
 
-	"github.com/spf13/pflag"
-
 	"github.com/ory/x/cmdx"
 
 	"github.com/spf13/cobra"
@@ -31,7 +29,8 @@ const (
 	FlagPageToken  = "page-token"
 )
 
-func registerRelationTupleFlags(flags *pflag.FlagSet) {
+func registerRelationTupleFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
 	flags.String(FlagNamespace, "", "Set the requested namespace")
 	flags.String(FlagSubjectID, "", "Set the requested subject ID")
 	flags.String(FlagSubjectSet, "", `Set the requested subject set; format: "namespace:object#relation"`)
@@ -42,9 +41,24 @@ func registerRelationTupleFlags(flags *pflag.FlagSet) {
 	if err := flags.MarkHidden(FlagSubject); err != nil {
 		panic(err.Error())
 	}
+
+	if err := cmd.RegisterFlagCompletionFunc(FlagNamespace, client.CompleteNamespaces); err != nil {
+		panic(err.Error())
+	}
+	if err := cmd.RegisterFlagCompletionFunc(FlagRelation, client.CompleteRelations(client.NamespaceFromFlag(FlagNamespace))); err != nil {
+		panic(err.Error())
+	}
 }
 
 func readQueryFromFlags(cmd *cobra.Command) (*rts.RelationQuery, error) {
+	query, err := readAPIQueryFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return query.ToProto(), nil
+}
+
+func readAPIQueryFromFlags(cmd *cobra.Command) (*ketoapi.RelationQuery, error) {
 	getStringPtr := func(flagName string) *string {
 		if f := cmd.Flags().Lookup(flagName); f.Changed {
 			return x.Ptr(f.Value.String())
@@ -66,7 +80,7 @@ func readQueryFromFlags(cmd *cobra.Command) (*rts.RelationQuery, error) {
 		query.SubjectSet = s
 	}
 
-	return query.ToProto(), nil
+	return query, nil
 }
 
 func newGetCmd() *cobra.Command {
@@ -85,7 +99,7 @@ func newGetCmd() *cobra.Command {
 	}
 
 	registerPackageFlags(cmd.Flags())
-	registerRelationTupleFlags(cmd.Flags())
+	registerRelationTupleFlags(cmd)
 
 	cmd.Flags().StringVar(&pageToken, FlagPageToken, "", "page token acquired from a previous response")
 	cmd.Flags().Int32Var(&pageSize, FlagPageSize, 100, "maximum number of items to return")
@@ -0,0 +1,154 @@
+package relationtuple
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+const FlagIncludeNamespaces = "include-namespaces"
+
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <snapshot-file>",
+		Short: "Export relation tuples into a snapshot, NDJSON, CSV, or JSON file",
+		Long: "Export all relation tuples into a snapshot file.\n" +
+			"All relation tuples are read from the same consistent snapshot of the database.\n" +
+			"Pass the special filename `-` to write to STD_OUT.\n\n" +
+			"With --format ndjson, csv, or json, the relation tuples matching the query flags are paged through with the read API instead, " +
+			"and can be imported again with `keto relation-tuple create`. Such an export can be resumed with --resume if it was interrupted.",
+		Example: `keto relation-tuple export snapshot.ndjson --include-namespaces
+keto relation-tuple export viewers.csv --format csv --namespace doc --relation viewer
+keto relation-tuple export viewers.csv --format csv --namespace doc --relation viewer --resume`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format := flagx.MustGetString(cmd, FlagExportFormat)
+			if format != ExportFormatSnapshot {
+				if cmd.Flags().Changed(FlagIncludeNamespaces) {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The --%s flag is only supported by the %s format.\n", FlagIncludeNamespaces, ExportFormatSnapshot)
+					return cmdx.FailSilently(cmd)
+				}
+				return exportRelationTuples(cmd, args[0], format)
+			}
+			for _, f := range []string{FlagNamespace, FlagObject, FlagRelation, FlagSubjectID, FlagSubjectSet, FlagResume} {
+				if cmd.Flags().Changed(f) {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The --%s flag is not supported by the %s format, use --%s %s, %s, or %s.\n", f, ExportFormatSnapshot, FlagExportFormat, InputFormatNDJSON, InputFormatCSV, InputFormatJSON)
+					return cmdx.FailSilently(cmd)
+				}
+			}
+
+			u := client.GetAdminURL(cmd)
+			u.Path = relationtuple.SnapshotRoute
+			u.RawQuery = url.Values{
+				"include_namespaces": {strconv.FormatBool(flagx.MustGetBool(cmd, FlagIncludeNamespaces))},
+			}.Encode()
+
+			req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not export snapshot: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not export snapshot: %s\n", client.ErrorFromResponse(resp))
+				return cmdx.FailSilently(cmd)
+			}
+
+			out := cmd.OutOrStdout()
+			if args[0] != "-" {
+				f, err := os.Create(args[0])
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not create file %s: %s\n", args[0], err)
+					return cmdx.FailSilently(cmd)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if _, err := io.Copy(out, resp.Body); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not write snapshot: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+	cmd.Flags().Bool(FlagIncludeNamespaces, false, "Include the configured namespaces in the snapshot.")
+	cmd.Flags().String(FlagExportFormat, ExportFormatSnapshot, fmt.Sprintf("The format of the export, one of %s, %s, %s, or %s.", ExportFormatSnapshot, InputFormatNDJSON, InputFormatCSV, InputFormatJSON))
+	cmd.Flags().Bool(FlagResume, false, "Resume an interrupted export to the same file.")
+	cmd.Flags().Int32(FlagPageSize, 1000, "The number of relation tuples read per request.")
+	registerRelationTupleFlags(cmd)
+
+	return cmd
+}
+
+func newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <snapshot-file>",
+		Short: "Import relation tuples from a snapshot",
+		Long: "Import all relation tuples from a snapshot file created by `export`.\n" +
+			"The target instance must not contain any relation tuples.\n" +
+			"Pass the special filename `-` to read from STD_IN.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not open file %s: %s\n", args[0], err)
+					return cmdx.FailSilently(cmd)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			u := client.GetAdminURL(cmd)
+			u.Path = relationtuple.SnapshotRoute
+
+			req, err := client.NewRequest(cmd, http.MethodPut, u.String(), in)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/x-ndjson")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not import snapshot: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not import snapshot: %s\n", client.ErrorFromResponse(resp))
+				return cmdx.FailSilently(cmd)
+			}
+
+			var result ketoapi.SnapshotImportResult
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not decode response: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d relation tuples.\n", result.Imported)
+			return nil
+		},
+	}
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	client.RegisterAdminRemoteURLFlag(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,211 @@
+package relationtuple
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	FlagSince = "since"
+
+	WatchFormatText = "text"
+)
+
+// sseEvent is one server-sent event of the watch endpoint. The type of the
+// event is also part of the data, so it is not parsed.
+type sseEvent struct {
+	id, data string
+}
+
+func newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print relation tuple changes as they happen",
+		Long: "Print the relation tuple changes of the write API as they happen, until the command is interrupted.\n" +
+			"The changes can be filtered with the same flags as in `keto relation-tuple get`. The relation tuple history has to be enabled on the server.\n\n" +
+			"Every change is printed with its ID. Pass the ID of the last printed change with --since to continue after it, " +
+			"the command also prints it when it stops. Without --since, only changes after the start are printed.\n" +
+			"With --format ndjson, every event is printed as one JSON object per line, including the namespace reloads.",
+		Example: `keto relation-tuple watch --namespace files
+keto relation-tuple watch --namespace files --relation viewer --format ndjson
+keto relation-tuple watch --since 4211`,
+		Args: cobra.NoArgs,
+		RunE: watchRelationTuples,
+	}
+
+	client.RegisterRemoteURLFlags(cmd.Flags())
+	registerRelationTupleFlags(cmd)
+	cmd.Flags().String(FlagSince, "", "Print the changes after this change ID.")
+	cmd.Flags().String(FlagExportFormat, WatchFormatText, fmt.Sprintf("The output format, one of %s or %s.", WatchFormatText, InputFormatNDJSON))
+
+	return cmd
+}
+
+func watchRelationTuples(cmd *cobra.Command, _ []string) error {
+	format := flagx.MustGetString(cmd, FlagExportFormat)
+	if format != WatchFormatText && format != InputFormatNDJSON {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unknown format %q, expected one of %s or %s.\n", format, WatchFormatText, InputFormatNDJSON)
+		return cmdx.FailSilently(cmd)
+	}
+	query, err := readAPIQueryFromFlags(cmd)
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the query: %s\n", err)
+		return cmdx.FailSilently(cmd)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	cursor := flagx.MustGetString(cmd, FlagSince)
+	retry := time.Second
+	printResume := func() {
+		if cursor != "" {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Continue with --%s %s\n", FlagSince, cursor)
+		}
+	}
+
+	u := client.GetWriteURL(cmd)
+	u.Path = relationtuple.WatchRoute
+	u.RawQuery = query.ToURLQuery().Encode()
+	for {
+		req, err := client.NewRequest(cmd, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", "text/event-stream")
+		if cursor != "" {
+			req.Header.Set("Last-Event-ID", cursor)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if ctx.Err() != nil {
+			printResume()
+			return nil
+		} else if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not watch the relation tuples: %s\n", err)
+			printResume()
+			return cmdx.FailSilently(cmd)
+		}
+		if resp.StatusCode != http.StatusOK {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not watch the relation tuples: %s\n", client.ErrorFromResponse(resp))
+			_ = resp.Body.Close()
+			printResume()
+			return cmdx.FailSilently(cmd)
+		}
+
+		err = readEvents(resp.Body, func(e *sseEvent, r time.Duration) error {
+			if r > 0 {
+				retry = r
+			}
+			if e.id != "" {
+				// The cursor is sent without data when the stream starts.
+				cursor = e.id
+			}
+			if e.data == "" {
+				return nil
+			}
+			return printWatchEvent(cmd, format, e)
+		})
+		_ = resp.Body.Close()
+		if ctx.Err() != nil {
+			printResume()
+			return nil
+		} else if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not watch the relation tuples: %s\n", err)
+			printResume()
+			return cmdx.FailSilently(cmd)
+		}
+
+		// The server ends the stream regularly, it is resumed after the last
+		// event.
+		select {
+		case <-ctx.Done():
+			printResume()
+			return nil
+		case <-time.After(retry):
+		}
+	}
+}
+
+// readEvents parses the server-sent events of the stream until it ends. The
+// reconnection delay is passed along with the event if the server changed it.
+func readEvents(r io.Reader, handle func(e *sseEvent, retry time.Duration) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var (
+		e     sseEvent
+		retry time.Duration
+		data  []string
+	)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			e.data = strings.Join(data, "\n")
+			if err := handle(&e, retry); err != nil {
+				return err
+			}
+			e, retry, data = sseEvent{}, 0, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// comments are used as heartbeat
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			e.id = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return s.Err()
+}
+
+func printWatchEvent(cmd *cobra.Command, format string, e *sseEvent) error {
+	if format == InputFormatNDJSON {
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), e.data)
+		return err
+	}
+
+	var we ketoapi.WatchEvent
+	if err := json.Unmarshal([]byte(e.data), &we); err != nil {
+		return fmt.Errorf("could not decode the event: %w", err)
+	}
+	switch we.Type {
+	case ketoapi.WatchEventNamespaces:
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Namespaces: %s\n", strings.Join(we.Namespaces, ", "))
+	case ketoapi.WatchEventRelationTuple:
+		if we.Change == nil {
+			return nil
+		}
+		_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\t%s\n",
+			we.ID, we.Change.Time.UTC().Format(time.RFC3339), we.Change.Action, we.Change.RelationTuple)
+		return err
+	}
+	return nil
+}
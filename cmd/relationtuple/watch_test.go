@@ -0,0 +1,117 @@
+package relationtuple
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/x/cmdx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestWatch(t *testing.T) {
+	reg := driver.NewSqliteTestRegistry(t, false)
+	ctx := context.Background()
+	require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "files"}, {Name: "groups"}}))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, true))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryWatchInterval, "10ms"))
+
+	r := &x.WriteRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterWriteRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	remote := strings.TrimPrefix(ts.URL, "http://")
+
+	alice := &ketoapi.RelationTuple{Namespace: "files", Object: "a", Relation: "viewer", SubjectID: x.Ptr("alice")}
+	bob := &ketoapi.RelationTuple{Namespace: "files", Object: "b", Relation: "viewer", SubjectID: x.Ptr("bob")}
+	editors := &ketoapi.RelationTuple{Namespace: "groups", Object: "editors", Relation: "member", SubjectID: x.Ptr("bob")}
+
+	// watch runs the command until the expected number of lines was printed.
+	watch := func(t *testing.T, lines int, args ...string) ([]string, string) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		stdOut, w := io.Pipe()
+		stdErr := &strings.Builder{}
+		eg := cmdx.ExecBackgroundCtx(ctx, newWatchCmd(), nil, w, stdErr, append(args, "--"+client.FlagWriteRemote, remote)...)
+
+		var out []string
+		s := bufio.NewScanner(stdOut)
+		for len(out) < lines && s.Scan() {
+			out = append(out, s.Text())
+		}
+		cancel()
+		go func() { _, _ = io.Copy(io.Discard, stdOut) }()
+		require.NoError(t, eg.Wait())
+		_ = w.Close()
+		require.Len(t, out, lines, stdErr.String())
+		return out, stdErr.String()
+	}
+
+	t.Run("case=prints the filtered changes", func(t *testing.T) {
+		relationtuple.MapAndWriteTuples(t, reg, alice)
+		written := make(chan error, 1)
+		go func() {
+			// Give the command time to connect, earlier changes are not
+			// printed.
+			time.Sleep(200 * time.Millisecond)
+			its, err := reg.Mapper().FromTuple(ctx, editors, bob)
+			if err == nil {
+				err = reg.RelationTupleManager().WriteRelationTuples(ctx, its...)
+			}
+			written <- err
+		}()
+
+		out, stdErr := watch(t, 1, "--"+FlagNamespace, "files")
+		require.NoError(t, <-written)
+		fields := strings.Split(out[0], "\t")
+		require.Len(t, fields, 4)
+		assert.Equal(t, []string{"insert", "files:b#viewer@bob"}, fields[2:])
+		assert.Contains(t, stdErr, "Namespaces: files, groups\n")
+		assert.Contains(t, stdErr, "Continue with --since "+fields[0])
+	})
+
+	t.Run("case=resumes since a change as NDJSON", func(t *testing.T) {
+		// Both were written before, so they are deleted to be written again.
+		its, err := reg.Mapper().FromTuple(ctx, alice, bob)
+		require.NoError(t, err)
+		require.NoError(t, reg.RelationTupleManager().DeleteRelationTuples(ctx, its...))
+
+		latest, err := reg.RelationTupleHistoryManager().LatestRelationTupleHistoryID(ctx)
+		require.NoError(t, err)
+		relationtuple.MapAndWriteTuples(t, reg, alice, bob)
+
+		out, _ := watch(t, 2, "--"+FlagSince, strconv.FormatInt(latest+1, 10), "--"+FlagExportFormat, InputFormatNDJSON)
+		var namespaces, change ketoapi.WatchEvent
+		require.NoError(t, json.Unmarshal([]byte(out[0]), &namespaces))
+		assert.Equal(t, ketoapi.WatchEventNamespaces, namespaces.Type)
+		require.NoError(t, json.Unmarshal([]byte(out[1]), &change))
+		assert.Equal(t, ketoapi.WatchEventRelationTuple, change.Type)
+		assert.Equal(t, bob, change.Change.RelationTuple)
+	})
+
+	t.Run("case=history disabled", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, false))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, true)) })
+
+		_, stdErr, err := cmdx.ExecCtx(ctx, newWatchCmd(), nil, "--"+client.FlagWriteRemote, remote)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdErr, "The relation tuple history is not enabled")
+	})
+}
@@ -28,7 +28,12 @@ import (
 
 	"github.com/ory/keto/cmd/expand"
 
+	"github.com/ory/keto/cmd/benchmark"
 	"github.com/ory/keto/cmd/check"
+	"github.com/ory/keto/cmd/cleanup"
+	"github.com/ory/keto/cmd/diff"
+	"github.com/ory/keto/cmd/doctor"
+	"github.com/ory/keto/cmd/fixtures"
 
 	"github.com/ory/keto/cmd/server"
 	"github.com/ory/keto/internal/driver/config"
@@ -38,7 +43,10 @@ import (
 
 	"github.com/ory/keto/cmd/migrate"
 	"github.com/ory/keto/cmd/namespace"
+	"github.com/ory/keto/cmd/opl"
 	"github.com/ory/keto/cmd/relationtuple"
+	"github.com/ory/keto/cmd/simulate"
+	"github.com/ory/keto/cmd/validate"
 
 	"github.com/spf13/cobra"
 )
@@ -55,10 +63,18 @@ func NewRootCmd(opts ...ketoctx.Option) *cobra.Command {
 	relationtuple.RegisterCommandsRecursive(cmd)
 	namespace.RegisterCommandsRecursive(cmd, opts)
 	migrate.RegisterCommandsRecursive(cmd, opts)
+	cleanup.RegisterCommandsRecursive(cmd, opts)
+	doctor.RegisterCommandsRecursive(cmd, opts)
 	server.RegisterCommandsRecursive(cmd, opts)
 	check.RegisterCommandsRecursive(cmd)
 	expand.RegisterCommandsRecursive(cmd)
 	status.RegisterCommandRecursive(cmd)
+	opl.RegisterCommandsRecursive(cmd)
+	validate.RegisterCommandsRecursive(cmd, opts)
+	benchmark.RegisterCommandsRecursive(cmd)
+	simulate.RegisterCommandsRecursive(cmd, opts)
+	fixtures.RegisterCommandsRecursive(cmd)
+	diff.RegisterCommandsRecursive(cmd)
 
 	cmd.AddCommand(cmdx.Version(&config.Version, &config.Commit, &config.Date))
 
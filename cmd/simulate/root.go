@@ -0,0 +1,185 @@
+package simulate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/cmd/check"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketoctx"
+)
+
+const (
+	FlagOPL        = "opl"
+	FlagTuples     = "tuples"
+	FlagAssertions = "assertions"
+	FlagMaxDepth   = "max-depth"
+)
+
+func newSimulateCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Test a permission model against relation tuple fixtures",
+		Long: `Test a permission model without a server or a database.
+The namespaces of the Ory Permission Language file and the relation tuples of the fixture files are loaded into an in-memory instance, then every assertion is checked.
+
+Fixture files contain one relation tuple per line, in the form namespace:object#relation@subject or as a JSON object (NDJSON), or a JSON array of relation tuples as accepted by ` + "`keto relation-tuple create`" + `.
+Assertion files use the format of ` + "`keto check --batch`" + `: one relation tuple per line that is expected to be allowed, or denied if it starts with !.
+Comments (starting with //) and blank lines are ignored in both.
+
+The configuration is not read, so that the result only depends on the given files. The command fails if any assertion does not hold.`,
+		Example: `keto simulate --opl namespaces.keto.ts --tuples fixtures.txt --assertions assertions.txt
+keto simulate --opl namespaces.keto.ts --tuples users.json --tuples documents.txt --assertions assertions.txt --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			oplFile, _ := cmd.Flags().GetString(FlagOPL)
+			tupleFiles, _ := cmd.Flags().GetStringSlice(FlagTuples)
+			assertionsFile, _ := cmd.Flags().GetString(FlagAssertions)
+			maxDepth, err := cmd.Flags().GetInt32(FlagMaxDepth)
+			if err != nil {
+				return err
+			}
+
+			namespaces, errs := schema.ParseLocalFiles(oplFile)
+			if len(errs) > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the Ory Permission Language file %s:\n", oplFile)
+				for _, err := range errs {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+				return cmdx.FailSilently(cmd)
+			}
+
+			var fixtures []*ketoapi.RelationTuple
+			for _, fn := range tupleFiles {
+				tuples, err := readFixtures(fn)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+					return cmdx.FailSilently(cmd)
+				}
+				fixtures = append(fixtures, tuples...)
+			}
+
+			f, err := os.Open(assertionsFile)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not open file %s: %v\n", assertionsFile, err)
+				return cmdx.FailSilently(cmd)
+			}
+			defer f.Close()
+			assertions, err := check.ParseBatch(f, assertionsFile)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			nn := make([]*namespace.Namespace, len(namespaces))
+			for i := range namespaces {
+				nn[i] = &namespaces[i]
+			}
+			reg, err := driver.NewInMemoryRegistry(cmd.Context(), nn, opts...)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not start the in-memory instance: %v\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+			ctx := cmd.Context()
+
+			for _, t := range fixtures {
+				its, err := reg.Mapper().FromTuple(ctx, t)
+				if err == nil {
+					err = reg.RelationTupleManager().WriteRelationTuples(ctx, its...)
+				}
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not load the relation tuple fixture %s: %s\n", t, errorMessage(err))
+					return cmdx.FailSilently(cmd)
+				}
+			}
+
+			out := check.NewBatchOutput(assertions)
+			for _, c := range assertions {
+				allowed := false
+				its, err := reg.Mapper().FromTuple(ctx, c.Tuple)
+				if err == nil {
+					allowed, err = reg.PermissionEngine().CheckIsMember(ctx, its[0], int(maxDepth))
+				}
+				if err != nil {
+					err = errors.New(errorMessage(err))
+				}
+				out.Record(c, allowed, err)
+			}
+			return out.Print(cmd)
+		},
+	}
+
+	cmd.Flags().String(FlagOPL, "", "The Ory Permission Language file with the namespaces.")
+	cmd.Flags().StringSlice(FlagTuples, nil, "The relation tuple fixture files. Can be repeated.")
+	cmd.Flags().String(FlagAssertions, "", "The file with the expected check results.")
+	cmd.Flags().Int32P(FlagMaxDepth, "d", 0, "Maximum depth of the search tree. If the value is less than 1 or greater than the global max-depth then the global max-depth will be used instead.")
+	cmdx.RegisterFormatFlags(cmd.Flags())
+	_ = cmd.MarkFlagRequired(FlagOPL)
+	_ = cmd.MarkFlagRequired(FlagAssertions)
+
+	return cmd
+}
+
+// errorMessage adds the reason of API errors, e.g. which namespace is
+// unknown.
+func errorMessage(err error) string {
+	var rc herodot.ReasonCarrier
+	if errors.As(err, &rc) && rc.Reason() != "" {
+		return err.Error() + ": " + rc.Reason()
+	}
+	return err.Error()
+}
+
+// readFixtures reads a JSON array of relation tuples, or one relation tuple
+// per line, either as a JSON object or in the string format.
+func readFixtures(fn string) ([]*ketoapi.RelationTuple, error) {
+	raw, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file %s: %w", fn, err)
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var tuples []*ketoapi.RelationTuple
+		if err := json.Unmarshal(trimmed, &tuples); err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", fn, err)
+		}
+		return tuples, nil
+	}
+
+	var tuples []*ketoapi.RelationTuple
+	s := bufio.NewScanner(bytes.NewReader(raw))
+	for i := 1; s.Scan(); i++ {
+		row := strings.TrimSpace(s.Text())
+		if row == "" || strings.HasPrefix(row, "//") {
+			continue
+		}
+		var t *ketoapi.RelationTuple
+		if strings.HasPrefix(row, "{") {
+			t = &ketoapi.RelationTuple{}
+			err = json.Unmarshal([]byte(row), t)
+		} else {
+			t, err = (&ketoapi.RelationTuple{}).FromString(row)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s:%d\n  %s\n\n%w", fn, i, row, err)
+		}
+		tuples = append(tuples, t)
+	}
+	return tuples, s.Err()
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command, opts []ketoctx.Option) {
+	parent.AddCommand(newSimulateCmd(opts))
+}
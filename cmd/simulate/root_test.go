@@ -0,0 +1,106 @@
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd/check"
+)
+
+const model = `import { Namespace, SubjectSet, Context } from "@ory/keto-namespace-types"
+
+class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}
+
+class Document implements Namespace {
+  related: {
+    viewers: (User | SubjectSet<Group, "members">)[]
+    owners: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      this.related.owners.includes(ctx.subject),
+  }
+}
+`
+
+func TestSimulate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dir := t.TempDir()
+	write := func(t *testing.T, name, content string) string {
+		fn := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0600))
+		return fn
+	}
+	opl := write(t, "namespaces.keto.ts", model)
+	groups := write(t, "groups.json", `[{"namespace": "Group", "object": "dev", "relation": "members", "subject_id": "alice"}]`)
+	documents := write(t, "documents.txt", `// readme is visible to the dev group
+Document:readme#viewers@Group:dev#members
+Document:readme#owners@carol
+`)
+
+	cmd := &cmdx.CommandExecuter{
+		New:            func() *cobra.Command { return newSimulateCmd(nil) },
+		Ctx:            ctx,
+		PersistentArgs: []string{"--" + FlagOPL, opl, "--" + FlagTuples, groups, "--" + FlagTuples, documents},
+	}
+
+	t.Run("case=all assertions hold", func(t *testing.T) {
+		assertions := write(t, "pass.txt", `Document:readme#view@alice
+Document:readme#view@carol
+!Document:readme#view@bob
+`)
+		stdOut, stdErr, err := cmd.Exec(nil, "--"+FlagAssertions, assertions, "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))
+		require.NoError(t, err, stdErr)
+		assert.Equal(t, "3 checks: 3 passed, 0 failed, 0 errors\n", stdErr)
+
+		var out check.BatchOutput
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &out))
+		require.Len(t, out.Results, 3)
+		assert.True(t, out.Results[0].Allowed)
+		assert.False(t, out.Results[2].Allowed)
+	})
+
+	t.Run("case=fails if an assertion does not hold", func(t *testing.T) {
+		assertions := write(t, "fail.txt", "Document:readme#view@bob\nDocument:readme#view@Unknown:x#y\n")
+		stdOut, stdErr, err := cmd.Exec(nil, "--"+FlagAssertions, assertions)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, "2 checks: 0 passed, 1 failed, 1 errors\n", stdErr)
+		assert.Regexp(t, `view@bob\s+Allowed\s+Denied\s+false`, stdOut)
+		assert.Contains(t, stdOut, `Unknown namespace with name "Unknown".`)
+	})
+
+	t.Run("case=unknown namespace in the fixtures", func(t *testing.T) {
+		fixtures := write(t, "unknown.txt", "Folder:root#owners@alice\n")
+		assertions := write(t, "empty.txt", "")
+		_, stdErr, err := cmd.Exec(nil, "--"+FlagTuples, fixtures, "--"+FlagAssertions, assertions)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdErr, `Could not load the relation tuple fixture Folder:root#owners@alice`)
+		assert.Contains(t, stdErr, `Unknown namespace with name "Folder".`)
+	})
+
+	t.Run("case=invalid permission model", func(t *testing.T) {
+		invalid := write(t, "invalid.keto.ts", "class Document implements Namespace {\n")
+		assertions := write(t, "empty.txt", "")
+		_, stdErr, err := cmd.Exec(nil, "--"+FlagOPL, invalid, "--"+FlagAssertions, assertions)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Contains(t, stdErr, "Could not parse the Ory Permission Language file "+invalid)
+	})
+}
@@ -0,0 +1,57 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+func NewConfigCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "config [<config.yml> ...]",
+		Short: "Validate configuration files",
+		Long: `Validates configuration files against the configuration schema and the rules
+that the schema cannot express, e.g. that all APIs listen on different ports.
+Multiple files are merged like when they are passed to "keto serve". If no file
+is given, the files of the --config flag are validated. Environment variables
+are not taken into account.
+
+Prints every problem together with how to fix it, and exits with a non-zero
+code if there is any.`,
+		Example: `keto validate config keto.yml
+keto validate config -c keto.yml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				var err error
+				files, err = cmd.Flags().GetStringSlice(configx.FlagConfig)
+				if err != nil || len(files) == 0 {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "No configuration file given. Pass the files as arguments or with --config.")
+					return cmdx.FailSilently(cmd)
+				}
+			}
+			name := strings.Join(files, ", ")
+
+			problems, err := config.ValidateFiles(cmd.Context(), files...)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not load %s: %s\n", name, err)
+				return cmdx.FailSilently(cmd)
+			}
+			if len(problems) > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Found %d problem(s) in %s:\n\n", len(problems), name)
+				for _, p := range problems {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n\n", p)
+				}
+				return cmdx.FailSilently(cmd)
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "The configuration in %s is valid.\n", name)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,256 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRootCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "keto"}
+	configx.RegisterConfigFlag(cmd.PersistentFlags(), []string{})
+	RegisterCommandsRecursive(cmd, nil)
+	return cmd
+}
+
+func writeConfig(t *testing.T, content string) string {
+	fn := filepath.Join(t.TempDir(), "keto.yml")
+	require.NoError(t, os.WriteFile(fn, []byte(content), 0600))
+	return fn
+}
+
+func TestValidateConfig(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: newRootCmd}
+
+	t.Run("case=valid config", func(t *testing.T) {
+		fn := writeConfig(t, `
+dsn: memory
+namespaces:
+  - name: docs
+    id: 0
+`)
+		assert.Equal(t, "The configuration in "+fn+" is valid.\n", cmd.ExecNoErr(t, "validate", "config", fn))
+		assert.Equal(t, "The configuration in "+fn+" is valid.\n", cmd.ExecNoErr(t, "validate", "config", "-c", fn))
+	})
+
+	t.Run("case=schema violations", func(t *testing.T) {
+		fn := writeConfig(t, `
+dsn: memory
+serve:
+  read:
+    pot: 4466
+  write:
+    port: "abc"
+log:
+  level: verbose
+`)
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", fn)
+		assert.Contains(t, stdErr, "Found 3 problem(s)")
+		assert.Contains(t, stdErr, "log.level: value must be one of")
+		assert.Contains(t, stdErr, `serve.read: unknown key(s) pot
+  Fix: Rename "pot" to "port".`)
+		assert.Contains(t, stdErr, "serve.write.port: expected integer, but got string\n  Fix: The port to listen on. The default is 4467.")
+	})
+
+	t.Run("case=missing keys", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, "namespaces: [{name: docs}]"))
+		assert.Contains(t, stdErr, "dsn: the key is required but missing\n  Fix: Add dsn.")
+		assert.Contains(t, stdErr, "namespaces.0.id: the key is required but missing")
+	})
+
+	t.Run("case=semantic problems", func(t *testing.T) {
+		fn := writeConfig(t, `
+dsn: postgres://primary
+namespaces:
+  location: file:///etc/keto/namespaces.keto.ts
+  namespaces:
+    - name: docs
+serve:
+  read:
+    socket:
+      path: /var/run/keto.sock
+  write:
+    port: 4466
+    socket:
+      path: /var/run/keto.sock
+    tls:
+      client_auth:
+        ca:
+          path: /etc/keto/client-ca.pem
+cdc:
+  enabled: true
+  sink:
+    type: nats
+read_replicas:
+  dsns: [postgres://primary]
+namespace_storage:
+  - dsn: postgres://telemetry
+    namespaces: [telemetry]
+  - dsn: postgres://other
+    namespaces: [telemetry]
+authn:
+  api_keys:
+    - id: ci
+      key: a-sufficiently-long-key
+  rules:
+    - path: /relation-tuples/check
+      authenticators: [jwt]
+`)
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", fn)
+		for _, expected := range []string{
+			"Found 8 problem(s)",
+			`namespaces: namespaces.location and an inline list of namespaces ("namespaces") are mutually exclusive`,
+			"serve.write.port: the port 4466 is also used by serve.read.port",
+			"serve.write.socket.path: the socket /var/run/keto.sock is also used by serve.read.socket.path",
+			"serve.write.tls.client_auth: client certificates are required, but the API does not use TLS",
+			"cdc.sink.url: change data capture is enabled, but the key is not set",
+			"read_replicas.dsns.0: the read replica is the primary database",
+			`namespace_storage.1.namespaces: the namespace "telemetry" is mapped to more than one database`,
+			`authn.rules.0.authenticators: the authenticator "jwt" is not configured, so it never accepts a token`,
+		} {
+			assert.Contains(t, stdErr, expected)
+		}
+		assert.NotContains(t, stdErr, "does not match any of the allowed forms")
+	})
+
+	t.Run("case=redis with SQL-only features", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: postgres://primary
+namespaces: [{name: docs, id: 1}]
+redis:
+  url: redis://localhost:6379/0
+history:
+  enabled: true
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "history.enabled: the relation tuples are stored in Redis, which does not support the history API")
+	})
+
+	t.Run("case=snapshot of a persistent database", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: postgres://primary
+namespaces: [{name: docs, id: 1}]
+memory_snapshot:
+  path: /var/lib/keto/snapshot.sqlite
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "memory_snapshot.path: only the in-memory database can be snapshotted")
+	})
+
+	t.Run("case=sharding problems", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: postgres://primary
+namespaces: [{name: docs, id: 1}]
+sharding:
+  dsns: [postgres://shard-1, postgres://primary, postgres://shard-1]
+history:
+  enabled: true
+`))
+		assert.Contains(t, stdErr, "Found 3 problem(s)")
+		assert.Contains(t, stdErr, "sharding.dsns.1: the shard is the primary database")
+		assert.Contains(t, stdErr, "sharding.dsns.2: the shard is listed more than once")
+		assert.Contains(t, stdErr, "history.enabled: the relation tuples are sharded, which does not support the history API")
+	})
+
+	t.Run("case=closure of sharded relation tuples", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: postgres://primary
+namespaces:
+  - name: groups
+    id: 1
+    config:
+      closure:
+        relations: [member]
+sharding:
+  dsns: [postgres://shard-1]
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "namespaces.0.config.closure: the relation tuples are sharded, which does not support closures")
+	})
+
+	t.Run("case=encrypted identifiers in Redis", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: memory
+redis:
+  url: redis://localhost:6379
+secrets:
+  cipher: [file:///run/secrets/keto-cipher]
+  mapping_id: file:///run/secrets/keto-mapping-id
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "secrets.cipher: the relation tuples are stored in Redis, which does not support encrypting the identifiers")
+	})
+
+	t.Run("case=encrypted identifiers without the UUID secret", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: memory
+secrets:
+  cipher: [file:///run/secrets/keto-cipher]
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "secrets.mapping_id: the identifiers are encrypted, but the secret of their UUIDs is not set")
+	})
+
+	t.Run("case=tenancy without tenants of the clients", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: memory
+tenancy:
+  enabled: true
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "tenancy.enabled: tenancy is enabled, but no authenticator is configured, so all requests are rejected")
+
+		stdErr = cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: memory
+tenancy:
+  enabled: true
+authn:
+  jwt:
+    jwks_url: https://auth.example.com/.well-known/jwks.json
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "authn.jwt.tenant_claim: tenancy is enabled, but the tenant claim is not set, so all requests with these tokens are rejected")
+	})
+
+	t.Run("case=orphaned mappings of several databases", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: postgres://primary
+namespace_storage:
+  - dsn: postgres://documents
+    namespaces: [documents]
+uuid_mapping_gc:
+  interval: 1h
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, "uuid_mapping_gc.interval: the relation tuples are stored in more than one database, which does not support collecting orphaned UUID mappings")
+	})
+
+	t.Run("case=unregistered interceptor plugin", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", writeConfig(t, `
+dsn: memory
+serve:
+  grpc:
+    interceptors:
+      - name: unregistered-test-plugin
+        apis: [read]
+`))
+		assert.Contains(t, stdErr, "Found 1 problem(s)")
+		assert.Contains(t, stdErr, `serve.grpc.interceptors.0.name: the gRPC interceptor plugin "unregistered-test-plugin" is not registered in this build`)
+	})
+
+	t.Run("case=unreadable file", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config", filepath.Join(t.TempDir(), "missing.yml"))
+		assert.Contains(t, stdErr, "Could not load")
+	})
+
+	t.Run("case=no file", func(t *testing.T) {
+		stdErr := cmd.ExecExpectedErr(t, "validate", "config")
+		assert.Contains(t, stdErr, "No configuration file given.")
+	})
+}
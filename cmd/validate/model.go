@@ -0,0 +1,125 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ory/x/cmdx"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/internal/validation"
+	"github.com/ory/keto/ketoctx"
+)
+
+// modelResult prints the result of a validation file as a table.
+type modelResult struct {
+	*validation.Result
+}
+
+func NewModelCmd(opts []ketoctx.Option) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "model <validation.yml>",
+		Short: "Validate a permission model against assertions",
+		Long: `Loads the schema and the relationships of a validation file into an in-memory
+instance, and checks that every assertion and expected expansion holds. Neither
+a server nor a database is needed.
+
+The validation file is written in YAML:
+
+  schema: |
+    class User implements Namespace {}
+    class Document implements Namespace {
+      related: { viewers: User[] }
+    }
+  # or, relative to the validation file:
+  # schema_file: namespaces.keto.ts
+  relationships: |
+    Document:readme#viewers@alice
+  assertions:
+    allowed:
+      - Document:readme#viewers@alice
+    denied:
+      - Document:readme#viewers@bob
+  expected_expansions:
+    Document:readme#viewers:
+      - alice
+
+Expected expansions list the subjects of the leaves of the expand tree of the
+subject set, as returned by "keto expand". Exits with a non-zero code if any
+assertion or expected expansion does not hold.`,
+		Example: `keto validate model validation.yml
+keto validate model validation.yml --format json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := validation.ReadFile(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			res, err := validation.Run(cmd.Context(), f, opts...)
+			var schemaErr *validation.SchemaError
+			if errors.As(err, &schemaErr) {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not parse the schema of %s:\n", args[0])
+				for _, err := range schemaErr.Errors {
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err)
+				}
+				return cmdx.FailSilently(cmd)
+			} else if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not validate %s: %s\n", args[0], err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			cmdx.PrintTable(cmd, &modelResult{res})
+			passed, failed, errored := res.Summary()
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d assertions and expansions: %d passed, %d failed, %d errors\n", passed+failed+errored, passed, failed, errored)
+			if !res.Passed() {
+				return cmdx.FailSilently(cmd)
+			}
+			return nil
+		},
+	}
+	cmdx.RegisterFormatFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (r *modelResult) Header() []string {
+	return []string{"KIND", "SUBJECT", "EXPECTED", "RESULT", "PASSED"}
+}
+
+func (r *modelResult) Table() [][]string {
+	data := make([][]string, 0, r.Len())
+	for _, a := range r.Assertions {
+		result := allowedString(a.Allowed)
+		if a.Error != "" {
+			result = "Error: " + a.Error
+		}
+		data = append(data, []string{"assertion", a.Tuple, allowedString(a.Expected), result, strconv.FormatBool(a.Passed())})
+	}
+	for _, e := range r.Expansions {
+		result := strings.Join(e.Actual, ", ")
+		if e.Error != "" {
+			result = "Error: " + e.Error
+		}
+		data = append(data, []string{"expansion", e.SubjectSet, strings.Join(e.Expected, ", "), result, strconv.FormatBool(e.Passed())})
+	}
+	return data
+}
+
+func (r *modelResult) Interface() interface{} {
+	return r.Result
+}
+
+func (r *modelResult) Len() int {
+	return len(r.Assertions) + len(r.Expansions)
+}
+
+func allowedString(allowed bool) string {
+	if allowed {
+		return "Allowed"
+	}
+	return "Denied"
+}
@@ -0,0 +1,58 @@
+package validate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ory/x/cmdx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/validation"
+)
+
+const model = `
+schema: |
+  class User implements Namespace {}
+  class Document implements Namespace {
+    related: {
+      viewers: User[]
+    }
+  }
+relationships: |
+  Document:readme#viewers@alice
+assertions:
+  allowed:
+    - Document:readme#viewers@alice
+  denied:
+    - Document:readme#viewers@bob
+expected_expansions:
+  Document:readme#viewers:
+    - alice
+`
+
+func TestValidateModel(t *testing.T) {
+	cmd := cmdx.CommandExecuter{New: newRootCmd}
+
+	t.Run("case=valid model", func(t *testing.T) {
+		stdOut, stdErr, err := cmd.Exec(nil, "validate", "model", writeConfig(t, model), "--"+cmdx.FlagFormat, string(cmdx.FormatJSON))
+		require.NoError(t, err, stdErr)
+		assert.Equal(t, "3 assertions and expansions: 3 passed, 0 failed, 0 errors\n", stdErr)
+
+		var res validation.Result
+		require.NoError(t, json.Unmarshal([]byte(stdOut), &res))
+		assert.True(t, res.Passed())
+	})
+
+	t.Run("case=failing assertion", func(t *testing.T) {
+		_, stdErr, err := cmd.Exec(nil, "validate", "model", writeConfig(t, model+"    - bob\n"))
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Equal(t, "3 assertions and expansions: 2 passed, 1 failed, 0 errors\n", stdErr)
+	})
+
+	t.Run("case=invalid schema", func(t *testing.T) {
+		fn := writeConfig(t, "schema: 'class Document implements Namespace { related: { viewers: Unknown[] } }'")
+		stdErr := cmd.ExecExpectedErr(t, "validate", "model", fn)
+		assert.Contains(t, stdErr, "Could not parse the schema of "+fn+":\n")
+	})
+}
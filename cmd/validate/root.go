@@ -0,0 +1,21 @@
+package validate
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/keto/ketoctx"
+)
+
+func NewValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Validate Ory Keto files",
+	}
+}
+
+func RegisterCommandsRecursive(parent *cobra.Command, opts []ketoctx.Option) {
+	rootCmd := NewValidateCmd()
+	rootCmd.AddCommand(NewConfigCmd(), NewModelCmd(opts))
+
+	parent.AddCommand(rootCmd)
+}
@@ -0,0 +1,174 @@
+//go:build js && wasm
+
+// Command wasm exposes the check engine of Ory Keto to JavaScript. Build it
+// with
+//
+//	GOOS=js GOARCH=wasm go build -o keto.wasm ./contrib/wasm
+//
+// and load it with the wasm_exec.js of the Go distribution. It sets the global
+// keto object with two functions:
+//
+//	keto.compile(opl) // {namespaces: ["Document", ...]} or {errors: ["...", ...]}
+//	keto.newChecker(opl, source)
+//
+// The source is either an array of relation tuples in the string format
+// "namespace:object#relation@subject", or a function that receives a query
+// with the optional fields namespace, object, relation, subject_id, and
+// subject_set, and returns such an array, or a promise of one. The checker has
+// one function, check(tuple, maxDepth), which returns a promise of whether the
+// relation tuple, in the same format, is allowed.
+package main
+
+import (
+	"context"
+	"syscall/js"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketocheck"
+)
+
+func main() {
+	js.Global().Set("keto", js.ValueOf(map[string]interface{}{
+		"compile":    js.FuncOf(compile),
+		"newChecker": js.FuncOf(newChecker),
+	}))
+	select {}
+}
+
+func compile(_ js.Value, args []js.Value) interface{} {
+	names, errs := ketocheck.Compile(args[0].String())
+	if len(errs) > 0 {
+		msgs := make([]interface{}, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return map[string]interface{}{"errors": msgs}
+	}
+	nn := make([]interface{}, len(names))
+	for i, n := range names {
+		nn[i] = n
+	}
+	return map[string]interface{}{"namespaces": nn}
+}
+
+func newChecker(_ js.Value, args []js.Value) interface{} {
+	var source ketocheck.TupleSource = jsSource{f: args[1]}
+	if args[1].Type() != js.TypeFunction {
+		tuples, err := parseTuples(args[1])
+		if err != nil {
+			panic(js.Global().Get("Error").New(err.Error()))
+		}
+		source = tuples
+	}
+	c, err := ketocheck.NewChecker(args[0].String(), source)
+	if err != nil {
+		panic(js.Global().Get("Error").New(err.Error()))
+	}
+
+	return js.ValueOf(map[string]interface{}{
+		"check": js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+			tuple, err := (&ketoapi.RelationTuple{}).FromString(args[0].String())
+			maxDepth := 0
+			if len(args) > 1 && args[1].Type() == js.TypeNumber {
+				maxDepth = args[1].Int()
+			}
+			return promise(func() (interface{}, error) {
+				if err != nil {
+					return nil, err
+				}
+				return c.Check(context.Background(), tuple, maxDepth)
+			})
+		}),
+	})
+}
+
+// jsSource calls a JavaScript function for the relation tuples.
+type jsSource struct {
+	f js.Value
+}
+
+func (s jsSource) RelationTuples(_ context.Context, query *ketoapi.RelationQuery) ([]*ketoapi.RelationTuple, error) {
+	q := make(map[string]interface{})
+	if query.Namespace != nil {
+		q["namespace"] = *query.Namespace
+	}
+	if query.Object != nil {
+		q["object"] = *query.Object
+	}
+	if query.Relation != nil {
+		q["relation"] = *query.Relation
+	}
+	if query.SubjectID != nil {
+		q["subject_id"] = *query.SubjectID
+	}
+	if query.SubjectSet != nil {
+		q["subject_set"] = query.SubjectSet.String()
+	}
+
+	res := s.f.Invoke(q)
+	if then := res.Get("then"); then.Type() == js.TypeFunction {
+		var err error
+		if res, err = await(res); err != nil {
+			return nil, err
+		}
+	}
+	return parseTuples(res)
+}
+
+func parseTuples(v js.Value) (ketocheck.Tuples, error) {
+	tuples := make(ketocheck.Tuples, v.Length())
+	for i := range tuples {
+		t, err := (&ketoapi.RelationTuple{}).FromString(v.Index(i).String())
+		if err != nil {
+			return nil, err
+		}
+		tuples[i] = t
+	}
+	return tuples, nil
+}
+
+// await blocks until the promise is settled. It must not be called from the
+// goroutine of a JavaScript callback, which would block the event loop.
+func await(p js.Value) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	ch := make(chan result, 1)
+	resolve := js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		ch <- result{v: args[0]}
+		return nil
+	})
+	defer resolve.Release()
+	reject := js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		ch <- result{err: errors.New(args[0].Call("toString").String())}
+		return nil
+	})
+	defer reject.Release()
+
+	p.Call("then", resolve, reject)
+	r := <-ch
+	return r.v, r.err
+}
+
+// promise runs f on a new goroutine, so that the event loop is not blocked,
+// and returns a promise of its result.
+func promise(f func() (interface{}, error)) js.Value {
+	var executor js.Func
+	executor = js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		resolve, reject := args[0], args[1]
+		go func() {
+			defer executor.Release()
+			v, err := f()
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(v)
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(executor)
+}
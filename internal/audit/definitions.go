@@ -0,0 +1,56 @@
+// Package audit records relation tuple writes and check decisions, together
+// with who made the request, to the configured audit log sinks.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	EventType string
+	Outcome   string
+
+	// Event is one entry of the audit log.
+	Event struct {
+		Time time.Time `json:"time"`
+		Type EventType `json:"type"`
+		// Actor is the authenticated client that made the request. It is
+		// empty for anonymous requests.
+		Actor     *authn.Subject `json:"actor,omitempty"`
+		RequestID string         `json:"request_id,omitempty"`
+		Outcome   Outcome        `json:"outcome"`
+		Error     string         `json:"error,omitempty"`
+
+		// RelationTuple is the checked relation tuple.
+		RelationTuple *ketoapi.RelationTuple `json:"relation_tuple,omitempty"`
+		// Deltas are the relation tuples that were inserted or deleted.
+		Deltas []*ketoapi.PatchDelta `json:"deltas,omitempty"`
+		// Query matches the relation tuples that were deleted.
+		Query *ketoapi.RelationQuery `json:"query,omitempty"`
+	}
+
+	// Sink writes events to the audit log. Write must only return once the
+	// events are persisted or handed off.
+	Sink interface {
+		Write(ctx context.Context, events []*Event) error
+		Close() error
+	}
+
+	LoggerProvider interface {
+		AuditLogger() *Logger
+	}
+)
+
+const (
+	EventTypeCheck EventType = "check"
+	EventTypeWrite EventType = "write"
+
+	OutcomeAllowed Outcome = "allowed"
+	OutcomeDenied  Outcome = "denied"
+	OutcomeSuccess Outcome = "success"
+	OutcomeError   Outcome = "error"
+)
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/ory/keto/internal/x/kafkax"
+)
+
+// KafkaSink produces the events to a Kafka topic through a Kafka REST proxy
+// (v2 API). Records are keyed by the request ID, so that the events of one
+// request keep their order.
+type KafkaSink struct {
+	p *kafkax.Producer
+}
+
+func NewKafkaSink(proxyURL, topic string) (*KafkaSink, error) {
+	if err := checkHTTPURL(proxyURL); err != nil {
+		return nil, err
+	}
+	if topic == "" {
+		topic = "keto.audit"
+	}
+	p, err := kafkax.NewProducer(proxyURL, topic)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaSink{p: p}, nil
+}
+
+func (s *KafkaSink) Write(ctx context.Context, events []*Event) error {
+	records := make([]*kafkax.Record, len(events))
+	for i, e := range events {
+		records[i] = &kafkax.Record{Key: e.RequestID, Value: e}
+	}
+	return s.p.Produce(ctx, records)
+}
+
+func (s *KafkaSink) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/requestid"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	dependencies interface {
+		config.Provider
+		x.LoggerProvider
+	}
+	// Logger buffers the audit events of the handlers and writes them to the
+	// sink in the background, so that a slow sink does not slow down the
+	// requests. Events that do not fit in the buffer are dropped and logged.
+	Logger struct {
+		d      dependencies
+		events chan *Event
+	}
+)
+
+const (
+	batchSize       = 100
+	shutdownTimeout = 5 * time.Second
+)
+
+func NewLogger(d dependencies) *Logger {
+	return &Logger{
+		d:      d,
+		events: make(chan *Event, d.Config(context.Background()).AuditBufferSize()),
+	}
+}
+
+// Check records a check decision, subject to the sample rate of checks.
+func (l *Logger) Check(ctx context.Context, tuple *ketoapi.RelationTuple, allowed bool, err error) {
+	if !l.d.Config(ctx).AuditEnabled() {
+		return
+	}
+	if rate := l.d.Config(ctx).AuditChecksSampleRate(); rate < 1 && rand.Float64() >= rate {
+		return
+	}
+
+	e := &Event{Type: EventTypeCheck, RelationTuple: tuple, Outcome: OutcomeDenied}
+	if allowed {
+		e.Outcome = OutcomeAllowed
+	}
+	l.record(ctx, e, err)
+}
+
+// Write records the insertion and deletion of relation tuples.
+func (l *Logger) Write(ctx context.Context, deltas []*ketoapi.PatchDelta, err error) {
+	if !l.d.Config(ctx).AuditEnabled() {
+		return
+	}
+	l.record(ctx, &Event{Type: EventTypeWrite, Deltas: deltas, Outcome: OutcomeSuccess}, err)
+}
+
+// Delete records the deletion of all relation tuples matching the query.
+func (l *Logger) Delete(ctx context.Context, query *ketoapi.RelationQuery, err error) {
+	if !l.d.Config(ctx).AuditEnabled() {
+		return
+	}
+	l.record(ctx, &Event{Type: EventTypeWrite, Query: query, Outcome: OutcomeSuccess}, err)
+}
+
+func (l *Logger) record(ctx context.Context, e *Event, err error) {
+	e.Time = time.Now().UTC()
+	e.RequestID = requestid.FromContext(ctx)
+	e.Actor, _ = authn.FromContext(ctx)
+	if err != nil {
+		e.Outcome = OutcomeError
+		e.Error = err.Error()
+	}
+
+	select {
+	case l.events <- e:
+	default:
+		l.d.Logger().WithContext(ctx).WithField("type", e.Type).Error("The audit log buffer is full, dropping the event.")
+	}
+}
+
+// Run writes the recorded events to the sink until the context is canceled.
+// Then the buffered events are written before it returns.
+func (l *Logger) Run(ctx context.Context, sink Sink) error {
+	defer sink.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			for batch := l.next(nil); len(batch) > 0; batch = l.next(nil) {
+				l.write(flushCtx, sink, batch)
+			}
+			return nil
+		case e := <-l.events:
+			l.write(ctx, sink, l.next(e))
+		}
+	}
+}
+
+// next returns the first event, if any, and the events that are buffered
+// after it, up to the batch size.
+func (l *Logger) next(first *Event) []*Event {
+	batch := make([]*Event, 0, batchSize)
+	if first != nil {
+		batch = append(batch, first)
+	}
+	for len(batch) < batchSize {
+		select {
+		case e := <-l.events:
+			batch = append(batch, e)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (l *Logger) write(ctx context.Context, sink Sink, batch []*Event) {
+	if err := sink.Write(ctx, batch); err != nil {
+		l.d.Logger().WithError(err).WithField("events", len(batch)).Error("Unable to write to the audit log.")
+	}
+}
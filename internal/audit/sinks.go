@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+type (
+	// FileSink appends the events to a file, one JSON object per line.
+	FileSink struct {
+		f   *os.File
+		enc *json.Encoder
+	}
+	// WebhookSink posts the events as a JSON array to a URL.
+	WebhookSink struct {
+		url string
+		c   *http.Client
+	}
+	multiSink []Sink
+)
+
+// NewSink creates a sink that writes to all configured sinks.
+func NewSink(cs []config.AuditSink) (Sink, error) {
+	if len(cs) == 0 {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("%s must be set to enable the audit log", config.KeyAuditSinks))
+	}
+	sinks := make(multiSink, 0, len(cs))
+	for _, c := range cs {
+		s, err := newSink(c)
+		if err != nil {
+			_ = sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func newSink(c config.AuditSink) (Sink, error) {
+	switch c.Type {
+	case "file":
+		return NewFileSink(c.Path)
+	case "syslog":
+		return NewSyslogSink(c.URL, c.Tag)
+	case "webhook":
+		return NewWebhookSink(c.URL)
+	case "kafka":
+		return NewKafkaSink(c.URL, c.Topic)
+	default:
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("unknown audit log sink type %q", c.Type))
+	}
+}
+
+// Write writes to all sinks, also if some fail.
+func (ss multiSink) Write(ctx context.Context, events []*Event) error {
+	var firstErr error
+	for _, s := range ss {
+		if err := s.Write(ctx, events); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (ss multiSink) Close() error {
+	var firstErr error
+	for _, s := range ss {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NewFileSink opens the file for appending, or writes to stdout if the path is
+// "-".
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("the path of the audit log file must be set"))
+	}
+	f := os.Stdout
+	if path != "-" {
+		var err error
+		f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return &FileSink{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *FileSink) Write(_ context.Context, events []*Event) error {
+	for _, e := range events {
+		if err := s.enc.Encode(e); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	if s.f == os.Stdout {
+		return nil
+	}
+	return errors.WithStack(s.f.Close())
+}
+
+func NewWebhookSink(webhookURL string) (*WebhookSink, error) {
+	if err := checkHTTPURL(webhookURL); err != nil {
+		return nil, err
+	}
+	return &WebhookSink{url: webhookURL, c: http.DefaultClient}, nil
+}
+
+func (s *WebhookSink) Write(ctx context.Context, events []*Event) error {
+	enc, err := json.Marshal(events)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(enc))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("audit log webhook responded with status code %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+func checkHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return nil
+	default:
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("unsupported audit log sink URL scheme %q, expected http or https", u.Scheme))
+	}
+}
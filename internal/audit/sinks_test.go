@@ -0,0 +1,188 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+var testEvents = []*audit.Event{{
+	Time:          time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	Type:          audit.EventTypeCheck,
+	Actor:         &authn.Subject{ID: "client", Authenticator: config.AuthenticatorAPIKey},
+	RequestID:     "r1",
+	Outcome:       audit.OutcomeDenied,
+	RelationTuple: &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")},
+}, {
+	Time:      time.Date(2022, 1, 1, 0, 0, 1, 0, time.UTC),
+	Type:      audit.EventTypeWrite,
+	RequestID: "r2",
+	Outcome:   audit.OutcomeSuccess,
+	Deltas: []*ketoapi.PatchDelta{{
+		Action:        ketoapi.ActionInsert,
+		RelationTuple: &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")},
+	}},
+}}
+
+func TestFileSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for i := 0; i < 2; i++ {
+		s, err := audit.NewFileSink(path)
+		require.NoError(t, err)
+		require.NoError(t, s.Write(ctx, testEvents[i:i+1]))
+		require.NoError(t, s.Close())
+	}
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	require.Len(t, lines, 2, "the file is appended to")
+	for i, l := range lines {
+		var e audit.Event
+		require.NoError(t, json.Unmarshal([]byte(l), &e))
+		assert.Equal(t, testEvents[i], &e)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=posts the events", func(t *testing.T) {
+		var received []*audit.Event
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer srv.Close()
+
+		s, err := audit.NewWebhookSink(srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, s.Write(ctx, testEvents))
+		assert.Equal(t, testEvents, received)
+	})
+
+	t.Run("case=fails on error responses", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		s, err := audit.NewWebhookSink(srv.URL)
+		require.NoError(t, err)
+		err = s.Write(ctx, testEvents)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nope")
+	})
+}
+
+func TestKafkaSink(t *testing.T) {
+	ctx := context.Background()
+
+	var body struct {
+		Records []struct {
+			Key   string       `json:"key"`
+			Value *audit.Event `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/keto.audit", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":1},{"partition":0,"offset":2}]}`))
+	}))
+	defer srv.Close()
+
+	s, err := audit.NewKafkaSink(srv.URL, "")
+	require.NoError(t, err)
+	require.NoError(t, s.Write(ctx, testEvents))
+
+	require.Len(t, body.Records, 2)
+	assert.Equal(t, "r1", body.Records[0].Key)
+	assert.Equal(t, testEvents[1], body.Records[1].Value)
+}
+
+func TestSyslogSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("syslog is not supported on Windows")
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := audit.NewSyslogSink("udp://"+conn.LocalAddr().String(), "")
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Write(context.Background(), testEvents[:1]))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	msg := string(buf[:n])
+	assert.Contains(t, msg, " keto[")
+
+	var e audit.Event
+	require.NoError(t, json.Unmarshal([]byte(msg[strings.Index(msg, "{"):]), &e))
+	assert.Equal(t, testEvents[0], &e)
+}
+
+func TestNewSink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=writes to all sinks", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := audit.NewSink([]config.AuditSink{
+			{Type: "file", Path: filepath.Join(dir, "a.log")},
+			{Type: "file", Path: filepath.Join(dir, "b.log")},
+		})
+		require.NoError(t, err)
+		require.NoError(t, s.Write(ctx, testEvents))
+		require.NoError(t, s.Close())
+
+		for _, f := range []string{"a.log", "b.log"} {
+			fh, err := os.Open(filepath.Join(dir, f))
+			require.NoError(t, err)
+			raw, err := io.ReadAll(fh)
+			require.NoError(t, err)
+			require.NoError(t, fh.Close())
+			assert.Equal(t, 2, strings.Count(string(raw), "\n"), f)
+		}
+	})
+
+	for _, tc := range []struct {
+		name  string
+		sinks []config.AuditSink
+	}{
+		{name: "no sinks"},
+		{name: "unknown type", sinks: []config.AuditSink{{Type: "carrier-pigeon"}}},
+		{name: "file without path", sinks: []config.AuditSink{{Type: "file"}}},
+		{name: "webhook with unsupported scheme", sinks: []config.AuditSink{{Type: "webhook", URL: "ftp://example.com"}}},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			_, err := audit.NewSink(tc.sinks)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+	"net/url"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+)
+
+// SyslogSink sends every event as a JSON message to syslog.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog server at the address, e.g.
+// udp://127.0.0.1:514, or to the local syslog daemon if it is empty.
+func NewSyslogSink(address, tag string) (*SyslogSink, error) {
+	var network, raddr string
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		switch u.Scheme {
+		case "udp", "tcp":
+			network, raddr = u.Scheme, u.Host
+		case "unix", "unixgram":
+			network, raddr = u.Scheme, u.Path
+		default:
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("unsupported syslog URL scheme %q, expected udp, tcp, unix, or unixgram", u.Scheme))
+		}
+	}
+	if tag == "" {
+		tag = "keto"
+	}
+
+	w, err := syslog.Dial(network, raddr, syslog.LOG_AUTH|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Write(_ context.Context, events []*Event) error {
+	for _, e := range events {
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := s.w.Info(string(msg)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	return errors.WithStack(s.w.Close())
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+package audit
+
+import (
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+)
+
+type SyslogSink struct {
+	Sink
+}
+
+func NewSyslogSink(string, string) (*SyslogSink, error) {
+	return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("the syslog audit log sink is not supported on this platform"))
+}
@@ -0,0 +1,178 @@
+// Package authn authenticates the clients of the read, write, and admin APIs
+// by their bearer tokens.
+package authn
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x"
+)
+
+type (
+	AuthenticatorProvider interface {
+		Authenticator() *Authenticator
+	}
+	Authenticator struct {
+		d  dependencies
+		hc *http.Client
+
+		mx             sync.Mutex
+		keySets        map[string]*keySet
+		jwksFetches    singleflight.Group
+		introspections map[[sha256.Size]byte]cachedIntrospection
+	}
+	dependencies interface {
+		config.Provider
+		x.LoggerProvider
+	}
+
+	// Subject is the authenticated client of a request.
+	Subject struct {
+		// ID identifies the client, e.g. the ID of the API key or the subject
+		// of the token.
+		ID string `json:"id"`
+		// Authenticator is the name of the authenticator that accepted the
+		// token.
+		Authenticator string `json:"authenticator"`
+		// Scopes are the OAuth 2.0 scopes of the token, if any.
+		Scopes []string `json:"scopes,omitempty"`
+		// Tenant is the only tenant the client can access, or "*" if it can
+		// access all tenants. Clients that are not bound to a tenant cannot
+		// access any tenant if tenancy is enabled.
+		Tenant string `json:"tenant,omitempty"`
+	}
+
+	contextKey struct{}
+)
+
+var (
+	// errInvalidToken is returned by authenticators that do not accept the
+	// token.
+	errInvalidToken = errors.New("the bearer token is invalid")
+
+	ErrMissingToken = herodot.ErrUnauthorized.WithReason("A bearer token has to be provided in the Authorization header.")
+	ErrInvalidToken = herodot.ErrUnauthorized.WithReason("The bearer token is invalid or expired.")
+)
+
+func NewAuthenticator(d dependencies) *Authenticator {
+	return &Authenticator{
+		d:              d,
+		hc:             http.DefaultClient,
+		keySets:        make(map[string]*keySet),
+		introspections: make(map[[sha256.Size]byte]cachedIntrospection),
+	}
+}
+
+// NewContext returns a context that carries the subject.
+func NewContext(ctx context.Context, s *Subject) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the authenticated subject of the request, if there is
+// one.
+func FromContext(ctx context.Context) (*Subject, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Subject)
+	return s, ok && s != nil
+}
+
+// Enabled returns whether any authenticator is configured. Otherwise, all
+// requests are anonymous.
+func (a *Authenticator) Enabled(ctx context.Context) bool {
+	return len(a.d.Config(ctx).Authenticators()) > 0
+}
+
+// Authenticate authenticates the request to the API ("read", "write", or
+// "admin") with the REST path or gRPC method by the value of its Authorization
+// header. It returns a nil subject for anonymous requests, which are allowed
+// if authentication is disabled or the rule of the request allows them. Then
+// requests with a token that no authenticator accepts are anonymous as well.
+func (a *Authenticator) Authenticate(ctx context.Context, api, path, authorization string) (*Subject, error) {
+	if !a.Enabled(ctx) {
+		return nil, nil
+	}
+	allowed, err := a.d.Config(ctx).AuthenticatorsFor(api, path)
+	if err != nil {
+		return nil, err
+	}
+
+	anonymous := slices.Contains(allowed, config.AuthenticatorAnonymous)
+	token := bearerToken(authorization)
+	if token == "" {
+		if anonymous {
+			return nil, nil
+		}
+		return nil, errors.WithStack(ErrMissingToken)
+	}
+
+	for _, name := range allowed {
+		var authenticate func(context.Context, string) (*Subject, error)
+		switch name {
+		case config.AuthenticatorAPIKey:
+			authenticate = a.apiKey
+		case config.AuthenticatorJWT:
+			authenticate = a.jwt
+		case config.AuthenticatorIntrospection:
+			authenticate = a.introspect
+		default:
+			continue
+		}
+
+		s, err := authenticate(ctx, token)
+		if err == nil {
+			return s, nil
+		}
+		if !errors.Is(err, errInvalidToken) {
+			a.d.Logger().WithError(err).WithField("authenticator", name).Warn("Unable to authenticate the bearer token.")
+		}
+	}
+	// Rules that allow anonymous requests also allow tokens that no
+	// authenticator accepts.
+	if anonymous {
+		return nil, nil
+	}
+	return nil, errors.WithStack(ErrInvalidToken)
+}
+
+// bearerToken returns the token of the Authorization header, or an empty
+// string if it is not a bearer token.
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func (a *Authenticator) apiKey(ctx context.Context, token string) (*Subject, error) {
+	keys, err := a.d.Config(ctx).APIKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(k.Key)) == 1 {
+			return &Subject{ID: k.ID, Authenticator: config.AuthenticatorAPIKey, Tenant: k.Tenant}, nil
+		}
+	}
+	return nil, errInvalidToken
+}
+
+// tenantClaim returns the tenant in the claim of the token, or an empty string
+// if the claim is not configured or not a string.
+func tenantClaim(claims map[string]interface{}, name string) string {
+	if name == "" {
+		return ""
+	}
+	tenant, _ := claims[name].(string)
+	return tenant
+}
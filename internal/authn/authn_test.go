@@ -0,0 +1,369 @@
+package authn_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+const apiKey = "a-sufficiently-long-key"
+
+func newAuthenticator(t *testing.T, values map[string]interface{}) *authn.Authenticator {
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+	for k, v := range values {
+		require.NoError(t, reg.Config(context.Background()).Set(k, v))
+	}
+	return reg.Authenticator()
+}
+
+func assertUnauthorized(t *testing.T, err error) {
+	t.Helper()
+	herodotErr := &herodot.DefaultError{}
+	require.ErrorAs(t, err, &herodotErr)
+	assert.Equal(t, http.StatusUnauthorized, herodotErr.StatusCode())
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	apiKeys := []map[string]interface{}{{"id": "ci", "key": apiKey}}
+
+	t.Run("case=anonymous without authenticators", func(t *testing.T) {
+		a := newAuthenticator(t, nil)
+		assert.False(t, a.Enabled(ctx))
+		s, err := a.Authenticate(ctx, "write", "/admin/relation-tuples", "")
+		require.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	t.Run("case=api keys", func(t *testing.T) {
+		a := newAuthenticator(t, map[string]interface{}{config.KeyAuthnAPIKeys: apiKeys})
+		require.True(t, a.Enabled(ctx))
+
+		s, err := a.Authenticate(ctx, "read", "/relation-tuples/check", "Bearer "+apiKey)
+		require.NoError(t, err)
+		assert.Equal(t, &authn.Subject{ID: "ci", Authenticator: config.AuthenticatorAPIKey}, s)
+
+		_, err = a.Authenticate(ctx, "read", "/relation-tuples/check", "")
+		assertUnauthorized(t, err)
+		_, err = a.Authenticate(ctx, "read", "/relation-tuples/check", "Bearer not-the-key")
+		assertUnauthorized(t, err)
+		_, err = a.Authenticate(ctx, "read", "/relation-tuples/check", "Basic "+apiKey)
+		assertUnauthorized(t, err)
+	})
+
+	t.Run("case=rules", func(t *testing.T) {
+		a := newAuthenticator(t, map[string]interface{}{
+			config.KeyAuthnAPIKeys: apiKeys,
+			config.KeyAuthnRules: []map[string]interface{}{
+				{"apis": []string{"read"}, "path": "/relation-tuples/check", "authenticators": []string{"anonymous"}},
+				{"path": "/admin/namespaces", "authenticators": []string{"anonymous", "api_key"}},
+			},
+		})
+
+		for _, tc := range []struct {
+			api, path, authorization string
+			expectedID               string
+			unauthorized             bool
+		}{
+			{api: "read", path: "/relation-tuples/check/openapi"},
+			{api: "read", path: "/relation-tuples/check", authorization: "Bearer " + apiKey},
+			{api: "write", path: "/relation-tuples/check", unauthorized: true},
+			{api: "read", path: "/relation-tuples", unauthorized: true},
+			{api: "admin", path: "/admin/namespaces", authorization: "Bearer namespace-api-key"},
+			{api: "admin", path: "/admin/namespaces", authorization: "Bearer " + apiKey, expectedID: "ci"},
+		} {
+			t.Run("path="+tc.api+tc.path, func(t *testing.T) {
+				s, err := a.Authenticate(ctx, tc.api, tc.path, tc.authorization)
+				if tc.unauthorized {
+					assertUnauthorized(t, err)
+					return
+				}
+				require.NoError(t, err)
+				if tc.expectedID == "" {
+					assert.Nil(t, s)
+				} else {
+					assert.Equal(t, tc.expectedID, s.ID)
+				}
+			})
+		}
+	})
+
+	t.Run("case=json web tokens", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "known", Algorithm: string(jose.RS256), Use: "sig"}}})
+		require.NoError(t, err)
+
+		a := newAuthenticator(t, map[string]interface{}{
+			config.KeyAuthnJWT: map[string]interface{}{
+				"jwks_url":     "base64://" + base64.StdEncoding.EncodeToString(jwks),
+				"issuer":       "https://auth.example.com",
+				"audience":     "keto",
+				"tenant_claim": "tenant",
+			},
+		})
+
+		sign := func(t *testing.T, kid string, signingKey interface{}, claims jwt.Claims, scope ...string) string {
+			signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: signingKey},
+				(&jose.SignerOptions{}).WithHeader("kid", kid))
+			require.NoError(t, err)
+			builder := jwt.Signed(signer).Claims(claims)
+			if len(scope) > 0 {
+				builder = builder.Claims(map[string]interface{}{"scope": scope[0]})
+			}
+			token, err := builder.CompactSerialize()
+			require.NoError(t, err)
+			return token
+		}
+		valid := jwt.Claims{
+			Subject:  "svc",
+			Issuer:   "https://auth.example.com",
+			Audience: jwt.Audience{"keto", "other"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+
+		s, err := a.Authenticate(ctx, "read", "/relation-tuples", "Bearer "+sign(t, "known", key, valid, "read write"))
+		require.NoError(t, err)
+		assert.Equal(t, &authn.Subject{ID: "svc", Authenticator: config.AuthenticatorJWT, Scopes: []string{"read", "write"}}, s)
+
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "known"))
+		require.NoError(t, err)
+		token, err := jwt.Signed(signer).Claims(valid).Claims(map[string]interface{}{"tenant": "acme"}).CompactSerialize()
+		require.NoError(t, err)
+		s, err = a.Authenticate(ctx, "read", "/relation-tuples", "Bearer "+token)
+		require.NoError(t, err)
+		assert.Equal(t, &authn.Subject{ID: "svc", Authenticator: config.AuthenticatorJWT, Tenant: "acme"}, s)
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		expired, wrongIssuer, wrongAudience := valid, valid, valid
+		expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		wrongIssuer.Issuer = "https://evil.example.com"
+		wrongAudience.Audience = jwt.Audience{"other"}
+		for name, token := range map[string]string{
+			"expired":        sign(t, "known", key, expired),
+			"wrong issuer":   sign(t, "known", key, wrongIssuer),
+			"wrong audience": sign(t, "known", key, wrongAudience),
+			"wrong key":      sign(t, "known", otherKey, valid),
+			"unknown key":    sign(t, "unknown", otherKey, valid),
+			"not a jwt":      "not.a.jwt",
+		} {
+			t.Run("token="+name, func(t *testing.T) {
+				_, err := a.Authenticate(ctx, "read", "/relation-tuples", "Bearer "+token)
+				assertUnauthorized(t, err)
+			})
+		}
+	})
+
+	t.Run("case=rotated json web key sets", func(t *testing.T) {
+		var (
+			mx      sync.Mutex
+			keys    []jose.JSONWebKey
+			fetches int
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mx.Lock()
+			defer mx.Unlock()
+			fetches++
+			_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: keys})
+		}))
+		t.Cleanup(srv.Close)
+		publish := func(ks ...jose.JSONWebKey) {
+			mx.Lock()
+			defer mx.Unlock()
+			keys = ks
+		}
+		fetched := func() int {
+			mx.Lock()
+			defer mx.Unlock()
+			return fetches
+		}
+
+		a := newAuthenticator(t, map[string]interface{}{
+			config.KeyAuthnJWT: map[string]interface{}{"jwks_url": srv.URL},
+		})
+		newKey := func(t *testing.T, kid string) (*rsa.PrivateKey, jose.JSONWebKey) {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			require.NoError(t, err)
+			return key, jose.JSONWebKey{Key: key.Public(), KeyID: kid, Algorithm: string(jose.RS256), Use: "sig"}
+		}
+		token := func(t *testing.T, kid string, key *rsa.PrivateKey) string {
+			signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", kid))
+			require.NoError(t, err)
+			token, err := jwt.Signed(signer).Claims(jwt.Claims{Subject: "svc", Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}).CompactSerialize()
+			require.NoError(t, err)
+			return "Bearer " + token
+		}
+		oldKey, oldJWK := newKey(t, "old")
+		newPrivate, newJWK := newKey(t, "new")
+
+		authn.SetJWKSIntervals(t, time.Hour, 0)
+		publish(oldJWK)
+		_, err := a.Authenticate(ctx, "read", "/relation-tuples", token(t, "old", oldKey))
+		require.NoError(t, err)
+
+		// A newly published key is fetched on first use.
+		publish(oldJWK, newJWK)
+		_, err = a.Authenticate(ctx, "read", "/relation-tuples", token(t, "new", newPrivate))
+		require.NoError(t, err)
+
+		// Unknown keys are only fetched once per refresh interval.
+		authn.SetJWKSIntervals(t, time.Hour, time.Hour)
+		before := fetched()
+		for i := 0; i < 3; i++ {
+			_, err = a.Authenticate(ctx, "read", "/relation-tuples", token(t, "unknown", oldKey))
+			assertUnauthorized(t, err)
+		}
+		assert.Equal(t, before, fetched())
+
+		// Removed keys are rejected once the set expired.
+		publish(newJWK)
+		authn.SetJWKSIntervals(t, 0, 0)
+		_, err = a.Authenticate(ctx, "read", "/relation-tuples", token(t, "old", oldKey))
+		assertUnauthorized(t, err)
+		_, err = a.Authenticate(ctx, "read", "/relation-tuples", token(t, "new", newPrivate))
+		require.NoError(t, err)
+	})
+
+	t.Run("case=oauth2 token introspection", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, secret, _ := r.BasicAuth()
+			if id != "keto" || secret != "introspection-secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.PostFormValue("token") {
+			case "active-token":
+				_, _ = w.Write([]byte(`{"active": true, "sub": "alice", "scope": "keto:read", "tenant": "acme"}`))
+			case "client-token":
+				_, _ = w.Write([]byte(`{"active": true, "client_id": "svc"}`))
+			default:
+				_, _ = w.Write([]byte(`{"active": false}`))
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		a := newAuthenticator(t, map[string]interface{}{
+			config.KeyAuthnIntrospection: map[string]interface{}{
+				"url":           srv.URL,
+				"client_id":     "keto",
+				"client_secret": "introspection-secret",
+				"tenant_claim":  "tenant",
+			},
+		})
+
+		s, err := a.Authenticate(ctx, "write", "/admin/relation-tuples", "Bearer active-token")
+		require.NoError(t, err)
+		assert.Equal(t, &authn.Subject{ID: "alice", Authenticator: config.AuthenticatorIntrospection, Scopes: []string{"keto:read"}, Tenant: "acme"}, s)
+
+		s, err = a.Authenticate(ctx, "write", "/admin/relation-tuples", "Bearer client-token")
+		require.NoError(t, err)
+		assert.Equal(t, "svc", s.ID)
+		assert.Equal(t, "", s.Tenant)
+
+		_, err = a.Authenticate(ctx, "write", "/admin/relation-tuples", "Bearer inactive-token")
+		assertUnauthorized(t, err)
+	})
+
+	t.Run("case=caches introspection results until the token expires", func(t *testing.T) {
+		var (
+			mx             sync.Mutex
+			introspections = map[string]int{}
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.PostFormValue("token")
+			mx.Lock()
+			introspections[token]++
+			mx.Unlock()
+			switch token {
+			case "valid-token":
+				_, _ = fmt.Fprintf(w, `{"active": true, "sub": "alice", "exp": %d}`, time.Now().Add(time.Hour).Unix())
+			case "expired-token":
+				_, _ = fmt.Fprintf(w, `{"active": true, "sub": "alice", "exp": %d}`, time.Now().Add(-time.Minute).Unix())
+			case "no-expiry-token":
+				_, _ = w.Write([]byte(`{"active": true, "sub": "alice"}`))
+			default:
+				_, _ = w.Write([]byte(`{"active": false}`))
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		a := newAuthenticator(t, map[string]interface{}{
+			config.KeyAuthnIntrospection: map[string]interface{}{"url": srv.URL},
+		})
+		for i := 0; i < 3; i++ {
+			for _, token := range []string{"valid-token", "expired-token", "no-expiry-token"} {
+				s, err := a.Authenticate(ctx, "read", "/relation-tuples", "Bearer "+token)
+				require.NoError(t, err)
+				assert.Equal(t, "alice", s.ID)
+			}
+			_, err := a.Authenticate(ctx, "read", "/relation-tuples", "Bearer inactive-token")
+			assertUnauthorized(t, err)
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+		assert.Equal(t, map[string]int{"valid-token": 1, "expired-token": 3, "no-expiry-token": 3, "inactive-token": 3}, introspections)
+	})
+
+	t.Run("case=json web key set fetches are not canceled with the request", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		jwk := jose.JSONWebKey{Key: key.Public(), KeyID: "detached", Algorithm: string(jose.RS256), Use: "sig"}
+
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk}})
+		}))
+		t.Cleanup(srv.Close)
+
+		a := newAuthenticator(t, map[string]interface{}{
+			config.KeyAuthnJWT: map[string]interface{}{"jwks_url": srv.URL},
+		})
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "detached"))
+		require.NoError(t, err)
+		token, err := jwt.Signed(signer).Claims(jwt.Claims{Subject: "svc", Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}).CompactSerialize()
+		require.NoError(t, err)
+
+		// The first caller starts the fetch and gives up before it completes.
+		canceled, cancel := context.WithCancel(ctx)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		s, err := a.Authenticate(canceled, "read", "/relation-tuples", "Bearer "+token)
+		require.NoError(t, err)
+		assert.Equal(t, "svc", s.ID)
+	})
+
+	t.Run("case=context", func(t *testing.T) {
+		_, ok := authn.FromContext(ctx)
+		assert.False(t, ok)
+
+		s, ok := authn.FromContext(authn.NewContext(ctx, &authn.Subject{ID: "ci"}))
+		require.True(t, ok)
+		assert.Equal(t, "ci", s.ID)
+	})
+}
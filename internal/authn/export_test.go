@@ -0,0 +1,16 @@
+package authn
+
+import (
+	"testing"
+	"time"
+)
+
+// SetJWKSIntervals overrides the TTL and refresh interval of the JSON Web Key
+// Sets for the test.
+func SetJWKSIntervals(t testing.TB, ttl, refresh time.Duration) {
+	prevTTL, prevRefresh := jwksTTL, jwksRefreshInterval
+	jwksTTL, jwksRefreshInterval = ttl, refresh
+	t.Cleanup(func() {
+		jwksTTL, jwksRefreshInterval = prevTTL, prevRefresh
+	})
+}
@@ -0,0 +1,144 @@
+package authn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+// maxCachedIntrospections bounds the number of cached introspection results.
+// Once it is reached, further results are not cached until entries expire.
+const maxCachedIntrospections = 10_000
+
+type (
+	// introspection is the response of an OAuth 2.0 token introspection
+	// endpoint (RFC 7662).
+	introspection struct {
+		Active   bool   `json:"active"`
+		Subject  string `json:"sub"`
+		ClientID string `json:"client_id"`
+		Scope    string `json:"scope"`
+		Expiry   int64  `json:"exp"`
+	}
+
+	// cachedIntrospection is the subject of an active token until the token
+	// expires.
+	cachedIntrospection struct {
+		subject   Subject
+		expiresAt time.Time
+	}
+)
+
+func (a *Authenticator) introspect(ctx context.Context, token string) (*Subject, error) {
+	conf, err := a.d.Config(ctx).IntrospectionAuthenticator()
+	if err != nil {
+		return nil, err
+	} else if conf == nil {
+		return nil, errInvalidToken
+	}
+
+	// Only a hash of the token is kept in memory.
+	key := sha256.Sum256([]byte(conf.URL + "\x00" + token))
+	if s, ok := a.cachedIntrospection(key); ok {
+		return s, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, conf.URL,
+		strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if conf.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(conf.ClientSecret))
+	}
+
+	resp, err := a.hc.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("the introspection endpoint responded with status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the introspection response")
+	}
+	var (
+		i   introspection
+		all map[string]interface{}
+	)
+	if err := json.Unmarshal(body, &i); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the introspection response")
+	}
+	if err := json.Unmarshal(body, &all); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the introspection response")
+	}
+	if !i.Active {
+		return nil, errInvalidToken
+	}
+	id := i.Subject
+	if id == "" {
+		id = i.ClientID
+	}
+	if id == "" {
+		return nil, errInvalidToken
+	}
+	s := &Subject{ID: id, Authenticator: config.AuthenticatorIntrospection, Scopes: strings.Fields(i.Scope), Tenant: tenantClaim(all, conf.TenantClaim)}
+	if i.Expiry > 0 {
+		a.cacheIntrospection(key, s, time.Unix(i.Expiry, 0))
+	}
+	return s, nil
+}
+
+// cachedIntrospection returns the cached subject of the token, if the token
+// has not expired yet.
+func (a *Authenticator) cachedIntrospection(key [sha256.Size]byte) (*Subject, bool) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	c, ok := a.introspections[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(c.expiresAt) {
+		delete(a.introspections, key)
+		return nil, false
+	}
+	s := c.subject
+	return &s, true
+}
+
+// cacheIntrospection caches the subject of an active token until the token
+// expires. Tokens without an expiry are introspected on every request.
+func (a *Authenticator) cacheIntrospection(key [sha256.Size]byte, s *Subject, expiresAt time.Time) {
+	now := time.Now()
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if len(a.introspections) >= maxCachedIntrospections {
+		for k, c := range a.introspections {
+			if !now.Before(c.expiresAt) {
+				delete(a.introspections, k)
+			}
+		}
+		if len(a.introspections) >= maxCachedIntrospections {
+			return
+		}
+	}
+	a.introspections[key] = cachedIntrospection{subject: *s, expiresAt: expiresAt}
+}
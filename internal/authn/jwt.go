@@ -0,0 +1,183 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ory/x/fetcher"
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+var (
+	// jwksTTL is how long a JSON Web Key Set is used before it is fetched
+	// again, so that removed keys are no longer accepted. If the fetch fails,
+	// the cached keys are used until the next attempt.
+	jwksTTL = 5 * time.Minute
+	// jwksRefreshInterval is the minimum time between two fetches of a JSON
+	// Web Key Set, e.g. when a token is signed by an unknown key. It keeps
+	// tokens with made-up key IDs from causing a fetch each.
+	jwksRefreshInterval = time.Minute
+)
+
+// jwksFetchTimeout limits how long authentication waits for the JSON Web Key
+// Set.
+const jwksFetchTimeout = 10 * time.Second
+
+// keySet is a cached JSON Web Key Set.
+type keySet struct {
+	sync.Mutex
+	keys                   jose.JSONWebKeySet
+	fetchedAt, attemptedAt time.Time
+}
+
+func (a *Authenticator) jwt(ctx context.Context, token string) (*Subject, error) {
+	conf, err := a.d.Config(ctx).JWTAuthenticator()
+	if err != nil {
+		return nil, err
+	} else if conf == nil {
+		return nil, errInvalidToken
+	}
+
+	tok, err := jwt.ParseSigned(token)
+	if err != nil || len(tok.Headers) == 0 {
+		return nil, errInvalidToken
+	}
+	keys, err := a.keys(conf.JWKSURL, tok.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		claims jwt.Claims
+		extra  struct {
+			Scope string   `json:"scope"`
+			Scp   []string `json:"scp"`
+		}
+		all      map[string]interface{}
+		verified bool
+	)
+	for _, k := range keys {
+		if err := tok.Claims(k, &claims, &extra, &all); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errInvalidToken
+	}
+
+	expected := jwt.Expected{Issuer: conf.Issuer, Time: time.Now()}
+	if conf.Audience != "" {
+		expected.Audience = jwt.Audience{conf.Audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, jwt.DefaultLeeway); err != nil || claims.Subject == "" {
+		return nil, errInvalidToken
+	}
+
+	scopes := extra.Scp
+	if extra.Scope != "" {
+		scopes = strings.Fields(extra.Scope)
+	}
+	return &Subject{ID: claims.Subject, Authenticator: config.AuthenticatorJWT, Scopes: scopes, Tenant: tenantClaim(all, conf.TenantClaim)}, nil
+}
+
+// keys returns the keys of the set with the ID, or all keys if the token does
+// not name one. The set is fetched again after the TTL, or if it does not
+// contain the key, at most once per refresh interval. Concurrent fetches of
+// the same set are coalesced, and the set is not locked while fetching.
+func (a *Authenticator) keys(url, kid string) ([]jose.JSONWebKey, error) {
+	a.mx.Lock()
+	ks, ok := a.keySets[url]
+	if !ok {
+		ks = &keySet{}
+		a.keySets[url] = ks
+	}
+	a.mx.Unlock()
+
+	find := func() []jose.JSONWebKey {
+		if kid == "" {
+			return ks.keys.Keys
+		}
+		return ks.keys.Key(kid)
+	}
+
+	ks.Lock()
+	keys := find()
+	refresh := ks.fetchedAt.IsZero() ||
+		(time.Since(ks.fetchedAt) >= jwksTTL || len(keys) == 0) && time.Since(ks.attemptedAt) >= jwksRefreshInterval
+	ks.Unlock()
+	if !refresh {
+		return keys, nil
+	}
+
+	// The fetch is shared by all concurrent callers, so it must not be
+	// canceled with the request of the first one.
+	_, err, _ := a.jwksFetches.Do(url, func() (interface{}, error) {
+		set, err := a.fetchKeySet(context.Background(), url)
+
+		ks.Lock()
+		defer ks.Unlock()
+		ks.attemptedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		ks.keys, ks.fetchedAt = *set, ks.attemptedAt
+		return nil, nil
+	})
+
+	ks.Lock()
+	defer ks.Unlock()
+	if err != nil {
+		if ks.fetchedAt.IsZero() {
+			return nil, err
+		}
+		a.d.Logger().WithError(err).WithField("jwks_url", url).Warn("Could not refresh the JSON Web Key Set, using the cached keys.")
+	}
+	return find(), nil
+}
+
+// fetchKeySet fetches the JSON Web Key Set. Fetches from http(s) URLs time out
+// after jwksFetchTimeout.
+func (a *Authenticator) fetchKeySet(ctx context.Context, url string) (*jose.JSONWebKeySet, error) {
+	var raw io.Reader
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := a.hc.Do(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to fetch the JSON Web Key Set")
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("unable to fetch the JSON Web Key Set: the server responded with status code %d", resp.StatusCode)
+		}
+		raw = resp.Body
+	} else {
+		buf, err := fetcher.NewFetcher().Fetch(url)
+		if err != nil {
+			return nil, errors.WithMessage(err, "unable to fetch the JSON Web Key Set")
+		}
+		raw = buf
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(raw).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the JSON Web Key Set")
+	}
+	return &set, nil
+}
@@ -0,0 +1,116 @@
+// Package authz enforces the meta-permissions: which authenticated clients may
+// read and write in which namespaces, as defined by the relation tuples of a
+// reserved namespace.
+package authz
+
+import (
+	"context"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+
+	// AllNamespaces is the object that grants access to all namespaces. It is
+	// also required for requests that are not limited to one namespace.
+	AllNamespaces = "*"
+)
+
+type (
+	AuthorizerProvider interface {
+		Authorizer() *Authorizer
+	}
+	Authorizer struct {
+		d dependencies
+	}
+	dependencies interface {
+		config.Provider
+		x.LoggerProvider
+		check.EngineProvider
+		relationtuple.MapperProvider
+	}
+)
+
+var ErrAnonymous = herodot.ErrForbidden.WithReason("Meta-permissions are enabled, so the client has to authenticate.")
+
+func NewAuthorizer(d dependencies) *Authorizer {
+	return &Authorizer{d: d}
+}
+
+// Enabled returns whether meta-permissions are enforced.
+func (a *Authorizer) Enabled(ctx context.Context) bool {
+	return a.d.Config(ctx).MetaPermissionsNamespace() != ""
+}
+
+// Authorize returns an error unless the authenticated client of the context
+// may do the action ("read" or "write") in all of the namespaces. An empty
+// namespace stands for a request that is not limited to one namespace.
+func (a *Authorizer) Authorize(ctx context.Context, action string, namespaces ...string) error {
+	c := a.d.Config(ctx)
+	meta := c.MetaPermissionsNamespace()
+	if meta == "" {
+		return nil
+	}
+	s, ok := authn.FromContext(ctx)
+	if !ok {
+		return errors.WithStack(ErrAnonymous)
+	}
+	if slices.Contains(c.MetaPermissionsSuperusers(), s.ID) {
+		return nil
+	}
+
+	checked := make(map[string]bool, len(namespaces))
+	for _, n := range namespaces {
+		if n == "" {
+			n = AllNamespaces
+		}
+		if checked[n] {
+			continue
+		}
+		checked[n] = true
+
+		allowed, err := a.allowed(ctx, meta, n, action, s.ID)
+		if err != nil {
+			return err
+		}
+		if !allowed && n != AllNamespaces {
+			if allowed, err = a.allowed(ctx, meta, AllNamespaces, action, s.ID); err != nil {
+				return err
+			}
+		}
+		if !allowed {
+			if n == AllNamespaces {
+				return errors.WithStack(herodot.ErrForbidden.WithReasonf(
+					"The client %q may not %s in all namespaces, so the request has to be limited to one namespace.", s.ID, action))
+			}
+			return errors.WithStack(herodot.ErrForbidden.WithReasonf("The client %q may not %s in the namespace %q.", s.ID, action, n))
+		}
+	}
+	return nil
+}
+
+// allowed checks whether the client has the relation on the object in the
+// reserved namespace.
+func (a *Authorizer) allowed(ctx context.Context, meta, object, relation, client string) (bool, error) {
+	ts, err := a.d.Mapper().FromTuple(ctx, &ketoapi.RelationTuple{
+		Namespace: meta,
+		Object:    object,
+		Relation:  relation,
+		SubjectID: &client,
+	})
+	if err != nil {
+		return false, err
+	}
+	return a.d.PermissionEngine().CheckIsMember(ctx, ts[0], 0)
+}
@@ -0,0 +1,90 @@
+package authz_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/authz"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{
+		{Name: "meta"}, {Name: "groups"}, {Name: "docs"}, {Name: "secrets"},
+	}))
+
+	tuples := make([]*ketoapi.RelationTuple, 0, 4)
+	for _, s := range []string{
+		"meta:docs#read@ci",
+		"meta:*#read@auditor",
+		"meta:docs#write@groups:editors#member",
+		"groups:editors#member@bob",
+	} {
+		tuple, err := (&ketoapi.RelationTuple{}).FromString(s)
+		require.NoError(t, err)
+		tuples = append(tuples, tuple)
+	}
+	relationtuple.MapAndWriteTuples(t, reg, tuples...)
+
+	a := reg.Authorizer()
+	as := func(id string) context.Context {
+		return authn.NewContext(ctx, &authn.Subject{ID: id})
+	}
+	assertForbidden := func(t *testing.T, err error) {
+		t.Helper()
+		herodotErr := &herodot.DefaultError{}
+		require.ErrorAs(t, err, &herodotErr)
+		assert.Equal(t, http.StatusForbidden, herodotErr.StatusCode())
+	}
+
+	t.Run("case=disabled", func(t *testing.T) {
+		assert.False(t, a.Enabled(ctx))
+		assert.NoError(t, a.Authorize(ctx, authz.ActionWrite, "secrets"))
+	})
+
+	require.NoError(t, reg.Config(ctx).Set(config.KeyMetaPermissionsNamespace, "meta"))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyMetaPermissionsSuperusers, []string{"root"}))
+	require.True(t, a.Enabled(ctx))
+
+	for _, tc := range []struct {
+		client, action string
+		namespaces     []string
+		allowed        bool
+	}{
+		{client: "ci", action: authz.ActionRead, namespaces: []string{"docs"}, allowed: true},
+		{client: "ci", action: authz.ActionRead, namespaces: []string{"docs", "secrets"}},
+		{client: "ci", action: authz.ActionRead, namespaces: []string{""}},
+		{client: "ci", action: authz.ActionWrite, namespaces: []string{"docs"}},
+		{client: "auditor", action: authz.ActionRead, namespaces: []string{"secrets", "docs"}, allowed: true},
+		{client: "auditor", action: authz.ActionRead, namespaces: []string{""}, allowed: true},
+		{client: "auditor", action: authz.ActionWrite, namespaces: []string{"docs"}},
+		{client: "bob", action: authz.ActionWrite, namespaces: []string{"docs", "docs"}, allowed: true},
+		{client: "bob", action: authz.ActionWrite, namespaces: []string{"meta"}},
+		{client: "root", action: authz.ActionWrite, namespaces: []string{"meta", ""}, allowed: true},
+	} {
+		t.Run("client="+tc.client+"/action="+tc.action, func(t *testing.T) {
+			err := a.Authorize(as(tc.client), tc.action, tc.namespaces...)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assertForbidden(t, err)
+			}
+		})
+	}
+
+	t.Run("case=anonymous", func(t *testing.T) {
+		assertForbidden(t, a.Authorize(ctx, authz.ActionRead, "docs"))
+	})
+}
@@ -0,0 +1,41 @@
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// Change is a relation tuple change that was recorded in the outbox, but
+	// not yet published.
+	Change struct {
+		ID     int64
+		Action ketoapi.PatchAction
+		Tuple  *relationtuple.RelationTuple
+		Time   time.Time
+	}
+	Outbox interface {
+		// GetRelationTupleChanges returns the oldest changes in the outbox, at
+		// most limit many.
+		GetRelationTupleChanges(ctx context.Context, limit int) ([]*Change, error)
+		// DeleteRelationTupleChanges removes published changes from the outbox.
+		DeleteRelationTupleChanges(ctx context.Context, ids ...int64) error
+		// LeaseOutbox acquires or renews the lease on the outbox of the
+		// network for holder and returns whether holder has it. Only the
+		// holder of the lease publishes changes, so that replicas do not
+		// publish the same changes concurrently.
+		LeaseOutbox(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+	}
+	OutboxProvider interface {
+		RelationTupleOutbox() Outbox
+	}
+	// Sink publishes changes to a message broker. Publish must only return
+	// once the broker acknowledged all changes.
+	Sink interface {
+		Publish(ctx context.Context, changes []*ketoapi.RelationTupleChange) error
+		Close() error
+	}
+)
@@ -0,0 +1,40 @@
+package cdc
+
+import (
+	"context"
+
+	"github.com/ory/keto/internal/x/kafkax"
+	"github.com/ory/keto/ketoapi"
+)
+
+// KafkaSink publishes changes to a Kafka topic through a Confluent REST Proxy
+// (v2 API). It does not talk to the Kafka brokers directly, so the proxy has to
+// be deployed in front of the cluster. Records are keyed by the namespace and object of the relation tuple, so
+// that all changes to one object end up in the same partition and keep their
+// order.
+type KafkaSink struct {
+	p *kafkax.Producer
+}
+
+func NewKafkaSink(proxyURL, topic string) (*KafkaSink, error) {
+	p, err := kafkax.NewProducer(proxyURL, topic)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaSink{p: p}, nil
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, changes []*ketoapi.RelationTupleChange) error {
+	records := make([]*kafkax.Record, len(changes))
+	for i, c := range changes {
+		records[i] = &kafkax.Record{
+			Key:   c.RelationTuple.Namespace + ":" + c.RelationTuple.Object,
+			Value: c,
+		}
+	}
+	return s.p.Produce(ctx, records)
+}
+
+func (s *KafkaSink) Close() error {
+	return nil
+}
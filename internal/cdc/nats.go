@@ -0,0 +1,177 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+// NATSSink publishes changes to a NATS server. It speaks the plain text client
+// protocol and waits for the server to answer a PING after every batch, which
+// guarantees that all messages of the batch were processed.
+type NATSSink struct {
+	u       *url.URL
+	subject string
+
+	mx   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+const natsDialTimeout = 5 * time.Second
+
+func NewNATSSink(rawURL, subject string) (*NATSSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch u.Scheme {
+	case "nats", "tls":
+	default:
+		return nil, errors.Errorf("unsupported NATS URL scheme %q, expected nats or tls", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &NATSSink{u: u, subject: subject}, nil
+}
+
+func (s *NATSSink) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: natsDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", s.u.Host)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if s.u.Scheme == "tls" {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.u.Hostname(), MinVersion: tls.VersionTLS12})
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(natsDialTimeout))
+	}
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		_ = conn.Close()
+		return errors.WithStack(err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		_ = conn.Close()
+		return errors.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "ory-keto",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if user := s.u.User; user != nil {
+		if pass, ok := user.Password(); ok {
+			opts["user"], opts["pass"] = user.Username(), pass
+		} else {
+			opts["auth_token"] = user.Username()
+		}
+	}
+	connectOpts, err := json.Marshal(opts)
+	if err != nil {
+		_ = conn.Close()
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", connectOpts); err != nil {
+		_ = conn.Close()
+		return errors.WithStack(err)
+	}
+
+	s.conn, s.r = conn, r
+	return nil
+}
+
+func (s *NATSSink) Publish(ctx context.Context, changes []*ketoapi.RelationTupleChange) (err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		// The connection state is unknown after an error, so we reconnect on
+		// the next publish.
+		if err != nil {
+			_ = s.conn.Close()
+			s.conn, s.r = nil, nil
+		}
+	}()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsDialTimeout)
+	}
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return errors.WithStack(err)
+	}
+
+	w := bufio.NewWriter(s.conn)
+	for _, c := range changes {
+		payload, err := json.Marshal(c)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if _, err := fmt.Fprintf(w, "PUB %s %d\r\n%s\r\n", s.subject, len(payload), payload); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := w.WriteString("PING\r\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch op := strings.TrimSpace(line); {
+		case op == "PONG":
+			return nil
+		case op == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return errors.WithStack(err)
+			}
+		case strings.HasPrefix(op, "-ERR"):
+			return errors.Errorf("NATS server returned an error: %s", strings.TrimSpace(strings.TrimPrefix(op, "-ERR")))
+		default:
+			// +OK and INFO messages can be ignored.
+		}
+	}
+}
+
+func (s *NATSSink) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.r = nil, nil
+	return errors.WithStack(err)
+}
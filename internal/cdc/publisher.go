@@ -0,0 +1,162 @@
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/tenancy"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	PublisherDependencies interface {
+		OutboxProvider
+		relationtuple.MapperProvider
+		config.Provider
+		x.LoggerProvider
+	}
+	Publisher struct {
+		d      PublisherDependencies
+		sink   Sink
+		holder string
+	}
+)
+
+// leaseTTL is how long the lease on an outbox is held without being renewed.
+// A publisher renews it before every batch, so that another replica only
+// takes over once the holder stopped publishing.
+const leaseTTL = 30 * time.Second
+
+func NewPublisher(d PublisherDependencies, sink Sink) *Publisher {
+	return &Publisher{d: d, sink: sink, holder: uuid.Must(uuid.NewV4()).String()}
+}
+
+// NewSink creates the sink that is configured in c.
+func NewSink(c config.CDCSinkConfig) (Sink, error) {
+	if c.URL == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("%s must be set to enable change data capture", config.KeyCDCSinkURL))
+	}
+	switch c.Type {
+	case "nats":
+		return NewNATSSink(c.URL, c.Topic)
+	case "kafka":
+		return NewKafkaSink(c.URL, c.Topic)
+	default:
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("unknown change data capture sink type %q", c.Type))
+	}
+}
+
+// Run publishes pending changes until the context is canceled. Failed
+// publishes are retried on the next tick, so that every change is delivered at
+// least once.
+func (p *Publisher) Run(ctx context.Context) error {
+	defer p.sink.Close()
+
+	ticker := time.NewTicker(p.d.Config(ctx).CDCPollInterval())
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PublishPending(ctx); err != nil {
+			p.d.Logger().WithError(err).Error("could not publish relation tuple changes, will retry")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes all changes that are currently in the outboxes of
+// all tenants and returns how many were published.
+func (p *Publisher) PublishPending(ctx context.Context) (int, error) {
+	ctxs, err := tenancy.Contexts(ctx, p.d)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, ctx := range ctxs {
+		n, err := p.publishPending(ctx)
+		published += n
+		if err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+// publishPending publishes the changes in the outbox of the network of the
+// context. It publishes nothing while another publisher holds the lease on
+// the outbox.
+func (p *Publisher) publishPending(ctx context.Context) (int, error) {
+	batchSize := p.d.Config(ctx).CDCBatchSize()
+	published := 0
+
+	for {
+		leased, err := p.d.RelationTupleOutbox().LeaseOutbox(ctx, p.holder, leaseTTL)
+		if err != nil || !leased {
+			return published, err
+		}
+
+		changes, err := p.d.RelationTupleOutbox().GetRelationTupleChanges(ctx, batchSize)
+		if err != nil {
+			return published, err
+		}
+		if len(changes) == 0 {
+			return published, nil
+		}
+
+		events, err := p.toEvents(ctx, changes)
+		if err != nil {
+			return published, err
+		}
+		if err := p.sink.Publish(ctx, events); err != nil {
+			return published, err
+		}
+
+		ids := make([]int64, len(changes))
+		for i, c := range changes {
+			ids[i] = c.ID
+		}
+		if err := p.d.RelationTupleOutbox().DeleteRelationTupleChanges(ctx, ids...); err != nil {
+			return published, err
+		}
+		published += len(changes)
+	}
+}
+
+func (p *Publisher) toEvents(ctx context.Context, changes []*Change) ([]*ketoapi.RelationTupleChange, error) {
+	its := make([]*relationtuple.RelationTuple, len(changes))
+	for i, c := range changes {
+		its[i] = c.Tuple
+	}
+	tuples, err := p.d.Mapper().ToTuple(ctx, its...)
+	if err != nil {
+		return nil, err
+	}
+	if len(tuples) != len(changes) {
+		return nil, errors.WithStack(fmt.Errorf("expected %d mapped relation tuples, got %d", len(changes), len(tuples)))
+	}
+
+	tenant, _ := tenancy.FromContext(ctx)
+	events := make([]*ketoapi.RelationTupleChange, len(changes))
+	for i, c := range changes {
+		events[i] = &ketoapi.RelationTupleChange{
+			Action:        c.Action,
+			RelationTuple: tuples[i],
+			Time:          c.Time,
+			Tenant:        tenant,
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,162 @@
+package cdc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/cdc"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+var testChanges = []*ketoapi.RelationTupleChange{{
+	Action:        ketoapi.ActionInsert,
+	RelationTuple: &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")},
+	Time:          time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+}, {
+	Action:        ketoapi.ActionDelete,
+	RelationTuple: &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")},
+	Time:          time.Date(2022, 1, 1, 0, 0, 1, 0, time.UTC),
+}}
+
+// fakeNATSServer accepts a single connection and records all published
+// messages.
+func fakeNATSServer(t *testing.T, reply string) (addr string, published <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	msgs := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PUB":
+				n, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, n+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				msgs <- fields[1] + " " + string(payload[:n])
+			case "PING":
+				_, _ = conn.Write([]byte(reply))
+			}
+		}
+	}()
+
+	return l.Addr().String(), msgs
+}
+
+func TestNATSSink(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("case=publishes and waits for the server", func(t *testing.T) {
+		addr, published := fakeNATSServer(t, "PONG\r\n")
+
+		s, err := cdc.NewNATSSink("nats://"+addr, "keto.changes")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+
+		require.NoError(t, s.Publish(ctx, testChanges))
+		for _, c := range testChanges {
+			expected, err := json.Marshal(c)
+			require.NoError(t, err)
+			assert.Equal(t, "keto.changes "+string(expected), <-published)
+		}
+	})
+
+	t.Run("case=reports server errors", func(t *testing.T) {
+		addr, _ := fakeNATSServer(t, "-ERR 'Permissions Violation'\r\n")
+
+		s, err := cdc.NewNATSSink("nats://"+addr, "keto.changes")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+
+		assert.ErrorContains(t, s.Publish(ctx, testChanges), "Permissions Violation")
+	})
+
+	t.Run("case=rejects unknown scheme", func(t *testing.T) {
+		_, err := cdc.NewNATSSink("http://127.0.0.1:4222", "keto.changes")
+		assert.Error(t, err)
+	})
+}
+
+func TestKafkaSink(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		status  int
+		reply   string
+		records []json.RawMessage
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/keto.changes", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+
+		var body struct {
+			Records []json.RawMessage `json:"records"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		records = body.Records
+
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(ts.Close)
+
+	s, err := cdc.NewKafkaSink(ts.URL+"/", "keto.changes")
+	require.NoError(t, err)
+
+	t.Run("case=publishes keyed records", func(t *testing.T) {
+		status, reply = http.StatusOK, `{"offsets":[{"partition":0,"offset":1},{"partition":0,"offset":2}]}`
+
+		require.NoError(t, s.Publish(ctx, testChanges))
+		require.Len(t, records, 2)
+
+		var rec struct {
+			Key   string                      `json:"key"`
+			Value ketoapi.RelationTupleChange `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal(records[1], &rec))
+		assert.Equal(t, "n:o", rec.Key)
+		assert.Equal(t, *testChanges[1], rec.Value)
+	})
+
+	t.Run("case=reports record errors", func(t *testing.T) {
+		status, reply = http.StatusOK, `{"offsets":[{"partition":0,"offset":1},{"error_code":50003,"error":"timeout"}]}`
+
+		assert.ErrorContains(t, s.Publish(ctx, testChanges), "timeout")
+	})
+
+	t.Run("case=reports status errors", func(t *testing.T) {
+		status, reply = http.StatusNotFound, `{"error_code":40401,"message":"Topic not found."}`
+
+		assert.ErrorContains(t, s.Publish(ctx, testChanges), "404")
+	})
+}
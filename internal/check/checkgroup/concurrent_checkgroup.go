@@ -3,8 +3,17 @@ package checkgroup
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 )
 
+// running is the number of subchecks of all checkgroups that are running.
+var running int64
+
+// Running returns the number of goroutines that currently run subchecks.
+func Running() int64 {
+	return atomic.LoadInt64(&running)
+}
+
 // A concurrentCheckgroup is a collection of goroutines performing checks.
 type concurrentCheckgroup struct {
 	// ctx is the main context of the checkgroup. If ctx is cancelled, all
@@ -92,7 +101,11 @@ func (g *concurrentCheckgroup) startConsumer() {
 						continue
 					}
 					totalChecks++
-					go check(g.subcheckCtx, resultCh)
+					atomic.AddInt64(&running, 1)
+					go func() {
+						defer atomic.AddInt64(&running, -1)
+						check(g.subcheckCtx, resultCh)
+					}()
 
 				case <-g.finalizeCh:
 					if finalizing {
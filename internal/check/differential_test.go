@@ -0,0 +1,234 @@
+package check_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+// referenceEvaluator is a slow but obviously correct evaluator of checks. It
+// follows the definition of subject-set rewrites literally, without
+// concurrency, pagination, depth limits, or cycle detection. Therefore, it only
+// terminates on models and relation tuples without cycles.
+type referenceEvaluator struct {
+	namespaces map[string]*namespace.Namespace
+	tuples     []*relationtuple.RelationTuple
+}
+
+func (e *referenceEvaluator) isMember(ns string, object uuid.UUID, relation string, subject relationtuple.Subject) bool {
+	for _, t := range e.tuples {
+		if t.Namespace != ns || t.Object != object || t.Relation != relation {
+			continue
+		}
+		if t.Subject.Equals(subject) {
+			return true
+		}
+		if s, ok := t.Subject.(*relationtuple.SubjectSet); ok && s.Relation != check.WildcardRelation && e.isMember(s.Namespace, s.Object, s.Relation, subject) {
+			return true
+		}
+	}
+
+	for _, r := range e.namespaces[ns].Relations {
+		if r.Name == relation && r.SubjectSetRewrite != nil {
+			return e.eval(ns, object, r.SubjectSetRewrite, subject)
+		}
+	}
+	return false
+}
+
+func (e *referenceEvaluator) eval(ns string, object uuid.UUID, rewrite ast.Child, subject relationtuple.Subject) bool {
+	switch c := rewrite.(type) {
+	case *ast.SubjectSetRewrite:
+		for _, child := range c.Children {
+			isMember := e.eval(ns, object, child, subject)
+			if c.Operation == ast.OperatorAnd && !isMember {
+				return false
+			} else if c.Operation == ast.OperatorOr && isMember {
+				return true
+			}
+		}
+		return c.Operation == ast.OperatorAnd
+	case *ast.ComputedSubjectSet:
+		return e.isMember(ns, object, c.Relation, subject)
+	case *ast.TupleToSubjectSet:
+		for _, t := range e.tuples {
+			if t.Namespace != ns || t.Object != object || t.Relation != c.Relation {
+				continue
+			}
+			if s, ok := t.Subject.(*relationtuple.SubjectSet); ok && e.isMember(s.Namespace, s.Object, c.ComputedSubjectSetRelation, subject) {
+				return true
+			}
+		}
+		return false
+	case *ast.InvertResult:
+		return !e.eval(ns, object, c.Child, subject)
+	}
+	panic(fmt.Sprintf("unknown rewrite %T", rewrite))
+}
+
+// randomModel generates namespaces with the same relations and random
+// rewrites, and relation tuples between their objects. To keep the model
+// acyclic, computed subject sets only reference relations that are declared
+// earlier, and subject sets only reference objects that come earlier in the
+// order of namespaces and objects.
+type randomModel struct {
+	r          *rand.Rand
+	namespaces []*namespace.Namespace
+	tuples     []string
+}
+
+const (
+	differentialNamespaces = 3
+	differentialObjects    = 3
+	differentialRelations  = 4
+	differentialUsers      = 3
+)
+
+func newRandomModel(seed int64) *randomModel {
+	m := &randomModel{r: rand.New(rand.NewSource(seed))}
+	for n := 0; n < differentialNamespaces; n++ {
+		ns := &namespace.Namespace{Name: fmt.Sprintf("n%d", n)}
+		for rel := 0; rel < differentialRelations; rel++ {
+			r := ast.Relation{Name: fmt.Sprintf("r%d", rel)}
+			if rel > 0 && m.r.Intn(4) > 0 {
+				r.SubjectSetRewrite = m.rewrite(rel, 2)
+			}
+			ns.Relations = append(ns.Relations, r)
+		}
+		m.namespaces = append(m.namespaces, ns)
+	}
+
+	for n := 0; n < differentialNamespaces; n++ {
+		for o := 0; o < differentialObjects; o++ {
+			for rel := 0; rel < differentialRelations; rel++ {
+				for i := m.r.Intn(3); i > 0; i-- {
+					m.tuples = append(m.tuples, fmt.Sprintf("n%d:o%d#r%d@%s", n, o, rel, m.subject(n, o)))
+				}
+			}
+		}
+	}
+	return m
+}
+
+// rewrite returns a random rewrite of the relation with the index rel.
+func (m *randomModel) rewrite(rel, depth int) *ast.SubjectSetRewrite {
+	rw := &ast.SubjectSetRewrite{Operation: ast.OperatorOr}
+	if m.r.Intn(2) == 0 {
+		rw.Operation = ast.OperatorAnd
+	}
+	for i := m.r.Intn(3); i >= 0; i-- {
+		rw.Children = append(rw.Children, m.child(rel, depth))
+	}
+	return rw
+}
+
+func (m *randomModel) child(rel, depth int) ast.Child {
+	switch m.r.Intn(5) {
+	case 0:
+		if depth > 0 {
+			return m.rewrite(rel, depth-1)
+		}
+	case 1:
+		if depth > 0 {
+			return &ast.InvertResult{Child: m.child(rel, depth-1)}
+		}
+	case 2:
+		return &ast.TupleToSubjectSet{
+			Relation:                   fmt.Sprintf("r%d", m.r.Intn(differentialRelations)),
+			ComputedSubjectSetRelation: fmt.Sprintf("r%d", m.r.Intn(differentialRelations)),
+		}
+	}
+	return &ast.ComputedSubjectSet{Relation: fmt.Sprintf("r%d", m.r.Intn(rel))}
+}
+
+// subject returns a user, or a subject set of an object before the object o in
+// the namespace n.
+func (m *randomModel) subject(n, o int) string {
+	before := n*differentialObjects + o
+	if before == 0 || m.r.Intn(3) == 0 {
+		return m.user()
+	}
+	i := m.r.Intn(before)
+	relation := check.WildcardRelation
+	if m.r.Intn(3) > 0 {
+		relation = fmt.Sprintf("r%d", m.r.Intn(differentialRelations))
+	}
+	return fmt.Sprintf("n%d:o%d#%s", i/differentialObjects, i%differentialObjects, relation)
+}
+
+func (m *randomModel) user() string {
+	return fmt.Sprintf("u%d", m.r.Intn(differentialUsers))
+}
+
+func (m *randomModel) String() string {
+	var sb strings.Builder
+	for _, ns := range m.namespaces {
+		raw, _ := json.Marshal(ns.Relations)
+		_, _ = fmt.Fprintf(&sb, "namespace %s: %s\n", ns.Name, raw)
+	}
+	return fmt.Sprintf("%s\nrelation tuples:\n%s", sb.String(), strings.Join(m.tuples, "\n"))
+}
+
+// TestDifferential compares the results of the engine with the reference
+// evaluator for all checks on random models.
+func TestDifferential(t *testing.T) {
+	models := 50
+	if testing.Short() {
+		models = 10
+	}
+
+	for seed := int64(0); seed < int64(models); seed++ {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			m := newRandomModel(seed)
+
+			reg := newDepsProvider(t, m.namespaces)
+			require.NoError(t, reg.Config(ctx).Set(config.KeyLimitMaxReadDepth, 100))
+			insertFixtures(t, reg.RelationTupleManager(), m.tuples)
+			e := check.NewEngine(reg)
+
+			ref := &referenceEvaluator{namespaces: make(map[string]*namespace.Namespace)}
+			for _, ns := range m.namespaces {
+				ref.namespaces[ns.Name] = ns
+			}
+			for _, s := range m.tuples {
+				ref.tuples = append(ref.tuples, tupleFromString(t, s))
+			}
+
+			var failed []string
+			for n := 0; n < differentialNamespaces; n++ {
+				for o := 0; o < differentialObjects; o++ {
+					for rel := 0; rel < differentialRelations; rel++ {
+						for u := 0; u < differentialUsers; u++ {
+							query := fmt.Sprintf("n%d:o%d#r%d@u%d", n, o, rel, u)
+							tuple := tupleFromString(t, query)
+
+							expected := ref.isMember(tuple.Namespace, tuple.Object, tuple.Relation, tuple.Subject)
+							actual, err := e.CheckIsMember(ctx, tuple, 100)
+							require.NoError(t, err, query)
+							if actual != expected {
+								failed = append(failed, fmt.Sprintf("%s: expected %t, got %t", query, expected, actual))
+							}
+						}
+					}
+				}
+			}
+			if len(failed) > 0 {
+				t.Fatalf("the engine differs from the reference evaluator:\n%s\n\n%s", strings.Join(failed, "\n"), m)
+			}
+		})
+	}
+}
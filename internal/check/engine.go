@@ -3,12 +3,12 @@ package check
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ory/herodot"
 	"github.com/pkg/errors"
 
 	"github.com/ory/keto/internal/check/checkgroup"
-	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/namespace"
 	"github.com/ory/keto/internal/namespace/ast"
 	"github.com/ory/keto/internal/relationtuple"
@@ -22,13 +22,31 @@ type (
 		PermissionEngine() *Engine
 	}
 	Engine struct {
-		d EngineDependencies
+		d      EngineDependencies
+		config func(ctx context.Context) Config
+		mapper TupleMapper
+		m      *Metrics
 	}
+	// EngineDependencies are the dependencies of the engine. If they also
+	// provide the configuration and the mapper of the registry, the engine
+	// uses them, except in WebAssembly builds, which have no registry.
 	EngineDependencies interface {
 		relationtuple.ManagerProvider
-		config.Provider
 		x.LoggerProvider
 	}
+	// Config is the configuration that the engine reads on every check.
+	Config interface {
+		MaxReadDepth() int
+		NamespaceManager() (namespace.Manager, error)
+		SlowChecksThreshold() time.Duration
+		SlowChecksIncludeTree() bool
+	}
+	// TupleMapper maps relation tuples back to their names for the logs of
+	// slow checks. Without one, the logs contain the relation tuples with
+	// UUIDs.
+	TupleMapper interface {
+		ToTuple(ctx context.Context, ts ...*relationtuple.RelationTuple) ([]*ketoapi.RelationTuple, error)
+	}
 
 	EngineOpt func(*Engine)
 
@@ -39,8 +57,17 @@ type (
 
 const WildcardRelation = "..."
 
+// WithConfig sets the configuration of the engine, which is required if the
+// dependencies do not provide it.
+func WithConfig(c func(ctx context.Context) Config) EngineOpt {
+	return func(e *Engine) {
+		e.config = c
+	}
+}
+
 func NewEngine(d EngineDependencies, opts ...EngineOpt) *Engine {
-	e := &Engine{d: d}
+	e := &Engine{d: d, m: NewMetrics()}
+	useRegistry(e)
 	for _, opt := range opts {
 		opt(e)
 	}
@@ -65,18 +92,39 @@ func (e *Engine) CheckIsMember(ctx context.Context, r *relationTuple, restDepth
 func (e *Engine) CheckRelationTuple(ctx context.Context, r *relationTuple, restDepth int) checkgroup.Result {
 	// global max-depth takes precedence when it is the lesser or if the request
 	// max-depth is less than or equal to 0
-	if globalMaxDepth := e.d.Config(ctx).MaxReadDepth(); restDepth <= 0 || globalMaxDepth < restDepth {
+	if globalMaxDepth := e.config(ctx).MaxReadDepth(); restDepth <= 0 || globalMaxDepth < restDepth {
 		restDepth = globalMaxDepth
 	}
 
+	start := time.Now()
+	ctx, stats := withStats(ctx, restDepth)
+
+	var result checkgroup.Result
 	resultCh := make(chan checkgroup.Result)
 	go e.checkIsAllowed(ctx, r, restDepth)(ctx, resultCh)
 	select {
-	case result := <-resultCh:
-		return result
+	case result = <-resultCh:
 	case <-ctx.Done():
-		return checkgroup.Result{Err: errors.WithStack(ctx.Err())}
+		result = checkgroup.Result{Err: errors.WithStack(ctx.Err())}
 	}
+
+	duration := time.Since(start)
+	e.m.observe(r.Namespace, result, duration, stats)
+	e.logSlowCheck(ctx, r, result, duration, stats)
+	return result
+}
+
+// Metrics returns the metrics of the checks of the engine.
+func (e *Engine) Metrics() *Metrics {
+	return e.m
+}
+
+// getRelationTuples queries the relation tuples and records the query in the
+// statistics of the check.
+func (e *Engine) getRelationTuples(ctx context.Context, q *query, opts ...x.PaginationOptionSetter) ([]*relationTuple, string, error) {
+	res, next, err := e.d.RelationTupleManager().GetRelationTuples(ctx, q, opts...)
+	statsFromContext(ctx).query(len(res))
+	return res, next, err
 }
 
 // checkExpandSubject checks the expansions of the subject set of the tuple.
@@ -107,7 +155,7 @@ func (e *Engine) checkExpandSubject(r *relationTuple, restDepth int) checkgroup.
 			query     = &query{Namespace: &r.Namespace, Object: &r.Object, Relation: &r.Relation}
 		)
 		for {
-			subjects, pageToken, err = e.d.RelationTupleManager().GetRelationTuples(innerCtx, query, x.WithToken(pageToken))
+			subjects, pageToken, err = e.getRelationTuples(innerCtx, query, x.WithToken(pageToken))
 			if errors.Is(err, herodot.ErrNotFound) {
 				g.Add(checkgroup.NotMemberFunc)
 				break
@@ -156,7 +204,7 @@ func (e *Engine) checkDirect(r *relationTuple, restDepth int) checkgroup.CheckFu
 		e.d.Logger().
 			WithField("request", r.String()).
 			Trace("check direct")
-		if rels, _, err := e.d.RelationTupleManager().GetRelationTuples(
+		if rels, _, err := e.getRelationTuples(
 			ctx,
 			r.ToQuery(),
 			x.WithSize(1),
@@ -176,6 +224,69 @@ func (e *Engine) checkDirect(r *relationTuple, restDepth int) checkgroup.CheckFu
 	}
 }
 
+// closureManager returns nil if the dependencies do not provide closures.
+func (e *Engine) closureManager() relationtuple.ClosureManager {
+	if p, ok := e.d.(relationtuple.ClosureManagerProvider); ok {
+		return p.RelationTupleClosureManager()
+	}
+	return nil
+}
+
+// hasClosure returns whether the subject ID of the relation tuple can be looked
+// up in the closure of the relation.
+func (e *Engine) hasClosure(ctx context.Context, r *relationTuple) (bool, error) {
+	if _, ok := r.Subject.(*relationtuple.SubjectID); !ok {
+		return false, nil
+	}
+	cm := e.closureManager()
+	if cm == nil {
+		return false, nil
+	}
+	return cm.HasClosure(ctx, r.Namespace, r.Relation)
+}
+
+// checkClosure looks the subject up in the closure of the relation, and
+// otherwise checks the subject sets where the closure stops.
+func (e *Engine) checkClosure(r *relationTuple, restDepth int) checkgroup.CheckFunc {
+	return func(ctx context.Context, resultCh chan<- checkgroup.Result) {
+		e.d.Logger().
+			WithField("request", r.String()).
+			Trace("check closure")
+
+		isMember, open, err := e.closureManager().CheckClosure(ctx, r)
+		statsFromContext(ctx).query(len(open))
+		if err != nil {
+			resultCh <- checkgroup.Result{Err: err}
+			return
+		} else if isMember {
+			resultCh <- checkgroup.Result{
+				Membership: checkgroup.IsMember,
+				Tree: &ketoapi.Tree[*relationtuple.RelationTuple]{
+					Type:  ketoapi.TreeNodeLeaf,
+					Tuple: r,
+				},
+			}
+			return
+		}
+
+		g := checkgroup.New(ctx)
+		g.Add(checkgroup.NotMemberFunc)
+		for _, s := range open {
+			g.Add(e.checkIsAllowed(
+				ctx,
+				&relationTuple{
+					Namespace: s.Namespace,
+					Object:    s.Object,
+					Relation:  s.Relation,
+					Subject:   r.Subject,
+				},
+				restDepth-1,
+			))
+		}
+		resultCh <- g.Result()
+	}
+}
+
 // checkIsAllowed checks if the relation tuple is allowed (there is a path from
 // the relation tuple subject to the namespace, object and relation) either
 // directly (in the database), or through subject-set expansions, or through
@@ -191,8 +302,18 @@ func (e *Engine) checkIsAllowed(ctx context.Context, r *relationTuple, restDepth
 	e.d.Logger().
 		WithField("request", r.String()).
 		Trace("check is allowed")
+	statsFromContext(ctx).visit(restDepth)
 
 	g := checkgroup.New(ctx)
+	if closed, err := e.hasClosure(ctx, r); err != nil {
+		g.Add(checkgroup.ErrorFunc(err))
+		return g.CheckFunc()
+	} else if closed {
+		// The closure contains the relation tuples and subject set expansions,
+		// and relations with a closure have no rewrites.
+		g.Add(e.checkClosure(r, restDepth))
+		return g.CheckFunc()
+	}
 	g.Add(e.checkDirect(r, restDepth-1))
 	g.Add(e.checkExpandSubject(r, restDepth))
 
@@ -229,7 +350,7 @@ func (e *Engine) astRelationFor(ctx context.Context, r *relationTuple) (*ast.Rel
 }
 
 func (e *Engine) namespaceFor(ctx context.Context, r *relationTuple) (*namespace.Namespace, error) {
-	namespaceManager, err := e.d.Config(ctx).NamespaceManager()
+	namespaceManager, err := e.config(ctx).NamespaceManager()
 	if err != nil {
 		return nil, err
 	}
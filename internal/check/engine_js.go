@@ -0,0 +1,7 @@
+//go:build js
+
+package check
+
+// useRegistry does nothing, because the registry does not build for js. The
+// configuration has to be set with WithConfig.
+func useRegistry(*Engine) {}
@@ -0,0 +1,32 @@
+//go:build !js
+
+package check
+
+import (
+	"context"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+// registryMapper maps relation tuples through the mapper of the registry,
+// which is only created when it is used.
+type registryMapper struct {
+	p relationtuple.MapperProvider
+}
+
+func (m registryMapper) ToTuple(ctx context.Context, ts ...*relationtuple.RelationTuple) ([]*ketoapi.RelationTuple, error) {
+	return m.p.Mapper().ToTuple(ctx, ts...)
+}
+
+// useRegistry uses the configuration and the mapper of the dependencies, if
+// they provide them.
+func useRegistry(e *Engine) {
+	if p, ok := e.d.(config.Provider); ok {
+		e.config = func(ctx context.Context) Config { return p.Config(ctx) }
+	}
+	if p, ok := e.d.(relationtuple.MapperProvider); ok {
+		e.mapper = registryMapper{p: p}
+	}
+}
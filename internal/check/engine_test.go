@@ -19,12 +19,14 @@ import (
 
 type configProvider = config.Provider
 type loggerProvider = x.LoggerProvider
+type mapperProvider = relationtuple.MapperProvider
 
 // deps is defined to capture engine dependencies in a single struct
 type deps struct {
 	*relationtuple.ManagerWrapper // managerProvider
 	configProvider
 	loggerProvider
+	mapperProvider
 }
 
 func newDepsProvider(t testing.TB, namespaces []*namespace.Namespace, pageOpts ...x.PaginationOptionSetter) *deps {
@@ -36,6 +38,7 @@ func newDepsProvider(t testing.TB, namespaces []*namespace.Namespace, pageOpts .
 		ManagerWrapper: mr,
 		configProvider: reg,
 		loggerProvider: reg,
+		mapperProvider: reg,
 	}
 }
 
@@ -1,3 +1,5 @@
+//go:build !js
+
 package check
 
 import (
@@ -15,6 +17,9 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"google.golang.org/grpc"
 
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/quota"
 	"github.com/ory/keto/internal/relationtuple"
 	"github.com/ory/keto/internal/x"
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
@@ -23,6 +28,8 @@ import (
 type (
 	handlerDependencies interface {
 		EngineProvider
+		audit.LoggerProvider
+		quota.EnforcerProvider
 		relationtuple.ManagerProvider
 		relationtuple.MapperProvider
 		x.LoggerProvider
@@ -56,6 +63,8 @@ func (h *Handler) RegisterReadRoutes(r *x.ReadRouter) {
 
 func (h *Handler) RegisterWriteRoutes(_ *x.WriteRouter) {}
 
+func (h *Handler) RegisterAdminRoutes(_ *x.AdminRouter) {}
+
 func (h *Handler) RegisterReadGRPC(s *grpc.Server) {
 	rts.RegisterCheckServiceServer(s, h)
 }
@@ -152,6 +161,18 @@ func (h *Handler) getCheck(ctx context.Context, q url.Values) (bool, error) {
 		return false, err
 	}
 
+	return h.check(ctx, tuple, maxDepth)
+}
+
+// check checks the relation tuple and records the decision in the audit log.
+func (h *Handler) check(ctx context.Context, tuple *ketoapi.RelationTuple, maxDepth int) (allowed bool, err error) {
+	if err := h.d.QuotaEnforcer().Check(ctx, tuple); err != nil {
+		return false, err
+	}
+	defer func() {
+		h.d.AuditLogger().Check(ctx, tuple, allowed, err)
+	}()
+
 	it, err := h.d.Mapper().FromTuple(ctx, tuple)
 	// herodot.ErrNotFound occurs when the namespace is unknown
 	if errors.Is(err, herodot.ErrNotFound) {
@@ -234,15 +255,7 @@ func (h *Handler) postCheck(ctx context.Context, body io.Reader, query url.Value
 	if err := json.NewDecoder(body).Decode(&tuple); err != nil {
 		return false, herodot.ErrBadRequest.WithErrorf("could not unmarshal json: %s", err.Error())
 	}
-	t, err := h.d.Mapper().FromTuple(ctx, &tuple)
-	// herodot.ErrNotFound occurs when the namespace is unknown
-	if errors.Is(err, herodot.ErrNotFound) {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-
-	return h.d.PermissionEngine().CheckIsMember(ctx, t[0], maxDepth)
+	return h.check(ctx, &tuple, maxDepth)
 }
 
 func (h *Handler) Check(ctx context.Context, req *rts.CheckRequest) (*rts.CheckResponse, error) {
@@ -258,18 +271,41 @@ func (h *Handler) Check(ctx context.Context, req *rts.CheckRequest) (*rts.CheckR
 		return nil, err
 	}
 
+	if req.Latest {
+		ctx = persistence.WithConsistency(ctx, persistence.ConsistencyStrong)
+	}
+
+	// Managers that read at snapshots evaluate the check no earlier than the
+	// requested snaptoken, or at the latest snapshot, whose token is returned.
+	snaptoken := "not yet implemented"
+	if s, ok := h.d.RelationTupleManager().(relationtuple.Snapshotter); ok {
+		snaptoken = ""
+		if req.Snaptoken != "" && !req.Latest {
+			ctx = relationtuple.WithSnaptoken(ctx, req.Snaptoken)
+		} else {
+			if snaptoken, err = s.Snaptoken(ctx); err != nil {
+				return nil, err
+			}
+			ctx = relationtuple.WithSnaptoken(ctx, snaptoken)
+		}
+	}
+
+	if err := h.d.QuotaEnforcer().Check(ctx, tuple); err != nil {
+		return nil, err
+	}
 	internalTuple, err := h.d.Mapper().FromTuple(ctx, tuple)
 	if err != nil {
+		h.d.AuditLogger().Check(ctx, tuple, false, err)
 		return nil, err
 	}
 	allowed, err := h.d.PermissionEngine().CheckIsMember(ctx, internalTuple[0], int(req.MaxDepth))
-	// TODO add content change handling
+	h.d.AuditLogger().Check(ctx, tuple, allowed, err)
 	if err != nil {
 		return nil, err
 	}
 
 	return &rts.CheckResponse{
 		Allowed:   allowed,
-		Snaptoken: "not yet implemented",
+		Snaptoken: snaptoken,
 	}, nil
 }
@@ -0,0 +1,71 @@
+package check
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/keto/internal/check/checkgroup"
+)
+
+// Metrics exports the latency, depth, and database load of checks per
+// namespace, and the number of running subchecks.
+type Metrics struct {
+	duration, depth, tuples, queries *prometheus.HistogramVec
+	goroutines                       prometheus.GaugeFunc
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+func NewMetrics() *Metrics {
+	histogram := func(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "keto_check_" + name,
+			Help:    help,
+			Buckets: buckets,
+		}, append([]string{"namespace"}, labels...))
+	}
+	return &Metrics{
+		duration: histogram("duration_seconds", "The duration of checks.", prometheus.DefBuckets, "result"),
+		depth:    histogram("depth", "The deepest level of the checks that was evaluated.", []float64{0, 1, 2, 3, 4, 5, 7, 10, 15, 20, 30, 50}),
+		tuples:   histogram("tuples_fetched", "The number of relation tuples fetched from the database per check.", prometheus.ExponentialBuckets(1, 4, 8)),
+		queries:  histogram("queries", "The number of database queries per check.", prometheus.ExponentialBuckets(1, 2, 10)),
+		goroutines: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "keto_check_goroutines",
+			Help: "The number of goroutines that run subchecks.",
+		}, func() float64 { return float64(checkgroup.Running()) }),
+	}
+}
+
+func (m *Metrics) observe(namespace string, result checkgroup.Result, duration time.Duration, s *checkStats) {
+	m.duration.WithLabelValues(namespace, resultLabel(result)).Observe(duration.Seconds())
+	m.depth.WithLabelValues(namespace).Observe(float64(s.depth()))
+	m.tuples.WithLabelValues(namespace).Observe(float64(s.tupleCount()))
+	m.queries.WithLabelValues(namespace).Observe(float64(s.queryCount()))
+}
+
+func resultLabel(result checkgroup.Result) string {
+	switch {
+	case result.Err != nil:
+		return "error"
+	case result.Membership == checkgroup.IsMember:
+		return "allowed"
+	}
+	return "denied"
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.duration, m.depth, m.tuples, m.queries, m.goroutines}
+}
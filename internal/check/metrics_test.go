@@ -0,0 +1,61 @@
+package check_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/namespace"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	reg := newDepsProvider(t, []*namespace.Namespace{{Name: "doc"}, {Name: "group"}})
+	require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx,
+		tupleFromString(t, "doc:readme#viewer@group:dev#member"),
+		tupleFromString(t, "group:dev#member@alice"),
+	))
+	e := check.NewEngine(reg)
+
+	allowed, err := e.CheckIsMember(ctx, tupleFromString(t, "doc:readme#viewer@alice"), 0)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	m := e.Metrics()
+	assert.Equal(t, 1, testutil.CollectAndCount(m, "keto_check_duration_seconds"))
+	assert.Equal(t, 1, testutil.CollectAndCount(m, "keto_check_tuples_fetched"))
+	assert.Equal(t, 1, testutil.CollectAndCount(m, "keto_check_queries"))
+	assert.Equal(t, 1, testutil.CollectAndCount(m, "keto_check_goroutines"))
+
+	// The subject set of the group is one level below the document.
+	require.NoError(t, testutil.CollectAndCompare(m, strings.NewReader(`
+# HELP keto_check_depth The deepest level of the checks that was evaluated.
+# TYPE keto_check_depth histogram
+keto_check_depth_bucket{namespace="doc",le="0"} 0
+keto_check_depth_bucket{namespace="doc",le="1"} 1
+keto_check_depth_bucket{namespace="doc",le="2"} 1
+keto_check_depth_bucket{namespace="doc",le="3"} 1
+keto_check_depth_bucket{namespace="doc",le="4"} 1
+keto_check_depth_bucket{namespace="doc",le="5"} 1
+keto_check_depth_bucket{namespace="doc",le="7"} 1
+keto_check_depth_bucket{namespace="doc",le="10"} 1
+keto_check_depth_bucket{namespace="doc",le="15"} 1
+keto_check_depth_bucket{namespace="doc",le="20"} 1
+keto_check_depth_bucket{namespace="doc",le="30"} 1
+keto_check_depth_bucket{namespace="doc",le="50"} 1
+keto_check_depth_bucket{namespace="doc",le="+Inf"} 1
+keto_check_depth_sum{namespace="doc"} 1
+keto_check_depth_count{namespace="doc"} 1
+`), "keto_check_depth"))
+
+	allowed, err = e.CheckIsMember(ctx, tupleFromString(t, "doc:readme#viewer@bob"), 0)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 2, testutil.CollectAndCount(m, "keto_check_duration_seconds"), "allowed and denied checks are counted separately")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ory/keto/internal/namespace/ast"
 	"github.com/ory/keto/internal/relationtuple"
 	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/graph"
 	"github.com/ory/keto/ketoapi"
 )
 
@@ -56,31 +57,38 @@ func (e *Engine) checkSubjectSetRewrite(
 	}
 
 	for _, child := range rewrite.Children {
+		childCtx := ctx
+		if rewrite.Operation == ast.OperatorAnd {
+			// Every operand of an intersection has to hold on its own, so it
+			// must not skip the subjects that another operand visited.
+			childCtx = graph.CopyVisited(ctx)
+		}
+
 		switch c := child.(type) {
 
 		case *ast.TupleToSubjectSet:
 			checks = append(checks, checkgroup.WithEdge(checkgroup.Edge{
 				Tuple: *tuple,
 				Type:  ketoapi.TreeNodeTupleToSubjectSet,
-			}, e.checkTupleToSubjectSet(tuple, c, restDepth)))
+			}, e.checkTupleToSubjectSet(childCtx, tuple, c, restDepth)))
 
 		case *ast.ComputedSubjectSet:
 			checks = append(checks, checkgroup.WithEdge(checkgroup.Edge{
 				Tuple: *tuple,
 				Type:  ketoapi.TreeNodeComputedSubjectSet,
-			}, e.checkComputedSubjectSet(ctx, tuple, c, restDepth)))
+			}, e.checkComputedSubjectSet(childCtx, tuple, c, restDepth)))
 
 		case *ast.SubjectSetRewrite:
 			checks = append(checks, checkgroup.WithEdge(checkgroup.Edge{
 				Tuple: *tuple,
 				Type:  toTreeNodeType(c.Operation),
-			}, e.checkSubjectSetRewrite(ctx, tuple, c, restDepth)))
+			}, e.checkSubjectSetRewrite(childCtx, tuple, c, restDepth)))
 
 		case *ast.InvertResult:
 			checks = append(checks, checkgroup.WithEdge(checkgroup.Edge{
 				Tuple: *tuple,
 				Type:  ketoapi.TreeNodeNot,
-			}, e.checkInverted(ctx, tuple, c, restDepth)))
+			}, e.checkInverted(childCtx, tuple, c, restDepth)))
 
 		default:
 			return checkNotImplemented
@@ -107,6 +115,10 @@ func (e *Engine) checkInverted(
 		WithField("request", tuple.String()).
 		Trace("invert check")
 
+	// The result of the child is inverted, so it must not skip the subjects
+	// that other branches visited.
+	ctx = graph.CopyVisited(ctx)
+
 	var check checkgroup.CheckFunc
 
 	switch c := inverted.Child.(type) {
@@ -115,7 +127,7 @@ func (e *Engine) checkInverted(
 		check = checkgroup.WithEdge(checkgroup.Edge{
 			Tuple: *tuple,
 			Type:  ketoapi.TreeNodeTupleToSubjectSet,
-		}, e.checkTupleToSubjectSet(tuple, c, restDepth))
+		}, e.checkTupleToSubjectSet(ctx, tuple, c, restDepth))
 
 	case *ast.ComputedSubjectSet:
 		check = checkgroup.WithEdge(checkgroup.Edge{
@@ -140,7 +152,9 @@ func (e *Engine) checkInverted(
 	}
 
 	return func(ctx context.Context, resultCh chan<- checkgroup.Result) {
-		innerCh := make(chan checkgroup.Result)
+		// The channel is buffered, so that the check does not block forever
+		// if the context is cancelled before its result is received.
+		innerCh := make(chan checkgroup.Result, 1)
 		go check(ctx, innerCh)
 		select {
 		case result := <-innerCh:
@@ -203,6 +217,7 @@ func (e *Engine) checkComputedSubjectSet(
 //
 // * For each matching subject, then check if subject#owner@user.
 func (e *Engine) checkTupleToSubjectSet(
+	ctx context.Context,
 	tuple *relationTuple,
 	subjectSet *ast.TupleToSubjectSet,
 	restDepth int,
@@ -218,6 +233,9 @@ func (e *Engine) checkTupleToSubjectSet(
 		WithField("tuple to subject-set computed", subjectSet.ComputedSubjectSetRelation).
 		Trace("check tuple to subjectSet")
 
+	// The subject sets are checked with the context of this branch, which
+	// carries the subjects it visited.
+	branchCtx := ctx
 	return func(ctx context.Context, resultCh chan<- checkgroup.Result) {
 		var (
 			prevPage, nextPage string
@@ -226,7 +244,7 @@ func (e *Engine) checkTupleToSubjectSet(
 		)
 		g := checkgroup.New(ctx)
 		for nextPage = "x"; nextPage != "" && !g.Done(); prevPage = nextPage {
-			tuples, nextPage, err = e.d.RelationTupleManager().GetRelationTuples(
+			tuples, nextPage, err = e.getRelationTuples(
 				ctx,
 				&query{
 					Namespace: &tuple.Namespace,
@@ -242,7 +260,7 @@ func (e *Engine) checkTupleToSubjectSet(
 			for _, t := range tuples {
 				if subSet, ok := t.Subject.(*relationtuple.SubjectSet); ok {
 					g.Add(e.checkIsAllowed(
-						ctx,
+						branchCtx,
 						&relationTuple{
 							Namespace: subSet.Namespace,
 							Object:    subSet.Object,
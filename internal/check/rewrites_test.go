@@ -14,6 +14,7 @@ import (
 	"github.com/ory/keto/internal/namespace"
 	"github.com/ory/keto/internal/namespace/ast"
 	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/schema"
 	"github.com/ory/keto/ketoapi"
 )
 
@@ -256,6 +257,64 @@ func TestUsersetRewrites(t *testing.T) {
 	})
 }
 
+func TestNegationFromOPL(t *testing.T) {
+	parsed, errs := schema.Parse(`
+class User implements Namespace {}
+
+class Folder implements Namespace {
+  related: {
+    viewers: User[]
+    blocked: User[]
+  }
+}
+
+class File implements Namespace {
+  related: {
+    parents: Folder[]
+    viewers: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) &&
+      !this.related.parents.traverse((p) => p.related.blocked.includes(ctx.subject)),
+    notBlocked: (ctx: Context): boolean =>
+      !!this.related.parents.traverse((p) => p.related.blocked.includes(ctx.subject)),
+  }
+}`)
+	require.Empty(t, errs)
+	nn := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		nn[i] = &parsed[i]
+	}
+
+	reg := newDepsProvider(t, nn)
+	insertFixtures(t, reg.RelationTupleManager(), []string{
+		"File:readme#parents@Folder:docs#",
+		"File:readme#viewers@alice",
+		"File:readme#viewers@mallory",
+		"Folder:docs#blocked@mallory",
+	})
+
+	e := check.NewEngine(reg)
+	for _, tc := range []struct {
+		query    string
+		expected checkgroup.Result
+	}{
+		{query: "File:readme#view@alice", expected: checkgroup.ResultIsMember},
+		{query: "File:readme#view@mallory", expected: checkgroup.ResultNotMember},
+		{query: "File:readme#view@bob", expected: checkgroup.ResultNotMember},
+		{query: "File:readme#notBlocked@mallory", expected: checkgroup.ResultIsMember},
+		{query: "File:readme#notBlocked@alice", expected: checkgroup.ResultNotMember},
+	} {
+		t.Run("case="+tc.query, func(t *testing.T) {
+			res := e.CheckRelationTuple(context.Background(), tupleFromString(t, tc.query), 100)
+			require.NoError(t, res.Err)
+			assert.Equal(t, tc.expected.Membership, res.Membership)
+		})
+	}
+}
+
 // assertPath asserts that the given path can be found in the tree.
 func assertPath(t *testing.T, path path, tree *ketoapi.Tree[*relationtuple.RelationTuple]) {
 	require.NotNil(t, tree)
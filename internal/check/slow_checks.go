@@ -0,0 +1,103 @@
+package check
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/check/checkgroup"
+	"github.com/ory/keto/ketoapi"
+)
+
+// logSlowCheck logs the check if it took longer than the configured
+// threshold. The relation tuples are mapped back to their names where
+// possible, which queries the database, but only for slow checks.
+func (e *Engine) logSlowCheck(ctx context.Context, r *relationTuple, result checkgroup.Result, duration time.Duration, s *checkStats) {
+	threshold := e.config(ctx).SlowChecksThreshold()
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+
+	l := e.d.Logger().
+		WithField("namespace", r.Namespace).
+		WithField("relation", r.Relation).
+		WithField("duration", duration.String()).
+		WithField("threshold", threshold.String()).
+		WithField("result", resultLabel(result)).
+		WithField("depth", s.depth()).
+		WithField("max_depth", s.maxDepth).
+		WithField("queries", s.queryCount()).
+		WithField("tuples_fetched", s.tupleCount())
+
+	if mapped, err := e.toTuple(ctx, r); err == nil {
+		l = l.WithField("tuple", mapped[0].String())
+	} else {
+		l = l.WithField("tuple", r.String())
+	}
+
+	if e.config(ctx).SlowChecksIncludeTree() && result.Tree != nil {
+		if tree, err := e.mapTree(ctx, result.Tree); err == nil {
+			l = l.WithField("tree", tree)
+		} else {
+			l = l.WithField("tree", result.Tree)
+		}
+	}
+	if result.Err != nil {
+		l = l.WithError(result.Err)
+	}
+
+	l.Warn("slow check")
+}
+
+// mapTree maps all relation tuples of the tree back to their names with one
+// query.
+func (e *Engine) mapTree(ctx context.Context, tree *ketoapi.Tree[*relationTuple]) (*ketoapi.Tree[*ketoapi.RelationTuple], error) {
+	var (
+		tuples []*relationTuple
+		walk   func(*ketoapi.Tree[*relationTuple])
+	)
+	walk = func(t *ketoapi.Tree[*relationTuple]) {
+		if t == nil {
+			return
+		}
+		if t.Tuple != nil && t.Tuple.Subject != nil {
+			tuples = append(tuples, t.Tuple)
+		}
+		for _, c := range t.Children {
+			walk(c)
+		}
+	}
+	walk(tree)
+
+	mapped, err := e.toTuple(ctx, tuples...)
+	if err != nil {
+		return nil, err
+	}
+	byTuple := make(map[*relationTuple]*ketoapi.RelationTuple, len(tuples))
+	for i, t := range tuples {
+		byTuple[t] = mapped[i]
+	}
+
+	var convert func(*ketoapi.Tree[*relationTuple]) *ketoapi.Tree[*ketoapi.RelationTuple]
+	convert = func(t *ketoapi.Tree[*relationTuple]) *ketoapi.Tree[*ketoapi.RelationTuple] {
+		if t == nil {
+			return nil
+		}
+		res := &ketoapi.Tree[*ketoapi.RelationTuple]{Type: t.Type, Tuple: byTuple[t.Tuple]}
+		for _, c := range t.Children {
+			res.Children = append(res.Children, convert(c))
+		}
+		return res
+	}
+	return convert(tree), nil
+}
+
+// toTuple maps the relation tuples back to their names, if the engine has a
+// mapper.
+func (e *Engine) toTuple(ctx context.Context, ts ...*relationTuple) ([]*ketoapi.RelationTuple, error) {
+	if e.mapper == nil {
+		return nil, errors.New("the engine has no tuple mapper")
+	}
+	return e.mapper.ToTuple(ctx, ts...)
+}
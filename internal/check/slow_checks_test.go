@@ -0,0 +1,97 @@
+package check_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ory/x/logrusx"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type hookedLogger struct{ l *logrusx.Logger }
+
+func (h *hookedLogger) Logger() *logrusx.Logger { return h.l }
+
+func TestSlowCheckLog(t *testing.T) {
+	ctx := context.Background()
+
+	reg := newDepsProvider(t, []*namespace.Namespace{{Name: "doc"}, {Name: "group"}})
+	l, hook := test.NewNullLogger()
+	reg.loggerProvider = &hookedLogger{l: logrusx.New("test", "today", logrusx.UseLogger(l))}
+
+	relationtuple.MapAndWriteTuples(t, reg,
+		&ketoapi.RelationTuple{Namespace: "doc", Object: "readme", Relation: "viewer", SubjectSet: &ketoapi.SubjectSet{Namespace: "group", Object: "dev", Relation: "member"}},
+		&ketoapi.RelationTuple{Namespace: "group", Object: "dev", Relation: "member", SubjectID: x.Ptr("alice")},
+	)
+	mapped, err := reg.Mapper().FromTuple(ctx, &ketoapi.RelationTuple{Namespace: "doc", Object: "readme", Relation: "viewer", SubjectID: x.Ptr("alice")})
+	require.NoError(t, err)
+	e := check.NewEngine(reg)
+
+	t.Run("case=fast checks are not logged", func(t *testing.T) {
+		hook.Reset()
+		require.NoError(t, reg.Config(ctx).Set(config.KeySlowChecksThreshold, "1h"))
+
+		allowed, err := e.CheckIsMember(ctx, mapped[0], 0)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("case=slow checks are logged", func(t *testing.T) {
+		hook.Reset()
+		require.NoError(t, reg.Config(ctx).Set(config.KeySlowChecksThreshold, "1ns"))
+		require.NoError(t, reg.Config(ctx).Set(config.KeySlowChecksIncludeTree, true))
+
+		allowed, err := e.CheckIsMember(ctx, mapped[0], 0)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		entry := hook.LastEntry()
+		require.NotNil(t, entry)
+		assert.Equal(t, "slow check", entry.Message)
+		assert.Equal(t, "doc:readme#viewer@alice", entry.Data["tuple"])
+		assert.Equal(t, "allowed", entry.Data["result"])
+		assert.Equal(t, 1, entry.Data["depth"])
+		assert.Positive(t, entry.Data["queries"])
+		assert.Positive(t, entry.Data["tuples_fetched"])
+
+		tree, ok := entry.Data["tree"].(*ketoapi.Tree[*ketoapi.RelationTuple])
+		require.True(t, ok, "%T", entry.Data["tree"])
+		var tuples []string
+		var walk func(*ketoapi.Tree[*ketoapi.RelationTuple])
+		walk = func(t *ketoapi.Tree[*ketoapi.RelationTuple]) {
+			if t.Tuple != nil {
+				tuples = append(tuples, t.Tuple.String())
+			}
+			for _, c := range t.Children {
+				walk(c)
+			}
+		}
+		walk(tree)
+		assert.Contains(t, tuples, "group:dev#member@alice", "the tree is mapped back to names")
+	})
+
+	t.Run("case=denied checks have no tree", func(t *testing.T) {
+		hook.Reset()
+		denied, err := reg.Mapper().FromTuple(ctx, &ketoapi.RelationTuple{Namespace: "doc", Object: "readme", Relation: "viewer", SubjectID: x.Ptr("bob")})
+		require.NoError(t, err)
+
+		allowed, err := e.CheckIsMember(ctx, denied[0], 0)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		entry := hook.LastEntry()
+		require.NotNil(t, entry)
+		assert.Equal(t, "denied", entry.Data["result"])
+		assert.NotContains(t, entry.Data, "tree")
+	})
+}
@@ -0,0 +1,64 @@
+package check
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type (
+	// checkStats are collected while a check is evaluated. They are safe for
+	// concurrent use by the subchecks, and a nil *checkStats ignores all
+	// updates.
+	checkStats struct {
+		// The 64-bit fields are accessed atomically and have to be aligned.
+		tuples, queries int64
+		minRestDepth    int64
+		maxDepth        int
+	}
+	statsContextKey struct{}
+)
+
+func withStats(ctx context.Context, maxDepth int) (context.Context, *checkStats) {
+	s := &checkStats{maxDepth: maxDepth, minRestDepth: int64(maxDepth)}
+	return context.WithValue(ctx, statsContextKey{}, s), s
+}
+
+func statsFromContext(ctx context.Context) *checkStats {
+	s, _ := ctx.Value(statsContextKey{}).(*checkStats)
+	return s
+}
+
+// visit records that a subcheck with the rest depth was started.
+func (s *checkStats) visit(restDepth int) {
+	if s == nil {
+		return
+	}
+	for {
+		min := atomic.LoadInt64(&s.minRestDepth)
+		if int64(restDepth) >= min || atomic.CompareAndSwapInt64(&s.minRestDepth, min, int64(restDepth)) {
+			return
+		}
+	}
+}
+
+// query records a database query that returned n relation tuples.
+func (s *checkStats) query(n int) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.queries, 1)
+	atomic.AddInt64(&s.tuples, int64(n))
+}
+
+// depth returns the deepest level of the check that was evaluated.
+func (s *checkStats) depth() int {
+	return s.maxDepth - int(atomic.LoadInt64(&s.minRestDepth))
+}
+
+func (s *checkStats) queryCount() int {
+	return int(atomic.LoadInt64(&s.queries))
+}
+
+func (s *checkStats) tupleCount() int {
+	return int(atomic.LoadInt64(&s.tuples))
+}
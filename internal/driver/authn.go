@@ -0,0 +1,97 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ory/keto/internal/authn"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+// authnMiddleware authenticates the requests to the API, except for the
+// health, version, status, and OpenAPI endpoints, and adds the subject to
+// their context.
+func (r *RegistryDefault) authnMiddleware(api string) func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	a := r.Authenticator()
+	return func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		if !isAPIRequest(req) {
+			next(rw, req)
+			return
+		}
+		s, err := a.Authenticate(req.Context(), api, req.URL.Path, req.Header.Get("Authorization"))
+		if err != nil {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			r.Writer().WriteError(rw, req, err)
+			return
+		}
+		if s != nil {
+			req = req.WithContext(authn.NewContext(req.Context(), s))
+		}
+		next(rw, req)
+	}
+}
+
+// isAuthnExempt returns whether the gRPC method is exempt from
+// authentication, like the health and version endpoints of the REST API.
+func isAuthnExempt(method string) bool {
+	for _, service := range []string{
+		grpcHealthV1.Health_ServiceDesc.ServiceName,
+		rts.VersionService_ServiceDesc.ServiceName,
+		"grpc.reflection.",
+	} {
+		if strings.HasPrefix(method, "/"+service) {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcAuthenticate authenticates the gRPC request by its authorization
+// metadata and returns the context with the subject.
+func (r *RegistryDefault) grpcAuthenticate(ctx context.Context, a *authn.Authenticator, api, method string) (context.Context, error) {
+	if isAuthnExempt(method) {
+		return ctx, nil
+	}
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) > 0 {
+			authorization = v[0]
+		}
+	}
+	s, err := a.Authenticate(ctx, api, method, authorization)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if s != nil {
+		ctx = authn.NewContext(ctx, s)
+	}
+	return ctx, nil
+}
+
+// authnInterceptors return the interceptors that authenticate the requests to
+// the gRPC API.
+func (r *RegistryDefault) authnInterceptors(api string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	a := r.Authenticator()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := r.grpcAuthenticate(ctx, a, api, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := r.grpcAuthenticate(ss.Context(), a, api, info.FullMethod)
+			if err != nil {
+				return err
+			}
+			wrapped := grpcMiddleware.WrapServerStream(ss)
+			wrapped.WrappedContext = ctx
+			return handler(srv, wrapped)
+		}
+}
@@ -0,0 +1,203 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/ory/keto/internal/authz"
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/expand"
+	"github.com/ory/keto/internal/graphql"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+// authzMiddleware enforces the meta-permissions on the REST endpoints. It has
+// to run after the authentication middleware.
+func (r *RegistryDefault) authzMiddleware() func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	a := r.Authorizer()
+	return func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		if !a.Enabled(req.Context()) {
+			next(rw, req)
+			return
+		}
+		action, namespaces, ok, err := restNamespaces(req)
+		if !ok {
+			next(rw, req)
+			return
+		} else if err != nil {
+			r.Writer().WriteError(rw, req, err)
+			return
+		}
+		if err := a.Authorize(req.Context(), action, namespaces...); err != nil {
+			r.Writer().WriteError(rw, req, err)
+			return
+		}
+		next(rw, req)
+	}
+}
+
+// restNamespaces returns the action of the request and the namespaces it
+// accesses, or false if the endpoint is not subject to meta-permissions or
+// authorizes the request itself. All other endpoints, like the snapshots,
+// schema migrations, and namespace renames, require write access to all
+// namespaces. The body is read and replaced, so that the handler can read it
+// again.
+func restNamespaces(req *http.Request) (action string, namespaces []string, ok bool, err error) {
+	if !isAPIRequest(req) || req.URL.Path == graphql.RouteBase {
+		return "", nil, false, nil
+	}
+	query := []string{req.URL.Query().Get("namespace")}
+	switch req.URL.Path {
+	case relationtuple.ReadRouteBase, relationtuple.CountRoute, expand.RouteBase, relationtuple.HistoryRoute, relationtuple.WatchRoute:
+		return authz.ActionRead, query, true, nil
+	case relationtuple.RestoreRoute, relationtuple.BulkDeleteRoute:
+		return authz.ActionWrite, query, true, nil
+	case check.RouteBase, check.OpenAPIRouteBase:
+		if req.Method != http.MethodPost {
+			return authz.ActionRead, query, true, nil
+		}
+		var tuple ketoapi.RelationTuple
+		if err := decodeBody(req, &tuple); err != nil {
+			return "", nil, true, err
+		}
+		return authz.ActionRead, []string{tuple.Namespace}, true, nil
+	case relationtuple.WriteRouteBase:
+		switch req.Method {
+		case http.MethodPut:
+			var tuple ketoapi.RelationTuple
+			if err := decodeBody(req, &tuple); err != nil {
+				return "", nil, true, err
+			}
+			return authz.ActionWrite, []string{tuple.Namespace}, true, nil
+		case http.MethodPatch:
+			var deltas []*ketoapi.PatchDelta
+			if err := decodeBody(req, &deltas); err != nil {
+				return "", nil, true, err
+			}
+			namespaces := make([]string, 0, len(deltas))
+			for _, d := range deltas {
+				if d != nil && d.RelationTuple != nil {
+					namespaces = append(namespaces, d.RelationTuple.Namespace)
+				}
+			}
+			return authz.ActionWrite, namespaces, true, nil
+		default:
+			return authz.ActionWrite, query, true, nil
+		}
+	}
+	return authz.ActionWrite, []string{authz.AllNamespaces}, true, nil
+}
+
+// decodeBody decodes the JSON body of the request and replaces it, so that the
+// handler can read it again. Bodies that are not valid JSON are left to the
+// handler to reject.
+func decodeBody(req *http.Request, v interface{}) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to read the request body: %s", err))
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	_ = json.Unmarshal(body, v)
+	return nil
+}
+
+// grpcNamespaces returns the action of the gRPC request and the namespaces it
+// accesses, or false if the request is not subject to meta-permissions.
+func grpcNamespaces(req interface{}) (string, []string, bool) {
+	switch req := req.(type) {
+	case *rts.GetMigrationStatusRequest:
+		return authz.ActionWrite, []string{authz.AllNamespaces}, true
+	case *rts.CheckRequest:
+		if req.GetTuple() != nil {
+			return authz.ActionRead, []string{req.GetTuple().GetNamespace()}, true
+		}
+		return authz.ActionRead, []string{req.GetNamespace()}, true
+	case *rts.ExpandRequest:
+		return authz.ActionRead, []string{req.GetSubject().GetSet().GetNamespace()}, true
+	case *rts.ListRelationTuplesRequest:
+		if req.GetRelationQuery() != nil {
+			return authz.ActionRead, []string{req.GetRelationQuery().GetNamespace()}, true
+		}
+		return authz.ActionRead, []string{req.GetQuery().GetNamespace()}, true
+	case *rts.StreamRelationTuplesRequest:
+		return authz.ActionRead, []string{req.GetRelationQuery().GetNamespace()}, true
+	case *rts.TransactRelationTuplesRequest:
+		namespaces := make([]string, 0, len(req.GetRelationTupleDeltas()))
+		for _, d := range req.GetRelationTupleDeltas() {
+			namespaces = append(namespaces, d.GetRelationTuple().GetNamespace())
+		}
+		return authz.ActionWrite, namespaces, true
+	case *rts.DeleteRelationTuplesRequest:
+		if req.GetRelationQuery() != nil {
+			return authz.ActionWrite, []string{req.GetRelationQuery().GetNamespace()}, true
+		}
+		return authz.ActionWrite, []string{req.GetQuery().GetNamespace()}, true
+	}
+	return "", nil, false
+}
+
+// authzInterceptor enforces the meta-permissions on the gRPC methods of the
+// relation tuples. It has to run after the authentication interceptor.
+func (r *RegistryDefault) authzInterceptor() grpc.UnaryServerInterceptor {
+	a := r.Authorizer()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !a.Enabled(ctx) {
+			return handler(ctx, req)
+		}
+		action, namespaces, ok := grpcNamespaces(req)
+		if !ok {
+			if isAuthzExempt(info.FullMethod) {
+				return handler(ctx, req)
+			}
+			action, namespaces = authz.ActionWrite, []string{authz.AllNamespaces}
+		}
+		if err := a.Authorize(ctx, action, namespaces...); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// isAuthzExempt returns whether the gRPC method is not subject to
+// meta-permissions, because it is exempt from authentication or authorizes the
+// request itself. Unknown methods require write access to all namespaces.
+func isAuthzExempt(method string) bool {
+	return isAuthnExempt(method) || strings.HasPrefix(method, "/"+extAuthzServiceName+"/")
+}
+
+// authzStreamInterceptor enforces the meta-permissions on the streaming gRPC
+// methods, once their request was received.
+func (r *RegistryDefault) authzStreamInterceptor() grpc.StreamServerInterceptor {
+	a := r.Authorizer()
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !a.Enabled(ss.Context()) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &authzServerStream{ServerStream: ss, a: a})
+	}
+}
+
+type authzServerStream struct {
+	grpc.ServerStream
+	a *authz.Authorizer
+}
+
+func (s *authzServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if action, namespaces, ok := grpcNamespaces(m); ok {
+		return s.a.Authorize(s.Context(), action, namespaces...)
+	}
+	return nil
+}
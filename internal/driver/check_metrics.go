@@ -0,0 +1,27 @@
+package driver
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var checkMetricsMx sync.Mutex
+
+// registerCheckMetrics exports the metrics of the permission engine like
+// registerPoolMetrics.
+func (r *RegistryDefault) registerCheckMetrics() (unregister func()) {
+	checkMetricsMx.Lock()
+	defer checkMetricsMx.Unlock()
+
+	c := r.PermissionEngine().Metrics()
+	if err := prometheus.Register(c); err != nil {
+		r.Logger().WithError(err).Warn("Unable to export the check metrics.")
+		return func() {}
+	}
+	return func() {
+		checkMetricsMx.Lock()
+		defer checkMetricsMx.Unlock()
+		prometheus.Unregister(c)
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+const (
+	KeyAuditEnabled          = "audit.enabled"
+	KeyAuditSinks            = "audit.sinks"
+	KeyAuditChecksSampleRate = "audit.checks.sample_rate"
+	KeyAuditBufferSize       = "audit.buffer_size"
+)
+
+// AuditSink is a destination of the audit log.
+type AuditSink struct {
+	// Type is one of "file", "syslog", "webhook", or "kafka".
+	Type string `json:"type"`
+	// Path is the file the events are appended to.
+	Path string `json:"path"`
+	// URL is the address of the syslog server, the webhook, or the Kafka REST
+	// proxy.
+	URL string `json:"url"`
+	// Topic is the Kafka topic.
+	Topic string `json:"topic"`
+	// Tag is the syslog tag.
+	Tag string `json:"tag"`
+}
+
+func (k *Config) AuditEnabled() bool {
+	return k.p.BoolF(KeyAuditEnabled, false)
+}
+
+// AuditSinks returns the destinations of the audit log.
+func (k *Config) AuditSinks() ([]AuditSink, error) {
+	var sinks []AuditSink
+	if err := k.decode(KeyAuditSinks, &sinks); err != nil {
+		return nil, err
+	}
+	for i := range sinks {
+		sinks[i].URL = k.secret(KeyAuditSinks, sinks[i].URL)
+	}
+	return sinks, nil
+}
+
+// AuditChecksSampleRate returns the fraction of check decisions that are
+// recorded in the audit log. Writes are always recorded.
+func (k *Config) AuditChecksSampleRate() float64 {
+	return k.p.Float64F(KeyAuditChecksSampleRate, 1)
+}
+
+func (k *Config) AuditBufferSize() int {
+	return k.p.IntF(KeyAuditBufferSize, 1000)
+}
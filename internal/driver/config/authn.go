@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	KeyAuthnAPIKeys       = "authn.api_keys"
+	KeyAuthnJWT           = "authn.jwt"
+	KeyAuthnIntrospection = "authn.oauth2_introspection"
+	KeyAuthnRules         = "authn.rules"
+
+	AuthenticatorAPIKey        = "api_key"
+	AuthenticatorJWT           = "jwt"
+	AuthenticatorIntrospection = "oauth2_introspection"
+	// AuthenticatorAnonymous allows requests without credentials in rules.
+	AuthenticatorAnonymous = "anonymous"
+)
+
+type (
+	// APIKey is a static bearer token of a client.
+	APIKey struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+		// Tenant is the only tenant the client can access if tenancy is
+		// enabled, or "*" if it can access all tenants.
+		Tenant string `json:"tenant,omitempty"`
+	}
+	// JWTAuthenticator validates bearer tokens that are JSON Web Tokens signed
+	// by one of the keys of the JSON Web Key Set.
+	JWTAuthenticator struct {
+		JWKSURL  string `json:"jwks_url"`
+		Issuer   string `json:"issuer"`
+		Audience string `json:"audience"`
+		// TenantClaim is the claim that holds the tenant of the client.
+		TenantClaim string `json:"tenant_claim"`
+	}
+	// IntrospectionAuthenticator validates bearer tokens through an OAuth 2.0
+	// token introspection endpoint (RFC 7662).
+	IntrospectionAuthenticator struct {
+		URL          string `json:"url"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		// TenantClaim is the member of the introspection response that holds
+		// the tenant of the client.
+		TenantClaim string `json:"tenant_claim"`
+	}
+	// AuthnRule overrides which authenticators are accepted for the requests
+	// to some APIs whose REST path or gRPC method starts with the path.
+	AuthnRule struct {
+		APIs           []string `json:"apis"`
+		Path           string   `json:"path"`
+		Authenticators []string `json:"authenticators"`
+	}
+)
+
+// APIKeys returns the static API keys of the clients.
+func (k *Config) APIKeys() ([]APIKey, error) {
+	var keys []APIKey
+	if err := k.decode(KeyAuthnAPIKeys, &keys); err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i].Key = k.secret(KeyAuthnAPIKeys, keys[i].Key)
+	}
+	return keys, nil
+}
+
+// JWTAuthenticator returns the configuration of the JSON Web Token
+// authenticator, or nil if it is not configured.
+func (k *Config) JWTAuthenticator() (*JWTAuthenticator, error) {
+	var a *JWTAuthenticator
+	return a, k.decode(KeyAuthnJWT, &a)
+}
+
+// IntrospectionAuthenticator returns the configuration of the OAuth 2.0 token
+// introspection authenticator, or nil if it is not configured.
+func (k *Config) IntrospectionAuthenticator() (*IntrospectionAuthenticator, error) {
+	var a *IntrospectionAuthenticator
+	if err := k.decode(KeyAuthnIntrospection, &a); err != nil || a == nil {
+		return nil, err
+	}
+	a.ClientSecret = k.secret(KeyAuthnIntrospection, a.ClientSecret)
+	return a, nil
+}
+
+// Authenticators returns the names of the configured authenticators. If there
+// are none, the APIs do not require authentication.
+func (k *Config) Authenticators() []string {
+	var names []string
+	for _, a := range []struct{ name, key string }{
+		{AuthenticatorAPIKey, KeyAuthnAPIKeys},
+		{AuthenticatorJWT, KeyAuthnJWT},
+		{AuthenticatorIntrospection, KeyAuthnIntrospection},
+	} {
+		if k.p.Exists(a.key) {
+			names = append(names, a.name)
+		}
+	}
+	return names
+}
+
+// AuthenticatorsFor returns the authenticators that are accepted for the
+// request to the API ("read", "write", or "admin") with the REST path or gRPC
+// method. The first matching rule decides, and without one all configured
+// authenticators are accepted.
+func (k *Config) AuthenticatorsFor(api, path string) ([]string, error) {
+	var rules []AuthnRule
+	if err := k.decode(KeyAuthnRules, &rules); err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if (len(r.APIs) == 0 || slices.Contains(r.APIs, api)) && strings.HasPrefix(path, r.Path) {
+			return r.Authenticators, nil
+		}
+	}
+	return k.Authenticators(), nil
+}
+
+// decode decodes the value of the key into v, which is left as is if the key
+// is not set.
+func (k *Config) decode(key string, v interface{}) error {
+	raw := k.p.Get(key)
+	if raw == nil {
+		return nil
+	}
+	enc, err := json.Marshal(raw)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.Wrapf(json.Unmarshal(enc, v), "unable to decode %s", key)
+}
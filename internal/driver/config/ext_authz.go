@@ -0,0 +1,55 @@
+package config
+
+const (
+	KeyExtAuthzRules     = "ext_authz.rules"
+	KeyExtAuthzUnmatched = "ext_authz.unmatched"
+
+	ExtAuthzAllow = "allow"
+	ExtAuthzDeny  = "deny"
+)
+
+type (
+	// ExtAuthzRule maps the HTTP requests that Envoy authorizes through the
+	// ext_authz protocol to the relation tuple that is checked. The namespace,
+	// object, relation, and subject can contain placeholders, which are
+	// replaced with the attributes of the request.
+	ExtAuthzRule struct {
+		// Methods are the HTTP methods the rule applies to, or all if empty.
+		Methods []string `json:"methods"`
+		// Host is the host the rule applies to, or all if empty.
+		Host string `json:"host"`
+		// Path is a template of the path the rule applies to. A {name}
+		// segment matches any segment, and a final {name...} segment the rest
+		// of the path.
+		Path       string              `json:"path"`
+		Namespace  string              `json:"namespace"`
+		Object     string              `json:"object"`
+		Relation   string              `json:"relation"`
+		SubjectID  string              `json:"subject_id"`
+		SubjectSet *ExtAuthzSubjectSet `json:"subject_set"`
+	}
+	ExtAuthzSubjectSet struct {
+		Namespace string `json:"namespace"`
+		Object    string `json:"object"`
+		Relation  string `json:"relation"`
+	}
+)
+
+// ExtAuthzEnabled is true if rules for the Envoy ext_authz service are
+// configured.
+func (k *Config) ExtAuthzEnabled() bool {
+	return k.p.Exists(KeyExtAuthzRules)
+}
+
+// ExtAuthzRules returns the rules of the Envoy ext_authz service. The first
+// rule that matches a request decides.
+func (k *Config) ExtAuthzRules() ([]ExtAuthzRule, error) {
+	var rules []ExtAuthzRule
+	return rules, k.decode(KeyExtAuthzRules, &rules)
+}
+
+// ExtAuthzAllowUnmatched returns whether requests that no rule matches are
+// allowed.
+func (k *Config) ExtAuthzAllowUnmatched() bool {
+	return k.p.StringF(KeyExtAuthzUnmatched, ExtAuthzDeny) == ExtAuthzAllow
+}
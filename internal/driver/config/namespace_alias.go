@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/tenancy"
+)
+
+type (
+	// NamespaceAliasLoader loads the aliases of namespaces that are being
+	// renamed, mapping the old name to the new one.
+	NamespaceAliasLoader func(ctx context.Context) (map[string]string, error)
+
+	namespaceAliases struct {
+		sync.Mutex
+		load     NamespaceAliasLoader
+		interval func() time.Duration
+		// tenants are the cached aliases of every tenant. Requests without a
+		// tenant use the empty string.
+		tenants map[string]*loadedAliases
+	}
+	loadedAliases struct {
+		aliases  map[string]string
+		loadedAt time.Time
+	}
+
+	// aliasNamespaceManager resolves the old name of a namespace that is
+	// being renamed to the namespace with the new name.
+	aliasNamespaceManager struct {
+		namespace.Manager
+		aliases *namespaceAliases
+	}
+)
+
+var (
+	_ namespace.Manager   = (*aliasNamespaceManager)(nil)
+	_ namespace.Versioner = (*aliasNamespaceManager)(nil)
+)
+
+// SetNamespaceAliasLoader makes the namespace manager resolve the aliases
+// returned by load. They are cached for the refresh interval of the namespace
+// administration API.
+func (k *Config) SetNamespaceAliasLoader(load NamespaceAliasLoader) {
+	k.nmLock.Lock()
+	defer k.nmLock.Unlock()
+
+	k.aliases = &namespaceAliases{
+		load:     load,
+		interval: k.NamespaceAPIRefreshInterval,
+	}
+}
+
+// ReloadNamespaceAliases drops the cached aliases, so that they are loaded
+// again on the next access.
+func (k *Config) ReloadNamespaceAliases() {
+	k.nmLock.Lock()
+	defer k.nmLock.Unlock()
+
+	if k.aliases != nil {
+		k.aliases.Lock()
+		k.aliases.tenants = nil
+		k.aliases.Unlock()
+	}
+}
+
+// NamespaceAliases returns the old names of the namespaces that are being
+// renamed, mapped to their new names.
+func (k *Config) NamespaceAliases(ctx context.Context) (map[string]string, error) {
+	k.nmLock.Lock()
+	aliases := k.aliases
+	k.nmLock.Unlock()
+
+	if aliases == nil {
+		return nil, nil
+	}
+	return aliases.get(ctx)
+}
+
+func (a *namespaceAliases) get(ctx context.Context) (map[string]string, error) {
+	tenant, _ := tenancy.FromContext(ctx)
+	a.Lock()
+	if l, ok := a.tenants[tenant]; ok && time.Since(l.loadedAt) < a.interval() {
+		defer a.Unlock()
+		return l.aliases, nil
+	}
+	a.Unlock()
+
+	// The lock is not held while loading, as the loader might have to wait for
+	// a database connection that is held by another caller.
+	aliases, err := a.load(ctx)
+
+	a.Lock()
+	defer a.Unlock()
+	l, ok := a.tenants[tenant]
+	if err != nil {
+		if !ok {
+			return nil, err
+		}
+		// Keep the last known aliases until the next refresh.
+		l.loadedAt = time.Now()
+		return l.aliases, nil
+	}
+
+	if a.tenants == nil {
+		a.tenants = make(map[string]*loadedAliases)
+	}
+	a.tenants[tenant] = &loadedAliases{aliases: aliases, loadedAt: time.Now()}
+	return aliases, nil
+}
+
+func (s *aliasNamespaceManager) GetNamespaceByName(ctx context.Context, name string) (*namespace.Namespace, error) {
+	n, err := s.Manager.GetNamespaceByName(ctx, name)
+	if !errors.Is(err, herodot.ErrNotFound) {
+		return n, err
+	}
+
+	aliases, aErr := s.aliases.get(ctx)
+	if aErr != nil {
+		return nil, aErr
+	}
+	if to, ok := aliases[name]; ok {
+		return s.Manager.GetNamespaceByName(ctx, to)
+	}
+	return nil, err
+}
+
+// Version returns the version of the namespaces, as the aliases do not change
+// them.
+func (s *aliasNamespaceManager) Version(ctx context.Context) string {
+	if v, ok := s.Manager.(namespace.Versioner); ok {
+		return v.Version(ctx)
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/tenancy"
+)
+
+type (
+	// ManagedNamespaceLoader loads the namespaces that are managed through the
+	// namespace administration API. The configured namespaces can be
+	// referenced by the managed ones.
+	ManagedNamespaceLoader func(ctx context.Context, configured []*namespace.Namespace) ([]*namespace.Namespace, error)
+
+	managedNamespaces struct {
+		sync.Mutex
+		load     ManagedNamespaceLoader
+		interval func() time.Duration
+		// tenants are the cached namespaces of every tenant. Requests without
+		// a tenant use the empty string.
+		tenants map[string]*loadedNamespaces
+	}
+	loadedNamespaces struct {
+		namespaces []*namespace.Namespace
+		loadedAt   time.Time
+		version    uint64
+	}
+
+	// managedNamespaceManager merges the configured namespaces with the
+	// managed ones. Configured namespaces take precedence.
+	managedNamespaceManager struct {
+		namespace.Manager
+		managed *managedNamespaces
+	}
+)
+
+var (
+	_ namespace.Manager   = (*managedNamespaceManager)(nil)
+	_ namespace.Versioner = (*managedNamespaceManager)(nil)
+)
+
+// SetManagedNamespaceLoader makes the namespace manager include the namespaces
+// returned by load. They are cached for the refresh interval of the namespace
+// administration API.
+func (k *Config) SetManagedNamespaceLoader(load ManagedNamespaceLoader) {
+	k.nmLock.Lock()
+	defer k.nmLock.Unlock()
+
+	k.managed = &managedNamespaces{
+		load:     load,
+		interval: k.NamespaceAPIRefreshInterval,
+	}
+}
+
+// ReloadManagedNamespaces drops the cached managed namespaces, so that they
+// are loaded again on the next access.
+func (k *Config) ReloadManagedNamespaces() {
+	k.nmLock.Lock()
+	defer k.nmLock.Unlock()
+
+	if k.managed != nil {
+		k.managed.Lock()
+		k.managed.tenants = nil
+		k.managed.Unlock()
+	}
+}
+
+func (m *managedNamespaces) get(ctx context.Context, configured namespace.Manager) ([]*namespace.Namespace, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	tenant, _ := tenancy.FromContext(ctx)
+	l, ok := m.tenants[tenant]
+	if !ok {
+		l = &loadedNamespaces{}
+		if m.tenants == nil {
+			m.tenants = make(map[string]*loadedNamespaces)
+		}
+		m.tenants[tenant] = l
+	}
+	if !l.loadedAt.IsZero() && time.Since(l.loadedAt) < m.interval() {
+		return l.namespaces, nil
+	}
+
+	nn, err := configured.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := m.load(ctx, nn)
+	if err != nil {
+		if l.loadedAt.IsZero() {
+			return nil, err
+		}
+		// Keep serving the last known namespaces until the next refresh.
+		l.loadedAt = time.Now()
+		return l.namespaces, nil
+	}
+
+	l.namespaces, l.loadedAt, l.version = managed, time.Now(), nextNamespacesVersion()
+	return l.namespaces, nil
+}
+
+// version returns the version of the managed namespaces of the context, or
+// false if they could not be loaded.
+func (m *managedNamespaces) version(ctx context.Context, configured namespace.Manager) (uint64, bool) {
+	if _, err := m.get(ctx, configured); err != nil {
+		return 0, false
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	tenant, _ := tenancy.FromContext(ctx)
+	l, ok := m.tenants[tenant]
+	if !ok || l.version == 0 {
+		return 0, false
+	}
+	return l.version, true
+}
+
+func (s *managedNamespaceManager) GetNamespaceByName(ctx context.Context, name string) (*namespace.Namespace, error) {
+	n, err := s.Manager.GetNamespaceByName(ctx, name)
+	if !errors.Is(err, herodot.ErrNotFound) {
+		return n, err
+	}
+
+	managed, mErr := s.managed.get(ctx, s.Manager)
+	if mErr != nil {
+		return nil, mErr
+	}
+	for _, n := range managed {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return nil, err
+}
+
+func (s *managedNamespaceManager) Namespaces(ctx context.Context) ([]*namespace.Namespace, error) {
+	nn, err := s.Manager.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := s.managed.get(ctx, s.Manager)
+	if err != nil {
+		return nil, err
+	}
+
+	configured := make(map[string]bool, len(nn))
+	for _, n := range nn {
+		configured[n.Name] = true
+	}
+	for _, n := range managed {
+		if !configured[n.Name] {
+			nc := *n
+			nn = append(nn, &nc)
+		}
+	}
+	return nn, nil
+}
+
+// Version combines the versions of the configured and the managed namespaces.
+// It is empty if the configured namespaces are not versioned.
+func (s *managedNamespaceManager) Version(ctx context.Context) string {
+	configured, ok := s.Manager.(namespace.Versioner)
+	if !ok {
+		return ""
+	}
+	managed, ok := s.managed.version(ctx, s.Manager)
+	if !ok {
+		return ""
+	}
+	return configured.Version(ctx) + "." + strconv.FormatUint(managed, 10)
+}
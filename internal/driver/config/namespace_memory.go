@@ -3,6 +3,8 @@ package config
 import (
 	"context"
 	"reflect"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/ory/herodot"
 	"github.com/pkg/errors"
@@ -11,24 +13,41 @@ import (
 )
 
 type (
-	memoryNamespaceManager []*namespace.Namespace
+	memoryNamespaceManager struct {
+		namespaces []*namespace.Namespace
+		version    uint64
+	}
+)
+
+var (
+	_ namespace.Manager   = &memoryNamespaceManager{}
+	_ namespace.Versioner = &memoryNamespaceManager{}
+
+	// namespacesVersion counts the sets of namespaces loaded by all managers.
+	namespacesVersion uint64
 )
 
-var _ namespace.Manager = &memoryNamespaceManager{}
+// nextNamespacesVersion returns the version of a new set of namespaces.
+func nextNamespacesVersion() uint64 {
+	return atomic.AddUint64(&namespacesVersion, 1)
+}
 
 func NewMemoryNamespaceManager(nn ...*namespace.Namespace) *memoryNamespaceManager {
-	nm := make(memoryNamespaceManager, len(nn))
+	nm := &memoryNamespaceManager{
+		namespaces: make([]*namespace.Namespace, len(nn)),
+		version:    nextNamespacesVersion(),
+	}
 
 	for i, np := range nn {
 		n := *np
-		nm[i] = &n
+		nm.namespaces[i] = &n
 	}
 
-	return &nm
+	return nm
 }
 
 func (s *memoryNamespaceManager) GetNamespaceByName(_ context.Context, name string) (*namespace.Namespace, error) {
-	for _, n := range *s {
+	for _, n := range s.namespaces {
 		if n.Name == name {
 			return n, nil
 		}
@@ -38,7 +57,7 @@ func (s *memoryNamespaceManager) GetNamespaceByName(_ context.Context, name stri
 }
 
 func (s *memoryNamespaceManager) GetNamespaceByConfigID(_ context.Context, id int32) (*namespace.Namespace, error) {
-	for _, n := range *s {
+	for _, n := range s.namespaces {
 		if n.ID == id {
 			return n, nil
 		}
@@ -48,9 +67,9 @@ func (s *memoryNamespaceManager) GetNamespaceByConfigID(_ context.Context, id in
 }
 
 func (s *memoryNamespaceManager) Namespaces(_ context.Context) ([]*namespace.Namespace, error) {
-	nn := make([]*namespace.Namespace, 0, len(*s))
+	nn := make([]*namespace.Namespace, 0, len(s.namespaces))
 
-	for _, n := range *s {
+	for _, n := range s.namespaces {
 		nc := *n
 		nn = append(nn, &nc)
 	}
@@ -59,5 +78,9 @@ func (s *memoryNamespaceManager) Namespaces(_ context.Context) ([]*namespace.Nam
 }
 
 func (s *memoryNamespaceManager) ShouldReload(newValue interface{}) bool {
-	return !reflect.DeepEqual(newValue, []*namespace.Namespace(*s))
+	return !reflect.DeepEqual(newValue, s.namespaces)
+}
+
+func (s *memoryNamespaceManager) Version(context.Context) string {
+	return strconv.FormatUint(s.version, 10)
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,4 +20,14 @@ func TestNamespaceMemoryManager(t *testing.T) {
 		assert.True(t, nm.ShouldReload([]*namespace.Namespace{{Name: "3"}}))
 		assert.True(t, nm.ShouldReload("foo"))
 	})
+
+	t.Run("method=version", func(t *testing.T) {
+		ctx := context.Background()
+		n := &namespace.Namespace{Name: "2"}
+		nm := NewMemoryNamespaceManager(n)
+
+		assert.NotEmpty(t, nm.Version(ctx))
+		assert.Equal(t, nm.Version(ctx), nm.Version(ctx))
+		assert.NotEqual(t, nm.Version(ctx), NewMemoryNamespaceManager(n).Version(ctx))
+	})
 }
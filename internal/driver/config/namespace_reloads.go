@@ -0,0 +1,22 @@
+package config
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NamespaceReloads counts how often the namespace watchers reloaded the
+// namespaces, by the watcher ("file", "opl", or "remote") and the result
+// ("success" or "failure", if the namespaces could not be parsed and the last
+// known namespaces are kept).
+var NamespaceReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "keto_namespace_reloads_total",
+	Help: "The total number of namespace reloads by the namespace watchers.",
+}, []string{"watcher", "result"})
+
+func countNamespaceReload(watcher string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	NamespaceReloads.WithLabelValues(watcher, result).Inc()
+}
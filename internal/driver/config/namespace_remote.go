@@ -0,0 +1,357 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/logrusx"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+)
+
+type (
+	// remoteObject is a namespace file in a remote location. The ETag changes
+	// whenever the content changes.
+	remoteObject struct {
+		Key  string
+		ETag string
+	}
+
+	// remoteSource lists and reads the namespace files of a remote location.
+	remoteSource interface {
+		objects(ctx context.Context) ([]remoteObject, error)
+		read(ctx context.Context, key string) ([]byte, error)
+	}
+
+	// remoteWatcher is implemented by remote sources that notify about
+	// changes, so that they do not have to be polled.
+	remoteWatcher interface {
+		watch(ctx context.Context, changed func())
+	}
+
+	remoteContent struct {
+		etag string
+		raw  []byte
+	}
+
+	// remoteNamespaceWatcher polls an s3://, gs://, https://, or k8s://
+	// location for namespace files. Files are only downloaded again if their
+	// ETag changed.
+	remoteNamespaceWatcher struct {
+		sync.RWMutex
+		namespaces []*namespace.Namespace
+		version    uint64 // changes with every successful parse
+		l          *logrusx.Logger
+		src        remoteSource
+		target     string
+		config     interface{} // the configuration value the watcher was created for
+		opl        bool        // whether all files are written in the Ory Permission Language
+
+		polling  sync.Mutex
+		contents map[string]remoteContent
+	}
+)
+
+var (
+	_ namespace.Manager   = (*remoteNamespaceWatcher)(nil)
+	_ namespace.Versioner = (*remoteNamespaceWatcher)(nil)
+)
+
+// isRemoteLocation returns whether the namespace location has to be polled
+// instead of being watched on the local file system.
+func isRemoteLocation(target string) bool {
+	for _, scheme := range []string{"s3://", "gs://", "https://", "http://", "k8s://"} {
+		if strings.HasPrefix(target, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func newRemoteSource(target string) (remoteSource, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3Source(u)
+	case "gs":
+		return newGCSSource(u)
+	case "http", "https":
+		return &httpSource{url: u.String()}, nil
+	case "k8s":
+		return newKubernetesSource(u)
+	}
+	return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unknown scheme %q of the namespace location %q.", u.Scheme, target))
+}
+
+// newRemoteNamespaceWatcher loads the namespaces from the remote location and
+// polls it for changes until ctx is done. If opl is false, the location
+// contains namespace files in YAML, JSON, or TOML, and ".ts" files which are
+// merged into one Ory Permission Language model.
+func newRemoteNamespaceWatcher(ctx context.Context, l *logrusx.Logger, target string, config interface{}, opl bool, interval time.Duration) (*remoteNamespaceWatcher, error) {
+	src, err := newRemoteSource(target)
+	if err != nil {
+		return nil, err
+	}
+	w := &remoteNamespaceWatcher{
+		l:        l,
+		src:      src,
+		target:   target,
+		config:   config,
+		opl:      opl,
+		contents: make(map[string]remoteContent),
+	}
+	if err := w.poll(ctx); err != nil {
+		return nil, err
+	}
+
+	reload := func() {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.l.WithError(err).Errorf("Could not load the namespaces from %s, keeping the last known namespaces.", w.target)
+		}
+	}
+	if rw, ok := src.(remoteWatcher); ok {
+		go rw.watch(ctx, reload)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reload()
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// poll downloads the files that changed since the last poll, and replaces the
+// namespaces if all files could be parsed.
+func (w *remoteNamespaceWatcher) poll(ctx context.Context) error {
+	w.polling.Lock()
+	defer w.polling.Unlock()
+
+	objects, err := w.src.objects(ctx)
+	if err != nil {
+		return err
+	}
+
+	changed := len(objects) != len(w.contents)
+	contents := make(map[string]remoteContent, len(objects))
+	for _, o := range objects {
+		if c, ok := w.contents[o.Key]; ok && o.ETag != "" && c.etag == o.ETag {
+			contents[o.Key] = c
+			continue
+		}
+		raw, err := w.src.read(ctx, o.Key)
+		if err != nil {
+			return err
+		}
+		contents[o.Key] = remoteContent{etag: o.ETag, raw: raw}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	w.contents = contents
+	w.l.WithField("location", w.target).Info("A change to the namespace files was detected.")
+
+	namespaces, err := w.parse(contents)
+	countNamespaceReload("remote", err)
+	if err != nil {
+		return err
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	w.namespaces, w.version = namespaces, nextNamespacesVersion()
+	return nil
+}
+
+func (w *remoteNamespaceWatcher) parse(contents map[string]remoteContent) ([]*namespace.Namespace, error) {
+	var (
+		namespaces []*namespace.Namespace
+		opl        = make(map[string]string)
+		keys       = make([]string, 0, len(contents))
+	)
+	for key := range contents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if w.opl || path.Ext(key) == ".ts" {
+			opl[key] = string(contents[key].raw)
+			continue
+		}
+		parse, err := GetParser(key)
+		if err != nil {
+			w.l.WithError(err).WithField("file_name", key).Warn("could not infer format from file extension")
+			continue
+		}
+		n := &namespace.Namespace{}
+		if err := parse(contents[key].raw, n); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Could not parse the namespace file %s: %s", key, err))
+		}
+		namespaces = append(namespaces, n)
+	}
+
+	if len(opl) > 0 {
+		parsed, errs := schema.ParseContents(opl)
+		if len(errs) > 0 {
+			for _, err := range errs {
+				w.l.WithError(err).WithField("location", w.target).Error("could not parse the Ory Permission Language files")
+			}
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Could not parse the Ory Permission Language files at %s: %s", w.target, errs[0]))
+		}
+		for i := range parsed {
+			namespaces = append(namespaces, &parsed[i])
+		}
+	}
+	return namespaces, nil
+}
+
+func (w *remoteNamespaceWatcher) GetNamespaceByName(_ context.Context, name string) (*namespace.Namespace, error) {
+	w.RLock()
+	defer w.RUnlock()
+
+	for _, n := range w.namespaces {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("Unknown namespace with name %q.", name))
+}
+
+func (w *remoteNamespaceWatcher) GetNamespaceByConfigID(_ context.Context, id int32) (*namespace.Namespace, error) {
+	w.RLock()
+	defer w.RUnlock()
+
+	for _, n := range w.namespaces {
+		if n.ID == id {
+			return n, nil
+		}
+	}
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("Unknown namespace with ID %d.", id))
+}
+
+func (w *remoteNamespaceWatcher) Namespaces(_ context.Context) ([]*namespace.Namespace, error) {
+	w.RLock()
+	defer w.RUnlock()
+
+	nn := make([]*namespace.Namespace, len(w.namespaces))
+	copy(nn, w.namespaces)
+	return nn, nil
+}
+
+func (w *remoteNamespaceWatcher) Version(context.Context) string {
+	w.RLock()
+	defer w.RUnlock()
+
+	return strconv.FormatUint(w.version, 10)
+}
+
+func (w *remoteNamespaceWatcher) ShouldReload(newValue interface{}) bool {
+	return newValue != w.config
+}
+
+// httpSource is a single file served over HTTP. It is requested with the
+// ETag of the last response, so that unchanged files are not transferred
+// again.
+type httpSource struct {
+	url  string
+	last remoteContent
+}
+
+func (s *httpSource) objects(ctx context.Context) ([]remoteObject, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if s.last.etag != "" {
+		req.Header.Set("If-None-Match", s.last.etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+	case http.StatusOK:
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		etag := resp.Header.Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(raw)
+			etag = hex.EncodeToString(sum[:])
+		}
+		s.last = remoteContent{etag: etag, raw: raw}
+	default:
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Could not get %s: unexpected status code %d", s.url, resp.StatusCode))
+	}
+
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return []remoteObject{{Key: path.Base(u.Path), ETag: s.last.etag}}, nil
+}
+
+func (s *httpSource) read(context.Context, string) ([]byte, error) {
+	return s.last.raw, nil
+}
+
+// objectKeys returns the key and the prefix of the objects in a bucket
+// location. If the location ends with a slash, all objects with that prefix
+// are namespace files, otherwise only the one object.
+func objectKeys(u *url.URL) (prefix string, single bool) {
+	prefix = strings.TrimPrefix(u.Path, "/")
+	return prefix, prefix != "" && !strings.HasSuffix(prefix, "/")
+}
+
+// relativeKey returns the key of the object relative to the prefix, so that
+// Ory Permission Language files can import each other by relative paths.
+func relativeKey(prefix, key string) string {
+	return strings.TrimPrefix(key, prefix)
+}
+
+func unexpectedStatus(resp *http.Response, what string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Could not %s: unexpected status code %d: %s", what, resp.StatusCode, strings.TrimSpace(string(body))))
+}
+
+func readObject(req *http.Request, what string) ([]byte, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, what)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	return raw, errors.WithStack(err)
+}
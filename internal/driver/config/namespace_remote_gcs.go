@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x/gcpx"
+)
+
+type (
+	// gcsSource lists and reads the namespace files in a Google Cloud Storage
+	// bucket, e.g. gs://bucket/opl/ or gs://bucket/namespaces.keto.ts. Requests
+	// are authorized with the token of the service account from the metadata
+	// server, or sent anonymously if there is none. STORAGE_EMULATOR_HOST
+	// points the source to an emulator.
+	gcsSource struct {
+		bucket, prefix string
+		single         bool
+		base           string
+		tokens         *gcpx.TokenSource // nil if no token is used
+	}
+	gcsObject struct {
+		Name string `json:"name"`
+		ETag string `json:"etag"`
+	}
+	gcsObjects struct {
+		Items         []gcsObject `json:"items"`
+		NextPageToken string      `json:"nextPageToken"`
+	}
+)
+
+func newGCSSource(u *url.URL) (*gcsSource, error) {
+	s := &gcsSource{
+		bucket: u.Host,
+		base:   "https://storage.googleapis.com",
+		tokens: gcpx.NewTokenSource(),
+	}
+	s.prefix, s.single = objectKeys(u)
+
+	if emulator := os.Getenv("STORAGE_EMULATOR_HOST"); emulator != "" {
+		s.base = strings.TrimSuffix(emulator, "/")
+		if !strings.Contains(s.base, "://") {
+			s.base = "http://" + s.base
+		}
+		s.tokens = nil
+	}
+	return s, nil
+}
+
+func (s *gcsSource) objectURL(name string) string {
+	return s.base + "/storage/v1/b/" + url.PathEscape(s.bucket) + "/o/" + url.PathEscape(name)
+}
+
+func (s *gcsSource) objects(ctx context.Context) ([]remoteObject, error) {
+	if s.single {
+		var o gcsObject
+		if err := s.getJSON(ctx, s.objectURL(s.prefix), &o); err != nil {
+			return nil, err
+		}
+		return []remoteObject{{Key: path.Base(s.prefix), ETag: o.ETag}}, nil
+	}
+
+	var (
+		objects []remoteObject
+		token   string
+	)
+	for {
+		query := url.Values{"prefix": {s.prefix}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		var res gcsObjects
+		if err := s.getJSON(ctx, s.base+"/storage/v1/b/"+url.PathEscape(s.bucket)+"/o?"+query.Encode(), &res); err != nil {
+			return nil, err
+		}
+		for _, o := range res.Items {
+			if strings.HasSuffix(o.Name, "/") {
+				continue
+			}
+			objects = append(objects, remoteObject{Key: relativeKey(s.prefix, o.Name), ETag: o.ETag})
+		}
+		if res.NextPageToken == "" {
+			return objects, nil
+		}
+		token = res.NextPageToken
+	}
+}
+
+func (s *gcsSource) read(ctx context.Context, key string) ([]byte, error) {
+	if !s.single {
+		key = s.prefix + key
+	}
+	req, err := s.request(ctx, s.objectURL(key)+"?alt=media")
+	if err != nil {
+		return nil, err
+	}
+	return readObject(req, "get gs://"+s.bucket+"/"+key)
+}
+
+func (s *gcsSource) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := s.request(ctx, u)
+	if err != nil {
+		return err
+	}
+	raw, err := readObject(req, "list gs://"+s.bucket+"/"+s.prefix)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(json.Unmarshal(raw, v))
+}
+
+func (s *gcsSource) request(ctx context.Context, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s.tokens.Authorize(req)
+	return req, nil
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+)
+
+type (
+	// kubernetesSource reads the namespace files from the Kubernetes API, and
+	// watches it for changes instead of relying on mounted files:
+	//
+	//   - k8s://configmap/<namespace>/<name> uses every key of the ConfigMap
+	//     as a file.
+	//   - k8s://ketonamespaces/<namespace> uses the Ory Permission Language of
+	//     every KetoNamespace custom resource in the namespace as the file
+	//     "<name>.ts". The resources can be filtered with the "labelSelector"
+	//     query parameter.
+	//
+	// Inside of a cluster, the service account of the pod is used. The "api"
+	// query parameter points to another API server, e.g. of `kubectl proxy`.
+	kubernetesSource struct {
+		collection string // the URL path of the ConfigMaps or KetoNamespaces
+		configMap  string // the name of the ConfigMap, if any
+		query      url.Values
+		api        string
+		tokenFile  string
+		client     *http.Client
+
+		sync.Mutex
+		files           map[string]string
+		resourceVersion string
+	}
+	k8sMetadata struct {
+		Name            string `json:"name"`
+		ResourceVersion string `json:"resourceVersion"`
+	}
+	k8sConfigMap struct {
+		Metadata k8sMetadata       `json:"metadata"`
+		Data     map[string]string `json:"data"`
+	}
+	k8sKetoNamespace struct {
+		Metadata k8sMetadata `json:"metadata"`
+		Spec     struct {
+			OPL string `json:"opl"`
+		} `json:"spec"`
+	}
+	k8sKetoNamespaceList struct {
+		Metadata k8sMetadata        `json:"metadata"`
+		Items    []k8sKetoNamespace `json:"items"`
+	}
+	k8sWatchEvent struct {
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+)
+
+const (
+	k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	KetoNamespaceGroup   = "keto.ory.sh"
+	KetoNamespaceVersion = "v1alpha1"
+)
+
+func newKubernetesSource(u *url.URL) (*kubernetesSource, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	s := &kubernetesSource{
+		query:     url.Values{},
+		api:       u.Query().Get("api"),
+		tokenFile: k8sServiceAccountDir + "/token",
+		client:    http.DefaultClient,
+	}
+	switch {
+	case u.Host == "configmap" && len(parts) == 2:
+		s.collection = "/api/v1/namespaces/" + url.PathEscape(parts[0]) + "/configmaps"
+		s.configMap = parts[1]
+	case u.Host == "ketonamespaces" && len(parts) == 1 && parts[0] != "":
+		s.collection = "/apis/" + KetoNamespaceGroup + "/" + KetoNamespaceVersion + "/namespaces/" + url.PathEscape(parts[0]) + "/ketonamespaces"
+		if selector := u.Query().Get("labelSelector"); selector != "" {
+			s.query.Set("labelSelector", selector)
+		}
+	default:
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+			"The Kubernetes namespace location %q has to be k8s://configmap/<namespace>/<name> or k8s://ketonamespaces/<namespace>.", u.String()))
+	}
+
+	if s.api == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+				"Keto is not running in a Kubernetes cluster, set the API server with the \"api\" query parameter of %q.", u.String()))
+		}
+		s.api = "https://" + net.JoinHostPort(host, port)
+
+		ca, err := os.ReadFile(k8sServiceAccountDir + "/ca.crt")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		s.client = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		}}
+	}
+	s.api = strings.TrimSuffix(s.api, "/")
+	return s, nil
+}
+
+func (s *kubernetesSource) request(ctx context.Context, path string, query url.Values) (*http.Request, error) {
+	u := s.api + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	// The token is read for every request, because bound service account
+	// tokens are rotated.
+	if token, err := os.ReadFile(s.tokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	return req, nil
+}
+
+func (s *kubernetesSource) objects(ctx context.Context) ([]remoteObject, error) {
+	path := s.collection
+	if s.configMap != "" {
+		path += "/" + url.PathEscape(s.configMap)
+	}
+	req, err := s.request(ctx, path, s.query)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, "get "+path)
+	}
+
+	files := make(map[string]string)
+	var (
+		objects         []remoteObject
+		resourceVersion string
+	)
+	if s.configMap != "" {
+		var cm k8sConfigMap
+		if err := json.NewDecoder(resp.Body).Decode(&cm); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		resourceVersion = cm.Metadata.ResourceVersion
+		for key, content := range cm.Data {
+			files[key] = content
+			objects = append(objects, remoteObject{Key: key, ETag: cm.Metadata.ResourceVersion})
+		}
+	} else {
+		var list k8sKetoNamespaceList
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		resourceVersion = list.Metadata.ResourceVersion
+		for _, n := range list.Items {
+			files[n.Metadata.Name+".ts"] = n.Spec.OPL
+			objects = append(objects, remoteObject{Key: n.Metadata.Name + ".ts", ETag: n.Metadata.ResourceVersion})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	s.Lock()
+	defer s.Unlock()
+	s.files, s.resourceVersion = files, resourceVersion
+	return objects, nil
+}
+
+func (s *kubernetesSource) read(_ context.Context, key string) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	return []byte(s.files[key]), nil
+}
+
+// watch calls changed whenever the watched resources change, until ctx is
+// done. The watch is restarted after the API server closes it.
+func (s *kubernetesSource) watch(ctx context.Context, changed func()) {
+	for ctx.Err() == nil {
+		wait := time.Second
+		if err := s.watchOnce(ctx, changed); err != nil && ctx.Err() == nil {
+			// Resynchronize, e.g. after the resource version expired.
+			changed()
+			wait = 5 * time.Second
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (s *kubernetesSource) watchOnce(ctx context.Context, changed func()) error {
+	s.Lock()
+	query := url.Values{"watch": {"true"}, "resourceVersion": {s.resourceVersion}}
+	s.Unlock()
+	for k, v := range s.query {
+		query[k] = v
+	}
+
+	if s.configMap != "" {
+		// A single object is watched through its collection.
+		query.Set("fieldSelector", "metadata.name="+s.configMap)
+	}
+	req, err := s.request(ctx, s.collection, query)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return unexpectedStatus(resp, "watch "+s.collection)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e k8sWatchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return errors.WithStack(err)
+		}
+		if e.Type == "ERROR" {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Watching %s failed: %s", s.collection, e.Object))
+		}
+		changed()
+	}
+	return errors.WithStack(scanner.Err())
+}
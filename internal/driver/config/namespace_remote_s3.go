@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x/awsx"
+)
+
+type (
+	// s3Source lists and reads the namespace files in an S3 bucket, e.g.
+	// s3://bucket/opl/ or s3://bucket/namespaces.keto.ts. Requests are signed
+	// with the credentials from the standard AWS environment variables, or sent
+	// anonymously if there are none. The region can be set with the "region"
+	// query parameter, and an S3 compatible endpoint with the "endpoint" query
+	// parameter or AWS_ENDPOINT_URL.
+	s3Source struct {
+		bucket, prefix string
+		single         bool
+		region         string
+		endpoint       *url.URL // nil for AWS
+		creds          awsx.Credentials
+		now            func() time.Time
+	}
+	s3ListBucketResult struct {
+		Contents []struct {
+			Key  string `xml:"Key"`
+			ETag string `xml:"ETag"`
+		} `xml:"Contents"`
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+	}
+)
+
+func newS3Source(u *url.URL) (*s3Source, error) {
+	s := &s3Source{
+		bucket: u.Host,
+		region: firstNonEmpty(u.Query().Get("region"), os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		creds:  awsx.CredentialsFromEnv(),
+		now:    time.Now,
+	}
+	s.prefix, s.single = objectKeys(u)
+
+	if endpoint := firstNonEmpty(u.Query().Get("endpoint"), os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); endpoint != "" {
+		var err error
+		if s.endpoint, err = url.Parse(endpoint); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return s, nil
+}
+
+func (s *s3Source) objects(ctx context.Context) ([]remoteObject, error) {
+	if s.single {
+		req, err := s.request(ctx, http.MethodHead, s.prefix, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, unexpectedStatus(resp, "get s3://"+s.bucket+"/"+s.prefix)
+		}
+		return []remoteObject{{Key: path.Base(s.prefix), ETag: resp.Header.Get("ETag")}}, nil
+	}
+
+	var (
+		objects []remoteObject
+		token   string
+	)
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.request(ctx, http.MethodGet, "", query)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := readObject(req, "list s3://"+s.bucket+"/"+s.prefix)
+		if err != nil {
+			return nil, err
+		}
+		var res s3ListBucketResult
+		if err := xml.Unmarshal(raw, &res); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, c := range res.Contents {
+			if strings.HasSuffix(c.Key, "/") {
+				continue
+			}
+			objects = append(objects, remoteObject{Key: relativeKey(s.prefix, c.Key), ETag: c.ETag})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (s *s3Source) read(ctx context.Context, key string) ([]byte, error) {
+	if !s.single {
+		key = s.prefix + key
+	}
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return readObject(req, "get s3://"+s.bucket+"/"+key)
+}
+
+// request creates a request for the object with the key, or for the bucket if
+// the key is empty. Custom endpoints are addressed path-style, AWS
+// virtual-hosted-style.
+func (s *s3Source) request(ctx context.Context, method, key string, query url.Values) (*http.Request, error) {
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region), Path: "/" + key}
+	if s.endpoint != nil {
+		u = &url.URL{Scheme: s.endpoint.Scheme, Host: s.endpoint.Host, Path: strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.bucket}
+		if key != "" {
+			u.Path += "/" + key
+		}
+	}
+	u.RawPath = awsURIEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if s.creds.AccessKeyID != "" {
+		s.sign(req)
+	}
+	return req, nil
+}
+
+// sign signs the request with AWS Signature Version 4.
+func (s *s3Source) sign(req *http.Request) {
+	req.Header.Set("X-Amz-Content-Sha256", awsx.EmptyPayloadHash)
+	awsx.SignRequest(req, s.creds, s.region, "s3", awsx.EmptyPayloadHash, s.now())
+}
+
+// canonicalQuery encodes the query sorted by key, as required for signing.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, and the
+// slash unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
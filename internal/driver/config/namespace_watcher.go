@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/ghodss/yaml"
@@ -31,11 +33,13 @@ type (
 		Parser   Parser
 
 		namespace *namespace.Namespace // last successfully parsed namespace. May be nil if there was a parse error
+		parseErr  error                // error of the last parse attempt
 	}
 
 	NamespaceWatcher struct {
 		sync.RWMutex
 		namespaces map[string]*NamespaceFile
+		version    uint64 // changes with every event
 		ec         watcherx.EventChannel
 		l          *logrusx.Logger
 		target     string
@@ -43,7 +47,11 @@ type (
 	}
 )
 
-var _ namespace.Manager = (*NamespaceWatcher)(nil)
+var (
+	_ namespace.Manager           = (*NamespaceWatcher)(nil)
+	_ namespace.LoadErrorReporter = (*NamespaceWatcher)(nil)
+	_ namespace.Versioner         = (*NamespaceWatcher)(nil)
+)
 
 func NewNamespaceWatcher(ctx context.Context, l *logrusx.Logger, target string) (*NamespaceWatcher, error) {
 	u, err := urlx.Parse(target)
@@ -112,22 +120,28 @@ func eventHandler(ctx context.Context, nw *NamespaceWatcher, done <-chan int, in
 				func() {
 					nw.Lock()
 					defer nw.Unlock()
+					nw.version = nextNamespacesVersion()
 
 					delete(nw.namespaces, e.Source())
 				}()
+				countNamespaceReload("file", nil)
 			case *watcherx.ChangeEvent:
 				// the lock is acquired before parsing to ensure that the getters are waiting for the updated values
 				func() {
 					nw.Lock()
 					defer nw.Unlock()
+					nw.version = nextNamespacesVersion()
 
 					n := readNamespaceFile(nw.l, e.Reader(), e.Source())
 					if n == nil {
 						return
-					} else if n.namespace == nil {
+					}
+					countNamespaceReload("file", n.parseErr)
+					if n.namespace == nil {
 						// parse failed, rolling back to previous working version
 						if existing, ok := nw.namespaces[e.Source()]; ok {
 							existing.Contents = n.Contents
+							existing.parseErr = n.parseErr
 						} else {
 							nw.namespaces[e.Source()] = n
 						}
@@ -159,7 +173,7 @@ func readNamespaceFile(l *logrusx.Logger, r io.Reader, source string) *Namespace
 	n := namespace.Namespace{}
 	if err := parse(raw, &n); err != nil {
 		l.WithError(errors.WithStack(err)).WithField("file_name", source).Error("could not parse namespace file")
-		return &NamespaceFile{Name: source, Contents: raw, Parser: parse}
+		return &NamespaceFile{Name: source, Contents: raw, Parser: parse, parseErr: errors.WithStack(err)}
 	}
 
 	return &NamespaceFile{Name: source, Contents: raw, Parser: parse, namespace: &n}
@@ -204,6 +218,32 @@ func (n *NamespaceWatcher) Namespaces(_ context.Context) ([]*namespace.Namespace
 	return nspaces, nil
 }
 
+func (n *NamespaceWatcher) Version(context.Context) string {
+	n.RLock()
+	defer n.RUnlock()
+
+	return strconv.FormatUint(n.version, 10)
+}
+
+// LoadError returns the parse error of the first namespace file that was never
+// parsed successfully. Files with a last known version are not reported.
+func (n *NamespaceWatcher) LoadError() error {
+	n.RLock()
+	defer n.RUnlock()
+
+	names := make([]string, 0, len(n.namespaces))
+	for name, nsf := range n.namespaces {
+		if nsf.namespace == nil && nsf.parseErr != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return errors.Wrapf(n.namespaces[names[0]].parseErr, "could not parse the namespace file %s", names[0])
+}
+
 func (n *NamespaceWatcher) NamespaceFiles() []*NamespaceFile {
 	n.RLock()
 	defer n.RUnlock()
@@ -225,14 +265,19 @@ func (n *NamespaceWatcher) ShouldReload(newValue interface{}) bool {
 	return v != n.target
 }
 
+// GetParser returns the parser for the namespace file format of the file
+// extension. Namespaces with a configuration in the format of the Zanzibar
+// paper get the relations of that configuration.
 func GetParser(fn string) (Parser, error) {
 	switch ext := stringsx.SwitchExact(filepath.Ext(fn)); {
 	case ext.AddCase(".yaml"), ext.AddCase(".yml"):
-		return yaml.Unmarshal, nil
+		return withZanzibarRelations(yaml.Unmarshal), nil
 	case ext.AddCase(".json"):
-		return json.Unmarshal, nil
+		return withZanzibarRelations(json.Unmarshal), nil
 	case ext.AddCase(".toml"):
-		return toml.Unmarshal, nil
+		return withZanzibarRelations(toml.Unmarshal), nil
+	case ext.AddCase(".textproto"), ext.AddCase(".txtpb"), ext.AddCase(".pbtxt"):
+		return withZanzibarRelations(parseZanzibarText), nil
 	default:
 		return nil, ext.ToUnknownCaseErr()
 	}
@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
 )
 
 func TestNamespaceProvider(t *testing.T) {
@@ -92,6 +93,7 @@ func TestNamespaceProvider(t *testing.T) {
 		require.NoError(t, err)
 
 		assert.Equal(t, []*namespace.Namespace{n}, nspaces)
+		assert.NoError(t, ws.LoadError())
 	})
 
 	t.Run("case=reads namespace files from directory", func(t *testing.T) {
@@ -185,6 +187,58 @@ func TestNamespaceProvider(t *testing.T) {
 		// files are included even if ns is unparsable
 		nsfs := nw.NamespaceFiles()
 		assert.Equal(t, 2, len(nsfs))
+
+		err = nw.LoadError()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "malformed.yml")
+	})
+
+	t.Run("case=loads Zanzibar namespace configs", func(t *testing.T) {
+		dir := t.TempDir()
+		writeDir(t, dir, map[string]interface{}{
+			"doc.textproto": `name: "doc"
+relation { name: "owner" }
+relation {
+  name: "viewer"
+  userset_rewrite {
+    union {
+      child { _this {} }
+      child { computed_userset { relation: "owner" } }
+    }
+  }
+}
+`,
+			"folder.yml": `id: 1
+name: folder
+config:
+  relation:
+    - name: parent
+    - name: viewer
+      usersetRewrite:
+        tupleToUserset:
+          tupleset: {relation: parent}
+          computedUserset: {object: $TUPLE_USERSET_OBJECT, relation: viewer}
+`,
+		})
+
+		nw, hook := setup(t, "file://"+dir)
+		require.Empty(t, hook.Entries)
+
+		doc, err := nw.GetNamespaceByName(context.Background(), "doc")
+		require.NoError(t, err)
+		assert.Equal(t, []ast.Relation{
+			{Name: "owner"},
+			{Name: "viewer", SubjectSetRewrite: &ast.SubjectSetRewrite{Children: ast.Children{&ast.ComputedSubjectSet{Relation: "owner"}}}},
+		}, doc.Relations)
+
+		folder, err := nw.GetNamespaceByName(context.Background(), "folder")
+		require.NoError(t, err)
+		assert.Equal(t, []ast.Relation{
+			{Name: "parent"},
+			{Name: "viewer", SubjectSetRewrite: &ast.SubjectSetRewrite{Children: ast.Children{
+				&ast.TupleToSubjectSet{Relation: "parent", ComputedSubjectSetRelation: "viewer"},
+			}}},
+		}, folder.Relations)
 	})
 
 	t.Run("method=should reload", func(t *testing.T) {
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+)
+
+// parseZanzibarText parses a namespace configuration in the protocol buffer
+// text format of the Zanzibar paper. It is decoded like a namespace file with
+// the ID 0 and the whole configuration as its config, as the format has no
+// namespace IDs.
+func parseZanzibarText(raw []byte, v interface{}) error {
+	config, err := schema.ZanzibarTextToJSON(raw)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var c struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(config, &c); err != nil {
+		return errors.WithStack(err)
+	}
+	if c.Name == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The Zanzibar namespace configuration is missing its name."))
+	}
+
+	n, err := json.Marshal(map[string]interface{}{"id": 0, "name": c.Name, "config": json.RawMessage(config)})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(json.Unmarshal(n, v))
+}
+
+// withZanzibarRelations decodes the relations of namespaces that are
+// configured in the format of the Zanzibar paper after parsing.
+func withZanzibarRelations(parse Parser) Parser {
+	return func(raw []byte, v interface{}) error {
+		if err := parse(raw, v); err != nil {
+			return err
+		}
+		if n, ok := v.(*namespace.Namespace); ok {
+			return loadZanzibarRelations(n)
+		}
+		return nil
+	}
+}
+
+func loadZanzibarRelations(n *namespace.Namespace) error {
+	if !schema.IsZanzibarConfig(n.Config) {
+		return nil
+	}
+	_, relations, err := schema.ConvertZanzibar(n.Config)
+	if err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not convert the Zanzibar configuration of namespace %q: %s", n.Name, err))
+	}
+	n.Relations = relations
+	return nil
+}
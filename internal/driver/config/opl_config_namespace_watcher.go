@@ -0,0 +1,270 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/urlx"
+	"github.com/ory/x/watcherx"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+)
+
+type (
+	// oplLocation is the location of an Ory Permission Language file, as set in
+	// `namespaces.location`.
+	oplLocation string
+
+	oplConfigWatcher struct {
+		sync.RWMutex
+		namespaces []*namespace.Namespace
+		version    uint64 // changes with every successful parse
+		ec         watcherx.EventChannel
+		l          *logrusx.Logger
+		target     string
+		local      bool   // whether the target is local, so that imports can be resolved
+		pattern    string // glob of the watched files if the target is a directory or a glob
+		w          watcherx.Watcher
+		parseErr   error // error of the last parse attempt
+	}
+)
+
+var (
+	_ namespace.Manager           = (*oplConfigWatcher)(nil)
+	_ namespace.LoadErrorReporter = (*oplConfigWatcher)(nil)
+	_ namespace.Versioner         = (*oplConfigWatcher)(nil)
+)
+
+func newOPLConfigWatcher(ctx context.Context, l *logrusx.Logger, target string) (*oplConfigWatcher, error) {
+	u, err := urlx.Parse(target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	w := &oplConfigWatcher{
+		ec:     make(watcherx.EventChannel),
+		l:      l,
+		target: target,
+		local:  u.Scheme == "file" || u.Scheme == "",
+	}
+
+	var dir string
+	if w.local {
+		w.pattern, dir, err = oplFilePattern(u.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if w.pattern != "" {
+		w.w, err = watcherx.WatchDirectory(ctx, dir, w.ec)
+	} else {
+		w.w, err = watcherx.Watch(ctx, u, w.ec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := w.w.DispatchNow()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	initialEventsProcessed := make(chan struct{})
+	go w.handleEvents(ctx, done, initialEventsProcessed)
+	<-initialEventsProcessed
+
+	return w, nil
+}
+
+func (w *oplConfigWatcher) handleEvents(ctx context.Context, done <-chan int, initialEventsProcessed chan<- struct{}) {
+	initialDone := false
+	for {
+		select {
+		case <-done:
+			if !initialDone {
+				initialDone = true
+				close(initialEventsProcessed)
+			}
+		case <-ctx.Done():
+			return
+		case e, open := <-w.ec:
+			if !open {
+				return
+			}
+
+			if initialDone {
+				w.l.WithField("file", e.Source()).WithField("event_type", fmt.Sprintf("%T", e)).Info("A change to the Ory Permission Language file was detected.")
+			}
+
+			switch etyped := e.(type) {
+			case *watcherx.RemoveEvent:
+				if w.pattern != "" {
+					w.readOPLFiles()
+				} else {
+					w.l.WithField("file", e.Source()).Warn("The Ory Permission Language file was removed, keeping the last known namespaces.")
+				}
+			case *watcherx.ChangeEvent:
+				if w.pattern != "" {
+					// Any of the files could import the changed file, so all
+					// of them are parsed again.
+					w.readOPLFiles()
+				} else {
+					w.readOPL(etyped.Reader(), etyped.Source())
+				}
+			case *watcherx.ErrorEvent:
+				w.l.WithError(etyped).Errorf("Received error while watching the Ory Permission Language file at %s.", w.target)
+			}
+		}
+	}
+}
+
+func (w *oplConfigWatcher) readOPL(r io.Reader, source string) {
+	// the lock is acquired before parsing to ensure that the getters are waiting for the updated values
+	w.Lock()
+	defer w.Unlock()
+
+	var (
+		parsed []namespace.Namespace
+		errs   []error
+	)
+	if w.local {
+		parsed, errs = schema.ParseLocalFiles(source)
+	} else {
+		raw, err := io.ReadAll(r)
+		if err != nil {
+			w.l.WithError(errors.WithStack(err)).WithField("file_name", source).Error("could not read the Ory Permission Language file")
+			return
+		}
+		parsed, errs = schema.Parse(string(raw))
+	}
+	w.setNamespaces(parsed, errs, source)
+}
+
+func (w *oplConfigWatcher) readOPLFiles() {
+	w.Lock()
+	defer w.Unlock()
+
+	matches, err := filepath.Glob(w.pattern)
+	if err != nil {
+		w.l.WithError(errors.WithStack(err)).WithField("file_name", w.pattern).Error("could not list the Ory Permission Language files")
+		return
+	}
+	files := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && !info.IsDir() {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		w.l.WithField("file_name", w.pattern).Warn("No Ory Permission Language files were found, keeping the last known namespaces.")
+		return
+	}
+
+	parsed, errs := schema.ParseLocalFiles(files...)
+	w.setNamespaces(parsed, errs, w.pattern)
+}
+
+// setNamespaces replaces the namespaces if the files were parsed without
+// errors. The caller has to hold the lock.
+func (w *oplConfigWatcher) setNamespaces(parsed []namespace.Namespace, errs []error, source string) {
+	if len(errs) > 0 {
+		for _, err := range errs {
+			w.l.WithError(err).WithField("file_name", source).Error("could not parse the Ory Permission Language file, keeping the last known namespaces")
+		}
+		w.parseErr = errors.Wrapf(errs[0], "could not parse the Ory Permission Language file %s", source)
+		countNamespaceReload("opl", w.parseErr)
+		return
+	}
+	w.parseErr = nil
+	countNamespaceReload("opl", nil)
+
+	namespaces := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		namespaces[i] = &parsed[i]
+	}
+	w.namespaces, w.version = namespaces, nextNamespacesVersion()
+}
+
+// LoadError returns the parse error of the Ory Permission Language files if
+// they were never parsed successfully.
+func (w *oplConfigWatcher) LoadError() error {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.namespaces != nil {
+		return nil
+	}
+	return w.parseErr
+}
+
+// oplFilePattern returns the glob of the Ory Permission Language files and the
+// directory to watch if the location is a directory or a glob. Only the last
+// path element may contain a glob. For a single file, the pattern is empty.
+func oplFilePattern(location string) (pattern, dir string, err error) {
+	if strings.ContainsAny(location, "*?[") {
+		dir = filepath.Dir(location)
+		if strings.ContainsAny(dir, "*?[") {
+			return "", "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Only the last element of the Ory Permission Language location %q may contain a glob.", location))
+		}
+		if _, err := filepath.Match(location, ""); err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		return location, dir, nil
+	}
+
+	info, err := os.Stat(location)
+	if err != nil || !info.IsDir() {
+		// A missing file is reported by the file watcher.
+		return "", "", nil
+	}
+	return filepath.Join(location, "*.ts"), location, nil
+}
+
+func (w *oplConfigWatcher) GetNamespaceByName(_ context.Context, name string) (*namespace.Namespace, error) {
+	w.RLock()
+	defer w.RUnlock()
+
+	for _, n := range w.namespaces {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("Unknown namespace with name %q.", name))
+}
+
+func (w *oplConfigWatcher) GetNamespaceByConfigID(_ context.Context, id int32) (*namespace.Namespace, error) {
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("Namespaces defined in the Ory Permission Language have no ID, but got %d.", id))
+}
+
+func (w *oplConfigWatcher) Namespaces(_ context.Context) ([]*namespace.Namespace, error) {
+	w.RLock()
+	defer w.RUnlock()
+
+	nn := make([]*namespace.Namespace, len(w.namespaces))
+	copy(nn, w.namespaces)
+	return nn, nil
+}
+
+func (w *oplConfigWatcher) Version(context.Context) string {
+	w.RLock()
+	defer w.RUnlock()
+
+	return strconv.FormatUint(w.version, 10)
+}
+
+func (w *oplConfigWatcher) ShouldReload(newValue interface{}) bool {
+	v, ok := newValue.(oplLocation)
+	return !ok || string(v) != w.target
+}
@@ -5,7 +5,13 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net"
+	"os"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ory/keto/embedx"
 
@@ -18,6 +24,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/cors"
 	"github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
 
 	"github.com/ory/keto/internal/namespace"
 )
@@ -25,17 +32,112 @@ import (
 const (
 	KeyDSN = "dsn"
 
+	KeySecretsRefreshInterval = "secrets.refresh_interval"
+	KeySecretsCipher          = "secrets.cipher"
+	KeySecretsMappingID       = "secrets.mapping_id"
+
+	KeyReadReplicaDSNs               = "read_replicas.dsns"
+	KeyReadReplicaDefaultConsistency = "read_replicas.default_consistency"
+
+	KeyNamespaceStorage = "namespace_storage"
+
+	KeyDatabasePoolMaxOpenConnections    = "database_pool.max_open_connections"
+	KeyDatabasePoolMaxIdleConnections    = "database_pool.max_idle_connections"
+	KeyDatabasePoolMaxConnectionLifetime = "database_pool.max_connection_lifetime"
+	KeyDatabasePoolMaxConnectionIdleTime = "database_pool.max_connection_idle_time"
+	KeyDatabasePoolStatementTimeout      = "database_pool.statement_timeout"
+	KeyDatabasePoolConnectTimeout        = "database_pool.connect_timeout"
+
+	KeyUUIDMappingCacheSize = "uuid_mapping_cache.size"
+
+	KeyUUIDMappingGCInterval    = "uuid_mapping_gc.interval"
+	KeyUUIDMappingGCGracePeriod = "uuid_mapping_gc.grace_period"
+	KeyUUIDMappingGCBatchSize   = "uuid_mapping_gc.batch_size"
+
+	KeyShardingDSNs = "sharding.dsns"
+	KeyShardingKey  = "sharding.key"
+
+	KeyMemorySnapshotPath     = "memory_snapshot.path"
+	KeyMemorySnapshotInterval = "memory_snapshot.interval"
+
+	KeyRedisURL       = "redis.url"
+	KeyRedisKeyPrefix = "redis.key_prefix"
+
+	KeyDynamoDBTable    = "dynamodb.table"
+	KeyDynamoDBRegion   = "dynamodb.region"
+	KeyDynamoDBEndpoint = "dynamodb.endpoint"
+
+	KeySpannerDatabase = "spanner.database"
+	KeySpannerEndpoint = "spanner.endpoint"
+
 	KeyLimitMaxReadDepth = "limit.max_read_depth"
 	KeyReadAPIHost       = "serve.read.host"
 	KeyReadAPIPort       = "serve.read.port"
 
+	KeyLimitDefaultPageSize    = "limit.default_page_size"
+	KeyLimitMaxPageSize        = "limit.max_page_size"
+	KeyLimitMaxExpandNodes     = "limit.max_expand_nodes"
+	KeyLimitMaxRequestBodySize = "limit.max_request_body_size"
+
+	KeyRateLimitKey               = "rate_limit.key"
+	KeyRateLimitTrustForwardedFor = "rate_limit.trust_forwarded_for"
+	KeyRateLimitTrustedProxies    = "rate_limit.trusted_proxies"
+
+	KeyMetaPermissionsNamespace  = "meta_permissions.namespace"
+	KeyMetaPermissionsSuperusers = "meta_permissions.superusers"
+
 	KeyWriteAPIHost = "serve.write.host"
 	KeyWriteAPIPort = "serve.write.port"
 
 	KeyMetricsHost = "serve.metrics.host"
 	KeyMetricsPort = "serve.metrics.port"
 
-	KeyNamespaces = "namespaces"
+	KeyOTLPMetricsEndpoint = "metrics.otlp.endpoint"
+	KeyOTLPMetricsHeaders  = "metrics.otlp.headers"
+	KeyOTLPMetricsInterval = "metrics.otlp.interval"
+
+	KeyTupleCountMetricsInterval    = "metrics.relation_tuples.interval"
+	KeyTupleCountMetricsPerRelation = "metrics.relation_tuples.per_relation"
+	KeyTupleCountMetricsEstimate    = "metrics.relation_tuples.estimate"
+
+	KeyGRPCReflection   = "serve.grpc.reflection"
+	KeyGRPCInterceptors = "serve.grpc.interceptors"
+
+	KeyAdminAPIEnabled = "serve.admin.enabled"
+	KeyAdminAPIHost    = "serve.admin.host"
+	KeyAdminAPIPort    = "serve.admin.port"
+	KeyAdminAPIDebug   = "serve.admin.debug.enabled"
+
+	KeyNamespaces                       = "namespaces"
+	KeyNamespacesLocation               = "namespaces.location"
+	KeyNamespacesExperimentalStrictMode = "namespaces.experimental_strict_mode"
+
+	KeyCDCEnabled      = "cdc.enabled"
+	KeyCDCSinkType     = "cdc.sink.type"
+	KeyCDCSinkURL      = "cdc.sink.url"
+	KeyCDCSinkTopic    = "cdc.sink.topic"
+	KeyCDCPollInterval = "cdc.poll_interval"
+	KeyCDCBatchSize    = "cdc.batch_size"
+
+	KeyHistoryEnabled       = "history.enabled"
+	KeyHistoryWatchInterval = "history.watch_interval"
+
+	KeyGraphQLEnabled      = "graphql.enabled"
+	KeyGraphQLMaxDepth     = "graphql.max_depth"
+	KeyGraphQLMaxBatchSize = "graphql.max_batch_size"
+
+	KeyRequestIDSQLComments = "request_id.sql_comments"
+
+	KeyTenancyEnabled = "tenancy.enabled"
+	KeyTenancyHeader  = "tenancy.header"
+	KeyTenancyTenants = "tenancy.tenants"
+	KeyTenancyPattern = "tenancy.pattern"
+
+	KeyNamespaceAPIEnabled         = "namespace_api.enabled"
+	KeyNamespaceAPIKeys            = "namespace_api.api_keys"
+	KeyNamespaceAPIRefreshInterval = "namespace_api.refresh_interval"
+
+	KeyNamespaceSourcesPollInterval = "namespace_sources.poll_interval"
 
 	DSNMemory = "sqlite://file::memory:?_fk=true&cache=shared"
 )
@@ -49,10 +151,17 @@ type (
 		nm                     namespace.Manager
 		cancelNamespaceManager context.CancelFunc
 		nmLock                 sync.Mutex
+		managed                *managedNamespaces
+		aliases                *namespaceAliases
+		secrets                secrets
+		reloadDatabase         func()
 	}
 	Provider interface {
 		Config(ctx context.Context) *Config
 	}
+	CDCSinkConfig struct {
+		Type, URL, Topic string
+	}
 )
 
 func New(ctx context.Context, l *logrusx.Logger, p *configx.Provider) *Config {
@@ -81,8 +190,8 @@ func NewProvider(ctx context.Context, flags *pflag.FlagSet, config *Config, opts
 		append(opts,
 			configx.WithFlags(flags),
 			configx.WithStderrValidationReporter(),
-			configx.WithImmutables(KeyDSN, "serve"),
-			configx.OmitKeysFromTracing(KeyDSN),
+			configx.WithImmutables("serve", KeyNamespaceStorage, "sharding"),
+			configx.OmitKeysFromTracing(KeyDSN, KeyReadReplicaDSNs, KeyNamespaceStorage, KeyShardingDSNs, KeyNamespaceAPIKeys, KeyCDCSinkURL, KeyAuditSinks, KeyOTLPMetricsHeaders, KeyAuthnAPIKeys, KeyAuthnIntrospection, KeySidecarPrimaryAPIKey, KeySecretsCipher, KeySecretsMappingID),
 			configx.WithLogrusWatcher(config.l),
 			configx.WithContext(ctx),
 			configx.AttachWatcher(config.watcher),
@@ -108,7 +217,9 @@ func (k *Config) watcher(_ watcherx.Event, err error) {
 	if err != nil {
 		return
 	}
-	nm, err := k.NamespaceManager()
+	k.notifyDatabaseChange()
+
+	nm, err := k.ConfiguredNamespaceManager()
 	if err != nil {
 		k.l.WithError(err).Error("got internal error in config watcher: could not get namespace manager")
 		return
@@ -143,12 +254,34 @@ func (k *Config) Set(key string, v interface{}) error {
 		return err
 	}
 
-	if key == KeyNamespaces {
+	switch key {
+	case KeyNamespaces:
 		k.resetNamespaceManager()
+	case KeyDSN, KeyReadReplicaDSNs:
+		k.notifyDatabaseChange()
 	}
 	return nil
 }
 
+// SetDatabaseReloader sets the function that is called when the configuration
+// changed, so that it can reconnect if the data source names changed.
+func (k *Config) SetDatabaseReloader(reload func()) {
+	k.nmLock.Lock()
+	defer k.nmLock.Unlock()
+
+	k.reloadDatabase = reload
+}
+
+func (k *Config) notifyDatabaseChange() {
+	k.nmLock.Lock()
+	reload := k.reloadDatabase
+	k.nmLock.Unlock()
+
+	if reload != nil {
+		reload()
+	}
+}
+
 func (k *Config) ReadAPIListenOn() string {
 	return fmt.Sprintf(
 		"%s:%d",
@@ -161,6 +294,116 @@ func (k *Config) MaxReadDepth() int {
 	return k.p.Int(KeyLimitMaxReadDepth)
 }
 
+// PageSize returns the page size for a request that asked for the given one.
+// Requests without a page size get the default page size, and larger page
+// sizes are reduced to the maximum page size.
+func (k *Config) PageSize(requested int) int {
+	max := k.p.IntF(KeyLimitMaxPageSize, 1000)
+	if requested <= 0 {
+		requested = k.p.IntF(KeyLimitDefaultPageSize, 100)
+	}
+	if requested > max {
+		return max
+	}
+	return requested
+}
+
+// UUIDMappingCacheSize returns the maximum number of UUID mappings that are
+// cached in each direction, or 0 if they are not cached.
+func (k *Config) UUIDMappingCacheSize() int {
+	return k.p.IntF(KeyUUIDMappingCacheSize, 10000)
+}
+
+// MaxExpandNodes returns the maximum number of nodes of an expanded subject
+// set tree, or 0 if there is no limit.
+func (k *Config) MaxExpandNodes() int {
+	return k.p.IntF(KeyLimitMaxExpandNodes, 0)
+}
+
+// MaxRequestBodySize returns the maximum size of request bodies in bytes, or 0
+// if there is no limit.
+func (k *Config) MaxRequestBodySize() int64 {
+	return int64(k.p.IntF(KeyLimitMaxRequestBodySize, 0))
+}
+
+// RateLimit is the rate of requests a client may send to some endpoints.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+const (
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyAPIKey = "api_key"
+)
+
+// RateLimit returns the rate limit of the endpoints ("check" or "write"), and
+// whether they are rate limited at all.
+func (k *Config) RateLimit(endpoints string) (RateLimit, bool) {
+	rps := k.p.Float64F("rate_limit."+endpoints+".requests_per_second", 0)
+	if rps <= 0 {
+		return RateLimit{}, false
+	}
+	return RateLimit{
+		RequestsPerSecond: rps,
+		Burst:             k.p.IntF("rate_limit."+endpoints+".burst", int(math.Ceil(rps))),
+	}, true
+}
+
+// RateLimitKey returns how rate limited clients are told apart, either by
+// their IP address or, if they are authenticated, by their subject.
+func (k *Config) RateLimitKey() string {
+	return k.p.StringF(KeyRateLimitKey, RateLimitKeyIP)
+}
+
+func (k *Config) RateLimitTrustForwardedFor() bool {
+	return k.p.BoolF(KeyRateLimitTrustForwardedFor, false)
+}
+
+// RateLimitTrustedProxies returns the networks of the proxies whose
+// X-Forwarded-For entries are trusted. Invalid entries are reported by the
+// configuration validation and skipped here.
+func (k *Config) RateLimitTrustedProxies() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range k.p.StringsF(KeyRateLimitTrustedProxies, nil) {
+		if n, err := ParseTrustedProxy(s); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// ParseTrustedProxy parses a trusted proxy, which is either a CIDR network or
+// a single IP address.
+func ParseTrustedProxy(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.Errorf("%q is neither an IP address nor a CIDR network", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, errors.WithStack(err)
+}
+
+// MetaPermissionsNamespace returns the namespace that controls which clients
+// may read and write in which namespaces, or an empty string if all
+// clients may access all namespaces.
+func (k *Config) MetaPermissionsNamespace() string {
+	return k.p.StringF(KeyMetaPermissionsNamespace, "")
+}
+
+// MetaPermissionsSuperusers returns the IDs of the clients that may access all
+// namespaces regardless of the meta-permissions.
+func (k *Config) MetaPermissionsSuperusers() []string {
+	return k.p.StringsF(KeyMetaPermissionsSuperusers, []string{})
+}
+
 func (k *Config) WriteAPIListenOn() string {
 	return fmt.Sprintf(
 		"%s:%d",
@@ -169,11 +412,68 @@ func (k *Config) WriteAPIListenOn() string {
 	)
 }
 
+// AdminAPIEnabled returns whether the schema and operational endpoints are
+// served on their own admin API instead of the write API.
+func (k *Config) AdminAPIEnabled() bool {
+	return k.p.BoolF(KeyAdminAPIEnabled, false)
+}
+
+// AdminAPIDebugEnabled returns whether the profiling and runtime debug
+// endpoints are served on the admin API.
+func (k *Config) AdminAPIDebugEnabled() bool {
+	return k.p.BoolF(KeyAdminAPIDebug, false)
+}
+
+func (k *Config) AdminAPIListenOn() string {
+	return fmt.Sprintf(
+		"%s:%d",
+		k.p.StringF(KeyAdminAPIHost, ""),
+		k.p.IntF(KeyAdminAPIPort, 4469),
+	)
+}
+
+// GRPCReflection returns whether the gRPC servers offer server reflection.
+func (k *Config) GRPCReflection() bool {
+	return k.p.BoolF(KeyGRPCReflection, true)
+}
+
+// GRPCInterceptorPlugin enables a gRPC interceptor plugin registered with
+// ketoctx.RegisterGRPCInterceptorPlugin.
+type GRPCInterceptorPlugin struct {
+	Name string `json:"name"`
+	// APIs are the APIs the plugin intercepts, both read and write if empty.
+	APIs   []string        `json:"apis"`
+	Config json.RawMessage `json:"config"`
+}
+
+// GRPCInterceptorPlugins returns the enabled gRPC interceptor plugins in the
+// order they are called.
+func (k *Config) GRPCInterceptorPlugins() ([]GRPCInterceptorPlugin, error) {
+	raw := k.p.Get(KeyGRPCInterceptors)
+	if raw == nil {
+		return nil, nil
+	}
+	enc, err := json.Marshal(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var plugins []GRPCInterceptorPlugin
+	if err := json.Unmarshal(enc, &plugins); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range plugins {
+		if len(plugins[i].APIs) == 0 {
+			plugins[i].APIs = []string{"read", "write"}
+		}
+	}
+	return plugins, nil
+}
+
 func (k *Config) CORS(iface string) (cors.Options, bool) {
 	switch iface {
-	case "read", "write", "metrics":
+	case "read", "write", "admin", "metrics":
 	default:
-		panic("expected interface 'read' or 'write', but got unknown interface " + iface)
+		panic("expected interface 'read', 'write', 'admin', or 'metrics', but got unknown interface " + iface)
 	}
 
 	return k.p.CORS("serve."+iface, cors.Options{
@@ -184,12 +484,223 @@ func (k *Config) CORS(iface string) (cors.Options, bool) {
 	})
 }
 
+// Socket is a unix socket an API listens on in addition to its TCP port.
+type Socket struct {
+	Path string
+	configx.UnixPermission
+}
+
+// Socket returns the unix socket the API ("read" or "write") listens on, or
+// nil if it only listens on its TCP port.
+func (k *Config) Socket(iface string) *Socket {
+	prefix := "serve." + iface + ".socket."
+	path := k.p.String(prefix + "path")
+	if path == "" {
+		return nil
+	}
+	return &Socket{
+		Path: path,
+		UnixPermission: configx.UnixPermission{
+			Owner: k.p.String(prefix + "owner"),
+			Group: k.p.String(prefix + "group"),
+			Mode:  os.FileMode(k.p.IntF(prefix+"mode", 0755)),
+		},
+	}
+}
+
 func (k *Config) DSN() string {
 	dsn := k.p.String(KeyDSN)
 	if dsn == "memory" {
 		return DSNMemory
 	}
-	return dsn
+	return k.secret(KeyDSN, dsn)
+}
+
+// MemorySnapshotPath returns the file that the in-memory database is
+// snapshotted to and restored from, or an empty string if it is not
+// snapshotted.
+func (k *Config) MemorySnapshotPath() string {
+	return k.p.String(KeyMemorySnapshotPath)
+}
+
+// MemorySnapshotInterval returns how often the in-memory database is
+// snapshotted.
+func (k *Config) MemorySnapshotInterval() time.Duration {
+	return k.p.DurationF(KeyMemorySnapshotInterval, 5*time.Minute)
+}
+
+// ReadReplicaDSNs returns the data source names of the read replicas of the
+// primary database.
+func (k *Config) ReadReplicaDSNs() []string {
+	return k.secretList(KeyReadReplicaDSNs, k.p.StringsF(KeyReadReplicaDSNs, []string{}))
+}
+
+// DatabasePool is the configuration of the connection pools of the
+// databases. Zero values keep the defaults of the connection library.
+type DatabasePool struct {
+	MaxOpenConnections    int
+	MaxIdleConnections    int
+	MaxConnectionLifetime time.Duration
+	MaxConnectionIdleTime time.Duration
+	StatementTimeout      time.Duration
+}
+
+// DatabasePool returns the configuration of the connection pools of all
+// databases.
+func (k *Config) DatabasePool() DatabasePool {
+	return DatabasePool{
+		MaxOpenConnections:    k.p.IntF(KeyDatabasePoolMaxOpenConnections, 0),
+		MaxIdleConnections:    k.p.IntF(KeyDatabasePoolMaxIdleConnections, 0),
+		MaxConnectionLifetime: k.p.DurationF(KeyDatabasePoolMaxConnectionLifetime, time.Hour),
+		MaxConnectionIdleTime: k.p.DurationF(KeyDatabasePoolMaxConnectionIdleTime, 10*time.Minute),
+		StatementTimeout:      k.p.DurationF(KeyDatabasePoolStatementTimeout, 0),
+	}
+}
+
+// DatabaseConnectTimeout returns how long connecting to a database is retried.
+func (k *Config) DatabaseConnectTimeout() time.Duration {
+	return k.p.DurationF(KeyDatabasePoolConnectTimeout, 5*time.Minute)
+}
+
+// UUIDMappingGC is the configuration of the garbage collection of UUID
+// mappings that no relation tuple references anymore.
+type UUIDMappingGC struct {
+	// Interval is 0 if the orphaned mappings are only collected by
+	// `keto cleanup`.
+	Interval    time.Duration
+	GracePeriod time.Duration
+	BatchSize   int
+}
+
+func (k *Config) UUIDMappingGC() UUIDMappingGC {
+	return UUIDMappingGC{
+		Interval:    k.p.DurationF(KeyUUIDMappingGCInterval, 0),
+		GracePeriod: k.p.DurationF(KeyUUIDMappingGCGracePeriod, 24*time.Hour),
+		BatchSize:   k.p.IntF(KeyUUIDMappingGCBatchSize, 1000),
+	}
+}
+
+// TupleCountMetrics is the configuration of the metrics of the number of
+// relation tuples per namespace.
+type TupleCountMetrics struct {
+	// Interval is 0 if the relation tuples are not counted.
+	Interval time.Duration
+	// PerRelation also counts the relation tuples of every relation that is
+	// defined in the Ory Permission Language.
+	PerRelation bool
+	// Estimate uses the row estimate of the query planner where available.
+	Estimate bool
+}
+
+func (k *Config) TupleCountMetrics() TupleCountMetrics {
+	return TupleCountMetrics{
+		Interval:    k.p.DurationF(KeyTupleCountMetricsInterval, 0),
+		PerRelation: k.p.BoolF(KeyTupleCountMetricsPerRelation, false),
+		Estimate:    k.p.BoolF(KeyTupleCountMetricsEstimate, false),
+	}
+}
+
+// NamespaceStorage is a database that stores the relation tuples of some
+// namespaces instead of the primary database.
+type NamespaceStorage struct {
+	DSN        string   `json:"dsn"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// NamespaceStorage returns the databases that store the relation tuples of
+// specific namespaces. All other namespaces are stored in the primary
+// database.
+func (k *Config) NamespaceStorage() ([]NamespaceStorage, error) {
+	raw := k.p.Get(KeyNamespaceStorage)
+	if raw == nil {
+		return nil, nil
+	}
+	enc, err := json.Marshal(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var storages []NamespaceStorage
+	if err := json.Unmarshal(enc, &storages); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	mapped := make(map[string]bool)
+	for i := range storages {
+		for _, n := range storages[i].Namespaces {
+			if mapped[n] {
+				return nil, errors.Errorf("namespace %q is mapped to more than one database in %s", n, KeyNamespaceStorage)
+			}
+			mapped[n] = true
+		}
+		storages[i].DSN = k.secret(KeyNamespaceStorage, storages[i].DSN)
+	}
+	return storages, nil
+}
+
+// ShardingDSNs returns the data source names of the databases the relation
+// tuples are distributed over, or nil if they are stored in the primary
+// database.
+func (k *Config) ShardingDSNs() []string {
+	return k.secretList(KeyShardingDSNs, k.p.StringsF(KeyShardingDSNs, []string{}))
+}
+
+// ShardingKey returns the part of the relation tuples that determines their
+// shard, either "object" or "namespace".
+func (k *Config) ShardingKey() string {
+	return k.p.StringF(KeyShardingKey, "object")
+}
+
+// RedisURL returns the URL of the Redis server that stores the relation tuples
+// and UUID mappings instead of the database, or an empty string if they are
+// stored in the database.
+func (k *Config) RedisURL() string {
+	return k.secret(KeyRedisURL, k.p.String(KeyRedisURL))
+}
+
+// RedisKeyPrefix returns the prefix of all keys in Redis.
+func (k *Config) RedisKeyPrefix() string {
+	return k.p.StringF(KeyRedisKeyPrefix, "keto")
+}
+
+// DynamoDBTable returns the DynamoDB table that stores the relation tuples and
+// UUID mappings instead of the database, or an empty string if they are stored
+// in the database.
+func (k *Config) DynamoDBTable() string {
+	return k.p.String(KeyDynamoDBTable)
+}
+
+// DynamoDBRegion returns the AWS region of the DynamoDB table. It defaults to
+// the region of the standard AWS environment variables.
+func (k *Config) DynamoDBRegion() string {
+	if r := k.p.String(KeyDynamoDBRegion); r != "" {
+		return r
+	}
+	return firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+}
+
+// DynamoDBEndpoint returns the endpoint of a DynamoDB compatible database, or
+// an empty string for the endpoint of the region.
+func (k *Config) DynamoDBEndpoint() string {
+	return k.p.String(KeyDynamoDBEndpoint)
+}
+
+// SpannerDatabase returns the Cloud Spanner database that stores the relation
+// tuples and UUID mappings instead of the database, or an empty string if they
+// are stored in the database.
+func (k *Config) SpannerDatabase() string {
+	return k.p.String(KeySpannerDatabase)
+}
+
+// SpannerEndpoint returns the endpoint of the Cloud Spanner REST API, or an
+// empty string for the default endpoint.
+func (k *Config) SpannerEndpoint() string {
+	return k.p.String(KeySpannerEndpoint)
+}
+
+// DefaultReadConsistency returns the consistency of read API requests that do
+// not request one.
+func (k *Config) DefaultReadConsistency() string {
+	return k.p.StringF(KeyReadReplicaDefaultConsistency, "eventual")
 }
 
 func (k *Config) TracingServiceName() string {
@@ -204,7 +715,29 @@ func (k *Config) TracingConfig() *otelx.Config {
 	return k.p.TracingConfig("Ory Keto")
 }
 
+// NamespaceManager returns the manager of all namespaces, including the
+// namespaces managed through the namespace administration API. Namespaces
+// that are being renamed can also be found by their old name.
 func (k *Config) NamespaceManager() (namespace.Manager, error) {
+	nm, err := k.ConfiguredNamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+
+	k.nmLock.Lock()
+	defer k.nmLock.Unlock()
+	if k.managed != nil {
+		nm = &managedNamespaceManager{Manager: nm, managed: k.managed}
+	}
+	if k.aliases != nil {
+		nm = &aliasNamespaceManager{Manager: nm, aliases: k.aliases}
+	}
+	return nm, nil
+}
+
+// ConfiguredNamespaceManager returns the manager of the namespaces from the
+// configuration only.
+func (k *Config) ConfiguredNamespaceManager() (namespace.Manager, error) {
 	k.nmLock.Lock()
 	defer k.nmLock.Unlock()
 
@@ -220,12 +753,26 @@ func (k *Config) NamespaceManager() (namespace.Manager, error) {
 		switch nTyped := nn.(type) {
 		case string:
 			var err error
-			k.nm, err = NewNamespaceWatcher(ctx, k.l, nTyped)
+			if isRemoteLocation(nTyped) {
+				k.nm, err = newRemoteNamespaceWatcher(ctx, k.l, nTyped, nTyped, false, k.NamespaceSourcesPollInterval())
+			} else {
+				k.nm, err = NewNamespaceWatcher(ctx, k.l, nTyped)
+			}
 			if err != nil {
 				return nil, err
 			}
 		case []*namespace.Namespace:
 			k.nm = NewMemoryNamespaceManager(nTyped...)
+		case oplLocation:
+			var err error
+			if isRemoteLocation(string(nTyped)) {
+				k.nm, err = newRemoteNamespaceWatcher(ctx, k.l, string(nTyped), nTyped, true, k.NamespaceSourcesPollInterval())
+			} else {
+				k.nm, err = newOPLConfigWatcher(ctx, k.l, string(nTyped))
+			}
+			if err != nil {
+				return nil, err
+			}
 		default:
 			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("got unexpected namespaces type %T", nn))
 		}
@@ -234,11 +781,13 @@ func (k *Config) NamespaceManager() (namespace.Manager, error) {
 	return k.nm, nil
 }
 
-// getNamespaces returns string or []*namespace.Namespace
+// getNamespaces returns string, oplLocation, or []*namespace.Namespace
 func (k *Config) getNamespaces() (interface{}, error) {
 	switch nTyped := k.p.GetF(KeyNamespaces, "file://./keto_namespaces").(type) {
 	case string:
 		return nTyped, nil
+	case map[string]interface{}:
+		return oplLocation(k.p.String(KeyNamespacesLocation)), nil
 	case []*namespace.Namespace:
 		return nTyped, nil
 	case []interface{}:
@@ -252,6 +801,11 @@ func (k *Config) getNamespaces() (interface{}, error) {
 		if err := json.Unmarshal(nEnc, &nn); err != nil {
 			return nil, errors.WithStack(err)
 		}
+		for _, n := range nn {
+			if err := loadZanzibarRelations(n); err != nil {
+				return nil, err
+			}
+		}
 
 		return nn, nil
 	default:
@@ -266,3 +820,133 @@ func (k *Config) MetricsListenOn() string {
 		k.p.IntF(KeyMetricsPort, 4468),
 	)
 }
+
+// OTLPMetricsEndpoint returns the URL the metrics are pushed to with the
+// OpenTelemetry protocol, or an empty string if they are not pushed.
+func (k *Config) OTLPMetricsEndpoint() string {
+	return k.p.String(KeyOTLPMetricsEndpoint)
+}
+
+// OTLPMetricsHeaders returns the headers sent with the metrics, e.g. for
+// authentication.
+func (k *Config) OTLPMetricsHeaders() (map[string]string, error) {
+	var headers map[string]string
+	if err := k.decode(KeyOTLPMetricsHeaders, &headers); err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		headers[name] = k.secret(KeyOTLPMetricsHeaders, value)
+	}
+	return headers, nil
+}
+
+func (k *Config) OTLPMetricsInterval() time.Duration {
+	return k.p.DurationF(KeyOTLPMetricsInterval, time.Minute)
+}
+
+func (k *Config) CDCEnabled() bool {
+	return k.p.BoolF(KeyCDCEnabled, false)
+}
+
+func (k *Config) CDCSink() CDCSinkConfig {
+	return CDCSinkConfig{
+		Type:  k.p.String(KeyCDCSinkType),
+		URL:   k.secret(KeyCDCSinkURL, k.p.String(KeyCDCSinkURL)),
+		Topic: k.p.StringF(KeyCDCSinkTopic, "keto.relation_tuples"),
+	}
+}
+
+func (k *Config) CDCPollInterval() time.Duration {
+	return k.p.DurationF(KeyCDCPollInterval, time.Second)
+}
+
+func (k *Config) CDCBatchSize() int {
+	return k.p.IntF(KeyCDCBatchSize, 100)
+}
+
+func (k *Config) HistoryEnabled() bool {
+	return k.p.BoolF(KeyHistoryEnabled, false)
+}
+
+// HistoryWatchInterval returns how often the history is polled for new changes
+// while clients watch the relation tuples.
+func (k *Config) HistoryWatchInterval() time.Duration {
+	return k.p.DurationF(KeyHistoryWatchInterval, time.Second)
+}
+
+// GraphQLEnabled returns whether the GraphQL endpoint of the read API is
+// served.
+func (k *Config) GraphQLEnabled() bool {
+	return k.p.BoolF(KeyGraphQLEnabled, false)
+}
+
+// GraphQLMaxDepth returns how deeply the selections of a GraphQL query may be
+// nested.
+func (k *Config) GraphQLMaxDepth() int {
+	return k.p.IntF(KeyGraphQLMaxDepth, 16)
+}
+
+// GraphQLMaxBatchSize returns how many relation tuples can be checked at once
+// with the checks field of the GraphQL endpoint.
+func (k *Config) GraphQLMaxBatchSize() int {
+	return k.p.IntF(KeyGraphQLMaxBatchSize, 100)
+}
+
+// RequestIDSQLComments returns whether SQL queries are prefixed with a comment
+// carrying the request ID.
+func (k *Config) RequestIDSQLComments() bool {
+	return k.p.BoolF(KeyRequestIDSQLComments, false)
+}
+
+// TenancyEnabled returns whether every request to the APIs has to belong to a
+// tenant, whose data is isolated from the other tenants.
+func (k *Config) TenancyEnabled() bool {
+	return k.p.BoolF(KeyTenancyEnabled, false)
+}
+
+// TenancyHeader returns the HTTP header that carries the tenant of a request.
+// The gRPC metadata key is its lower case version.
+func (k *Config) TenancyHeader() string {
+	return k.p.StringF(KeyTenancyHeader, "X-Keto-Tenant")
+}
+
+// TenancyAllowed returns whether requests can belong to the tenant, which has
+// to be listed or match the pattern. Without both, all tenants are allowed.
+func (k *Config) TenancyAllowed(tenant string) bool {
+	tenants, pattern := k.p.Strings(KeyTenancyTenants), k.p.String(KeyTenancyPattern)
+	if len(tenants) == 0 && pattern == "" {
+		return true
+	}
+	if slices.Contains(tenants, tenant) {
+		return true
+	}
+	if pattern == "" {
+		return false
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	return err == nil && re.MatchString(tenant)
+}
+
+func (k *Config) NamespaceAPIEnabled() bool {
+	return k.p.BoolF(KeyNamespaceAPIEnabled, false)
+}
+
+func (k *Config) NamespaceAPIKeys() []string {
+	return k.secretList(KeyNamespaceAPIKeys, k.p.StringsF(KeyNamespaceAPIKeys, []string{}))
+}
+
+func (k *Config) NamespaceAPIRefreshInterval() time.Duration {
+	return k.p.DurationF(KeyNamespaceAPIRefreshInterval, 10*time.Second)
+}
+
+// NamespaceSourcesPollInterval returns how often namespaces from s3://, gs://,
+// https://, and k8s:// locations are checked for changes.
+func (k *Config) NamespaceSourcesPollInterval() time.Duration {
+	return k.p.DurationF(KeyNamespaceSourcesPollInterval, 30*time.Second)
+}
+
+// StrictMode returns whether relation tuples have to conform to the Ory
+// Permission Language schema to be written.
+func (k *Config) StrictMode() bool {
+	return k.p.BoolF(KeyNamespacesExperimentalStrictMode, false)
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ory/keto/embedx"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
 )
 
 func TestKoanfNamespaceManager(t *testing.T) {
@@ -89,6 +93,26 @@ func TestKoanfNamespaceManager(t *testing.T) {
 		)
 	})
 
+	t.Run("case=converts inline Zanzibar namespace configs", func(t *testing.T) {
+		_, p := setup(t)
+
+		var value []interface{}
+		require.NoError(t, json.Unmarshal([]byte(`[{"id": 0, "name": "doc", "config": {"relation": [
+  {"name": "owner"},
+  {"name": "viewer", "userset_rewrite": {"union": {"child": [{"_this": {}}, {"computed_userset": {"relation": "owner"}}]}}}
+]}}]`), &value))
+		require.NoError(t, p.Set(KeyNamespaces, value))
+
+		nm, err := p.NamespaceManager()
+		require.NoError(t, err)
+		n, err := nm.GetNamespaceByName(context.Background(), "doc")
+		require.NoError(t, err)
+		assert.Equal(t, []ast.Relation{
+			{Name: "owner"},
+			{Name: "viewer", SubjectSetRewrite: &ast.SubjectSetRewrite{Children: ast.Children{&ast.ComputedSubjectSet{Relation: "owner"}}}},
+		}, n.Relations)
+	})
+
 	t.Run("case=reloads namespace manager when namespaces are updated using Set()", func(t *testing.T) {
 		_, p := setup(t)
 
@@ -117,6 +141,105 @@ func TestKoanfNamespaceManager(t *testing.T) {
 		assert.True(t, ok)
 	})
 
+	t.Run("case=creates OPL watcher when namespaces has a location", func(t *testing.T) {
+		_, p := setup(t)
+
+		fn := filepath.Join(t.TempDir(), "namespaces.keto.ts")
+		require.NoError(t, os.WriteFile(fn, []byte(`
+class User implements Namespace {}
+class Document implements Namespace {
+  related: {
+    viewers: User[]
+  }
+}`), 0600))
+
+		require.NoError(t, p.Set(KeyNamespaces, map[string]interface{}{"location": "file://" + fn}))
+
+		nm, err := p.NamespaceManager()
+		require.NoError(t, err)
+		_, ok := nm.(*oplConfigWatcher)
+		require.True(t, ok)
+
+		n, err := nm.GetNamespaceByName(context.Background(), "Document")
+		require.NoError(t, err)
+		require.Len(t, n.Relations, 1)
+		assert.Equal(t, "viewers", n.Relations[0].Name)
+
+		require.NoError(t, p.Set(KeyNamespacesExperimentalStrictMode, true))
+		assert.True(t, p.StrictMode())
+		sameNM, err := p.NamespaceManager()
+		require.NoError(t, err)
+		assert.Same(t, nm, sameNM, "toggling strict mode must not reload the namespaces")
+	})
+
+	t.Run("case=OPL watcher resolves imports", func(t *testing.T) {
+		_, p := setup(t)
+
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "users.ts"), []byte(`class User implements Namespace {}`), 0600))
+		fn := filepath.Join(dir, "namespaces.keto.ts")
+		require.NoError(t, os.WriteFile(fn, []byte(`
+import { User } from "./users"
+
+class Document implements Namespace {
+  related: {
+    viewers: User[]
+  }
+}`), 0600))
+
+		require.NoError(t, p.Set(KeyNamespaces, map[string]interface{}{"location": "file://" + fn}))
+
+		nm, err := p.NamespaceManager()
+		require.NoError(t, err)
+		nn, err := nm.Namespaces(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, nn, 2)
+	})
+
+	t.Run("case=OPL watcher merges and reloads files matching a glob", func(t *testing.T) {
+		_, p := setup(t)
+
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "users.ts"), []byte(`class User implements Namespace {}`), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "documents.ts"), []byte(`
+class Document implements Namespace {
+  related: {
+    viewers: User[]
+  }
+}`), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a namespace"), 0600))
+
+		require.NoError(t, p.Set(KeyNamespaces, map[string]interface{}{"location": "file://" + filepath.Join(dir, "*.ts")}))
+
+		nm, err := p.NamespaceManager()
+		require.NoError(t, err)
+		names := func() []string {
+			nn, err := nm.Namespaces(context.Background())
+			require.NoError(t, err)
+			names := make([]string, len(nn))
+			for i, n := range nn {
+				names[i] = n.Name
+			}
+			return names
+		}
+		assert.ElementsMatch(t, []string{"User", "Document"}, names())
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "groups.ts"), []byte(`class Group implements Namespace {}`), 0600))
+		assert.Eventually(t, func() bool {
+			return len(names()) == 3
+		}, 5*time.Second, 10*time.Millisecond)
+
+		t.Run("case=directory", func(t *testing.T) {
+			_, p := setup(t)
+			require.NoError(t, p.Set(KeyNamespaces, map[string]interface{}{"location": "file://" + dir}))
+			nm, err := p.NamespaceManager()
+			require.NoError(t, err)
+			nn, err := nm.Namespaces(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, nn, 3)
+		})
+	})
+
 	t.Run("case=uses passed configx provider", func(t *testing.T) {
 		ctx := context.Background()
 		cp, err := configx.New(ctx, embedx.ConfigSchema, configx.WithValue(KeyDSN, "foobar"))
@@ -0,0 +1,34 @@
+package config
+
+import "math"
+
+const KeyQuotas = "quotas"
+
+// Quota limits the relation tuples, writes, and checks of a tenant. Every
+// tenant has its own quota, unless the quota is restricted to one tenant.
+// Without a namespace, the quota covers all namespaces together. Zero values
+// mean no limit.
+type Quota struct {
+	Tenant            string    `json:"tenant"`
+	Namespace         string    `json:"namespace"`
+	MaxRelationTuples int       `json:"max_relation_tuples"`
+	Writes            RateLimit `json:"writes"`
+	Checks            RateLimit `json:"checks"`
+}
+
+// Quotas returns the configured quotas, with the default burst of their rate
+// limits set.
+func (k *Config) Quotas() ([]Quota, error) {
+	var quotas []Quota
+	if err := k.decode(KeyQuotas, &quotas); err != nil {
+		return nil, err
+	}
+	for i := range quotas {
+		for _, l := range []*RateLimit{&quotas[i].Writes, &quotas[i].Checks} {
+			if l.Burst == 0 {
+				l.Burst = int(math.Ceil(l.RequestsPerSecond))
+			}
+		}
+	}
+	return quotas, nil
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// secrets caches the resolved references to secrets, so that they are
+	// fetched only once per refresh interval.
+	secrets struct {
+		sync.Mutex
+		resolved map[string]*resolvedSecret
+	}
+	resolvedSecret struct {
+		value      string
+		resolvedAt time.Time
+	}
+)
+
+func (k *Config) SecretsRefreshInterval() time.Duration {
+	return k.p.DurationF(KeySecretsRefreshInterval, 5*time.Minute)
+}
+
+// SecretsCipher returns the secrets that encrypt the identifiers at rest. The
+// first secret encrypts, all secrets decrypt. Encryption is disabled if there
+// are none.
+func (k *Config) SecretsCipher() []string {
+	return k.secretList(KeySecretsCipher, k.p.Strings(KeySecretsCipher))
+}
+
+// SecretsMappingID returns the secret the UUIDs of encrypted identifiers are
+// derived from. Unlike the encryption secrets, it is never rotated.
+func (k *Config) SecretsMappingID() string {
+	return k.secret(KeySecretsMappingID, k.p.String(KeySecretsMappingID))
+}
+
+// secret returns the secret the value of the key references, or the value
+// itself if it is not a reference. A secret is resolved again after the
+// refresh interval, so that rotated secrets are picked up. If that fails, the
+// last resolved value is kept.
+func (k *Config) secret(key, value string) string {
+	if _, ok := parseSecretReference(value); !ok {
+		return value
+	}
+
+	k.secrets.Lock()
+	cached, ok := k.secrets.resolved[value]
+	k.secrets.Unlock()
+	if ok && time.Since(cached.resolvedAt) < k.SecretsRefreshInterval() {
+		return cached.value
+	}
+
+	resolved, err := resolveSecret(k.ctx, value)
+	if err != nil {
+		if ok {
+			k.l.WithError(err).WithField("key", key).Warn("Unable to refresh secret, keeping the last resolved value.")
+			resolved = cached.value
+		} else {
+			k.l.WithError(err).WithField("key", key).Error("Unable to resolve secret.")
+			return ""
+		}
+	}
+
+	k.secrets.Lock()
+	defer k.secrets.Unlock()
+	if k.secrets.resolved == nil {
+		k.secrets.resolved = make(map[string]*resolvedSecret)
+	}
+	k.secrets.resolved[value] = &resolvedSecret{value: resolved, resolvedAt: time.Now()}
+	return resolved
+}
+
+// secretList resolves all values like secret. Values that could not be
+// resolved are omitted.
+func (k *Config) secretList(key string, values []string) []string {
+	resolved := make([]string, 0, len(values))
+	for _, v := range values {
+		if s := k.secret(key, v); s != "" {
+			resolved = append(resolved, s)
+		}
+	}
+	return resolved
+}
+
+const (
+	secretSchemeFile              = "file"
+	secretSchemeEnvFile           = "env-file"
+	secretSchemeVault             = "vault"
+	secretSchemeAWSSecretsManager = "aws-secretsmanager"
+)
+
+// secretReference references a secret that is stored outside of the
+// configuration file:
+//
+//	file:///run/secrets/dsn               the content of the file
+//	env-file:///etc/keto/secrets.env#DSN  the variable DSN of the env-file
+//	vault://secret/data/keto#dsn          the field dsn of the HashiCorp Vault secret
+//	aws-secretsmanager://keto/prod#dsn    the key dsn of the AWS Secrets Manager secret
+//
+// The key selects a single value of a secret that holds several. It is
+// optional for Vault and AWS Secrets Manager secrets with only one value.
+type secretReference struct {
+	scheme, location, key string
+}
+
+func parseSecretReference(value string) (*secretReference, bool) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok {
+		return nil, false
+	}
+	switch scheme {
+	case secretSchemeFile, secretSchemeEnvFile, secretSchemeVault, secretSchemeAWSSecretsManager:
+	default:
+		return nil, false
+	}
+	r := &secretReference{scheme: scheme, location: rest}
+	if i := strings.LastIndex(rest, "#"); i >= 0 {
+		r.location, r.key = rest[:i], rest[i+1:]
+	}
+	return r, true
+}
+
+// resolveSecret returns the secret the value references. Values that are not
+// references are returned as they are.
+func resolveSecret(ctx context.Context, value string) (string, error) {
+	r, ok := parseSecretReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	var (
+		secret string
+		err    error
+	)
+	switch r.scheme {
+	case secretSchemeFile:
+		secret, err = resolveFileSecret(r)
+	case secretSchemeEnvFile:
+		secret, err = resolveEnvFileSecret(r)
+	case secretSchemeVault:
+		secret, err = resolveVaultSecret(ctx, r)
+	case secretSchemeAWSSecretsManager:
+		secret, err = resolveAWSSecret(ctx, r)
+	}
+	if err != nil {
+		return "", errors.WithMessagef(err, "could not resolve secret %s://%s", r.scheme, r.location)
+	}
+	return secret, nil
+}
+
+func resolveFileSecret(r *secretReference) (string, error) {
+	if r.key != "" {
+		return "", errors.New("file references do not support keys, use env-file:// instead")
+	}
+	raw, err := os.ReadFile(r.location)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// secretStatusError returns the error for an unexpected response of a secret
+// store. Unlike unexpectedStatus, it is not a herodot error, whose reason would
+// not be logged.
+func secretStatusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// selectSecretKey returns the value of the key, or the only value if no key is
+// given.
+func selectSecretKey(values map[string]string, key string) (string, error) {
+	if key == "" {
+		if len(values) != 1 {
+			return "", errors.Errorf("the secret has %d values, select one with a #key fragment", len(values))
+		}
+		for _, v := range values {
+			return v, nil
+		}
+	}
+	v, ok := values[key]
+	if !ok {
+		return "", errors.Errorf("the secret has no key %q", key)
+	}
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x/awsx"
+)
+
+// resolveAWSSecret reads a secret from AWS Secrets Manager. The credentials
+// and region are read from the standard AWS environment variables, but the
+// region of a secret ARN takes precedence.
+//
+// The secret string is returned as it is, unless a key is given. Then it is
+// parsed as a JSON object, as written by the AWS console for key/value
+// secrets.
+func resolveAWSSecret(ctx context.Context, r *secretReference) (string, error) {
+	creds := awsx.CredentialsFromEnv()
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY have to be set")
+	}
+	region := firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(r.location, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", errors.New("AWS_REGION is not set")
+	}
+
+	endpoint := firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER"), os.Getenv("AWS_ENDPOINT_URL"),
+		fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region))
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": r.location})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awsx.SignRequest(req, creds, region, "secretsmanager", awsx.PayloadHash(body), time.Now())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", secretStatusError(resp)
+	}
+
+	var res struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if res.SecretString == nil {
+		return "", errors.New("only secret strings are supported, but the secret is binary")
+	}
+	if r.key == "" {
+		return *res.SecretString, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(*res.SecretString), &values); err != nil {
+		return "", errors.Errorf("the secret is not a JSON object, so the key %q can not be selected", r.key)
+	}
+	strValues := make(map[string]string, len(values))
+	for k, v := range values {
+		strValues[k] = fmt.Sprint(v)
+	}
+	return selectSecretKey(strValues, r.key)
+}
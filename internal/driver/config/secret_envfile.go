@@ -0,0 +1,58 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// resolveEnvFileSecret reads the variable from a file of KEY=VALUE lines, as used by
+// Docker and systemd.
+func resolveEnvFileSecret(r *secretReference) (string, error) {
+	if r.key == "" {
+		return "", errors.New("env-file references need the #VARIABLE to read")
+	}
+	f, err := os.Open(r.location)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	values, err := parseEnvFile(f)
+	if err != nil {
+		return "", err
+	}
+	return selectSecretKey(values, r.key)
+}
+
+func parseEnvFile(f *os.File) (map[string]string, error) {
+	values := make(map[string]string)
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, errors.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, errors.Errorf("line %d: invalid quoted value", n)
+			}
+			value = unquoted
+		case len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, errors.WithStack(s.Err())
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// resolveVaultSecret reads a secret from HashiCorp Vault. The address and token are
+// read from the environment variables VAULT_ADDR and VAULT_TOKEN, and
+// optionally VAULT_NAMESPACE, like the Vault CLI does. Both the KV version 1
+// and 2 secrets engines are supported.
+func resolveVaultSecret(ctx context.Context, r *secretReference) (string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", errors.New("VAULT_TOKEN is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(r.location, "/"), nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", secretStatusError(resp)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	data := body.Data
+	// The KV version 2 secrets engine nests the secret in data.data, next to
+	// its metadata.
+	if nested, ok := data["data"]; ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return "", errors.WithStack(err)
+			}
+		}
+	}
+
+	values := make(map[string]string, len(data))
+	for k, raw := range data {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+		values[k] = s
+	}
+	return selectSecretKey(values, r.key)
+}
@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+const (
+	KeySidecarPrimaryURL      = "sidecar.primary.url"
+	KeySidecarPrimaryAdminURL = "sidecar.primary.admin_url"
+	KeySidecarPrimaryAPIKey   = "sidecar.primary.api_key"
+	KeySidecarNamespaces      = "sidecar.namespaces"
+	KeySidecarRetryInterval   = "sidecar.retry_interval"
+)
+
+// Sidecar is the configuration of the sidecar mode, in which the relation
+// tuples are replicated from the write API of a primary cluster.
+type Sidecar struct {
+	PrimaryURL string
+	// PrimaryAdminURL is where the snapshot of the primary is requested. It
+	// is the PrimaryURL if the primary does not serve a separate admin API.
+	PrimaryAdminURL string
+	// APIKey is sent as bearer token to the primary.
+	APIKey string
+	// Namespaces are the replicated namespaces. Without any, all namespaces
+	// that are configured locally are replicated.
+	Namespaces    []string
+	RetryInterval time.Duration
+}
+
+// SidecarEnabled is true if a primary is configured, which makes this instance
+// a read-only replica of it.
+func (k *Config) SidecarEnabled() bool {
+	return k.p.String(KeySidecarPrimaryURL) != ""
+}
+
+func (k *Config) Sidecar() Sidecar {
+	return Sidecar{
+		PrimaryURL:      k.p.String(KeySidecarPrimaryURL),
+		PrimaryAdminURL: k.p.StringF(KeySidecarPrimaryAdminURL, k.p.String(KeySidecarPrimaryURL)),
+		APIKey:          k.secret(KeySidecarPrimaryAPIKey, k.p.String(KeySidecarPrimaryAPIKey)),
+		Namespaces:      k.p.Strings(KeySidecarNamespaces),
+		RetryInterval:   k.p.DurationF(KeySidecarRetryInterval, time.Second),
+	}
+}
@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+const (
+	KeySlowChecksThreshold   = "slow_checks.threshold"
+	KeySlowChecksIncludeTree = "slow_checks.include_tree"
+)
+
+// SlowChecksThreshold returns the duration above which checks are logged. 0
+// disables the slow-check log.
+func (k *Config) SlowChecksThreshold() time.Duration {
+	return k.p.DurationF(KeySlowChecksThreshold, 0)
+}
+
+// SlowChecksIncludeTree returns whether the tree of the check is added to the
+// slow-check log.
+func (k *Config) SlowChecksIncludeTree() bool {
+	return k.p.BoolF(KeySlowChecksIncludeTree, false)
+}
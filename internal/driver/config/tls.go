@@ -0,0 +1,111 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"os"
+
+	"github.com/ory/x/tlsx"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+// TLS returns the TLS configuration of the interface ("read", "write",
+// "admin", or "metrics"), or nil if TLS is disabled. If a client CA is
+// configured, clients have to present a certificate that was issued by it, and
+// that has one of the allowed subject alternative names, if there are any.
+func (k *Config) TLS(iface string) (*tls.Config, error) {
+	prefix := "serve." + iface + ".tls."
+
+	caPath, caBase64 := k.p.String(prefix+"client_auth.ca.path"), k.p.String(prefix+"client_auth.ca.base64")
+	certs, err := tlsx.Certificate(
+		k.p.String(prefix+"cert.base64"), k.p.String(prefix+"key.base64"),
+		k.p.String(prefix+"cert.path"), k.p.String(prefix+"key.path"),
+	)
+	if errors.Is(err, tlsx.ErrNoCertificatesConfigured) {
+		if caPath != "" || caBase64 != "" {
+			return nil, errors.Errorf("%sclient_auth requires the certificate and key of the server to be set", prefix)
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "unable to load the TLS certificate of the %s API", iface)
+	}
+
+	cfg := &tls.Config{
+		Certificates: certs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caPath == "" && caBase64 == "" {
+		return cfg, nil
+	}
+
+	pem, err := readPEM(caPath, caBase64)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unable to load the client CA of the %s API", iface)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("the client CA of the %s API contains no PEM-encoded certificates", iface)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if allowed := k.p.Strings(prefix + "client_auth.allowed_sans"); len(allowed) > 0 {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+				return errors.New("no verified client certificate")
+			}
+			leaf := cs.VerifiedChains[0][0]
+			for _, san := range subjectAltNames(leaf) {
+				if slices.Contains(allowed, san) {
+					return nil
+				}
+			}
+			return errors.Errorf("the client certificate %q has none of the allowed subject alternative names", leaf.Subject.CommonName)
+		}
+	}
+	return cfg, nil
+}
+
+// TLSFiles returns the paths of the certificate files of the interface, which
+// are reloaded when they change.
+func (k *Config) TLSFiles(iface string) []string {
+	prefix := "serve." + iface + ".tls."
+	var files []string
+	for _, key := range []string{"cert.path", "key.path", "client_auth.ca.path"} {
+		if f := k.p.String(prefix + key); f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// readPEM reads PEM-encoded content either from the file at the path or from
+// the base64 string.
+func readPEM(path, b64 string) ([]byte, error) {
+	if path != "" {
+		pem, err := os.ReadFile(path)
+		return pem, errors.WithStack(err)
+	}
+	pem, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to base64 decode")
+	}
+	return pem, nil
+}
+
+// subjectAltNames returns the DNS names, IP addresses, email addresses, and
+// URIs of the certificate.
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.EmailAddresses)+len(cert.URIs))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
@@ -0,0 +1,607 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/dbal"
+	"github.com/ory/x/jsonschemax"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/otelx"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/keto/embedx"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoctx"
+)
+
+// Problem is a problem of a configuration, together with how to fix it.
+type Problem struct {
+	// Key is the configuration key of the problem, e.g. serve.read.port.
+	Key     string
+	Message string
+	Fix     string
+}
+
+func (p *Problem) String() string {
+	s := p.Message
+	if p.Key != "" {
+		s = p.Key + ": " + s
+	}
+	if p.Fix != "" {
+		s += "\n  Fix: " + p.Fix
+	}
+	return s
+}
+
+// ValidateFiles loads the configuration files and returns the problems it
+// has. These are violations of the configuration schema, and of the rules
+// that the schema cannot express, which would otherwise only fail when
+// serving. Environment variables are not taken into account. The returned
+// error is set if the files could not be loaded at all.
+func ValidateFiles(ctx context.Context, files ...string) ([]*Problem, error) {
+	p, err := configx.New(ctx, embedx.ConfigSchema,
+		configx.WithConfigFiles(files...),
+		configx.WithContext(ctx),
+		configx.DisableEnvLoading(),
+		configx.SkipValidation(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(p.Raw())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	v, err := newConfigValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The semantic problems explain schema violations of the same key better.
+	semantic := semanticProblems(raw)
+	explained := make(map[string]bool, len(semantic))
+	for _, p := range semantic {
+		explained[p.Key] = true
+	}
+	var problems []*Problem
+	for _, p := range v.schemaProblems(raw) {
+		if !explained[p.Key] {
+			problems = append(problems, p)
+		}
+	}
+	problems = append(problems, semantic...)
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+	return problems, nil
+}
+
+type configValidator struct {
+	schema *jsonschema.Schema
+	paths  map[string]jsonschemax.Path
+}
+
+func newConfigValidator(ctx context.Context) (*configValidator, error) {
+	id := gjson.GetBytes(embedx.ConfigSchema, "$id").String()
+	c := jsonschema.NewCompiler()
+	c.ExtractAnnotations = true
+	if err := c.AddResource(id, strings.NewReader(string(embedx.ConfigSchema))); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := otelx.AddConfigSchema(c); err != nil {
+		return nil, err
+	}
+	if err := logrusx.AddConfigSchema(c); err != nil {
+		return nil, err
+	}
+	s, err := c.Compile(ctx, id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	paths, err := jsonschemax.ListPathsWithInitializedSchema(s)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &configValidator{schema: s, paths: make(map[string]jsonschemax.Path, len(paths))}
+	for _, p := range paths {
+		v.paths[p.Name] = p
+	}
+	return v, nil
+}
+
+func (v *configValidator) schemaProblems(raw []byte) []*Problem {
+	err := v.schema.Validate(strings.NewReader(string(raw)))
+	if err == nil {
+		return nil
+	}
+	var e *jsonschema.ValidationError
+	if !errors.As(err, &e) {
+		return []*Problem{{Message: err.Error()}}
+	}
+
+	var problems []*Problem
+	var collect func(e *jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if isAlternative(e) {
+			// If the value has the type of only one of the alternatives, that
+			// one was meant. Otherwise, the causes of all alternatives would
+			// be confusing.
+			var meant []*jsonschema.ValidationError
+			for _, c := range e.Causes {
+				if !(c.InstancePtr == e.InstancePtr && strings.HasSuffix(c.SchemaPtr, "/type")) {
+					meant = append(meant, c)
+				}
+			}
+			if len(meant) == 1 {
+				collect(meant[0])
+				return
+			}
+		}
+		if len(e.Causes) > 0 && !isAlternative(e) {
+			for _, c := range e.Causes {
+				collect(c)
+			}
+			return
+		}
+		problems = append(problems, v.problem(e))
+	}
+	collect(e)
+	return problems
+}
+
+var quotedName = regexp.MustCompile(`"([^"]+)"`)
+
+func (v *configValidator) problem(e *jsonschema.ValidationError) *Problem {
+	key := pointerToKey(e.InstancePtr)
+
+	if ctx, ok := e.Context.(*jsonschema.ValidationErrorContextRequired); ok && len(ctx.Missing) > 0 {
+		missing := pointerToKey(ctx.Missing[0])
+		return &Problem{Key: missing, Message: "the key is required but missing", Fix: "Add " + missing + "." + v.hint(missing)}
+	}
+
+	if strings.HasPrefix(e.Message, "additionalProperties") {
+		var unknown []string
+		for _, m := range quotedName.FindAllStringSubmatch(e.Message, -1) {
+			unknown = append(unknown, m[1])
+		}
+		p := &Problem{Key: key, Message: "unknown key(s) " + strings.Join(unknown, ", ")}
+		children := v.children(key)
+		for _, u := range unknown {
+			if c := schema.Closest(u, children); c != "" {
+				p.Fix += fmt.Sprintf("Rename %q to %q. ", u, c)
+			}
+		}
+		if p.Fix == "" {
+			p.Fix = "Remove the key(s). Allowed keys are: " + strings.Join(children, ", ") + "."
+		}
+		p.Fix = strings.TrimSpace(p.Fix)
+		return p
+	}
+
+	message := e.Message
+	if isAlternative(e) {
+		message = "the value does not match any of the allowed forms"
+	}
+	return &Problem{Key: key, Message: message, Fix: strings.TrimSpace(v.hint(key))}
+}
+
+// hint describes the allowed values of the key.
+func (v *configValidator) hint(key string) string {
+	p, ok := v.paths[key]
+	if !ok {
+		return ""
+	}
+	var hint string
+	if d := firstSentence(p.Description); d != "" {
+		hint += " " + d
+	}
+	switch {
+	case len(p.Enum) > 0:
+		allowed := make([]string, len(p.Enum))
+		for i, e := range p.Enum {
+			allowed[i] = fmt.Sprintf("%v", e)
+		}
+		hint += " Allowed values are: " + strings.Join(allowed, ", ") + "."
+	case len(p.Examples) > 0:
+		hint += fmt.Sprintf(" For example: %v", p.Examples[0])
+	case p.Default != nil:
+		hint += fmt.Sprintf(" The default is %v.", p.Default)
+	}
+	return hint
+}
+
+// children returns the names of the keys directly below the key.
+func (v *configValidator) children(key string) []string {
+	prefix := key + "."
+	if key == "" {
+		prefix = ""
+	}
+	var children []string
+	for name := range v.paths {
+		if rest := strings.TrimPrefix(name, prefix); rest != name || prefix == "" {
+			if rest != "" && !strings.Contains(rest, ".") {
+				children = append(children, rest)
+			}
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func isAlternative(e *jsonschema.ValidationError) bool {
+	return strings.HasSuffix(e.SchemaPtr, "/oneOf") || strings.HasSuffix(e.SchemaPtr, "/anyOf")
+}
+
+func pointerToKey(ptr string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(strings.TrimPrefix(ptr, "#"), "/"), "/", ".")
+}
+
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i >= 0 {
+		return s[:i+1]
+	}
+	return s
+}
+
+// semanticProblems checks the rules that the configuration schema cannot
+// express.
+func semanticProblems(raw []byte) []*Problem {
+	var problems []*Problem
+	get := func(key string) gjson.Result { return gjson.GetBytes(raw, key) }
+
+	// namespaces is either a location, an inline list, or an object with the
+	// location of Ory Permission Language files.
+	switch ns := get(KeyNamespaces); {
+	case ns.IsObject():
+		ns.ForEach(func(k, _ gjson.Result) bool {
+			if k.String() == "location" || k.String() == "experimental_strict_mode" {
+				return true
+			}
+			problems = append(problems, &Problem{
+				Key:     KeyNamespaces,
+				Message: fmt.Sprintf("%s and an inline list of namespaces (%q) are mutually exclusive", KeyNamespacesLocation, k.String()),
+				Fix:     "Either keep namespaces.location and move the namespaces into Ory Permission Language files, or replace the namespaces object with the inline list.",
+			})
+			return false
+		})
+	case ns.IsArray():
+		names := make(map[string]bool)
+		for i, n := range ns.Array() {
+			if n.Get("location").Exists() {
+				problems = append(problems, &Problem{
+					Key:     KeyNamespaces + "." + strconv.Itoa(i),
+					Message: fmt.Sprintf("%s and an inline list of namespaces are mutually exclusive", KeyNamespacesLocation),
+					Fix:     "Set namespaces.location directly, without the list: `namespaces: {location: ...}`.",
+				})
+				continue
+			}
+			name := n.Get("name").String()
+			if names[name] {
+				problems = append(problems, &Problem{
+					Key:     KeyNamespaces + "." + strconv.Itoa(i),
+					Message: fmt.Sprintf("the namespace %q is defined more than once", name),
+					Fix:     "Remove or rename one of the namespaces.",
+				})
+			}
+			names[name] = true
+		}
+	}
+
+	for i, proxy := range get(KeyRateLimitTrustedProxies).Array() {
+		if _, err := ParseTrustedProxy(proxy.String()); err != nil {
+			problems = append(problems, &Problem{
+				Key:     KeyRateLimitTrustedProxies + "." + strconv.Itoa(i),
+				Message: fmt.Sprintf("%q is neither an IP address nor a CIDR network", proxy.String()),
+				Fix:     "Use an IP address like 10.0.0.1, or a network like 10.0.0.0/8.",
+			})
+		}
+	}
+
+	// All APIs need their own port if they listen on the same interface.
+	type api struct {
+		hostKey, portKey string
+		defaultPort      int64
+	}
+	apis := []api{
+		{KeyReadAPIHost, KeyReadAPIPort, 4466},
+		{KeyWriteAPIHost, KeyWriteAPIPort, 4467},
+		{KeyMetricsHost, KeyMetricsPort, 4468},
+	}
+	if get(KeyAdminAPIEnabled).Bool() {
+		apis = append(apis, api{KeyAdminAPIHost, KeyAdminAPIPort, 4469})
+	}
+	for i, a := range apis {
+		for _, b := range apis[:i] {
+			aPort, bPort := get(a.portKey), get(b.portKey)
+			aHost, bHost := get(a.hostKey).String(), get(b.hostKey).String()
+			samePort := portOrDefault(aPort, a.defaultPort) == portOrDefault(bPort, b.defaultPort) && portOrDefault(aPort, a.defaultPort) != 0
+			sameHost := aHost == bHost || aHost == "" || bHost == ""
+			if samePort && sameHost {
+				problems = append(problems, &Problem{
+					Key:     a.portKey,
+					Message: fmt.Sprintf("the port %d is also used by %s", portOrDefault(aPort, a.defaultPort), b.portKey),
+					Fix:     fmt.Sprintf("Use another port for %s, e.g. the default %d, or listen on different hosts.", a.portKey, a.defaultPort),
+				})
+			}
+		}
+	}
+
+	if read, write := get("serve.read.socket.path").String(), get("serve.write.socket.path").String(); read != "" && read == write {
+		problems = append(problems, &Problem{
+			Key:     "serve.write.socket.path",
+			Message: fmt.Sprintf("the socket %s is also used by serve.read.socket.path", write),
+			Fix:     "Use another path for the socket of the write API.",
+		})
+	}
+
+	for _, iface := range []string{"read", "write", "admin", "metrics"} {
+		tls := "serve." + iface + ".tls"
+		if get(tls+".client_auth").Exists() && !(get(tls+".cert").Exists() && get(tls+".key").Exists()) {
+			problems = append(problems, &Problem{
+				Key:     tls + ".client_auth",
+				Message: "client certificates are required, but the API does not use TLS",
+				Fix:     fmt.Sprintf("Set %[1]s.cert and %[1]s.key to the certificate and key of the server.", tls),
+			})
+		}
+	}
+
+	configured := map[string]bool{
+		AuthenticatorAPIKey:        get(KeyAuthnAPIKeys).Exists(),
+		AuthenticatorJWT:           get(KeyAuthnJWT).Exists(),
+		AuthenticatorIntrospection: get(KeyAuthnIntrospection).Exists(),
+		AuthenticatorAnonymous:     true,
+	}
+	for i, r := range get(KeyAuthnRules).Array() {
+		for _, a := range r.Get("authenticators").Array() {
+			if name := a.String(); !configured[name] {
+				problems = append(problems, &Problem{
+					Key:     KeyAuthnRules + "." + strconv.Itoa(i) + ".authenticators",
+					Message: fmt.Sprintf("the authenticator %q is not configured, so it never accepts a token", name),
+					Fix:     fmt.Sprintf("Configure authn.%s, or remove it from the rule.", name),
+				})
+			}
+		}
+	}
+
+	authnEnabled := configured[AuthenticatorAPIKey] || configured[AuthenticatorJWT] || configured[AuthenticatorIntrospection]
+	if get(KeyMetaPermissionsNamespace).String() != "" && !authnEnabled {
+		problems = append(problems, &Problem{
+			Key:     KeyMetaPermissionsNamespace,
+			Message: "meta-permissions are enabled, but no authenticator is configured, so all requests are denied",
+			Fix:     "Configure at least one authenticator in authn.",
+		})
+	}
+
+	if get(KeyTenancyEnabled).Bool() {
+		if !authnEnabled {
+			problems = append(problems, &Problem{
+				Key:     KeyTenancyEnabled,
+				Message: "tenancy is enabled, but no authenticator is configured, so all requests are rejected",
+				Fix:     "Configure at least one authenticator in authn, and bind its clients to tenants.",
+			})
+		}
+		for _, k := range []string{KeyAuthnJWT, KeyAuthnIntrospection} {
+			if get(k).Exists() && get(k+".tenant_claim").String() == "" {
+				problems = append(problems, &Problem{
+					Key:     k + ".tenant_claim",
+					Message: "tenancy is enabled, but the tenant claim is not set, so all requests with these tokens are rejected",
+					Fix:     fmt.Sprintf("Set %s.tenant_claim to the claim that holds the tenant of the client.", k),
+				})
+			}
+		}
+	}
+
+	if def, max := get(KeyLimitDefaultPageSize), get(KeyLimitMaxPageSize); def.Exists() && max.Exists() && def.Int() > max.Int() {
+		problems = append(problems, &Problem{
+			Key:     KeyLimitDefaultPageSize,
+			Message: fmt.Sprintf("the default page size %d is larger than the maximum page size %d", def.Int(), max.Int()),
+			Fix:     fmt.Sprintf("Lower %s or raise %s.", KeyLimitDefaultPageSize, KeyLimitMaxPageSize),
+		})
+	}
+
+	if get(KeyCDCEnabled).Bool() {
+		for _, k := range []string{KeyCDCSinkType, KeyCDCSinkURL} {
+			if get(k).String() == "" {
+				problems = append(problems, &Problem{
+					Key:     k,
+					Message: "change data capture is enabled, but the key is not set",
+					Fix:     fmt.Sprintf("Set %s, or disable change data capture with %s: false.", k, KeyCDCEnabled),
+				})
+			}
+		}
+	}
+
+	if get(KeyNamespaceAPIEnabled).Bool() && !authnEnabled && len(get(KeyNamespaceAPIKeys).Array()) == 0 {
+		problems = append(problems, &Problem{
+			Key:     KeyNamespaceAPIKeys,
+			Message: "the namespace administration API is enabled, but neither an authenticator nor API keys are set, so all requests are rejected",
+			Fix:     "Configure at least one authenticator in authn, or add at least one API key with 16 or more characters.",
+		})
+	} else if authnEnabled && len(get(KeyNamespaceAPIKeys).Array()) > 0 {
+		problems = append(problems, &Problem{
+			Key:     KeyNamespaceAPIKeys,
+			Message: "an authenticator is configured, so the namespace administration API ignores these API keys",
+			Fix:     "Move the keys to authn.api_keys, or remove them.",
+		})
+	}
+
+	if len(get(KeySecretsCipher).Array()) > 0 && get(KeySecretsMappingID).String() == "" {
+		problems = append(problems, &Problem{
+			Key:     KeySecretsMappingID,
+			Message: "the identifiers are encrypted, but the secret of their UUIDs is not set, so all identifiers are rejected",
+			Fix:     fmt.Sprintf("Set %s to a secret of at least 32 characters. If identifiers were encrypted before, set it to the last secret of %s to keep their UUIDs.", KeySecretsMappingID, KeySecretsCipher),
+		})
+	}
+
+	dsn := get(KeyDSN).String()
+	for i, r := range get(KeyReadReplicaDSNs).Array() {
+		if r.String() == dsn {
+			problems = append(problems, &Problem{
+				Key:     KeyReadReplicaDSNs + "." + strconv.Itoa(i),
+				Message: "the read replica is the primary database",
+				Fix:     "Remove the primary database from the read replicas.",
+			})
+		}
+	}
+
+	mapped := make(map[string]bool)
+	for i, s := range get(KeyNamespaceStorage).Array() {
+		key := KeyNamespaceStorage + "." + strconv.Itoa(i)
+		if s.Get("dsn").String() == dsn {
+			problems = append(problems, &Problem{
+				Key:     key + ".dsn",
+				Message: "the namespace storage is the primary database",
+				Fix:     "Remove the entry, the namespaces are stored in the primary database anyway.",
+			})
+		}
+		for _, n := range s.Get("namespaces").Array() {
+			if mapped[n.String()] {
+				problems = append(problems, &Problem{
+					Key:     key + ".namespaces",
+					Message: fmt.Sprintf("the namespace %q is mapped to more than one database", n.String()),
+					Fix:     "Remove the namespace from all but one database.",
+				})
+			}
+			mapped[n.String()] = true
+		}
+	}
+
+	sharded := len(get(KeyShardingDSNs).Array()) > 0
+	shards := make(map[string]bool)
+	for i, s := range get(KeyShardingDSNs).Array() {
+		key := KeyShardingDSNs + "." + strconv.Itoa(i)
+		switch {
+		case s.String() == dsn:
+			problems = append(problems, &Problem{
+				Key:     key,
+				Message: "the shard is the primary database",
+				Fix:     "Use a separate database for each shard, the primary database keeps the network and the UUID mappings.",
+			})
+		case shards[s.String()]:
+			problems = append(problems, &Problem{
+				Key:     key,
+				Message: "the shard is listed more than once",
+				Fix:     "Remove the duplicate, or use a separate database for each shard.",
+			})
+		}
+		shards[s.String()] = true
+	}
+	for _, f := range []struct{ key, feature string }{
+		{KeyHistoryEnabled, "the history API"},
+		{KeyCDCEnabled, "change data capture"},
+	} {
+		if sharded && get(f.key).Bool() {
+			problems = append(problems, &Problem{
+				Key:     f.key,
+				Message: fmt.Sprintf("the relation tuples are sharded, which does not support %s", f.feature),
+				Fix:     fmt.Sprintf("Remove %s, or remove %s.", f.key, KeyShardingDSNs),
+			})
+		}
+	}
+
+	if get(KeyMemorySnapshotPath).String() != "" && !dbal.IsMemorySQLite(get(KeyDSN).String()) && get(KeyDSN).String() != "memory" {
+		problems = append(problems, &Problem{
+			Key:     KeyMemorySnapshotPath,
+			Message: "only the in-memory database can be snapshotted",
+			Fix:     fmt.Sprintf("Set %s to \"memory\", or remove %s.", KeyDSN, KeyMemorySnapshotPath),
+		})
+	}
+
+	// The relation tuples can be stored in Redis, DynamoDB, or Cloud Spanner
+	// instead of the database, but these do not support all features.
+	var backends []string
+	for _, b := range []struct{ key, name string }{{KeyRedisURL, "redis"}, {KeyDynamoDBTable, "dynamodb"}, {KeySpannerDatabase, "spanner"}} {
+		if get(b.key).String() != "" {
+			backends = append(backends, b.name)
+		}
+	}
+	if len(backends) > 1 {
+		problems = append(problems, &Problem{
+			Key:     backends[1],
+			Message: fmt.Sprintf("the relation tuples can only be stored in one of %s", strings.Join(backends, " and ")),
+			Fix:     "Remove all but one of them.",
+		})
+	}
+	names := map[string]string{"redis": "Redis", "dynamodb": "DynamoDB", "spanner": "Cloud Spanner"}
+	for _, b := range backends {
+		for _, f := range []struct {
+			key, feature string
+			enabled      bool
+		}{
+			{KeyHistoryEnabled, "the history API", get(KeyHistoryEnabled).Bool()},
+			{KeyCDCEnabled, "change data capture", get(KeyCDCEnabled).Bool()},
+			{KeyNamespaceStorage, "namespace storage", len(get(KeyNamespaceStorage).Array()) > 0},
+			{KeyShardingDSNs, "sharding", sharded},
+			{KeySecretsCipher, "encrypting the identifiers", len(get(KeySecretsCipher).Array()) > 0},
+		} {
+			if f.enabled {
+				problems = append(problems, &Problem{
+					Key:     f.key,
+					Message: fmt.Sprintf("the relation tuples are stored in %s, which does not support %s", names[b], f.feature),
+					Fix:     fmt.Sprintf("Remove %s, or store the relation tuples in the database by removing %s.", f.key, b),
+				})
+			}
+		}
+	}
+
+	// A closure only spans the relation tuples of one database.
+	var spread string
+	switch {
+	case len(backends) > 0:
+		spread = "stored in " + names[backends[0]]
+	case sharded:
+		spread = "sharded"
+	case len(get(KeyNamespaceStorage).Array()) > 0:
+		spread = "stored in more than one database"
+	}
+	if ns := get(KeyNamespaces); spread != "" && ns.IsArray() {
+		for i, n := range ns.Array() {
+			if len(n.Get("config.closure.relations").Array()) > 0 {
+				problems = append(problems, &Problem{
+					Key:     KeyNamespaces + "." + strconv.Itoa(i) + ".config.closure",
+					Message: fmt.Sprintf("the relation tuples are %s, which does not support closures", spread),
+					Fix:     "Remove the closure, checks of the relations are evaluated without it.",
+				})
+			}
+		}
+	}
+
+	// The UUID mappings are referenced from all databases.
+	if interval, err := time.ParseDuration(get(KeyUUIDMappingGCInterval).String()); spread != "" && err == nil && interval > 0 {
+		problems = append(problems, &Problem{
+			Key:     KeyUUIDMappingGCInterval,
+			Message: fmt.Sprintf("the relation tuples are %s, which does not support collecting orphaned UUID mappings", spread),
+			Fix:     fmt.Sprintf("Remove %s.", KeyUUIDMappingGCInterval),
+		})
+	}
+
+	// Plugins are compiled into the binary, so they are only known here.
+	for i, p := range get(KeyGRPCInterceptors).Array() {
+		name := p.Get("name").String()
+		if _, ok := ketoctx.LookupGRPCInterceptorPlugin(name); !ok && name != "" {
+			problems = append(problems, &Problem{
+				Key:     KeyGRPCInterceptors + "." + strconv.Itoa(i) + ".name",
+				Message: fmt.Sprintf("the gRPC interceptor plugin %q is not registered in this build", name),
+				Fix:     "Use a build that imports the package registering the plugin, or remove the plugin.",
+			})
+		}
+	}
+
+	return problems
+}
+
+func portOrDefault(port gjson.Result, def int64) int64 {
+	if !port.Exists() {
+		return def
+	}
+	return port.Int()
+}
@@ -2,12 +2,14 @@ package driver
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 
@@ -17,6 +19,7 @@ import (
 
 	"github.com/ory/x/logrusx"
 
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	"github.com/julienschmidt/httprouter"
@@ -27,8 +30,14 @@ import (
 	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/cdc"
 	"github.com/ory/keto/internal/check"
 	"github.com/ory/keto/internal/expand"
+	"github.com/ory/keto/internal/extauthz"
+	"github.com/ory/keto/internal/graphql"
+	"github.com/ory/keto/internal/namespace/definition"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
 	"github.com/ory/keto/internal/relationtuple"
 	"github.com/ory/keto/internal/x"
 
@@ -47,6 +56,8 @@ import (
 	"google.golang.org/grpc"
 )
 
+const softDeletePurgeInterval = time.Hour
+
 func (r *RegistryDefault) enableSqa(cmd *cobra.Command) {
 	ctx := cmd.Context()
 
@@ -88,7 +99,11 @@ func (r *RegistryDefault) ServeAll(ctx context.Context) error {
 	innerCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	doneShutdown := make(chan struct{}, 3)
+	nServers := 3
+	if r.Config(ctx).AdminAPIEnabled() {
+		nServers++
+	}
+	doneShutdown := make(chan struct{}, nServers)
 
 	go func() {
 		osSignals := make(chan os.Signal, 1)
@@ -116,13 +131,166 @@ func (r *RegistryDefault) ServeAll(ctx context.Context) error {
 		}
 	}()
 
+	defer r.registerPoolMetrics()()
+	defer r.registerMappingCacheMetrics()()
+	defer r.registerCheckMetrics()()
+	defer r.registerQuotaMetrics()()
+	defer r.registerNamespaceReloadMetrics()()
+
+	// The servers reject all requests if the plugins cannot be loaded, so
+	// they are not started at all.
+	if _, err := r.interceptorPlugins(innerCtx); err != nil {
+		return err
+	}
+
 	eg := &errgroup.Group{}
 
 	eg.Go(r.serveRead(innerCtx, doneShutdown))
 	eg.Go(r.serveWrite(innerCtx, doneShutdown))
 	eg.Go(r.serveMetrics(innerCtx, doneShutdown))
+	if r.Config(ctx).AdminAPIEnabled() {
+		eg.Go(r.serveAdmin(innerCtx, doneShutdown))
+	} else if r.Config(ctx).AdminAPIDebugEnabled() {
+		r.Logger().Warn("The debug endpoints are only served on the admin API, which is disabled.")
+	}
+	if r.Config(ctx).CDCEnabled() {
+		eg.Go(r.serveCDC(innerCtx))
+	}
+	if r.Config(ctx).AuditEnabled() {
+		eg.Go(r.serveAuditLog(innerCtx))
+	}
+	if r.Config(ctx).SidecarEnabled() {
+		eg.Go(r.replicatePrimary(innerCtx))
+	}
+	if r.Config(ctx).OTLPMetricsEndpoint() != "" {
+		eg.Go(r.exportOTLPMetrics(innerCtx))
+	}
+	eg.Go(r.purgeDeletedRelationTuples(innerCtx))
+	if r.Config(ctx).UUIDMappingGC().Interval > 0 {
+		eg.Go(r.collectOrphanedMappings(innerCtx))
+	}
+	if r.Config(ctx).TupleCountMetrics().Interval > 0 {
+		eg.Go(r.countRelationTuplesPeriodically(innerCtx))
+	}
+	eg.Go(r.reloadDatabasePeriodically(innerCtx))
+	eg.Go(r.updateHealthStatus(innerCtx))
+	if r.memorySnapshotEnabled(ctx) {
+		eg.Go(r.snapshotMemoryPeriodically(innerCtx))
+	}
 
-	return eg.Wait()
+	err := eg.Wait()
+	if r.memorySnapshotEnabled(ctx) {
+		if err := r.WriteMemorySnapshot(ctx); err != nil {
+			r.Logger().WithError(err).Error("could not snapshot the in-memory database on shutdown")
+		} else {
+			r.Logger().WithField("path", r.Config(ctx).MemorySnapshotPath()).Info("Snapshotted the in-memory database.")
+		}
+	}
+	return err
+}
+
+// purgeDeletedRelationTuples periodically deletes soft deleted relation tuples
+// that are past their retention window, in the networks of all tenants.
+func (r *RegistryDefault) purgeDeletedRelationTuples(ctx context.Context) func() error {
+	return func() error {
+		ticker := time.NewTicker(softDeletePurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			ctxs, err := r.TenantContexts(ctx)
+			if err != nil {
+				r.Logger().WithError(err).Error("could not list the tenants to purge soft deleted relation tuples of, will retry")
+				continue
+			}
+			for _, ctx := range ctxs {
+				n, err := r.RelationTupleSoftDeleteManager().PurgeDeletedRelationTuples(ctx)
+				if err != nil {
+					r.Logger().WithError(err).Error("could not purge soft deleted relation tuples, will retry")
+					continue
+				}
+				if n > 0 {
+					r.Logger().WithField("count", n).Info("Purged soft deleted relation tuples past their retention window")
+				}
+			}
+		}
+	}
+}
+
+// collectOrphanedMappings periodically deletes the UUID mappings that no
+// relation tuple references anymore. The collection is not scoped by network,
+// so it covers the mappings of all tenants at once.
+func (r *RegistryDefault) collectOrphanedMappings(ctx context.Context) func() error {
+	return func() error {
+		gm := r.MappingGCManager()
+		if gm == nil {
+			r.Logger().Warnf("The orphaned UUID mappings can only be collected if the relation tuples are stored in the primary SQL database, ignoring %s.", config.KeyUUIDMappingGCInterval)
+			return nil
+		}
+
+		ticker := time.NewTicker(r.Config(ctx).UUIDMappingGC().Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			gc := r.Config(ctx).UUIDMappingGC()
+			res, err := gm.CollectOrphanedMappings(ctx, relationtuple.MappingGCOptions{GracePeriod: gc.GracePeriod, BatchSize: gc.BatchSize})
+			if err != nil {
+				r.Logger().WithError(err).Error("could not collect the orphaned UUID mappings, will retry")
+				continue
+			}
+			if res.Marked > 0 || res.Deleted > 0 {
+				r.Logger().
+					WithField("marked", res.Marked).
+					WithField("unmarked", res.Unmarked).
+					WithField("deleted", res.Deleted).
+					Info("Collected orphaned UUID mappings")
+			}
+		}
+	}
+}
+
+func (r *RegistryDefault) serveCDC(ctx context.Context) func() error {
+	return func() error {
+		sink, err := cdc.NewSink(r.Config(ctx).CDCSink())
+		if err != nil {
+			return err
+		}
+		r.Logger().WithField("sink", r.Config(ctx).CDCSink().Type).Info("Publishing relation tuple changes")
+		return cdc.NewPublisher(r, sink).Run(ctx)
+	}
+}
+
+func (r *RegistryDefault) serveAuditLog(ctx context.Context) func() error {
+	return func() error {
+		sinks, err := r.Config(ctx).AuditSinks()
+		if err != nil {
+			return err
+		}
+		sink, err := audit.NewSink(sinks)
+		if err != nil {
+			return err
+		}
+		r.Logger().WithField("sinks", len(sinks)).Info("Writing the audit log")
+		return r.AuditLogger().Run(ctx, sink)
+	}
+}
+
+func (r *RegistryDefault) replicatePrimary(ctx context.Context) func() error {
+	return func() error {
+		r.Logger().WithField("primary", r.Config(ctx).Sidecar().PrimaryURL).Info("Replicating the relation tuples of the primary")
+		return r.SidecarReplicator().Run(ctx)
+	}
 }
 
 func (r *RegistryDefault) serveRead(ctx context.Context, done chan<- struct{}) func() error {
@@ -133,7 +301,11 @@ func (r *RegistryDefault) serveRead(ctx context.Context, done chan<- struct{}) f
 	}
 
 	return func() error {
-		return multiplexPort(ctx, r.Logger().WithField("endpoint", "read"), r.Config(ctx).ReadAPIListenOn(), rt, s, done)
+		tlsConfig, err := r.tlsConfig(ctx, "read")
+		if err != nil {
+			return err
+		}
+		return multiplexPort(ctx, r.Logger().WithField("endpoint", "read"), r.Config(ctx).ReadAPIListenOn(), r.Config(ctx).Socket("read"), tlsConfig, rt, s, done)
 	}
 }
 
@@ -145,31 +317,54 @@ func (r *RegistryDefault) serveWrite(ctx context.Context, done chan<- struct{})
 	}
 
 	return func() error {
-		return multiplexPort(ctx, r.Logger().WithField("endpoint", "write"), r.Config(ctx).WriteAPIListenOn(), rt, s, done)
+		tlsConfig, err := r.tlsConfig(ctx, "write")
+		if err != nil {
+			return err
+		}
+		return multiplexPort(ctx, r.Logger().WithField("endpoint", "write"), r.Config(ctx).WriteAPIListenOn(), r.Config(ctx).Socket("write"), tlsConfig, rt, s, done)
 	}
 }
 
 func (r *RegistryDefault) serveMetrics(ctx context.Context, done chan<- struct{}) func() error {
+	return r.serveHTTP(ctx, "metrics", r.Config(ctx).MetricsListenOn(), r.metricsRouter, done)
+}
+
+func (r *RegistryDefault) serveAdmin(ctx context.Context, done chan<- struct{}) func() error {
+	return r.serveHTTP(ctx, "admin", r.Config(ctx).AdminAPIListenOn(), r.AdminRouter, done)
+}
+
+// serveHTTP serves an API that is only available over HTTP.
+func (r *RegistryDefault) serveHTTP(ctx context.Context, iface, addr string, router func(context.Context) http.Handler, done chan<- struct{}) func() error {
 	return func() error {
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 
+		tlsConfig, err := r.tlsConfig(ctx, iface)
+		if err != nil {
+			return err
+		}
+
 		eg := &errgroup.Group{}
 		// nolint: gosec,G112 graceful.WithDefaults already sets a timeout
 		s := graceful.WithDefaults(&http.Server{
-			Handler: r.metricsRouter(ctx),
-			Addr:    r.Config(ctx).MetricsListenOn(),
+			Handler:   router(ctx),
+			Addr:      addr,
+			TLSConfig: tlsConfig,
 		})
 
 		eg.Go(func() error {
-			if err := s.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			listenAndServe := s.ListenAndServe
+			if tlsConfig != nil {
+				listenAndServe = func() error { return s.ListenAndServeTLS("", "") }
+			}
+			if err := listenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 				return errors.WithStack(err)
 			}
 			return nil
 		})
 		eg.Go(func() (err error) {
 			defer func() {
-				l := r.Logger().WithField("endpoint", "metrics")
+				l := r.Logger().WithField("endpoint", iface)
 				if err != nil {
 					l.WithError(err).Error("graceful shutdown failed")
 				} else {
@@ -188,17 +383,23 @@ func (r *RegistryDefault) serveMetrics(ctx context.Context, done chan<- struct{}
 	}
 }
 
-func multiplexPort(ctx context.Context, log *logrusx.Logger, addr string, router http.Handler, grpcS *grpc.Server, done chan<- struct{}) error {
+func multiplexPort(ctx context.Context, log *logrusx.Logger, addr string, socket *config.Socket, tlsConfig *tls.Config, router http.Handler, grpcS *grpc.Server, done chan<- struct{}) error {
 	l, err := (&net.ListenConfig{}).Listen(ctx, "tcp", addr)
 	if err != nil {
 		return err
 	}
-
-	m := cmux.New(l)
-	m.SetReadTimeout(graceful.DefaultReadTimeout)
-
-	grpcL := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
-	httpL := m.Match(cmux.HTTP1())
+	l, router = withTLS(l, tlsConfig, router)
+
+	listeners := []net.Listener{l}
+	if socket != nil {
+		sl, err := listenSocket(ctx, socket)
+		if err != nil {
+			_ = l.Close()
+			return err
+		}
+		log.WithField("socket", socket.Path).Info("Listening on unix socket")
+		listeners = append(listeners, sl)
+	}
 
 	// nolint: gosec,G112 graceful.WithDefaults already sets a timeout
 	restS := graceful.WithDefaults(&http.Server{
@@ -207,28 +408,41 @@ func multiplexPort(ctx context.Context, log *logrusx.Logger, addr string, router
 
 	eg := &errgroup.Group{}
 
-	eg.Go(func() error {
-		if err := grpcS.Serve(grpcL); !errors.Is(err, cmux.ErrServerClosed) {
-			return errors.WithStack(err)
-		}
-		return nil
-	})
+	for i, l := range listeners {
+		m := cmux.New(l)
+		m.SetReadTimeout(graceful.DefaultReadTimeout)
 
-	eg.Go(func() error {
-		if err := restS.Serve(httpL); !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, cmux.ErrServerClosed) {
-			return errors.WithStack(err)
+		grpcL := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+		httpMatchers := []cmux.Matcher{cmux.HTTP1()}
+		if i == 0 && tlsConfig != nil {
+			// REST clients negotiate HTTP/2 as well when using TLS.
+			httpMatchers = append(httpMatchers, cmux.HTTP2())
 		}
-		return nil
-	})
+		httpL := m.Match(httpMatchers...)
 
-	eg.Go(func() error {
-		err := m.Serve()
-		if err != nil && !errors.Is(err, net.ErrClosed) {
-			// unexpected error
-			return errors.WithStack(err)
-		}
-		return nil
-	})
+		eg.Go(func() error {
+			if err := grpcS.Serve(grpcL); !errors.Is(err, cmux.ErrServerClosed) {
+				return errors.WithStack(err)
+			}
+			return nil
+		})
+
+		eg.Go(func() error {
+			if err := restS.Serve(httpL); !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, cmux.ErrServerClosed) {
+				return errors.WithStack(err)
+			}
+			return nil
+		})
+
+		eg.Go(func() error {
+			err := m.Serve()
+			if err != nil && !errors.Is(err, net.ErrClosed) {
+				// unexpected error
+				return errors.WithStack(err)
+			}
+			return nil
+		})
+	}
 
 	eg.Go(func() (err error) {
 		defer func() {
@@ -281,6 +495,9 @@ func (r *RegistryDefault) allHandlers() []Handler {
 			relationtuple.NewHandler(r),
 			check.NewHandler(r),
 			expand.NewHandler(r),
+			definition.NewHandler(r),
+			namespacehandler.NewHandler(r),
+			graphql.NewHandler(r),
 		}
 	}
 	return r.handlers
@@ -288,15 +505,24 @@ func (r *RegistryDefault) allHandlers() []Handler {
 
 func (r *RegistryDefault) ReadRouter(ctx context.Context) http.Handler {
 	n := negroni.New()
+	n.UseFunc(r.requestIDMiddleware)
 	for _, f := range r.defaultHttpMiddlewares {
 		n.UseFunc(f)
 	}
-	n.Use(reqlog.NewMiddlewareFromLogger(r.l, "read#Ory Keto").ExcludePaths(healthx.AliveCheckPath, healthx.ReadyCheckPath))
+	n.Use(r.requestLogMiddleware("read#Ory Keto").ExcludePaths(r.healthPaths()...))
+	n.UseFunc(r.requestBodyLimitMiddleware)
+	n.UseFunc(r.authnMiddleware("read"))
+	n.UseFunc(r.rateLimitMiddleware(rateLimitCheck, isCheckRequest))
+	n.UseFunc(r.tenancyMiddleware)
+	n.UseFunc(r.authzMiddleware())
+	n.UseFunc(r.readConsistencyMiddleware)
 
 	br := &x.ReadRouter{Router: httprouter.New()}
 
-	r.HealthHandler().SetHealthRoutes(br.Router, false)
+	r.setHealthRoutes(br.Router)
 	r.HealthHandler().SetVersionRoutes(br.Router)
+	r.registerOpenAPIRoute(br.Router)
+	r.registerStatusRoute(br.Router)
 
 	for _, h := range r.allHandlers() {
 		h.RegisterReadRoutes(br)
@@ -319,18 +545,34 @@ func (r *RegistryDefault) ReadRouter(ctx context.Context) http.Handler {
 
 func (r *RegistryDefault) WriteRouter(ctx context.Context) http.Handler {
 	n := negroni.New()
+	n.UseFunc(r.requestIDMiddleware)
 	for _, f := range r.defaultHttpMiddlewares {
 		n.UseFunc(f)
 	}
-	n.Use(reqlog.NewMiddlewareFromLogger(r.l, "write#Ory Keto").ExcludePaths(healthx.AliveCheckPath, healthx.ReadyCheckPath))
+	n.Use(r.requestLogMiddleware("write#Ory Keto").ExcludePaths(r.healthPaths()...))
+	n.UseFunc(r.requestBodyLimitMiddleware)
+	n.UseFunc(r.authnMiddleware("write"))
+	n.UseFunc(r.rateLimitMiddleware(rateLimitWrite, isAPIRequest))
+	n.UseFunc(r.tenancyMiddleware)
+	n.UseFunc(r.authzMiddleware())
+	n.UseFunc(r.sidecarMiddleware)
 
 	pr := &x.WriteRouter{Router: httprouter.New()}
 
-	r.HealthHandler().SetHealthRoutes(pr.Router, false)
+	r.setHealthRoutes(pr.Router)
 	r.HealthHandler().SetVersionRoutes(pr.Router)
+	r.registerOpenAPIRoute(pr.Router)
+	r.registerStatusRoute(pr.Router)
 
+	adminDisabled := !r.Config(ctx).AdminAPIEnabled()
 	for _, h := range r.allHandlers() {
 		h.RegisterWriteRoutes(pr)
+		if adminDisabled {
+			h.RegisterAdminRoutes(&x.AdminRouter{Router: pr.Router})
+		}
+	}
+	if adminDisabled {
+		r.registerMigrationStatusRoute(&x.AdminRouter{Router: pr.Router})
 	}
 
 	n.UseHandler(pr)
@@ -348,18 +590,66 @@ func (r *RegistryDefault) WriteRouter(ctx context.Context) http.Handler {
 	return handler
 }
 
+// AdminRouter serves the schema and operational endpoints if the admin API is
+// enabled.
+func (r *RegistryDefault) AdminRouter(ctx context.Context) http.Handler {
+	n := negroni.New()
+	n.UseFunc(r.requestIDMiddleware)
+	for _, f := range r.defaultHttpMiddlewares {
+		n.UseFunc(f)
+	}
+	n.Use(r.requestLogMiddleware("admin#Ory Keto").ExcludePaths(r.healthPaths()...))
+	n.UseFunc(r.requestBodyLimitMiddleware)
+	n.UseFunc(r.authnMiddleware("admin"))
+	n.UseFunc(r.tenancyMiddleware)
+	n.UseFunc(r.authzMiddleware())
+	n.UseFunc(r.sidecarMiddleware)
+
+	pr := &x.AdminRouter{Router: httprouter.New()}
+
+	r.setHealthRoutes(pr.Router)
+	r.HealthHandler().SetVersionRoutes(pr.Router)
+	r.registerOpenAPIRoute(pr.Router)
+	r.registerStatusRoute(pr.Router)
+
+	for _, h := range r.allHandlers() {
+		h.RegisterAdminRoutes(pr)
+	}
+	r.registerMigrationStatusRoute(pr)
+	if r.Config(ctx).AdminAPIDebugEnabled() {
+		r.registerDebugRoutes(pr.Router)
+	}
+
+	n.UseHandler(pr)
+
+	if r.sqaService != nil {
+		n.Use(r.sqaService)
+	}
+
+	var handler http.Handler = n
+	options, enabled := r.Config(ctx).CORS("admin")
+	if enabled {
+		handler = cors.New(options).Handler(handler)
+	}
+
+	return handler
+}
+
 func (r *RegistryDefault) unaryInterceptors(ctx context.Context) []grpc.UnaryServerInterceptor {
-	is := make([]grpc.UnaryServerInterceptor, len(r.defaultUnaryInterceptors), len(r.defaultUnaryInterceptors)+2)
+	is := make([]grpc.UnaryServerInterceptor, len(r.defaultUnaryInterceptors), len(r.defaultUnaryInterceptors)+5)
 	copy(is, r.defaultUnaryInterceptors)
+	is = append(is, herodot.UnaryErrorUnwrapInterceptor)
+	// The span is started first, so that the request ID can be recorded on it.
+	if r.Tracer(ctx).IsLoaded() {
+		is = append(is, grpcOtel.UnaryServerInterceptor(grpcOtel.WithTracerProvider(otel.GetTracerProvider())))
+	}
+	requestIDUnary, _ := r.requestIDInterceptors()
 	is = append(is,
-		herodot.UnaryErrorUnwrapInterceptor,
+		requestIDUnary,
 		grpcMiddleware.ChainUnaryServer(
 			grpc_logrus.UnaryServerInterceptor(r.l.Entry),
 		),
 	)
-	if r.Tracer(ctx).IsLoaded() {
-		is = append(is, grpcOtel.UnaryServerInterceptor(grpcOtel.WithTracerProvider(otel.GetTracerProvider())))
-	}
 	if r.sqaService != nil {
 		is = append(is, r.sqaService.UnaryInterceptor)
 	}
@@ -367,17 +657,20 @@ func (r *RegistryDefault) unaryInterceptors(ctx context.Context) []grpc.UnarySer
 }
 
 func (r *RegistryDefault) streamInterceptors(ctx context.Context) []grpc.StreamServerInterceptor {
-	is := make([]grpc.StreamServerInterceptor, len(r.defaultStreamInterceptors), len(r.defaultStreamInterceptors)+2)
+	is := make([]grpc.StreamServerInterceptor, len(r.defaultStreamInterceptors), len(r.defaultStreamInterceptors)+5)
 	copy(is, r.defaultStreamInterceptors)
+	is = append(is, herodot.StreamErrorUnwrapInterceptor)
+	// The span is started first, so that the request ID can be recorded on it.
+	if r.Tracer(ctx).IsLoaded() {
+		is = append(is, grpcOtel.StreamServerInterceptor(grpcOtel.WithTracerProvider(otel.GetTracerProvider())))
+	}
+	_, requestIDStream := r.requestIDInterceptors()
 	is = append(is,
-		herodot.StreamErrorUnwrapInterceptor,
+		requestIDStream,
 		grpcMiddleware.ChainStreamServer(
 			grpc_logrus.StreamServerInterceptor(r.l.Entry),
 		),
 	)
-	if r.Tracer(ctx).IsLoaded() {
-		is = append(is, grpcOtel.StreamServerInterceptor(grpcOtel.WithTracerProvider(otel.GetTracerProvider())))
-	}
 	if r.sqaService != nil {
 		is = append(is, r.sqaService.StreamInterceptor)
 	}
@@ -385,14 +678,26 @@ func (r *RegistryDefault) streamInterceptors(ctx context.Context) []grpc.StreamS
 }
 
 func (r *RegistryDefault) ReadGRPCServer(ctx context.Context) *grpc.Server {
-	s := grpc.NewServer(
-		grpc.ChainStreamInterceptor(r.streamInterceptors(ctx)...),
-		grpc.ChainUnaryInterceptor(r.unaryInterceptors(ctx)...),
-	)
+	// The ext_authz checks count against the rate limit of the check API.
+	rateLimitUnary, rateLimitStream := r.rateLimitInterceptors(rateLimitCheck, checkServiceName, extAuthzServiceName)
+	authnUnary, authnStream := r.authnInterceptors("read")
+	tenancyUnary, tenancyStream := r.tenancyInterceptors()
+	customUnary, customStream := r.customInterceptors(ctx, "read")
+	stream := append(append(r.streamInterceptors(ctx), authnStream, rateLimitStream, tenancyStream), customStream...)
+	unary := append(append(r.unaryInterceptors(ctx), authnUnary, rateLimitUnary, tenancyUnary), customUnary...)
+	s := grpc.NewServer(append(r.grpcLimitOptions(ctx),
+		grpc.ChainStreamInterceptor(append(stream, r.authzStreamInterceptor(), r.readConsistencyStreamInterceptor)...),
+		grpc.ChainUnaryInterceptor(append(unary, r.authzInterceptor(), r.readConsistencyUnaryInterceptor)...),
+	)...)
 
 	grpcHealthV1.RegisterHealthServer(s, r.HealthServer())
 	rts.RegisterVersionServiceServer(s, r)
-	reflection.Register(s)
+	if r.Config(ctx).ExtAuthzEnabled() {
+		authv3.RegisterAuthorizationServer(s, extauthz.NewHandler(r))
+	}
+	if r.Config(ctx).GRPCReflection() {
+		reflection.Register(s)
+	}
 
 	for _, h := range r.allHandlers() {
 		h.RegisterReadGRPC(s)
@@ -402,14 +707,23 @@ func (r *RegistryDefault) ReadGRPCServer(ctx context.Context) *grpc.Server {
 }
 
 func (r *RegistryDefault) WriteGRPCServer(ctx context.Context) *grpc.Server {
-	s := grpc.NewServer(
-		grpc.ChainStreamInterceptor(r.streamInterceptors(ctx)...),
-		grpc.ChainUnaryInterceptor(r.unaryInterceptors(ctx)...),
-	)
+	rateLimitUnary, rateLimitStream := r.rateLimitInterceptors(rateLimitWrite, writeServiceName)
+	authnUnary, authnStream := r.authnInterceptors("write")
+	tenancyUnary, tenancyStream := r.tenancyInterceptors()
+	customUnary, customStream := r.customInterceptors(ctx, "write")
+	stream := append(append(r.streamInterceptors(ctx), authnStream, rateLimitStream, tenancyStream), customStream...)
+	unary := append(append(r.unaryInterceptors(ctx), authnUnary, rateLimitUnary, tenancyUnary), customUnary...)
+	s := grpc.NewServer(append(r.grpcLimitOptions(ctx),
+		grpc.ChainStreamInterceptor(append(stream, r.authzStreamInterceptor(), r.sidecarStreamInterceptor)...),
+		grpc.ChainUnaryInterceptor(append(unary, r.authzInterceptor(), r.sidecarUnaryInterceptor)...),
+	)...)
 
 	grpcHealthV1.RegisterHealthServer(s, r.HealthServer())
 	rts.RegisterVersionServiceServer(s, r)
-	reflection.Register(s)
+	rts.RegisterMigrationServiceServer(s, r)
+	if r.Config(ctx).GRPCReflection() {
+		reflection.Register(s)
+	}
 
 	for _, h := range r.allHandlers() {
 		h.RegisterWriteGRPC(s)
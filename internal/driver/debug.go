@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	DebugPprofRoute = "/debug/pprof/"
+	DebugGCRoute    = "/debug/gc"
+)
+
+// GCStats are the statistics of the garbage collector and the memory
+// allocator, to diagnose memory spikes without taking a heap profile.
+type GCStats struct {
+	NumGC          int64           `json:"num_gc"`
+	LastGC         time.Time       `json:"last_gc"`
+	PauseTotal     time.Duration   `json:"pause_total_ns"`
+	RecentPauses   []time.Duration `json:"recent_pauses_ns"`
+	HeapAlloc      uint64          `json:"heap_alloc_bytes"`
+	HeapInuse      uint64          `json:"heap_inuse_bytes"`
+	HeapIdle       uint64          `json:"heap_idle_bytes"`
+	HeapReleased   uint64          `json:"heap_released_bytes"`
+	HeapObjects    uint64          `json:"heap_objects"`
+	Sys            uint64          `json:"sys_bytes"`
+	NextGC         uint64          `json:"next_gc_bytes"`
+	GCCPUFraction  float64         `json:"gc_cpu_fraction"`
+	NumGoroutine   int             `json:"num_goroutine"`
+	TotalAllocated uint64          `json:"total_alloc_bytes"`
+}
+
+// registerDebugRoutes serves the Go profiler and the garbage collector
+// statistics. They are only registered on the admin API if enabled.
+func (r *RegistryDefault) registerDebugRoutes(router *httprouter.Router) {
+	router.GET(DebugPprofRoute+"*profile", servePprof)
+	router.POST(DebugPprofRoute+"*profile", servePprof)
+	router.GET(DebugGCRoute, r.getGCStats)
+}
+
+// servePprof dispatches to the handlers of net/http/pprof, which serves the
+// named profiles like goroutine or heap from its index.
+func servePprof(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	switch ps.ByName("profile") {
+	case "/cmdline":
+		pprof.Cmdline(w, r)
+	case "/profile":
+		pprof.Profile(w, r)
+	case "/symbol":
+		pprof.Symbol(w, r)
+	case "/trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Index(w, r)
+	}
+}
+
+func (r *RegistryDefault) getGCStats(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var (
+		gc  debug.GCStats
+		mem runtime.MemStats
+	)
+	debug.ReadGCStats(&gc)
+	runtime.ReadMemStats(&mem)
+
+	r.Writer().Write(w, req, &GCStats{
+		NumGC:          gc.NumGC,
+		LastGC:         gc.LastGC,
+		PauseTotal:     gc.PauseTotal,
+		RecentPauses:   gc.Pause,
+		HeapAlloc:      mem.HeapAlloc,
+		HeapInuse:      mem.HeapInuse,
+		HeapIdle:       mem.HeapIdle,
+		HeapReleased:   mem.HeapReleased,
+		HeapObjects:    mem.HeapObjects,
+		Sys:            mem.Sys,
+		NextGC:         mem.NextGC,
+		GCCPUFraction:  mem.GCCPUFraction,
+		NumGoroutine:   runtime.NumGoroutine(),
+		TotalAllocated: mem.TotalAlloc,
+	})
+}
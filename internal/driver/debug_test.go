@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	ctx := context.Background()
+
+	get := func(t *testing.T, h http.Handler, path string) (int, string) {
+		ts := httptest.NewServer(h)
+		defer ts.Close()
+
+		resp, err := ts.Client().Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("case=disabled by default", func(t *testing.T) {
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+
+		for _, path := range []string{DebugPprofRoute, DebugGCRoute} {
+			code, _ := get(t, r.AdminRouter(ctx), path)
+			assert.Equal(t, http.StatusNotFound, code, path)
+		}
+	})
+
+	t.Run("case=enabled", func(t *testing.T) {
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), WithConfig(config.KeyAdminAPIDebug, true))
+		admin := r.AdminRouter(ctx)
+
+		code, body := get(t, admin, DebugPprofRoute)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "goroutine")
+
+		code, body = get(t, admin, DebugPprofRoute+"goroutine?debug=2")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "TestDebugRoutes", "the goroutine dump contains the stack traces")
+
+		code, body = get(t, admin, DebugPprofRoute+"cmdline")
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, body)
+
+		code, body = get(t, admin, DebugGCRoute)
+		require.Equal(t, http.StatusOK, code)
+		var s GCStats
+		require.NoError(t, json.Unmarshal([]byte(body), &s))
+		assert.Positive(t, s.NumGoroutine)
+		assert.Positive(t, s.HeapAlloc)
+
+		t.Run("case=not served on the write API", func(t *testing.T) {
+			code, _ := get(t, r.WriteRouter(ctx), DebugGCRoute)
+			assert.Equal(t, http.StatusNotFound, code)
+		})
+	})
+}
@@ -0,0 +1,342 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+)
+
+type (
+	DoctorStatus string
+
+	// DoctorCheck is the result of one check of `keto doctor`.
+	DoctorCheck struct {
+		Name    string       `json:"name"`
+		Status  DoctorStatus `json:"status"`
+		Message string       `json:"message"`
+		Hint    string       `json:"hint,omitempty"`
+	}
+)
+
+const (
+	DoctorStatusOK      DoctorStatus = "ok"
+	DoctorStatusWarning DoctorStatus = "warning"
+	DoctorStatusFailed  DoctorStatus = "failed"
+	DoctorStatusSkipped DoctorStatus = "skipped"
+
+	// doctorPings is how many queries the database latency is averaged
+	// over.
+	doctorPings = 3
+	// slowDatabaseLatency is the round trip time above which the database
+	// is reported as slow.
+	slowDatabaseLatency = 100 * time.Millisecond
+	// maxClockSkew is the difference between the clocks of this host and the
+	// database above which the clock skew is reported.
+	maxClockSkew = time.Second
+)
+
+// expectedIndexes are the indexes of the relation tuple table after all
+// migrations were applied, per dialect. MySQL has no partial indexes, so it
+// has fewer of them.
+var expectedIndexes = map[string][]string{
+	"mysql": {
+		"keto_relation_tuples_uuid_full_idx",
+		"keto_relation_tuples_uuid_reverse_subject_idx",
+		"keto_relation_tuples_subject_ids_reverse_idx",
+		"keto_relation_tuples_subject_sets_reverse_idx",
+	},
+	"": {
+		"keto_relation_tuples_uuid_full_idx",
+		"keto_relation_tuples_uuid_subject_ids_idx",
+		"keto_relation_tuples_uuid_subject_sets_idx",
+		"keto_relation_tuples_uuid_reverse_subject_ids_idx",
+		"keto_relation_tuples_uuid_reverse_subject_sets_idx",
+		"keto_relation_tuples_subject_ids_reverse_idx",
+		"keto_relation_tuples_subject_sets_reverse_idx",
+	},
+}
+
+// Doctor diagnoses the database and the namespaces. Unlike the ready checks,
+// it also reports problems that do not prevent serving requests, and explains
+// how to fix them. The checks that depend on a failed check are skipped.
+func (r *RegistryDefault) Doctor(ctx context.Context) []DoctorCheck {
+	database := r.doctorDatabase(ctx)
+	if database.Status == DoctorStatusFailed {
+		reason := "the database is not reachable"
+		return []DoctorCheck{
+			database,
+			skippedCheck("migrations", reason),
+			r.doctorNamespaces(ctx),
+			skippedCheck("indexes", reason),
+			skippedCheck("clock skew", reason),
+		}
+	}
+
+	migrations, migrated := r.doctorMigrations(ctx)
+	indexes := skippedCheck("indexes", "the primary database has pending migrations")
+	if migrated {
+		indexes = r.doctorIndexes(ctx)
+	}
+	return []DoctorCheck{database, migrations, r.doctorNamespaces(ctx), indexes, r.doctorClockSkew(ctx)}
+}
+
+func skippedCheck(name, reason string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: DoctorStatusSkipped, Message: "Skipped because " + reason + "."}
+}
+
+func failedCheck(name string, err error, hint string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: DoctorStatusFailed, Message: err.Error(), Hint: hint}
+}
+
+// doctorDatabase checks that the primary database is reachable, and reports
+// the average round trip time of a trivial query.
+func (r *RegistryDefault) doctorDatabase(ctx context.Context) DoctorCheck {
+	const name = "database"
+	hint := fmt.Sprintf("Check that the database is running and reachable with the DSN of %s.", config.KeyDSN)
+
+	conn, err := r.PopConnection(ctx)
+	if err != nil {
+		return failedCheck(name, err, hint)
+	}
+	var total time.Duration
+	for i := 0; i < doctorPings; i++ {
+		start := time.Now()
+		if err := sqlcon.HandleError(conn.WithContext(ctx).RawQuery("SELECT 1").Exec()); err != nil {
+			return failedCheck(name, err, hint)
+		}
+		total += time.Since(start)
+	}
+
+	latency := (total / doctorPings).Round(time.Microsecond)
+	if latency > slowDatabaseLatency {
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusWarning,
+			Message: fmt.Sprintf("The %s database responds slowly, a query takes %s on average.", conn.Dialect.Name(), latency),
+			Hint:    "Run Ory Keto close to the database, and check the load of the database server.",
+		}
+	}
+	return DoctorCheck{
+		Name:    name,
+		Status:  DoctorStatusOK,
+		Message: fmt.Sprintf("The %s database is reachable, a query takes %s on average.", conn.Dialect.Name(), latency),
+	}
+}
+
+// doctorMigrations checks the migration status of all databases. It also
+// returns whether the primary database has no pending migrations.
+func (r *RegistryDefault) doctorMigrations(ctx context.Context) (DoctorCheck, bool) {
+	const name = "migrations"
+
+	s, err := r.MigrationStatus(ctx)
+	if err != nil {
+		return failedCheck(name, err, ""), false
+	}
+	migrated := len(s.Databases) > 0 && !s.Databases[0].Pending
+
+	var dirty, expand, contract []string
+	for _, db := range s.Databases {
+		switch {
+		case db.Dirty:
+			dirty = append(dirty, db.Name)
+		case db.ExpandPending:
+			expand = append(expand, db.Name)
+		case db.Pending:
+			contract = append(contract, db.Name)
+		}
+	}
+	switch {
+	case len(dirty) > 0:
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusFailed,
+			Message: fmt.Sprintf("The migrations of %s are out of order.", strings.Join(dirty, ", ")),
+			Hint:    "Run `keto migrate status` to find the pending migrations that precede applied ones, and migrate down to the last migration before them.",
+		}, migrated
+	case len(expand) > 0:
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusFailed,
+			Message: fmt.Sprintf("Migrations that this version requires are pending on %s.", strings.Join(expand, ", ")),
+			Hint:    "Run `keto migrate up`.",
+		}, migrated
+	case len(contract) > 0:
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusWarning,
+			Message: fmt.Sprintf("Migrations of the contract phase are pending on %s.", strings.Join(contract, ", ")),
+			Hint:    "Run `keto migrate up` once all instances run this version.",
+		}, migrated
+	}
+	return DoctorCheck{
+		Name:    name,
+		Status:  DoctorStatusOK,
+		Message: fmt.Sprintf("All migrations are applied to %d database(s).", len(s.Databases)),
+	}, migrated
+}
+
+// doctorNamespaces checks that the namespaces can be loaded, and that there
+// is at least one.
+func (r *RegistryDefault) doctorNamespaces(ctx context.Context) DoctorCheck {
+	const name = "namespaces"
+	hint := fmt.Sprintf("Run `keto namespace validate` or `keto namespace lint` on the files of %s.", config.KeyNamespaces)
+
+	nm, err := r.Config(ctx).NamespaceManager()
+	if err != nil {
+		return failedCheck(name, err, hint)
+	}
+	nn, err := nm.Namespaces(ctx)
+	if err != nil {
+		return failedCheck(name, err, hint)
+	}
+
+	configured, err := r.Config(ctx).ConfiguredNamespaceManager()
+	if err != nil {
+		return failedCheck(name, err, hint)
+	}
+	if l, ok := configured.(namespace.LoadErrorReporter); ok {
+		if err := l.LoadError(); err != nil {
+			return failedCheck(name, err, hint)
+		}
+	}
+
+	if len(nn) == 0 {
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusWarning,
+			Message: "No namespaces are configured, so all requests fail.",
+			Hint:    fmt.Sprintf("Configure the namespaces with %s.", config.KeyNamespaces),
+		}
+	}
+	return DoctorCheck{
+		Name:    name,
+		Status:  DoctorStatusOK,
+		Message: fmt.Sprintf("%d namespace(s) are loaded.", len(nn)),
+	}
+}
+
+// doctorIndexes checks that the relation tuple table of the primary database
+// has all indexes that the queries rely on.
+func (r *RegistryDefault) doctorIndexes(ctx context.Context) DoctorCheck {
+	const name = "indexes"
+
+	conn, err := r.PopConnection(ctx)
+	if err != nil {
+		return failedCheck(name, err, "")
+	}
+	present, err := tableIndexes(ctx, conn, "keto_relation_tuples")
+	if err != nil {
+		return failedCheck(name, err, "")
+	}
+
+	expected, ok := expectedIndexes[conn.Dialect.Name()]
+	if !ok {
+		expected = expectedIndexes[""]
+	}
+	var missing []string
+	for _, idx := range expected {
+		if !present[idx] {
+			missing = append(missing, idx)
+		}
+	}
+	if len(missing) > 0 {
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusWarning,
+			Message: fmt.Sprintf("The relation tuple table is missing the indexes %s, so queries are slow.", strings.Join(missing, ", ")),
+			Hint:    "The indexes are created by the migrations. Recreate them as defined in the migrations of the database, or migrate down and up again.",
+		}
+	}
+	return DoctorCheck{
+		Name:    name,
+		Status:  DoctorStatusOK,
+		Message: fmt.Sprintf("All %d indexes of the relation tuple table are present.", len(expected)),
+	}
+}
+
+func tableIndexes(ctx context.Context, conn *pop.Connection, table string) (map[string]bool, error) {
+	var query string
+	switch d := conn.Dialect.Name(); d {
+	case "sqlite3":
+		query = "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ?"
+	case "postgres", "cockroach":
+		query = "SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ?"
+	case "mysql":
+		query = "SELECT DISTINCT index_name FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ?"
+	default:
+		return nil, errors.Errorf("listing the indexes is not supported for the %s dialect", d)
+	}
+
+	var names []string
+	if err := conn.Store.SelectContext(ctx, &names, conn.Dialect.TranslateSQL(query), table); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	res := make(map[string]bool, len(names))
+	for _, n := range names {
+		res[n] = true
+	}
+	return res, nil
+}
+
+// doctorClockSkew compares the clock of the database server with the clock of
+// this host, accounting for half of the round trip time. A skew makes the
+// timestamps that the database sets hard to correlate with the logs.
+func (r *RegistryDefault) doctorClockSkew(ctx context.Context) DoctorCheck {
+	const name = "clock skew"
+
+	conn, err := r.PopConnection(ctx)
+	if err != nil {
+		return failedCheck(name, err, "")
+	}
+	var query string
+	switch d := conn.Dialect.Name(); d {
+	case "sqlite3":
+		return skippedCheck(name, "SQLite uses the clock of this host")
+	case "postgres", "cockroach":
+		query = "SELECT CAST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) AS TEXT)"
+	case "mysql":
+		query = "SELECT CAST(UNIX_TIMESTAMP(NOW(6)) AS CHAR)"
+	default:
+		return skippedCheck(name, fmt.Sprintf("the %s dialect is not supported", d))
+	}
+
+	var epoch string
+	start := time.Now()
+	if err := conn.Store.GetContext(ctx, &epoch, query); err != nil {
+		return failedCheck(name, sqlcon.HandleError(err), "")
+	}
+	rtt := time.Since(start)
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(epoch), 64)
+	if err != nil {
+		return failedCheck(name, errors.Wrapf(err, "could not parse the database time %q", epoch), "")
+	}
+
+	local := start.Add(rtt / 2)
+	skew := time.Unix(0, int64(seconds*float64(time.Second))).Sub(local).Round(time.Millisecond)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs > maxClockSkew {
+		return DoctorCheck{
+			Name:    name,
+			Status:  DoctorStatusWarning,
+			Message: fmt.Sprintf("The clock of the database differs by %s from the clock of this host.", skew),
+			Hint:    "Synchronize the clocks of this host and the database server, e.g. with NTP.",
+		}
+	}
+	return DoctorCheck{
+		Name:    name,
+		Status:  DoctorStatusOK,
+		Message: fmt.Sprintf("The clock of the database differs by %s from the clock of this host.", skew),
+	}
+}
@@ -0,0 +1,83 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/ketoctx"
+)
+
+// customInterceptors return the interceptors that are called after a request
+// to the API was authenticated, first the ones given with
+// ketoctx.WithGRPCInterceptors and then the ones of the configured plugins.
+func (r *RegistryDefault) customInterceptors(ctx context.Context, api string) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	plugins, err := r.interceptorPlugins(ctx)
+	if err != nil {
+		// The plugins might reject requests, so none are served without them.
+		r.Logger().WithError(err).Error("Unable to load the gRPC interceptor plugins, all gRPC requests are rejected.")
+		return []grpc.UnaryServerInterceptor{rejectUnary}, []grpc.StreamServerInterceptor{rejectStream}
+	}
+
+	is := r.apiInterceptors[api]
+	unary := append([]grpc.UnaryServerInterceptor{}, is.Unary...)
+	stream := append([]grpc.StreamServerInterceptor{}, is.Stream...)
+	if p, ok := plugins[api]; ok {
+		unary = append(unary, p.Unary...)
+		stream = append(stream, p.Stream...)
+	}
+	return unary, stream
+}
+
+// interceptorPlugins returns the interceptors of the plugins configured in
+// serve.grpc.interceptors by API. The plugins are only created once.
+func (r *RegistryDefault) interceptorPlugins(ctx context.Context) (map[string]*ketoctx.GRPCInterceptors, error) {
+	r.pluginsOnce.Do(func() {
+		r.pluginInterceptors, r.pluginsErr = r.newInterceptorPlugins(ctx)
+	})
+	return r.pluginInterceptors, r.pluginsErr
+}
+
+func (r *RegistryDefault) newInterceptorPlugins(ctx context.Context) (map[string]*ketoctx.GRPCInterceptors, error) {
+	plugins, err := r.Config(ctx).GRPCInterceptorPlugins()
+	if err != nil {
+		return nil, err
+	}
+
+	is := make(map[string]*ketoctx.GRPCInterceptors)
+	for _, p := range plugins {
+		plugin, ok := ketoctx.LookupGRPCInterceptorPlugin(p.Name)
+		if !ok {
+			return nil, errors.Errorf("the gRPC interceptor plugin %q is not registered, this build has the plugins %v", p.Name, ketoctx.GRPCInterceptorPluginNames())
+		}
+		for _, api := range p.APIs {
+			pi, err := plugin(ctx, api, p.Config)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to create the gRPC interceptor plugin %q of the %s API", p.Name, api)
+			}
+			if pi == nil {
+				continue
+			}
+			if is[api] == nil {
+				is[api] = &ketoctx.GRPCInterceptors{}
+			}
+			is[api].Unary = append(is[api].Unary, pi.Unary...)
+			is[api].Stream = append(is[api].Stream, pi.Stream...)
+		}
+		r.Logger().WithField("plugin", p.Name).WithField("apis", p.APIs).Info("Loaded the gRPC interceptor plugin.")
+	}
+	return is, nil
+}
+
+var errPluginsNotLoaded = status.Error(codes.Unavailable, "the gRPC interceptor plugins could not be loaded")
+
+func rejectUnary(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error) {
+	return nil, errPluginsNotLoaded
+}
+
+func rejectStream(interface{}, grpc.ServerStream, *grpc.StreamServerInfo, grpc.StreamHandler) error {
+	return errPluginsNotLoaded
+}
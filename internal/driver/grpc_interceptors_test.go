@@ -0,0 +1,101 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoctx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestGRPCInterceptorPlugins(t *testing.T) {
+	var called []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			called = append(called, name)
+			return handler(ctx, req)
+		}
+	}
+
+	ketoctx.RegisterGRPCInterceptorPlugin("driver-test", func(_ context.Context, api string, raw json.RawMessage) (*ketoctx.GRPCInterceptors, error) {
+		var c struct {
+			Tag string `json:"tag"`
+		}
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, err
+		}
+		return &ketoctx.GRPCInterceptors{Unary: []grpc.UnaryServerInterceptor{record(c.Tag + "-" + api)}}, nil
+	})
+	ketoctx.RegisterGRPCInterceptorPlugin("driver-test-broken", func(context.Context, string, json.RawMessage) (*ketoctx.GRPCInterceptors, error) {
+		return nil, errors.New("broken")
+	})
+
+	getVersion := func(t *testing.T, s *grpc.Server) error {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() { _ = s.Serve(l) }()
+		t.Cleanup(s.Stop)
+
+		conn, err := grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		_, err = rts.NewVersionServiceClient(conn).GetVersion(context.Background(), &rts.GetVersionRequest{})
+		return err
+	}
+
+	t.Run("case=options and plugins are called in order", func(t *testing.T) {
+		ctx := context.Background()
+		called = nil
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+		r.apiInterceptors = map[string]ketoctx.GRPCInterceptors{
+			"read": {Unary: []grpc.UnaryServerInterceptor{record("option")}},
+		}
+		require.NoError(t, r.Config(ctx).Set(config.KeyGRPCInterceptors, []map[string]interface{}{
+			{"name": "driver-test", "config": map[string]interface{}{"tag": "first"}},
+			{"name": "driver-test", "apis": []string{"write"}, "config": map[string]interface{}{"tag": "second"}},
+		}))
+
+		require.NoError(t, getVersion(t, r.ReadGRPCServer(ctx)))
+		assert.Equal(t, []string{"option", "first-read"}, called)
+
+		called = nil
+		require.NoError(t, getVersion(t, r.WriteGRPCServer(ctx)))
+		assert.Equal(t, []string{"first-write", "second-write"}, called)
+	})
+
+	t.Run("case=requests are rejected if a plugin cannot be loaded", func(t *testing.T) {
+		ctx := context.Background()
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+		require.NoError(t, r.Config(ctx).Set(config.KeyGRPCInterceptors, []map[string]interface{}{
+			{"name": "driver-test-broken"},
+		}))
+
+		_, err := r.interceptorPlugins(ctx)
+		assert.ErrorContains(t, err, `unable to create the gRPC interceptor plugin "driver-test-broken" of the read API: broken`)
+		assert.Equal(t, codes.Unavailable, status.Code(getVersion(t, r.ReadGRPCServer(ctx))))
+	})
+
+	t.Run("case=unknown plugins cannot be loaded", func(t *testing.T) {
+		ctx := context.Background()
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+		require.NoError(t, r.Config(ctx).Set(config.KeyGRPCInterceptors, []map[string]interface{}{
+			{"name": "driver-test-unknown"},
+		}))
+
+		_, err := r.interceptorPlugins(ctx)
+		assert.ErrorContains(t, err, `the gRPC interceptor plugin "driver-test-unknown" is not registered`)
+	})
+}
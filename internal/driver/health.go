@@ -0,0 +1,212 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/sidecar"
+)
+
+const (
+	// healthCheckInterval is how often the status of the gRPC health service
+	// is updated.
+	healthCheckInterval = 5 * time.Second
+
+	// ReadyCheckRoute reports a single ready check, for probes that only
+	// depend on some of them.
+	ReadyCheckRoute = healthx.ReadyCheckPath + "/:check"
+)
+
+// readyCheckers are the checks of the readiness endpoint and the gRPC health
+// service. Each check is also reported as its own gRPC health service, and on
+// its own readiness endpoint. The liveness endpoint does not run them, so
+// that an instance is not restarted if a dependency is down.
+func (r *RegistryDefault) readyCheckers() healthx.ReadyCheckers {
+	return healthx.ReadyCheckers{
+		"database": func(req *http.Request) error {
+			return r.checkDatabase(req.Context())
+		},
+		"migrations": func(req *http.Request) error {
+			return r.checkMigrations(req.Context())
+		},
+		"namespaces": func(req *http.Request) error {
+			return r.checkNamespaces(req.Context())
+		},
+		"sidecar": func(req *http.Request) error {
+			return r.checkSidecar(req.Context())
+		},
+	}
+}
+
+func (r *RegistryDefault) checkDatabase(ctx context.Context) error {
+	conn, err := r.PopConnection(ctx)
+	if err != nil {
+		return err
+	}
+	return sqlcon.HandleError(conn.WithContext(ctx).RawQuery("SELECT 1").Exec())
+}
+
+// checkMigrations fails if a database has pending migrations of the expand
+// phase, which this version requires, or is dirty. Pending contract
+// migrations are only applied after the rollout, so they are ignored.
+func (r *RegistryDefault) checkMigrations(ctx context.Context) error {
+	s, err := r.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	for _, db := range s.Databases {
+		switch {
+		case db.Dirty:
+			return errors.Errorf("the database %s is dirty", db.Name)
+		case db.ExpandPending:
+			return errors.Errorf("the database %s has pending migrations", db.Name)
+		}
+	}
+	return nil
+}
+
+// checkNamespaces fails if the namespaces cannot be listed, or if a namespace
+// file could not be loaded and there is no last known version of it.
+func (r *RegistryDefault) checkNamespaces(ctx context.Context) error {
+	nm, err := r.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	if _, err := nm.Namespaces(ctx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	configured, err := r.Config(ctx).ConfiguredNamespaceManager()
+	if err != nil {
+		return err
+	}
+	if l, ok := configured.(namespace.LoadErrorReporter); ok {
+		return l.LoadError()
+	}
+	return nil
+}
+
+// checkSidecar fails until a sidecar loaded the snapshot of the primary, so
+// that it does not answer checks with incomplete relation tuples.
+func (r *RegistryDefault) checkSidecar(ctx context.Context) error {
+	if !r.Config(ctx).SidecarEnabled() || r.SidecarReplicator().Synced() {
+		return nil
+	}
+	return sidecar.ErrNotSynced
+}
+
+// healthPaths are the paths of the liveness and readiness endpoints, which are
+// excluded from the request log.
+func (r *RegistryDefault) healthPaths() []string {
+	paths := []string{healthx.AliveCheckPath, healthx.ReadyCheckPath}
+	for name := range r.readyCheckers() {
+		paths = append(paths, healthx.ReadyCheckPath+"/"+name)
+	}
+	return paths
+}
+
+// readyStatus is the response of the readiness endpoints. It names the
+// failing checks, which herodot would hide in a generic error.
+type readyStatus struct {
+	Status string            `json:"status,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// setHealthRoutes registers the liveness and readiness endpoints, and the
+// endpoints of the single ready checks.
+func (r *RegistryDefault) setHealthRoutes(router *httprouter.Router) {
+	router.Handler(http.MethodGet, healthx.AliveCheckPath, r.HealthHandler().Alive())
+	router.GET(healthx.ReadyCheckPath, r.getReady)
+	router.GET(ReadyCheckRoute, r.getReadyCheck)
+}
+
+func (r *RegistryDefault) getReady(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	r.writeReadyStatus(w, req, r.readyCheckers())
+}
+
+// getReadyCheck responds like the readiness endpoint, but only runs the check
+// with the name of the path.
+func (r *RegistryDefault) getReadyCheck(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	name := ps.ByName("check")
+	check, ok := r.readyCheckers()[name]
+	if !ok {
+		r.Writer().WriteError(w, req, errors.WithStack(herodot.ErrNotFound.WithReasonf("Unknown ready check %q.", name)))
+		return
+	}
+	r.writeReadyStatus(w, req, healthx.ReadyCheckers{name: check})
+}
+
+// writeReadyStatus runs the checks and responds with 503 if any of them
+// failed. The errors may contain sensitive information, so they are only
+// logged.
+func (r *RegistryDefault) writeReadyStatus(w http.ResponseWriter, req *http.Request, checkers healthx.ReadyCheckers) {
+	errs := make(map[string]string)
+	for name, check := range checkers {
+		if err := check(req); err != nil {
+			r.Logger().WithError(err).WithField("check", name).Warn("Ready check failed.")
+			errs[name] = "The check failed, the error was logged."
+		}
+	}
+	if len(errs) > 0 {
+		r.Writer().WriteCode(w, req, http.StatusServiceUnavailable, &readyStatus{Errors: errs})
+		return
+	}
+	r.Writer().Write(w, req, &readyStatus{Status: "ok"})
+}
+
+// updateHealthStatus runs the ready checks periodically and reports the
+// results through the gRPC health service, until the context is done. Then
+// all services are reported as not serving.
+func (r *RegistryDefault) updateHealthStatus(ctx context.Context) func() error {
+	return func() error {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			r.checkHealth(ctx)
+
+			select {
+			case <-ctx.Done():
+				r.HealthServer().Shutdown()
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// checkHealth runs the ready checks once and reports the results through the
+// gRPC health service. The empty service name stands for the overall health.
+func (r *RegistryDefault) checkHealth(ctx context.Context) {
+	checkers := r.readyCheckers()
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthx.ReadyCheckPath, nil)
+	if err != nil {
+		return
+	}
+	overall := grpcHealthV1.HealthCheckResponse_SERVING
+	for _, name := range names {
+		status := grpcHealthV1.HealthCheckResponse_SERVING
+		if err := checkers[name](req); err != nil {
+			r.Logger().WithError(err).WithField("check", name).Warn("Health check failed.")
+			status, overall = grpcHealthV1.HealthCheckResponse_NOT_SERVING, grpcHealthV1.HealthCheckResponse_NOT_SERVING
+		}
+		r.HealthServer().SetServingStatus(name, status)
+	}
+	r.HealthServer().SetServingStatus("", overall)
+}
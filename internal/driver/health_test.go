@@ -0,0 +1,99 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ory/x/healthx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestReadyChecks(t *testing.T) {
+	ctx := context.Background()
+
+	get := func(t *testing.T, r *RegistryDefault, path string) (int, map[string]interface{}) {
+		ts := httptest.NewServer(r.ReadRouter(ctx))
+		defer ts.Close()
+
+		resp, err := ts.Client().Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	t.Run("case=all checks pass", func(t *testing.T) {
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+
+		for _, path := range []string{
+			healthx.ReadyCheckPath,
+			healthx.ReadyCheckPath + "/database",
+			healthx.ReadyCheckPath + "/migrations",
+			healthx.ReadyCheckPath + "/namespaces",
+		} {
+			code, body := get(t, r, path)
+			assert.Equal(t, http.StatusOK, code, path)
+			assert.Equal(t, "ok", body["status"], path)
+		}
+
+		r.checkHealth(ctx)
+		for _, service := range []string{"", "database", "migrations", "namespaces"} {
+			res, err := r.HealthServer().Check(ctx, &grpcHealthV1.HealthCheckRequest{Service: service})
+			require.NoError(t, err)
+			assert.Equal(t, grpcHealthV1.HealthCheckResponse_SERVING, res.Status, service)
+		}
+	})
+
+	t.Run("case=pending migrations are not ready", func(t *testing.T) {
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+
+		s, err := r.MigrationStatus(ctx)
+		require.NoError(t, err)
+		migrations := s.Databases[0].Migrations
+		conn, err := r.PopConnection(ctx)
+		require.NoError(t, err)
+		require.NoError(t, conn.RawQuery("DELETE FROM "+conn.MigrationTableName()+" WHERE version = ?", migrations[len(migrations)-1].Version).Exec())
+
+		code, body := get(t, r, healthx.ReadyCheckPath)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, []string{"migrations"}, keys(body["errors"]))
+
+		code, _ = get(t, r, healthx.ReadyCheckPath+"/migrations")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		code, _ = get(t, r, healthx.ReadyCheckPath+"/database")
+		assert.Equal(t, http.StatusOK, code)
+
+		// The liveness does not depend on the ready checks.
+		code, _ = get(t, r, healthx.AliveCheckPath)
+		assert.Equal(t, http.StatusOK, code)
+
+		r.checkHealth(ctx)
+		res, err := r.HealthServer().Check(ctx, &grpcHealthV1.HealthCheckRequest{Service: "migrations"})
+		require.NoError(t, err)
+		assert.Equal(t, grpcHealthV1.HealthCheckResponse_NOT_SERVING, res.Status)
+	})
+
+	t.Run("case=unknown check", func(t *testing.T) {
+		r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+
+		code, _ := get(t, r, healthx.ReadyCheckPath+"/unknown")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func keys(m interface{}) []string {
+	var res []string
+	for k := range m.(map[string]interface{}) {
+		res = append(res, k)
+	}
+	return res
+}
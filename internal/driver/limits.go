@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+var errRequestBodyTooLarge = herodot.DefaultError{
+	CodeField:   http.StatusRequestEntityTooLarge,
+	StatusField: http.StatusText(http.StatusRequestEntityTooLarge),
+	ErrorField:  "The request body is too large.",
+}
+
+// requestBodyLimitMiddleware rejects request bodies that are larger than the
+// configured maximum.
+func (r *RegistryDefault) requestBodyLimitMiddleware(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	max := r.Config(req.Context()).MaxRequestBodySize()
+	if max > 0 && req.Body != nil {
+		if req.ContentLength > max {
+			r.Writer().WriteError(rw, req, errors.WithStack(errRequestBodyTooLarge.WithReasonf(
+				"The request body has %d bytes, but at most %d bytes are allowed by limit.max_request_body_size.", req.ContentLength, max)))
+			return
+		}
+		// The content length might not be known in advance.
+		req.Body = http.MaxBytesReader(rw, req.Body, max)
+	}
+	next(rw, req)
+}
+
+// grpcLimitOptions returns the options that apply the configured limits to a
+// gRPC server.
+func (r *RegistryDefault) grpcLimitOptions(ctx context.Context) []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if max := r.Config(ctx).MaxRequestBodySize(); max > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(max)))
+	}
+	return opts
+}
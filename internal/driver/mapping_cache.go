@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/keto/internal/persistence/sql"
+)
+
+type (
+	// mappingCacheCollector exports the statistics of the UUID mapping cache
+	// of the registry.
+	mappingCacheCollector struct {
+		r *RegistryDefault
+
+		hits, misses, evictions, entries, size *prometheus.Desc
+	}
+	mappingCacheStatser interface {
+		MappingCacheStats() (toUUID, toString sql.MappingCacheStats, ok bool)
+	}
+)
+
+var mappingCacheCollectorMx sync.Mutex
+
+func newMappingCacheCollector(r *RegistryDefault) *mappingCacheCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("keto_uuid_mapping_cache_"+name, help, []string{"direction"}, nil)
+	}
+	return &mappingCacheCollector{
+		r:         r,
+		hits:      desc("hits_total", "The total number of UUID mappings found in the cache."),
+		misses:    desc("misses_total", "The total number of UUID mappings not found in the cache."),
+		evictions: desc("evictions_total", "The total number of UUID mappings evicted because the cache was full."),
+		entries:   desc("entries", "The number of cached UUID mappings."),
+		size:      desc("size", "The maximum number of cached UUID mappings."),
+	}
+}
+
+func (c *mappingCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.hits, c.misses, c.evictions, c.entries, c.size} {
+		ch <- d
+	}
+}
+
+func (c *mappingCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s, ok := c.r.MappingManager().(mappingCacheStatser)
+	if !ok {
+		return
+	}
+	toUUID, toString, ok := s.MappingCacheStats()
+	if !ok {
+		return
+	}
+	for direction, s := range map[string]sql.MappingCacheStats{"to_uuid": toUUID, "to_string": toString} {
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, direction)
+		}
+		counter := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v, direction)
+		}
+		counter(c.hits, float64(s.Hits))
+		counter(c.misses, float64(s.Misses))
+		counter(c.evictions, float64(s.Evictions))
+		gauge(c.entries, float64(s.Entries))
+		gauge(c.size, float64(s.Size))
+	}
+}
+
+// registerMappingCacheMetrics exports the UUID mapping cache statistics like
+// registerPoolMetrics.
+func (r *RegistryDefault) registerMappingCacheMetrics() (unregister func()) {
+	mappingCacheCollectorMx.Lock()
+	defer mappingCacheCollectorMx.Unlock()
+
+	c := newMappingCacheCollector(r)
+	if err := prometheus.Register(c); err != nil {
+		r.Logger().WithError(err).Warn("Unable to export the UUID mapping cache metrics.")
+		return func() {}
+	}
+	return func() {
+		mappingCacheCollectorMx.Lock()
+		defer mappingCacheCollectorMx.Unlock()
+		prometheus.Unregister(c)
+	}
+}
@@ -0,0 +1,41 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestMappingCacheMetrics(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), WithConfig(config.KeyUUIDMappingCacheSize, 10))
+
+	ids, err := r.MappingManager().MapStringsToUUIDs(ctx, "foo")
+	require.NoError(t, err)
+	_, err = r.MappingManager().MapStringsToUUIDs(ctx, "foo")
+	require.NoError(t, err)
+	_, err = r.MappingManager().MapUUIDsToStrings(ctx, ids...)
+	require.NoError(t, err)
+
+	c := newMappingCacheCollector(r)
+	assert.Equal(t, 10, testutil.CollectAndCount(c))
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP keto_uuid_mapping_cache_hits_total The total number of UUID mappings found in the cache.
+# TYPE keto_uuid_mapping_cache_hits_total counter
+keto_uuid_mapping_cache_hits_total{direction="to_string"} 1
+keto_uuid_mapping_cache_hits_total{direction="to_uuid"} 1
+`), "keto_uuid_mapping_cache_hits_total"))
+
+	unregister := r.registerMappingCacheMetrics()
+	unregister()
+
+	r = NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), WithConfig(config.KeyUUIDMappingCacheSize, 0))
+	assert.Zero(t, testutil.CollectAndCount(newMappingCacheCollector(r)))
+}
@@ -0,0 +1,156 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ory/x/dbal"
+	"github.com/pkg/errors"
+)
+
+// memorySnapshotEnabled returns whether the in-memory database is snapshotted.
+func (r *RegistryDefault) memorySnapshotEnabled(ctx context.Context) bool {
+	return r.Config(ctx).MemorySnapshotPath() != "" && dbal.IsMemorySQLite(r.Config(ctx).DSN())
+}
+
+// WriteMemorySnapshot writes the in-memory database to the snapshot file. The
+// file is replaced atomically, so a crash while writing keeps the previous
+// snapshot.
+func (r *RegistryDefault) WriteMemorySnapshot(ctx context.Context) error {
+	path := r.Config(ctx).MemorySnapshotPath()
+	conn, err := r.PopConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	// VACUUM INTO fails if the file exists.
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+	if err := conn.WithContext(ctx).RawQuery("VACUUM INTO ?", tmp).Exec(); err != nil {
+		return errors.Wrap(err, "could not snapshot the in-memory database")
+	}
+	return errors.WithStack(os.Rename(tmp, path))
+}
+
+// restoreMemorySnapshot copies the schema and the data of the snapshot file
+// into the in-memory database, if both exist and the database is still empty.
+// The migrations run afterwards, so snapshots of older versions are upgraded.
+func (r *RegistryDefault) restoreMemorySnapshot(ctx context.Context) error {
+	path := r.Config(ctx).MemorySnapshotPath()
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		r.Logger().WithField("path", path).Info("There is no snapshot of the in-memory database yet.")
+		return nil
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// ATTACH only applies to one connection, so the database is opened
+	// again, which shares the in-memory database through the shared cache.
+	// The standard connection keeps it alive afterwards.
+	if _, err := r.PopConnection(ctx); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", strings.TrimPrefix(r.Config(ctx).DSN(), "sqlite://"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	var tables int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables); err != nil {
+		return errors.WithStack(err)
+	}
+	if tables > 0 {
+		r.Logger().WithField("path", path).Warn("The in-memory database is not empty, so the snapshot is not restored.")
+		return nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return errors.Wrapf(err, "could not open the snapshot %s", path)
+	}
+	defer func() { _, _ = conn.ExecContext(context.Background(), "DETACH DATABASE snapshot") }()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The foreign keys are checked on commit, when all rows are copied.
+	if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+		return errors.WithStack(err)
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT type, name, sql FROM snapshot.sqlite_master
+WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END`)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	type object struct{ typ, name, sql string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.typ, &o.name, &o.sql); err != nil {
+			_ = rows.Close()
+			return errors.WithStack(err)
+		}
+		objects = append(objects, o)
+	}
+	if err := rows.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, o := range objects {
+		if _, err := tx.ExecContext(ctx, o.sql); err != nil {
+			return errors.Wrapf(err, "could not restore the %s %s", o.typ, o.name)
+		}
+	}
+	for _, o := range objects {
+		if o.typ != "table" {
+			continue
+		}
+		quoted := `"` + strings.ReplaceAll(o.name, `"`, `""`) + `"`
+		if _, err := tx.ExecContext(ctx, "INSERT INTO main."+quoted+" SELECT * FROM snapshot."+quoted); err != nil {
+			return errors.Wrapf(err, "could not restore the rows of the table %s", o.name)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	r.Logger().WithField("path", path).Info("Restored the in-memory database from the snapshot.")
+	return nil
+}
+
+// snapshotMemoryPeriodically snapshots the in-memory database on the
+// interval. The last snapshot is written by ServeAll after the servers shut
+// down.
+func (r *RegistryDefault) snapshotMemoryPeriodically(ctx context.Context) func() error {
+	return func() error {
+		ticker := time.NewTicker(r.Config(ctx).MemorySnapshotInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			if err := r.WriteMemorySnapshot(ctx); err != nil {
+				r.Logger().WithError(err).Error("could not snapshot the in-memory database, will retry")
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package driver_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/persistence/sql"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestMemorySnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.sqlite")
+
+	newRegistry := func(t *testing.T) *driver.RegistryDefault {
+		dsn := dbx.GetSqlite(t, dbx.SQLiteMemory)
+		// The migrations run after the snapshot is restored.
+		dsn.MigrateUp = false
+		return driver.NewTestRegistry(t, dsn,
+			driver.WithNamespaces([]*namespace.Namespace{{Name: "n"}}),
+			driver.WithConfig(config.KeyMemorySnapshotPath, path),
+		)
+	}
+
+	first := newRegistry(t)
+	tuple := &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")}
+	relationtuple.MapAndWriteTuples(t, first, tuple)
+	require.NoError(t, first.WriteMemorySnapshot(ctx))
+	// A second snapshot replaces the first one.
+	require.NoError(t, first.WriteMemorySnapshot(ctx))
+
+	second := newRegistry(t)
+	assert.Equal(t, first.Persister().(*sql.Persister).NetworkID(ctx), second.Persister().(*sql.Persister).NetworkID(ctx))
+	res, _, err := second.RelationTupleManager().GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("n")})
+	require.NoError(t, err)
+	mapped, err := second.Mapper().ToTuple(ctx, res...)
+	require.NoError(t, err)
+	assert.Equal(t, []*ketoapi.RelationTuple{tuple}, mapped)
+}
@@ -0,0 +1,72 @@
+package driver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ory/x/popx"
+	"github.com/pkg/errors"
+)
+
+// MigrationPhase is the phase of a rollout in which a migration is applied.
+//
+// Expand migrations only add to the schema, so that the previous and the new
+// version of Ory Keto can both run against it while the new version is rolled
+// out. Contract migrations remove what only the previous version used, and
+// are applied once it is no longer running. Contract migrations are marked by
+// the "-contract" suffix of their name, all other migrations are expand
+// migrations.
+type MigrationPhase string
+
+const (
+	MigrationPhaseAll      MigrationPhase = "all"
+	MigrationPhaseExpand   MigrationPhase = "expand"
+	MigrationPhaseContract MigrationPhase = "contract"
+
+	contractMigrationSuffix = "-contract"
+)
+
+// MigrationPhaseOf returns the phase of the migration with the given name.
+func MigrationPhaseOf(name string) MigrationPhase {
+	if strings.HasSuffix(name, contractMigrationSuffix) {
+		return MigrationPhaseContract
+	}
+	return MigrationPhaseExpand
+}
+
+// PhaseMigrationBox returns a migration box that only contains the migrations
+// of the phase. The contract phase can only be applied once all migrations of
+// the expand phase are applied.
+func PhaseMigrationBox(ctx context.Context, mb *popx.MigrationBox, phase MigrationPhase) (*popx.MigrationBox, error) {
+	switch phase {
+	case MigrationPhaseAll:
+		return mb, nil
+	case MigrationPhaseExpand:
+	case MigrationPhaseContract:
+		s, err := mb.Status(ctx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, m := range s {
+			if m.State == popx.Pending && MigrationPhaseOf(m.Name) == MigrationPhaseExpand {
+				return nil, errors.Errorf("the migration %s_%s of the expand phase is not applied yet, apply the expand phase first", m.Version, m.Name)
+			}
+		}
+	default:
+		return nil, errors.Errorf("unknown migration phase %q, expected one of %q, %q, or %q", phase, MigrationPhaseAll, MigrationPhaseExpand, MigrationPhaseContract)
+	}
+
+	m := *mb.Migrator
+	m.Migrations = make(map[string]popx.Migrations, len(mb.Migrations))
+	for direction, migrations := range mb.Migrations {
+		m.Migrations[direction] = make(popx.Migrations, 0, len(migrations))
+		for _, mi := range migrations {
+			if MigrationPhaseOf(mi.Name) == phase {
+				m.Migrations[direction] = append(m.Migrations[direction], mi)
+			}
+		}
+	}
+	box := *mb
+	box.Migrator = &m
+	return &box, nil
+}
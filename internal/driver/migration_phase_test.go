@@ -0,0 +1,73 @@
+package driver
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ory/x/popx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestMigrationPhases(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+	conn, err := r.PopConnection(ctx)
+	require.NoError(t, err)
+
+	// The contract migration drops the column that only the previous version
+	// uses, after a later expand migration of the same release.
+	mb, err := popx.NewMigrationBox(fstest.MapFS{
+		"10000000000001_phase-test-table.up.sql":              {Data: []byte("CREATE TABLE phase_test (id INTEGER, legacy INTEGER);")},
+		"10000000000001_phase-test-table.down.sql":            {Data: []byte("DROP TABLE phase_test;")},
+		"10000000000002_phase-test-contract.up.sql":           {Data: []byte("ALTER TABLE phase_test DROP COLUMN legacy;")},
+		"10000000000002_phase-test-contract.down.sql":         {Data: []byte("ALTER TABLE phase_test ADD COLUMN legacy INTEGER;")},
+		"10000000000003_phase-test-index.up.sql":              {Data: []byte("CREATE INDEX phase_test_idx ON phase_test (id);")},
+		"10000000000003_phase-test-index.down.sql":            {Data: []byte("DROP INDEX phase_test_idx;")},
+		"10000000000004_phase-test-cleanup-contract.up.sql":   {Data: []byte("")},
+		"10000000000004_phase-test-cleanup-contract.down.sql": {Data: []byte("")},
+	}, popx.NewMigrator(conn, r.Logger(), r.Tracer(ctx), 0))
+	require.NoError(t, err)
+
+	states := func(t *testing.T) []string {
+		s, err := mb.Status(ctx)
+		require.NoError(t, err)
+		states := make([]string, len(s))
+		for i, m := range s {
+			states[i] = m.State
+		}
+		return states
+	}
+
+	_, err = PhaseMigrationBox(ctx, mb, MigrationPhaseContract)
+	assert.ErrorContains(t, err, "10000000000001_phase-test-table")
+	_, err = PhaseMigrationBox(ctx, mb, "unknown")
+	assert.Error(t, err)
+
+	expand, err := PhaseMigrationBox(ctx, mb, MigrationPhaseExpand)
+	require.NoError(t, err)
+	require.NoError(t, expand.Up(ctx))
+	assert.Equal(t, []string{popx.Applied, popx.Pending, popx.Applied, popx.Pending}, states(t))
+
+	s, err := databaseMigrationStatus(ctx, "primary", mb)
+	require.NoError(t, err)
+	assert.True(t, s.Pending)
+	assert.False(t, s.ExpandPending)
+	assert.False(t, s.Dirty, "pending contract migrations before applied expand migrations are expected")
+	assert.Equal(t, "10000000000003", s.Version)
+
+	contract, err := PhaseMigrationBox(ctx, mb, MigrationPhaseContract)
+	require.NoError(t, err)
+	require.NoError(t, contract.Up(ctx))
+	assert.Equal(t, []string{popx.Applied, popx.Applied, popx.Applied, popx.Applied}, states(t))
+
+	// A pending migration before an applied contract migration is dirty.
+	require.NoError(t, conn.RawQuery("DELETE FROM "+conn.MigrationTableName()+" WHERE version = ?", "10000000000002").Exec())
+	s, err = databaseMigrationStatus(ctx, "primary", mb)
+	require.NoError(t, err)
+	assert.False(t, s.ExpandPending)
+	assert.True(t, s.Dirty)
+}
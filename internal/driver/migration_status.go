@@ -0,0 +1,223 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/x/popx"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+type (
+	// The migration status of the databases.
+	//
+	// swagger:model migrationStatus
+	MigrationStatus struct {
+		// Whether any of the databases has pending migrations.
+		//
+		// required: true
+		Pending bool `json:"pending"`
+		// Whether any of the databases has pending migrations of the expand
+		// phase. A new version can only be rolled out once they are applied.
+		//
+		// required: true
+		ExpandPending bool `json:"expand_pending"`
+		// Whether any of the databases is dirty.
+		//
+		// required: true
+		Dirty bool `json:"dirty"`
+		// The migration status of each database, starting with the primary
+		// one.
+		//
+		// required: true
+		Databases []*DatabaseMigrationStatus `json:"databases"`
+	}
+
+	// The migration status of one database.
+	//
+	// swagger:model databaseMigrationStatus
+	DatabaseMigrationStatus struct {
+		// The name of the database, either `primary`, `namespace-storage-<i>`,
+		// or `shard-<i>`.
+		//
+		// required: true
+		Name string `json:"name"`
+		// The version of the last applied migration. It is empty if no
+		// migration was applied yet.
+		//
+		// required: true
+		Version string `json:"version"`
+		// Whether the database has pending migrations.
+		//
+		// required: true
+		Pending bool `json:"pending"`
+		// Whether the database has pending migrations of the expand phase.
+		//
+		// required: true
+		ExpandPending bool `json:"expand_pending"`
+		// Whether a pending migration precedes an applied one, other than
+		// contract migrations that are not applied yet. This happens after an
+		// interrupted migration or if the database was migrated by a newer
+		// Ory Keto version.
+		//
+		// required: true
+		Dirty bool `json:"dirty"`
+		// All migrations of the database, oldest first.
+		//
+		// required: true
+		Migrations popx.MigrationStatuses `json:"migrations"`
+	}
+)
+
+const MigrationStatusRoute = "/admin/migrations/status"
+
+// MigrationStatus returns the migration status of the primary database and
+// all databases the relation tuples are stored in.
+func (r *RegistryDefault) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	storages, err := r.namespaceStorages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := r.shards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &MigrationStatus{Databases: make([]*DatabaseMigrationStatus, 0, 1+len(storages)+len(shards))}
+	add := func(name string, mb *popx.MigrationBox) error {
+		s, err := databaseMigrationStatus(ctx, name, mb)
+		if err != nil {
+			return err
+		}
+		res.Pending = res.Pending || s.Pending
+		res.ExpandPending = res.ExpandPending || s.ExpandPending
+		res.Dirty = res.Dirty || s.Dirty
+		res.Databases = append(res.Databases, s)
+		return nil
+	}
+
+	mb, err := r.MigrationBox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := add("primary", mb); err != nil {
+		return nil, err
+	}
+
+	type database struct {
+		name string
+		conn *pop.Connection
+	}
+	var extra []database
+	for i, s := range storages {
+		extra = append(extra, database{"namespace-storage-" + strconv.Itoa(i), s.conn})
+	}
+	for i, s := range shards {
+		extra = append(extra, database{"shard-" + strconv.Itoa(i), s.conn})
+	}
+	for _, db := range extra {
+		mb, err := r.migrationBox(ctx, db.conn)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(db.name, mb); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func databaseMigrationStatus(ctx context.Context, name string, mb *popx.MigrationBox) (*DatabaseMigrationStatus, error) {
+	statuses, err := mb.Status(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := &DatabaseMigrationStatus{Name: name, Migrations: statuses}
+	for _, m := range statuses {
+		phase := MigrationPhaseOf(m.Name)
+		switch m.State {
+		case popx.Applied:
+			s.Version = m.Version
+			// The migrations are sorted by version, so an applied migration
+			// after a pending one means they were not applied in order. Only
+			// contract migrations may be pending while later expand migrations
+			// are applied.
+			s.Dirty = s.Dirty || s.ExpandPending || (phase == MigrationPhaseContract && s.Pending)
+		case popx.Pending:
+			s.Pending = true
+			s.ExpandPending = s.ExpandPending || phase == MigrationPhaseExpand
+		}
+	}
+	if s.Migrations == nil {
+		s.Migrations = popx.MigrationStatuses{}
+	}
+	return s, nil
+}
+
+func (r *RegistryDefault) registerMigrationStatusRoute(router *x.AdminRouter) {
+	router.GET(MigrationStatusRoute, r.getMigrationStatus)
+}
+
+// swagger:route GET /admin/migrations/status write getMigrationStatus
+//
+// # Get the Migration Status
+//
+// Use this endpoint to get the migration status of the databases, for example
+// to only roll out a new version once the migrations of the expand phase are
+// applied. The database is dirty if a pending migration precedes an applied
+// one.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: migrationStatus
+//	  500: genericError
+func (r *RegistryDefault) getMigrationStatus(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	s, err := r.MigrationStatus(req.Context())
+	if err != nil {
+		r.Writer().WriteError(w, req, err)
+		return
+	}
+	r.Writer().Write(w, req, s)
+}
+
+func (r *RegistryDefault) GetMigrationStatus(ctx context.Context, _ *rts.GetMigrationStatusRequest) (*rts.GetMigrationStatusResponse, error) {
+	s, err := r.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &rts.GetMigrationStatusResponse{
+		Pending:       s.Pending,
+		ExpandPending: s.ExpandPending,
+		Dirty:         s.Dirty,
+		Databases:     make([]*rts.DatabaseMigrationStatus, len(s.Databases)),
+	}
+	for i, db := range s.Databases {
+		res.Databases[i] = &rts.DatabaseMigrationStatus{
+			Name:          db.Name,
+			Version:       db.Version,
+			Pending:       db.Pending,
+			ExpandPending: db.ExpandPending,
+			Dirty:         db.Dirty,
+			Migrations:    make([]*rts.Migration, len(db.Migrations)),
+		}
+		for j, m := range db.Migrations {
+			state := rts.MigrationState_MIGRATION_STATE_PENDING
+			if m.State == popx.Applied {
+				state = rts.MigrationState_MIGRATION_STATE_APPLIED
+			}
+			res.Databases[i].Migrations[j] = &rts.Migration{Version: m.Version, Name: m.Name, State: state}
+		}
+	}
+	return res, nil
+}
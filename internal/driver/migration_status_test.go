@@ -0,0 +1,81 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ory/x/popx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/x/dbx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestMigrationStatus(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+
+	getStatus := func(t *testing.T) *MigrationStatus {
+		ts := httptest.NewServer(r.AdminRouter(ctx))
+		defer ts.Close()
+
+		resp, err := ts.Client().Get(ts.URL + MigrationStatusRoute)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var s MigrationStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&s))
+		return &s
+	}
+
+	t.Run("case=all migrations are applied", func(t *testing.T) {
+		s := getStatus(t)
+		assert.False(t, s.Pending)
+		assert.False(t, s.Dirty)
+		require.Len(t, s.Databases, 1)
+
+		db := s.Databases[0]
+		assert.Equal(t, "primary", db.Name)
+		require.NotEmpty(t, db.Migrations)
+		assert.Equal(t, db.Migrations[len(db.Migrations)-1].Version, db.Version)
+		for _, m := range db.Migrations {
+			assert.Equal(t, popx.Applied, m.State)
+		}
+	})
+
+	t.Run("case=a pending migration before an applied one is dirty", func(t *testing.T) {
+		before := getStatus(t).Databases[0].Migrations
+		removed := before[len(before)/2]
+
+		conn, err := r.PopConnection(ctx)
+		require.NoError(t, err)
+		require.NoError(t, conn.RawQuery("DELETE FROM "+conn.MigrationTableName()+" WHERE version = ?", removed.Version).Exec())
+
+		s := getStatus(t)
+		assert.True(t, s.Pending)
+		assert.True(t, s.Dirty)
+		assert.Equal(t, before[len(before)-1].Version, s.Databases[0].Version)
+
+		res, err := r.GetMigrationStatus(ctx, &rts.GetMigrationStatusRequest{})
+		require.NoError(t, err)
+		assert.True(t, res.Pending)
+		assert.True(t, res.Dirty)
+		require.Len(t, res.Databases, 1)
+		for i, m := range res.Databases[0].Migrations {
+			expected := rts.MigrationState_MIGRATION_STATE_APPLIED
+			if i == len(before)/2 {
+				expected = rts.MigrationState_MIGRATION_STATE_PENDING
+			}
+			assert.Equal(t, expected, m.State, m.Version)
+		}
+	})
+
+	t.Run("case=the service is registered on the write API", func(t *testing.T) {
+		assert.Contains(t, r.WriteGRPCServer(ctx).GetServiceInfo(), "ory.keto.relation_tuples.v1alpha2.MigrationService")
+	})
+}
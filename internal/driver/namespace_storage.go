@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/x/dbal"
+	"github.com/ory/x/networkx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/persistence/sql"
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+type (
+	// namespaceStorage is a database that stores the relation tuples of some
+	// namespaces instead of the primary database.
+	namespaceStorage struct {
+		config.NamespaceStorage
+		conn *pop.Connection
+	}
+	// namespaceStorageDependencies are the dependencies of the persister of a
+	// namespace storage or a shard, which connects to its own database.
+	namespaceStorageDependencies struct {
+		*RegistryDefault
+		conn *pop.Connection
+	}
+)
+
+func (d *namespaceStorageDependencies) PopConnection(context.Context) (*pop.Connection, error) {
+	return d.conn, nil
+}
+
+func (d *namespaceStorageDependencies) ReadReplicaConnections(context.Context) ([]*pop.Connection, error) {
+	return nil, nil
+}
+
+// namespaceStorages returns the connections to the databases of the
+// namespace storage, which are kept for the whole time.
+func (r *RegistryDefault) namespaceStorages(ctx context.Context) ([]*namespaceStorage, error) {
+	r.dbMx.Lock()
+	defer r.dbMx.Unlock()
+
+	if r.storage == nil {
+		configured, err := r.Config(ctx).NamespaceStorage()
+		if err != nil {
+			return nil, err
+		}
+		storages := make([]*namespaceStorage, len(configured))
+		for i, s := range configured {
+			conn, err := r.popConnection(ctx, s.DSN)
+			if err != nil {
+				for _, s := range storages[:i] {
+					closeConnections(s.conn)
+				}
+				return nil, err
+			}
+			storages[i] = &namespaceStorage{NamespaceStorage: s, conn: conn}
+		}
+		r.storage = storages
+	}
+	return r.storage, nil
+}
+
+// migrateExtraDatabases applies the migrations to the databases of the
+// namespace storage and the shards. If onlyInMemory is set, only in-memory
+// databases are migrated, as they are created on startup.
+func (r *RegistryDefault) migrateExtraDatabases(ctx context.Context, onlyInMemory bool) error {
+	storages, err := r.namespaceStorages(ctx)
+	if err != nil {
+		return err
+	}
+	shards, err := r.shards(ctx)
+	if err != nil {
+		return err
+	}
+
+	dbs := make(map[string]*pop.Connection, len(storages)+len(shards))
+	for _, s := range storages {
+		dbs[s.DSN] = s.conn
+	}
+	for _, s := range shards {
+		dbs[s.dsn] = s.conn
+	}
+	for dsn, conn := range dbs {
+		if onlyInMemory && !dbal.IsMemorySQLite(dsn) {
+			continue
+		}
+		mb, err := r.migrationBox(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := mb.Up(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extraPersister returns a persister for the relation tuples of the network
+// in another database than the primary one.
+func (r *RegistryDefault) extraPersister(ctx context.Context, conn *pop.Connection, nid uuid.UUID) (*sql.Persister, error) {
+	// The relation tuples reference the network of the primary database.
+	if err := ensureNetwork(ctx, conn, nid); err != nil {
+		return nil, err
+	}
+	return sql.NewPersister(ctx, &namespaceStorageDependencies{RegistryDefault: r, conn: conn}, nid)
+}
+
+// namespaceRouter returns the relation tuple manager that routes requests to
+// the persisters of the namespace storage and to the primary manager, or nil
+// if there is no namespace storage.
+func (r *RegistryDefault) namespaceRouter(ctx context.Context, nid uuid.UUID, primary relationtuple.Manager) (*persistence.NamespaceRouter, error) {
+	storages, err := r.namespaceStorages(ctx)
+	if err != nil || len(storages) == 0 {
+		return nil, err
+	}
+
+	routed := make([]persistence.NamespaceStorage, len(storages))
+	for i, s := range storages {
+		p, err := r.extraPersister(ctx, s.conn, nid)
+		if err != nil {
+			return nil, err
+		}
+		routed[i] = persistence.NamespaceStorage{Manager: p, Namespaces: s.Namespaces}
+	}
+	return persistence.NewNamespaceRouter(primary, routed...), nil
+}
+
+// ensureNetwork creates the network in the database if it does not exist yet.
+func ensureNetwork(ctx context.Context, conn *pop.Connection, nid uuid.UUID) error {
+	exists, err := conn.WithContext(ctx).Where("id = ?", nid).Exists(&networkx.Network{})
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if !exists {
+		if err := conn.WithContext(ctx).Create(&networkx.Network{ID: nid}); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+	return nil
+}
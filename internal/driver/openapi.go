@@ -0,0 +1,45 @@
+package driver
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/keto/spec"
+)
+
+// OpenAPIPath serves the OpenAPI 3 document of the HTTP API, so that clients
+// can be generated from the running server.
+const OpenAPIPath = "/.well-known/openapi.json"
+
+// The OpenAPI 3 document describing the HTTP API.
+//
+// swagger:model openAPIDocument
+// nolint:deadcode,unused
+type openAPIDocument map[string]interface{}
+
+func (r *RegistryDefault) registerOpenAPIRoute(router *httprouter.Router) {
+	router.GET(OpenAPIPath, r.getOpenAPIDocument)
+}
+
+// swagger:route GET /.well-known/openapi.json metadata getOpenAPIDocument
+//
+// # Get the OpenAPI Document
+//
+// Use this endpoint to get the OpenAPI 3 document describing the HTTP API of
+// this version of Ory Keto, for example to generate a client.
+//
+// This endpoint does not require authentication and is served on the read,
+// write, and admin APIs.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: openAPIDocument
+func (r *RegistryDefault) getOpenAPIDocument(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(spec.API)
+}
@@ -0,0 +1,45 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+
+	for name, router := range map[string]http.Handler{
+		"read":  r.ReadRouter(ctx),
+		"write": r.WriteRouter(ctx),
+		"admin": r.AdminRouter(ctx),
+	} {
+		t.Run("api="+name, func(t *testing.T) {
+			ts := httptest.NewServer(router)
+			defer ts.Close()
+
+			resp, err := ts.Client().Get(ts.URL + OpenAPIPath)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			var doc struct {
+				OpenAPI string                            `json:"openapi"`
+				Paths   map[string]map[string]interface{} `json:"paths"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+			assert.Equal(t, "3.0.3", doc.OpenAPI)
+			assert.Contains(t, doc.Paths, OpenAPIPath)
+			assert.Contains(t, doc.Paths, MigrationStatusRoute)
+		})
+	}
+}
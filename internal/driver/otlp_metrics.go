@@ -0,0 +1,54 @@
+package driver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/otlpmetrics"
+)
+
+var namespaceReloadMetricsMx sync.Mutex
+
+// registerNamespaceReloadMetrics exports the reload counter of the namespace
+// watchers like registerPoolMetrics.
+func (r *RegistryDefault) registerNamespaceReloadMetrics() (unregister func()) {
+	namespaceReloadMetricsMx.Lock()
+	defer namespaceReloadMetricsMx.Unlock()
+
+	if err := prometheus.Register(config.NamespaceReloads); err != nil {
+		r.Logger().WithError(err).Warn("Unable to export the namespace reload metrics.")
+		return func() {}
+	}
+	return func() {
+		namespaceReloadMetricsMx.Lock()
+		defer namespaceReloadMetricsMx.Unlock()
+		prometheus.Unregister(config.NamespaceReloads)
+	}
+}
+
+// exportOTLPMetrics pushes the metrics that are served by the metrics API to
+// the configured OTLP endpoint.
+func (r *RegistryDefault) exportOTLPMetrics(ctx context.Context) func() error {
+	return func() error {
+		headers, err := r.Config(ctx).OTLPMetricsHeaders()
+		if err != nil {
+			return err
+		}
+		e, err := otlpmetrics.NewExporter(r.Config(ctx).OTLPMetricsEndpoint(), headers, map[string]string{
+			"service.name":    r.Config(ctx).TracingServiceName(),
+			"service.version": config.Version,
+		}, prometheus.DefaultGatherer)
+		if err != nil {
+			return err
+		}
+
+		r.Logger().WithField("endpoint", r.Config(ctx).OTLPMetricsEndpoint()).Info("Exporting metrics with OTLP")
+		e.Run(ctx, r.Config(ctx).OTLPMetricsInterval(), func(err error) {
+			r.Logger().WithError(err).Warn("Unable to export the metrics with OTLP.")
+		})
+		return nil
+	}
+}
@@ -0,0 +1,190 @@
+package driver
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/ory/x/dbal"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+type (
+	// poolCollector exports the statistics of the connection pools of all
+	// databases of the registry.
+	poolCollector struct {
+		r *RegistryDefault
+
+		openConnections, inUse, idle, maxOpen         *prometheus.Desc
+		waitCount, waitDuration                       *prometheus.Desc
+		maxIdleClosed, maxIdleTimeClosed, maxLifetime *prometheus.Desc
+	}
+	statser interface {
+		Stats() sql.DBStats
+	}
+)
+
+var (
+	poolCollectorMx sync.Mutex
+	// trackedPools maps the connections returned by popConnection to their
+	// connection pools.
+	trackedPools sync.Map
+)
+
+func trackPool(conn *pop.Connection, store interface{}) {
+	if s, ok := store.(statser); ok {
+		trackedPools.Store(conn, s)
+	}
+}
+
+func untrackPool(conn *pop.Connection) {
+	trackedPools.Delete(conn)
+}
+
+func poolOf(conn *pop.Connection) (statser, bool) {
+	s, ok := trackedPools.Load(conn)
+	if !ok {
+		return nil, false
+	}
+	return s.(statser), true
+}
+
+// tunePoolDSN adds the connection pool configuration to the data source
+// name. Options set in the data source name take precedence. The statement
+// timeout is set through the session variable of the database, SQLite has
+// none.
+func tunePoolDSN(dsn string, pool config.DatabasePool) string {
+	scheme, _, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return dsn
+	}
+	base, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return dsn
+	}
+
+	options := make(map[string]string)
+	// An in-memory SQLite database is deleted with its last connection.
+	if !dbal.IsMemorySQLite(dsn) {
+		options["max_conn_lifetime"] = pool.MaxConnectionLifetime.String()
+		options["max_conn_idle_time"] = pool.MaxConnectionIdleTime.String()
+	}
+	if pool.MaxOpenConnections > 0 {
+		options["max_conns"] = strconv.Itoa(pool.MaxOpenConnections)
+	}
+	if pool.MaxIdleConnections > 0 {
+		options["max_idle_conns"] = strconv.Itoa(pool.MaxIdleConnections)
+	}
+	if ms := pool.StatementTimeout.Milliseconds(); ms > 0 {
+		switch scheme {
+		case "postgres", "postgresql", "cockroach", "cockroachdb", "crdb":
+			options["statement_timeout"] = strconv.FormatInt(ms, 10)
+		case "mysql":
+			options["max_execution_time"] = strconv.FormatInt(ms, 10)
+		}
+	}
+
+	for k, v := range options {
+		if !query.Has(k) {
+			query.Set(k, v)
+		}
+	}
+	return base + "?" + query.Encode()
+}
+
+func newPoolCollector(r *RegistryDefault) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("keto_sql_"+name, help, []string{"database"}, nil)
+	}
+	return &poolCollector{
+		r:                 r,
+		openConnections:   desc("open_connections", "The number of established connections, both in use and idle."),
+		inUse:             desc("in_use_connections", "The number of connections currently in use."),
+		idle:              desc("idle_connections", "The number of idle connections."),
+		maxOpen:           desc("max_open_connections", "The maximum number of open connections."),
+		waitCount:         desc("wait_count_total", "The total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "The total number of connections closed due to the maximum idle connections."),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "The total number of connections closed due to the maximum connection idle time."),
+		maxLifetime:       desc("max_lifetime_closed_total", "The total number of connections closed due to the maximum connection lifetime."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.openConnections, c.inUse, c.idle, c.maxOpen, c.waitCount, c.waitDuration, c.maxIdleClosed, c.maxIdleTimeClosed, c.maxLifetime} {
+		ch <- d
+	}
+}
+
+// pools returns the connection pools by the name of their database.
+func (c *poolCollector) pools() map[string]statser {
+	c.r.dbMx.Lock()
+	defer c.r.dbMx.Unlock()
+
+	pools := make(map[string]statser)
+	add := func(name string, conn *pop.Connection) {
+		if s, ok := poolOf(conn); ok {
+			pools[name] = s
+		}
+	}
+	if c.r.conn != nil {
+		add("primary", c.r.conn)
+	}
+	for i, conn := range c.r.replicas {
+		add("replica-"+strconv.Itoa(i), conn)
+	}
+	for i, s := range c.r.storage {
+		add("namespace-storage-"+strconv.Itoa(i), s.conn)
+	}
+	for i, s := range c.r.shardConns {
+		add("shard-"+strconv.Itoa(i), s.conn)
+	}
+	return pools
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, pool := range c.pools() {
+		s := pool.Stats()
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, name)
+		}
+		counter := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v, name)
+		}
+		gauge(c.openConnections, float64(s.OpenConnections))
+		gauge(c.inUse, float64(s.InUse))
+		gauge(c.idle, float64(s.Idle))
+		gauge(c.maxOpen, float64(s.MaxOpenConnections))
+		counter(c.waitCount, float64(s.WaitCount))
+		counter(c.waitDuration, s.WaitDuration.Seconds())
+		counter(c.maxIdleClosed, float64(s.MaxIdleClosed))
+		counter(c.maxIdleTimeClosed, float64(s.MaxIdleTimeClosed))
+		counter(c.maxLifetime, float64(s.MaxLifetimeClosed))
+	}
+}
+
+// registerPoolMetrics exports the connection pool statistics through the
+// default Prometheus registry, which the metrics endpoint serves, until the
+// returned function is called. Only one registry per process can export
+// them.
+func (r *RegistryDefault) registerPoolMetrics() (unregister func()) {
+	poolCollectorMx.Lock()
+	defer poolCollectorMx.Unlock()
+
+	c := newPoolCollector(r)
+	if err := prometheus.Register(c); err != nil {
+		r.Logger().WithError(err).Warn("Unable to export the connection pool metrics.")
+		return func() {}
+	}
+	return func() {
+		poolCollectorMx.Lock()
+		defer poolCollectorMx.Unlock()
+		prometheus.Unregister(c)
+	}
+}
@@ -0,0 +1,75 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestTunePoolDSN(t *testing.T) {
+	pool := config.DatabasePool{
+		MaxOpenConnections:    20,
+		MaxConnectionLifetime: time.Hour,
+		MaxConnectionIdleTime: 10 * time.Minute,
+		StatementTimeout:      30 * time.Second,
+	}
+
+	for _, tc := range []struct {
+		dsn, expected string
+	}{
+		{
+			dsn:      "postgres://user:pw@host:5432/keto?sslmode=disable",
+			expected: "postgres://user:pw@host:5432/keto?max_conn_idle_time=10m0s&max_conn_lifetime=1h0m0s&max_conns=20&sslmode=disable&statement_timeout=30000",
+		},
+		{
+			dsn:      "mysql://user:pw@tcp(host:3306)/keto?max_conns=5",
+			expected: "mysql://user:pw@tcp(host:3306)/keto?max_conn_idle_time=10m0s&max_conn_lifetime=1h0m0s&max_conns=5&max_execution_time=30000",
+		},
+		{
+			dsn:      "sqlite:///var/lib/keto/db.sqlite?_fk=true",
+			expected: "sqlite:///var/lib/keto/db.sqlite?_fk=true&max_conn_idle_time=10m0s&max_conn_lifetime=1h0m0s&max_conns=20",
+		},
+		{
+			dsn:      "sqlite://file::memory:?_fk=true&cache=shared",
+			expected: "sqlite://file::memory:?_fk=true&cache=shared&max_conns=20",
+		},
+		{
+			dsn:      "memory",
+			expected: "memory",
+		},
+	} {
+		t.Run("dsn="+tc.dsn, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tunePoolDSN(tc.dsn, pool))
+		})
+	}
+}
+
+func TestPoolMetrics(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteFile), WithConfig(config.KeyDatabasePoolMaxOpenConnections, 7))
+
+	conn, err := r.PopConnection(ctx)
+	require.NoError(t, err)
+	pool, ok := poolOf(conn)
+	require.True(t, ok)
+	assert.Equal(t, 7, pool.Stats().MaxOpenConnections)
+
+	c := newPoolCollector(r)
+	assert.Equal(t, 9, testutil.CollectAndCount(c))
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP keto_sql_max_open_connections The maximum number of open connections.
+# TYPE keto_sql_max_open_connections gauge
+keto_sql_max_open_connections{database="primary"} 7
+`), "keto_sql_max_open_connections"))
+
+	unregister := r.registerPoolMetrics()
+	unregister()
+}
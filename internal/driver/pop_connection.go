@@ -10,9 +10,24 @@ import (
 	otelsql "github.com/ory/x/otelx/sql"
 	"github.com/ory/x/sqlcon"
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+
+	"github.com/ory/keto/internal/x/requestid"
 )
 
+// connectionDrainTimeout is how long old database connections are kept open
+// after a reload, so that running requests can finish.
+const connectionDrainTimeout = 30 * time.Second
+
 func (r *RegistryDefault) PopConnectionWithOpts(ctx context.Context, popOpts ...func(*pop.ConnectionDetails)) (*pop.Connection, error) {
+	return r.popConnection(ctx, r.Config(ctx).DSN(), popOpts...)
+}
+
+func (r *RegistryDefault) popConnection(ctx context.Context, dsn string, popOpts ...func(*pop.ConnectionDetails)) (*pop.Connection, error) {
+	if dsn == "" {
+		return nil, errors.New("the data source name is empty, see the logs for why it could not be resolved")
+	}
+
 	tracer := r.Tracer(ctx)
 
 	var opts []instrumentedsql.Opt
@@ -22,7 +37,7 @@ func (r *RegistryDefault) PopConnectionWithOpts(ctx context.Context, popOpts ...
 			instrumentedsql.WithOmitArgs(),
 		}
 	}
-	pool, idlePool, connMaxLifetime, connMaxIdleTime, cleanedDSN := sqlcon.ParseConnectionOptions(r.Logger(), r.Config(ctx).DSN())
+	pool, idlePool, connMaxLifetime, connMaxIdleTime, cleanedDSN := sqlcon.ParseConnectionOptions(r.Logger(), tunePoolDSN(tuneSQLiteDSN(dsn), r.Config(ctx).DatabasePool()))
 	connDetails := &pop.ConnectionDetails{
 		URL:                       sqlcon.FinalizeDSN(r.Logger(), cleanedDSN),
 		IdlePool:                  idlePool,
@@ -35,9 +50,14 @@ func (r *RegistryDefault) PopConnectionWithOpts(ctx context.Context, popOpts ...
 	for _, o := range popOpts {
 		o(connDetails)
 	}
+	if r.Config(ctx).RequestIDSQLComments() {
+		if err := commentQueries(connDetails); err != nil {
+			return nil, err
+		}
+	}
 
 	bc := backoff.NewExponentialBackOff()
-	bc.MaxElapsedTime = time.Minute * 5
+	bc.MaxElapsedTime = r.Config(ctx).DatabaseConnectTimeout()
 	bc.Reset()
 
 	var conn *pop.Connection
@@ -63,21 +83,162 @@ func (r *RegistryDefault) PopConnectionWithOpts(ctx context.Context, popOpts ...
 		return nil, errors.WithStack(err)
 	}
 
+	// The context wrapper hides the statistics of the connection pool.
+	wrapped := conn.WithContext(ctx)
+	trackPool(wrapped, conn.Store)
+
 	// Close this connection when the context is closed.
 	go func() {
 		<-ctx.Done()
+		untrackPool(wrapped)
 		conn.Close()
 	}()
 
-	return conn.WithContext(ctx), nil
+	return wrapped, nil
 }
 
-// PopConnection returns the standard connection that is kept for the whole time.
+// PopConnection returns the standard connection that is kept for the whole
+// time, or until the database configuration changes.
 func (r *RegistryDefault) PopConnection(ctx context.Context) (*pop.Connection, error) {
+	r.dbMx.Lock()
+	defer r.dbMx.Unlock()
+
 	if r.conn == nil {
-		var err error
-		r.conn, err = r.PopConnectionWithOpts(ctx)
-		return r.conn, err
+		dsn, pool := r.Config(ctx).DSN(), r.Config(ctx).DatabasePool()
+		conn, err := r.popConnection(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		r.conn, r.dsn, r.pool = conn, dsn, pool
 	}
 	return r.conn, nil
 }
+
+// ReadReplicaConnections returns the connections to the read replicas, which
+// are kept for the whole time, or until the database configuration changes.
+func (r *RegistryDefault) ReadReplicaConnections(ctx context.Context) ([]*pop.Connection, error) {
+	r.dbMx.Lock()
+	defer r.dbMx.Unlock()
+
+	if r.replicas == nil {
+		dsns := r.Config(ctx).ReadReplicaDSNs()
+		replicas, err := r.replicaConnections(ctx, dsns)
+		if err != nil {
+			return nil, err
+		}
+		r.replicas, r.replicaDSNs = replicas, dsns
+	}
+	return r.replicas, nil
+}
+
+func (r *RegistryDefault) replicaConnections(ctx context.Context, dsns []string) ([]*pop.Connection, error) {
+	replicas := make([]*pop.Connection, len(dsns))
+	for i, dsn := range dsns {
+		c, err := r.popConnection(ctx, dsn)
+		if err != nil {
+			closeConnections(replicas[:i]...)
+			return nil, err
+		}
+		replicas[i] = c
+	}
+	return replicas, nil
+}
+
+// ReloadDatabase connects to the database again if the data source names or
+// the connection pool settings changed, e.g. because the credentials were
+// rotated. The persister switches to the new connections right
+// away, and the old ones are closed after the drain timeout, so that running
+// requests can finish.
+func (r *RegistryDefault) ReloadDatabase(ctx context.Context) error {
+	r.reloadMx.Lock()
+	defer r.reloadMx.Unlock()
+
+	dsn, replicaDSNs, pool := r.Config(ctx).DSN(), r.Config(ctx).ReadReplicaDSNs(), r.Config(ctx).DatabasePool()
+	r.dbMx.Lock()
+	unchanged := r.conn == nil || (dsn == r.dsn && slices.Equal(replicaDSNs, r.replicaDSNs) && pool == r.pool)
+	r.dbMx.Unlock()
+	if unchanged || r.p == nil {
+		return nil
+	}
+
+	r.Logger().Info("The database configuration changed, reconnecting.")
+	conn, err := r.popConnection(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	replicas, err := r.replicaConnections(ctx, replicaDSNs)
+	if err != nil {
+		closeConnections(conn)
+		return err
+	}
+
+	r.dbMx.Lock()
+	old := append([]*pop.Connection{r.conn}, r.replicas...)
+	r.conn, r.replicas, r.dsn, r.replicaDSNs, r.pool = conn, replicas, dsn, replicaDSNs, pool
+	r.mb = nil
+	r.dbMx.Unlock()
+	r.p.SetConnections(conn, replicas)
+
+	go func() {
+		select {
+		case <-time.After(connectionDrainTimeout):
+		case <-ctx.Done():
+		}
+		closeConnections(old...)
+		r.Logger().Info("Closed the connections to the old database configuration.")
+	}()
+	return nil
+}
+
+// reloadDatabasePeriodically reloads the database configuration whenever
+// secrets are refreshed, so that rotated credentials are picked up. Changes of
+// the configuration file are reloaded right away.
+func (r *RegistryDefault) reloadDatabasePeriodically(ctx context.Context) func() error {
+	return func() error {
+		ticker := time.NewTicker(r.Config(ctx).SecretsRefreshInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			if err := r.ReloadDatabase(ctx); err != nil {
+				r.Logger().WithError(err).Error("could not reconnect to the database, will retry")
+			}
+		}
+	}
+}
+
+func closeConnections(conns ...*pop.Connection) {
+	for _, c := range conns {
+		if c != nil {
+			untrackPool(c)
+			_ = c.Close()
+		}
+	}
+}
+
+// commentQueries switches the connection to a driver that comments all queries
+// with the request ID. The driver is instrumented instead of pop's, because pop
+// only instruments the drivers it knows.
+func commentQueries(connDetails *pop.ConnectionDetails) error {
+	finalized := *connDetails
+	if err := finalized.Finalize(); err != nil {
+		return errors.WithStack(err)
+	}
+	var opts []instrumentedsql.Opt
+	if connDetails.UseInstrumentedDriver {
+		opts = connDetails.InstrumentedDriverOptions
+	}
+	name, err := requestid.RegisterSQLDriver(finalized.Dialect, opts...)
+	if err != nil {
+		return err
+	}
+	connDetails.Driver = name
+	connDetails.UseInstrumentedDriver = false
+	connDetails.InstrumentedDriverOptions = nil
+	return nil
+}
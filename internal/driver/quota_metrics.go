@@ -0,0 +1,27 @@
+package driver
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var quotaMetricsMx sync.Mutex
+
+// registerQuotaMetrics exports the metrics of the quota enforcer like
+// registerCheckMetrics.
+func (r *RegistryDefault) registerQuotaMetrics() (unregister func()) {
+	quotaMetricsMx.Lock()
+	defer quotaMetricsMx.Unlock()
+
+	c := r.QuotaEnforcer().Metrics()
+	if err := prometheus.Register(c); err != nil {
+		r.Logger().WithError(err).Warn("Unable to export the quota metrics.")
+		return func() {}
+	}
+	return func() {
+		quotaMetricsMx.Lock()
+		defer quotaMetricsMx.Unlock()
+		prometheus.Unregister(c)
+	}
+}
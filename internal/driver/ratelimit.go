@@ -0,0 +1,232 @@
+package driver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/healthx"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/graphql"
+	"github.com/ory/keto/internal/x/ratelimit"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const (
+	rateLimitCheck = "check"
+	rateLimitWrite = "write"
+)
+
+var errTooManyRequests = herodot.DefaultError{
+	CodeField:     http.StatusTooManyRequests,
+	StatusField:   http.StatusText(http.StatusTooManyRequests),
+	GRPCCodeField: codes.ResourceExhausted,
+	ErrorField:    "Too many requests.",
+}
+
+// rateLimiter returns the rate limiter of the endpoints, or nil if they are
+// not rate limited. The limiter is replaced when its limit changes.
+func (r *RegistryDefault) rateLimiter(ctx context.Context, endpoints string) *ratelimit.Limiter {
+	limit, ok := r.Config(ctx).RateLimit(endpoints)
+	if !ok {
+		return nil
+	}
+
+	r.rateLimitMx.Lock()
+	defer r.rateLimitMx.Unlock()
+	if r.rateLimiters == nil {
+		r.rateLimiters = make(map[string]*ratelimit.Limiter)
+	}
+	if l, ok := r.rateLimiters[endpoints]; ok && l.Limit() == limit {
+		return l
+	}
+	l := ratelimit.NewLimiter(limit)
+	r.rateLimiters[endpoints] = l
+	return l
+}
+
+// rateLimit returns an error and how long to wait if the client exceeded the
+// rate limit of the endpoints.
+func (r *RegistryDefault) rateLimit(ctx context.Context, endpoints, key string) (time.Duration, *herodot.DefaultError) {
+	l := r.rateLimiter(ctx, endpoints)
+	if l == nil {
+		return 0, nil
+	}
+	if wait, ok := l.Take(key, time.Now()); !ok {
+		retryAfter := ratelimit.RetryAfter(wait)
+		return retryAfter, errTooManyRequests.WithReasonf(
+			"The rate limit of the %s endpoints was exceeded, retry in %s.", endpoints, retryAfter)
+	}
+	return 0, nil
+}
+
+// rateLimitKey returns the key the client is rate limited by. It has to be
+// called after authentication, so that only verified subjects are keys.
+func (r *RegistryDefault) rateLimitKey(ctx context.Context, remoteAddr string, forwardedFor []string) string {
+	c := r.Config(ctx)
+	if c.RateLimitKey() == config.RateLimitKeyAPIKey {
+		if s, ok := authn.FromContext(ctx); ok {
+			return "subject:" + s.Authenticator + ":" + s.ID
+		}
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if !c.RateLimitTrustForwardedFor() {
+		return "ip:" + host
+	}
+	return "ip:" + forwardedClient(host, forwardedFor, c.RateLimitTrustedProxies())
+}
+
+// forwardedClient returns the address of the client from the X-Forwarded-For
+// headers of a request from the peer. Clients can send the header themselves,
+// so it is read from the right, and the first entry that is not a trusted
+// proxy is the client. Without trusted proxies, the last entry is the client,
+// as the proxy in front of Ory Keto appended it.
+func forwardedClient(peer string, forwardedFor []string, trusted []*net.IPNet) string {
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		for _, n := range trusted {
+			if ip != nil && n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(trusted) > 0 && !isTrusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, h := range forwardedFor {
+		for _, hop := range strings.Split(h, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		return peer
+	}
+	for i := len(hops) - 1; i > 0; i-- {
+		if !isTrusted(hops[i]) {
+			return hops[i]
+		}
+	}
+	return hops[0]
+}
+
+// rateLimitMiddleware rate limits the requests to the endpoints for which
+// limited returns true.
+func (r *RegistryDefault) rateLimitMiddleware(endpoints string, limited func(req *http.Request) bool) func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	return func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		if !limited(req) {
+			next(rw, req)
+			return
+		}
+		ctx := req.Context()
+		key := r.rateLimitKey(ctx, req.RemoteAddr, req.Header.Values("X-Forwarded-For"))
+		if retryAfter, err := r.rateLimit(ctx, endpoints, key); err != nil {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			r.Writer().WriteError(rw, req, errors.WithStack(err))
+			return
+		}
+		next(rw, req)
+	}
+}
+
+// isCheckRequest also counts GraphQL requests, which are mostly checks.
+func isCheckRequest(req *http.Request) bool {
+	switch req.URL.Path {
+	case check.RouteBase, check.OpenAPIRouteBase, graphql.RouteBase:
+		return true
+	}
+	return false
+}
+
+// isAPIRequest returns false for the health, version, status, and OpenAPI
+// endpoints, which are never rate limited.
+func isAPIRequest(req *http.Request) bool {
+	switch req.URL.Path {
+	case healthx.AliveCheckPath, healthx.ReadyCheckPath, healthx.VersionPath, StatusRoute, OpenAPIPath:
+		return false
+	}
+	return !strings.HasPrefix(req.URL.Path, healthx.ReadyCheckPath+"/")
+}
+
+// grpcRateLimitKey returns the key the client of the gRPC request is rate
+// limited by.
+func (r *RegistryDefault) grpcRateLimitKey(ctx context.Context) string {
+	var (
+		remoteAddr   string
+		forwardedFor []string
+	)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		forwardedFor = md.Get("x-forwarded-for")
+	}
+	return r.rateLimitKey(ctx, remoteAddr, forwardedFor)
+}
+
+// grpcRateLimit returns an error if the client exceeded the rate limit of the
+// endpoints, and sets the retry-after header of the response.
+func (r *RegistryDefault) grpcRateLimit(ctx context.Context, endpoints string) error {
+	retryAfter, err := r.rateLimit(ctx, endpoints, r.grpcRateLimitKey(ctx))
+	if err != nil {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(retryAfter.Seconds()))))
+		// The error is not wrapped, so that gRPC sees its status code.
+		return err
+	}
+	return nil
+}
+
+// rateLimitInterceptors return the interceptors that rate limit the methods
+// of the gRPC services.
+func (r *RegistryDefault) rateLimitInterceptors(endpoints string, services ...string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	limited := func(method string) bool {
+		for _, s := range services {
+			if strings.HasPrefix(method, "/"+s+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if limited(info.FullMethod) {
+				if err := r.grpcRateLimit(ctx, endpoints); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if limited(info.FullMethod) {
+				if err := r.grpcRateLimit(ss.Context(), endpoints); err != nil {
+					return err
+				}
+			}
+			return handler(srv, ss)
+		}
+}
+
+var (
+	checkServiceName = rts.CheckService_ServiceDesc.ServiceName
+	writeServiceName = rts.WriteService_ServiceDesc.ServiceName
+	// The Envoy package does not export the service description.
+	extAuthzServiceName = "envoy.service.auth.v3.Authorization"
+)
@@ -0,0 +1,40 @@
+package driver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+func TestForwardedClient(t *testing.T) {
+	var trusted []*net.IPNet
+	for _, s := range []string{"10.0.0.0/8", "192.168.1.1"} {
+		n, err := config.ParseTrustedProxy(s)
+		require.NoError(t, err)
+		trusted = append(trusted, n)
+	}
+
+	for _, tc := range []struct {
+		name, peer, expected string
+		forwardedFor         []string
+		trusted              []*net.IPNet
+	}{
+		{name: "no header", peer: "10.0.0.1", expected: "10.0.0.1", trusted: trusted},
+		{name: "last entry without trusted proxies", peer: "10.0.0.1", forwardedFor: []string{"1.1.1.1, 2.2.2.2"}, expected: "2.2.2.2"},
+		{name: "skips trusted proxies", peer: "10.0.0.1", forwardedFor: []string{"1.1.1.1, 2.2.2.2, 10.0.0.2", "192.168.1.1"}, expected: "2.2.2.2", trusted: trusted},
+		{name: "spoofed entries are ignored", peer: "10.0.0.1", forwardedFor: []string{"10.0.0.3, 3.3.3.3, 10.0.0.2"}, expected: "3.3.3.3", trusted: trusted},
+		{name: "untrusted peer", peer: "3.3.3.3", forwardedFor: []string{"1.1.1.1"}, expected: "3.3.3.3", trusted: trusted},
+		{name: "only trusted entries", peer: "10.0.0.1", forwardedFor: []string{"10.0.0.3, 10.0.0.2"}, expected: "10.0.0.3", trusted: trusted},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, forwardedClient(tc.peer, tc.forwardedFor, tc.trusted))
+		})
+	}
+
+	_, err := config.ParseTrustedProxy("not an address")
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ory/keto/internal/persistence"
+)
+
+// readConsistency returns the context for a read API request, which carries
+// the consistency the client requested, or the default one.
+func (r *RegistryDefault) readConsistency(ctx context.Context, requested string) (context.Context, error) {
+	if requested == "" {
+		requested = r.Config(ctx).DefaultReadConsistency()
+	}
+	c, err := persistence.ParseConsistency(requested)
+	if err != nil {
+		return nil, err
+	}
+	return persistence.WithConsistency(ctx, c), nil
+}
+
+func (r *RegistryDefault) readConsistencyMiddleware(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	ctx, err := r.readConsistency(req.Context(), req.Header.Get(persistence.ConsistencyHeader))
+	if err != nil {
+		r.Writer().WriteError(rw, req, err)
+		return
+	}
+	next(rw, req.WithContext(ctx))
+}
+
+func requestedConsistency(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vs := md.Get(persistence.ConsistencyHeader); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+func (r *RegistryDefault) readConsistencyUnaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := r.readConsistency(ctx, requestedConsistency(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (r *RegistryDefault) readConsistencyStreamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := r.readConsistency(ss.Context(), requestedConsistency(ss.Context()))
+	if err != nil {
+		return err
+	}
+	wrapped := grpcMiddleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+	return handler(srv, wrapped)
+}
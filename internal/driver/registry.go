@@ -11,11 +11,16 @@ import (
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/authz"
 	"github.com/ory/keto/internal/check"
 	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/expand"
 	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/quota"
 	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/sidecar"
 	"github.com/ory/keto/internal/x"
 )
 
@@ -28,21 +33,33 @@ type (
 		x.WriterProvider
 
 		relationtuple.ManagerProvider
+		relationtuple.MapperProvider
+		relationtuple.ClosureManagerProvider
+		relationtuple.MappingCipherManagerProvider
+		relationtuple.MappingGCManagerProvider
 		expand.EngineProvider
 		check.EngineProvider
+		authn.AuthenticatorProvider
+		authz.AuthorizerProvider
+		audit.LoggerProvider
+		quota.EnforcerProvider
+		sidecar.ReplicatorProvider
 		persistence.Migrator
 		persistence.Provider
 
 		PopConnection(ctx context.Context) (*pop.Connection, error)
 		PopConnectionWithOpts(ctx context.Context, f ...func(*pop.ConnectionDetails)) (*pop.Connection, error)
+		ReadReplicaConnections(ctx context.Context) ([]*pop.Connection, error)
 
 		HealthHandler() *healthx.Handler
+		Doctor(ctx context.Context) []DoctorCheck
 		Tracer(ctx context.Context) *otelx.Tracer
 		MetricsHandler() *prometheus.Handler
 		PrometheusManager() *prometheus.MetricsManager
 
 		ReadRouter(ctx context.Context) http.Handler
 		WriteRouter(ctx context.Context) http.Handler
+		AdminRouter(ctx context.Context) http.Handler
 
 		ReadGRPCServer(ctx context.Context) *grpc.Server
 		WriteGRPCServer(ctx context.Context) *grpc.Server
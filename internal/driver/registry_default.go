@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 	"github.com/ory/herodot"
 	"github.com/ory/x/dbal"
 	"github.com/ory/x/fsx"
@@ -20,14 +21,27 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/authz"
+	"github.com/ory/keto/internal/cdc"
 	"github.com/ory/keto/internal/check"
 	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/expand"
+	"github.com/ory/keto/internal/namespace/definition"
 	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/persistence/dynamodb"
+	"github.com/ory/keto/internal/persistence/redis"
+	"github.com/ory/keto/internal/persistence/spanner"
 	"github.com/ory/keto/internal/persistence/sql"
 	"github.com/ory/keto/internal/persistence/sql/migrations/uuidmapping"
+	"github.com/ory/keto/internal/quota"
 	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/sidecar"
 	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/awsx"
+	"github.com/ory/keto/internal/x/gcpx"
+	"github.com/ory/keto/internal/x/ratelimit"
 	"github.com/ory/keto/ketoctx"
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 )
@@ -41,6 +55,8 @@ var (
 	_ Registry                             = (*RegistryDefault)(nil)
 	_ rts.VersionServiceServer             = (*RegistryDefault)(nil)
 	_ ketoctx.ContextualizerProvider       = (*RegistryDefault)(nil)
+	_ cdc.PublisherDependencies            = (*RegistryDefault)(nil)
+	_ definition.ManagerProvider           = (*RegistryDefault)(nil)
 )
 
 type (
@@ -56,6 +72,16 @@ type (
 		ctxer  ketoctx.Contextualizer
 		mapper *relationtuple.Mapper
 
+		replicas       []*pop.Connection
+		dsn            string
+		replicaDSNs    []string
+		pool           config.DatabasePool
+		storage        []*namespaceStorage
+		shardConns     []*shard
+		rtm            relationtuple.Manager
+		mm             relationtuple.MappingManager
+		dbMx           sync.Mutex
+		reloadMx       sync.Mutex
 		initialized    sync.Once
 		healthH        *healthx.Handler
 		healthServer   *health.Server
@@ -68,10 +94,37 @@ type (
 		defaultUnaryInterceptors  []grpc.UnaryServerInterceptor
 		defaultStreamInterceptors []grpc.StreamServerInterceptor
 		defaultHttpMiddlewares    []func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+		apiInterceptors           map[string]ketoctx.GRPCInterceptors
+
+		pluginsOnce        sync.Once
+		pluginInterceptors map[string]*ketoctx.GRPCInterceptors
+		pluginsErr         error
+
+		rateLimitMx  sync.Mutex
+		rateLimiters map[string]*ratelimit.Limiter
+
+		authenticator *authn.Authenticator
+		authorizer    *authz.Authorizer
+
+		auditOnce   sync.Once
+		auditLogger *audit.Logger
+
+		quotaOnce     sync.Once
+		quotaEnforcer *quota.Enforcer
+
+		sidecarOnce       sync.Once
+		sidecarReplicator *sidecar.Replicator
+
+		// nid is the network of the deployment, which the networks of the
+		// tenants are derived from.
+		nid            uuid.UUID
+		tenantMx       sync.Mutex
+		tenantNetworks *x.LRU[uuid.UUID, struct{}]
 	}
 	Handler interface {
 		RegisterReadRoutes(r *x.ReadRouter)
 		RegisterWriteRoutes(r *x.WriteRouter)
+		RegisterAdminRoutes(r *x.AdminRouter)
 		RegisterReadGRPC(s *grpc.Server)
 		RegisterWriteGRPC(s *grpc.Server)
 	}
@@ -97,7 +150,7 @@ func (r *RegistryDefault) Config(ctx context.Context) *config.Config {
 
 func (r *RegistryDefault) HealthHandler() *healthx.Handler {
 	if r.healthH == nil {
-		r.healthH = healthx.NewHandler(r.Writer(), config.Version, healthx.ReadyCheckers{})
+		r.healthH = healthx.NewHandler(r.Writer(), config.Version, r.readyCheckers())
 	}
 
 	return r.healthH
@@ -160,6 +213,9 @@ func (r *RegistryDefault) RelationTupleManager() relationtuple.Manager {
 	if r.p == nil {
 		panic("no relation tuple manager, but expected to have one")
 	}
+	if r.rtm != nil {
+		return r.rtm
+	}
 	return r.p
 }
 
@@ -167,6 +223,77 @@ func (r *RegistryDefault) MappingManager() relationtuple.MappingManager {
 	if r.p == nil {
 		panic("no relation tuple manager, but expected to have one")
 	}
+	if r.mm != nil {
+		return r.mm
+	}
+	return r.p
+}
+
+func (r *RegistryDefault) RelationTupleHistoryManager() relationtuple.HistoryManager {
+	if r.p == nil {
+		panic("no relation tuple history manager, but expected to have one")
+	}
+	return r.p
+}
+
+func (r *RegistryDefault) RelationTupleSoftDeleteManager() relationtuple.SoftDeleteManager {
+	if r.p == nil {
+		panic("no relation tuple soft delete manager, but expected to have one")
+	}
+	return r.p
+}
+
+// RelationTupleClosureManager returns nil if the relation tuples are sharded
+// or stored in more than one database, as the closures only span the relation
+// tuples of one database.
+func (r *RegistryDefault) RelationTupleClosureManager() relationtuple.ClosureManager {
+	if cm, ok := r.RelationTupleManager().(relationtuple.ClosureManager); ok {
+		return cm
+	}
+	return nil
+}
+
+func (r *RegistryDefault) MappingCipherManager() relationtuple.MappingCipherManager {
+	if cm, ok := r.MappingManager().(relationtuple.MappingCipherManager); ok {
+		return cm
+	}
+	return nil
+}
+
+// MappingGCManager returns nil unless the relation tuples are stored in the
+// primary database, which also stores the UUID mappings.
+func (r *RegistryDefault) MappingGCManager() relationtuple.MappingGCManager {
+	if gm, ok := r.RelationTupleManager().(relationtuple.MappingGCManager); ok {
+		return gm
+	}
+	return nil
+}
+
+func (r *RegistryDefault) SchemaMigrationManager() relationtuple.SchemaMigrationManager {
+	if r.p == nil {
+		panic("no schema migration manager, but expected to have one")
+	}
+	return r.p
+}
+
+func (r *RegistryDefault) NamespaceRenameManager() relationtuple.NamespaceRenameManager {
+	if r.p == nil {
+		panic("no namespace rename manager, but expected to have one")
+	}
+	return r.p
+}
+
+func (r *RegistryDefault) NamespaceDefinitionManager() definition.Manager {
+	if r.p == nil {
+		panic("no namespace definition manager, but expected to have one")
+	}
+	return r.p
+}
+
+func (r *RegistryDefault) RelationTupleOutbox() cdc.Outbox {
+	if r.p == nil {
+		panic("no relation tuple outbox, but expected to have one")
+	}
 	return r.p
 }
 
@@ -184,6 +311,52 @@ func (r *RegistryDefault) PermissionEngine() *check.Engine {
 	return r.ce
 }
 
+func (r *RegistryDefault) AuditLogger() *audit.Logger {
+	r.auditOnce.Do(func() {
+		r.auditLogger = audit.NewLogger(r)
+	})
+	return r.auditLogger
+}
+
+func (r *RegistryDefault) QuotaEnforcer() *quota.Enforcer {
+	r.quotaOnce.Do(func() {
+		r.quotaEnforcer = quota.NewEnforcer(r, r.countQuotaRelationTuples)
+	})
+	return r.quotaEnforcer
+}
+
+func (r *RegistryDefault) SidecarReplicator() *sidecar.Replicator {
+	r.sidecarOnce.Do(func() {
+		r.sidecarReplicator = sidecar.NewReplicator(r)
+	})
+	return r.sidecarReplicator
+}
+
+// countQuotaRelationTuples counts the relation tuples of the relation tuple
+// quotas.
+func (r *RegistryDefault) countQuotaRelationTuples(ctx context.Context, namespace string) (int, error) {
+	q := &relationtuple.RelationQuery{}
+	if namespace != "" {
+		q.Namespace = &namespace
+	}
+	n, _, err := r.RelationTupleManager().CountRelationTuples(ctx, q, false)
+	return n, err
+}
+
+func (r *RegistryDefault) Authenticator() *authn.Authenticator {
+	if r.authenticator == nil {
+		r.authenticator = authn.NewAuthenticator(r)
+	}
+	return r.authenticator
+}
+
+func (r *RegistryDefault) Authorizer() *authz.Authorizer {
+	if r.authorizer == nil {
+		r.authorizer = authz.NewAuthorizer(r)
+	}
+	return r.authorizer
+}
+
 func (r *RegistryDefault) ExpandEngine() *expand.Engine {
 	if r.ee == nil {
 		r.ee = expand.NewEngine(r)
@@ -197,16 +370,7 @@ func (r *RegistryDefault) MigrationBox(ctx context.Context) (*popx.MigrationBox,
 		if err != nil {
 			return nil, err
 		}
-		namespaces, err := r.Config(ctx).NamespaceManager()
-		if err != nil {
-			return nil, err
-		}
-
-		mb, err := popx.NewMigrationBox(
-			fsx.Merge(sql.Migrations, networkx.Migrations),
-			popx.NewMigrator(c, r.Logger(), r.Tracer(ctx), 0),
-			popx.WithGoMigrations(uuidmapping.Migrations(namespaces)),
-		)
+		mb, err := r.migrationBox(ctx, c)
 		if err != nil {
 			return nil, err
 		}
@@ -216,6 +380,19 @@ func (r *RegistryDefault) MigrationBox(ctx context.Context) (*popx.MigrationBox,
 	return r.mb, nil
 }
 
+func (r *RegistryDefault) migrationBox(ctx context.Context, c *pop.Connection) (*popx.MigrationBox, error) {
+	namespaces, err := r.Config(ctx).NamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return popx.NewMigrationBox(
+		fsx.Merge(sql.Migrations, networkx.Migrations),
+		popx.NewMigrator(c, r.Logger(), r.Tracer(ctx), 0),
+		popx.WithGoMigrations(uuidmapping.Migrations(namespaces)),
+	)
+}
+
 func (r *RegistryDefault) MigrateUp(ctx context.Context) error {
 	mb, err := r.MigrationBox(ctx)
 	if err != nil {
@@ -224,6 +401,9 @@ func (r *RegistryDefault) MigrateUp(ctx context.Context) error {
 	if err := mb.Up(ctx); err != nil {
 		return err
 	}
+	if err := r.migrateExtraDatabases(ctx, false); err != nil {
+		return err
+	}
 	return r.Init(ctx)
 }
 
@@ -257,6 +437,11 @@ func (r *RegistryDefault) DetermineNetwork(ctx context.Context) (*networkx.Netwo
 
 func (r *RegistryDefault) InitWithoutNetworkID(ctx context.Context) error {
 	if dbal.IsMemorySQLite(r.Config(ctx).DSN()) {
+		if r.memorySnapshotEnabled(ctx) {
+			if err := r.restoreMemorySnapshot(ctx); err != nil {
+				return err
+			}
+		}
 		mb, err := r.MigrationBox(ctx)
 		if err != nil {
 			return err
@@ -266,7 +451,7 @@ func (r *RegistryDefault) InitWithoutNetworkID(ctx context.Context) error {
 			return err
 		}
 	}
-	return nil
+	return r.migrateExtraDatabases(ctx, true)
 }
 
 func (r *RegistryDefault) Init(ctx context.Context) (err error) {
@@ -281,10 +466,62 @@ func (r *RegistryDefault) Init(ctx context.Context) (err error) {
 				return err
 			}
 
+			r.nid = network.ID
 			r.p, err = sql.NewPersister(ctx, r, network.ID)
 			if err != nil {
 				return err
 			}
+			var primary relationtuple.Manager = r.p
+			sharded, err := r.shardRouter(ctx, network.ID)
+			if err != nil {
+				return err
+			}
+			if sharded != nil {
+				primary, r.rtm = sharded, sharded
+			}
+			router, err := r.namespaceRouter(ctx, network.ID, primary)
+			if err != nil {
+				return err
+			}
+			if router != nil {
+				r.rtm = router
+			}
+			if url := r.c.RedisURL(); url != "" {
+				c, err := redis.NewClient(ctx, url)
+				if err != nil {
+					return err
+				}
+				p := redis.NewPersister(r, c, network.ID, r.c.RedisKeyPrefix())
+				r.rtm, r.mm = p, p
+			}
+			if table := r.c.DynamoDBTable(); table != "" {
+				c, err := dynamodb.NewClient(r.c.DynamoDBEndpoint(), r.c.DynamoDBRegion(), awsx.CredentialsFromEnv())
+				if err != nil {
+					return err
+				}
+				p := dynamodb.NewPersister(r, c, table, network.ID)
+				r.rtm, r.mm = p, p
+			}
+			if database := r.c.SpannerDatabase(); database != "" {
+				c, err := spanner.NewClient(r.c.SpannerEndpoint(), database, gcpx.NewTokenSource())
+				if err != nil {
+					return err
+				}
+				p := spanner.NewPersister(r, c, network.ID)
+				r.rtm, r.mm = p, p
+			}
+
+			if r.c.NamespaceAPIEnabled() {
+				r.c.SetManagedNamespaceLoader(definition.NewLoader(r))
+			}
+			r.c.SetNamespaceAliasLoader(r.p.LoadNamespaceAliases)
+			r.c.SetDatabaseReloader(func() {
+				go func() {
+					if err := r.ReloadDatabase(ctx); err != nil {
+						r.Logger().WithError(err).Error("could not reconnect to the database")
+					}
+				}()
+			})
 
 			return nil
 		}()
@@ -9,7 +9,9 @@ import (
 	"github.com/ory/keto/ketoctx"
 
 	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/tenancy"
 	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/internal/x/requestid"
 
 	"github.com/sirupsen/logrus"
 
@@ -35,6 +37,8 @@ func NewDefaultRegistry(ctx context.Context, flags *pflag.FlagSet, withoutNetwor
 		l = newLogger(ctx)
 	}
 
+	l.Logger.AddHook(requestid.LogHook{})
+
 	c := config.New(ctx, l, nil)
 	cp, err := config.NewProvider(ctx, flags, c)
 	if err != nil {
@@ -45,10 +49,14 @@ func NewDefaultRegistry(ctx context.Context, flags *pflag.FlagSet, withoutNetwor
 	r := &RegistryDefault{
 		c:                         c,
 		l:                         l,
-		ctxer:                     options.Contextualizer(),
+		ctxer:                     tenancy.NewContextualizer(options.Contextualizer()),
 		defaultUnaryInterceptors:  options.GRPCUnaryInterceptors(),
 		defaultStreamInterceptors: options.GRPCStreamInterceptors(),
 		defaultHttpMiddlewares:    options.HTTPMiddlewares(),
+		apiInterceptors: map[string]ketoctx.GRPCInterceptors{
+			"read":  options.GRPCInterceptors("read"),
+			"write": options.GRPCInterceptors("write"),
+		},
 	}
 
 	init := r.Init
@@ -78,6 +86,12 @@ func WithNamespaces(namespaces []*namespace.Namespace) newRegistryOption {
 	}
 }
 
+func WithConfig(key string, value interface{}) newRegistryOption {
+	return func(t testing.TB, r *RegistryDefault) {
+		require.NoError(t, r.c.Set(key, value))
+	}
+}
+
 func NewTestRegistry(t testing.TB, dsn *dbx.DsnT, opts ...newRegistryOption) *RegistryDefault {
 	l := logrusx.New("Ory Keto", "testing")
 	ctx, cancel := context.WithCancel(context.Background())
@@ -94,7 +108,7 @@ func NewTestRegistry(t testing.TB, dsn *dbx.DsnT, opts ...newRegistryOption) *Re
 	r := &RegistryDefault{
 		c:     c,
 		l:     l,
-		ctxer: &ketoctx.DefaultContextualizer{},
+		ctxer: tenancy.NewContextualizer(&ketoctx.DefaultContextualizer{}),
 	}
 
 	for _, opt := range opts {
@@ -0,0 +1,28 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/dbal"
+	"github.com/spf13/pflag"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/ketoctx"
+)
+
+// NewInMemoryRegistry returns a registry with a new in-memory database and
+// the namespaces. Neither the configuration files nor any flags are read, so
+// that the registry only depends on the arguments.
+func NewInMemoryRegistry(ctx context.Context, namespaces []*namespace.Namespace, opts ...ketoctx.Option) (Registry, error) {
+	ctx = configx.ContextWithConfigOptions(ctx, configx.WithValues(map[string]interface{}{
+		// Every registry gets its own database, the shared cache of the
+		// "memory" DSN would be shared between them.
+		config.KeyDSN:        dbal.NewSQLiteInMemoryDatabase("keto-" + uuid.Must(uuid.NewV4()).String()),
+		config.KeyNamespaces: namespaces,
+		"log.level":          "error",
+	}))
+	return NewDefaultRegistry(ctx, pflag.NewFlagSet("in-memory", pflag.ContinueOnError), false, opts...)
+}
@@ -0,0 +1,71 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus/ctxlogrus"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/reqlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ory/keto/internal/x/requestid"
+)
+
+// requestIDMiddleware accepts or generates the request ID, and returns it in
+// the response header. It is also set on the request header, so that the error
+// responses carry it.
+func (r *RegistryDefault) requestIDMiddleware(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	id := requestid.FromClient(req.Header.Get(requestid.Header))
+	req.Header.Set(requestid.Header, id)
+	rw.Header().Set(requestid.Header, id)
+	next(rw, req.WithContext(requestid.Start(req.Context(), id)))
+}
+
+// requestLogMiddleware logs the requests with their ID. It has to run after
+// the request ID middleware.
+func (r *RegistryDefault) requestLogMiddleware(name string) *reqlog.Middleware {
+	m := reqlog.NewMiddlewareFromLogger(r.l, name)
+	m.Before = func(l *logrusx.Logger, req *http.Request, remoteAddr string) *logrusx.Logger {
+		return reqlog.DefaultBefore(l, req, remoteAddr).WithField(requestid.LogField, requestid.FromContext(req.Context()))
+	}
+	return m
+}
+
+// grpcRequestID accepts or generates the request ID of the gRPC call, and
+// returns the context carrying it and the response header.
+func (r *RegistryDefault) grpcRequestID(ctx context.Context) (context.Context, metadata.MD) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestid.MetadataKey); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	id = requestid.FromClient(id)
+	ctx = requestid.Start(ctx, id)
+	ctx = ctxlogrus.ToContext(ctx, r.Logger().Entry.WithField(requestid.LogField, id))
+	return ctx, metadata.Pairs(requestid.MetadataKey, id)
+}
+
+// requestIDInterceptors return the interceptors that accept or generate the
+// request ID of the gRPC calls. They have to run before the logging
+// interceptors.
+func (r *RegistryDefault) requestIDInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, header := r.grpcRequestID(ctx)
+			if err := grpc.SetHeader(ctx, header); err != nil {
+				r.Logger().WithContext(ctx).WithError(err).Warn("Unable to set the request ID header.")
+			}
+			return handler(ctx, req)
+		}, func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, header := r.grpcRequestID(ss.Context())
+			if err := ss.SetHeader(header); err != nil {
+				r.Logger().WithContext(ctx).WithError(err).Warn("Unable to set the request ID header.")
+			}
+			wrapped := grpcMiddleware.WrapServerStream(ss)
+			wrapped.WrappedContext = ctx
+			return handler(srv, wrapped)
+		}
+}
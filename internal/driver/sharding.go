@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+// shard is one of the databases the relation tuples are distributed over.
+type shard struct {
+	dsn  string
+	conn *pop.Connection
+}
+
+// shards returns the connections to the shards, which are kept for the whole
+// time.
+func (r *RegistryDefault) shards(ctx context.Context) ([]*shard, error) {
+	r.dbMx.Lock()
+	defer r.dbMx.Unlock()
+
+	if r.shardConns == nil {
+		dsns := r.Config(ctx).ShardingDSNs()
+		shards := make([]*shard, len(dsns))
+		for i, dsn := range dsns {
+			conn, err := r.popConnection(ctx, dsn)
+			if err != nil {
+				for _, s := range shards[:i] {
+					closeConnections(s.conn)
+				}
+				return nil, err
+			}
+			shards[i] = &shard{dsn: dsn, conn: conn}
+		}
+		r.shardConns = shards
+	}
+	return r.shardConns, nil
+}
+
+// shardRouter returns the relation tuple manager that distributes the
+// relation tuples over the shards, or nil if they are not sharded.
+func (r *RegistryDefault) shardRouter(ctx context.Context, nid uuid.UUID) (*persistence.ShardRouter, error) {
+	shards, err := r.shards(ctx)
+	if err != nil || len(shards) == 0 {
+		return nil, err
+	}
+
+	managers := make([]relationtuple.Manager, len(shards))
+	for i, s := range shards {
+		if managers[i], err = r.extraPersister(ctx, s.conn, nid); err != nil {
+			return nil, err
+		}
+	}
+	return persistence.NewShardRouter(persistence.ShardKey(r.Config(ctx).ShardingKey()), managers...), nil
+}
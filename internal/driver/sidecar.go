@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/sidecar"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+// changesRelationTuples returns whether the REST request writes relation
+// tuples, which only the primary of a sidecar may do.
+func changesRelationTuples(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+		return false
+	}
+	p := req.URL.Path
+	return p == relationtuple.WriteRouteBase ||
+		strings.HasPrefix(p, relationtuple.WriteRouteBase+"/") ||
+		strings.HasPrefix(p, relationtuple.NamespaceRenameRouteBase) ||
+		p == relationtuple.SchemaMigrationApplyRoute
+}
+
+// sidecarMiddleware rejects the writes of relation tuples to a sidecar, as
+// they would be overwritten by the changes of the primary.
+func (r *RegistryDefault) sidecarMiddleware(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if r.Config(req.Context()).SidecarEnabled() && changesRelationTuples(req) {
+		r.Writer().WriteError(rw, req, errors.WithStack(sidecar.ErrReadOnly))
+		return
+	}
+	next(rw, req)
+}
+
+// sidecarUnaryInterceptor rejects the calls of the gRPC write service to a
+// sidecar.
+func (r *RegistryDefault) sidecarUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if r.Config(ctx).SidecarEnabled() && isWriteServiceMethod(info.FullMethod) {
+		return nil, errors.WithStack(sidecar.ErrReadOnly)
+	}
+	return handler(ctx, req)
+}
+
+func (r *RegistryDefault) sidecarStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if r.Config(ss.Context()).SidecarEnabled() && isWriteServiceMethod(info.FullMethod) {
+		return errors.WithStack(sidecar.ErrReadOnly)
+	}
+	return handler(srv, ss)
+}
+
+func isWriteServiceMethod(method string) bool {
+	return strings.HasPrefix(method, "/"+rts.WriteService_ServiceDesc.ServiceName+"/")
+}
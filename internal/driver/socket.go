@@ -0,0 +1,33 @@
+package driver
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+// listenSocket listens on the unix socket and sets the permissions of the
+// socket file. A socket file that was left behind, e.g. after a crash, is
+// replaced.
+func listenSocket(ctx context.Context, socket *config.Socket) (net.Listener, error) {
+	if fi, err := os.Lstat(socket.Path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(socket.Path); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	l, err := (&net.ListenConfig{}).Listen(ctx, "unix", socket.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := socket.SetPermission(socket.Path); err != nil {
+		_ = l.Close()
+		return nil, errors.WithStack(err)
+	}
+	return l, nil
+}
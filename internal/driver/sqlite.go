@@ -0,0 +1,43 @@
+package driver
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ory/x/dbal"
+)
+
+// sqliteDefaults are the connection options of SQLite database files, which
+// tune them for a single node that serves concurrent requests: the write-ahead
+// log lets reads run concurrently to a write, transactions take the write lock
+// when they begin instead of failing when they upgrade to it, and waiting for
+// the lock is bounded by the busy timeout. In WAL mode, synchronous=NORMAL is
+// still safe against corruption, and only the last transactions before a power
+// loss can be rolled back. The sql persister additionally queues the
+// transactions, so that only one at a time waits for the lock.
+var sqliteDefaults = map[string]string{
+	"_journal_mode": "WAL",
+	"_synchronous":  "NORMAL",
+	"_txlock":       "immediate",
+	"_busy_timeout": "10000",
+}
+
+// tuneSQLiteDSN adds the sqliteDefaults to SQLite data source names of
+// database files. Options set in the data source name take precedence, and
+// other databases are returned as they are.
+func tuneSQLiteDSN(dsn string) string {
+	if !strings.HasPrefix(dsn, "sqlite://") || dbal.IsMemorySQLite(dsn) {
+		return dsn
+	}
+	base, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return dsn
+	}
+	for k, v := range sqliteDefaults {
+		if !query.Has(k) {
+			query.Set(k, v)
+		}
+	}
+	return base + "?" + query.Encode()
+}
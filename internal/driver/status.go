@@ -0,0 +1,138 @@
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+// The status of an Ory Keto instance. Instances with different hashes use a
+// different configuration.
+//
+// swagger:model instanceStatus
+type InstanceStatus struct {
+	// The version of Ory Keto.
+	//
+	// required: true
+	Version string `json:"version"`
+	// The commit Ory Keto was built from.
+	//
+	// required: true
+	Commit string `json:"commit"`
+	// When Ory Keto was built.
+	//
+	// required: true
+	BuildDate string `json:"build_date"`
+	// The SHA-256 hash of the namespaces, including the ones managed through
+	// the namespace administration API.
+	//
+	// required: true
+	NamespacesHash string `json:"namespaces_hash"`
+	// The SHA-256 hash of the configuration.
+	//
+	// required: true
+	ConfigHash string `json:"config_hash"`
+	// The version of the last migration applied to the primary database. It
+	// is empty if no migration was applied yet.
+	//
+	// required: true
+	MigrationVersion string `json:"migration_version"`
+}
+
+const StatusRoute = "/status"
+
+// InstanceStatus returns the status of this instance.
+func (r *RegistryDefault) InstanceStatus(ctx context.Context) (*InstanceStatus, error) {
+	nm, err := r.Config(ctx).NamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+	nn, err := nm.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	namespacesHash, err := namespace.Digest(nn)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(r.Config(ctx).Source().All())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	configHash := sha256.Sum256(raw)
+
+	mb, err := r.MigrationBox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := databaseMigrationStatus(ctx, "primary", mb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstanceStatus{
+		Version:          config.Version,
+		Commit:           config.Commit,
+		BuildDate:        config.Date,
+		NamespacesHash:   namespacesHash,
+		ConfigHash:       hex.EncodeToString(configHash[:]),
+		MigrationVersion: migrations.Version,
+	}, nil
+}
+
+func (r *RegistryDefault) registerStatusRoute(router *httprouter.Router) {
+	router.GET(StatusRoute, r.getStatus)
+}
+
+// swagger:route GET /status metadata getStatus
+//
+// # Get the Instance Status
+//
+// Use this endpoint to get the version of Ory Keto, hashes of its namespaces
+// and configuration, and the version of the last applied migration, for
+// example to detect instances with a different configuration.
+//
+// This endpoint does not require authentication and is served on the read,
+// write, and admin APIs.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: instanceStatus
+//	  500: genericError
+func (r *RegistryDefault) getStatus(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	s, err := r.InstanceStatus(req.Context())
+	if err != nil {
+		r.Writer().WriteError(w, req, err)
+		return
+	}
+	r.Writer().Write(w, req, s)
+}
+
+func (r *RegistryDefault) GetStatus(ctx context.Context, _ *rts.GetStatusRequest) (*rts.GetStatusResponse, error) {
+	s, err := r.InstanceStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rts.GetStatusResponse{
+		Version:          s.Version,
+		Commit:           s.Commit,
+		BuildDate:        s.BuildDate,
+		NamespacesHash:   s.NamespacesHash,
+		ConfigHash:       s.ConfigHash,
+		MigrationVersion: s.MigrationVersion,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/x/dbx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestInstanceStatus(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), WithNamespaces([]*namespace.Namespace{{Name: "files"}, {Name: "groups"}}))
+
+	getStatus := func(t *testing.T) *InstanceStatus {
+		ts := httptest.NewServer(r.ReadRouter(ctx))
+		defer ts.Close()
+
+		resp, err := ts.Client().Get(ts.URL + StatusRoute)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var s InstanceStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&s))
+		return &s
+	}
+
+	initial := getStatus(t)
+	assert.Equal(t, config.Version, initial.Version)
+	assert.Len(t, initial.NamespacesHash, 64)
+	assert.Len(t, initial.ConfigHash, 64)
+
+	migrations, err := r.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrations.Databases[0].Version, initial.MigrationVersion)
+
+	t.Run("case=gRPC", func(t *testing.T) {
+		res, err := r.GetStatus(ctx, &rts.GetStatusRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, initial.NamespacesHash, res.NamespacesHash)
+		assert.Equal(t, initial.ConfigHash, res.ConfigHash)
+		assert.Equal(t, initial.MigrationVersion, res.MigrationVersion)
+	})
+
+	t.Run("case=the order of the namespaces does not matter", func(t *testing.T) {
+		require.NoError(t, r.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "groups"}, {Name: "files"}}))
+
+		s := getStatus(t)
+		assert.Equal(t, initial.NamespacesHash, s.NamespacesHash)
+		assert.NotEqual(t, initial.ConfigHash, s.ConfigHash)
+	})
+
+	t.Run("case=the relations are part of the namespaces hash", func(t *testing.T) {
+		require.NoError(t, r.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "groups"}, {Name: "files", Relations: []ast.Relation{{Name: "viewer"}}}}))
+
+		assert.NotEqual(t, initial.NamespacesHash, getStatus(t).NamespacesHash)
+	})
+}
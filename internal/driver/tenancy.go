@@ -0,0 +1,217 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/tenancy"
+	"github.com/ory/keto/internal/x"
+)
+
+type (
+	// tenantRecord records a tenant of the deployment, so that background
+	// jobs can cover its network.
+	tenantRecord struct {
+		// ID is the network of the tenant.
+		ID uuid.UUID `db:"id"`
+		// NetworkID is the network of the deployment.
+		NetworkID uuid.UUID `db:"nid"`
+		Name      string    `db:"name"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+)
+
+var _ tenancy.ContextsProvider = (*RegistryDefault)(nil)
+
+// tenantNetworkCacheSize is the number of tenant networks that are known to
+// exist.
+const tenantNetworkCacheSize = 10000
+
+func (tenantRecord) TableName() string {
+	return "keto_tenants"
+}
+
+// tenantContext returns the context of the request with its tenant, which is
+// the one the client is bound to, or the requested one if the client can
+// access all tenants. Anonymous requests and requests of clients that are not
+// bound to a tenant are rejected. The network of the tenant is created first
+// if necessary. Without tenancy, the context is returned as is.
+func (r *RegistryDefault) tenantContext(ctx context.Context, requested string) (context.Context, error) {
+	c := r.Config(ctx)
+	if !c.TenancyEnabled() {
+		return ctx, nil
+	}
+
+	s, ok := authn.FromContext(ctx)
+	if !ok || s.Tenant == "" {
+		return nil, errors.WithStack(tenancy.ErrUnboundClient)
+	}
+	tenant := requested
+	if s.Tenant != tenancy.AnyTenant {
+		if requested != "" && requested != s.Tenant {
+			return nil, errors.WithStack(tenancy.ErrForeignTenant)
+		}
+		tenant = s.Tenant
+	}
+	switch {
+	case tenant == "":
+		return nil, errors.WithStack(tenancy.ErrMissingTenant.WithReasonf("A tenant has to be provided in the %s header.", c.TenancyHeader()))
+	case !tenancy.Valid(tenant):
+		return nil, errors.WithStack(tenancy.ErrInvalidTenant)
+	case !c.TenancyAllowed(tenant):
+		return nil, errors.WithStack(tenancy.ErrUnknownTenant)
+	}
+
+	ctx = tenancy.NewContext(ctx, tenant)
+	if err := r.ensureTenantNetwork(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// ensureTenantNetwork creates the network of the tenant of the context in the
+// primary database and the databases of the namespace storage and the shards,
+// as their relation tuples reference it, and records the tenant. The most
+// recently used networks that exist are remembered.
+func (r *RegistryDefault) ensureTenantNetwork(ctx context.Context, tenant string) error {
+	nid := r.Contextualizer().Network(ctx, r.nid)
+
+	r.tenantMx.Lock()
+	defer r.tenantMx.Unlock()
+
+	if r.tenantNetworks == nil {
+		r.tenantNetworks = x.NewLRU[uuid.UUID, struct{}](tenantNetworkCacheSize, 0)
+	}
+	if _, ok := r.tenantNetworks.Get(nid); ok {
+		return nil
+	}
+
+	conns := []*pop.Connection{r.Persister().Connection(ctx)}
+	storages, err := r.namespaceStorages(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range storages {
+		conns = append(conns, s.conn)
+	}
+	shards, err := r.shards(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range shards {
+		conns = append(conns, s.conn)
+	}
+	for _, conn := range conns {
+		if err := ensureNetwork(ctx, conn, nid); err != nil {
+			return err
+		}
+	}
+	if err := r.recordTenant(ctx, conns[0], nid, tenant); err != nil {
+		return err
+	}
+
+	r.tenantNetworks.Add(nid, struct{}{})
+	return nil
+}
+
+// recordTenant records the tenant in the network of the deployment, unless
+// another instance did already.
+func (r *RegistryDefault) recordTenant(ctx context.Context, conn *pop.Connection, nid uuid.UUID, tenant string) error {
+	conn = conn.WithContext(ctx)
+	exists, err := conn.Where("id = ?", nid).Exists(&tenantRecord{})
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if exists {
+		return nil
+	}
+	err = sqlcon.HandleError(conn.Create(&tenantRecord{
+		ID:        nid,
+		NetworkID: r.Contextualizer().Network(tenancy.NewContext(ctx, ""), r.nid),
+		Name:      tenant,
+		CreatedAt: time.Now().UTC(),
+	}))
+	if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return nil
+	}
+	return err
+}
+
+// TenantContexts returns the given context, and a context for every recorded
+// tenant if tenancy is enabled.
+func (r *RegistryDefault) TenantContexts(ctx context.Context) ([]context.Context, error) {
+	ctxs := []context.Context{ctx}
+	if !r.Config(ctx).TenancyEnabled() {
+		return ctxs, nil
+	}
+
+	var tenants []tenantRecord
+	if err := r.Persister().Connection(ctx).Where("nid = ?", r.Contextualizer().Network(ctx, r.nid)).Order("name").All(&tenants); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	for _, t := range tenants {
+		ctxs = append(ctxs, tenancy.NewContext(ctx, t.Name))
+	}
+	return ctxs, nil
+}
+
+// tenancyMiddleware adds the tenant to the context of the requests to the API.
+// It has to run after the authentication middleware.
+func (r *RegistryDefault) tenancyMiddleware(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if !isAPIRequest(req) {
+		next(rw, req)
+		return
+	}
+	ctx, err := r.tenantContext(req.Context(), req.Header.Get(r.Config(req.Context()).TenancyHeader()))
+	if err != nil {
+		r.Writer().WriteError(rw, req, err)
+		return
+	}
+	next(rw, req.WithContext(ctx))
+}
+
+// grpcTenantContext adds the tenant of the gRPC call to the context.
+func (r *RegistryDefault) grpcTenantContext(ctx context.Context, method string) (context.Context, error) {
+	if isAuthnExempt(method) {
+		return ctx, nil
+	}
+	var requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(strings.ToLower(r.Config(ctx).TenancyHeader())); len(v) > 0 {
+			requested = v[0]
+		}
+	}
+	return r.tenantContext(ctx, requested)
+}
+
+// tenancyInterceptors return the interceptors that add the tenant to the
+// context of the gRPC calls. They have to run after the authentication
+// interceptors.
+func (r *RegistryDefault) tenancyInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := r.grpcTenantContext(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := r.grpcTenantContext(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			wrapped := grpcMiddleware.WrapServerStream(ss)
+			wrapped.WrappedContext = ctx
+			return handler(srv, wrapped)
+		}
+}
@@ -0,0 +1,144 @@
+package driver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/watcherx"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// tlsReloadDelay is how long the reloader waits after a change to the
+// certificate files before it loads them, so that a certificate and key that
+// are written one after the other are loaded together.
+const tlsReloadDelay = 200 * time.Millisecond
+
+var errTLSDisabled = errors.New("TLS was disabled, which requires a restart")
+
+// tlsReloader holds the TLS configuration of an API and loads it again when
+// the certificate files change or the process receives SIGHUP, so that
+// rotated certificates are used without a restart. Established connections
+// keep the configuration of their handshake.
+type tlsReloader struct {
+	iface   string
+	load    func() (*tls.Config, error)
+	current atomic.Value // *tls.Config
+	l       *logrusx.Logger
+}
+
+// tlsConfig returns the TLS configuration of the API, or nil if TLS is
+// disabled. The certificates are reloaded until the context is done.
+func (r *RegistryDefault) tlsConfig(ctx context.Context, iface string) (*tls.Config, error) {
+	cfg, err := r.Config(ctx).TLS(iface)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+
+	rl := &tlsReloader{
+		iface: iface,
+		load:  func() (*tls.Config, error) { return r.Config(ctx).TLS(iface) },
+		l:     r.Logger(),
+	}
+	rl.store(cfg)
+	go rl.watch(ctx, r.Config(ctx).TLSFiles(iface))
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return rl.current.Load().(*tls.Config), nil
+		},
+		// The HTTP server requires a certificate getter, even though all
+		// handshakes use the configuration returned above.
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &rl.current.Load().(*tls.Config).Certificates[0], nil
+		},
+	}, nil
+}
+
+func (rl *tlsReloader) store(cfg *tls.Config) {
+	cfg = cfg.Clone()
+	// The REST and gRPC APIs share the port, so both protocols are offered.
+	cfg.NextProtos = []string{"h2", "http/1.1"}
+	rl.current.Store(cfg)
+}
+
+func (rl *tlsReloader) reload() {
+	cfg, err := rl.load()
+	if err == nil && cfg == nil {
+		err = errTLSDisabled
+	}
+	if err != nil {
+		rl.l.WithError(err).WithField("endpoint", rl.iface).Error("Unable to reload the TLS certificate, keeping the previous one.")
+		return
+	}
+	rl.store(cfg)
+	rl.l.WithField("endpoint", rl.iface).Info("Reloaded the TLS certificate.")
+}
+
+// watch reloads the configuration when one of the files changes or the
+// process receives SIGHUP.
+func (rl *tlsReloader) watch(ctx context.Context, files []string) {
+	events := make(watcherx.EventChannel)
+	for _, f := range files {
+		// The watcher closes its channel when it stops, so each file needs its
+		// own one.
+		ec := make(watcherx.EventChannel)
+		if _, err := watcherx.WatchFile(ctx, f, ec); err != nil {
+			rl.l.WithError(err).WithField("file", f).Warn("Unable to watch the TLS certificate file, send SIGHUP to reload it.")
+			continue
+		}
+		go func() {
+			for e := range ec {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			rl.reload()
+		case e := <-events:
+			if err, ok := e.(*watcherx.ErrorEvent); ok {
+				rl.l.WithError(err).WithField("file", e.Source()).Warn("Received error while watching the TLS certificate file.")
+				continue
+			}
+			delay = time.After(tlsReloadDelay)
+		case <-delay:
+			delay = nil
+			rl.reload()
+		}
+	}
+}
+
+// withTLS wraps the listener so that connections are served over TLS, if the
+// configuration is set. The TLS connection is terminated before the
+// connections are multiplexed, so the HTTP server does not see it and would
+// only speak HTTP/1.1. Because clients negotiate HTTP/2 through ALPN, the
+// handler is wrapped to serve HTTP/2 on the terminated connections as well.
+func withTLS(l net.Listener, tlsConfig *tls.Config, router http.Handler) (net.Listener, http.Handler) {
+	if tlsConfig == nil {
+		return l, router
+	}
+	return tls.NewListener(l, tlsConfig), h2c.NewHandler(router, &http2.Server{})
+}
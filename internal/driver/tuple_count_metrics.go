@@ -0,0 +1,140 @@
+package driver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+type (
+	// tupleCountCollector exports the relation tuple counts of the last
+	// run of countRelationTuples. The counts of a run replace all previous
+	// ones, so that deleted namespaces are not exported anymore.
+	tupleCountCollector struct {
+		sync.Mutex
+		namespaces map[string]int
+		relations  map[namespaceRelation]int
+
+		perNamespace, perRelation *prometheus.Desc
+	}
+	namespaceRelation struct {
+		namespace, relation string
+	}
+)
+
+var tupleCountCollectorMx sync.Mutex
+
+func newTupleCountCollector() *tupleCountCollector {
+	return &tupleCountCollector{
+		perNamespace: prometheus.NewDesc("keto_relation_tuples", "The number of relation tuples per namespace.", []string{"namespace"}, nil),
+		perRelation:  prometheus.NewDesc("keto_relation_tuples_per_relation", "The number of relation tuples per namespace and relation.", []string{"namespace", "relation"}, nil),
+	}
+}
+
+func (c *tupleCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.perNamespace
+	ch <- c.perRelation
+}
+
+func (c *tupleCountCollector) Collect(ch chan<- prometheus.Metric) {
+	c.Lock()
+	defer c.Unlock()
+
+	for n, count := range c.namespaces {
+		ch <- prometheus.MustNewConstMetric(c.perNamespace, prometheus.GaugeValue, float64(count), n)
+	}
+	for nr, count := range c.relations {
+		ch <- prometheus.MustNewConstMetric(c.perRelation, prometheus.GaugeValue, float64(count), nr.namespace, nr.relation)
+	}
+}
+
+func (c *tupleCountCollector) set(namespaces map[string]int, relations map[namespaceRelation]int) {
+	c.Lock()
+	defer c.Unlock()
+	c.namespaces, c.relations = namespaces, relations
+}
+
+// registerTupleCountMetrics exports the relation tuple counts like
+// registerPoolMetrics.
+func (r *RegistryDefault) registerTupleCountMetrics(c *tupleCountCollector) (unregister func()) {
+	tupleCountCollectorMx.Lock()
+	defer tupleCountCollectorMx.Unlock()
+
+	if err := prometheus.Register(c); err != nil {
+		r.Logger().WithError(err).Warn("Unable to export the relation tuple counts.")
+		return func() {}
+	}
+	return func() {
+		tupleCountCollectorMx.Lock()
+		defer tupleCountCollectorMx.Unlock()
+		prometheus.Unregister(c)
+	}
+}
+
+// countRelationTuples counts the relation tuples of all namespaces, and of
+// their relations if configured.
+func (r *RegistryDefault) countRelationTuples(ctx context.Context, c *tupleCountCollector) error {
+	cfg := r.Config(ctx).TupleCountMetrics()
+	nm, err := r.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	nn, err := nm.Namespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	namespaces := make(map[string]int, len(nn))
+	relations := make(map[namespaceRelation]int)
+	for _, n := range nn {
+		name := n.Name
+		count, _, err := r.RelationTupleManager().CountRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &name}, cfg.Estimate)
+		if err != nil {
+			return err
+		}
+		namespaces[name] = count
+
+		if !cfg.PerRelation {
+			continue
+		}
+		for _, rel := range n.Relations {
+			relation := rel.Name
+			count, _, err := r.RelationTupleManager().CountRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &name, Relation: &relation}, cfg.Estimate)
+			if err != nil {
+				return err
+			}
+			relations[namespaceRelation{namespace: name, relation: relation}] = count
+		}
+	}
+
+	c.set(namespaces, relations)
+	return nil
+}
+
+// countRelationTuplesPeriodically exports the relation tuple counts, which
+// are updated every configured interval.
+func (r *RegistryDefault) countRelationTuplesPeriodically(ctx context.Context) func() error {
+	return func() error {
+		c := newTupleCountCollector()
+		defer r.registerTupleCountMetrics(c)()
+
+		ticker := time.NewTicker(r.Config(ctx).TupleCountMetrics().Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.countRelationTuples(ctx, c); err != nil && ctx.Err() == nil {
+				r.Logger().WithError(err).Error("could not count the relation tuples, will retry")
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
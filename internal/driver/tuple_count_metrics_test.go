@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestTupleCountMetrics(t *testing.T) {
+	ctx := context.Background()
+	r := NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), WithNamespaces([]*namespace.Namespace{
+		{Name: "docs", Relations: []ast.Relation{{Name: "viewer"}, {Name: "owner"}}},
+		{Name: "groups"},
+	}))
+
+	relationtuple.MapAndWriteTuples(t, r,
+		&ketoapi.RelationTuple{Namespace: "docs", Object: "a", Relation: "viewer", SubjectID: x.Ptr("alice")},
+		&ketoapi.RelationTuple{Namespace: "docs", Object: "b", Relation: "viewer", SubjectID: x.Ptr("alice")},
+		&ketoapi.RelationTuple{Namespace: "docs", Object: "a", Relation: "owner", SubjectID: x.Ptr("bob")},
+	)
+
+	c := newTupleCountCollector()
+	require.NoError(t, r.countRelationTuples(ctx, c))
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP keto_relation_tuples The number of relation tuples per namespace.
+# TYPE keto_relation_tuples gauge
+keto_relation_tuples{namespace="docs"} 3
+keto_relation_tuples{namespace="groups"} 0
+`)))
+
+	t.Run("case=per relation", func(t *testing.T) {
+		require.NoError(t, r.Config(ctx).Set(config.KeyTupleCountMetricsPerRelation, true))
+		require.NoError(t, r.countRelationTuples(ctx, c))
+		require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP keto_relation_tuples_per_relation The number of relation tuples per namespace and relation.
+# TYPE keto_relation_tuples_per_relation gauge
+keto_relation_tuples_per_relation{namespace="docs",relation="owner"} 1
+keto_relation_tuples_per_relation{namespace="docs",relation="viewer"} 2
+`), "keto_relation_tuples_per_relation"))
+	})
+
+	t.Run("case=removed namespaces are not exported", func(t *testing.T) {
+		require.NoError(t, r.Config(ctx).Set(config.KeyTupleCountMetricsPerRelation, false))
+		require.NoError(t, r.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "docs"}}))
+		require.NoError(t, r.countRelationTuples(ctx, c))
+		require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP keto_relation_tuples The number of relation tuples per namespace.
+# TYPE keto_relation_tuples gauge
+keto_relation_tuples{namespace="docs"} 3
+`)))
+	})
+}
@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ory/x/cmdx"
+	"github.com/phayes/freeport"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/cmd"
+	cliclient "github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestServeAdminAPI(t *testing.T) {
+	t.Parallel()
+
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		config.KeyAdminAPIEnabled: true,
+		config.KeyAdminAPIHost:    "127.0.0.1",
+		config.KeyAdminAPIPort:    port,
+		config.KeyNamespaces:      []*namespace.Namespace{{Name: "docs"}},
+	})
+
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	adminURL := "http://" + reg.Config(ctx).AdminAPIListenOn()
+	writeURL := "http://" + reg.Config(ctx).WriteAPIListenOn()
+	for !healthReady(t, adminURL) || !healthReady(t, writeURL) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Run("case=operational endpoints are only served on the admin API", func(t *testing.T) {
+		resp, err := http.Get(adminURL + namespacehandler.RouteBase)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = http.Get(writeURL + namespacehandler.RouteBase)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("case=CLI uses the admin remote", func(t *testing.T) {
+		c := &cmdx.CommandExecuter{
+			New: func() *cobra.Command { return cmd.NewRootCmd(nil) },
+			Ctx: ctx,
+		}
+		stdOut := c.ExecNoErr(t, "namespace", "export",
+			"--"+cliclient.FlagWriteRemote, reg.Config(ctx).WriteAPIListenOn(),
+			"--"+cliclient.FlagAdminRemote, reg.Config(ctx).AdminAPIListenOn())
+		assert.Contains(t, stdOut, "class docs")
+
+		c.ExecExpectedErr(t, "namespace", "export",
+			"--"+cliclient.FlagWriteRemote, reg.Config(ctx).WriteAPIListenOn())
+	})
+}
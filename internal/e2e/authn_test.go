@@ -0,0 +1,113 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/healthx"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/cmd"
+	cliclient "github.com/ory/keto/cmd/client"
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x/dbx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestServeAuthentication(t *testing.T) {
+	t.Parallel()
+
+	const apiKey = "a-sufficiently-long-key"
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		config.KeyNamespaces:   []*namespace.Namespace{{Name: "docs"}},
+		config.KeyAuthnAPIKeys: []map[string]interface{}{{"id": "ci", "key": apiKey}},
+		config.KeyAuthnRules: []map[string]interface{}{
+			{"apis": []string{"read"}, "path": check.RouteBase, "authenticators": []string{"anonymous"}},
+		},
+	})
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	readURL := "http://" + reg.Config(ctx).ReadAPIListenOn()
+	writeURL := "http://" + reg.Config(ctx).WriteAPIListenOn()
+	for !healthReady(t, readURL) || !healthReady(t, writeURL) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	do := func(t *testing.T, method, url, token string, body []byte) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("case=REST", func(t *testing.T) {
+		body := []byte(`{"namespace":"docs","object":"readme","relation":"view","subject_id":"alice"}`)
+		resp := do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, "", body)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+		assert.Equal(t, http.StatusUnauthorized, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, "wrong-key", body).StatusCode)
+		assert.Equal(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, apiKey, body).StatusCode)
+
+		assert.Equal(t, http.StatusUnauthorized, do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+"?namespace=docs", "", nil).StatusCode)
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+"?namespace=docs", apiKey, nil).StatusCode)
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, readURL+check.RouteBase+"?namespace=docs&object=readme&relation=view&subject_id=alice", "", nil).StatusCode)
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, writeURL+healthx.ReadyCheckPath, "", nil).StatusCode)
+	})
+
+	t.Run("case=gRPC", func(t *testing.T) {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).WriteAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		client := rts.NewWriteServiceClient(conn)
+		req := &rts.TransactRelationTuplesRequest{RelationTupleDeltas: []*rts.RelationTupleDelta{{
+			Action:        rts.RelationTupleDelta_ACTION_INSERT,
+			RelationTuple: &rts.RelationTuple{Namespace: "docs", Object: "readme", Relation: "edit", Subject: rts.NewSubjectID("alice")},
+		}}}
+		_, err = client.TransactRelationTuples(ctx, req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "%+v", err)
+
+		_, err = client.TransactRelationTuples(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+apiKey), req)
+		require.NoError(t, err)
+
+		_, err = grpcHealthV1.NewHealthClient(conn).Check(ctx, &grpcHealthV1.HealthCheckRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("case=CLI sends the bearer token", func(t *testing.T) {
+		c := &cmdx.CommandExecuter{
+			New: func() *cobra.Command { return cmd.NewRootCmd(nil) },
+			Ctx: ctx,
+		}
+		remote := "--" + cliclient.FlagReadRemote
+		stdErr := c.ExecExpectedErr(t, "relation-tuple", "get", "--namespace", "docs", remote, reg.Config(ctx).ReadAPIListenOn())
+		assert.Contains(t, stdErr, codes.Unauthenticated.String())
+
+		stdOut := c.ExecNoErr(t, "relation-tuple", "get", "--namespace", "docs", remote, reg.Config(ctx).ReadAPIListenOn(),
+			"--"+cliclient.FlagBearerToken, apiKey)
+		assert.Contains(t, stdOut, "readme")
+	})
+}
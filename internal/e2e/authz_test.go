@@ -0,0 +1,146 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/phayes/freeport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestServeMetaPermissions(t *testing.T) {
+	t.Parallel()
+
+	const (
+		rootKey = "the-key-of-the-root-client"
+		teamKey = "the-key-of-the-team-client"
+	)
+	adminPort, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		config.KeyAdminAPIEnabled: true,
+		config.KeyAdminAPIHost:    "127.0.0.1",
+		config.KeyAdminAPIPort:    adminPort,
+		config.KeyNamespaces:      []*namespace.Namespace{{Name: "meta"}, {Name: "docs"}, {Name: "secrets"}},
+		config.KeyAuthnAPIKeys: []map[string]interface{}{
+			{"id": "root", "key": rootKey},
+			{"id": "team", "key": teamKey},
+		},
+		config.KeyMetaPermissionsNamespace:  "meta",
+		config.KeyMetaPermissionsSuperusers: []string{"root"},
+	})
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	readURL := "http://" + reg.Config(ctx).ReadAPIListenOn()
+	writeURL := "http://" + reg.Config(ctx).WriteAPIListenOn()
+	adminURL := "http://" + reg.Config(ctx).AdminAPIListenOn()
+	for !healthReady(t, readURL) || !healthReady(t, writeURL) || !healthReady(t, adminURL) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	do := func(t *testing.T, method, url, token, body string) int {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	put := func(t *testing.T, token, namespace string) int {
+		return do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, token,
+			`{"namespace":"`+namespace+`","object":"o","relation":"r","subject_id":"s"}`)
+	}
+
+	// The superuser grants the team access to the docs namespace.
+	require.Equal(t, http.StatusForbidden, put(t, teamKey, "meta"))
+	require.Equal(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, rootKey,
+		`{"namespace":"meta","object":"docs","relation":"write","subject_id":"team"}`))
+	require.Equal(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, rootKey,
+		`{"namespace":"meta","object":"docs","relation":"read","subject_id":"team"}`))
+
+	t.Run("case=REST", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, put(t, teamKey, "docs"))
+		assert.Equal(t, http.StatusForbidden, put(t, teamKey, "secrets"))
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodPatch, writeURL+relationtuple.WriteRouteBase, teamKey,
+			`[{"action":"insert","relation_tuple":{"namespace":"docs","object":"o","relation":"r","subject_id":"t"}},
+			  {"action":"insert","relation_tuple":{"namespace":"secrets","object":"o","relation":"r","subject_id":"t"}}]`))
+
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+"?namespace=docs", teamKey, ""))
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase, teamKey, ""))
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase, rootKey, ""))
+		assert.Equal(t, http.StatusOK, do(t, http.MethodPost, readURL+check.OpenAPIRouteBase, teamKey,
+			`{"namespace":"docs","object":"o","relation":"r","subject_id":"s"}`))
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodPost, readURL+check.OpenAPIRouteBase, teamKey,
+			`{"namespace":"secrets","object":"o","relation":"r","subject_id":"s"}`))
+	})
+
+	t.Run("case=other endpoints require write access to all namespaces", func(t *testing.T) {
+		for _, req := range []struct{ method, path string }{
+			{http.MethodGet, relationtuple.SnapshotRoute},
+			{http.MethodPut, relationtuple.SnapshotRoute},
+			{http.MethodPost, relationtuple.SchemaMigrationApplyRoute},
+			{http.MethodGet, relationtuple.NamespaceRenameRouteBase},
+			{http.MethodPost, relationtuple.NamespaceRenameRouteBase + "/docs/finish"},
+			{http.MethodGet, "/an/unknown/path"},
+		} {
+			t.Run("endpoint="+req.method+" "+req.path, func(t *testing.T) {
+				assert.Equal(t, http.StatusForbidden, do(t, req.method, adminURL+req.path, teamKey, ""))
+				assert.Equal(t, http.StatusForbidden, do(t, req.method, writeURL+req.path, teamKey, ""))
+			})
+		}
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, adminURL+relationtuple.SnapshotRoute, rootKey, ""))
+		assert.Equal(t, http.StatusNotFound, do(t, http.MethodGet, writeURL+relationtuple.SnapshotRoute, rootKey, ""))
+
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).WriteAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		_, err = rts.NewMigrationServiceClient(conn).GetMigrationStatus(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+teamKey), &rts.GetMigrationStatusRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "%+v", err)
+	})
+
+	t.Run("case=gRPC", func(t *testing.T) {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).ReadAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		teamCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+teamKey)
+		client := rts.NewCheckServiceClient(conn)
+		_, err = client.Check(teamCtx, &rts.CheckRequest{Tuple: &rts.RelationTuple{Namespace: "docs", Object: "o", Relation: "r", Subject: rts.NewSubjectID("s")}})
+		require.NoError(t, err)
+		_, err = client.Check(teamCtx, &rts.CheckRequest{Tuple: &rts.RelationTuple{Namespace: "secrets", Object: "o", Relation: "r", Subject: rts.NewSubjectID("s")}})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "%+v", err)
+
+		streamFirst := func(namespace *string) error {
+			stream, err := rts.NewReadServiceClient(conn).StreamRelationTuples(teamCtx, &rts.StreamRelationTuplesRequest{RelationQuery: &rts.RelationQuery{Namespace: namespace}})
+			require.NoError(t, err)
+			_, err = stream.Recv()
+			return err
+		}
+		assert.NoError(t, streamFirst(x.Ptr("docs")))
+		assert.Equal(t, codes.PermissionDenied, status.Code(streamFirst(x.Ptr("secrets"))))
+		assert.Equal(t, codes.PermissionDenied, status.Code(streamFirst(nil)))
+	})
+}
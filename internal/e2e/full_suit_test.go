@@ -120,10 +120,14 @@ func TestServeConfig(t *testing.T) {
 	t.Parallel()
 
 	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
-		"serve.read.cors.enabled":         true,
-		"serve.read.cors.debug":           true,
-		"serve.read.cors.allowed_methods": []string{http.MethodGet},
-		"serve.read.cors.allowed_origins": []string{"https://ory.sh"},
+		"serve.read.cors.enabled":            true,
+		"serve.read.cors.debug":              true,
+		"serve.read.cors.allowed_methods":    []string{http.MethodGet},
+		"serve.read.cors.allowed_origins":    []string{"https://ory.sh"},
+		"serve.write.cors.enabled":           true,
+		"serve.write.cors.allowed_origins":   []string{"https://admin.ory.sh"},
+		"serve.write.cors.allowed_headers":   []string{"X-Admin-Token"},
+		"serve.write.cors.allow_credentials": false,
 	})
 
 	closeServer := startServer(ctx, t, reg)
@@ -141,4 +145,29 @@ func TestServeConfig(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 	assert.Equal(t, "https://ory.sh", resp.Header.Get("Access-Control-Allow-Origin"), "%+v", resp.Header)
+
+	t.Run("case=write API has its own CORS settings", func(t *testing.T) {
+		for !healthReady(t, "http://"+reg.Config(ctx).WriteAPIListenOn()) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		preflight := func(t *testing.T, origin string) *http.Response {
+			req, err := http.NewRequest(http.MethodOptions, "http://"+reg.Config(ctx).WriteAPIListenOn()+relationtuple.WriteRouteBase, nil)
+			require.NoError(t, err)
+			req.Header.Set("Origin", origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			req.Header.Set("Access-Control-Request-Headers", "X-Admin-Token")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			return resp
+		}
+
+		resp := preflight(t, "https://admin.ory.sh")
+		assert.Equal(t, "https://admin.ory.sh", resp.Header.Get("Access-Control-Allow-Origin"), "%+v", resp.Header)
+		assert.Equal(t, "X-Admin-Token", resp.Header.Get("Access-Control-Allow-Headers"), "%+v", resp.Header)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"), "%+v", resp.Header)
+
+		resp = preflight(t, "https://ory.sh")
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"), "%+v", resp.Header)
+	})
 }
@@ -0,0 +1,79 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionV1Alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestGRPCHealthAndReflection(t *testing.T) {
+	t.Parallel()
+
+	dial := func(t *testing.T, ctx context.Context, remote string) *grpc.ClientConn {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, remote, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	listServices := func(t *testing.T, ctx context.Context, conn *grpc.ClientConn) error {
+		stream, err := reflectionV1Alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&reflectionV1Alpha.ServerReflectionRequest{
+			MessageRequest: &reflectionV1Alpha.ServerReflectionRequest_ListServices{},
+		}))
+		_, err = stream.Recv()
+		return err
+	}
+
+	t.Run("case=health service reports the checks", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), nil)
+		closeServer := startServer(ctx, t, reg)
+		t.Cleanup(closeServer)
+
+		for _, remote := range []string{reg.Config(ctx).ReadAPIListenOn(), reg.Config(ctx).WriteAPIListenOn()} {
+			client := grpcHealthV1.NewHealthClient(dial(t, ctx, remote))
+			for _, service := range []string{"", "database", "namespaces"} {
+				assert.Eventually(t, func() bool {
+					resp, err := client.Check(ctx, &grpcHealthV1.HealthCheckRequest{Service: service})
+					return err == nil && resp.Status == grpcHealthV1.HealthCheckResponse_SERVING
+				}, 5*time.Second, 10*time.Millisecond, "service %q on %s", service, remote)
+			}
+
+			_, err := client.Check(ctx, &grpcHealthV1.HealthCheckRequest{Service: "unknown"})
+			assert.Equal(t, codes.NotFound, status.Code(err))
+		}
+
+		assert.NoError(t, listServices(t, ctx, dial(t, ctx, reg.Config(ctx).ReadAPIListenOn())))
+	})
+
+	t.Run("case=reflection can be disabled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+			config.KeyGRPCReflection: false,
+		})
+		closeServer := startServer(ctx, t, reg)
+		t.Cleanup(closeServer)
+
+		for _, remote := range []string{reg.Config(ctx).ReadAPIListenOn(), reg.Config(ctx).WriteAPIListenOn()} {
+			err := listServices(t, ctx, dial(t, ctx, remote))
+			assert.Equal(t, codes.Unimplemented, status.Code(err), "%+v", err)
+		}
+	})
+}
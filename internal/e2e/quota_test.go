@@ -0,0 +1,151 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/tenancy"
+	"github.com/ory/keto/internal/x/dbx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestServeQuotas(t *testing.T) {
+	t.Parallel()
+
+	const (
+		tenantHeader = "X-Keto-Tenant"
+		operatorKey  = "a-key-for-all-of-the-tenants"
+	)
+	// The rates are so low that no tokens are refilled during the test.
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		config.KeyNamespaces: []*namespace.Namespace{
+			{Name: "docs"}, {Name: "files"},
+			{Name: "trash", Config: json.RawMessage(`{"soft_delete":{"enabled":true}}`)},
+		},
+		config.KeyTenancyEnabled: true,
+		config.KeyAuthnAPIKeys:   []map[string]interface{}{{"id": "operator", "key": operatorKey, "tenant": tenancy.AnyTenant}},
+		config.KeyQuotas: []map[string]interface{}{
+			{"namespace": "docs", "max_relation_tuples": 2},
+			{"namespace": "files", "writes": map[string]interface{}{"requests_per_second": 0.001, "burst": 1}},
+			{"namespace": "trash", "max_relation_tuples": 1},
+			{"tenant": "a", "checks": map[string]interface{}{"requests_per_second": 0.001, "burst": 2}},
+		},
+	})
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	readURL := "http://" + reg.Config(ctx).ReadAPIListenOn()
+	writeURL := "http://" + reg.Config(ctx).WriteAPIListenOn()
+	for !healthReady(t, readURL) || !healthReady(t, writeURL) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	do := func(t *testing.T, method, url, tenant, body string) int {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+operatorKey)
+		req.Header.Set(tenantHeader, tenant)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	put := func(t *testing.T, tenant, namespace, object string) int {
+		return do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, tenant,
+			`{"namespace":"`+namespace+`","object":"`+object+`","relation":"view","subject_id":"alice"}`)
+	}
+
+	t.Run("case=relation tuples are limited per tenant", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, put(t, "a", "docs", "1"))
+		assert.Equal(t, http.StatusCreated, put(t, "a", "docs", "2"))
+		assert.Equal(t, http.StatusForbidden, put(t, "a", "docs", "3"))
+		assert.Equal(t, http.StatusCreated, put(t, "b", "docs", "3"))
+
+		// A transaction that does not add relation tuples is allowed.
+		assert.Equal(t, http.StatusNoContent, do(t, http.MethodPatch, writeURL+relationtuple.WriteRouteBase, "a",
+			`[{"action":"delete","relation_tuple":{"namespace":"docs","object":"2","relation":"view","subject_id":"alice"}},`+
+				`{"action":"insert","relation_tuple":{"namespace":"docs","object":"3","relation":"view","subject_id":"alice"}}]`))
+
+		assert.Equal(t, http.StatusNoContent, do(t, http.MethodDelete, writeURL+relationtuple.WriteRouteBase+"?namespace=docs&object=1", "a", ""))
+		assert.Equal(t, http.StatusCreated, put(t, "a", "docs", "4"))
+	})
+
+	t.Run("case=writes are limited per tenant and namespace", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, put(t, "a", "files", "1"))
+		assert.Equal(t, http.StatusTooManyRequests, put(t, "a", "files", "2"))
+		assert.Equal(t, http.StatusTooManyRequests, do(t, http.MethodDelete, writeURL+relationtuple.WriteRouteBase+"?namespace=files", "a", ""))
+		assert.Equal(t, http.StatusCreated, put(t, "b", "files", "1"))
+
+		assert.Equal(t, http.StatusTooManyRequests, do(t, http.MethodPost, writeURL+relationtuple.BulkDeleteRoute+"?namespace=files", "a", ""))
+		assert.Equal(t, http.StatusTooManyRequests, do(t, http.MethodPost, writeURL+relationtuple.RestoreRoute+"?namespace=files", "a", ""))
+	})
+
+	t.Run("case=restores are limited per tenant", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, put(t, "a", "trash", "1"))
+		assert.Equal(t, http.StatusNoContent, do(t, http.MethodDelete, writeURL+relationtuple.WriteRouteBase+"?namespace=trash", "a", ""))
+		assert.Equal(t, http.StatusCreated, put(t, "a", "trash", "2"))
+
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodPost, writeURL+relationtuple.RestoreRoute+"?namespace=trash", "a", ""))
+		assert.Equal(t, http.StatusNoContent, do(t, http.MethodDelete, writeURL+relationtuple.WriteRouteBase+"?namespace=trash&object=2", "a", ""))
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodPost, writeURL+relationtuple.RestoreRoute+"?namespace=trash", "a", ""),
+			"both deleted relation tuples would be restored")
+		assert.Equal(t, http.StatusOK, do(t, http.MethodPost, writeURL+relationtuple.RestoreRoute+"?namespace=trash&object=1", "a", ""))
+	})
+
+	t.Run("case=exceeded relation tuple quotas have the same code over gRPC", func(t *testing.T) {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).WriteAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		_, err = rts.NewWriteServiceClient(conn).TransactRelationTuples(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+operatorKey, "x-keto-tenant", "a"), &rts.TransactRelationTuplesRequest{
+			RelationTupleDeltas: []*rts.RelationTupleDelta{{
+				Action:        rts.RelationTupleDelta_ACTION_INSERT,
+				RelationTuple: &rts.RelationTuple{Namespace: "docs", Object: "5", Relation: "view", Subject: rts.NewSubjectID("alice")},
+			}},
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "%+v", err)
+	})
+
+	t.Run("case=checks are limited for the tenant", func(t *testing.T) {
+		checkURL := readURL + check.OpenAPIRouteBase + "?namespace=docs&object=3&relation=view&subject_id=alice"
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, do(t, http.MethodGet, checkURL, "a", ""))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, do(t, http.MethodGet, checkURL, "a", ""))
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, do(t, http.MethodGet, checkURL, "b", ""))
+		}
+
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).ReadAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		client := rts.NewCheckServiceClient(conn)
+		req := &rts.CheckRequest{Tuple: &rts.RelationTuple{Namespace: "docs", Object: "3", Relation: "view", Subject: rts.NewSubjectID("alice")}}
+		_, err = client.Check(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+operatorKey, "x-keto-tenant", "a"), req)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "%+v", err)
+		res, err := client.Check(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+operatorKey, "x-keto-tenant", "b"), req)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	})
+}
@@ -0,0 +1,103 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/internal/x/requestid"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestServeRequestID(t *testing.T) {
+	t.Parallel()
+
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		config.KeyNamespaces:           []*namespace.Namespace{{Name: "docs"}},
+		config.KeyRequestIDSQLComments: true,
+	})
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	readURL := "http://" + reg.Config(ctx).ReadAPIListenOn()
+	writeURL := "http://" + reg.Config(ctx).WriteAPIListenOn()
+	for !healthReady(t, readURL) || !healthReady(t, writeURL) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	do := func(t *testing.T, method, url, id, body string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if id != "" {
+			req.Header.Set(requestid.Header, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("case=REST", func(t *testing.T) {
+		resp := do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, "client-id-1",
+			`{"namespace":"docs","object":"readme","relation":"view","subject_id":"alice"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "client-id-1", resp.Header.Get(requestid.Header))
+
+		resp = do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+"?namespace=docs", "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, requestid.Valid(resp.Header.Get(requestid.Header)))
+
+		resp = do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+"?namespace=docs", "not a valid */ id", "")
+		assert.NotEqual(t, "not a valid */ id", resp.Header.Get(requestid.Header))
+		assert.True(t, requestid.Valid(resp.Header.Get(requestid.Header)))
+	})
+
+	t.Run("case=REST errors carry the request ID", func(t *testing.T) {
+		resp := do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+"?namespace=unknown", "client-id-2", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		var body struct {
+			Error struct {
+				Request string `json:"request"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "client-id-2", body.Error.Request)
+	})
+
+	t.Run("case=gRPC", func(t *testing.T) {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).ReadAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		client := rts.NewReadServiceClient(conn)
+		var header metadata.MD
+		_, err = client.ListRelationTuples(metadata.AppendToOutgoingContext(ctx, requestid.MetadataKey, "client-id-3"),
+			&rts.ListRelationTuplesRequest{RelationQuery: &rts.RelationQuery{Namespace: x.Ptr("docs")}}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"client-id-3"}, header.Get(requestid.MetadataKey))
+
+		_, err = client.ListRelationTuples(ctx,
+			&rts.ListRelationTuplesRequest{RelationQuery: &rts.RelationQuery{Namespace: x.Ptr("unknown")}}, grpc.Header(&header))
+		assert.Equal(t, codes.NotFound, status.Code(err), "%+v", err)
+		require.Len(t, header.Get(requestid.MetadataKey), 1)
+		assert.True(t, requestid.Valid(header.Get(requestid.MetadataKey)[0]))
+	})
+}
@@ -0,0 +1,188 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/definition"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/tenancy"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+func TestServeTenancy(t *testing.T) {
+	t.Parallel()
+
+	const (
+		anyTenantKey  = "a-key-for-all-of-the-tenants"
+		acmeKey       = "a-key-for-the-acme-tenant"
+		namespaceKey  = "a-key-for-the-namespace-api"
+		unboundKey    = "a-key-without-a-tenant"
+		tenantHeader  = "X-Keto-Tenant"
+		tenantMDKey   = "x-keto-tenant"
+		tupleBody     = `{"namespace":"docs","object":"readme","relation":"view","subject_id":"alice"}`
+		checkQuery    = "?namespace=docs&object=readme&relation=view&subject_id=alice"
+		readmeQuery   = "?namespace=docs&object=readme"
+		folderBody    = `{"namespace":"Folder","object":"root","relation":"owners","subject_id":"alice"}`
+		defaultTenant = ""
+	)
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		config.KeyNamespaces:     []*namespace.Namespace{{Name: "docs"}},
+		config.KeyTenancyEnabled: true,
+		config.KeyAuthnAPIKeys: []map[string]interface{}{
+			{"id": "operator", "key": anyTenantKey, "tenant": tenancy.AnyTenant},
+			{"id": "acme", "key": acmeKey, "tenant": "acme"},
+			{"id": "namespaces", "key": namespaceKey, "tenant": tenancy.AnyTenant},
+			{"id": "unbound", "key": unboundKey},
+		},
+		config.KeyNamespaceAPIEnabled: true,
+	})
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	readURL := "http://" + reg.Config(ctx).ReadAPIListenOn()
+	writeURL := "http://" + reg.Config(ctx).WriteAPIListenOn()
+	for !healthReady(t, readURL) || !healthReady(t, writeURL) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	do := func(t *testing.T, method, url, token, tenant, body string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if tenant != defaultTenant {
+			req.Header.Set(tenantHeader, tenant)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+	allowed := func(t *testing.T, token, tenant string) bool {
+		resp := do(t, http.MethodGet, readURL+check.OpenAPIRouteBase+checkQuery, token, tenant, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var res check.RESTResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res.Allowed
+	}
+	tuples := func(t *testing.T, token, tenant string) []*ketoapi.RelationTuple {
+		resp := do(t, http.MethodGet, readURL+relationtuple.ReadRouteBase+readmeQuery, token, tenant, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var res ketoapi.GetResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res.RelationTuples
+	}
+
+	t.Run("case=requests need a valid tenant", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, anyTenantKey, defaultTenant, tupleBody).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, anyTenantKey, "../other", tupleBody).StatusCode)
+		assert.True(t, healthReady(t, readURL))
+	})
+
+	t.Run("case=relation tuples are isolated", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, anyTenantKey, "a", tupleBody).StatusCode)
+
+		assert.True(t, allowed(t, anyTenantKey, "a"))
+		assert.False(t, allowed(t, anyTenantKey, "b"))
+		assert.Len(t, tuples(t, anyTenantKey, "a"), 1)
+		assert.Len(t, tuples(t, anyTenantKey, "b"), 0)
+	})
+
+	t.Run("case=tenants can be restricted", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyTenancyTenants, []string{"a", "b", "acme"}))
+		require.NoError(t, reg.Config(ctx).Set(config.KeyTenancyPattern, "customer-[0-9]+"))
+		t.Cleanup(func() {
+			require.NoError(t, reg.Config(ctx).Set(config.KeyTenancyTenants, []string{}))
+			require.NoError(t, reg.Config(ctx).Set(config.KeyTenancyPattern, ""))
+		})
+
+		assert.True(t, allowed(t, anyTenantKey, "a"))
+		assert.False(t, allowed(t, anyTenantKey, "customer-1"))
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodGet, readURL+check.OpenAPIRouteBase+checkQuery, anyTenantKey, "c", "").StatusCode)
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodGet, readURL+check.OpenAPIRouteBase+checkQuery, anyTenantKey, "customer-1x", "").StatusCode)
+	})
+
+	t.Run("case=background jobs cover the recorded tenants", func(t *testing.T) {
+		ctxs, err := tenancy.Contexts(ctx, reg)
+		require.NoError(t, err)
+		var tenants []string
+		for _, ctx := range ctxs {
+			tenant, _ := tenancy.FromContext(ctx)
+			tenants = append(tenants, tenant)
+		}
+		assert.Equal(t, []string{"", "a", "b", "customer-1"}, tenants)
+	})
+
+	t.Run("case=API keys can be bound to a tenant", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodGet, readURL+check.OpenAPIRouteBase+checkQuery, acmeKey, "a", "").StatusCode)
+		assert.False(t, allowed(t, acmeKey, defaultTenant))
+
+		assert.Equal(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, acmeKey, defaultTenant, tupleBody).StatusCode)
+		assert.True(t, allowed(t, acmeKey, "acme"))
+		assert.True(t, allowed(t, anyTenantKey, "acme"))
+		assert.Len(t, tuples(t, anyTenantKey, "acme"), 1)
+	})
+
+	t.Run("case=clients have to be bound to a tenant", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodGet, readURL+check.OpenAPIRouteBase+checkQuery, unboundKey, "a", "").StatusCode)
+		assert.Equal(t, http.StatusForbidden, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, unboundKey, defaultTenant, tupleBody).StatusCode)
+	})
+
+	t.Run("case=managed namespaces are isolated", func(t *testing.T) {
+		def, err := json.Marshal(&ketoapi.NamespaceDefinition{Name: "Folder", OPL: "class Folder implements Namespace {\n  related: {\n    owners: Folder[]\n  }\n}\n"})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, do(t, http.MethodPost, writeURL+definition.RouteBase, namespaceKey, "a", string(def)).StatusCode)
+
+		assert.Equal(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, anyTenantKey, "a", folderBody).StatusCode)
+		assert.NotEqual(t, http.StatusCreated, do(t, http.MethodPut, writeURL+relationtuple.WriteRouteBase, anyTenantKey, "b", folderBody).StatusCode)
+
+		resp := do(t, http.MethodGet, writeURL+definition.RouteBase, namespaceKey, "b", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var res ketoapi.ListNamespaceDefinitionsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		assert.Len(t, res.Definitions, 0)
+	})
+
+	t.Run("case=gRPC", func(t *testing.T) {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).ReadAPIListenOn(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+
+		client := rts.NewCheckServiceClient(conn)
+		req := &rts.CheckRequest{Tuple: &rts.RelationTuple{Namespace: "docs", Object: "readme", Relation: "view", Subject: rts.NewSubjectID("alice")}}
+		authorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+anyTenantKey)
+
+		_, err = client.Check(authorized, req)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "%+v", err)
+
+		res, err := client.Check(metadata.AppendToOutgoingContext(authorized, tenantMDKey, "a"), req)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+
+		res, err = client.Check(metadata.AppendToOutgoingContext(authorized, tenantMDKey, "b"), req)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+
+		_, err = client.Check(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+unboundKey, tenantMDKey, "a"), req)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "%+v", err)
+	})
+}
@@ -0,0 +1,215 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ory/x/healthx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpcHealthV1 "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Keto Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM-encoded certificate and key for the subject
+// alternative names.
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage, dnsNames []string, ips ...net.IP) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "keto-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, content []byte) string {
+	fn := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(fn, content, 0600))
+	return fn
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, x509.ExtKeyUsageServerAuth, nil, net.IPv4(127, 0, 0, 1))
+
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		"serve.read.tls.cert.path":                writeFile(t, dir, "server.pem", serverCert),
+		"serve.read.tls.key.path":                 writeFile(t, dir, "server-key.pem", serverKey),
+		"serve.read.tls.client_auth.ca.path":      writeFile(t, dir, "ca.pem", ca.pem),
+		"serve.read.tls.client_auth.allowed_sans": []string{"allowed.client.test"},
+	})
+
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(ca.pem)
+	clientTLS := func(t *testing.T, dnsNames ...string) *tls.Config {
+		cfg := &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+		if len(dnsNames) > 0 {
+			cert, key := ca.issue(t, x509.ExtKeyUsageClientAuth, dnsNames)
+			pair, err := tls.X509KeyPair(cert, key)
+			require.NoError(t, err)
+			cfg.Certificates = []tls.Certificate{pair}
+		}
+		return cfg
+	}
+	readyURL := "https://" + reg.Config(ctx).ReadAPIListenOn() + healthx.ReadyCheckPath
+	get := func(cfg *tls.Config) (*http.Response, error) {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}}
+		return c.Get(readyURL)
+	}
+
+	t.Run("case=REST clients with allowed certificates are served", func(t *testing.T) {
+		var (
+			resp *http.Response
+			err  error
+		)
+		for i := 0; i < 100; i++ {
+			if resp, err = get(clientTLS(t, "allowed.client.test")); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, resp.ProtoMajor)
+	})
+
+	t.Run("case=REST clients without certificates are rejected", func(t *testing.T) {
+		_, err := get(clientTLS(t))
+		assert.Error(t, err)
+	})
+
+	t.Run("case=REST clients with other certificates are rejected", func(t *testing.T) {
+		_, err := get(clientTLS(t, "other.client.test"))
+		assert.Error(t, err)
+	})
+
+	t.Run("case=gRPC clients with allowed certificates are served", func(t *testing.T) {
+		dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(dialCtx, reg.Config(ctx).ReadAPIListenOn(),
+			grpc.WithTransportCredentials(credentials.NewTLS(clientTLS(t, "allowed.client.test"))),
+			grpc.WithBlock())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		resp, err := grpcHealthV1.NewHealthClient(conn).Check(ctx, &grpcHealthV1.HealthCheckRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, grpcHealthV1.HealthCheckResponse_SERVING, resp.Status)
+	})
+}
+
+func TestServeTLSReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	firstCert, firstKey := ca.issue(t, x509.ExtKeyUsageServerAuth, nil, net.IPv4(127, 0, 0, 1))
+	certFile, keyFile := writeFile(t, dir, "server.pem", firstCert), writeFile(t, dir, "server-key.pem", firstKey)
+
+	ctx, reg, _ := newInitializedReg(t, dbx.GetSqlite(t, dbx.SQLiteMemory), map[string]interface{}{
+		"serve.read.tls.cert.path": certFile,
+		"serve.read.tls.key.path":  keyFile,
+	})
+
+	closeServer := startServer(ctx, t, reg)
+	t.Cleanup(closeServer)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(ca.pem)
+	newClient := func() *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}}}
+	}
+	readyURL := "https://" + reg.Config(ctx).ReadAPIListenOn() + healthx.ReadyCheckPath
+	servedCert := func(t *testing.T, c *http.Client) []byte {
+		resp, err := c.Get(readyURL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: resp.TLS.PeerCertificates[0].Raw})
+	}
+
+	established := newClient()
+	var err error
+	for i := 0; i < 100; i++ {
+		if _, err = established.Get(readyURL); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	require.Equal(t, firstCert, servedCert(t, established))
+
+	secondCert, secondKey := ca.issue(t, x509.ExtKeyUsageServerAuth, nil, net.IPv4(127, 0, 0, 1))
+	writeFile(t, dir, "server.pem", secondCert)
+	writeFile(t, dir, "server-key.pem", secondKey)
+
+	assert.Eventually(t, func() bool {
+		return bytes.Equal(secondCert, servedCert(t, newClient()))
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// The established connection is kept.
+	assert.Equal(t, firstCert, servedCert(t, established))
+}
@@ -3,6 +3,9 @@ package expand
 import (
 	"context"
 
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
 	"github.com/ory/keto/ketoapi"
 
 	"github.com/ory/keto/internal/driver/config"
@@ -24,8 +27,26 @@ type (
 	EngineProvider interface {
 		ExpandEngine() *Engine
 	}
+	// nodeBudget counts the nodes of a tree that can still be added before it
+	// exceeds the configured maximum.
+	nodeBudget struct {
+		max, left int
+	}
 )
 
+// use adds n nodes to the tree, and returns an error if that exceeds the
+// maximum.
+func (b *nodeBudget) use(n int) error {
+	if b.max == 0 {
+		return nil
+	}
+	if b.left -= n; b.left < 0 {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+			"The expanded tree has more than %d nodes, the limit set by limit.max_expand_nodes. Request a smaller max-depth instead.", b.max))
+	}
+	return nil
+}
+
 func NewEngine(d EngineDependencies) *Engine {
 	return &Engine{
 		d: d,
@@ -38,6 +59,15 @@ func (e *Engine) BuildTree(ctx context.Context, subject relationtuple.Subject, r
 		restDepth = globalMaxDepth
 	}
 
+	max := e.d.Config(ctx).MaxExpandNodes()
+	budget := &nodeBudget{max: max, left: max}
+	if err := budget.use(1); err != nil {
+		return nil, err
+	}
+	return e.buildTree(ctx, subject, restDepth, budget)
+}
+
+func (e *Engine) buildTree(ctx context.Context, subject relationtuple.Subject, restDepth int, budget *nodeBudget) (*relationtuple.Tree, error) {
 	if subSet, isSubjectSet := subject.(*relationtuple.SubjectSet); isSubjectSet {
 		ctx, wasAlreadyVisited := graph.CheckAndAddVisited(ctx, subject)
 		if wasAlreadyVisited {
@@ -76,9 +106,12 @@ func (e *Engine) BuildTree(ctx context.Context, subject relationtuple.Subject, r
 				return subTree, nil
 			}
 
+			if err := budget.use(len(rels)); err != nil {
+				return nil, err
+			}
 			children := make([]*relationtuple.Tree, len(rels))
 			for ri, r := range rels {
-				child, err := e.BuildTree(ctx, r.Subject, restDepth-1)
+				child, err := e.buildTree(ctx, r.Subject, restDepth-1, budget)
 				if err != nil {
 					return nil, err
 				}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
 
 	"github.com/ory/keto/ketoapi"
 
@@ -268,6 +269,30 @@ func TestEngine(t *testing.T) {
 		assert.Len(t, reg.RequestedPages, 2)
 	})
 
+	t.Run("case=respects max expand nodes", func(t *testing.T) {
+		reg := driver.NewSqliteTestRegistry(t, false)
+		ctx := context.Background()
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{}}))
+
+		root := &relationtuple.SubjectSet{Object: uuid.Must(uuid.NewV4()), Relation: "access"}
+		for _, user := range x.UUIDs(3) {
+			require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, &relationtuple.RelationTuple{
+				Object:   root.Object,
+				Relation: root.Relation,
+				Subject:  &relationtuple.SubjectID{ID: user},
+			}))
+		}
+
+		require.NoError(t, reg.Config(ctx).Set(config.KeyLimitMaxExpandNodes, 3))
+		_, err := reg.ExpandEngine().BuildTree(ctx, root, 10)
+		assert.ErrorIs(t, err, herodot.ErrBadRequest)
+
+		require.NoError(t, reg.Config(ctx).Set(config.KeyLimitMaxExpandNodes, 4))
+		tree, err := reg.ExpandEngine().BuildTree(ctx, root, 10)
+		require.NoError(t, err)
+		assert.Len(t, tree.Children, 3)
+	})
+
 	t.Run("case=handles subject sets as leaf", func(t *testing.T) {
 		reg, e := newTestEngine(t, []*namespace.Namespace{{}})
 
@@ -45,6 +45,8 @@ func (h *handler) RegisterReadRoutes(r *x.ReadRouter) {
 
 func (h *handler) RegisterWriteRoutes(_ *x.WriteRouter) {}
 
+func (h *handler) RegisterAdminRoutes(_ *x.AdminRouter) {}
+
 func (h *handler) RegisterReadGRPC(s *grpc.Server) {
 	rts.RegisterExpandServiceServer(s, h)
 }
@@ -57,6 +59,12 @@ type getExpandRequest struct {
 	MaxDepth int `json:"max-depth"`
 	// in:query
 	ketoapi.SubjectSet
+	// The fields of the relation tuples in the tree to return, as JSON field
+	// names with nested fields separated by dots, e.g. `subject_id`. All fields
+	// are returned if empty.
+	//
+	// in:query
+	Fields []string `json:"fields"`
 }
 
 // swagger:route GET /relation-tuples/expand read getExpand
@@ -85,6 +93,12 @@ func (h *handler) getExpand(w http.ResponseWriter, r *http.Request, _ httprouter
 		return
 	}
 
+	mask, err := x.FieldMaskFromURLQuery(r.URL.Query(), ketoapi.RelationTuple{})
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	subSet := (&ketoapi.SubjectSet{}).FromURLQuery(r.URL.Query())
 	internal, err := h.d.Mapper().FromSubjectSet(r.Context(), subSet)
 	if err != nil {
@@ -103,9 +117,42 @@ func (h *handler) getExpand(w http.ResponseWriter, r *http.Request, _ httprouter
 		return
 	}
 
+	if mask != nil && tree != nil {
+		masked, err := maskTree(tree, mask)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, err)
+			return
+		}
+		h.d.Writer().Write(w, r, masked)
+		return
+	}
+
 	h.d.Writer().Write(w, r, tree)
 }
 
+// maskTree applies the field mask to the tuples of the tree, keeping the
+// structure of the tree.
+func maskTree(t *ketoapi.Tree[*ketoapi.RelationTuple], mask *x.FieldMask) (map[string]interface{}, error) {
+	tuple, err := mask.Apply(t.Tuple)
+	if err != nil {
+		return nil, err
+	}
+	node := map[string]interface{}{
+		"type":  t.Type,
+		"tuple": tuple,
+	}
+	if len(t.Children) > 0 {
+		children := make([]interface{}, len(t.Children))
+		for i, c := range t.Children {
+			if children[i], err = maskTree(c, mask); err != nil {
+				return nil, err
+			}
+		}
+		node["children"] = children
+	}
+	return node, nil
+}
+
 func (h *handler) Expand(ctx context.Context, req *rts.ExpandRequest) (*rts.ExpandResponse, error) {
 	var subSet *ketoapi.SubjectSet
 
@@ -125,6 +172,10 @@ func (h *handler) Expand(ctx context.Context, req *rts.ExpandRequest) (*rts.Expa
 		}
 	}
 
+	if req.Snaptoken != "" {
+		ctx = relationtuple.WithSnaptoken(ctx, req.Snaptoken)
+	}
+
 	internal, err := h.d.Mapper().FromSubjectSet(ctx, subSet)
 	if err != nil {
 		return nil, err
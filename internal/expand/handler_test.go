@@ -117,4 +117,37 @@ func TestRESTHandler(t *testing.T) {
 		require.NoError(t, json.NewDecoder(bytes.NewBuffer(body)).Decode(&actualTree))
 		expand.AssertExternalTreesAreEqual(t, expectedTree, &actualTree)
 	})
+	t.Run("case=returns only the selected fields", func(t *testing.T) {
+		qs := (&ketoapi.SubjectSet{Namespace: nspace.Name, Object: "root", Relation: "parent of"}).ToURLQuery()
+		qs.Set("max-depth", "2")
+		qs.Set(x.FieldMaskQueryKey, "subject_id")
+		resp, err := ts.Client().Get(ts.URL + expand.RouteBase + "?" + qs.Encode())
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var tree struct {
+			Type     string                 `json:"type"`
+			Tuple    map[string]interface{} `json:"tuple"`
+			Children []struct {
+				Type  string                 `json:"type"`
+				Tuple map[string]interface{} `json:"tuple"`
+			} `json:"children"`
+		}
+		require.NoError(t, json.Unmarshal(body, &tree), "%s", body)
+		assert.Equal(t, string(ketoapi.TreeNodeUnion), tree.Type)
+		assert.Empty(t, tree.Tuple)
+		require.Len(t, tree.Children, 2)
+		for _, c := range tree.Children {
+			assert.Equal(t, string(ketoapi.TreeNodeLeaf), c.Type)
+			assert.Len(t, c.Tuple, 1)
+			assert.Contains(t, c.Tuple, "subject_id")
+		}
+
+		qs.Set(x.FieldMaskQueryKey, "subject_set.unknown")
+		resp, err = ts.Client().Get(ts.URL + expand.RouteBase + "?" + qs.Encode())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
 }
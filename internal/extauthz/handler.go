@@ -0,0 +1,282 @@
+// Package extauthz implements the Envoy ext_authz gRPC service, so that Envoy
+// and Istio can authorize HTTP requests with a check. The configured rules map
+// the attributes of a request to the relation tuple that is checked.
+package extauthz
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/authz"
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/quota"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	handlerDependencies interface {
+		config.Provider
+		check.EngineProvider
+		audit.LoggerProvider
+		authz.AuthorizerProvider
+		quota.EnforcerProvider
+		relationtuple.MapperProvider
+		x.LoggerProvider
+	}
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+var _ authv3.AuthorizationServer = (*Handler)(nil)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+// Check allows the request if the relation tuple of the first matching rule
+// is allowed. Requests that the meta-permissions or quotas do not allow are
+// denied, as Envoy may be configured to allow requests on errors. Other errors
+// of the check are returned, so that Envoy applies its failure mode.
+func (h *Handler) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	rules, err := h.d.Config(ctx).ExtAuthzRules()
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := req.GetAttributes()
+	tuple, matched, err := tupleFor(rules, attrs)
+	if err != nil {
+		h.d.Logger().
+			WithError(err).
+			WithField("method", attrs.GetRequest().GetHttp().GetMethod()).
+			WithField("path", attrs.GetRequest().GetHttp().GetPath()).
+			Debug("Denying the request, as the relation tuple of the matching rule could not be built.")
+		return denied(), nil
+	}
+	if !matched {
+		if h.d.Config(ctx).ExtAuthzAllowUnmatched() {
+			return allowed(), nil
+		}
+		return denied(), nil
+	}
+
+	ok, err := h.check(ctx, tuple)
+	if resp, isDenial := denial(err); isDenial {
+		return resp, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return denied(), nil
+	}
+	return allowed(), nil
+}
+
+// check checks the relation tuple like the check API, and records the
+// decision in the audit log. The namespace of the relation tuple is only
+// known here, so the meta-permissions are enforced here instead of by the
+// interceptor of the read API.
+func (h *Handler) check(ctx context.Context, tuple *ketoapi.RelationTuple) (ok bool, err error) {
+	if err := h.d.Authorizer().Authorize(ctx, authz.ActionRead, tuple.Namespace); err != nil {
+		return false, err
+	}
+	if err := h.d.QuotaEnforcer().Check(ctx, tuple); err != nil {
+		return false, err
+	}
+	defer func() {
+		h.d.AuditLogger().Check(ctx, tuple, ok, err)
+	}()
+
+	it, err := h.d.Mapper().FromTuple(ctx, tuple)
+	// herodot.ErrNotFound occurs when the namespace is unknown
+	if errors.Is(err, herodot.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return h.d.PermissionEngine().CheckIsMember(ctx, it[0], 0)
+}
+
+func allowed() *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{}},
+	}
+}
+
+func denied() *authv3.CheckResponse {
+	return deniedWith(codes.PermissionDenied, typev3.StatusCode_Forbidden)
+}
+
+func deniedWith(code codes.Code, httpStatus typev3.StatusCode) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+			Status: &typev3.HttpStatus{Code: httpStatus},
+		}},
+	}
+}
+
+// denial returns the response that denies the request if the error is a
+// denial of the meta-permissions (403) or of a quota (403 or 429).
+func denial(err error) (*authv3.CheckResponse, bool) {
+	var e *herodot.DefaultError
+	if !errors.As(err, &e) {
+		return nil, false
+	}
+	switch e.StatusCode() {
+	case http.StatusForbidden:
+		return denied(), true
+	case http.StatusTooManyRequests:
+		return deniedWith(codes.ResourceExhausted, typev3.StatusCode_TooManyRequests), true
+	}
+	return nil, false
+}
+
+// tupleFor returns the relation tuple of the first rule that matches the
+// request, or false if no rule matches.
+func tupleFor(rules []config.ExtAuthzRule, attrs *authv3.AttributeContext) (*ketoapi.RelationTuple, bool, error) {
+	req := attrs.GetRequest().GetHttp()
+	path, _, _ := strings.Cut(req.GetPath(), "?")
+
+	for _, r := range rules {
+		if len(r.Methods) > 0 && !slices.Contains(r.Methods, req.GetMethod()) {
+			continue
+		}
+		if r.Host != "" && !strings.EqualFold(r.Host, req.GetHost()) {
+			continue
+		}
+		params, ok := matchPath(r.Path, path)
+		if !ok {
+			continue
+		}
+
+		rep := &replacer{params: params, attrs: attrs, path: path}
+		tuple := &ketoapi.RelationTuple{
+			Namespace: rep.replace(r.Namespace),
+			Object:    rep.replace(r.Object),
+			Relation:  rep.replace(r.Relation),
+		}
+		if r.SubjectSet != nil {
+			tuple.SubjectSet = &ketoapi.SubjectSet{
+				Namespace: rep.replace(r.SubjectSet.Namespace),
+				Object:    rep.replace(r.SubjectSet.Object),
+				Relation:  rep.replace(r.SubjectSet.Relation),
+			}
+		} else {
+			id := rep.replace(r.SubjectID)
+			tuple.SubjectID = &id
+		}
+		return tuple, true, rep.err
+	}
+	return nil, false, nil
+}
+
+// matchPath matches the path against the template, and returns the values of
+// its named segments.
+func matchPath(template, path string) (map[string]string, bool) {
+	tmpl := strings.Split(strings.TrimPrefix(template, "/"), "/")
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	params := make(map[string]string)
+
+	for i, t := range tmpl {
+		if i == len(tmpl)-1 && strings.HasPrefix(t, "{") && strings.HasSuffix(t, "...}") {
+			if i >= len(segments) {
+				return nil, false
+			}
+			params[t[1:len(t)-len("...}")]] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[t[1:len(t)-1]] = segments[i]
+			continue
+		}
+		if t != segments[i] {
+			return nil, false
+		}
+	}
+	return params, len(tmpl) == len(segments)
+}
+
+// replacer replaces the placeholders of the rules with the attributes of a
+// request. The first placeholder that cannot be replaced is recorded as
+// error.
+type replacer struct {
+	params map[string]string
+	attrs  *authv3.AttributeContext
+	path   string
+	err    error
+}
+
+func (r *replacer) replace(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(s[:start])
+		b.WriteString(r.value(s[start+1 : start+end]))
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func (r *replacer) value(name string) string {
+	req := r.attrs.GetRequest().GetHttp()
+	var v string
+	if p, ok := r.params[name]; ok {
+		v = p
+	} else if strings.HasPrefix(name, "header.") {
+		v = req.GetHeaders()[strings.ToLower(strings.TrimPrefix(name, "header."))]
+	} else {
+		switch name {
+		case "method":
+			v = req.GetMethod()
+		case "host":
+			v = req.GetHost()
+		case "path":
+			v = r.path
+		case "principal":
+			v = r.attrs.GetSource().GetPrincipal()
+		default:
+			r.setErr(errors.Errorf("unknown placeholder {%s}", name))
+			return ""
+		}
+	}
+	if v == "" {
+		r.setErr(errors.Errorf("the request has no value for the placeholder {%s}", name))
+	}
+	return v
+}
+
+func (r *replacer) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,167 @@
+package extauthz_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{{Name: "files"}, {Name: "groups"}, {Name: "meta"}}))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyExtAuthzRules, []map[string]interface{}{
+		{
+			"methods":    []string{"GET"},
+			"path":       "/files/{file}",
+			"namespace":  "files",
+			"object":     "{file}",
+			"relation":   "view",
+			"subject_id": "{header.x-user-id}",
+		},
+		{
+			"path":      "/admin/{rest...}",
+			"namespace": "groups",
+			"object":    "admins",
+			"relation":  "member",
+			"subject_set": map[string]interface{}{
+				"namespace": "groups",
+				"object":    "{header.x-group}",
+				"relation":  "member",
+			},
+		},
+	}))
+
+	its, err := reg.Mapper().FromTuple(ctx,
+		&ketoapi.RelationTuple{Namespace: "files", Object: "report", Relation: "view", SubjectID: x.Ptr("alice")},
+		&ketoapi.RelationTuple{Namespace: "groups", Object: "admins", Relation: "member", SubjectSet: &ketoapi.SubjectSet{Namespace: "groups", Object: "ops", Relation: "member"}},
+	)
+	require.NoError(t, err)
+	require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, its...))
+
+	s := reg.ReadGRPCServer(ctx)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := authv3.NewAuthorizationClient(conn)
+
+	request := func(ctx context.Context, method, path string, headers map[string]string) (*authv3.CheckResponse, error) {
+		return client.Check(ctx, &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+				Method:  method,
+				Host:    "example.com",
+				Path:    path,
+				Headers: headers,
+			}},
+		}})
+	}
+	authorize := func(t *testing.T, method, path string, headers map[string]string) bool {
+		resp, err := request(ctx, method, path, headers)
+		require.NoError(t, err)
+		if resp.GetStatus().GetCode() == int32(codes.OK) {
+			assert.NotNil(t, resp.GetOkResponse())
+			return true
+		}
+		assert.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+		assert.Equal(t, typev3.StatusCode_Forbidden, resp.GetDeniedResponse().GetStatus().GetCode())
+		return false
+	}
+
+	for _, tc := range []struct {
+		name, method, path string
+		headers            map[string]string
+		allowed            bool
+	}{
+		{name: "allowed subject ID", method: "GET", path: "/files/report?download=1", headers: map[string]string{"x-user-id": "alice"}, allowed: true},
+		{name: "denied subject ID", method: "GET", path: "/files/report", headers: map[string]string{"x-user-id": "bob"}},
+		{name: "missing header", method: "GET", path: "/files/report"},
+		{name: "other method", method: "DELETE", path: "/files/report", headers: map[string]string{"x-user-id": "alice"}},
+		{name: "unknown object", method: "GET", path: "/files/unknown", headers: map[string]string{"x-user-id": "alice"}},
+		{name: "allowed subject set", method: "POST", path: "/admin/users/1", headers: map[string]string{"x-group": "ops"}, allowed: true},
+		{name: "denied subject set", method: "POST", path: "/admin/users/1", headers: map[string]string{"x-group": "dev"}},
+		{name: "unmatched", method: "GET", path: "/files/report/versions"},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.allowed, authorize(t, tc.method, tc.path, tc.headers))
+		})
+	}
+
+	t.Run("case=allows unmatched requests", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyExtAuthzUnmatched, config.ExtAuthzAllow))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyExtAuthzUnmatched, config.ExtAuthzDeny)) })
+
+		assert.True(t, authorize(t, "GET", "/", nil))
+		assert.False(t, authorize(t, "GET", "/files/report", nil))
+	})
+
+	t.Run("case=enforces the check quotas", func(t *testing.T) {
+		// The rate is so low that no tokens are refilled during the test.
+		require.NoError(t, reg.Config(ctx).Set(config.KeyQuotas, []map[string]interface{}{
+			{"namespace": "groups", "checks": map[string]interface{}{"requests_per_second": 0.001, "burst": 1}},
+		}))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyQuotas, []map[string]interface{}{})) })
+		headers := map[string]string{"x-group": "ops"}
+
+		assert.True(t, authorize(t, "POST", "/admin/users/1", headers))
+		resp, err := request(ctx, "POST", "/admin/users/1", headers)
+		require.NoError(t, err, "denials must not be errors, which Envoy may allow")
+		assert.Equal(t, int32(codes.ResourceExhausted), resp.GetStatus().GetCode())
+		assert.Equal(t, typev3.StatusCode_TooManyRequests, resp.GetDeniedResponse().GetStatus().GetCode())
+	})
+
+	t.Run("case=enforces the meta-permissions", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyAuthnAPIKeys, []map[string]interface{}{{"id": "envoy", "key": "the-key-of-envoy"}}))
+		require.NoError(t, reg.Config(ctx).Set(config.KeyMetaPermissionsNamespace, "meta"))
+		ctx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer the-key-of-envoy")
+		headers := map[string]string{"x-user-id": "alice"}
+
+		resp, err := request(ctx, "GET", "/files/report", headers)
+		require.NoError(t, err, "denials must not be errors, which Envoy may allow")
+		assert.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+		assert.Equal(t, typev3.StatusCode_Forbidden, resp.GetDeniedResponse().GetStatus().GetCode())
+
+		its, err := reg.Mapper().FromTuple(ctx, &ketoapi.RelationTuple{Namespace: "meta", Object: "files", Relation: "read", SubjectID: x.Ptr("envoy")})
+		require.NoError(t, err)
+		require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, its...))
+
+		resp, err = request(ctx, "GET", "/files/report", headers)
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+	})
+
+	t.Run("case=counts against the rate limit of the check API", func(t *testing.T) {
+		// The rate is so low that no tokens are refilled during the test.
+		require.NoError(t, reg.Config(ctx).Set("rate_limit.check.requests_per_second", 0.001))
+		require.NoError(t, reg.Config(ctx).Set("rate_limit.check.burst", 1))
+		ctx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer the-key-of-envoy")
+		headers := map[string]string{"x-user-id": "alice"}
+
+		resp, err := request(ctx, "GET", "/files/report", headers)
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+		_, err = request(ctx, "GET", "/files/report", headers)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "%+v", err)
+	})
+}
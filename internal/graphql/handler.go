@@ -0,0 +1,127 @@
+// Package graphql serves checks, expands, and relation tuple queries through
+// a GraphQL endpoint of the read API, so that several of them can be batched
+// in a single query document.
+package graphql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/authz"
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/expand"
+	"github.com/ory/keto/internal/quota"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+)
+
+type (
+	handlerDeps interface {
+		audit.LoggerProvider
+		quota.EnforcerProvider
+		check.EngineProvider
+		expand.EngineProvider
+		relationtuple.ManagerProvider
+		relationtuple.MapperProvider
+		authz.AuthorizerProvider
+		config.Provider
+		x.LoggerProvider
+		x.WriterProvider
+	}
+	handler struct {
+		d handlerDeps
+
+		mx sync.Mutex
+		// schema is parsed again when the maximum depth changes.
+		schema      *graphqlgo.Schema
+		schemaDepth int
+	}
+	request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+)
+
+const RouteBase = "/graphql"
+
+//go:embed schema.graphql
+var schemaDefinition string
+
+func NewHandler(d handlerDeps) *handler {
+	return &handler{d: d}
+}
+
+func (h *handler) RegisterReadRoutes(r *x.ReadRouter) {
+	r.GET(RouteBase, h.serveGraphQL)
+	r.POST(RouteBase, h.serveGraphQL)
+}
+
+func (h *handler) RegisterWriteRoutes(_ *x.WriteRouter) {}
+
+func (h *handler) RegisterAdminRoutes(_ *x.AdminRouter) {}
+
+func (h *handler) RegisterReadGRPC(_ *grpc.Server) {}
+
+func (h *handler) RegisterWriteGRPC(_ *grpc.Server) {}
+
+// serveGraphQL executes the query of the request. Queries are either sent as
+// JSON body of a POST request, or as query parameters of a GET request, with
+// the variables encoded as JSON.
+func (h *handler) serveGraphQL(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := h.d.Config(r.Context())
+	if !c.GraphQLEnabled() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The GraphQL API is disabled.")))
+		return
+	}
+
+	var req request
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not unmarshal json: %s", err.Error())))
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if raw := q.Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not unmarshal the variables: %s", err.Error())))
+				return
+			}
+		}
+	}
+	if req.Query == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError("the query is missing")))
+		return
+	}
+
+	// As in other GraphQL servers, errors of the query are part of the
+	// response and do not change the status code.
+	h.d.Writer().Write(w, r, h.getSchema(c.GraphQLMaxDepth()).Exec(r.Context(), req.Query, req.OperationName, req.Variables))
+}
+
+func (h *handler) getSchema(maxDepth int) *graphqlgo.Schema {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if h.schema == nil || h.schemaDepth != maxDepth {
+		h.schema = graphqlgo.MustParseSchema(schemaDefinition, &resolver{d: h.d},
+			graphqlgo.UseFieldResolvers(),
+			graphqlgo.MaxDepth(maxDepth),
+		)
+		h.schemaDepth = maxDepth
+	}
+	return h.schema
+}
@@ -0,0 +1,187 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/graphql"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewSqliteTestRegistry(t, false)
+	require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "docs"}, {Name: "groups"}, {Name: "meta"}}))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLEnabled, true))
+
+	var tuples []*ketoapi.RelationTuple
+	for _, s := range []string{
+		"docs:readme#view@groups:editors#member",
+		"docs:readme#owner@alice",
+		"groups:editors#member@bob",
+		"meta:docs#read@ci",
+	} {
+		tuple, err := (&ketoapi.RelationTuple{}).FromString(s)
+		require.NoError(t, err)
+		tuples = append(tuples, tuple)
+	}
+	relationtuple.MapAndWriteTuples(t, reg, tuples...)
+
+	r := httprouter.New()
+	graphql.NewHandler(reg).RegisterReadRoutes(&x.ReadRouter{Router: r})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if client := req.Header.Get("X-Client"); client != "" {
+			req = req.WithContext(authn.NewContext(req.Context(), &authn.Subject{ID: client}))
+		}
+		r.ServeHTTP(w, req)
+	}))
+	t.Cleanup(ts.Close)
+
+	post := func(t *testing.T, client, query string, variables map[string]interface{}) (int, string) {
+		body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, ts.URL+graphql.RouteBase, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Client", client)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(raw)
+	}
+
+	t.Run("case=batches checks in one document", func(t *testing.T) {
+		code, body := post(t, "", `query($bob: String!) {
+  viaGroup: check(tuple: {namespace: "docs", object: "readme", relation: "view", subjectId: $bob}) { allowed }
+  unknown: check(tuple: {namespace: "unknown", object: "readme", relation: "view", subjectId: "bob"}) { allowed }
+  checks(tuples: [
+    {namespace: "docs", object: "readme", relation: "owner", subjectId: "alice"},
+    {namespace: "docs", object: "readme", relation: "owner", subjectSet: {namespace: "groups", object: "editors", relation: "member"}}
+  ]) { allowed tuple { subjectId subjectSet { object } } }
+}`, map[string]interface{}{"bob": "bob"})
+		require.Equal(t, http.StatusOK, code, body)
+		assert.False(t, gjson.Get(body, "errors").Exists(), body)
+		assert.True(t, gjson.Get(body, "data.viaGroup.allowed").Bool(), body)
+		assert.False(t, gjson.Get(body, "data.unknown.allowed").Bool(), body)
+		assert.Equal(t, `[true,false]`, gjson.Get(body, "data.checks.#.allowed").Raw)
+		assert.Equal(t, "alice", gjson.Get(body, "data.checks.0.tuple.subjectId").String())
+		assert.Equal(t, "editors", gjson.Get(body, "data.checks.1.tuple.subjectSet.object").String())
+	})
+
+	t.Run("case=expands subject sets", func(t *testing.T) {
+		_, body := post(t, "", `{
+  expand(subjectSet: {namespace: "docs", object: "readme", relation: "view"}, maxDepth: 3) {
+    type
+    children { type tuple { subjectSet { namespace object relation } } children { type tuple { subjectId } } }
+  }
+}`, nil)
+		assert.False(t, gjson.Get(body, "errors").Exists(), body)
+		assert.Equal(t, "union", gjson.Get(body, "data.expand.type").String(), body)
+		assert.Equal(t, "editors", gjson.Get(body, "data.expand.children.0.tuple.subjectSet.object").String(), body)
+		assert.Equal(t, "bob", gjson.Get(body, "data.expand.children.0.children.0.tuple.subjectId").String(), body)
+	})
+
+	t.Run("case=lists relation tuples page by page", func(t *testing.T) {
+		const query = `query($token: String) {
+  relationTuples(query: {namespace: "docs", object: "readme"}, pageSize: 1, pageToken: $token) {
+    relationTuples { relation }
+    nextPageToken
+  }
+}`
+		_, body := post(t, "", query, nil)
+		assert.False(t, gjson.Get(body, "errors").Exists(), body)
+		require.Len(t, gjson.Get(body, "data.relationTuples.relationTuples").Array(), 1, body)
+		first := gjson.Get(body, "data.relationTuples.relationTuples.0.relation").String()
+		token := gjson.Get(body, "data.relationTuples.nextPageToken").String()
+		require.NotEmpty(t, token)
+
+		_, body = post(t, "", query, map[string]interface{}{"token": token})
+		require.Len(t, gjson.Get(body, "data.relationTuples.relationTuples").Array(), 1, body)
+		assert.ElementsMatch(t, []string{"owner", "view"}, []string{first, gjson.Get(body, "data.relationTuples.relationTuples.0.relation").String()})
+		assert.Empty(t, gjson.Get(body, "data.relationTuples.nextPageToken").String())
+	})
+
+	t.Run("case=queries are accepted as URL parameters", func(t *testing.T) {
+		resp, err := ts.Client().Get(ts.URL + graphql.RouteBase + "?" + url.Values{
+			"query":     {`query($o: String!) { check(tuple: {namespace: "docs", object: $o, relation: "owner", subjectId: "alice"}) { allowed } }`},
+			"variables": {`{"o": "readme"}`},
+		}.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.True(t, gjson.GetBytes(body, "data.check.allowed").Bool(), string(body))
+	})
+
+	t.Run("case=errors carry the status code", func(t *testing.T) {
+		_, body := post(t, "", `{
+  check(tuple: {namespace: "docs", object: "readme", relation: "owner"}) { allowed }
+}`, nil)
+		assert.Equal(t, "check", gjson.Get(body, "errors.0.path.0").String(), body)
+		assert.EqualValues(t, http.StatusBadRequest, gjson.Get(body, "errors.0.extensions.status_code").Int(), body)
+	})
+
+	t.Run("case=batch size is limited", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLMaxBatchSize, 1))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLMaxBatchSize, 100)) })
+
+		_, body := post(t, "", `{
+  checks(tuples: [
+    {namespace: "docs", object: "readme", relation: "owner", subjectId: "alice"},
+    {namespace: "docs", object: "readme", relation: "owner", subjectId: "bob"}
+  ]) { allowed }
+}`, nil)
+		assert.Contains(t, gjson.Get(body, "errors.0.extensions.reason").String(), "At most 1 relation tuples can be checked at once, but 2 were given.", body)
+	})
+
+	t.Run("case=query depth is limited", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLMaxDepth, 2))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLMaxDepth, 16)) })
+
+		_, body := post(t, "", `{ expand(subjectSet: {namespace: "docs", object: "readme", relation: "view"}) { children { children { type } } } }`, nil)
+		assert.Contains(t, gjson.Get(body, "errors.0.message").String(), "exceeds max depth 2", body)
+	})
+
+	t.Run("case=meta-permissions are enforced", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyMetaPermissionsNamespace, "meta"))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyMetaPermissionsNamespace, "")) })
+
+		const query = `{
+  docs: check(tuple: {namespace: "docs", object: "readme", relation: "owner", subjectId: "alice"}) { allowed }
+  groups: check(tuple: {namespace: "groups", object: "editors", relation: "member", subjectId: "bob"}) { allowed }
+}`
+		_, body := post(t, "ci", query, nil)
+		assert.True(t, gjson.Get(body, "data.docs.allowed").Bool(), body)
+		assert.Equal(t, "groups", gjson.Get(body, "errors.0.path.0").String(), body)
+		assert.EqualValues(t, http.StatusForbidden, gjson.Get(body, "errors.0.extensions.status_code").Int(), body)
+
+		_, body = post(t, "", `{ relationTuples { nextPageToken } }`, nil)
+		assert.EqualValues(t, http.StatusForbidden, gjson.Get(body, "errors.0.extensions.status_code").Int(), body)
+	})
+
+	t.Run("case=disabled", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLEnabled, false))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyGraphQLEnabled, true)) })
+
+		code, _ := post(t, "", `{ relationTuples { nextPageToken } }`, nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+}
@@ -0,0 +1,213 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ory/herodot"
+	pkgerrors "github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/authz"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	resolver struct {
+		d handlerDeps
+	}
+	checkResult struct {
+		Allowed bool
+		Tuple   *ketoapi.RelationTuple
+	}
+	checkArgs struct {
+		Tuple    ketoapi.RelationTuple
+		MaxDepth *int32
+	}
+	checksArgs struct {
+		Tuples   []*ketoapi.RelationTuple
+		MaxDepth *int32
+	}
+	expandArgs struct {
+		SubjectSet ketoapi.SubjectSet
+		MaxDepth   *int32
+	}
+	relationTuplesArgs struct {
+		Query     *ketoapi.RelationQuery
+		PageSize  *int32
+		PageToken *string
+	}
+)
+
+func (r *resolver) Check(ctx context.Context, args checkArgs) (*checkResult, error) {
+	if err := r.authorize(ctx, args.Tuple.Namespace); err != nil {
+		return nil, err
+	}
+	allowed, err := r.check(ctx, &args.Tuple, depth(args.MaxDepth))
+	if err != nil {
+		return nil, queryError(err)
+	}
+	return &checkResult{Allowed: allowed, Tuple: &args.Tuple}, nil
+}
+
+func (r *resolver) Checks(ctx context.Context, args checksArgs) (*[]*checkResult, error) {
+	if max := r.d.Config(ctx).GraphQLMaxBatchSize(); len(args.Tuples) > max {
+		return nil, queryError(pkgerrors.WithStack(herodot.ErrBadRequest.WithReasonf(
+			"At most %d relation tuples can be checked at once, but %d were given.", max, len(args.Tuples))))
+	}
+	namespaces := make([]string, len(args.Tuples))
+	for i, t := range args.Tuples {
+		namespaces[i] = t.Namespace
+	}
+	if err := r.authorize(ctx, namespaces...); err != nil {
+		return nil, err
+	}
+
+	res := make([]*checkResult, len(args.Tuples))
+	for i, t := range args.Tuples {
+		allowed, err := r.check(ctx, t, depth(args.MaxDepth))
+		if err != nil {
+			return nil, queryError(err)
+		}
+		res[i] = &checkResult{Allowed: allowed, Tuple: t}
+	}
+	return &res, nil
+}
+
+// check behaves like the check endpoint of the REST API.
+func (r *resolver) check(ctx context.Context, tuple *ketoapi.RelationTuple, maxDepth int) (allowed bool, err error) {
+	if err := r.d.QuotaEnforcer().Check(ctx, tuple); err != nil {
+		return false, err
+	}
+	defer func() {
+		r.d.AuditLogger().Check(ctx, tuple, allowed, err)
+	}()
+
+	switch {
+	case tuple.SubjectID == nil && tuple.SubjectSet == nil:
+		return false, pkgerrors.WithStack(ketoapi.ErrNilSubject)
+	case tuple.SubjectID != nil && tuple.SubjectSet != nil:
+		return false, pkgerrors.WithStack(ketoapi.ErrDuplicateSubject)
+	}
+
+	it, err := r.d.Mapper().FromTuple(ctx, tuple)
+	// herodot.ErrNotFound occurs when the namespace is unknown
+	if errors.Is(err, herodot.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return r.d.PermissionEngine().CheckIsMember(ctx, it[0], maxDepth)
+}
+
+func (r *resolver) Expand(ctx context.Context, args expandArgs) (*ketoapi.Tree[*ketoapi.RelationTuple], error) {
+	if err := r.authorize(ctx, args.SubjectSet.Namespace); err != nil {
+		return nil, err
+	}
+
+	internal, err := r.d.Mapper().FromSubjectSet(ctx, &args.SubjectSet)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	res, err := r.d.ExpandEngine().BuildTree(ctx, internal, depth(args.MaxDepth))
+	if err != nil {
+		return nil, queryError(err)
+	}
+	tree, err := r.d.Mapper().ToTree(ctx, res)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	return tree, nil
+}
+
+func (r *resolver) RelationTuples(ctx context.Context, args relationTuplesArgs) (*ketoapi.GetResponse, error) {
+	query := args.Query
+	if query == nil {
+		query = &ketoapi.RelationQuery{}
+	}
+	if query.SubjectID != nil && query.SubjectSet != nil {
+		return nil, queryError(pkgerrors.WithStack(ketoapi.ErrDuplicateSubject))
+	}
+	var namespace string
+	if query.Namespace != nil {
+		namespace = *query.Namespace
+	}
+	if err := r.authorize(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	var pageSize int
+	if args.PageSize != nil {
+		pageSize = int(*args.PageSize)
+	}
+	paginationOpts := []x.PaginationOptionSetter{x.WithSize(r.d.Config(ctx).PageSize(pageSize))}
+	if args.PageToken != nil && *args.PageToken != "" {
+		paginationOpts = append(paginationOpts, x.WithToken(*args.PageToken))
+	}
+
+	iq, err := r.d.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	ir, nextPage, err := r.d.RelationTupleManager().GetRelationTuples(ctx, iq, paginationOpts...)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	tuples, err := r.d.Mapper().ToTuple(ctx, ir...)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	return &ketoapi.GetResponse{RelationTuples: tuples, NextPageToken: nextPage}, nil
+}
+
+// authorize enforces the meta-permissions, because the namespaces of a query
+// are only known to the resolvers. An empty namespace stands for a query that
+// is not limited to one namespace.
+func (r *resolver) authorize(ctx context.Context, namespaces ...string) error {
+	a := r.d.Authorizer()
+	if !a.Enabled(ctx) {
+		return nil
+	}
+	if err := a.Authorize(ctx, authz.ActionRead, namespaces...); err != nil {
+		return queryError(err)
+	}
+	return nil
+}
+
+func depth(maxDepth *int32) int {
+	if maxDepth == nil {
+		return 0
+	}
+	return int(*maxDepth)
+}
+
+// resolverError adds the status code and reason of herodot errors to the
+// extensions of the GraphQL error.
+type resolverError struct {
+	err error
+}
+
+func queryError(err error) error {
+	return &resolverError{err: err}
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	ext := make(map[string]interface{})
+	var sc herodot.StatusCodeCarrier
+	if errors.As(e.err, &sc) {
+		ext["status_code"] = sc.StatusCode()
+	}
+	var rc herodot.ReasonCarrier
+	if errors.As(e.err, &rc) && rc.Reason() != "" {
+		ext["reason"] = rc.Reason()
+	}
+	return ext
+}
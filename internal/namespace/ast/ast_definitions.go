@@ -7,6 +7,10 @@ type (
 		Name              string             `json:"name"`
 		Types             []RelationType     `json:"types,omitempty"`
 		SubjectSetRewrite *SubjectSetRewrite `json:"rewrite,omitempty"`
+		Condition         *Condition         `json:"condition,omitempty"`
+		// MaxSubjects is the maximum number of subjects an object can have
+		// for this relation. Zero means that the number is not limited.
+		MaxSubjects int `json:"max_subjects,omitempty"`
 	}
 
 	RelationType struct {
@@ -14,6 +18,22 @@ type (
 		Relation  string `json:"relation,omitempty"` // optional
 	}
 
+	// Condition declares the typed parameters of a conditional relation. It
+	// is only part of the schema, the check engine does not evaluate
+	// conditions, so relation tuples of the relation are considered without
+	// any parameters.
+	Condition struct {
+		Parameters []ConditionParameter `json:"parameters"`
+	}
+
+	ConditionParameter struct {
+		Name string                 `json:"name"`
+		Type ConditionParameterType `json:"type"`
+		List bool                   `json:"list,omitempty"`
+	}
+
+	ConditionParameterType string
+
 	SubjectSetRewrite struct {
 		Operation Operator `json:"operator"`
 		Children  Children `json:"children"`
@@ -43,6 +63,16 @@ type (
 	}
 )
 
+const (
+	ConditionParameterString    ConditionParameterType = "string"
+	ConditionParameterNumber    ConditionParameterType = "number"
+	ConditionParameterBoolean   ConditionParameterType = "boolean"
+	ConditionParameterTimestamp ConditionParameterType = "timestamp"
+)
+
+// Operator is encoded as its name in JSON.
+//
+// swagger:type string
 type Operator int
 
 //go:generate stringer -type=Operator -linecomment
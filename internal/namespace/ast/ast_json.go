@@ -0,0 +1,83 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func (i *Operator) UnmarshalJSON(raw []byte) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return err
+	}
+	switch s {
+	case OperatorOr.String():
+		*i = OperatorOr
+	case OperatorAnd.String():
+		*i = OperatorAnd
+	default:
+		return fmt.Errorf("unknown operator %q, expected %q or %q", s, OperatorOr, OperatorAnd)
+	}
+	return nil
+}
+
+func (r *SubjectSetRewrite) UnmarshalJSON(raw []byte) error {
+	var rewrite struct {
+		Operation Operator          `json:"operator"`
+		Children  []json.RawMessage `json:"children"`
+	}
+	if err := json.Unmarshal(raw, &rewrite); err != nil {
+		return err
+	}
+	r.Operation, r.Children = rewrite.Operation, make(Children, len(rewrite.Children))
+	for i, c := range rewrite.Children {
+		child, err := unmarshalChild(c)
+		if err != nil {
+			return err
+		}
+		r.Children[i] = child
+	}
+	return nil
+}
+
+func (i *InvertResult) UnmarshalJSON(raw []byte) error {
+	var inverted struct {
+		Child json.RawMessage `json:"inverted"`
+	}
+	if err := json.Unmarshal(raw, &inverted); err != nil {
+		return err
+	}
+	child, err := unmarshalChild(inverted.Child)
+	if err != nil {
+		return err
+	}
+	i.Child = child
+	return nil
+}
+
+// unmarshalChild infers the type of the child from its keys, as the JSON
+// encoding of the children is not tagged with their type.
+func unmarshalChild(raw json.RawMessage) (Child, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, err
+	}
+
+	var child Child
+	switch {
+	case keys["inverted"] != nil:
+		child = &InvertResult{}
+	case keys["children"] != nil:
+		child = &SubjectSetRewrite{}
+	case keys["computed_subject_set_relation"] != nil:
+		child = &TupleToSubjectSet{}
+	case keys["relation"] != nil:
+		child = &ComputedSubjectSet{}
+	default:
+		return nil, fmt.Errorf("unknown subject-set rewrite child %s", raw)
+	}
+	if err := json.Unmarshal(raw, child); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
@@ -0,0 +1,70 @@
+package namespace
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSoftDeleteRetention is used if soft deletes are enabled for a
+// namespace without setting a retention.
+const DefaultSoftDeleteRetention = 30 * 24 * time.Hour
+
+type (
+	// Config is the structure of the namespace's `config` field.
+	Config struct {
+		SoftDelete *SoftDeleteConfig `json:"soft_delete,omitempty"`
+		Closure    *ClosureConfig    `json:"closure,omitempty"`
+	}
+	SoftDeleteConfig struct {
+		Enabled   bool   `json:"enabled"`
+		Retention string `json:"retention,omitempty"`
+	}
+	ClosureConfig struct {
+		Relations []string `json:"relations"`
+	}
+)
+
+func (n *Namespace) config() (*Config, error) {
+	var c Config
+	if len(n.Config) == 0 {
+		return &c, nil
+	}
+	if err := json.Unmarshal(n.Config, &c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &c, nil
+}
+
+// SoftDeleteRetention returns for how long deleted relation tuples of the
+// namespace can be restored. If soft deletes are not enabled, relation tuples
+// are deleted permanently.
+func (n *Namespace) SoftDeleteRetention() (retention time.Duration, enabled bool, err error) {
+	c, err := n.config()
+	if err != nil {
+		return 0, false, err
+	}
+	if c.SoftDelete == nil || !c.SoftDelete.Enabled {
+		return 0, false, nil
+	}
+	if c.SoftDelete.Retention == "" {
+		return DefaultSoftDeleteRetention, true, nil
+	}
+
+	retention, err = time.ParseDuration(c.SoftDelete.Retention)
+	if err != nil {
+		return 0, false, errors.WithStack(err)
+	}
+	return retention, true, nil
+}
+
+// ClosureRelations returns the relations of the namespace whose transitive
+// closure is materialized.
+func (n *Namespace) ClosureRelations() ([]string, error) {
+	c, err := n.config()
+	if err != nil || c.Closure == nil {
+		return nil, err
+	}
+	return c.Closure.Relations, nil
+}
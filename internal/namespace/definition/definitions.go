@@ -0,0 +1,115 @@
+// Package definition manages namespace definitions that are created, updated,
+// and deleted at runtime through the namespace administration API, instead of
+// being part of the configuration.
+package definition
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ory/herodot"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// Definition is the Ory Permission Language source of a single namespace.
+	Definition struct {
+		Name string
+		OPL  string
+		// Version is incremented whenever the definition changes.
+		Version   int64
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	ManagerProvider interface {
+		NamespaceDefinitionManager() Manager
+	}
+	Manager interface {
+		// GetNamespaceDefinition returns herodot.ErrNotFound if there is no
+		// definition with the given name.
+		GetNamespaceDefinition(ctx context.Context, name string) (*Definition, error)
+		// ListNamespaceDefinitions returns all definitions ordered by name.
+		ListNamespaceDefinitions(ctx context.Context) ([]*Definition, error)
+		// CreateNamespaceDefinition returns herodot.ErrConflict if a
+		// definition with the same name already exists.
+		CreateNamespaceDefinition(ctx context.Context, d *Definition) error
+		// UpdateNamespaceDefinition replaces the source of an existing
+		// definition, or returns herodot.ErrNotFound. Unless the version is
+		// zero, it returns ErrVersionMismatch if the stored definition has
+		// another version.
+		UpdateNamespaceDefinition(ctx context.Context, d *Definition) error
+		// DeleteNamespaceDefinition returns herodot.ErrNotFound if there is no
+		// definition with the given name. Unless the version is zero, it
+		// returns ErrVersionMismatch if the stored definition has another
+		// version.
+		DeleteNamespaceDefinition(ctx context.Context, name string, version int64) error
+	}
+)
+
+// ErrVersionMismatch is returned if a definition was changed concurrently.
+var ErrVersionMismatch = herodot.DefaultError{
+	CodeField:     http.StatusPreconditionFailed,
+	StatusField:   http.StatusText(http.StatusPreconditionFailed),
+	GRPCCodeField: codes.Aborted,
+	ErrorField:    "The namespace definition was changed in the meantime.",
+}
+
+func (d *Definition) ToAPI() *ketoapi.NamespaceDefinition {
+	return &ketoapi.NamespaceDefinition{
+		Name:      d.Name,
+		OPL:       d.OPL,
+		Version:   d.Version,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// Parse parses the definitions into namespaces. The definitions can reference
+// the configured namespaces and each other.
+func Parse(configured []*namespace.Namespace, definitions []*Definition) ([]*namespace.Namespace, []error) {
+	known := make([]namespace.Namespace, len(configured))
+	for i, n := range configured {
+		known[i] = *n
+	}
+	sources := make(map[string]string, len(definitions))
+	for _, d := range definitions {
+		sources[d.Name] = d.OPL
+	}
+
+	parsed, errs := schema.ParseDefinitions(known, sources)
+	nn := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		nn[i] = &parsed[i]
+	}
+	sort.Slice(nn, func(i, j int) bool { return nn[i].Name < nn[j].Name })
+	return nn, errs
+}
+
+// NewLoader returns a loader for the config that parses the stored
+// definitions. Definitions that became invalid, e.g. because a namespace they
+// reference was removed from the configuration, are still loaded as far as
+// they could be parsed, and the errors are logged.
+func NewLoader(d interface {
+	ManagerProvider
+	x.LoggerProvider
+}) config.ManagedNamespaceLoader {
+	return func(ctx context.Context, configured []*namespace.Namespace) ([]*namespace.Namespace, error) {
+		definitions, err := d.NamespaceDefinitionManager().ListNamespaceDefinitions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		nn, errs := Parse(configured, definitions)
+		for _, err := range errs {
+			d.Logger().WithError(err).Warn("A stored namespace definition is invalid.")
+		}
+		return nn, nil
+	}
+}
@@ -0,0 +1,461 @@
+package definition
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ory/keto/internal/authn"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	handlerDeps interface {
+		ManagerProvider
+		authn.AuthenticatorProvider
+		x.LoggerProvider
+		x.WriterProvider
+		config.Provider
+	}
+	handler struct {
+		d handlerDeps
+	}
+)
+
+const (
+	RouteBase = "/admin/namespaces"
+	RouteItem = RouteBase + "/:name"
+)
+
+var errPreconditionRequired = herodot.DefaultError{
+	CodeField:     http.StatusPreconditionRequired,
+	StatusField:   http.StatusText(http.StatusPreconditionRequired),
+	GRPCCodeField: codes.FailedPrecondition,
+	ErrorField:    "The version of the namespace definition has to be sent in the If-Match header.",
+}
+
+func NewHandler(d handlerDeps) *handler {
+	return &handler{
+		d: d,
+	}
+}
+
+func (h *handler) RegisterReadRoutes(_ *x.ReadRouter) {}
+
+func (h *handler) RegisterWriteRoutes(_ *x.WriteRouter) {}
+
+func (h *handler) RegisterAdminRoutes(r *x.AdminRouter) {
+	r.GET(RouteBase, h.authenticated(h.listDefinitions))
+	r.POST(RouteBase, h.authenticated(h.createDefinition))
+	r.GET(RouteItem, h.authenticated(h.getDefinition))
+	r.PUT(RouteItem, h.authenticated(h.updateDefinition))
+	r.DELETE(RouteItem, h.authenticated(h.deleteDefinition))
+}
+
+// The namespace administration API is only available over REST, as there is
+// no protobuf definition for it yet.
+func (h *handler) RegisterReadGRPC(_ *grpc.Server) {}
+
+func (h *handler) RegisterWriteGRPC(_ *grpc.Server) {}
+
+// authenticated only calls next if the namespace administration API is enabled
+// and the request is authenticated. If an authenticator is configured, the
+// request needs a subject from authn like every other API request. Otherwise,
+// it has to present one of the API keys of the namespace administration API.
+func (h *handler) authenticated(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := r.Context()
+		c := h.d.Config(ctx)
+		if !c.NamespaceAPIEnabled() {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The namespace administration API is disabled.")))
+			return
+		}
+
+		if h.d.Authenticator().Enabled(ctx) {
+			if _, ok := authn.FromContext(ctx); !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				h.d.Writer().WriteError(w, r, errors.WithStack(authn.ErrMissingToken))
+				return
+			}
+			next(w, r, ps)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		for _, key := range c.NamespaceAPIKeys() {
+			if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+				next(w, r, ps)
+				return
+			}
+		}
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithReason("A valid API key has to be provided as bearer token.")))
+	}
+}
+
+// validate checks that the definitions, as they would be after the change, are
+// valid together with the configured namespaces.
+func (h *handler) validate(ctx context.Context, definitions []*Definition) error {
+	nm, err := h.d.Config(ctx).ConfiguredNamespaceManager()
+	if err != nil {
+		return err
+	}
+	configured, err := nm.Namespaces(ctx)
+	if err != nil {
+		return err
+	}
+	if _, errs := Parse(configured, definitions); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return errors.WithStack(herodot.ErrBadRequest.
+			WithReason("The namespace definitions would be invalid after this change.").
+			WithDetail("errors", msgs))
+	}
+	return nil
+}
+
+// isConfigured returns whether the namespace is declared in the configuration.
+func (h *handler) isConfigured(ctx context.Context, name string) (bool, error) {
+	nm, err := h.d.Config(ctx).ConfiguredNamespaceManager()
+	if err != nil {
+		return false, err
+	}
+	_, err = nm.GetNamespaceByName(ctx, name)
+	if errors.Is(err, herodot.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// etag returns the entity tag of the definition, which is its version.
+func etag(d *Definition) string {
+	return strconv.Quote(strconv.FormatInt(d.Version, 10))
+}
+
+// ifMatch returns the version in the If-Match header, or false if there is no
+// such header. The version is zero for "*", which matches any version, and
+// negative if the header does not contain a version, which never matches.
+func ifMatch(r *http.Request) (int64, bool) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" {
+		return 0, false
+	} else if tag == "*" {
+		return 0, true
+	}
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return -1, true
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return -1, true
+	}
+	return version, true
+}
+
+// changed makes the change visible to this instance right away. Other
+// instances pick it up with their next refresh.
+func (h *handler) changed(ctx context.Context) {
+	h.d.Config(ctx).ReloadManagedNamespaces()
+}
+
+// swagger:route GET /admin/namespaces write listNamespaceDefinitions
+//
+// # List Namespace Definitions
+//
+// Lists the namespace definitions managed through the namespace administration
+// API. Namespaces from the configuration are not included.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: listNamespaceDefinitionsResponse
+//	  401: genericError
+//	  404: genericError
+//	  500: genericError
+func (h *handler) listDefinitions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	definitions, err := h.d.NamespaceDefinitionManager().ListNamespaceDefinitions(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	res := &ketoapi.ListNamespaceDefinitionsResponse{Definitions: make([]*ketoapi.NamespaceDefinition, len(definitions))}
+	for i, d := range definitions {
+		res.Definitions[i] = d.ToAPI()
+	}
+	h.d.Writer().Write(w, r, res)
+}
+
+// swagger:route GET /admin/namespaces/{name} write getNamespaceDefinition
+//
+// # Get a Namespace Definition
+//
+// The version of the definition is also returned as the ETag header.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: namespaceDefinition
+//	  401: genericError
+//	  404: genericError
+//	  500: genericError
+func (h *handler) getDefinition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	d, err := h.d.NamespaceDefinitionManager().GetNamespaceDefinition(r.Context(), ps.ByName("name"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(d))
+	h.d.Writer().Write(w, r, d.ToAPI())
+}
+
+// swagger:route POST /admin/namespaces write createNamespaceDefinition
+//
+// # Create a Namespace Definition
+//
+// Creates a namespace from its Ory Permission Language source. The source has
+// to declare exactly the namespace with the given name. It can reference the
+// configured namespaces, and import other managed namespaces by name, e.g.
+// `import { Group } from "./Group"`.
+//
+//	Consumes:
+//	-  application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  201: namespaceDefinition
+//	  400: genericError
+//	  401: genericError
+//	  404: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) createDefinition(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var body ketoapi.NamespaceDefinition
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+	if body.Name == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError("the name of the namespace is required")))
+		return
+	}
+
+	if configured, err := h.isConfigured(ctx, body.Name); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	} else if configured {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReasonf("The namespace %q is declared in the configuration.", body.Name)))
+		return
+	}
+
+	definitions, err := h.d.NamespaceDefinitionManager().ListNamespaceDefinitions(ctx)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	for _, d := range definitions {
+		if d.Name == body.Name {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReasonf("The namespace %q already exists.", body.Name)))
+			return
+		}
+	}
+
+	d := &Definition{Name: body.Name, OPL: body.OPL}
+	if err := h.validate(ctx, append(definitions, d)); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Logger().WithField("namespace", d.Name).Debug("creating namespace definition")
+	if err := h.d.NamespaceDefinitionManager().CreateNamespaceDefinition(ctx, d); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.changed(ctx)
+
+	w.Header().Set("ETag", etag(d))
+	h.d.Writer().WriteCreated(w, r, RouteBase+"/"+d.Name, d.ToAPI())
+}
+
+// swagger:route PUT /admin/namespaces/{name} write updateNamespaceDefinition
+//
+// # Update a Namespace Definition
+//
+// Replaces the Ory Permission Language source of a namespace. The change is
+// rejected if it would invalidate other managed namespaces.
+//
+// The If-Match header has to contain the ETag of the definition the change is
+// based on, so that concurrent changes are not overwritten. If the definition
+// was changed in the meantime, the update fails with 412. The ETag `*` updates
+// any version.
+//
+//	Consumes:
+//	-  application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: namespaceDefinition
+//	  400: genericError
+//	  401: genericError
+//	  404: genericError
+//	  412: genericError
+//	  428: genericError
+//	  500: genericError
+func (h *handler) updateDefinition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	name := ps.ByName("name")
+
+	version, ok := ifMatch(r)
+	if !ok {
+		h.d.Writer().WriteError(w, r, errors.WithStack(errPreconditionRequired))
+		return
+	}
+
+	var body ketoapi.NamespaceDefinition
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+	if body.Name != "" && body.Name != name {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("the name %q in the body does not match the name %q in the path, use the rename tooling to rename namespaces", body.Name, name)))
+		return
+	}
+
+	definitions, err := h.d.NamespaceDefinitionManager().ListNamespaceDefinitions(ctx)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	var d *Definition
+	for _, existing := range definitions {
+		if existing.Name == name {
+			d = existing
+		}
+	}
+	if d == nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf("The namespace %q does not exist.", name)))
+		return
+	}
+	if version != 0 && version != d.Version {
+		h.d.Writer().WriteError(w, r, errors.WithStack(ErrVersionMismatch))
+		return
+	}
+	d.OPL, d.Version = body.OPL, version
+	if err := h.validate(ctx, definitions); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Logger().WithField("namespace", name).Debug("updating namespace definition")
+	if err := h.d.NamespaceDefinitionManager().UpdateNamespaceDefinition(ctx, d); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.changed(ctx)
+
+	w.Header().Set("ETag", etag(d))
+	h.d.Writer().Write(w, r, d.ToAPI())
+}
+
+// swagger:route DELETE /admin/namespaces/{name} write deleteNamespaceDefinition
+//
+// # Delete a Namespace Definition
+//
+// Deletes a namespace definition. The change is rejected if other managed
+// namespaces reference the namespace. Relation tuples of the namespace are not
+// deleted. If the If-Match header is set, the definition is only deleted if it
+// still has that ETag.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  400: genericError
+//	  401: genericError
+//	  404: genericError
+//	  412: genericError
+//	  500: genericError
+func (h *handler) deleteDefinition(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	name := ps.ByName("name")
+
+	definitions, err := h.d.NamespaceDefinitionManager().ListNamespaceDefinitions(ctx)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	version, _ := ifMatch(r)
+	remaining := make([]*Definition, 0, len(definitions))
+	for _, d := range definitions {
+		if d.Name != name {
+			remaining = append(remaining, d)
+		} else if version != 0 && version != d.Version {
+			h.d.Writer().WriteError(w, r, errors.WithStack(ErrVersionMismatch))
+			return
+		}
+	}
+	if len(remaining) == len(definitions) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf("The namespace %q does not exist.", name)))
+		return
+	}
+	if err := h.validate(ctx, remaining); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Logger().WithField("namespace", name).Debug("deleting namespace definition")
+	if err := h.d.NamespaceDefinitionManager().DeleteNamespaceDefinition(ctx, name, version); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.changed(ctx)
+
+	w.WriteHeader(http.StatusNoContent)
+}
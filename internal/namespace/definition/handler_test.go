@@ -0,0 +1,269 @@
+package definition_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/definition"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+const apiKey = "0123456789abcdef"
+
+func TestNamespaceAPI(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "User"}}),
+		driver.WithConfig(config.KeyNamespaceAPIEnabled, true),
+		driver.WithConfig(config.KeyNamespaceAPIKeys, []string{apiKey}),
+	)
+	r := &x.AdminRouter{Router: httprouter.New()}
+	definition.NewHandler(reg).RegisterAdminRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	doWithHeader := func(t *testing.T, method, path string, body interface{}, header http.Header) (int, http.Header, string) {
+		var reqBody io.Reader
+		if body != nil {
+			raw, err := json.Marshal(body)
+			require.NoError(t, err)
+			reqBody = bytes.NewReader(raw)
+		}
+		req, err := http.NewRequest(method, ts.URL+path, reqBody)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Header, string(raw)
+	}
+	do := func(t *testing.T, method, path string, body interface{}) (int, string) {
+		code, _, res := doWithHeader(t, method, path, body, nil)
+		return code, res
+	}
+	ifMatch := func(etag string) http.Header {
+		return http.Header{"If-Match": {etag}}
+	}
+
+	managed := func(t *testing.T) (names []string) {
+		nm, err := reg.Config(ctx).NamespaceManager()
+		require.NoError(t, err)
+		nn, err := nm.Namespaces(ctx)
+		require.NoError(t, err)
+		for _, n := range nn {
+			names = append(names, n.Name)
+		}
+		return
+	}
+
+	group := &ketoapi.NamespaceDefinition{
+		Name: "Group",
+		OPL: `class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}`,
+	}
+	document := &ketoapi.NamespaceDefinition{
+		Name: "Document",
+		OPL: `import { Group } from "./Group"
+
+class Document implements Namespace {
+  related: {
+    viewers: SubjectSet<Group, "members">[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+  }
+}`,
+	}
+
+	t.Run("case=requires an API key", func(t *testing.T) {
+		resp, err := ts.Client().Get(ts.URL + definition.RouteBase)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+definition.RouteBase, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer not the key")
+		resp, err = ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("case=creates namespaces", func(t *testing.T) {
+		code, header, body := doWithHeader(t, http.MethodPost, definition.RouteBase, group, nil)
+		require.Equal(t, http.StatusCreated, code, body)
+		assert.Equal(t, `"1"`, header.Get("ETag"))
+		assert.Equal(t, int64(1), gjson.Get(body, "version").Int())
+		assert.Equal(t, "Group", gjson.Get(body, "name").String())
+		assert.NotEmpty(t, gjson.Get(body, "created_at").String())
+
+		code, body = do(t, http.MethodPost, definition.RouteBase, document)
+		require.Equal(t, http.StatusCreated, code, body)
+
+		assert.Equal(t, []string{"User", "Document", "Group"}, managed(t))
+
+		n, err := reg.Config(ctx).NamespaceManager()
+		require.NoError(t, err)
+		doc, err := n.GetNamespaceByName(ctx, "Document")
+		require.NoError(t, err)
+		require.Len(t, doc.Relations, 2)
+	})
+
+	t.Run("case=rejects conflicts", func(t *testing.T) {
+		code, body := do(t, http.MethodPost, definition.RouteBase, group)
+		assert.Equal(t, http.StatusConflict, code, body)
+
+		code, body = do(t, http.MethodPost, definition.RouteBase, &ketoapi.NamespaceDefinition{Name: "User", OPL: "class User implements Namespace {}"})
+		assert.Equal(t, http.StatusConflict, code, body)
+	})
+
+	t.Run("case=rejects invalid definitions", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			def  *ketoapi.NamespaceDefinition
+			err  string
+		}{
+			{
+				name: "syntax error",
+				def:  &ketoapi.NamespaceDefinition{Name: "Folder", OPL: "class Folder implements Namespace {"},
+				err:  "end of input",
+			},
+			{
+				name: "wrong name",
+				def:  &ketoapi.NamespaceDefinition{Name: "Folder", OPL: "class File implements Namespace {}"},
+				err:  `the definition of namespace "Folder" has to declare exactly that namespace`,
+			},
+			{
+				name: "unknown reference",
+				def:  &ketoapi.NamespaceDefinition{Name: "Folder", OPL: "class Folder implements Namespace {\n  related: {\n    owners: Users[]\n  }\n}"},
+				err:  `namespace "Users" was not declared`,
+			},
+		} {
+			t.Run("case="+tc.name, func(t *testing.T) {
+				code, body := do(t, http.MethodPost, definition.RouteBase, tc.def)
+				assert.Equal(t, http.StatusBadRequest, code, body)
+				assert.Contains(t, gjson.Get(body, "error.details.errors.0").String(), tc.err)
+			})
+		}
+	})
+
+	t.Run("case=gets and lists namespaces", func(t *testing.T) {
+		code, header, body := doWithHeader(t, http.MethodGet, definition.RouteBase+"/Group", nil, nil)
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, group.OPL, gjson.Get(body, "opl").String())
+		assert.Equal(t, `"1"`, header.Get("ETag"))
+
+		code, body = do(t, http.MethodGet, definition.RouteBase, nil)
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, `["Document","Group"]`, gjson.Get(body, "definitions.#.name").Raw)
+
+		code, _ = do(t, http.MethodGet, definition.RouteBase+"/Folder", nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	updated := `class Group implements Namespace {
+  related: {
+    members: (User | SubjectSet<Group, "members">)[]
+  }
+}`
+
+	t.Run("case=updates namespaces", func(t *testing.T) {
+		code, _, body := doWithHeader(t, http.MethodPut, definition.RouteBase+"/Group", &ketoapi.NamespaceDefinition{OPL: "class Group implements Namespace {}"}, ifMatch(`"1"`))
+		assert.Equal(t, http.StatusBadRequest, code, "Document references Group#members: %s", body)
+
+		code, header, body := doWithHeader(t, http.MethodPut, definition.RouteBase+"/Group", &ketoapi.NamespaceDefinition{OPL: updated}, ifMatch(`"1"`))
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, updated, gjson.Get(body, "opl").String())
+		assert.Equal(t, `"2"`, header.Get("ETag"))
+
+		n, err := reg.Config(ctx).NamespaceManager()
+		require.NoError(t, err)
+		g, err := n.GetNamespaceByName(ctx, "Group")
+		require.NoError(t, err)
+		require.Len(t, g.Relations, 1)
+		assert.Len(t, g.Relations[0].Types, 2)
+
+		code, _, _ = doWithHeader(t, http.MethodPut, definition.RouteBase+"/Folder", &ketoapi.NamespaceDefinition{OPL: "class Folder implements Namespace {}"}, ifMatch("*"))
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("case=does not overwrite concurrent changes", func(t *testing.T) {
+		code, body := do(t, http.MethodPut, definition.RouteBase+"/Group", &ketoapi.NamespaceDefinition{OPL: updated})
+		assert.Equal(t, http.StatusPreconditionRequired, code, body)
+
+		for _, etag := range []string{`"1"`, "1", `"not a version"`} {
+			code, _, body = doWithHeader(t, http.MethodPut, definition.RouteBase+"/Group", &ketoapi.NamespaceDefinition{OPL: updated}, ifMatch(etag))
+			assert.Equal(t, http.StatusPreconditionFailed, code, "%s: %s", etag, body)
+		}
+		code, _, body = doWithHeader(t, http.MethodDelete, definition.RouteBase+"/Document", nil, ifMatch(`"2"`))
+		assert.Equal(t, http.StatusPreconditionFailed, code, body)
+
+		code, header, body := doWithHeader(t, http.MethodPut, definition.RouteBase+"/Group", &ketoapi.NamespaceDefinition{OPL: updated}, ifMatch("*"))
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, `"3"`, header.Get("ETag"))
+
+		d, err := reg.NamespaceDefinitionManager().GetNamespaceDefinition(ctx, "Group")
+		require.NoError(t, err)
+		d.Version = 2
+		assert.ErrorIs(t, reg.NamespaceDefinitionManager().UpdateNamespaceDefinition(ctx, d), definition.ErrVersionMismatch)
+		assert.ErrorIs(t, reg.NamespaceDefinitionManager().DeleteNamespaceDefinition(ctx, "Group", 2), definition.ErrVersionMismatch)
+	})
+
+	t.Run("case=deletes namespaces", func(t *testing.T) {
+		code, body := do(t, http.MethodDelete, definition.RouteBase+"/Group", nil)
+		assert.Equal(t, http.StatusBadRequest, code, "Document references Group: %s", body)
+
+		code, _, body = doWithHeader(t, http.MethodDelete, definition.RouteBase+"/Document", nil, ifMatch(`"1"`))
+		require.Equal(t, http.StatusNoContent, code, body)
+		code, body = do(t, http.MethodDelete, definition.RouteBase+"/Group", nil)
+		require.Equal(t, http.StatusNoContent, code, body)
+
+		assert.Equal(t, []string{"User"}, managed(t))
+
+		code, _ = do(t, http.MethodDelete, definition.RouteBase+"/Group", nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("case=disabled", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaceAPIEnabled, false))
+		t.Cleanup(func() { _ = reg.Config(ctx).Set(config.KeyNamespaceAPIEnabled, true) })
+
+		code, _ := do(t, http.MethodGet, definition.RouteBase, nil)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("case=requires an authenticated subject if authn is enabled", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyAuthnAPIKeys, []map[string]interface{}{{"id": "operator", "key": "an-authn-api-key-of-the-test"}}))
+		t.Cleanup(func() { _ = reg.Config(ctx).Set(config.KeyAuthnAPIKeys, nil) })
+
+		// The router of the test does not authenticate, so the request has
+		// no subject and the key of the namespace API is not enough.
+		code, _ := do(t, http.MethodGet, definition.RouteBase, nil)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
@@ -0,0 +1,33 @@
+package definition
+
+import (
+	"github.com/ory/keto/ketoapi"
+)
+
+// swagger:parameters getNamespaceDefinition updateNamespaceDefinition deleteNamespaceDefinition
+// nolint:deadcode,unused
+type namespaceName struct {
+	// The name of the namespace.
+	//
+	// required: true
+	// in: path
+	Name string `json:"name"`
+}
+
+// swagger:parameters createNamespaceDefinition updateNamespaceDefinition
+// nolint:deadcode,unused
+type namespaceDefinitionBody struct {
+	// in: body
+	Payload ketoapi.NamespaceDefinition
+}
+
+// swagger:parameters updateNamespaceDefinition deleteNamespaceDefinition
+// nolint:deadcode,unused
+type namespaceDefinitionVersion struct {
+	// The ETag of the definition the change is based on, or `*` for any
+	// version. It is required for updates.
+	//
+	// in: header
+	// name: If-Match
+	IfMatch string `json:"If-Match"`
+}
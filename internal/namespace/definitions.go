@@ -24,6 +24,22 @@ type (
 		Namespaces(ctx context.Context) ([]*Namespace, error)
 		ShouldReload(newValue interface{}) bool
 	}
+	// LoadErrorReporter is implemented by managers that load the namespaces
+	// from files, so that the readiness check can report files that could
+	// not be loaded.
+	LoadErrorReporter interface {
+		// LoadError returns why namespaces could not be loaded, unless the
+		// last known version of them is still used.
+		LoadError() error
+	}
+	// Versioner is implemented by managers that know when their namespaces
+	// change, so that values derived from the namespaces can be cached.
+	Versioner interface {
+		// Version returns a value that differs for every set of namespaces
+		// returned for the context, also across managers. It is empty if
+		// the version is unknown.
+		Version(ctx context.Context) string
+	}
 	ManagerProvider interface {
 		NamespaceManager() (Manager, error)
 	}
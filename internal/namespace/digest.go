@@ -0,0 +1,35 @@
+package namespace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+// Digest returns the SHA-256 hash of the namespaces including their
+// relations, independent of the order of the namespaces. It only changes if
+// the namespace configuration changes.
+func Digest(nn []*Namespace) (string, error) {
+	type withRelations struct {
+		*Namespace
+		Relations []ast.Relation `json:"relations,omitempty"`
+	}
+
+	sorted := make([]withRelations, len(nn))
+	for i, n := range nn {
+		sorted[i] = withRelations{Namespace: n, Relations: n.Relations}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	raw, err := json.Marshal(sorted)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
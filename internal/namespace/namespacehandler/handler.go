@@ -0,0 +1,135 @@
+package namespacehandler
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/internal/x"
+)
+
+type (
+	handlerDeps interface {
+		x.WriterProvider
+		config.Provider
+	}
+	handler struct {
+		d handlerDeps
+	}
+
+	// The namespaces that are currently loaded by the server.
+	//
+	// swagger:model effectiveNamespaces
+	EffectiveNamespaces struct {
+		// required: true
+		Namespaces []*EffectiveNamespace `json:"namespaces"`
+	}
+
+	// A namespace with the relations and permissions it declares.
+	//
+	// swagger:model effectiveNamespace
+	EffectiveNamespace struct {
+		// required: true
+		Name string `json:"name"`
+		// The relations and permissions of the namespace. Permissions have a
+		// subject-set rewrite.
+		//
+		// required: true
+		Relations []ast.Relation `json:"relations"`
+	}
+)
+
+const (
+	RouteBase = "/admin/effective-namespaces"
+
+	FormatJSON = "json"
+	FormatOPL  = "opl"
+)
+
+func NewHandler(d handlerDeps) *handler {
+	return &handler{
+		d: d,
+	}
+}
+
+func (h *handler) RegisterReadRoutes(_ *x.ReadRouter) {}
+
+func (h *handler) RegisterWriteRoutes(_ *x.WriteRouter) {}
+
+func (h *handler) RegisterAdminRoutes(r *x.AdminRouter) {
+	r.GET(RouteBase, h.getEffectiveNamespaces)
+}
+
+// The effective namespaces are only available over REST, as there is no
+// protobuf definition for them yet.
+func (h *handler) RegisterReadGRPC(_ *grpc.Server) {}
+
+func (h *handler) RegisterWriteGRPC(_ *grpc.Server) {}
+
+// swagger:route GET /admin/effective-namespaces namespace getEffectiveNamespaces
+//
+// # Get the Effective Namespaces
+//
+// Use this endpoint to get the namespaces the server currently uses, including
+// the namespaces managed through the namespace administration API. With
+// `format=opl`, the namespaces are returned as an Ory Permission Language
+// model instead of their JSON syntax tree.
+//
+//	Produces:
+//	- application/json
+//	- text/plain
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: effectiveNamespaces
+//	  400: genericError
+//	  500: genericError
+func (h *handler) getEffectiveNamespaces(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", FormatJSON, FormatOPL:
+	default:
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unknown format %q, expected %q or %q.", format, FormatJSON, FormatOPL)))
+		return
+	}
+
+	nm, err := h.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	namespaces, err := nm.Namespaces(ctx)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if format == FormatOPL {
+		opl, err := schema.Print(namespaces)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Could not print the namespaces: %s", err)))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(opl))
+		return
+	}
+
+	res := &EffectiveNamespaces{Namespaces: make([]*EffectiveNamespace, len(namespaces))}
+	for i, n := range namespaces {
+		res.Namespaces[i] = &EffectiveNamespace{Name: n.Name, Relations: n.Relations}
+		if res.Namespaces[i].Relations == nil {
+			res.Namespaces[i].Relations = []ast.Relation{}
+		}
+	}
+	h.d.Writer().Write(w, r, res)
+}
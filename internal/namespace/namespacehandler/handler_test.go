@@ -0,0 +1,81 @@
+package namespacehandler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+const opl = `class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    parents: Document[]
+  }
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.owners.includes(ctx.subject) ||
+      this.related.parents.traverse((s) => s.permits.view(ctx)),
+  }
+}
+`
+
+func TestEffectiveNamespaces(t *testing.T) {
+	parsed, errs := schema.Parse(opl)
+	require.Empty(t, errs)
+	nn := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		nn[i] = &parsed[i]
+	}
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces(nn))
+	r := &x.AdminRouter{Router: httprouter.New()}
+	namespacehandler.NewHandler(reg).RegisterAdminRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	get := func(t *testing.T, query string) (*http.Response, string) {
+		resp, err := ts.Client().Get(ts.URL + namespacehandler.RouteBase + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	t.Run("case=json", func(t *testing.T) {
+		resp, body := get(t, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+		assert.Equal(t, `["User","Document"]`, gjson.Get(body, "namespaces.#.name").Raw)
+		assert.Equal(t, "[]", gjson.Get(body, "namespaces.0.relations").Raw)
+		assert.Equal(t, `["owners","parents","view"]`, gjson.Get(body, "namespaces.1.relations.#.name").Raw)
+		assert.Equal(t, "or", gjson.Get(body, "namespaces.1.relations.2.rewrite.operator").String())
+	})
+
+	t.Run("case=opl", func(t *testing.T) {
+		resp, body := get(t, "?format=opl")
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+		assert.Equal(t, opl, body)
+	})
+
+	t.Run("case=unknown format", func(t *testing.T) {
+		resp, body := get(t, "?format=yaml")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	})
+}
@@ -0,0 +1,10 @@
+package namespacehandler
+
+// swagger:parameters getEffectiveNamespaces
+// nolint:deadcode,unused
+type getEffectiveNamespacesParams struct {
+	// The format of the response, either "json" (the default) or "opl".
+	//
+	// in: query
+	Format string `json:"format"`
+}
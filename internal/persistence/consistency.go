@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+)
+
+// Consistency determines whether a read may be served by a read replica.
+type Consistency string
+
+const (
+	// ConsistencyEventual allows reads from read replicas, which might lag
+	// behind the primary database.
+	ConsistencyEventual Consistency = "eventual"
+	// ConsistencyStrong reads from the primary database.
+	ConsistencyStrong Consistency = "strong"
+
+	// ConsistencyHeader is the HTTP header and gRPC metadata key that
+	// overrides the consistency of a request.
+	ConsistencyHeader = "Keto-Consistency"
+)
+
+type consistencyKey struct{}
+
+// ParseConsistency parses the consistency a client requested.
+func ParseConsistency(s string) (Consistency, error) {
+	switch c := Consistency(s); c {
+	case ConsistencyEventual, ConsistencyStrong:
+		return c, nil
+	}
+	return "", errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+		"Unknown consistency %q, expected %q or %q.", s, ConsistencyEventual, ConsistencyStrong))
+}
+
+// WithConsistency returns a context in which reads have the given consistency.
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, c)
+}
+
+// ConsistencyFromContext returns the consistency of reads in the context. Reads
+// are strongly consistent unless the context says otherwise, so that only the
+// read API is served by read replicas.
+func ConsistencyFromContext(ctx context.Context) Consistency {
+	if c, ok := ctx.Value(consistencyKey{}).(Consistency); ok {
+		return c
+	}
+	return ConsistencyStrong
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/gobuffalo/pop/v6"
 
+	"github.com/ory/keto/internal/cdc"
+	"github.com/ory/keto/internal/namespace/definition"
 	"github.com/ory/keto/internal/relationtuple"
 )
 
@@ -15,8 +17,16 @@ type (
 	Persister interface {
 		relationtuple.Manager
 		relationtuple.MappingManager
+		relationtuple.HistoryManager
+		relationtuple.SoftDeleteManager
+		relationtuple.SchemaMigrationManager
+		relationtuple.NamespaceRenameManager
+		cdc.Outbox
+		definition.Manager
 
 		Connection(ctx context.Context) *pop.Connection
+		SetConnections(conn *pop.Connection, replicas []*pop.Connection)
+		LoadNamespaceAliases(ctx context.Context) (map[string]string, error)
 	}
 	Migrator interface {
 		MigrationBox(ctx context.Context) (*popx.MigrationBox, error)
@@ -26,6 +36,26 @@ type (
 	Provider interface {
 		Persister() Persister
 	}
+	// Partitioner converts the relation tuple table into a partitioned table.
+	Partitioner interface {
+		// PartitionRelationTuplesPlan returns the statements that
+		// PartitionRelationTuples executes.
+		PartitionRelationTuplesPlan(ctx context.Context, key PartitionKey, partitions int) ([]string, error)
+		PartitionRelationTuples(ctx context.Context, key PartitionKey, partitions int) error
+	}
+	// PartitionKey is the column the relation tuple table is partitioned by.
+	PartitionKey string
+)
+
+const (
+	// PartitionByNamespace keeps the relation tuples of a namespace in one
+	// partition, so that queries with a namespace only read one partition.
+	PartitionByNamespace PartitionKey = "namespace"
+	// PartitionByShardID spreads the relation tuples evenly over the
+	// partitions, independent of the size of the namespaces. PostgreSQL can
+	// not keep the relation tuples unique across these partitions, so it is
+	// rejected once the unique indexes of the relation tuples exist.
+	PartitionByShardID PartitionKey = "shard_id"
 )
 
 var (
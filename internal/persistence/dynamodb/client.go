@@ -0,0 +1,243 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x/awsx"
+)
+
+type (
+	// Client calls the DynamoDB JSON API. Only the operations and the string
+	// attributes the persister needs are supported.
+	Client struct {
+		endpoint *url.URL
+		region   string
+		creds    awsx.Credentials
+		hc       *http.Client
+	}
+	// apiError is an error response of the DynamoDB API.
+	apiError struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+
+	attribute struct {
+		S string `json:"S"`
+	}
+	item map[string]attribute
+
+	getItemInput struct {
+		TableName      string
+		Key            item
+		ConsistentRead bool `json:",omitempty"`
+	}
+	getItemOutput struct {
+		Item item
+	}
+	queryInput struct {
+		TableName                 string
+		IndexName                 string            `json:",omitempty"`
+		KeyConditionExpression    string            `json:",omitempty"`
+		FilterExpression          string            `json:",omitempty"`
+		ExpressionAttributeNames  map[string]string `json:",omitempty"`
+		ExpressionAttributeValues item              `json:",omitempty"`
+		ExclusiveStartKey         item              `json:",omitempty"`
+		Limit                     int               `json:",omitempty"`
+		ConsistentRead            bool              `json:",omitempty"`
+	}
+	queryOutput struct {
+		Items            []item
+		LastEvaluatedKey item
+	}
+	transactWriteItemsInput struct {
+		TransactItems []transactWriteItem
+	}
+	transactWriteItem struct {
+		Put    *putRequest    `json:",omitempty"`
+		Delete *deleteRequest `json:",omitempty"`
+	}
+	putRequest struct {
+		TableName string `json:",omitempty"`
+		Item      item
+	}
+	deleteRequest struct {
+		TableName string `json:",omitempty"`
+		Key       item
+	}
+	writeRequest struct {
+		PutRequest    *putRequest    `json:",omitempty"`
+		DeleteRequest *deleteRequest `json:",omitempty"`
+	}
+	batchWriteItemInput struct {
+		RequestItems map[string][]writeRequest
+	}
+	batchWriteItemOutput struct {
+		UnprocessedItems map[string][]writeRequest
+	}
+	keysAndAttributes struct {
+		Keys           []item
+		ConsistentRead bool `json:",omitempty"`
+	}
+	batchGetItemInput struct {
+		RequestItems map[string]keysAndAttributes
+	}
+	batchGetItemOutput struct {
+		Responses       map[string][]item
+		UnprocessedKeys map[string]keysAndAttributes
+	}
+)
+
+const (
+	// maxTransactItems and maxBatchWriteItems are the limits of the DynamoDB
+	// API for one request.
+	maxTransactItems   = 100
+	maxBatchWriteItems = 25
+	maxBatchGetItems   = 100
+	// maxUnprocessedRetries is how often unprocessed items of a batch request
+	// are retried, with an exponential backoff.
+	maxUnprocessedRetries = 8
+)
+
+// NewClient returns a client for the DynamoDB API of the region. The endpoint
+// can be set for DynamoDB compatible databases, e.g. DynamoDB local.
+func NewClient(endpoint, region string, creds awsx.Credentials) (*Client, error) {
+	if region == "" {
+		return nil, errors.New("the AWS region of DynamoDB is not set")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://dynamodb.%s.amazonaws.com", region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Client{
+		endpoint: u,
+		region:   region,
+		creds:    creds,
+		hc:       &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (e *apiError) Error() string {
+	// The type is namespaced, e.g.
+	// com.amazonaws.dynamodb.v20120810#ResourceNotFoundException.
+	return fmt.Sprintf("DynamoDB %s: %s", e.Type[strings.LastIndex(e.Type, "#")+1:], e.Message)
+}
+
+func (e *apiError) is(name string) bool {
+	return strings.HasSuffix(e.Type, "#"+name) || e.Type == name
+}
+
+// do calls the operation of the DynamoDB API.
+func (c *Client) do(ctx context.Context, operation string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+operation)
+	awsx.SignRequest(req, c.creds, c.region, "dynamodb", awsx.PayloadHash(body), time.Now())
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &apiError{}
+		if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Type == "" {
+			return errors.Errorf("DynamoDB %s returned status %d: %s", operation, resp.StatusCode, raw)
+		}
+		return errors.WithStack(apiErr)
+	}
+	return errors.WithStack(json.NewDecoder(resp.Body).Decode(out))
+}
+
+// batchWrite writes the requests in batches, and retries unprocessed items.
+// It is not atomic.
+func (c *Client) batchWrite(ctx context.Context, table string, requests []writeRequest) error {
+	for len(requests) > 0 {
+		n := maxBatchWriteItems
+		if n > len(requests) {
+			n = len(requests)
+		}
+		batch := requests[:n]
+		requests = requests[n:]
+
+		for i := 0; len(batch) > 0; i++ {
+			if i == maxUnprocessedRetries {
+				return errors.Errorf("DynamoDB did not process %d items of a batch write", len(batch))
+			}
+			if i > 0 {
+				if err := backoff(ctx, i); err != nil {
+					return err
+				}
+			}
+			var out batchWriteItemOutput
+			if err := c.do(ctx, "BatchWriteItem", &batchWriteItemInput{RequestItems: map[string][]writeRequest{table: batch}}, &out); err != nil {
+				return err
+			}
+			batch = out.UnprocessedItems[table]
+		}
+	}
+	return nil
+}
+
+// batchGet returns the items of the keys that exist, and retries unprocessed
+// keys.
+func (c *Client) batchGet(ctx context.Context, table string, keys []item, consistent bool) ([]item, error) {
+	var res []item
+	for len(keys) > 0 {
+		n := maxBatchGetItems
+		if n > len(keys) {
+			n = len(keys)
+		}
+		batch := keys[:n]
+		keys = keys[n:]
+
+		for i := 0; len(batch) > 0; i++ {
+			if i == maxUnprocessedRetries {
+				return nil, errors.Errorf("DynamoDB did not process %d keys of a batch get", len(batch))
+			}
+			if i > 0 {
+				if err := backoff(ctx, i); err != nil {
+					return nil, err
+				}
+			}
+			var out batchGetItemOutput
+			if err := c.do(ctx, "BatchGetItem", &batchGetItemInput{RequestItems: map[string]keysAndAttributes{
+				table: {Keys: batch, ConsistentRead: consistent},
+			}}, &out); err != nil {
+				return nil, err
+			}
+			res = append(res, out.Responses[table]...)
+			batch = out.UnprocessedKeys[table].Keys
+		}
+	}
+	return res, nil
+}
+
+func backoff(ctx context.Context, attempt int) error {
+	select {
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	case <-time.After(time.Duration(1<<attempt) * 10 * time.Millisecond):
+		return nil
+	}
+}
@@ -0,0 +1,235 @@
+package dynamodb_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type (
+	// fakeDynamoDB is an in-memory DynamoDB table with the key schema and
+	// indexes of the persister. It supports the subset of the JSON API the
+	// persister uses.
+	fakeDynamoDB struct {
+		sync.Mutex
+		items map[[2]string]fakeItem
+		// unprocessed makes batch writes leave the last item unprocessed,
+		// so that the retries are tested.
+		unprocessed bool
+	}
+	fakeItem    map[string]map[string]string
+	fakeRequest struct {
+		IndexName                 string
+		KeyConditionExpression    string
+		FilterExpression          string
+		ExpressionAttributeNames  map[string]string
+		ExpressionAttributeValues fakeItem
+		ExclusiveStartKey         fakeItem
+		Limit                     int
+		Key                       fakeItem
+		TransactItems             []struct {
+			Put    *struct{ Item fakeItem }
+			Delete *struct{ Key fakeItem }
+		}
+		RequestItems map[string]json.RawMessage
+	}
+	writeRequests []struct {
+		PutRequest    *struct{ Item fakeItem } `json:",omitempty"`
+		DeleteRequest *struct{ Key fakeItem }  `json:",omitempty"`
+	}
+)
+
+var (
+	keyCondition = regexp.MustCompile(`^#pk = :pk( AND begins_with\(#sk, :sk\))?$`)
+	indexKeys    = map[string][2]string{"": {"pk", "sk"}, "namespace": {"npk", "nsk"}, "subject": {"spk", "ssk"}}
+)
+
+// newFakeDynamoDB starts the fake and returns its endpoint.
+func newFakeDynamoDB(t *testing.T) string {
+	ts := httptest.NewServer(&fakeDynamoDB{items: make(map[[2]string]fakeItem), unprocessed: true})
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func (i fakeItem) get(attr string) string {
+	return i[attr]["S"]
+}
+
+func (i fakeItem) key() [2]string {
+	return [2]string{i.get("pk"), i.get("sk")}
+}
+
+// position is the order of the item in the index.
+func (i fakeItem) position(index string) [3]string {
+	return [3]string{i.get(indexKeys[index][1]), i.get("pk"), i.get("sk")}
+}
+
+func less(a, b [3]string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		f.fail(w, "MissingAuthenticationTokenException", "the request is not signed")
+		return
+	}
+	var req fakeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.fail(w, "SerializationException", err.Error())
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	switch op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810."); op {
+	case "GetItem":
+		res := map[string]interface{}{}
+		if i, ok := f.items[req.Key.key()]; ok {
+			res["Item"] = i
+		}
+		f.respond(w, res)
+	case "Query", "Scan":
+		f.query(w, op, &req)
+	case "TransactWriteItems":
+		seen := make(map[[2]string]bool)
+		for _, ti := range req.TransactItems {
+			var key [2]string
+			if ti.Put != nil {
+				key = ti.Put.Item.key()
+			} else {
+				key = ti.Delete.Key.key()
+			}
+			if seen[key] {
+				f.fail(w, "ValidationException", "Transaction request cannot include multiple operations on one item")
+				return
+			}
+			seen[key] = true
+		}
+		for _, ti := range req.TransactItems {
+			if ti.Put != nil {
+				f.items[ti.Put.Item.key()] = ti.Put.Item
+			} else {
+				delete(f.items, ti.Delete.Key.key())
+			}
+		}
+		f.respond(w, map[string]interface{}{})
+	case "BatchWriteItem":
+		unprocessed := make(map[string]writeRequests)
+		for table, raw := range req.RequestItems {
+			var requests writeRequests
+			if err := json.Unmarshal(raw, &requests); err != nil {
+				f.fail(w, "SerializationException", err.Error())
+				return
+			}
+			if f.unprocessed && len(requests) > 1 {
+				unprocessed[table] = requests[len(requests)-1:]
+				requests = requests[:len(requests)-1]
+			}
+			for _, wr := range requests {
+				if wr.PutRequest != nil {
+					f.items[wr.PutRequest.Item.key()] = wr.PutRequest.Item
+				} else {
+					delete(f.items, wr.DeleteRequest.Key.key())
+				}
+			}
+		}
+		f.respond(w, map[string]interface{}{"UnprocessedItems": unprocessed})
+	case "BatchGetItem":
+		responses := make(map[string][]fakeItem)
+		for table, raw := range req.RequestItems {
+			var keys struct{ Keys []fakeItem }
+			if err := json.Unmarshal(raw, &keys); err != nil {
+				f.fail(w, "SerializationException", err.Error())
+				return
+			}
+			responses[table] = []fakeItem{}
+			for _, k := range keys.Keys {
+				if i, ok := f.items[k.key()]; ok {
+					responses[table] = append(responses[table], i)
+				}
+			}
+		}
+		f.respond(w, map[string]interface{}{"Responses": responses})
+	default:
+		f.fail(w, "UnknownOperationException", op)
+	}
+}
+
+func (f *fakeDynamoDB) query(w http.ResponseWriter, op string, req *fakeRequest) {
+	keys, ok := indexKeys[req.IndexName]
+	if !ok {
+		f.fail(w, "ValidationException", "unknown index "+req.IndexName)
+		return
+	}
+	var prefix *string
+	if op == "Query" {
+		m := keyCondition.FindStringSubmatch(req.KeyConditionExpression)
+		if m == nil || req.ExpressionAttributeNames["#pk"] != keys[0] || (m[1] != "" && req.ExpressionAttributeNames["#sk"] != keys[1]) {
+			f.fail(w, "ValidationException", "unsupported key condition "+req.KeyConditionExpression)
+			return
+		}
+		if m[1] != "" {
+			p := req.ExpressionAttributeValues.get(":sk")
+			prefix = &p
+		}
+	} else if req.FilterExpression != "#nid = :nid" {
+		f.fail(w, "ValidationException", "unsupported filter "+req.FilterExpression)
+		return
+	}
+
+	var candidates []fakeItem
+	for _, i := range f.items {
+		if op == "Query" && (i.get(keys[0]) != req.ExpressionAttributeValues.get(":pk") || i.get(keys[1]) == "" ||
+			(prefix != nil && !strings.HasPrefix(i.get(keys[1]), *prefix))) {
+			continue
+		}
+		if req.ExclusiveStartKey != nil && !less(req.ExclusiveStartKey.position(req.IndexName), i.position(req.IndexName)) {
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		return less(candidates[a].position(req.IndexName), candidates[b].position(req.IndexName))
+	})
+
+	res := map[string]interface{}{}
+	if req.Limit > 0 && len(candidates) >= req.Limit {
+		candidates = candidates[:req.Limit]
+		last := candidates[len(candidates)-1]
+		lastKey := fakeItem{"pk": last["pk"], "sk": last["sk"]}
+		if req.IndexName != "" {
+			lastKey[keys[0]], lastKey[keys[1]] = last[keys[0]], last[keys[1]]
+		}
+		res["LastEvaluatedKey"] = lastKey
+	}
+	items := []fakeItem{}
+	for _, i := range candidates {
+		if op == "Scan" && i.get(req.ExpressionAttributeNames["#nid"]) != req.ExpressionAttributeValues.get(":nid") {
+			continue
+		}
+		items = append(items, i)
+	}
+	res["Items"] = items
+	f.respond(w, res)
+}
+
+func (f *fakeDynamoDB) respond(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeDynamoDB) fail(w http.ResponseWriter, errType, msg string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"__type": "com.amazonaws.dynamodb.v20120810#" + errType, "message": msg})
+}
@@ -0,0 +1,110 @@
+// Package dynamodb stores the relation tuples and UUID mappings in an Amazon
+// DynamoDB table.
+//
+// All items are stored in one table with the string attributes "pk" (partition
+// key) and "sk" (sort key), and two global secondary indexes. The variable
+// parts of the keys are JSON arrays, so that they cannot be confused with
+// each other. For a relation tuple of the network nid, namespace ns, object
+// obj, relation rel, and subject, the attributes are
+//
+//	pk    ["nid","ns","obj"]           all relation tuples of an object
+//	sk    ["rel",subject...]           queried by the prefix ["rel",
+//	npk   ["nid","ns"]                 partition key of the index "namespace"
+//	nsk   tuple                        sort key of the index "namespace"
+//	spk   ["nid",subject...]           partition key of the index "subject"
+//	ssk   tuple                        sort key of the index "subject"
+//	nid   "nid"                        to scan the relation tuples of a network
+//	tuple ["ns","obj","rel",subject...]
+//
+// where subject is "id" for subject IDs, and "ns","obj","rel" for subject
+// sets. The table serves the forward lookups of the check engine, i.e. the
+// subjects of an object's relation, and the index "subject" the reverse
+// lookups, i.e. the relation tuples of a subject, optionally in a namespace.
+// Both indexes have to project all attributes.
+//
+// The UUID mappings are stored in the same table, with pk ["nid","uuid"], sk
+// "uuid_mapping", and the attribute "string_representation".
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoctx"
+)
+
+type (
+	Persister struct {
+		c     *Client
+		table string
+		d     dependencies
+		nid   uuid.UUID
+	}
+	dependencies interface {
+		x.LoggerProvider
+		x.TracingProvider
+		ketoctx.ContextualizerProvider
+		config.Provider
+	}
+)
+
+const (
+	IndexNamespace = "namespace"
+	IndexSubject   = "subject"
+
+	defaultPageSize = 100
+)
+
+var (
+	_ relationtuple.Manager            = (*Persister)(nil)
+	_ relationtuple.MappingManager     = (*Persister)(nil)
+	_ relationtuple.TransactionLimiter = (*Persister)(nil)
+)
+
+// NewPersister returns a persister that stores the data of the network in the
+// DynamoDB table.
+func NewPersister(reg dependencies, c *Client, table string, nid uuid.UUID) *Persister {
+	return &Persister{
+		c:     c,
+		table: table,
+		d:     reg,
+		nid:   nid,
+	}
+}
+
+func (p *Persister) NetworkID(ctx context.Context) uuid.UUID {
+	return p.d.Contextualizer().Network(ctx, p.nid)
+}
+
+// consistentRead returns whether reads from the table have to be strongly
+// consistent. Reads from the indexes are always eventually consistent.
+func consistentRead(ctx context.Context) bool {
+	return persistence.ConsistencyFromContext(ctx) != persistence.ConsistencyEventual
+}
+
+func encode(parts ...string) string {
+	// Marshalling strings never fails.
+	enc, _ := json.Marshal(parts)
+	return string(enc)
+}
+
+// encodePrefix returns the prefix of all encodings that start with the parts.
+func encodePrefix(parts ...string) string {
+	enc := encode(parts...)
+	return enc[:len(enc)-1] + ","
+}
+
+func decode(s string) ([]string, error) {
+	var parts []string
+	if err := json.Unmarshal([]byte(s), &parts); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parts, nil
+}
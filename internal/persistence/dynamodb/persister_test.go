@@ -0,0 +1,142 @@
+package dynamodb_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/persistence/dynamodb"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x/awsx"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestPersister(t *testing.T) {
+	t.Parallel()
+
+	endpoint := newFakeDynamoDB(t)
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+	ctx := context.Background()
+
+	newPersister := func(t *testing.T) *dynamodb.Persister {
+		c, err := dynamodb.NewClient(endpoint, "us-east-1", awsx.Credentials{AccessKeyID: "test", SecretAccessKey: "test"})
+		require.NoError(t, err)
+		return dynamodb.NewPersister(reg, c, "keto", uuid.Must(uuid.NewV4()))
+	}
+
+	t.Run("relationtuple.ManagerTest", func(t *testing.T) {
+		relationtuple.ManagerTest(t, newPersister(t))
+	})
+
+	t.Run("relationtuple.IsolationTest", func(t *testing.T) {
+		relationtuple.IsolationTest(t, newPersister(t), newPersister(t))
+	})
+
+	t.Run("relationtuple.UUIDMappingManagerTest", func(t *testing.T) {
+		relationtuple.MappingManagerTest(t, newPersister(t))
+	})
+
+	t.Run("case=unknown UUIDs map to empty strings", func(t *testing.T) {
+		s, err := newPersister(t).MapUUIDsToStrings(ctx, uuid.Must(uuid.NewV4()))
+		require.NoError(t, err)
+		assert.Equal(t, []string{""}, s)
+	})
+
+	t.Run("case=reverse lookup by subject", func(t *testing.T) {
+		p := newPersister(t)
+		subject := &relationtuple.SubjectSet{Namespace: "groups", Object: uuid.Must(uuid.NewV4()), Relation: "member"}
+		rs := []*relationtuple.RelationTuple{
+			{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "view", Subject: subject},
+			{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "edit", Subject: subject},
+			{Namespace: "folders", Object: uuid.Must(uuid.NewV4()), Relation: "view", Subject: subject},
+		}
+		require.NoError(t, p.WriteRelationTuples(ctx, rs...))
+
+		res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Subject: subject})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, rs, res)
+
+		docs := "docs"
+		res, _, err = p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &docs, Subject: subject})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, rs[:2], res)
+	})
+
+	t.Run("case=namespace with a common prefix", func(t *testing.T) {
+		p := newPersister(t)
+		obj := uuid.Must(uuid.NewV4())
+		rs := []*relationtuple.RelationTuple{
+			{Namespace: "docs", Object: obj, Relation: "view", Subject: &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}},
+			{Namespace: "docs", Object: obj, Relation: "viewer", Subject: &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}},
+		}
+		require.NoError(t, p.WriteRelationTuples(ctx, rs...))
+
+		view := "view"
+		res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &rs[0].Namespace, Object: &obj, Relation: &view})
+		require.NoError(t, err)
+		assert.Equal(t, rs[:1], res)
+	})
+
+	t.Run("case=rejects transactions over the DynamoDB limit", func(t *testing.T) {
+		p := newPersister(t)
+		ns := "limits"
+		rs := make([]*relationtuple.RelationTuple, 101)
+		for i := range rs {
+			rs[i] = &relationtuple.RelationTuple{Namespace: ns, Object: uuid.Must(uuid.NewV4()), Relation: "view", Subject: &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}}
+		}
+
+		err := p.TransactRelationTuples(ctx, rs, nil)
+		herodotErr := &herodot.DefaultError{}
+		require.ErrorAs(t, err, &herodotErr)
+		assert.Equal(t, http.StatusBadRequest, herodotErr.StatusCode())
+		res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &ns})
+		require.NoError(t, err)
+		assert.Len(t, res, 0, "nothing is written")
+
+		// Deleting a query result is not limited.
+		require.NoError(t, p.WriteRelationTuples(ctx, rs[:100]...))
+		require.NoError(t, p.WriteRelationTuples(ctx, rs[100:]...))
+		require.NoError(t, p.DeleteAllRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &ns}))
+		res, _, err = p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &ns})
+		require.NoError(t, err)
+		assert.Len(t, res, 0)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	endpoint := newFakeDynamoDB(t)
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "docs"}}),
+		driver.WithConfig(config.KeyDynamoDBTable, "keto"),
+		driver.WithConfig(config.KeyDynamoDBRegion, "eu-central-1"),
+		driver.WithConfig(config.KeyDynamoDBEndpoint, endpoint),
+	)
+	ctx := context.Background()
+
+	require.IsType(t, &dynamodb.Persister{}, reg.RelationTupleManager())
+	require.IsType(t, &dynamodb.Persister{}, reg.MappingManager())
+
+	u, err := reg.MappingManager().MapStringsToUUIDs(ctx, "readme", "alice")
+	require.NoError(t, err)
+	rt := &relationtuple.RelationTuple{
+		Namespace: "docs",
+		Object:    u[0],
+		Relation:  "view",
+		Subject:   &relationtuple.SubjectID{ID: u[1]},
+	}
+	require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, rt))
+
+	allowed, err := reg.PermissionEngine().CheckIsMember(ctx, rt, 0)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
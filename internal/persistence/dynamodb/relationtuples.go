@@ -0,0 +1,528 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// request is a query of the table or an index, or a scan of the table.
+	request struct {
+		operation string
+		input     queryInput
+		// keyAttributes are the attributes of the table and index keys,
+		// which continue the request after an item.
+		keyAttributes []string
+		// exact is set if all items of the request match the relation query.
+		exact bool
+	}
+)
+
+const walkPageSize = 1000
+
+func subjectParts(s relationtuple.Subject) []string {
+	if ss, ok := s.(*relationtuple.SubjectSet); ok {
+		return []string{ss.Namespace, ss.Object.String(), ss.Relation}
+	}
+	return []string{s.UniqueID().String()}
+}
+
+func encodeTuple(r *relationtuple.RelationTuple) string {
+	return encode(append([]string{r.Namespace, r.Object.String(), r.Relation}, subjectParts(r.Subject)...)...)
+}
+
+func decodeTuple(s string) (*relationtuple.RelationTuple, error) {
+	parts, err := decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != 4 && len(parts) != 6 {
+		return nil, errors.Errorf("malformed relation tuple %q", s)
+	}
+	r := &relationtuple.RelationTuple{
+		Namespace: parts[0],
+		Relation:  parts[2],
+	}
+	if r.Object, err = uuid.FromString(parts[1]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(parts) == 4 {
+		id, err := uuid.FromString(parts[3])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r.Subject = &relationtuple.SubjectID{ID: id}
+		return r, nil
+	}
+	obj, err := uuid.FromString(parts[4])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.Subject = &relationtuple.SubjectSet{Namespace: parts[3], Object: obj, Relation: parts[5]}
+	return r, nil
+}
+
+// tupleKey returns the primary key of the relation tuple.
+func (p *Persister) tupleKey(ctx context.Context, r *relationtuple.RelationTuple) item {
+	return item{
+		"pk": {S: encode(p.NetworkID(ctx).String(), r.Namespace, r.Object.String())},
+		"sk": {S: encode(append([]string{r.Relation}, subjectParts(r.Subject)...)...)},
+	}
+}
+
+// tupleItem returns the item of the relation tuple, see the package
+// documentation.
+func (p *Persister) tupleItem(ctx context.Context, r *relationtuple.RelationTuple) item {
+	nid, tuple := p.NetworkID(ctx).String(), encodeTuple(r)
+	i := p.tupleKey(ctx, r)
+	i["npk"] = attribute{S: encode(nid, r.Namespace)}
+	i["nsk"] = attribute{S: tuple}
+	i["spk"] = attribute{S: encode(append([]string{nid}, subjectParts(r.Subject)...)...)}
+	i["ssk"] = attribute{S: tuple}
+	i["nid"] = attribute{S: nid}
+	i["tuple"] = attribute{S: tuple}
+	return i
+}
+
+// queryTuple returns the relation tuple if the query matches at most one.
+func queryTuple(q *relationtuple.RelationQuery) (*relationtuple.RelationTuple, bool) {
+	if q.Namespace == nil || q.Object == nil || q.Relation == nil || q.Subject == nil {
+		return nil, false
+	}
+	return &relationtuple.RelationTuple{
+		Namespace: *q.Namespace,
+		Object:    *q.Object,
+		Relation:  *q.Relation,
+		Subject:   q.Subject,
+	}, true
+}
+
+func matches(q *relationtuple.RelationQuery, r *relationtuple.RelationTuple) bool {
+	return (q.Namespace == nil || *q.Namespace == r.Namespace) &&
+		(q.Object == nil || *q.Object == r.Object) &&
+		(q.Relation == nil || *q.Relation == r.Relation) &&
+		(q.Subject == nil || q.Subject.Equals(r.Subject))
+}
+
+// plan returns the request that reads the fewest items to answer the query.
+func (p *Persister) plan(ctx context.Context, q *relationtuple.RelationQuery) *request {
+	nid := p.NetworkID(ctx).String()
+	query := func(index, pk, pkValue, sk, skPrefix string) queryInput {
+		in := queryInput{
+			TableName:                 p.table,
+			IndexName:                 index,
+			KeyConditionExpression:    "#pk = :pk",
+			ExpressionAttributeNames:  map[string]string{"#pk": pk},
+			ExpressionAttributeValues: item{":pk": {S: pkValue}},
+			ConsistentRead:            index == "" && consistentRead(ctx),
+		}
+		if skPrefix != "" {
+			in.KeyConditionExpression += " AND begins_with(#sk, :sk)"
+			in.ExpressionAttributeNames["#sk"] = sk
+			in.ExpressionAttributeValues[":sk"] = attribute{S: skPrefix}
+		}
+		return in
+	}
+
+	switch {
+	case q.Namespace != nil && q.Object != nil:
+		var relPrefix string
+		if q.Relation != nil {
+			relPrefix = encodePrefix(*q.Relation)
+		}
+		return &request{
+			operation:     "Query",
+			input:         query("", "pk", encode(nid, *q.Namespace, q.Object.String()), "sk", relPrefix),
+			keyAttributes: []string{"pk", "sk"},
+			exact:         q.Subject == nil,
+		}
+	case q.Subject != nil:
+		var nsPrefix string
+		if q.Namespace != nil {
+			nsPrefix = encodePrefix(*q.Namespace)
+		}
+		return &request{
+			operation:     "Query",
+			input:         query(IndexSubject, "spk", encode(append([]string{nid}, subjectParts(q.Subject)...)...), "ssk", nsPrefix),
+			keyAttributes: []string{"spk", "ssk", "pk", "sk"},
+			exact:         q.Object == nil && q.Relation == nil,
+		}
+	case q.Namespace != nil:
+		return &request{
+			operation:     "Query",
+			input:         query(IndexNamespace, "npk", encode(nid, *q.Namespace), "", ""),
+			keyAttributes: []string{"npk", "nsk", "pk", "sk"},
+			exact:         q.Relation == nil,
+		}
+	}
+	// The limit of a scan applies before the filter, so it is never exact.
+	return &request{
+		operation: "Scan",
+		input: queryInput{
+			TableName:                 p.table,
+			FilterExpression:          "#nid = :nid",
+			ExpressionAttributeNames:  map[string]string{"#nid": "nid"},
+			ExpressionAttributeValues: item{":nid": {S: nid}},
+			ConsistentRead:            consistentRead(ctx),
+		},
+		keyAttributes: []string{"pk", "sk"},
+	}
+}
+
+// find returns up to limit relation tuples matching the query, starting after
+// the key, and their items. A negative limit returns all of them.
+func (p *Persister) find(ctx context.Context, q *relationtuple.RelationQuery, start item, limit int) ([]*relationtuple.RelationTuple, []item, error) {
+	if r, ok := queryTuple(q); ok {
+		if start != nil {
+			return nil, nil, nil
+		}
+		var out getItemOutput
+		if err := p.c.do(ctx, "GetItem", &getItemInput{TableName: p.table, Key: p.tupleKey(ctx, r), ConsistentRead: consistentRead(ctx)}, &out); err != nil {
+			return nil, nil, err
+		}
+		if out.Item == nil {
+			return nil, nil, nil
+		}
+		return []*relationtuple.RelationTuple{r}, []item{out.Item}, nil
+	}
+
+	req := p.plan(ctx, q)
+	req.input.Limit = limit
+	if !req.exact || limit < 0 {
+		req.input.Limit = walkPageSize
+	}
+	req.input.ExclusiveStartKey = start
+
+	var (
+		res   []*relationtuple.RelationTuple
+		items []item
+	)
+	for limit < 0 || len(res) < limit {
+		var out queryOutput
+		if err := p.c.do(ctx, req.operation, &req.input, &out); err != nil {
+			return nil, nil, err
+		}
+		for _, i := range out.Items {
+			r, err := decodeTuple(i["tuple"].S)
+			if err != nil {
+				return nil, nil, err
+			}
+			if matches(q, r) {
+				res, items = append(res, r), append(items, i)
+				if len(res) == limit {
+					break
+				}
+			}
+		}
+		if out.LastEvaluatedKey == nil {
+			break
+		}
+		req.input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+	return res, items, nil
+}
+
+func (p *Persister) parsePageToken(token string) (item, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.WithStack(persistence.ErrMalformedPageToken)
+	}
+	var key item
+	if err := json.Unmarshal(raw, &key); err != nil || len(key) == 0 {
+		return nil, errors.WithStack(persistence.ErrMalformedPageToken)
+	}
+	return key, nil
+}
+
+func encodePageToken(i item, keyAttributes []string) string {
+	key := make(item, len(keyAttributes))
+	for _, a := range keyAttributes {
+		key[a] = i[a]
+	}
+	// Marshalling strings never fails.
+	raw, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// page returns one page of the relation tuples matching the query, and the
+// token of the next page.
+func (p *Persister) page(ctx context.Context, query *relationtuple.RelationQuery, options []x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	pagination := x.GetPaginationOptions(options...)
+	size := pagination.Size
+	if size == 0 {
+		size = defaultPageSize
+	}
+	start, err := p.parsePageToken(pagination.Token)
+	if err != nil {
+		return nil, "", err
+	}
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+
+	res, items, err := p.find(ctx, query, start, size+1)
+	if err != nil {
+		return nil, "", err
+	}
+	var nextPageToken string
+	if len(res) > size {
+		res = res[:size]
+		nextPageToken = encodePageToken(items[size-1], p.plan(ctx, query).keyAttributes)
+	}
+	return res, nextPageToken, nil
+}
+
+func (p *Persister) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.GetRelationTuples")
+	defer span.End()
+
+	res, nextPageToken, err := p.page(ctx, query, options)
+	if err != nil {
+		return nil, "", err
+	}
+	if res == nil {
+		res = []*relationtuple.RelationTuple{}
+	}
+	return res, nextPageToken, nil
+}
+
+func (p *Persister) WriteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.WriteRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, rs, nil)
+}
+
+// TouchRelationTuples is the same as WriteRelationTuples, as DynamoDB does not
+// record when a relation tuple was written.
+func (p *Persister) TouchRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.TouchRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, rs, nil)
+}
+
+func (p *Persister) DeleteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.DeleteRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, nil, rs)
+}
+
+func (p *Persister) TransactRelationTuples(ctx context.Context, insert []*relationtuple.RelationTuple, delete []*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.TransactRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, insert, delete)
+}
+
+// DeleteAllRelationTuples deletes the matching relation tuples in batches, so
+// it is not atomic.
+func (p *Persister) DeleteAllRelationTuples(ctx context.Context, query *relationtuple.RelationQuery) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.DeleteAllRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	res, _, err := p.find(ctx, query, nil, -1)
+	if err != nil {
+		return err
+	}
+	return p.deleteFound(ctx, res)
+}
+
+func (p *Persister) DeleteRelationTuplesPage(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.DeleteRelationTuplesPage")
+	defer span.End()
+
+	res, nextPageToken, err := p.page(ctx, query, options)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := p.deleteFound(ctx, res); err != nil {
+		return 0, "", err
+	}
+	return len(res), nextPageToken, nil
+}
+
+// WalkRelationTuples walks the pages as they are read. DynamoDB has no
+// snapshots, so the pages can include concurrent changes.
+func (p *Persister) WalkRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, f func(ctx context.Context, page []*relationtuple.RelationTuple) error) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.WalkRelationTuples")
+	defer span.End()
+
+	var nextPage string
+	for {
+		page, next, err := p.page(ctx, query, []x.PaginationOptionSetter{x.WithToken(nextPage), x.WithSize(walkPageSize)})
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := f(ctx, page); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		nextPage = next
+	}
+}
+
+// CountRelationTuples always counts exactly, by reading all matching relation
+// tuples.
+func (p *Persister) CountRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, _ bool) (int, bool, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.CountRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	res, _, err := p.find(ctx, query, nil, -1)
+	return len(res), false, err
+}
+
+// transact inserts and then deletes the relation tuples in one transaction.
+// DynamoDB limits transactions to 100 items, so larger changes are rejected.
+// The subject limits of the namespaces are checked before the changes are
+// written, so concurrent writes can exceed them.
+func (p *Persister) transact(ctx context.Context, insert, delete []*relationtuple.RelationTuple) error {
+	for _, rs := range [][]*relationtuple.RelationTuple{insert, delete} {
+		for _, r := range rs {
+			if r.Subject == nil {
+				return errors.WithStack(ketoapi.ErrNilSubject)
+			}
+		}
+	}
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	if p.d.Config(ctx).StrictMode() {
+		for _, r := range insert {
+			if err := relationtuple.ValidateSchema(ctx, nm, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	// DynamoDB rejects requests that change an item more than once, so only
+	// the last change of each relation tuple is written.
+	var (
+		order   []string
+		changes = make(map[string]*relationtuple.RelationTuple)
+		deletes = make(map[string]bool)
+	)
+	for i, r := range append(append([]*relationtuple.RelationTuple{}, insert...), delete...) {
+		tuple := encodeTuple(r)
+		if _, ok := changes[tuple]; !ok {
+			order = append(order, tuple)
+		}
+		changes[tuple], deletes[tuple] = r, i >= len(insert)
+	}
+	if len(order) == 0 {
+		return nil
+	} else if len(order) > maxTransactItems {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+			"DynamoDB can change at most %d relation tuples in one transaction, but the request changes %d. Split the request into smaller transactions.", maxTransactItems, len(order)))
+	}
+	if err := p.checkCardinality(ctx, insert, changes, deletes); err != nil {
+		return err
+	}
+
+	items := make([]transactWriteItem, len(order))
+	for i, tuple := range order {
+		if deletes[tuple] {
+			items[i].Delete = &deleteRequest{TableName: p.table, Key: p.tupleKey(ctx, changes[tuple])}
+		} else {
+			items[i].Put = &putRequest{TableName: p.table, Item: p.tupleItem(ctx, changes[tuple])}
+		}
+	}
+	err = p.c.do(ctx, "TransactWriteItems", &transactWriteItemsInput{TransactItems: items}, &struct{}{})
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.is("TransactionCanceledException") || apiErr.is("TransactionConflictException")) {
+		return errors.WithStack(herodot.ErrConflict.WithReason("The relation tuples were changed concurrently, please retry."))
+	}
+	return err
+}
+
+// MaxTransactionSize returns the limit of DynamoDB transactions.
+func (p *Persister) MaxTransactionSize() int {
+	return maxTransactItems
+}
+
+// deleteFound deletes relation tuples that were just read, in batches that are
+// not atomic. Deleting a query result is idempotent, so a failed deletion can
+// be retried.
+func (p *Persister) deleteFound(ctx context.Context, rs []*relationtuple.RelationTuple) error {
+	requests := make([]writeRequest, len(rs))
+	for i, r := range rs {
+		requests[i].DeleteRequest = &deleteRequest{Key: p.tupleKey(ctx, r)}
+	}
+	return p.c.batchWrite(ctx, p.table, requests)
+}
+
+// checkCardinality returns a conflict error if any object of the inserted
+// relation tuples would have more subjects for the relation than the
+// namespace allows after the changes.
+func (p *Persister) checkCardinality(ctx context.Context, insert []*relationtuple.RelationTuple, changes map[string]*relationtuple.RelationTuple, deletes map[string]bool) error {
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	checked := make(map[string]bool)
+	for _, r := range insert {
+		object := encode(r.Namespace, r.Object.String(), r.Relation)
+		if checked[object] {
+			continue
+		}
+		checked[object] = true
+
+		max, err := relationtuple.MaxSubjects(ctx, nm, r.Namespace, r.Relation)
+		if err != nil {
+			return err
+		}
+		if max == 0 {
+			continue
+		}
+		existing, _, err := p.find(ctx, &relationtuple.RelationQuery{Namespace: &r.Namespace, Object: &r.Object, Relation: &r.Relation}, nil, -1)
+		if err != nil {
+			return err
+		}
+		subjects := make(map[string]bool, len(existing))
+		for _, e := range existing {
+			subjects[encodeTuple(e)] = true
+		}
+		for tuple, c := range changes {
+			if c.Namespace == r.Namespace && c.Object == r.Object && c.Relation == r.Relation {
+				subjects[tuple] = !deletes[tuple]
+			}
+		}
+		count := 0
+		for _, ok := range subjects {
+			if ok {
+				count++
+			}
+		}
+		if count > max {
+			return errors.WithStack(herodot.ErrConflict.WithReasonf(
+				"Relation %q in namespace %q allows at most %d subject(s) per object, but the object would have %d.",
+				r.Relation, r.Namespace, max, count))
+		}
+	}
+	return nil
+}
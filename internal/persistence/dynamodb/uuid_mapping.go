@@ -0,0 +1,79 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	uuidMappingSortKey   = "uuid_mapping"
+	uuidMappingAttribute = "string_representation"
+)
+
+func (p *Persister) uuidMappingKey(ctx context.Context, id uuid.UUID) item {
+	return item{
+		"pk": {S: encode(p.NetworkID(ctx).String(), id.String())},
+		"sk": {S: uuidMappingSortKey},
+	}
+}
+
+func (p *Persister) MapStringsToUUIDs(ctx context.Context, s ...string) ([]uuid.UUID, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.MapStringsToUUIDs")
+	defer span.End()
+
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	uuids := make([]uuid.UUID, len(s))
+	written := make(map[uuid.UUID]bool, len(s))
+	requests := make([]writeRequest, 0, len(s))
+	for i, val := range s {
+		uuids[i] = uuid.NewV5(p.NetworkID(ctx), val)
+		if written[uuids[i]] {
+			continue
+		}
+		written[uuids[i]] = true
+		mapping := p.uuidMappingKey(ctx, uuids[i])
+		mapping[uuidMappingAttribute] = attribute{S: val}
+		requests = append(requests, writeRequest{PutRequest: &putRequest{Item: mapping}})
+	}
+	if err := p.c.batchWrite(ctx, p.table, requests); err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
+
+// MapUUIDsToStrings maps unknown UUIDs to the empty string.
+func (p *Persister) MapUUIDsToStrings(ctx context.Context, u ...uuid.UUID) ([]string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.dynamodb.MapUUIDsToStrings")
+	defer span.End()
+
+	if len(u) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]item, 0, len(u))
+	requested := make(map[uuid.UUID]bool, len(u))
+	for _, id := range u {
+		if !requested[id] {
+			requested[id] = true
+			keys = append(keys, p.uuidMappingKey(ctx, id))
+		}
+	}
+	items, err := p.c.batchGet(ctx, p.table, keys, consistentRead(ctx))
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]string, len(items))
+	for _, i := range items {
+		byKey[i["pk"].S] = i[uuidMappingAttribute].S
+	}
+
+	res := make([]string, len(u))
+	for i, id := range u {
+		res[i] = byKey[p.uuidMappingKey(ctx, id)["pk"].S]
+	}
+	return res, nil
+}
@@ -0,0 +1,223 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+)
+
+type (
+	// NamespaceStorage is a relation tuple manager that stores the relation
+	// tuples of some namespaces instead of the primary one.
+	NamespaceStorage struct {
+		Manager    relationtuple.Manager
+		Namespaces []string
+	}
+	// NamespaceRouter routes relation tuple requests to the manager that
+	// stores the namespace. Requests without a namespace are answered by all
+	// managers in turn.
+	NamespaceRouter struct {
+		managers    []relationtuple.Manager
+		byNamespace map[string]relationtuple.Manager
+	}
+)
+
+var _ relationtuple.Manager = (*NamespaceRouter)(nil)
+
+func NewNamespaceRouter(primary relationtuple.Manager, storages ...NamespaceStorage) *NamespaceRouter {
+	r := &NamespaceRouter{
+		managers:    []relationtuple.Manager{primary},
+		byNamespace: make(map[string]relationtuple.Manager),
+	}
+	for _, s := range storages {
+		r.managers = append(r.managers, s.Manager)
+		for _, n := range s.Namespaces {
+			r.byNamespace[n] = s.Manager
+		}
+	}
+	return r
+}
+
+func (r *NamespaceRouter) manager(namespace string) relationtuple.Manager {
+	if m, ok := r.byNamespace[namespace]; ok {
+		return m
+	}
+	return r.managers[0]
+}
+
+// queryManager returns the manager that stores the namespace of the query, or
+// nil if the query has no namespace.
+func (r *NamespaceRouter) queryManager(query *relationtuple.RelationQuery) relationtuple.Manager {
+	if query == nil || query.Namespace == nil {
+		return nil
+	}
+	return r.manager(*query.Namespace)
+}
+
+// tuplesManager returns the manager that stores all the relation tuples. The
+// managers do not share transactions, so changes across them are rejected.
+func (r *NamespaceRouter) tuplesManager(rs ...[]*relationtuple.RelationTuple) (relationtuple.Manager, error) {
+	var (
+		m     relationtuple.Manager
+		first string
+	)
+	for _, tuples := range rs {
+		for _, t := range tuples {
+			tm := r.manager(t.Namespace)
+			if m == nil {
+				m, first = tm, t.Namespace
+				continue
+			}
+			if tm != m {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+					"The namespaces %q and %q are stored in different databases and cannot be changed in one transaction.", first, t.Namespace))
+			}
+		}
+	}
+	if m == nil {
+		return r.managers[0], nil
+	}
+	return m, nil
+}
+
+// parsePageToken splits the page token of a request that is answered by all
+// managers into the index of the manager and its own page token.
+func (r *NamespaceRouter) parsePageToken(options []x.PaginationOptionSetter) (int, string, error) {
+	token := x.GetPaginationOptions(options...).Token
+	if token == "" {
+		return 0, "", nil
+	}
+	is, inner, ok := strings.Cut(token, ":")
+	i, err := strconv.Atoi(is)
+	if !ok || err != nil || i < 0 || i >= len(r.managers) {
+		return 0, "", errors.WithStack(ErrMalformedPageToken)
+	}
+	return i, inner, nil
+}
+
+// nextPageToken returns the page token that continues after the page of the
+// i-th manager.
+func (r *NamespaceRouter) nextPageToken(i int, inner string) string {
+	if inner == "" {
+		if i++; i == len(r.managers) {
+			return ""
+		}
+	}
+	return strconv.Itoa(i) + ":" + inner
+}
+
+func (r *NamespaceRouter) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	if m := r.queryManager(query); m != nil {
+		return m.GetRelationTuples(ctx, query, options...)
+	}
+
+	i, token, err := r.parsePageToken(options)
+	if err != nil {
+		return nil, "", err
+	}
+	res, next, err := r.managers[i].GetRelationTuples(ctx, query, append(options, x.WithToken(token))...)
+	if err != nil {
+		return nil, "", err
+	}
+	return res, r.nextPageToken(i, next), nil
+}
+
+func (r *NamespaceRouter) WriteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	m, err := r.tuplesManager(rs)
+	if err != nil {
+		return err
+	}
+	return m.WriteRelationTuples(ctx, rs...)
+}
+
+func (r *NamespaceRouter) DeleteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	m, err := r.tuplesManager(rs)
+	if err != nil {
+		return err
+	}
+	return m.DeleteRelationTuples(ctx, rs...)
+}
+
+func (r *NamespaceRouter) DeleteAllRelationTuples(ctx context.Context, query *relationtuple.RelationQuery) error {
+	if m := r.queryManager(query); m != nil {
+		return m.DeleteAllRelationTuples(ctx, query)
+	}
+	for _, m := range r.managers {
+		if err := m.DeleteAllRelationTuples(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *NamespaceRouter) DeleteRelationTuplesPage(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	if m := r.queryManager(query); m != nil {
+		return m.DeleteRelationTuplesPage(ctx, query, options...)
+	}
+
+	i, token, err := r.parsePageToken(options)
+	if err != nil {
+		return 0, "", err
+	}
+	deleted, next, err := r.managers[i].DeleteRelationTuplesPage(ctx, query, append(options, x.WithToken(token))...)
+	if err != nil {
+		return 0, "", err
+	}
+	return deleted, r.nextPageToken(i, next), nil
+}
+
+func (r *NamespaceRouter) TransactRelationTuples(ctx context.Context, insert []*relationtuple.RelationTuple, delete []*relationtuple.RelationTuple) error {
+	m, err := r.tuplesManager(insert, delete)
+	if err != nil {
+		return err
+	}
+	return m.TransactRelationTuples(ctx, insert, delete)
+}
+
+func (r *NamespaceRouter) TouchRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	m, err := r.tuplesManager(rs)
+	if err != nil {
+		return err
+	}
+	return m.TouchRelationTuples(ctx, rs...)
+}
+
+// WalkRelationTuples walks the relation tuples of all managers in turn if the
+// query has no namespace. Each manager reads from its own snapshot.
+func (r *NamespaceRouter) WalkRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, f func(ctx context.Context, page []*relationtuple.RelationTuple) error) error {
+	if m := r.queryManager(query); m != nil {
+		return m.WalkRelationTuples(ctx, query, f)
+	}
+	for _, m := range r.managers {
+		if err := m.WalkRelationTuples(ctx, query, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *NamespaceRouter) CountRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, estimate bool) (int, bool, error) {
+	if m := r.queryManager(query); m != nil {
+		return m.CountRelationTuples(ctx, query, estimate)
+	}
+
+	var (
+		total     int
+		estimated bool
+	)
+	for _, m := range r.managers {
+		n, e, err := m.CountRelationTuples(ctx, query, estimate)
+		if err != nil {
+			return 0, false, err
+		}
+		total += n
+		estimated = estimated || e
+	}
+	return total, estimated, nil
+}
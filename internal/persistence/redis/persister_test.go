@@ -0,0 +1,90 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/persistence/redis"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestPersister(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	url := "redis://" + s.Addr()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+	ctx := context.Background()
+
+	newPersister := func(t *testing.T) *redis.Persister {
+		c, err := redis.NewClient(ctx, url)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+		return redis.NewPersister(reg, c, uuid.Must(uuid.NewV4()), "keto")
+	}
+
+	t.Run("relationtuple.ManagerTest", func(t *testing.T) {
+		relationtuple.ManagerTest(t, newPersister(t))
+	})
+
+	t.Run("relationtuple.IsolationTest", func(t *testing.T) {
+		relationtuple.IsolationTest(t, newPersister(t), newPersister(t))
+	})
+
+	t.Run("relationtuple.UUIDMappingManagerTest", func(t *testing.T) {
+		relationtuple.MappingManagerTest(t, newPersister(t))
+	})
+
+	t.Run("case=unknown UUIDs map to empty strings", func(t *testing.T) {
+		s, err := newPersister(t).MapUUIDsToStrings(ctx, uuid.Must(uuid.NewV4()))
+		require.NoError(t, err)
+		assert.Equal(t, []string{""}, s)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	s := miniredis.RunT(t)
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "docs"}}),
+		driver.WithConfig(config.KeyRedisURL, "redis://"+s.Addr()),
+		driver.WithConfig(config.KeyRedisKeyPrefix, "test"),
+	)
+	ctx := context.Background()
+
+	require.IsType(t, &redis.Persister{}, reg.RelationTupleManager())
+	require.IsType(t, &redis.Persister{}, reg.MappingManager())
+
+	u, err := reg.MappingManager().MapStringsToUUIDs(ctx, "readme", "alice")
+	require.NoError(t, err)
+	require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, &relationtuple.RelationTuple{
+		Namespace: "docs",
+		Object:    u[0],
+		Relation:  "view",
+		Subject:   &relationtuple.SubjectID{ID: u[1]},
+	}))
+
+	allowed, err := reg.PermissionEngine().CheckIsMember(ctx, &relationtuple.RelationTuple{
+		Namespace: "docs",
+		Object:    u[0],
+		Relation:  "view",
+		Subject:   &relationtuple.SubjectID{ID: u[1]},
+	}, 0)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	assert.NotEmpty(t, s.Keys())
+	for _, k := range s.Keys() {
+		assert.Regexp(t, `^test:\{[0-9a-f-]+\}:`, k)
+	}
+}
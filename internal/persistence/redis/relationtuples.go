@@ -0,0 +1,503 @@
+package redis
+
+import (
+	"context"
+	"encoding/base64"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	walkPageSize = 1000
+	// maxTxRetries is how often a transaction is retried if a relation tuple
+	// it depends on was changed concurrently.
+	maxTxRetries = 10
+)
+
+// encodeTuple returns the sorted set member of the relation tuple.
+func encodeTuple(r *relationtuple.RelationTuple) string {
+	switch s := r.Subject.(type) {
+	case *relationtuple.SubjectID:
+		return encode(r.Namespace, r.Object.String(), r.Relation, s.ID.String())
+	case *relationtuple.SubjectSet:
+		return encode(r.Namespace, r.Object.String(), r.Relation, s.Namespace, s.Object.String(), s.Relation)
+	}
+	return ""
+}
+
+func decodeTuple(member string) (*relationtuple.RelationTuple, error) {
+	parts, err := decode(member)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != 4 && len(parts) != 6 {
+		return nil, errors.Errorf("malformed relation tuple %q", member)
+	}
+	r := &relationtuple.RelationTuple{
+		Namespace: parts[0],
+		Relation:  parts[2],
+	}
+	if r.Object, err = uuid.FromString(parts[1]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(parts) == 4 {
+		id, err := uuid.FromString(parts[3])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r.Subject = &relationtuple.SubjectID{ID: id}
+		return r, nil
+	}
+	obj, err := uuid.FromString(parts[4])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.Subject = &relationtuple.SubjectSet{Namespace: parts[3], Object: obj, Relation: parts[5]}
+	return r, nil
+}
+
+func (p *Persister) allKey(ctx context.Context) string {
+	return p.key(ctx, "tuples")
+}
+
+func (p *Persister) namespaceKey(ctx context.Context, namespace string) string {
+	return p.key(ctx, "namespace", namespace)
+}
+
+func (p *Persister) objectKey(ctx context.Context, namespace string, object uuid.UUID) string {
+	return p.key(ctx, "object", namespace, object.String())
+}
+
+func (p *Persister) relationKey(ctx context.Context, namespace string, object uuid.UUID, relation string) string {
+	return p.key(ctx, "relation", namespace, object.String(), relation)
+}
+
+func (p *Persister) subjectKey(ctx context.Context, s relationtuple.Subject) string {
+	if ss, ok := s.(*relationtuple.SubjectSet); ok {
+		return p.key(ctx, "subject", ss.Namespace, ss.Object.String(), ss.Relation)
+	}
+	return p.key(ctx, "subject", s.UniqueID().String())
+}
+
+// indexKeys returns the keys of all sorted sets the relation tuple is a member
+// of.
+func (p *Persister) indexKeys(ctx context.Context, r *relationtuple.RelationTuple) []string {
+	return []string{
+		p.allKey(ctx),
+		p.namespaceKey(ctx, r.Namespace),
+		p.objectKey(ctx, r.Namespace, r.Object),
+		p.relationKey(ctx, r.Namespace, r.Object, r.Relation),
+		p.subjectKey(ctx, r.Subject),
+	}
+}
+
+// index returns the key of the smallest sorted set that contains all relation
+// tuples matching the query, and whether all its members match.
+func (p *Persister) index(ctx context.Context, q *relationtuple.RelationQuery) (key string, exact bool) {
+	switch {
+	case q.Namespace != nil && q.Object != nil && q.Relation != nil:
+		return p.relationKey(ctx, *q.Namespace, *q.Object, *q.Relation), q.Subject == nil
+	case q.Subject != nil:
+		return p.subjectKey(ctx, q.Subject), q.Namespace == nil && q.Object == nil && q.Relation == nil
+	case q.Namespace != nil && q.Object != nil:
+		return p.objectKey(ctx, *q.Namespace, *q.Object), q.Relation == nil
+	case q.Namespace != nil:
+		return p.namespaceKey(ctx, *q.Namespace), q.Object == nil && q.Relation == nil
+	}
+	return p.allKey(ctx), q.Object == nil && q.Relation == nil
+}
+
+// queryTuple returns the relation tuple if the query matches at most one.
+func queryTuple(q *relationtuple.RelationQuery) (*relationtuple.RelationTuple, bool) {
+	if q.Namespace == nil || q.Object == nil || q.Relation == nil || q.Subject == nil {
+		return nil, false
+	}
+	return &relationtuple.RelationTuple{
+		Namespace: *q.Namespace,
+		Object:    *q.Object,
+		Relation:  *q.Relation,
+		Subject:   q.Subject,
+	}, true
+}
+
+func matches(q *relationtuple.RelationQuery, r *relationtuple.RelationTuple) bool {
+	return (q.Namespace == nil || *q.Namespace == r.Namespace) &&
+		(q.Object == nil || *q.Object == r.Object) &&
+		(q.Relation == nil || *q.Relation == r.Relation) &&
+		(q.Subject == nil || q.Subject.Equals(r.Subject))
+}
+
+// find returns up to limit relation tuples matching the query that come after
+// the member after, and their members. A negative limit returns all of them.
+func (p *Persister) find(ctx context.Context, q *relationtuple.RelationQuery, after string, limit int) ([]*relationtuple.RelationTuple, []string, error) {
+	if r, ok := queryTuple(q); ok {
+		member := encodeTuple(r)
+		if after != "" && member <= after {
+			return nil, nil, nil
+		}
+		err := p.c.ZScore(ctx, p.allKey(ctx), member).Err()
+		if errors.Is(err, goredis.Nil) {
+			return nil, nil, nil
+		} else if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		return []*relationtuple.RelationTuple{r}, []string{member}, nil
+	}
+
+	key, exact := p.index(ctx, q)
+	batch := int64(limit)
+	if !exact || limit < 0 {
+		batch = walkPageSize
+	}
+
+	var (
+		res     []*relationtuple.RelationTuple
+		members []string
+	)
+	for limit < 0 || len(res) < limit {
+		min := "-"
+		if after != "" {
+			min = "(" + after
+		}
+		page, err := p.c.ZRangeByLex(ctx, key, &goredis.ZRangeBy{Min: min, Max: "+", Count: batch}).Result()
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		for _, m := range page {
+			r, err := decodeTuple(m)
+			if err != nil {
+				return nil, nil, err
+			}
+			if matches(q, r) {
+				res, members = append(res, r), append(members, m)
+				if len(res) == limit {
+					break
+				}
+			}
+		}
+		if int64(len(page)) < batch {
+			break
+		}
+		after = page[len(page)-1]
+	}
+	return res, members, nil
+}
+
+func parsePageToken(token string) (string, error) {
+	after, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", errors.WithStack(persistence.ErrMalformedPageToken)
+	}
+	return string(after), nil
+}
+
+func encodePageToken(member string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(member))
+}
+
+// page returns one page of the relation tuples matching the query, their
+// members, and the token of the next page.
+func (p *Persister) page(ctx context.Context, query *relationtuple.RelationQuery, options []x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, []string, string, error) {
+	pagination := x.GetPaginationOptions(options...)
+	size := pagination.Size
+	if size == 0 {
+		size = defaultPageSize
+	}
+	after, err := parsePageToken(pagination.Token)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+
+	res, members, err := p.find(ctx, query, after, size+1)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var nextPageToken string
+	if len(res) > size {
+		res, members = res[:size], members[:size]
+		nextPageToken = encodePageToken(members[size-1])
+	}
+	return res, members, nextPageToken, nil
+}
+
+func (p *Persister) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.GetRelationTuples")
+	defer span.End()
+
+	res, _, nextPageToken, err := p.page(ctx, query, options)
+	if err != nil {
+		return nil, "", err
+	}
+	if res == nil {
+		res = []*relationtuple.RelationTuple{}
+	}
+	return res, nextPageToken, nil
+}
+
+func (p *Persister) WriteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.WriteRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, rs, nil)
+}
+
+// TouchRelationTuples is the same as WriteRelationTuples, as Redis does not
+// record when a relation tuple was written.
+func (p *Persister) TouchRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.TouchRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, rs, nil)
+}
+
+func (p *Persister) DeleteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.DeleteRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, nil, rs)
+}
+
+func (p *Persister) TransactRelationTuples(ctx context.Context, insert []*relationtuple.RelationTuple, delete []*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.TransactRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, insert, delete)
+}
+
+func (p *Persister) DeleteAllRelationTuples(ctx context.Context, query *relationtuple.RelationQuery) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.DeleteAllRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	res, _, err := p.find(ctx, query, "", -1)
+	if err != nil {
+		return err
+	}
+	return p.transact(ctx, nil, res)
+}
+
+func (p *Persister) DeleteRelationTuplesPage(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.DeleteRelationTuplesPage")
+	defer span.End()
+
+	res, _, nextPageToken, err := p.page(ctx, query, options)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := p.transact(ctx, nil, res); err != nil {
+		return 0, "", err
+	}
+	return len(res), nextPageToken, nil
+}
+
+// WalkRelationTuples reads all matching relation tuples with one command, so
+// that all pages are from the same snapshot.
+func (p *Persister) WalkRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, f func(ctx context.Context, page []*relationtuple.RelationTuple) error) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.WalkRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	var res []*relationtuple.RelationTuple
+	if _, ok := queryTuple(query); ok {
+		var err error
+		if res, _, err = p.find(ctx, query, "", -1); err != nil {
+			return err
+		}
+	} else {
+		key, _ := p.index(ctx, query)
+		members, err := p.c.ZRangeByLex(ctx, key, &goredis.ZRangeBy{Min: "-", Max: "+"}).Result()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, m := range members {
+			r, err := decodeTuple(m)
+			if err != nil {
+				return err
+			}
+			if matches(query, r) {
+				res = append(res, r)
+			}
+		}
+	}
+
+	for len(res) > 0 {
+		n := walkPageSize
+		if n > len(res) {
+			n = len(res)
+		}
+		if err := f(ctx, res[:n]); err != nil {
+			return err
+		}
+		res = res[n:]
+	}
+	return nil
+}
+
+// CountRelationTuples always counts exactly. This is cheap if the query
+// matches all members of an index, and requires reading the relation tuples
+// otherwise.
+func (p *Persister) CountRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, _ bool) (int, bool, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.CountRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	if _, ok := queryTuple(query); !ok {
+		if key, exact := p.index(ctx, query); exact {
+			n, err := p.c.ZCard(ctx, key).Result()
+			return int(n), false, errors.WithStack(err)
+		}
+	}
+	res, _, err := p.find(ctx, query, "", -1)
+	return len(res), false, err
+}
+
+// transact inserts and then deletes the relation tuples in one transaction.
+// If a namespace limits the number of subjects of a relation, the transaction
+// watches the affected objects, and is retried if they change concurrently.
+func (p *Persister) transact(ctx context.Context, insert, delete []*relationtuple.RelationTuple) error {
+	for _, rs := range [][]*relationtuple.RelationTuple{insert, delete} {
+		for _, r := range rs {
+			if r.Subject == nil {
+				return errors.WithStack(ketoapi.ErrNilSubject)
+			}
+		}
+	}
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	if p.d.Config(ctx).StrictMode() {
+		for _, r := range insert {
+			if err := relationtuple.ValidateSchema(ctx, nm, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	type limit struct {
+		r   *relationtuple.RelationTuple
+		max int
+	}
+	limits := make(map[string]limit)
+	for _, r := range insert {
+		key := p.relationKey(ctx, r.Namespace, r.Object, r.Relation)
+		if _, ok := limits[key]; ok {
+			continue
+		}
+		max, err := relationtuple.MaxSubjects(ctx, nm, r.Namespace, r.Relation)
+		if err != nil {
+			return err
+		}
+		if max > 0 {
+			limits[key] = limit{r, max}
+		}
+	}
+
+	write := func(pipe goredis.Pipeliner) error {
+		for _, r := range insert {
+			member := encodeTuple(r)
+			for _, key := range p.indexKeys(ctx, r) {
+				pipe.ZAdd(ctx, key, &goredis.Z{Member: member})
+			}
+		}
+		for _, r := range delete {
+			member := encodeTuple(r)
+			for _, key := range p.indexKeys(ctx, r) {
+				pipe.ZRem(ctx, key, member)
+			}
+		}
+		return nil
+	}
+	if len(limits) == 0 {
+		_, err := p.c.TxPipelined(ctx, write)
+		return errors.WithStack(err)
+	}
+
+	watched := make([]string, 0, len(limits))
+	for key := range limits {
+		watched = append(watched, key)
+	}
+	for i := 0; i < maxTxRetries; i++ {
+		err := p.c.Watch(ctx, func(tx *goredis.Tx) error {
+			for key, l := range limits {
+				count, err := p.countAfter(ctx, tx, key, insert, delete)
+				if err != nil {
+					return err
+				}
+				if count > l.max {
+					return errors.WithStack(herodot.ErrConflict.WithReasonf(
+						"Relation %q in namespace %q allows at most %d subject(s) per object, but the object would have %d.",
+						l.r.Relation, l.r.Namespace, l.max, count))
+				}
+			}
+			_, err := tx.TxPipelined(ctx, write)
+			return err
+		}, watched...)
+		if !errors.Is(err, goredis.TxFailedErr) {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(herodot.ErrConflict.WithReason("The relation tuples were changed concurrently, please retry."))
+}
+
+// countAfter returns how many relation tuples the sorted set of the key will
+// have after the transaction.
+func (p *Persister) countAfter(ctx context.Context, tx *goredis.Tx, key string, insert, delete []*relationtuple.RelationTuple) (int, error) {
+	n, err := tx.ZCard(ctx, key).Result()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	count := int(n)
+
+	// present tracks the members of the key as the transaction changes them.
+	// The deletes are applied after the inserts.
+	present := make(map[string]bool)
+	apply := func(rs []*relationtuple.RelationTuple, insert bool) error {
+		for _, r := range rs {
+			if p.relationKey(ctx, r.Namespace, r.Object, r.Relation) != key {
+				continue
+			}
+			member := encodeTuple(r)
+			exists, ok := present[member]
+			if !ok {
+				err := tx.ZScore(ctx, key, member).Err()
+				if err != nil && !errors.Is(err, goredis.Nil) {
+					return errors.WithStack(err)
+				}
+				exists = err == nil
+			}
+			switch {
+			case insert && !exists:
+				count++
+			case !insert && exists:
+				count--
+			}
+			present[member] = insert
+		}
+		return nil
+	}
+	if err := apply(insert, true); err != nil {
+		return 0, err
+	}
+	if err := apply(delete, false); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// The UUID mappings are stored in one hash of the network, from the UUID to
+// the string it represents.
+
+func (p *Persister) MapStringsToUUIDs(ctx context.Context, s ...string) ([]uuid.UUID, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.MapStringsToUUIDs")
+	defer span.End()
+
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	key := p.key(ctx, "uuids")
+	uuids := make([]uuid.UUID, len(s))
+	_, err := p.c.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, val := range s {
+			uuids[i] = uuid.NewV5(p.NetworkID(ctx), val)
+			pipe.HSetNX(ctx, key, uuids[i].String(), val)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return uuids, nil
+}
+
+// MapUUIDsToStrings maps unknown UUIDs to the empty string.
+func (p *Persister) MapUUIDsToStrings(ctx context.Context, u ...uuid.UUID) ([]string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.MapUUIDsToStrings")
+	defer span.End()
+
+	if len(u) == 0 {
+		return nil, nil
+	}
+
+	fields := make([]string, len(u))
+	for i, id := range u {
+		fields[i] = id.String()
+	}
+	values, err := p.c.HMGet(ctx, p.key(ctx, "uuids"), fields...).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	res := make([]string, len(values))
+	for i, v := range values {
+		res[i], _ = v.(string)
+	}
+	return res, nil
+}
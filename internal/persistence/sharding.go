@@ -0,0 +1,374 @@
+package persistence
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"hash/fnv"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// ShardKey is the part of a relation tuple that determines its shard.
+	ShardKey string
+	// ShardRouter distributes the relation tuples over several managers by
+	// the hash of their shard key. Queries that contain the shard key are
+	// answered by one shard, all others by all shards concurrently.
+	ShardRouter struct {
+		shards []relationtuple.Manager
+		key    ShardKey
+	}
+	// shardCursor is the position of a paginated request in one shard.
+	shardCursor struct {
+		Token string `json:"t,omitempty"`
+		Done  bool   `json:"d,omitempty"`
+	}
+)
+
+const (
+	// ShardByObject keeps all relation tuples of an object in one shard, so
+	// that checks only read from one shard per step.
+	ShardByObject ShardKey = "object"
+	// ShardByNamespace keeps all relation tuples of a namespace in one shard.
+	ShardByNamespace ShardKey = "namespace"
+
+	defaultPageSize = 100
+)
+
+var _ relationtuple.Manager = (*ShardRouter)(nil)
+
+func NewShardRouter(key ShardKey, shards ...relationtuple.Manager) *ShardRouter {
+	return &ShardRouter{shards: shards, key: key}
+}
+
+// shard returns the index of the shard that stores the relation tuples of
+// the object. The shards can therefore not be added or removed without
+// moving the relation tuples.
+func (r *ShardRouter) shard(namespace string, object []byte) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace))
+	if r.key == ShardByObject {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write(object)
+	}
+	return int(h.Sum32() % uint32(len(r.shards)))
+}
+
+func (r *ShardRouter) tupleShard(t *relationtuple.RelationTuple) int {
+	return r.shard(t.Namespace, t.Object.Bytes())
+}
+
+// queryShard returns the manager that stores all relation tuples of the
+// query, or nil if they can be stored in any shard.
+func (r *ShardRouter) queryShard(query *relationtuple.RelationQuery) relationtuple.Manager {
+	if len(r.shards) == 1 {
+		return r.shards[0]
+	}
+	if query == nil || query.Namespace == nil {
+		return nil
+	}
+	if r.key == ShardByNamespace {
+		return r.shards[r.shard(*query.Namespace, nil)]
+	}
+	if query.Object == nil {
+		return nil
+	}
+	return r.shards[r.shard(*query.Namespace, query.Object.Bytes())]
+}
+
+// byShard groups the relation tuples by their shard.
+func (r *ShardRouter) byShard(rs []*relationtuple.RelationTuple) [][]*relationtuple.RelationTuple {
+	grouped := make([][]*relationtuple.RelationTuple, len(r.shards))
+	for _, t := range rs {
+		i := r.tupleShard(t)
+		grouped[i] = append(grouped[i], t)
+	}
+	return grouped
+}
+
+// checkSubjects rejects relation tuples without subject before any shard is
+// changed, so that invalid changes are not applied partially.
+func checkSubjects(rs ...[]*relationtuple.RelationTuple) error {
+	for _, tuples := range rs {
+		for _, t := range tuples {
+			if t.Subject == nil {
+				return errors.WithStack(ketoapi.ErrNilSubject)
+			}
+		}
+	}
+	return nil
+}
+
+// scatter calls f for all shards concurrently and returns the first error.
+func (r *ShardRouter) scatter(ctx context.Context, f func(ctx context.Context, i int) error) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	for i := range r.shards {
+		i := i
+		eg.Go(func() error { return f(ctx, i) })
+	}
+	return eg.Wait()
+}
+
+func decodeShardCursors(token string, shards int) ([]shardCursor, error) {
+	cursors := make([]shardCursor, shards)
+	if token == "" {
+		return cursors, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.WithStack(ErrMalformedPageToken)
+	}
+	if err := json.Unmarshal(raw, &cursors); err != nil || len(cursors) != shards {
+		return nil, errors.WithStack(ErrMalformedPageToken)
+	}
+	return cursors, nil
+}
+
+func encodeShardCursors(cursors []shardCursor) (string, error) {
+	for _, c := range cursors {
+		if !c.Done {
+			raw, err := json.Marshal(cursors)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			return base64.RawURLEncoding.EncodeToString(raw), nil
+		}
+	}
+	return "", nil
+}
+
+// scatterPage requests the next page from all shards that have more results
+// concurrently. The page size is split between the shards, and shards are
+// asked again for the rest of the page if others run out of results, so the
+// combined page is full unless it is the last one. The page token holds the
+// position in every shard. page returns the number of results of the shard.
+func (r *ShardRouter) scatterPage(ctx context.Context, options []x.PaginationOptionSetter, page func(ctx context.Context, i int, options ...x.PaginationOptionSetter) (int, string, error)) (string, error) {
+	opts := x.GetPaginationOptions(options...)
+	cursors, err := decodeShardCursors(opts.Token, len(r.shards))
+	if err != nil {
+		return "", err
+	}
+	remaining := opts.Size
+	if remaining <= 0 {
+		remaining = defaultPageSize
+	}
+
+	for remaining > 0 {
+		var pending []int
+		for i, c := range cursors {
+			if !c.Done {
+				pending = append(pending, i)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		// If the rest of the page is smaller than the number of shards, the
+		// first shards are exhausted before the others are read.
+		sizes := make(map[int]int, len(pending))
+		for n, i := range pending {
+			if s := remaining / len(pending); n < remaining%len(pending) {
+				sizes[i] = s + 1
+			} else if s > 0 {
+				sizes[i] = s
+			}
+		}
+
+		counts := make([]int, len(r.shards))
+		if err := r.scatter(ctx, func(ctx context.Context, i int) error {
+			s, ok := sizes[i]
+			if !ok {
+				return nil
+			}
+			n, next, err := page(ctx, i, x.WithSize(s), x.WithToken(cursors[i].Token))
+			if err != nil {
+				return err
+			}
+			counts[i], cursors[i] = n, shardCursor{Token: next, Done: next == ""}
+			return nil
+		}); err != nil {
+			return "", err
+		}
+
+		var total, exhausted int
+		for i, n := range counts {
+			total += n
+			if cursors[i].Done {
+				exhausted++
+			}
+		}
+		remaining -= total
+		if total == 0 && exhausted == len(r.shards)-len(pending) {
+			// The shards return empty pages, which would never fill this
+			// page.
+			break
+		}
+	}
+	return encodeShardCursors(cursors)
+}
+
+func (r *ShardRouter) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	if m := r.queryShard(query); m != nil {
+		return m.GetRelationTuples(ctx, query, options...)
+	}
+
+	pages := make([][]*relationtuple.RelationTuple, len(r.shards))
+	next, err := r.scatterPage(ctx, options, func(ctx context.Context, i int, options ...x.PaginationOptionSetter) (int, string, error) {
+		res, next, err := r.shards[i].GetRelationTuples(ctx, query, options...)
+		pages[i] = append(pages[i], res...)
+		return len(res), next, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]*relationtuple.RelationTuple, 0)
+	for _, p := range pages {
+		res = append(res, p...)
+	}
+	return res, next, nil
+}
+
+// WriteRelationTuples writes the relation tuples of each shard atomically,
+// but not all of them together, e.g. if one of the databases fails.
+func (r *ShardRouter) WriteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	if err := checkSubjects(rs); err != nil {
+		return err
+	}
+	grouped := r.byShard(rs)
+	return r.scatter(ctx, func(ctx context.Context, i int) error {
+		if len(grouped[i]) == 0 {
+			return nil
+		}
+		return r.shards[i].WriteRelationTuples(ctx, grouped[i]...)
+	})
+}
+
+func (r *ShardRouter) DeleteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	if err := checkSubjects(rs); err != nil {
+		return err
+	}
+	grouped := r.byShard(rs)
+	return r.scatter(ctx, func(ctx context.Context, i int) error {
+		if len(grouped[i]) == 0 {
+			return nil
+		}
+		return r.shards[i].DeleteRelationTuples(ctx, grouped[i]...)
+	})
+}
+
+func (r *ShardRouter) DeleteAllRelationTuples(ctx context.Context, query *relationtuple.RelationQuery) error {
+	if m := r.queryShard(query); m != nil {
+		return m.DeleteAllRelationTuples(ctx, query)
+	}
+	return r.scatter(ctx, func(ctx context.Context, i int) error {
+		return r.shards[i].DeleteAllRelationTuples(ctx, query)
+	})
+}
+
+func (r *ShardRouter) DeleteRelationTuplesPage(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	if m := r.queryShard(query); m != nil {
+		return m.DeleteRelationTuplesPage(ctx, query, options...)
+	}
+
+	deleted := make([]int, len(r.shards))
+	next, err := r.scatterPage(ctx, options, func(ctx context.Context, i int, options ...x.PaginationOptionSetter) (int, string, error) {
+		n, next, err := r.shards[i].DeleteRelationTuplesPage(ctx, query, options...)
+		deleted[i] += n
+		return n, next, err
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	var total int
+	for _, n := range deleted {
+		total += n
+	}
+	return total, next, nil
+}
+
+// TransactRelationTuples applies the changes of each shard atomically, but
+// not all of them together. Changes of one object are always applied
+// atomically, as the object determines the shard.
+// TransactRelationTuples applies the transaction in the one shard that stores
+// all of its relation tuples. Transactions that span several shards are
+// rejected, as they could not be applied atomically.
+func (r *ShardRouter) TransactRelationTuples(ctx context.Context, insert []*relationtuple.RelationTuple, delete []*relationtuple.RelationTuple) error {
+	if err := checkSubjects(insert, delete); err != nil {
+		return err
+	}
+	shard := -1
+	for _, rs := range [][]*relationtuple.RelationTuple{insert, delete} {
+		for _, t := range rs {
+			if i := r.tupleShard(t); shard == -1 {
+				shard = i
+			} else if i != shard {
+				return errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+					"The relation tuples of the transaction are stored in different shards, sharded by %s. Transactions can only change the relation tuples of one shard, so split it into one transaction per %[1]s.", r.key))
+			}
+		}
+	}
+	if shard == -1 {
+		return nil
+	}
+	return r.shards[shard].TransactRelationTuples(ctx, insert, delete)
+}
+
+func (r *ShardRouter) TouchRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	if err := checkSubjects(rs); err != nil {
+		return err
+	}
+	grouped := r.byShard(rs)
+	return r.scatter(ctx, func(ctx context.Context, i int) error {
+		if len(grouped[i]) == 0 {
+			return nil
+		}
+		return r.shards[i].TouchRelationTuples(ctx, grouped[i]...)
+	})
+}
+
+// WalkRelationTuples walks the relation tuples of all shards in turn if the
+// query does not determine the shard, so that f is never called
+// concurrently. Each shard reads from its own snapshot.
+func (r *ShardRouter) WalkRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, f func(ctx context.Context, page []*relationtuple.RelationTuple) error) error {
+	if m := r.queryShard(query); m != nil {
+		return m.WalkRelationTuples(ctx, query, f)
+	}
+	for _, m := range r.shards {
+		if err := m.WalkRelationTuples(ctx, query, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ShardRouter) CountRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, estimate bool) (int, bool, error) {
+	if m := r.queryShard(query); m != nil {
+		return m.CountRelationTuples(ctx, query, estimate)
+	}
+
+	counts := make([]int, len(r.shards))
+	estimated := make([]bool, len(r.shards))
+	if err := r.scatter(ctx, func(ctx context.Context, i int) (err error) {
+		counts[i], estimated[i], err = r.shards[i].CountRelationTuples(ctx, query, estimate)
+		return err
+	}); err != nil {
+		return 0, false, err
+	}
+
+	var (
+		total int
+		e     bool
+	)
+	for i := range r.shards {
+		total += counts[i]
+		e = e || estimated[i]
+	}
+	return total, e, nil
+}
@@ -0,0 +1,275 @@
+package spanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x/gcpx"
+)
+
+type (
+	// Client calls the Cloud Spanner REST API on a pool of sessions of the
+	// database. Only the methods and the string columns the persister needs
+	// are supported.
+	Client struct {
+		endpoint, database string
+		tokens             *gcpx.TokenSource
+		hc                 *http.Client
+
+		// open holds a token per open session, idle the sessions that are
+		// not in use.
+		open chan struct{}
+		idle chan string
+	}
+	sessionKey struct{}
+	// apiError is an error response of the Cloud Spanner API.
+	apiError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	}
+
+	keyRange struct {
+		StartClosed []string `json:"startClosed,omitempty"`
+		StartOpen   []string `json:"startOpen,omitempty"`
+		EndClosed   []string `json:"endClosed,omitempty"`
+	}
+	keySet struct {
+		Keys   [][]string `json:"keys,omitempty"`
+		Ranges []keyRange `json:"ranges,omitempty"`
+	}
+	readOnly struct {
+		Strong              bool   `json:"strong,omitempty"`
+		MinReadTimestamp    string `json:"minReadTimestamp,omitempty"`
+		ReadTimestamp       string `json:"readTimestamp,omitempty"`
+		MaxStaleness        string `json:"maxStaleness,omitempty"`
+		ReturnReadTimestamp bool   `json:"returnReadTimestamp,omitempty"`
+	}
+	transactionOptions struct {
+		ReadOnly  *readOnly `json:"readOnly,omitempty"`
+		ReadWrite *struct{} `json:"readWrite,omitempty"`
+	}
+	transactionSelector struct {
+		SingleUse *transactionOptions `json:"singleUse,omitempty"`
+		ID        string              `json:"id,omitempty"`
+	}
+	readRequest struct {
+		Transaction *transactionSelector `json:"transaction,omitempty"`
+		Table       string               `json:"table"`
+		Index       string               `json:"index,omitempty"`
+		Columns     []string             `json:"columns"`
+		KeySet      keySet               `json:"keySet"`
+		Limit       int                  `json:"limit,omitempty,string"`
+	}
+	resultSet struct {
+		Metadata struct {
+			Transaction struct {
+				ReadTimestamp string `json:"readTimestamp"`
+			} `json:"transaction"`
+		} `json:"metadata"`
+		Rows [][]string `json:"rows"`
+	}
+	write struct {
+		Table   string     `json:"table"`
+		Columns []string   `json:"columns"`
+		Values  [][]string `json:"values"`
+	}
+	deletion struct {
+		Table  string `json:"table"`
+		KeySet keySet `json:"keySet"`
+	}
+	mutation struct {
+		InsertOrUpdate *write    `json:"insertOrUpdate,omitempty"`
+		Delete         *deletion `json:"delete,omitempty"`
+	}
+	commitRequest struct {
+		TransactionID        string              `json:"transactionId,omitempty"`
+		SingleUseTransaction *transactionOptions `json:"singleUseTransaction,omitempty"`
+		Mutations            []mutation          `json:"mutations"`
+	}
+	commitResponse struct {
+		CommitTimestamp string `json:"commitTimestamp"`
+	}
+)
+
+const (
+	defaultEndpoint = "https://spanner.googleapis.com"
+	// maxSessions limits the open sessions, as each session can only run one
+	// transaction at a time.
+	maxSessions = 100
+)
+
+// NewClient returns a client for the database, e.g.
+// projects/p/instances/i/databases/d. The endpoint can be set for the
+// emulator. Requests are authorized with the tokens, if they are not nil.
+func NewClient(endpoint, database string, tokens *gcpx.TokenSource) (*Client, error) {
+	if database == "" {
+		return nil, errors.New("the Cloud Spanner database is not set")
+	}
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		database: database,
+		tokens:   tokens,
+		hc:       &http.Client{Timeout: 30 * time.Second},
+		open:     make(chan struct{}, maxSessions),
+		idle:     make(chan string, maxSessions),
+	}, nil
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Cloud Spanner %s: %s", e.Status, e.Message)
+}
+
+// post calls the method of the resource.
+func (c *Client) post(ctx context.Context, resource string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/"+resource, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.tokens.Authorize(req)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var res struct {
+			Error *apiError `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &res); err != nil || res.Error == nil {
+			return errors.Errorf("Cloud Spanner %s returned status %d: %s", resource, resp.StatusCode, raw)
+		}
+		return errors.WithStack(res.Error)
+	}
+	return errors.WithStack(json.NewDecoder(resp.Body).Decode(out))
+}
+
+// call calls the method on the session of the context, or on a session of the
+// pool. A pooled session that the database deleted, e.g. because it was idle
+// for an hour, is replaced once.
+func (c *Client) call(ctx context.Context, method string, in, out interface{}) error {
+	if session, ok := ctx.Value(sessionKey{}).(string); ok {
+		return c.post(ctx, session+":"+method, in, out)
+	}
+	for attempt := 0; ; attempt++ {
+		session, err := c.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		err = c.post(ctx, session+":"+method, in, out)
+		c.release(session, err)
+		if isSessionNotFound(err) && attempt == 0 {
+			continue
+		}
+		return err
+	}
+}
+
+// withSession runs f with a context whose calls all use the same session, as
+// the reads and the commit of a read-write transaction have to.
+func (c *Client) withSession(ctx context.Context, f func(ctx context.Context) error) error {
+	session, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = f(context.WithValue(ctx, sessionKey{}, session))
+	c.release(session, err)
+	return err
+}
+
+// acquire returns an idle session, or creates one if fewer than maxSessions
+// are open. Otherwise, it waits for a session to become idle.
+func (c *Client) acquire(ctx context.Context) (string, error) {
+	select {
+	case session := <-c.idle:
+		return session, nil
+	default:
+	}
+	select {
+	case session := <-c.idle:
+		return session, nil
+	case c.open <- struct{}{}:
+	case <-ctx.Done():
+		return "", errors.WithStack(ctx.Err())
+	}
+
+	var s struct {
+		Name string `json:"name"`
+	}
+	if err := c.post(ctx, c.database+"/sessions", struct{}{}, &s); err != nil {
+		<-c.open
+		return "", err
+	}
+	return s.Name, nil
+}
+
+// release returns the session to the pool, unless the call failed because the
+// database deleted the session.
+func (c *Client) release(session string, err error) {
+	if isSessionNotFound(err) {
+		<-c.open
+		return
+	}
+	c.idle <- session
+}
+
+func (c *Client) read(ctx context.Context, req *readRequest) (*resultSet, error) {
+	var res resultSet
+	if err := c.call(ctx, "read", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) beginTransaction(ctx context.Context) (string, error) {
+	var tx struct {
+		ID string `json:"id"`
+	}
+	err := c.call(ctx, "beginTransaction", map[string]interface{}{"options": transactionOptions{ReadWrite: &struct{}{}}}, &tx)
+	return tx.ID, err
+}
+
+// commit commits the mutations in the transaction, or in a single-use
+// transaction if the ID is empty, and returns the commit timestamp.
+func (c *Client) commit(ctx context.Context, transactionID string, mutations []mutation) (string, error) {
+	req := &commitRequest{TransactionID: transactionID, Mutations: mutations}
+	if transactionID == "" {
+		req.SingleUseTransaction = &transactionOptions{ReadWrite: &struct{}{}}
+	}
+	var res commitResponse
+	if err := c.call(ctx, "commit", req, &res); err != nil {
+		return "", err
+	}
+	return res.CommitTimestamp, nil
+}
+
+func (c *Client) rollback(ctx context.Context, transactionID string) error {
+	return c.call(ctx, "rollback", map[string]string{"transactionId": transactionID}, &struct{}{})
+}
+
+func isSessionNotFound(err error) bool {
+	return isStatus(err, "NOT_FOUND") && strings.Contains(err.Error(), "Session not found")
+}
+
+func isStatus(err error, status string) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Status == status
+}
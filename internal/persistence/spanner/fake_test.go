@@ -0,0 +1,321 @@
+package spanner_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// fakeSpanner is an in-memory Spanner database with the schema of the
+	// persister. It supports the subset of the REST API the persister uses,
+	// and only keeps the latest version of each row.
+	fakeSpanner struct {
+		sync.Mutex
+		tables   map[string]map[string][]string
+		sessions map[string]bool
+		// transactions maps the IDs of the active transactions to their
+		// sessions.
+		transactions map[string]string
+		clock        time.Time
+		ids          int
+		// readOnly are the read-only options of all single-use reads.
+		readOnly []fakeReadOnly
+	}
+	fakeSchema struct {
+		columns []string
+		// key are the indexes of the key columns.
+		key     []int
+		indexes map[string][]int
+	}
+	fakeReadOnly struct {
+		Strong              bool
+		MinReadTimestamp    string
+		ReadTimestamp       string
+		MaxStaleness        string
+		ReturnReadTimestamp bool
+	}
+	fakeKeySet struct {
+		Keys   [][]string
+		Ranges []struct {
+			StartClosed, StartOpen, EndClosed []string
+		}
+	}
+	fakeTransaction struct {
+		SingleUse *struct {
+			ReadOnly *fakeReadOnly
+		}
+		ID string
+	}
+	fakeMutation struct {
+		InsertOrUpdate *struct {
+			Table   string
+			Columns []string
+			Values  [][]string
+		}
+		Delete *struct {
+			Table  string
+			KeySet fakeKeySet
+		}
+	}
+)
+
+var fakeSchemas = map[string]fakeSchema{
+	"keto_relation_tuples": {
+		columns: []string{"nid", "namespace", "object", "relation", "subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation"},
+		key:     []int{0, 1, 2, 3, 4, 5, 6, 7},
+		indexes: map[string][]int{"keto_relation_tuples_by_subject": {0, 4, 5, 6, 7, 1, 2, 3}},
+	},
+	"keto_uuid_mappings": {
+		columns: []string{"nid", "id", "string_representation"},
+		key:     []int{0, 1},
+	},
+}
+
+// newFakeSpanner starts the fake and returns it and its endpoint.
+func newFakeSpanner(t *testing.T) (*fakeSpanner, string) {
+	f := &fakeSpanner{
+		tables:       make(map[string]map[string][]string),
+		sessions:     make(map[string]bool),
+		transactions: make(map[string]string),
+		clock:        time.Now().UTC(),
+	}
+	for table := range fakeSchemas {
+		f.tables[table] = make(map[string][]string)
+	}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts.URL
+}
+
+func columns(row []string, indexes []int) []string {
+	res := make([]string, len(indexes))
+	for i, c := range indexes {
+		res[i] = row[c]
+	}
+	return res
+}
+
+// compare compares the key with the prefix of the same length.
+func compare(key, prefix []string) int {
+	for i := range prefix {
+		if i == len(key) {
+			return -1
+		}
+		if c := strings.Compare(key[i], prefix[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (ks *fakeKeySet) contains(key []string) bool {
+	for _, k := range ks.Keys {
+		if len(k) == len(key) && compare(key, k) == 0 {
+			return true
+		}
+	}
+	for _, r := range ks.Ranges {
+		if (r.StartClosed != nil && compare(key, r.StartClosed) < 0) ||
+			(r.StartOpen != nil && compare(key, r.StartOpen) <= 0) ||
+			compare(key, r.EndClosed) > 0 {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// expireSessions deletes all sessions, like Spanner does for idle ones.
+func (f *fakeSpanner) expireSessions() {
+	f.Lock()
+	defer f.Unlock()
+	f.sessions = make(map[string]bool)
+}
+
+func (f *fakeSpanner) lastReadOnly() fakeReadOnly {
+	f.Lock()
+	defer f.Unlock()
+	return f.readOnly[len(f.readOnly)-1]
+}
+
+func (f *fakeSpanner) timestamp() string {
+	return f.clock.Format(time.RFC3339Nano)
+}
+
+func (f *fakeSpanner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	resource := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if strings.HasSuffix(resource, "/sessions") {
+		f.ids++
+		name := fmt.Sprintf("%s/%d", resource, f.ids)
+		f.sessions[name] = true
+		f.respond(w, map[string]string{"name": name})
+		return
+	}
+	i := strings.LastIndex(resource, ":")
+	if i < 0 {
+		f.fail(w, http.StatusNotFound, "NOT_FOUND", "unknown resource "+resource)
+		return
+	}
+	if !f.sessions[resource[:i]] {
+		f.fail(w, http.StatusNotFound, "NOT_FOUND", "Session not found: "+resource[:i])
+		return
+	}
+
+	var req struct {
+		Transaction   *fakeTransaction
+		Table, Index  string
+		Columns       []string
+		KeySet        fakeKeySet
+		Limit         int `json:",string"`
+		TransactionID string
+		Mutations     []fakeMutation
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+		return
+	}
+
+	switch method := resource[i+1:]; method {
+	case "read":
+		f.read(w, resource[:i], req.Transaction, req.Table, req.Index, req.Columns, &req.KeySet, req.Limit)
+	case "beginTransaction":
+		// Like Spanner, a session only has one active transaction.
+		for id, session := range f.transactions {
+			if session == resource[:i] {
+				delete(f.transactions, id)
+			}
+		}
+		f.ids++
+		id := fmt.Sprintf("tx%d", f.ids)
+		f.transactions[id] = resource[:i]
+		f.respond(w, map[string]string{"id": id})
+	case "rollback":
+		delete(f.transactions, req.TransactionID)
+		f.respond(w, map[string]string{})
+	case "commit":
+		if req.TransactionID != "" && f.transactions[req.TransactionID] != resource[:i] {
+			f.fail(w, http.StatusBadRequest, "FAILED_PRECONDITION", "unknown transaction "+req.TransactionID)
+			return
+		}
+		delete(f.transactions, req.TransactionID)
+		for _, m := range req.Mutations {
+			if err := f.apply(&m); err != nil {
+				f.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+				return
+			}
+		}
+		f.clock = f.clock.Add(time.Millisecond)
+		f.respond(w, map[string]string{"commitTimestamp": f.timestamp()})
+	default:
+		f.fail(w, http.StatusNotFound, "NOT_FOUND", "unknown method "+method)
+	}
+}
+
+func (f *fakeSpanner) read(w http.ResponseWriter, session string, tx *fakeTransaction, table, index string, cols []string, ks *fakeKeySet, limit int) {
+	schema, ok := fakeSchemas[table]
+	if !ok {
+		f.fail(w, http.StatusBadRequest, "NOT_FOUND", "unknown table "+table)
+		return
+	}
+	key := schema.key
+	if index != "" {
+		if key, ok = schema.indexes[index]; !ok {
+			f.fail(w, http.StatusBadRequest, "NOT_FOUND", "unknown index "+index)
+			return
+		}
+	}
+	var projection []int
+	for _, c := range cols {
+		for i := range schema.columns {
+			if schema.columns[i] == c {
+				projection = append(projection, i)
+			}
+		}
+	}
+
+	metadata := map[string]interface{}{}
+	switch {
+	case tx == nil:
+		f.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", "the fake requires a transaction selector")
+		return
+	case tx.ID != "":
+		if f.transactions[tx.ID] != session {
+			f.fail(w, http.StatusBadRequest, "FAILED_PRECONDITION", "unknown transaction "+tx.ID)
+			return
+		}
+	case tx.SingleUse != nil && tx.SingleUse.ReadOnly != nil:
+		ro := *tx.SingleUse.ReadOnly
+		for _, ts := range []string{ro.MinReadTimestamp, ro.ReadTimestamp} {
+			if _, err := time.Parse(time.RFC3339Nano, ts); ts != "" && err != nil {
+				f.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+				return
+			}
+		}
+		f.readOnly = append(f.readOnly, ro)
+		if ro.ReturnReadTimestamp {
+			metadata["transaction"] = map[string]string{"readTimestamp": f.timestamp()}
+		}
+	default:
+		f.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", "reads need a read-only or an existing transaction")
+		return
+	}
+
+	var rows [][]string
+	for _, row := range f.tables[table] {
+		if ks.contains(columns(row, key)) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(a, b int) bool {
+		return compare(columns(rows[a], key), columns(rows[b], key)) < 0
+	})
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	res := make([][]string, len(rows))
+	for i, row := range rows {
+		res[i] = columns(row, projection)
+	}
+	f.respond(w, map[string]interface{}{"metadata": metadata, "rows": res})
+}
+
+func (f *fakeSpanner) apply(m *fakeMutation) error {
+	if m.InsertOrUpdate != nil {
+		schema := fakeSchemas[m.InsertOrUpdate.Table]
+		if strings.Join(m.InsertOrUpdate.Columns, ",") != strings.Join(schema.columns, ",") {
+			return fmt.Errorf("the fake requires all columns of %s in order", m.InsertOrUpdate.Table)
+		}
+		for _, row := range m.InsertOrUpdate.Values {
+			f.tables[m.InsertOrUpdate.Table][strings.Join(columns(row, schema.key), "\x00")] = row
+		}
+		return nil
+	}
+	schema := fakeSchemas[m.Delete.Table]
+	for k, row := range f.tables[m.Delete.Table] {
+		if m.Delete.KeySet.contains(columns(row, schema.key)) {
+			delete(f.tables[m.Delete.Table], k)
+		}
+	}
+	return nil
+}
+
+func (f *fakeSpanner) respond(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeSpanner) fail(w http.ResponseWriter, code int, status, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": code, "status": status, "message": msg}})
+}
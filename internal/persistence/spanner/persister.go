@@ -0,0 +1,192 @@
+// Package spanner stores the relation tuples and UUID mappings in a Google
+// Cloud Spanner database, which is strongly consistent across regions.
+//
+// The database needs the following tables and index. Subject IDs have empty
+// subject set columns, and subject sets an empty subject ID, so that the
+// columns can be part of the primary key.
+//
+//	CREATE TABLE keto_relation_tuples (
+//	  nid STRING(36) NOT NULL,
+//	  namespace STRING(MAX) NOT NULL,
+//	  object STRING(36) NOT NULL,
+//	  relation STRING(MAX) NOT NULL,
+//	  subject_id STRING(36) NOT NULL,
+//	  subject_set_namespace STRING(MAX) NOT NULL,
+//	  subject_set_object STRING(36) NOT NULL,
+//	  subject_set_relation STRING(MAX) NOT NULL,
+//	) PRIMARY KEY (nid, namespace, object, relation, subject_id,
+//	  subject_set_namespace, subject_set_object, subject_set_relation);
+//
+//	CREATE INDEX keto_relation_tuples_by_subject ON keto_relation_tuples (
+//	  nid, subject_id, subject_set_namespace, subject_set_object,
+//	  subject_set_relation, namespace, object, relation);
+//
+//	CREATE TABLE keto_uuid_mappings (
+//	  nid STRING(36) NOT NULL,
+//	  id STRING(36) NOT NULL,
+//	  string_representation STRING(MAX) NOT NULL,
+//	) PRIMARY KEY (nid, id);
+//
+// The table serves the forward lookups of the check engine, and the index the
+// reverse lookups of a subject's relation tuples.
+//
+// The persister is a relationtuple.Snapshotter. Snaptokens are the commit
+// timestamps of Spanner's TrueTime, so a read with a snaptoken is evaluated at
+// a snapshot that contains all writes up to it.
+package spanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoctx"
+)
+
+type (
+	Persister struct {
+		c   *Client
+		d   dependencies
+		nid uuid.UUID
+	}
+	dependencies interface {
+		x.LoggerProvider
+		x.TracingProvider
+		ketoctx.ContextualizerProvider
+		config.Provider
+	}
+
+	readTimestampKey struct{}
+)
+
+const (
+	TableRelationTuples   = "keto_relation_tuples"
+	IndexSubject          = "keto_relation_tuples_by_subject"
+	TableUUIDMappings     = "keto_uuid_mappings"
+	defaultPageSize       = 100
+	walkPageSize          = 1000
+	maxStaleness          = "15s"
+	maxCommitRetries      = 5
+	maxMutationsPerCommit = 2000
+)
+
+var (
+	_ relationtuple.Manager        = (*Persister)(nil)
+	_ relationtuple.MappingManager = (*Persister)(nil)
+	_ relationtuple.Snapshotter    = (*Persister)(nil)
+)
+
+// NewPersister returns a persister that stores the data of the network in the
+// Spanner database.
+func NewPersister(reg dependencies, c *Client, nid uuid.UUID) *Persister {
+	return &Persister{
+		c:   c,
+		d:   reg,
+		nid: nid,
+	}
+}
+
+func (p *Persister) NetworkID(ctx context.Context) uuid.UUID {
+	return p.d.Contextualizer().Network(ctx, p.nid)
+}
+
+// Snaptoken returns the timestamp of a strong read, which sees all writes
+// committed before.
+func (p *Persister) Snaptoken(ctx context.Context) (string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.Snaptoken")
+	defer span.End()
+
+	res, err := p.c.read(ctx, &readRequest{
+		Transaction: &transactionSelector{SingleUse: &transactionOptions{ReadOnly: &readOnly{Strong: true, ReturnReadTimestamp: true}}},
+		Table:       TableRelationTuples,
+		Columns:     []string{"nid"},
+		KeySet:      keySet{Keys: [][]string{}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.Metadata.Transaction.ReadTimestamp, nil
+}
+
+// withReadTimestamp returns a context in which all reads are at the exact
+// timestamp, so that they see the same snapshot.
+func withReadTimestamp(ctx context.Context, ts string) context.Context {
+	return context.WithValue(ctx, readTimestampKey{}, ts)
+}
+
+// readOnly returns the single-use transaction of a read. Reads are at the
+// snapshot of the context's snaptoken or later, eventually consistent reads
+// are at most 15 seconds stale, and all other reads are strong.
+func (p *Persister) readOnly(ctx context.Context) (*transactionSelector, error) {
+	opts := &readOnly{Strong: true}
+	if ts, ok := ctx.Value(readTimestampKey{}).(string); ok {
+		opts = &readOnly{ReadTimestamp: ts}
+	} else if token := relationtuple.SnaptokenFromContext(ctx); token != "" {
+		if _, err := time.Parse(time.RFC3339Nano, token); err != nil {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Malformed snaptoken %q.", token))
+		}
+		opts = &readOnly{MinReadTimestamp: token}
+	} else if persistence.ConsistencyFromContext(ctx) == persistence.ConsistencyEventual {
+		opts = &readOnly{MaxStaleness: maxStaleness}
+	}
+	return &transactionSelector{SingleUse: &transactionOptions{ReadOnly: opts}}, nil
+}
+
+// readWrite runs f in a read-write transaction and commits the mutations it
+// returns. The transaction uses one session of the pool, which f has to read
+// with through its context. Transactions that Spanner aborted because of
+// concurrent transactions, or whose session was deleted, are retried. It
+// records the commit timestamp as snaptoken.
+func (p *Persister) readWrite(ctx context.Context, f func(ctx context.Context, tx *transactionSelector) ([]mutation, error)) error {
+	for attempt := 0; ; attempt++ {
+		var ts string
+		err := p.c.withSession(ctx, func(ctx context.Context) error {
+			id, err := p.c.beginTransaction(ctx)
+			if err != nil {
+				return err
+			}
+			mutations, err := f(ctx, &transactionSelector{ID: id})
+			if err != nil {
+				_ = p.c.rollback(ctx, id)
+				return err
+			}
+			ts, err = p.c.commit(ctx, id, mutations)
+			return err
+		})
+		if (isStatus(err, "ABORTED") || isSessionNotFound(err)) && attempt < maxCommitRetries {
+			continue
+		} else if isStatus(err, "ABORTED") {
+			return errors.WithStack(herodot.ErrConflict.WithReason("The relation tuples were changed concurrently, please retry."))
+		} else if err != nil {
+			return err
+		}
+		relationtuple.RecordSnaptoken(ctx, ts)
+		return nil
+	}
+}
+
+// blindWrite commits the mutations without reading, in batches of at most
+// maxMutationsPerCommit that are not atomic. It records the commit timestamp
+// of the last batch as snaptoken.
+func (p *Persister) blindWrite(ctx context.Context, mutations []mutation) error {
+	for len(mutations) > 0 {
+		n := maxMutationsPerCommit
+		if n > len(mutations) {
+			n = len(mutations)
+		}
+		ts, err := p.c.commit(ctx, "", mutations[:n])
+		if err != nil {
+			return err
+		}
+		relationtuple.RecordSnaptoken(ctx, ts)
+		mutations = mutations[n:]
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package spanner_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/persistence/spanner"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+const database = "projects/test/instances/keto/databases/keto"
+
+func TestPersister(t *testing.T) {
+	t.Parallel()
+
+	fake, endpoint := newFakeSpanner(t)
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+	ctx := context.Background()
+
+	newPersister := func(t *testing.T) *spanner.Persister {
+		c, err := spanner.NewClient(endpoint, database, nil)
+		require.NoError(t, err)
+		return spanner.NewPersister(reg, c, uuid.Must(uuid.NewV4()))
+	}
+
+	t.Run("relationtuple.ManagerTest", func(t *testing.T) {
+		relationtuple.ManagerTest(t, newPersister(t))
+	})
+
+	t.Run("relationtuple.IsolationTest", func(t *testing.T) {
+		relationtuple.IsolationTest(t, newPersister(t), newPersister(t))
+	})
+
+	t.Run("relationtuple.UUIDMappingManagerTest", func(t *testing.T) {
+		relationtuple.MappingManagerTest(t, newPersister(t))
+	})
+
+	t.Run("case=unknown UUIDs map to empty strings", func(t *testing.T) {
+		s, err := newPersister(t).MapUUIDsToStrings(ctx, uuid.Must(uuid.NewV4()))
+		require.NoError(t, err)
+		assert.Equal(t, []string{""}, s)
+	})
+
+	t.Run("case=reverse lookup by subject", func(t *testing.T) {
+		p := newPersister(t)
+		subject := &relationtuple.SubjectSet{Namespace: "groups", Object: uuid.Must(uuid.NewV4()), Relation: "member"}
+		rs := []*relationtuple.RelationTuple{
+			{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "view", Subject: subject},
+			{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "edit", Subject: subject},
+			{Namespace: "folders", Object: uuid.Must(uuid.NewV4()), Relation: "view", Subject: subject},
+		}
+		require.NoError(t, p.WriteRelationTuples(ctx, rs...))
+
+		res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Subject: subject})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, rs, res)
+
+		docs := "docs"
+		res, _, err = p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &docs, Subject: subject})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, rs[:2], res)
+	})
+
+	t.Run("case=snaptokens", func(t *testing.T) {
+		p := newPersister(t)
+		rt := &relationtuple.RelationTuple{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "view", Subject: &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}}
+
+		writeCtx, written := relationtuple.WithSnaptokenRecorder(ctx)
+		require.NoError(t, p.WriteRelationTuples(writeCtx, rt))
+		token := written()
+		_, err := time.Parse(time.RFC3339Nano, token)
+		require.NoError(t, err, "%q", token)
+
+		res, _, err := p.GetRelationTuples(relationtuple.WithSnaptoken(ctx, token), &relationtuple.RelationQuery{Namespace: &rt.Namespace, Object: &rt.Object})
+		require.NoError(t, err)
+		assert.Equal(t, []*relationtuple.RelationTuple{rt}, res)
+		assert.Equal(t, token, fake.lastReadOnly().MinReadTimestamp)
+
+		latest, err := p.Snaptoken(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, latest, token)
+
+		_, _, err = p.GetRelationTuples(relationtuple.WithSnaptoken(ctx, "not a snaptoken"), &relationtuple.RelationQuery{})
+		assert.ErrorIs(t, err, herodot.ErrBadRequest)
+	})
+
+	t.Run("case=concurrent transactions use their own sessions", func(t *testing.T) {
+		reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+			driver.WithNamespaces([]*namespace.Namespace{{Name: "docs", Relations: []ast.Relation{{Name: "owner", MaxSubjects: 1}}}}))
+		c, err := spanner.NewClient(endpoint, database, nil)
+		require.NoError(t, err)
+		p := spanner.NewPersister(reg, c, uuid.Must(uuid.NewV4()))
+
+		// The subject limit makes each write a read-write transaction.
+		var eg errgroup.Group
+		for i := 0; i < 20; i++ {
+			eg.Go(func() error {
+				return p.WriteRelationTuples(ctx, &relationtuple.RelationTuple{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "owner", Subject: &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}})
+			})
+		}
+		require.NoError(t, eg.Wait())
+
+		res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{}, x.WithSize(100))
+		require.NoError(t, err)
+		assert.Len(t, res, 20)
+	})
+
+	t.Run("case=expired session", func(t *testing.T) {
+		p := newPersister(t)
+		_, err := p.MapStringsToUUIDs(ctx, "foo")
+		require.NoError(t, err)
+
+		fake.expireSessions()
+		_, _, err = p.GetRelationTuples(ctx, &relationtuple.RelationQuery{})
+		require.NoError(t, err)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	_, endpoint := newFakeSpanner(t)
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "docs"}}),
+		driver.WithConfig(config.KeySpannerDatabase, database),
+		driver.WithConfig(config.KeySpannerEndpoint, endpoint),
+	)
+	ctx := context.Background()
+
+	require.IsType(t, &spanner.Persister{}, reg.RelationTupleManager())
+	require.IsType(t, &spanner.Persister{}, reg.MappingManager())
+
+	tuple := &rts.RelationTuple{Namespace: "docs", Object: "readme", Relation: "view", Subject: rts.NewSubjectID("alice")}
+	transact, err := relationtuple.NewHandler(reg).TransactRelationTuples(ctx, &rts.TransactRelationTuplesRequest{
+		RelationTupleDeltas: []*rts.RelationTupleDelta{{Action: rts.RelationTupleDelta_ACTION_INSERT, RelationTuple: tuple}},
+	})
+	require.NoError(t, err)
+	require.Len(t, transact.Snaptokens, 1)
+	written, err := time.Parse(time.RFC3339Nano, transact.Snaptokens[0])
+	require.NoError(t, err)
+
+	res, err := check.NewHandler(reg).Check(ctx, &rts.CheckRequest{Tuple: tuple, Snaptoken: transact.Snaptokens[0]})
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Empty(t, res.Snaptoken, "the snaptoken is only returned if none was requested")
+
+	res, err = check.NewHandler(reg).Check(ctx, &rts.CheckRequest{Tuple: tuple, Latest: true})
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	latest, err := time.Parse(time.RFC3339Nano, res.Snaptoken)
+	require.NoError(t, err)
+	assert.False(t, latest.Before(written))
+}
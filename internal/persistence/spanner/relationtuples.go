@@ -0,0 +1,471 @@
+package spanner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// request is a read of the relation tuples table or the subject index.
+	request struct {
+		index string
+		// prefix is the prefix of the table or index keys that are read.
+		prefix []string
+		// exact is set if all rows of the request match the relation query.
+		exact bool
+	}
+)
+
+// tupleColumns are the columns of the relation tuples table, and the order of
+// rows in it.
+var tupleColumns = []string{
+	"nid", "namespace", "object", "relation",
+	"subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation",
+}
+
+func subjectColumns(s relationtuple.Subject) []string {
+	if ss, ok := s.(*relationtuple.SubjectSet); ok {
+		return []string{"", ss.Namespace, ss.Object.String(), ss.Relation}
+	}
+	return []string{s.UniqueID().String(), "", "", ""}
+}
+
+// tupleRow returns the row of the relation tuple, which is also its key.
+func (p *Persister) tupleRow(ctx context.Context, r *relationtuple.RelationTuple) []string {
+	return append([]string{p.NetworkID(ctx).String(), r.Namespace, r.Object.String(), r.Relation}, subjectColumns(r.Subject)...)
+}
+
+func decodeRow(row []string) (*relationtuple.RelationTuple, error) {
+	if len(row) != len(tupleColumns) {
+		return nil, errors.Errorf("malformed relation tuple row %q", row)
+	}
+	r := &relationtuple.RelationTuple{
+		Namespace: row[1],
+		Relation:  row[3],
+	}
+	var err error
+	if r.Object, err = uuid.FromString(row[2]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if row[4] != "" {
+		id, err := uuid.FromString(row[4])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r.Subject = &relationtuple.SubjectID{ID: id}
+		return r, nil
+	}
+	obj, err := uuid.FromString(row[6])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.Subject = &relationtuple.SubjectSet{Namespace: row[5], Object: obj, Relation: row[7]}
+	return r, nil
+}
+
+// key returns the key of the row in the table or index of the request.
+func (req *request) key(row []string) []string {
+	if req.index == "" {
+		return row
+	}
+	return []string{row[0], row[4], row[5], row[6], row[7], row[1], row[2], row[3]}
+}
+
+func matches(q *relationtuple.RelationQuery, r *relationtuple.RelationTuple) bool {
+	return (q.Namespace == nil || *q.Namespace == r.Namespace) &&
+		(q.Object == nil || *q.Object == r.Object) &&
+		(q.Relation == nil || *q.Relation == r.Relation) &&
+		(q.Subject == nil || q.Subject.Equals(r.Subject))
+}
+
+// plan returns the request with the longest key prefix for the query.
+func (p *Persister) plan(ctx context.Context, q *relationtuple.RelationQuery) *request {
+	nid := p.NetworkID(ctx).String()
+	// objectPrefix is the prefix of namespace, object, and relation, as far
+	// as the query sets them in that order.
+	var objectPrefix []string
+	if q.Namespace != nil {
+		objectPrefix = append(objectPrefix, *q.Namespace)
+		if q.Object != nil {
+			objectPrefix = append(objectPrefix, q.Object.String())
+			if q.Relation != nil {
+				objectPrefix = append(objectPrefix, *q.Relation)
+			}
+		}
+	}
+	set := 0
+	for _, ok := range []bool{q.Namespace != nil, q.Object != nil, q.Relation != nil} {
+		if ok {
+			set++
+		}
+	}
+
+	if q.Subject != nil && len(objectPrefix) < 3 {
+		return &request{
+			index:  IndexSubject,
+			prefix: append(append([]string{nid}, subjectColumns(q.Subject)...), objectPrefix...),
+			exact:  len(objectPrefix) == set,
+		}
+	}
+	req := &request{
+		prefix: append([]string{nid}, objectPrefix...),
+		exact:  len(objectPrefix) == set && q.Subject == nil,
+	}
+	if q.Subject != nil {
+		req.prefix = append(req.prefix, subjectColumns(q.Subject)...)
+		req.exact = true
+	}
+	return req
+}
+
+// find returns up to limit relation tuples matching the query, starting after
+// the key, and their keys. A negative limit returns all of them. The reads are
+// in the transaction, or in single-use read-only transactions if it is nil.
+func (p *Persister) find(ctx context.Context, q *relationtuple.RelationQuery, after []string, limit int, tx *transactionSelector) ([]*relationtuple.RelationTuple, [][]string, error) {
+	if tx == nil {
+		var err error
+		if tx, err = p.readOnly(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req := p.plan(ctx, q)
+	batch := limit
+	if !req.exact || limit < 0 {
+		batch = walkPageSize
+	}
+
+	var (
+		res  []*relationtuple.RelationTuple
+		keys [][]string
+	)
+	for limit < 0 || len(res) < limit {
+		r := keyRange{StartClosed: req.prefix, EndClosed: req.prefix}
+		if after != nil {
+			r.StartClosed, r.StartOpen = nil, after
+		}
+		rs, err := p.c.read(ctx, &readRequest{
+			Transaction: tx,
+			Table:       TableRelationTuples,
+			Index:       req.index,
+			Columns:     tupleColumns,
+			KeySet:      keySet{Ranges: []keyRange{r}},
+			Limit:       batch,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range rs.Rows {
+			rt, err := decodeRow(row)
+			if err != nil {
+				return nil, nil, err
+			}
+			after = req.key(row)
+			if matches(q, rt) {
+				res, keys = append(res, rt), append(keys, after)
+				if len(res) == limit {
+					break
+				}
+			}
+		}
+		if len(rs.Rows) < batch {
+			break
+		}
+	}
+	return res, keys, nil
+}
+
+func parsePageToken(token string) ([]string, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.WithStack(persistence.ErrMalformedPageToken)
+	}
+	var key []string
+	if err := json.Unmarshal(raw, &key); err != nil || len(key) != len(tupleColumns) {
+		return nil, errors.WithStack(persistence.ErrMalformedPageToken)
+	}
+	return key, nil
+}
+
+func encodePageToken(key []string) string {
+	// Marshalling strings never fails.
+	raw, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// page returns one page of the relation tuples matching the query, and the
+// token of the next page.
+func (p *Persister) page(ctx context.Context, query *relationtuple.RelationQuery, options []x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	pagination := x.GetPaginationOptions(options...)
+	size := pagination.Size
+	if size == 0 {
+		size = defaultPageSize
+	}
+	after, err := parsePageToken(pagination.Token)
+	if err != nil {
+		return nil, "", err
+	}
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+
+	res, keys, err := p.find(ctx, query, after, size+1, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	var nextPageToken string
+	if len(res) > size {
+		res = res[:size]
+		nextPageToken = encodePageToken(keys[size-1])
+	}
+	return res, nextPageToken, nil
+}
+
+func (p *Persister) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.GetRelationTuples")
+	defer span.End()
+
+	res, nextPageToken, err := p.page(ctx, query, options)
+	if err != nil {
+		return nil, "", err
+	}
+	if res == nil {
+		res = []*relationtuple.RelationTuple{}
+	}
+	return res, nextPageToken, nil
+}
+
+func (p *Persister) WriteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.WriteRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, rs, nil)
+}
+
+// TouchRelationTuples is the same as WriteRelationTuples, as the table does
+// not record when a relation tuple was written.
+func (p *Persister) TouchRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.TouchRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, rs, nil)
+}
+
+func (p *Persister) DeleteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.DeleteRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, nil, rs)
+}
+
+func (p *Persister) TransactRelationTuples(ctx context.Context, insert []*relationtuple.RelationTuple, delete []*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.TransactRelationTuples")
+	defer span.End()
+
+	return p.transact(ctx, insert, delete)
+}
+
+// DeleteAllRelationTuples deletes the relation tuples with one key range
+// deletion if the query is a prefix of the table's key, and otherwise reads
+// them first and deletes them in batches that are not atomic.
+func (p *Persister) DeleteAllRelationTuples(ctx context.Context, query *relationtuple.RelationQuery) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.DeleteAllRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	if req := p.plan(ctx, query); req.index == "" && req.exact {
+		return p.blindWrite(ctx, []mutation{{Delete: &deletion{
+			Table:  TableRelationTuples,
+			KeySet: keySet{Ranges: []keyRange{{StartClosed: req.prefix, EndClosed: req.prefix}}},
+		}}})
+	}
+	res, _, err := p.find(persistence.WithConsistency(ctx, persistence.ConsistencyStrong), query, nil, -1, nil)
+	if err != nil {
+		return err
+	}
+	return p.transact(ctx, nil, res)
+}
+
+func (p *Persister) DeleteRelationTuplesPage(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.DeleteRelationTuplesPage")
+	defer span.End()
+
+	res, nextPageToken, err := p.page(ctx, query, options)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := p.transact(ctx, nil, res); err != nil {
+		return 0, "", err
+	}
+	return len(res), nextPageToken, nil
+}
+
+// WalkRelationTuples reads all pages at the timestamp of the latest snapshot,
+// so concurrent changes are not included.
+func (p *Persister) WalkRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, f func(ctx context.Context, page []*relationtuple.RelationTuple) error) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.WalkRelationTuples")
+	defer span.End()
+
+	ts, err := p.Snaptoken(ctx)
+	if err != nil {
+		return err
+	}
+	snapshot := withReadTimestamp(ctx, ts)
+
+	var nextPage string
+	for {
+		page, next, err := p.page(snapshot, query, []x.PaginationOptionSetter{x.WithToken(nextPage), x.WithSize(walkPageSize)})
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := f(ctx, page); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		nextPage = next
+	}
+}
+
+// CountRelationTuples always counts exactly, by reading all matching relation
+// tuples.
+func (p *Persister) CountRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, _ bool) (int, bool, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.CountRelationTuples")
+	defer span.End()
+
+	if query == nil {
+		query = &relationtuple.RelationQuery{}
+	}
+	res, _, err := p.find(ctx, query, nil, -1, nil)
+	return len(res), false, err
+}
+
+// transact inserts and then deletes the relation tuples. If a namespace limits
+// the subjects of an inserted relation, the limit is checked in a read-write
+// transaction, so that concurrent writes cannot exceed it. Otherwise the
+// changes are committed without reading, atomically for up to
+// maxMutationsPerCommit changes and in batches for larger ones.
+func (p *Persister) transact(ctx context.Context, insert, delete []*relationtuple.RelationTuple) error {
+	for _, rs := range [][]*relationtuple.RelationTuple{insert, delete} {
+		for _, r := range rs {
+			if r.Subject == nil {
+				return errors.WithStack(ketoapi.ErrNilSubject)
+			}
+		}
+	}
+	if len(insert)+len(delete) == 0 {
+		return nil
+	}
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	if p.d.Config(ctx).StrictMode() {
+		for _, r := range insert {
+			if err := relationtuple.ValidateSchema(ctx, nm, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Spanner applies the mutations of a commit in order.
+	mutations := make([]mutation, 0, len(insert)+len(delete))
+	for _, r := range insert {
+		mutations = append(mutations, mutation{InsertOrUpdate: &write{
+			Table:   TableRelationTuples,
+			Columns: tupleColumns,
+			Values:  [][]string{p.tupleRow(ctx, r)},
+		}})
+	}
+	for _, r := range delete {
+		mutations = append(mutations, mutation{Delete: &deletion{
+			Table:  TableRelationTuples,
+			KeySet: keySet{Keys: [][]string{p.tupleRow(ctx, r)}},
+		}})
+	}
+
+	limited := false
+	for _, r := range insert {
+		max, err := relationtuple.MaxSubjects(ctx, nm, r.Namespace, r.Relation)
+		if err != nil {
+			return err
+		}
+		limited = limited || max > 0
+	}
+	if !limited {
+		return p.blindWrite(ctx, mutations)
+	}
+	return p.readWrite(ctx, func(ctx context.Context, tx *transactionSelector) ([]mutation, error) {
+		return mutations, p.checkCardinality(ctx, tx, insert, delete)
+	})
+}
+
+// checkCardinality returns a conflict error if any object of the inserted
+// relation tuples would have more subjects for the relation than the
+// namespace allows after the changes.
+func (p *Persister) checkCardinality(ctx context.Context, tx *transactionSelector, insert, delete []*relationtuple.RelationTuple) error {
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	checked := make(map[string]bool)
+	for _, r := range insert {
+		object := strings.Join([]string{r.Namespace, r.Object.String(), r.Relation}, "\x00")
+		if checked[object] {
+			continue
+		}
+		checked[object] = true
+
+		max, err := relationtuple.MaxSubjects(ctx, nm, r.Namespace, r.Relation)
+		if err != nil {
+			return err
+		}
+		if max == 0 {
+			continue
+		}
+		_, existing, err := p.find(ctx, &relationtuple.RelationQuery{Namespace: &r.Namespace, Object: &r.Object, Relation: &r.Relation}, nil, -1, tx)
+		if err != nil {
+			return err
+		}
+		subjects := make(map[string]bool, len(existing))
+		for _, key := range existing {
+			subjects[strings.Join(key, "\x00")] = true
+		}
+		for i, c := range append(append([]*relationtuple.RelationTuple{}, insert...), delete...) {
+			if c.Namespace == r.Namespace && c.Object == r.Object && c.Relation == r.Relation {
+				subjects[strings.Join(p.tupleRow(ctx, c), "\x00")] = i < len(insert)
+			}
+		}
+		count := 0
+		for _, ok := range subjects {
+			if ok {
+				count++
+			}
+		}
+		if count > max {
+			return errors.WithStack(herodot.ErrConflict.WithReasonf(
+				"Relation %q in namespace %q allows at most %d subject(s) per object, but the object would have %d.",
+				r.Relation, r.Namespace, max, count))
+		}
+	}
+	return nil
+}
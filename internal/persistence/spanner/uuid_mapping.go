@@ -0,0 +1,90 @@
+package spanner
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+// maxKeysPerRead is the number of UUID mappings that are read at once.
+const maxKeysPerRead = 1000
+
+func (p *Persister) MapStringsToUUIDs(ctx context.Context, s ...string) ([]uuid.UUID, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.MapStringsToUUIDs")
+	defer span.End()
+
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	nid := p.NetworkID(ctx)
+	uuids := make([]uuid.UUID, len(s))
+	written := make(map[uuid.UUID]bool, len(s))
+	mutations := make([]mutation, 0, len(s))
+	for i, val := range s {
+		uuids[i] = uuid.NewV5(nid, val)
+		if written[uuids[i]] {
+			continue
+		}
+		written[uuids[i]] = true
+		mutations = append(mutations, mutation{InsertOrUpdate: &write{
+			Table:   TableUUIDMappings,
+			Columns: []string{"nid", "id", "string_representation"},
+			Values:  [][]string{{nid.String(), uuids[i].String(), val}},
+		}})
+	}
+	if err := p.blindWrite(ctx, mutations); err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
+
+// MapUUIDsToStrings maps unknown UUIDs to the empty string.
+func (p *Persister) MapUUIDsToStrings(ctx context.Context, u ...uuid.UUID) ([]string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.spanner.MapUUIDsToStrings")
+	defer span.End()
+
+	if len(u) == 0 {
+		return nil, nil
+	}
+	tx, err := p.readOnly(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nid := p.NetworkID(ctx).String()
+	keys := make([][]string, 0, len(u))
+	requested := make(map[uuid.UUID]bool, len(u))
+	for _, id := range u {
+		if !requested[id] {
+			requested[id] = true
+			keys = append(keys, []string{nid, id.String()})
+		}
+	}
+	byID := make(map[string]string, len(keys))
+	for len(keys) > 0 {
+		n := maxKeysPerRead
+		if n > len(keys) {
+			n = len(keys)
+		}
+		rs, err := p.c.read(ctx, &readRequest{
+			Transaction: tx,
+			Table:       TableUUIDMappings,
+			Columns:     []string{"id", "string_representation"},
+			KeySet:      keySet{Keys: keys[:n]},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rs.Rows {
+			byID[row[0]] = row[1]
+		}
+		keys = keys[n:]
+	}
+
+	res := make([]string, len(u))
+	for i, id := range u {
+		res[i] = byID[id.String()]
+	}
+	return res, nil
+}
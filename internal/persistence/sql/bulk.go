@@ -0,0 +1,172 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+// maxBulkParameters limits the bound parameters of one statement. It is below
+// the lowest limit of the supported databases, which is 32766 for SQLite.
+const maxBulkParameters = 30000
+
+var (
+	relationTupleColumns = []string{"shard_id", "nid", "namespace", "object", "relation", "subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation", "commit_time"}
+	changeColumns        = []string{"nid", "action", "namespace", "object", "relation", "subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation", "created_at"}
+)
+
+// bulkInsert inserts the rows with multi-row INSERT statements, so that large
+// batches need a few round trips instead of one per row.
+func (p *Persister) bulkInsert(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	_, err := p.bulkInsertWithSuffix(ctx, table, columns, rows, "")
+	return err
+}
+
+// bulkInsertWithSuffix is like bulkInsert, but appends suffix to every
+// statement and returns the number of inserted rows.
+func (p *Persister) bulkInsertWithSuffix(ctx context.Context, table string, columns []string, rows [][]interface{}, suffix string) (int, error) {
+	perStatement := maxBulkParameters / len(columns)
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	prefix := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES "
+
+	inserted := 0
+	for len(rows) > 0 {
+		batch := rows
+		if len(batch) > perStatement {
+			batch = batch[:perStatement]
+		}
+		rows = rows[len(batch):]
+
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*len(columns))
+		for i, row := range batch {
+			placeholders[i] = placeholder
+			args = append(args, row...)
+		}
+		n, err := p.Connection(ctx).RawQuery(prefix+strings.Join(placeholders, ", ")+suffix, args...).ExecWithCount()
+		if err != nil {
+			return inserted, sqlcon.HandleError(err)
+		}
+		inserted += n
+	}
+	return inserted, nil
+}
+
+// newRelationTuple validates the relation tuple and returns its row.
+func (p *Persister) newRelationTuple(ctx context.Context, aliases namespaceAliases, rel *relationtuple.RelationTuple) (*RelationTuple, error) {
+	if rel.Subject == nil {
+		return nil, errors.WithStack(ketoapi.ErrNilSubject)
+	}
+	rel = aliases.resolve(rel)
+	if p.d.Config(ctx).StrictMode() {
+		nm, err := p.d.Config(ctx).NamespaceManager()
+		if err != nil {
+			return nil, err
+		}
+		if err := relationtuple.ValidateSchema(ctx, nm, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	rt := &RelationTuple{
+		ID:         uuid.Must(uuid.NewV4()),
+		NetworkID:  p.NetworkID(ctx),
+		CommitTime: time.Now(),
+		Namespace:  rel.Namespace,
+		Object:     rel.Object,
+		Relation:   rel.Relation,
+	}
+	return rt, rt.insertSubject(ctx, rel.Subject)
+}
+
+// insertRelationTuples inserts the relation tuples in batches and records
+// the changes. Relation tuples that exist already are skipped. It has to be
+// called in a transaction.
+func (p *Persister) insertRelationTuples(ctx context.Context, rs []*relationtuple.RelationTuple) error {
+	if len(rs) == 0 {
+		return nil
+	}
+	aliases, err := p.namespaceAliases(ctx)
+	if err != nil {
+		return err
+	}
+
+	tuples := make([]*RelationTuple, len(rs))
+	for i, r := range rs {
+		rt, err := p.newRelationTuple(ctx, aliases, r)
+		if err != nil {
+			return err
+		}
+		tuples[i] = rt
+	}
+	_, err = p.insertRows(ctx, tuples)
+	return err
+}
+
+// insertRows inserts the rows of the relation tuples that do not exist yet,
+// records the changes, and returns the inserted rows. The unique indexes of
+// the relation tuple table decide which rows exist already, so that concurrent
+// writes of the same relation tuple insert it once. It has to be called in a
+// transaction.
+func (p *Persister) insertRows(ctx context.Context, tuples []*RelationTuple) ([]*RelationTuple, error) {
+	rows := make([][]interface{}, len(tuples))
+	for i, rt := range tuples {
+		rows[i] = []interface{}{rt.ID, rt.NetworkID, rt.Namespace, rt.Object, rt.Relation, rt.SubjectID, rt.SubjectSetNamespace, rt.SubjectSetObject, rt.SubjectSetRelation, rt.CommitTime}
+	}
+
+	suffix := " ON CONFLICT DO NOTHING"
+	if p.Connection(ctx).Dialect.Name() == "mysql" {
+		suffix = " ON DUPLICATE KEY UPDATE shard_id = shard_id"
+	}
+	n, err := p.bulkInsertWithSuffix(ctx, "keto_relation_tuples", relationTupleColumns, rows, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted := tuples
+	if n < len(tuples) {
+		if inserted, err = p.existingRows(ctx, tuples); err != nil {
+			return nil, err
+		}
+	}
+	return inserted, p.recordChanges(ctx, ketoapi.ActionInsert, inserted...)
+}
+
+// existingRows returns the rows whose shard IDs exist in the relation tuple
+// table.
+func (p *Persister) existingRows(ctx context.Context, tuples []*RelationTuple) ([]*RelationTuple, error) {
+	found := make(map[uuid.UUID]bool, len(tuples))
+	for start := 0; start < len(tuples); start += maxBulkParameters {
+		end := start + maxBulkParameters
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+		ids := make([]interface{}, 0, end-start)
+		for _, rt := range tuples[start:end] {
+			ids = append(ids, rt.ID)
+		}
+
+		var existing relationTuples
+		if err := p.QueryWithNetwork(ctx).Select("shard_id").Where("shard_id IN (?)", ids...).All(&existing); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+		for _, rt := range existing {
+			found[rt.ID] = true
+		}
+	}
+
+	res := make([]*RelationTuple, 0, len(found))
+	for _, rt := range tuples {
+		if found[rt.ID] {
+			res = append(res, rt)
+		}
+	}
+	return res, nil
+}
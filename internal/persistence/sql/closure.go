@@ -0,0 +1,498 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// closureEntry is a member of the closure of an object's relation, either
+	// a subject ID or a subject set where the closure stops. The ID is derived
+	// from the relation and the member, so that checks look entries up by
+	// their primary key.
+	closureEntry struct {
+		ID                  uuid.UUID      `db:"id"`
+		NetworkID           uuid.UUID      `db:"nid"`
+		Namespace           string         `db:"namespace"`
+		Object              uuid.UUID      `db:"object"`
+		Relation            string         `db:"relation"`
+		SubjectID           uuid.NullUUID  `db:"subject_id"`
+		SubjectSetNamespace sql.NullString `db:"subject_set_namespace"`
+		SubjectSetObject    uuid.NullUUID  `db:"subject_set_object"`
+		SubjectSetRelation  sql.NullString `db:"subject_set_relation"`
+	}
+	closureEntries []*closureEntry
+	// closureNode is an object's relation that has a closure.
+	closureNode struct {
+		namespace string
+		object    uuid.UUID
+		relation  string
+	}
+	// closureRelations are the relations with a closure by namespace.
+	closureRelations map[string]map[string]bool
+	// versionedClosureRelations caches the closure relations of a version of
+	// the namespaces.
+	versionedClosureRelations struct {
+		version   string
+		relations closureRelations
+	}
+)
+
+// wildcardRelation is not expanded by the check engine, so subject sets with
+// it are not part of closures.
+const wildcardRelation = "..."
+
+var (
+	_ relationtuple.ClosureManager = (*Persister)(nil)
+
+	closureColumns = []string{"id", "nid", "namespace", "object", "relation", "subject_id", "subject_set_namespace", "subject_set_object", "subject_set_relation"}
+)
+
+func (closureEntries) TableName() string {
+	return "keto_relation_tuple_closure"
+}
+
+func (closureEntry) TableName() string {
+	return "keto_relation_tuple_closure"
+}
+
+func (e *closureEntry) member() relationtuple.Subject {
+	if e.SubjectID.Valid {
+		return &relationtuple.SubjectID{ID: e.SubjectID.UUID}
+	}
+	return &relationtuple.SubjectSet{
+		Namespace: e.SubjectSetNamespace.String,
+		Object:    e.SubjectSetObject.UUID,
+		Relation:  e.SubjectSetRelation.String,
+	}
+}
+
+func (c closureRelations) has(namespace, relation string) bool {
+	return c[namespace][relation]
+}
+
+func nodeOf(rt *RelationTuple) closureNode {
+	return closureNode{namespace: rt.Namespace, object: rt.Object, relation: rt.Relation}
+}
+
+// closureID returns the ID of the closure entry of the member in the node.
+func closureID(nid uuid.UUID, n closureNode, member relationtuple.Subject) uuid.UUID {
+	return uuid.NewV5(nid, n.namespace+":"+n.object.String()+"#"+n.relation+"@"+member.UniqueID().String())
+}
+
+// closable reports whether the relation of the namespace can have a closure.
+// Userset rewrites are evaluated by the check engine, so relations with one
+// cannot.
+func closable(n *namespace.Namespace, relation string) bool {
+	if len(n.Relations) == 0 {
+		return true
+	}
+	for _, r := range n.Relations {
+		if r.Name == relation {
+			return r.SubjectSetRewrite == nil
+		}
+	}
+	return false
+}
+
+// closureRelations returns the relations with a closure. They are cached until
+// the namespaces change, as checks look them up for every relation.
+func (p *Persister) closureRelations(ctx context.Context) (closureRelations, error) {
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+	var version string
+	if v, ok := nm.(namespace.Versioner); ok {
+		version = v.Version(ctx)
+	}
+	if cached, ok := p.closures.Load().(*versionedClosureRelations); ok && version != "" && cached.version == version {
+		return cached.relations, nil
+	}
+
+	nn, err := nm.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(closureRelations)
+	for _, n := range nn {
+		relations, err := n.ClosureRelations()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range relations {
+			if !closable(n, r) {
+				continue
+			}
+			if c[n.Name] == nil {
+				c[n.Name] = make(map[string]bool)
+			}
+			c[n.Name][r] = true
+		}
+	}
+
+	// The namespaces might have changed while they were listed.
+	if version != "" && version == nm.(namespace.Versioner).Version(ctx) {
+		p.closures.Store(&versionedClosureRelations{version: version, relations: c})
+	}
+	return c, nil
+}
+
+func (p *Persister) HasClosure(ctx context.Context, namespace, relation string) (bool, error) {
+	c, err := p.closureRelations(ctx)
+	if err != nil {
+		return false, err
+	}
+	return c.has(namespace, relation), nil
+}
+
+func (p *Persister) CheckClosure(ctx context.Context, r *relationtuple.RelationTuple) (bool, []*relationtuple.SubjectSet, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CheckClosure")
+	defer span.End()
+
+	if r.Subject == nil {
+		return false, nil, errors.WithStack(ketoapi.ErrNilSubject)
+	}
+	n := closureNode{namespace: r.Namespace, object: r.Object, relation: r.Relation}
+	isMember, err := p.readQueryWithNetwork(ctx).Where("id = ?", closureID(p.NetworkID(ctx), n, r.Subject)).Exists(&closureEntry{})
+	if err != nil {
+		return false, nil, sqlcon.HandleError(err)
+	}
+	if isMember {
+		return true, nil, nil
+	}
+
+	var res closureEntries
+	if err := p.queryClosure(p.readQueryWithNetwork(ctx), n).Where("subject_id IS NULL").All(&res); err != nil {
+		return false, nil, sqlcon.HandleError(err)
+	}
+	open := make([]*relationtuple.SubjectSet, len(res))
+	for i, e := range res {
+		open[i] = e.member().(*relationtuple.SubjectSet)
+	}
+	return false, open, nil
+}
+
+func (p *Persister) RebuildClosure(ctx context.Context) (int, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RebuildClosure")
+	defer span.End()
+
+	var entries int
+	err := p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		entries = 0
+		c, err := p.closureRelations(ctx)
+		if err != nil {
+			return err
+		}
+		if err := p.QueryWithNetwork(ctx).Delete(&closureEntry{}); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		for ns, relations := range c {
+			for rel := range relations {
+				var objects relationTuples
+				if err := p.QueryWithNetwork(ctx).
+					Select("DISTINCT object").
+					Where("namespace = ?", ns).
+					Where("relation = ?", rel).
+					All(&objects); err != nil {
+					return sqlcon.HandleError(err)
+				}
+				for _, o := range objects {
+					n := closureNode{namespace: ns, object: o.Object, relation: rel}
+					members, err := p.closureOf(ctx, c, n, nil)
+					if err != nil {
+						return err
+					}
+					if err := p.insertClosure(ctx, n, members, false); err != nil {
+						return err
+					}
+					entries += len(members)
+				}
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (p *Persister) queryClosure(q *pop.Query, n closureNode) *pop.Query {
+	return q.
+		Where("namespace = ?", n.namespace).
+		Where("object = ?", n.object).
+		Where("relation = ?", n.relation)
+}
+
+// updateClosure applies the inserts or deletes of the relation tuples to the
+// closures. Deletes have to be applied before the relation tuples are
+// deleted.
+func (p *Persister) updateClosure(ctx context.Context, action ketoapi.PatchAction, rts ...*RelationTuple) error {
+	c, err := p.closureRelations(ctx)
+	if err != nil || len(c) == 0 {
+		return err
+	}
+
+	if action == ketoapi.ActionDelete {
+		return p.removeFromClosure(ctx, c, rts)
+	}
+	for _, rt := range rts {
+		if !c.has(rt.Namespace, rt.Relation) {
+			continue
+		}
+		members, err := p.closureMembers(ctx, c, rt)
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			continue
+		}
+		ancestors, err := p.closureAncestors(ctx, c, nodeOf(rt))
+		if err != nil {
+			return err
+		}
+		for _, a := range ancestors {
+			if err := p.insertClosure(ctx, a, members, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// closureMembers returns what the relation tuple adds to the closure of its
+// object's relation.
+func (p *Persister) closureMembers(ctx context.Context, c closureRelations, rt *RelationTuple) ([]relationtuple.Subject, error) {
+	switch {
+	case rt.SubjectID.Valid:
+		return []relationtuple.Subject{&relationtuple.SubjectID{ID: rt.SubjectID.UUID}}, nil
+	case rt.SubjectSetRelation.String == wildcardRelation:
+		return nil, nil
+	case c.has(rt.SubjectSetNamespace.String, rt.SubjectSetRelation.String):
+		var res closureEntries
+		if err := p.queryClosure(p.QueryWithNetwork(ctx), closureNode{
+			namespace: rt.SubjectSetNamespace.String,
+			object:    rt.SubjectSetObject.UUID,
+			relation:  rt.SubjectSetRelation.String,
+		}).All(&res); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+		members := make([]relationtuple.Subject, len(res))
+		for i, e := range res {
+			members[i] = e.member()
+		}
+		return members, nil
+	default:
+		s, err := rt.toInternal()
+		if err != nil {
+			return nil, err
+		}
+		return []relationtuple.Subject{s.Subject}, nil
+	}
+}
+
+// removeFromClosure applies the deletes of the relation tuples to the
+// closures. The closures that reached a deleted subject set of a closure
+// relation are recomputed, for other subjects only their entries are checked.
+func (p *Persister) removeFromClosure(ctx context.Context, c closureRelations, rts []*RelationTuple) error {
+	deleted := make(map[uuid.UUID]bool, len(rts))
+	for _, rt := range rts {
+		deleted[rt.ID] = true
+	}
+
+	recompute := make(map[closureNode]bool)
+	var order []closureNode
+	for _, rt := range rts {
+		if !c.has(rt.Namespace, rt.Relation) || rt.SubjectSetRelation.String == wildcardRelation {
+			continue
+		}
+		ancestors, err := p.closureAncestors(ctx, c, nodeOf(rt))
+		if err != nil {
+			return err
+		}
+
+		if rt.SubjectSetRelation.Valid && c.has(rt.SubjectSetNamespace.String, rt.SubjectSetRelation.String) {
+			for _, a := range ancestors {
+				if !recompute[a] {
+					recompute[a] = true
+					order = append(order, a)
+				}
+			}
+			continue
+		}
+
+		s, err := rt.toInternal()
+		if err != nil {
+			return err
+		}
+		if err := p.removeMember(ctx, c, s.Subject, ancestors, deleted); err != nil {
+			return err
+		}
+	}
+
+	for _, n := range order {
+		members, err := p.closureOf(ctx, c, n, deleted)
+		if err != nil {
+			return err
+		}
+		if err := p.queryClosure(p.QueryWithNetwork(ctx), n).Delete(&closureEntry{}); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if err := p.insertClosure(ctx, n, members, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMember removes the member from the closures of the nodes that do not
+// reach it through other relation tuples anymore.
+func (p *Persister) removeMember(ctx context.Context, c closureRelations, member relationtuple.Subject, nodes []closureNode, deleted map[uuid.UUID]bool) error {
+	q := p.QueryWithNetwork(ctx)
+	if err := p.whereSubject(ctx, q, member); err != nil {
+		return err
+	}
+	var holders relationTuples
+	if err := q.All(&holders); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	reaching := make(map[closureNode]bool)
+	for _, h := range holders {
+		if deleted[h.ID] || !c.has(h.Namespace, h.Relation) || reaching[nodeOf(h)] {
+			continue
+		}
+		ancestors, err := p.closureAncestors(ctx, c, nodeOf(h))
+		if err != nil {
+			return err
+		}
+		for _, a := range ancestors {
+			reaching[a] = true
+		}
+	}
+
+	nid := p.NetworkID(ctx)
+	for _, n := range nodes {
+		if reaching[n] {
+			continue
+		}
+		if err := p.QueryWithNetwork(ctx).Where("id = ?", closureID(nid, n, member)).Delete(&closureEntry{}); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+	return nil
+}
+
+// closureAncestors returns the node and all nodes that reach it through
+// relation tuples of closure relations.
+func (p *Persister) closureAncestors(ctx context.Context, c closureRelations, n closureNode) ([]closureNode, error) {
+	nodes := []closureNode{n}
+	seen := map[closureNode]bool{n: true}
+	for i := 0; i < len(nodes); i++ {
+		var res relationTuples
+		if err := p.QueryWithNetwork(ctx).
+			Where("subject_set_namespace = ?", nodes[i].namespace).
+			Where("subject_set_object = ?", nodes[i].object).
+			Where("subject_set_relation = ?", nodes[i].relation).
+			Where("subject_id IS NULL").
+			All(&res); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+		for _, rt := range res {
+			if a := nodeOf(rt); c.has(a.namespace, a.relation) && !seen[a] {
+				seen[a] = true
+				nodes = append(nodes, a)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// closureOf computes the closure of the node from the relation tuples, except
+// for the excluded ones.
+func (p *Persister) closureOf(ctx context.Context, c closureRelations, n closureNode, exclude map[uuid.UUID]bool) ([]relationtuple.Subject, error) {
+	var members []relationtuple.Subject
+	nodes := []closureNode{n}
+	seen := map[closureNode]bool{n: true}
+	for i := 0; i < len(nodes); i++ {
+		var res relationTuples
+		if err := p.queryClosure(p.QueryWithNetwork(ctx), nodes[i]).All(&res); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+		for _, rt := range res {
+			if exclude[rt.ID] || rt.SubjectSetRelation.String == wildcardRelation {
+				continue
+			}
+			if rt.SubjectSetRelation.Valid && c.has(rt.SubjectSetNamespace.String, rt.SubjectSetRelation.String) {
+				set := closureNode{namespace: rt.SubjectSetNamespace.String, object: rt.SubjectSetObject.UUID, relation: rt.SubjectSetRelation.String}
+				if !seen[set] {
+					seen[set] = true
+					nodes = append(nodes, set)
+				}
+				continue
+			}
+			s, err := rt.toInternal()
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, s.Subject)
+		}
+	}
+	return members, nil
+}
+
+// insertClosure adds the members to the closure of the node. Unless
+// skipExisting is set, the members must not be in the closure yet.
+func (p *Persister) insertClosure(ctx context.Context, n closureNode, members []relationtuple.Subject, skipExisting bool) error {
+	nid := p.NetworkID(ctx)
+	rows := make(map[uuid.UUID][]interface{}, len(members))
+	ids := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		id := closureID(nid, n, m)
+		if _, ok := rows[id]; ok {
+			continue
+		}
+		row := []interface{}{id, nid, n.namespace, n.object, n.relation, uuid.NullUUID{}, sql.NullString{}, uuid.NullUUID{}, sql.NullString{}}
+		switch m := m.(type) {
+		case *relationtuple.SubjectID:
+			row[5] = uuid.NullUUID{UUID: m.ID, Valid: true}
+		case *relationtuple.SubjectSet:
+			row[6], row[7], row[8] = sql.NullString{String: m.Namespace, Valid: true}, uuid.NullUUID{UUID: m.Object, Valid: true}, sql.NullString{String: m.Relation, Valid: true}
+		}
+		rows[id] = row
+		ids = append(ids, id)
+	}
+
+	if skipExisting {
+		for len(ids) > 0 {
+			batch := ids
+			if len(batch) > maxBulkParameters {
+				batch = batch[:maxBulkParameters]
+			}
+			ids = ids[len(batch):]
+
+			var existing closureEntries
+			if err := p.QueryWithNetwork(ctx).Select("id").Where("id IN (?)", batch...).All(&existing); err != nil {
+				return sqlcon.HandleError(err)
+			}
+			for _, e := range existing {
+				delete(rows, e.ID)
+			}
+		}
+	}
+
+	values := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, row)
+	}
+	return p.bulkInsert(ctx, closureEntry{}.TableName(), closureColumns, values)
+}
@@ -0,0 +1,171 @@
+package sql_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestClosure(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{
+		{Name: "groups", Config: json.RawMessage(`{"closure":{"relations":["member"]}}`)},
+		{Name: "docs"},
+	}))
+	p := reg.Persister()
+	cm := reg.RelationTupleClosureManager()
+	require.NotNil(t, cm)
+
+	group := func(o uuid.UUID) *relationtuple.SubjectSet {
+		return &relationtuple.SubjectSet{Namespace: "groups", Object: o, Relation: "member"}
+	}
+	member := func(o uuid.UUID, s relationtuple.Subject) *relationtuple.RelationTuple {
+		return &relationtuple.RelationTuple{Namespace: "groups", Object: o, Relation: "member", Subject: s}
+	}
+	isMember := func(t *testing.T, o uuid.UUID, s relationtuple.Subject) bool {
+		ok, _, err := cm.CheckClosure(ctx, member(o, s))
+		require.NoError(t, err)
+		return ok
+	}
+
+	all, eng, backend := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	alice, bob := &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}, &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}
+	viewers := &relationtuple.SubjectSet{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "viewer"}
+
+	require.NoError(t, p.WriteRelationTuples(ctx,
+		member(all, group(eng)),
+		member(eng, group(backend)),
+		member(backend, alice),
+		member(all, viewers),
+	))
+
+	t.Run("case=members of nested groups", func(t *testing.T) {
+		assert.True(t, isMember(t, backend, alice))
+		assert.True(t, isMember(t, eng, alice))
+		assert.True(t, isMember(t, all, alice))
+		assert.False(t, isMember(t, all, bob))
+	})
+
+	t.Run("case=subject sets of other relations stop the closure", func(t *testing.T) {
+		ok, open, err := cm.CheckClosure(ctx, member(all, bob))
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, []*relationtuple.SubjectSet{viewers}, open)
+
+		require.NoError(t, p.WriteRelationTuples(ctx, &relationtuple.RelationTuple{Namespace: "docs", Object: viewers.Object, Relation: "viewer", Subject: bob}))
+		allowed, err := reg.PermissionEngine().CheckIsMember(ctx, member(all, bob), 0)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("case=cycles", func(t *testing.T) {
+		require.NoError(t, p.WriteRelationTuples(ctx, member(backend, group(all))))
+		assert.True(t, isMember(t, backend, viewers))
+
+		require.NoError(t, p.DeleteRelationTuples(ctx, member(backend, group(all))))
+		assert.False(t, isMember(t, backend, viewers))
+		assert.True(t, isMember(t, all, alice))
+	})
+
+	t.Run("case=members reachable on another path are kept", func(t *testing.T) {
+		require.NoError(t, p.WriteRelationTuples(ctx, member(eng, alice)))
+		require.NoError(t, p.DeleteRelationTuples(ctx, member(backend, alice)))
+		assert.False(t, isMember(t, backend, alice))
+		assert.True(t, isMember(t, eng, alice))
+		assert.True(t, isMember(t, all, alice))
+
+		require.NoError(t, p.DeleteRelationTuples(ctx, member(all, group(eng))))
+		assert.False(t, isMember(t, all, alice))
+		assert.True(t, isMember(t, eng, alice))
+	})
+
+	t.Run("case=checks use the closure", func(t *testing.T) {
+		allowed, err := reg.PermissionEngine().CheckIsMember(ctx, member(eng, alice), 0)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		allowed, err = reg.PermissionEngine().CheckIsMember(ctx, member(all, alice), 0)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("case=closure relations follow the namespaces", func(t *testing.T) {
+		ok, err := cm.HasClosure(ctx, "groups", "member")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "groups"}, {Name: "docs"}}))
+		ok, err = cm.HasClosure(ctx, "groups", "member")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestClosureRebuild(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{
+		{Name: "groups", Config: json.RawMessage(`{"closure":{"relations":["member"]}}`)},
+		{Name: "docs"},
+	}))
+	p := reg.Persister()
+	conn, err := reg.PopConnection(ctx)
+	require.NoError(t, err)
+
+	entries := func(t *testing.T) []string {
+		var ids []string
+		require.NoError(t, conn.Store.Select(&ids, "SELECT id FROM keto_relation_tuple_closure ORDER BY id"))
+		return ids
+	}
+
+	// Random writes and deletes have to result in the same closure as
+	// computing it from scratch.
+	groups := make([]uuid.UUID, 8)
+	for i := range groups {
+		groups[i] = uuid.Must(uuid.NewV4())
+	}
+	subjects := []relationtuple.Subject{
+		&relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())},
+		&relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())},
+		&relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())},
+		&relationtuple.SubjectSet{Namespace: "docs", Object: uuid.Must(uuid.NewV4()), Relation: "viewer"},
+	}
+	for _, g := range groups {
+		subjects = append(subjects, &relationtuple.SubjectSet{Namespace: "groups", Object: g, Relation: "member"})
+	}
+
+	rand := rand.New(rand.NewSource(1))
+	var written []*relationtuple.RelationTuple
+	for i := 0; i < 150; i++ {
+		if len(written) > 0 && rand.Intn(3) == 0 {
+			j := rand.Intn(len(written))
+			require.NoError(t, p.DeleteRelationTuples(ctx, written[j]))
+			written = append(written[:j], written[j+1:]...)
+			continue
+		}
+		rt := &relationtuple.RelationTuple{
+			Namespace: "groups",
+			Object:    groups[rand.Intn(len(groups))],
+			Relation:  "member",
+			Subject:   subjects[rand.Intn(len(subjects))],
+		}
+		require.NoError(t, p.WriteRelationTuples(ctx, rt))
+		written = append(written, rt)
+	}
+
+	incremental := entries(t)
+	require.NotEmpty(t, incremental)
+	n, err := reg.RelationTupleClosureManager().RebuildClosure(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(incremental), n)
+	assert.Equal(t, incremental, entries(t))
+}
@@ -0,0 +1,56 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+func (p *Persister) CountRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, estimate bool) (int, bool, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CountRelationTuples")
+	defer span.End()
+
+	sqlQuery := p.readQueryWithNetwork(ctx)
+	if err := p.whereQuery(ctx, sqlQuery, query); err != nil {
+		return 0, false, err
+	}
+
+	// Only PostgreSQL exposes a cheap row estimate through the query planner,
+	// all other dialects fall back to an exact count.
+	if estimate && sqlQuery.Connection.Dialect.Name() == "postgres" {
+		n, err := p.estimateRows(ctx, sqlQuery)
+		return n, true, err
+	}
+
+	n, err := sqlQuery.Count(&RelationTuple{})
+	return n, false, sqlcon.HandleError(err)
+}
+
+// estimateRows returns the number of rows the PostgreSQL query planner
+// expects q to return.
+func (p *Persister) estimateRows(ctx context.Context, q *pop.Query) (int, error) {
+	stmt, args := q.ToSQL(pop.NewModel(&RelationTuple{}, ctx), "1")
+
+	var raw string
+	if err := q.Connection.Store.GetContext(ctx, &raw, "EXPLAIN (FORMAT JSON) "+stmt, args...); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(plan) == 0 {
+		return 0, errors.New("the query plan is empty")
+	}
+	return int(plan[0].Plan.Rows), nil
+}
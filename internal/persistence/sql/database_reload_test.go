@@ -0,0 +1,61 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestReloadDatabase(t *testing.T) {
+	ctx := context.Background()
+	dsn := dbx.GetSqlite(t, dbx.SQLiteFile)
+	reg := driver.NewTestRegistry(t, dsn, driver.WithNamespaces([]*namespace.Namespace{{Name: "n"}}))
+	relationtuple.MapAndWriteTuples(t, reg, &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")})
+
+	count := func(t *testing.T) int {
+		res, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("n")})
+		require.NoError(t, err)
+		return len(res)
+	}
+
+	before := reg.Persister().Connection(ctx).Dialect
+
+	t.Run("case=unchanged configuration keeps the connection", func(t *testing.T) {
+		require.NoError(t, reg.ReloadDatabase(ctx))
+		assert.Same(t, before, reg.Persister().Connection(ctx).Dialect)
+	})
+
+	t.Run("case=changed pool settings reconnect", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyDSN, dsn.Conn+"&max_conns=4&max_idle_conns=2"))
+		require.NoError(t, reg.ReloadDatabase(ctx))
+
+		after := reg.Persister().Connection(ctx).Dialect
+		assert.NotSame(t, before, after)
+		c, err := reg.PopConnection(ctx)
+		require.NoError(t, err)
+		assert.Same(t, after, c.Dialect)
+
+		assert.Equal(t, 1, count(t))
+		relationtuple.MapAndWriteTuples(t, reg, &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("t")})
+		assert.Equal(t, 2, count(t))
+	})
+
+	t.Run("case=changed pool configuration reconnects", func(t *testing.T) {
+		before := reg.Persister().Connection(ctx).Dialect
+		require.NoError(t, reg.Config(ctx).Set(config.KeyDatabasePoolMaxOpenConnections, 8))
+		require.NoError(t, reg.ReloadDatabase(ctx))
+
+		assert.NotSame(t, before, reg.Persister().Connection(ctx).Dialect)
+		assert.Equal(t, 2, count(t))
+	})
+}
@@ -0,0 +1,104 @@
+package sql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// historyEntry is an append-only record of a relation tuple change. It has
+	// the same shape as an outbox entry, but is never deleted.
+	historyEntry   outboxEntry
+	historyEntries []*historyEntry
+)
+
+var _ relationtuple.HistoryManager = (*Persister)(nil)
+
+func (historyEntries) TableName() string {
+	return "keto_relation_tuple_history"
+}
+
+func (historyEntry) TableName() string {
+	return "keto_relation_tuple_history"
+}
+
+func (p *Persister) GetRelationTupleHistory(ctx context.Context, query *relationtuple.HistoryQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.HistoryEntry, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetRelationTupleHistory")
+	defer span.End()
+
+	opts := x.GetPaginationOptions(options...)
+	perPage := opts.Size
+	if perPage == 0 {
+		perPage = defaultPageSize
+	}
+	var lastID int64
+	if opts.Token != "" {
+		var err error
+		lastID, err = strconv.ParseInt(opts.Token, 10, 64)
+		if err != nil {
+			return nil, "", errors.WithStack(persistence.ErrMalformedPageToken)
+		}
+	}
+
+	sqlQuery := p.QueryWithNetwork(ctx).
+		Order("id").
+		Where("id > ?", lastID).
+		Limit(perPage + 1)
+	if err := p.whereQuery(ctx, sqlQuery, &query.RelationQuery); err != nil {
+		return nil, "", err
+	}
+	if !query.Since.IsZero() {
+		sqlQuery.Where("created_at >= ?", query.Since.UTC())
+	}
+	if !query.Until.IsZero() {
+		sqlQuery.Where("created_at < ?", query.Until.UTC())
+	}
+
+	var res historyEntries
+	if err := sqlQuery.All(&res); err != nil {
+		return nil, "", sqlcon.HandleError(err)
+	}
+
+	var nextPageToken string
+	if len(res) > perPage {
+		res = res[:perPage]
+		nextPageToken = strconv.FormatInt(res[len(res)-1].ID, 10)
+	}
+
+	entries := make([]*relationtuple.HistoryEntry, len(res))
+	for i, e := range res {
+		rt, err := (*outboxEntry)(e).toInternal()
+		if err != nil {
+			return nil, "", err
+		}
+		entries[i] = &relationtuple.HistoryEntry{
+			ID:     e.ID,
+			Action: ketoapi.PatchAction(e.Action),
+			Tuple:  rt,
+			Time:   e.CreatedAt,
+		}
+	}
+	return entries, nextPageToken, nil
+}
+
+func (p *Persister) LatestRelationTupleHistoryID(ctx context.Context) (int64, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.LatestRelationTupleHistoryID")
+	defer span.End()
+
+	var res historyEntries
+	if err := p.QueryWithNetwork(ctx).Order("id desc").Limit(1).All(&res); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	if len(res) == 0 {
+		return 0, nil
+	}
+	return res[0].ID, nil
+}
@@ -192,7 +192,15 @@ func migrateDownTo(t *testing.T, tm *popx.MigrationBox, version string) {
 
 	for i, status := range statuses {
 		if status.Version == version {
-			require.NoError(t, tm.Down(context.Background(), len(statuses)-i))
+			// Down rolls back the given number of applied migrations, so
+			// pending migrations after the version must not be counted.
+			var steps int
+			for _, s := range statuses[i:] {
+				if s.State == popx.Applied {
+					steps++
+				}
+			}
+			require.NoError(t, tm.Down(context.Background(), steps))
 			return
 		}
 	}
@@ -0,0 +1,17 @@
+CREATE TABLE keto_relation_tuple_outbox
+(
+    id                       BIGINT       NOT NULL AUTO_INCREMENT,
+    nid                      CHAR(36)     NOT NULL,
+    action                   VARCHAR(16)  NOT NULL,
+    namespace                VARCHAR(200) NOT NULL,
+    object                   CHAR(36)     NOT NULL,
+    relation                 VARCHAR(64)  NOT NULL,
+    subject_id               CHAR(36) NULL,
+    subject_set_namespace    VARCHAR(200) NULL,
+    subject_set_object       CHAR(36) NULL,
+    subject_set_relation     VARCHAR(64) NULL,
+    created_at               TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT keto_relation_tuple_outbox_nid_fk FOREIGN KEY (nid) REFERENCES networks (id),
+    INDEX                    keto_relation_tuple_outbox_nid_idx (nid, id)
+);
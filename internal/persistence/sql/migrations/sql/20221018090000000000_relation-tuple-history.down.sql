@@ -0,0 +1 @@
+DROP TABLE keto_relation_tuple_history;
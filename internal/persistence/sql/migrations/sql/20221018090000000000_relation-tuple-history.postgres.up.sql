@@ -0,0 +1,18 @@
+CREATE TABLE keto_relation_tuple_history
+(
+    id                       BIGSERIAL    NOT NULL,
+    nid                      UUID         NOT NULL,
+    action                   VARCHAR(16)  NOT NULL,
+    namespace                VARCHAR(200) NOT NULL,
+    object                   UUID         NOT NULL,
+    relation                 VARCHAR(64)  NOT NULL,
+    subject_id               UUID NULL,
+    subject_set_namespace    VARCHAR(200) NULL,
+    subject_set_object       UUID NULL,
+    subject_set_relation     VARCHAR(64) NULL,
+    created_at               TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT keto_relation_tuple_history_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
+CREATE INDEX keto_relation_tuple_history_object_idx ON keto_relation_tuple_history (nid, namespace, object, id);
+CREATE INDEX keto_relation_tuple_history_subject_idx ON keto_relation_tuple_history (nid, subject_id, subject_set_namespace, subject_set_object, subject_set_relation, id);
@@ -0,0 +1,18 @@
+CREATE TABLE keto_relation_tuple_history
+(
+    id                       INT8         NOT NULL DEFAULT unique_rowid(),
+    nid                      UUID         NOT NULL,
+    action                   VARCHAR(16)  NOT NULL,
+    namespace                VARCHAR(200) NOT NULL,
+    object                   UUID         NOT NULL,
+    relation                 VARCHAR(64)  NOT NULL,
+    subject_id               UUID NULL,
+    subject_set_namespace    VARCHAR(200) NULL,
+    subject_set_object       UUID NULL,
+    subject_set_relation     VARCHAR(64) NULL,
+    created_at               TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT keto_relation_tuple_history_nid_fk FOREIGN KEY (nid) REFERENCES networks (id),
+    INDEX                    keto_relation_tuple_history_object_idx (nid, namespace, object, id),
+    INDEX                    keto_relation_tuple_history_subject_idx (nid, subject_id, subject_set_namespace, subject_set_object, subject_set_relation, id)
+);
@@ -0,0 +1,17 @@
+CREATE TABLE keto_relation_tuple_trash
+(
+    shard_id                 CHAR(36)     NOT NULL,
+    nid                      CHAR(36)     NOT NULL,
+    namespace                VARCHAR(200) NOT NULL,
+    object                   CHAR(36)     NOT NULL,
+    relation                 VARCHAR(64)  NOT NULL,
+    subject_id               CHAR(36) NULL,
+    subject_set_namespace    VARCHAR(200) NULL,
+    subject_set_object       CHAR(36) NULL,
+    subject_set_relation     VARCHAR(64) NULL,
+    commit_time              TIMESTAMP    NOT NULL,
+    deleted_at               TIMESTAMP    NOT NULL,
+    PRIMARY KEY (shard_id, nid),
+    CONSTRAINT keto_relation_tuple_trash_nid_fk FOREIGN KEY (nid) REFERENCES networks (id),
+    INDEX                    keto_relation_tuple_trash_deleted_at_idx (nid, namespace, deleted_at)
+);
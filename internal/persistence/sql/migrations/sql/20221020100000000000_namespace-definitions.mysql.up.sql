@@ -0,0 +1,10 @@
+CREATE TABLE keto_namespace_definitions
+(
+    nid        CHAR(36)     NOT NULL,
+    name       VARCHAR(200) NOT NULL,
+    opl        TEXT         NOT NULL,
+    created_at TIMESTAMP    NOT NULL,
+    updated_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (nid, name),
+    CONSTRAINT keto_namespace_definitions_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
@@ -0,0 +1 @@
+DROP TABLE keto_namespace_schema_versions;
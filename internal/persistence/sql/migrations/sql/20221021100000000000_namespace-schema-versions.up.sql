@@ -0,0 +1,11 @@
+CREATE TABLE keto_namespace_schema_versions
+(
+    nid         UUID         NOT NULL,
+    namespace   VARCHAR(200) NOT NULL,
+    version     INTEGER      NOT NULL,
+    description TEXT         NOT NULL,
+    steps       TEXT         NOT NULL,
+    applied_at  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (nid, namespace, version),
+    CONSTRAINT keto_namespace_schema_versions_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
@@ -0,0 +1 @@
+DROP TABLE keto_namespace_renames;
@@ -0,0 +1,17 @@
+CREATE TABLE keto_namespace_renames
+(
+    id                 CHAR(36)     NOT NULL,
+    nid                CHAR(36)     NOT NULL,
+    from_namespace     VARCHAR(200) NOT NULL,
+    to_namespace       VARCHAR(200) NOT NULL,
+    state              VARCHAR(20)  NOT NULL,
+    definition_renamed BOOLEAN      NOT NULL,
+    rewritten          INTEGER      NOT NULL,
+    batches_allowed_at TIMESTAMP    NOT NULL,
+    created_at         TIMESTAMP    NOT NULL,
+    updated_at         TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT keto_namespace_renames_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
+
+CREATE INDEX keto_namespace_renames_from_idx ON keto_namespace_renames (nid, from_namespace);
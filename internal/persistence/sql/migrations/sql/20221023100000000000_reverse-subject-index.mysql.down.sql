@@ -0,0 +1,2 @@
+DROP INDEX keto_relation_tuples_subject_ids_reverse_idx ON keto_relation_tuples;
+DROP INDEX keto_relation_tuples_subject_sets_reverse_idx ON keto_relation_tuples;
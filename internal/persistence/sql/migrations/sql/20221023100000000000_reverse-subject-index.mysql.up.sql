@@ -0,0 +1,17 @@
+-- mysql has no partial indexes, the NULL columns of the other subject type are
+-- part of the index instead
+CREATE INDEX keto_relation_tuples_subject_ids_reverse_idx ON keto_relation_tuples (nid,
+                                                                                   subject_id,
+                                                                                   namespace,
+                                                                                   relation,
+                                                                                   shard_id
+    );
+
+CREATE INDEX keto_relation_tuples_subject_sets_reverse_idx ON keto_relation_tuples (nid,
+                                                                                    subject_set_namespace,
+                                                                                    subject_set_object,
+                                                                                    subject_set_relation,
+                                                                                    namespace,
+                                                                                    relation,
+                                                                                    shard_id
+    );
@@ -0,0 +1,17 @@
+-- The indexes end with the shard ID, the pagination order, so that pages of
+-- the relation tuples of a subject are read from the index without sorting.
+CREATE INDEX keto_relation_tuples_subject_ids_reverse_idx ON keto_relation_tuples (nid,
+                                                                                   subject_id,
+                                                                                   namespace,
+                                                                                   relation,
+                                                                                   shard_id
+    ) WHERE subject_set_namespace IS NULL AND subject_set_object IS NULL AND subject_set_relation IS NULL;
+
+CREATE INDEX keto_relation_tuples_subject_sets_reverse_idx ON keto_relation_tuples (nid,
+                                                                                    subject_set_namespace,
+                                                                                    subject_set_object,
+                                                                                    subject_set_relation,
+                                                                                    namespace,
+                                                                                    relation,
+                                                                                    shard_id
+    ) WHERE subject_id IS NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE keto_relation_tuple_closure
+(
+    id                       CHAR(36)     NOT NULL,
+    nid                      CHAR(36)     NOT NULL,
+    namespace                VARCHAR(200) NOT NULL,
+    object                   CHAR(36)     NOT NULL,
+    relation                 VARCHAR(64)  NOT NULL,
+    subject_id               CHAR(36) NULL,
+    subject_set_namespace    VARCHAR(200) NULL,
+    subject_set_object       CHAR(36) NULL,
+    subject_set_relation     VARCHAR(64) NULL,
+    PRIMARY KEY (id, nid),
+    CONSTRAINT keto_relation_tuple_closure_nid_fk FOREIGN KEY (nid) REFERENCES networks (id),
+    INDEX                    keto_relation_tuple_closure_set_idx (nid, namespace, object, relation, subject_id)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE keto_relation_tuple_closure
+(
+    id                       UUID         NOT NULL,
+    nid                      UUID         NOT NULL,
+    namespace                VARCHAR(200) NOT NULL,
+    object                   UUID         NOT NULL,
+    relation                 VARCHAR(64)  NOT NULL,
+    subject_id               UUID NULL,
+    subject_set_namespace    VARCHAR(200) NULL,
+    subject_set_object       UUID NULL,
+    subject_set_relation     VARCHAR(64) NULL,
+    PRIMARY KEY (id, nid),
+    CONSTRAINT keto_relation_tuple_closure_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
+CREATE INDEX keto_relation_tuple_closure_set_idx ON keto_relation_tuple_closure (nid, namespace, object, relation, subject_id);
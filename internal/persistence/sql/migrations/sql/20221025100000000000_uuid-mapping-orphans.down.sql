@@ -0,0 +1 @@
+ALTER TABLE keto_uuid_mappings DROP COLUMN orphaned_at;
@@ -0,0 +1 @@
+ALTER TABLE keto_uuid_mappings ADD COLUMN orphaned_at TIMESTAMP NULL;
@@ -0,0 +1 @@
+DROP INDEX keto_uuid_mappings_orphaned_at_idx ON keto_uuid_mappings;
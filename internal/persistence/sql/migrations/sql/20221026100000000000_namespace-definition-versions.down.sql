@@ -0,0 +1 @@
+ALTER TABLE keto_namespace_definitions DROP COLUMN version;
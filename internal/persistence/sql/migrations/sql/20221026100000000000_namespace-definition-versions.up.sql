@@ -0,0 +1 @@
+ALTER TABLE keto_namespace_definitions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+CREATE TABLE keto_tenants
+(
+    id         UUID        NOT NULL,
+    nid        UUID        NOT NULL,
+    name       VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT keto_tenants_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
+
+CREATE INDEX keto_tenants_nid_idx ON keto_tenants (nid);
@@ -0,0 +1,8 @@
+CREATE TABLE keto_relation_tuple_outbox_leases
+(
+    nid        UUID        NOT NULL,
+    holder     VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP   NOT NULL,
+    PRIMARY KEY (nid),
+    CONSTRAINT keto_relation_tuple_outbox_leases_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
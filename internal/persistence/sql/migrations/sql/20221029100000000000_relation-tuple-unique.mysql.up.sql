@@ -0,0 +1,27 @@
+-- Duplicates of a relation tuple were possible before, only the oldest one is
+-- kept.
+DELETE
+FROM keto_relation_tuples
+WHERE shard_id IN (SELECT shard_id
+                   FROM (SELECT shard_id,
+                                ROW_NUMBER() OVER (PARTITION BY nid, namespace, object, relation, subject_id, subject_set_namespace, subject_set_object, subject_set_relation ORDER BY commit_time, shard_id) AS n
+                         FROM keto_relation_tuples) AS duplicates
+                   WHERE n > 1);
+
+-- mysql has no partial indexes, but NULL values never conflict, so the columns
+-- of the other subject type are part of the index instead
+CREATE UNIQUE INDEX keto_relation_tuples_subject_ids_unique_idx ON keto_relation_tuples (nid,
+                                                                                        namespace,
+                                                                                        object,
+                                                                                        relation,
+                                                                                        subject_id
+    );
+
+CREATE UNIQUE INDEX keto_relation_tuples_subject_sets_unique_idx ON keto_relation_tuples (nid,
+                                                                                         namespace,
+                                                                                         object,
+                                                                                         relation,
+                                                                                         subject_set_namespace,
+                                                                                         subject_set_object,
+                                                                                         subject_set_relation
+    );
@@ -0,0 +1,25 @@
+-- Duplicates of a relation tuple were possible before, only the oldest one is
+-- kept.
+DELETE
+FROM keto_relation_tuples
+WHERE shard_id IN (SELECT shard_id
+                   FROM (SELECT shard_id,
+                                ROW_NUMBER() OVER (PARTITION BY nid, namespace, object, relation, subject_id, subject_set_namespace, subject_set_object, subject_set_relation ORDER BY commit_time, shard_id) AS n
+                         FROM keto_relation_tuples) AS duplicates
+                   WHERE n > 1);
+
+CREATE UNIQUE INDEX keto_relation_tuples_subject_ids_unique_idx ON keto_relation_tuples (nid,
+                                                                                        namespace,
+                                                                                        object,
+                                                                                        relation,
+                                                                                        subject_id
+    ) WHERE subject_set_namespace IS NULL AND subject_set_object IS NULL AND subject_set_relation IS NULL;
+
+CREATE UNIQUE INDEX keto_relation_tuples_subject_sets_unique_idx ON keto_relation_tuples (nid,
+                                                                                         namespace,
+                                                                                         object,
+                                                                                         relation,
+                                                                                         subject_set_namespace,
+                                                                                         subject_set_object,
+                                                                                         subject_set_relation
+    ) WHERE subject_id IS NULL;
@@ -0,0 +1,12 @@
+-- A row is locked while the subjects of a relation with a limited number of
+-- subjects are counted, so that concurrent writes are counted one after the
+-- other.
+CREATE TABLE keto_relation_tuple_cardinality_locks
+(
+    nid       CHAR(36)     NOT NULL,
+    namespace VARCHAR(200) NOT NULL,
+    object    CHAR(36)     NOT NULL,
+    relation  VARCHAR(64)  NOT NULL,
+    PRIMARY KEY (nid, namespace, object, relation),
+    CONSTRAINT keto_relation_tuple_cardinality_locks_nid_fk FOREIGN KEY (nid) REFERENCES networks (id)
+);
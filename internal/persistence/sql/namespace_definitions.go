@@ -0,0 +1,162 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace/definition"
+)
+
+type (
+	namespaceDefinition struct {
+		NetworkID uuid.UUID `db:"nid"`
+		Name      string    `db:"name"`
+		OPL       string    `db:"opl"`
+		Version   int64     `db:"version"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	namespaceDefinitions []*namespaceDefinition
+)
+
+var _ definition.Manager = (*Persister)(nil)
+
+func (namespaceDefinitions) TableName() string {
+	return "keto_namespace_definitions"
+}
+
+func (namespaceDefinition) TableName() string {
+	return "keto_namespace_definitions"
+}
+
+func (d *namespaceDefinition) toDefinition() *definition.Definition {
+	return &definition.Definition{
+		Name:      d.Name,
+		OPL:       d.OPL,
+		Version:   d.Version,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+func notFound(name string) error {
+	return errors.WithStack(herodot.ErrNotFound.WithReasonf("The namespace %q does not exist.", name))
+}
+
+func (p *Persister) GetNamespaceDefinition(ctx context.Context, name string) (*definition.Definition, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetNamespaceDefinition")
+	defer span.End()
+
+	var res namespaceDefinitions
+	if err := p.QueryWithNetwork(ctx).Where("name = ?", name).All(&res); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if len(res) == 0 {
+		return nil, notFound(name)
+	}
+	return res[0].toDefinition(), nil
+}
+
+func (p *Persister) ListNamespaceDefinitions(ctx context.Context) ([]*definition.Definition, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListNamespaceDefinitions")
+	defer span.End()
+
+	var res namespaceDefinitions
+	if err := p.QueryWithNetwork(ctx).Order("name ASC").All(&res); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	dd := make([]*definition.Definition, len(res))
+	for i, d := range res {
+		dd[i] = d.toDefinition()
+	}
+	return dd, nil
+}
+
+func (p *Persister) CreateNamespaceDefinition(ctx context.Context, d *definition.Definition) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateNamespaceDefinition")
+	defer span.End()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := p.Connection(ctx).RawQuery(
+		"INSERT INTO keto_namespace_definitions (nid, name, opl, version, created_at, updated_at) VALUES (?, ?, ?, 1, ?, ?)",
+		p.NetworkID(ctx), d.Name, d.OPL, now, now,
+	).Exec(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+	d.Version, d.CreatedAt, d.UpdatedAt = 1, now, now
+	return nil
+}
+
+func (p *Persister) UpdateNamespaceDefinition(ctx context.Context, d *definition.Definition) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateNamespaceDefinition")
+	defer span.End()
+
+	return p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		existing, err := p.GetNamespaceDefinition(ctx, d.Name)
+		if err != nil {
+			return err
+		}
+		version := d.Version
+		if version == 0 {
+			version = existing.Version
+		}
+
+		// The version is compared again in the update, in case the
+		// definition changed after it was read.
+		now := time.Now().UTC().Truncate(time.Second)
+		n, err := c.RawQuery(
+			"UPDATE keto_namespace_definitions SET opl = ?, updated_at = ?, version = version + 1 WHERE nid = ? AND name = ? AND version = ?",
+			d.OPL, now, p.NetworkID(ctx), d.Name, version,
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if n == 0 {
+			return errors.WithStack(definition.ErrVersionMismatch)
+		}
+		d.Version, d.CreatedAt, d.UpdatedAt = version+1, existing.CreatedAt, now
+		return nil
+	})
+}
+
+func (p *Persister) DeleteNamespaceDefinition(ctx context.Context, name string, version int64) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteNamespaceDefinition")
+	defer span.End()
+
+	if version == 0 {
+		n, err := p.Connection(ctx).RawQuery(
+			"DELETE FROM keto_namespace_definitions WHERE nid = ? AND name = ?",
+			p.NetworkID(ctx), name,
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if n == 0 {
+			return notFound(name)
+		}
+		return nil
+	}
+
+	return p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		if _, err := p.GetNamespaceDefinition(ctx, name); err != nil {
+			return err
+		}
+		n, err := c.RawQuery(
+			"DELETE FROM keto_namespace_definitions WHERE nid = ? AND name = ? AND version = ?",
+			p.NetworkID(ctx), name, version,
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if n == 0 {
+			return errors.WithStack(definition.ErrVersionMismatch)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,387 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace/definition"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	namespaceRename struct {
+		ID                uuid.UUID `db:"id"`
+		NetworkID         uuid.UUID `db:"nid"`
+		From              string    `db:"from_namespace"`
+		To                string    `db:"to_namespace"`
+		State             string    `db:"state"`
+		DefinitionRenamed bool      `db:"definition_renamed"`
+		Rewritten         int       `db:"rewritten"`
+		BatchesAllowedAt  time.Time `db:"batches_allowed_at"`
+		CreatedAt         time.Time `db:"created_at"`
+		UpdatedAt         time.Time `db:"updated_at"`
+	}
+	namespaceRenames []*namespaceRename
+
+	// namespaceAliases maps the old names of namespaces that are being
+	// renamed to the new ones.
+	namespaceAliases map[string]string
+)
+
+var _ relationtuple.NamespaceRenameManager = (*Persister)(nil)
+
+func (namespaceRenames) TableName() string {
+	return "keto_namespace_renames"
+}
+
+func (namespaceRename) TableName() string {
+	return "keto_namespace_renames"
+}
+
+func (r *namespaceRename) toAPI() *ketoapi.NamespaceRename {
+	return &ketoapi.NamespaceRename{
+		From:              r.From,
+		To:                r.To,
+		State:             ketoapi.NamespaceRenameState(r.State),
+		DefinitionRenamed: r.DefinitionRenamed,
+		Rewritten:         r.Rewritten,
+		BatchesAllowedAt:  r.BatchesAllowedAt,
+		CreatedAt:         r.CreatedAt,
+		UpdatedAt:         r.UpdatedAt,
+	}
+}
+
+// activeNamespaceRenames returns the renames that are not completed.
+func (p *Persister) activeNamespaceRenames(ctx context.Context) (namespaceRenames, error) {
+	var res namespaceRenames
+	if err := p.QueryWithNetwork(ctx).
+		Where("state != ?", string(ketoapi.NamespaceRenameCompleted)).
+		Order("created_at ASC").
+		All(&res); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return res, nil
+}
+
+func (p *Persister) activeNamespaceRename(ctx context.Context, from string) (*namespaceRename, error) {
+	var res namespaceRenames
+	if err := p.QueryWithNetwork(ctx).
+		Where("from_namespace = ?", from).
+		Where("state != ?", string(ketoapi.NamespaceRenameCompleted)).
+		All(&res); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if len(res) == 0 {
+		return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("There is no active rename of namespace %q.", from))
+	}
+	return res[0], nil
+}
+
+// LoadNamespaceAliases returns the aliases of all active renames.
+func (p *Persister) LoadNamespaceAliases(ctx context.Context) (map[string]string, error) {
+	renames, err := p.activeNamespaceRenames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string, len(renames))
+	for _, r := range renames {
+		aliases[r.From] = r.To
+	}
+	return aliases, nil
+}
+
+// namespaceAliases returns the aliases that queries have to consider.
+func (p *Persister) namespaceAliases(ctx context.Context) (namespaceAliases, error) {
+	return p.d.Config(ctx).NamespaceAliases(ctx)
+}
+
+// names returns all names of the namespace: the name itself, and the other
+// name if the namespace is being renamed.
+func (a namespaceAliases) names(name string) []interface{} {
+	names := []interface{}{name}
+	for from, to := range a {
+		switch name {
+		case from:
+			names = append(names, to)
+		case to:
+			names = append(names, from)
+		}
+	}
+	return names
+}
+
+// resolve returns rt with the old names of namespaces that are being renamed
+// replaced by the new ones.
+func (a namespaceAliases) resolve(rt *relationtuple.RelationTuple) *relationtuple.RelationTuple {
+	for from, to := range a {
+		rt, _ = relationtuple.RenameNamespaceOf(rt, from, to)
+	}
+	return rt
+}
+
+func (p *Persister) StartNamespaceRename(ctx context.Context, from, to string) (*ketoapi.NamespaceRename, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.StartNamespaceRename")
+	defer span.End()
+
+	if err := relationtuple.ValidateNamespaceRename(from, to); err != nil {
+		return nil, err
+	}
+
+	var res *namespaceRename
+	err := p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		active, err := p.activeNamespaceRenames(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range active {
+			for _, name := range []string{r.From, r.To} {
+				if name == from || name == to {
+					return errors.WithStack(herodot.ErrConflict.WithReasonf(
+						"The namespace %q is already being renamed from %q to %q.", name, r.From, r.To))
+				}
+			}
+		}
+
+		definitionRenamed, err := p.renameNamespaceDefinition(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		if !definitionRenamed {
+			nm, err := p.d.Config(ctx).NamespaceManager()
+			if err != nil {
+				return err
+			}
+			if _, err := nm.GetNamespaceByName(ctx, to); errors.Is(err, herodot.ErrNotFound) {
+				return errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+					"The namespace %q is not declared. Declare it in the configuration before renaming %q.", to, from))
+			} else if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		res = &namespaceRename{
+			ID:                uuid.Must(uuid.NewV4()),
+			From:              from,
+			To:                to,
+			State:             string(ketoapi.NamespaceRenameRewriting),
+			DefinitionRenamed: definitionRenamed,
+			// All instances know the alias after their next refresh.
+			BatchesAllowedAt: now.Add(p.d.Config(ctx).NamespaceAPIRefreshInterval()),
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+		return sqlcon.HandleError(p.CreateWithNetwork(ctx, res))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.d.Config(ctx).ReloadNamespaceAliases()
+	if res.DefinitionRenamed {
+		p.d.Config(ctx).ReloadManagedNamespaces()
+	}
+	return res.toAPI(), nil
+}
+
+// renameNamespaceDefinition renames the managed definition of the namespace,
+// if there is one, and the references to it in all managed definitions. It
+// returns whether the namespace has a managed definition.
+func (p *Persister) renameNamespaceDefinition(ctx context.Context, from, to string) (bool, error) {
+	definitions, err := p.ListNamespaceDefinitions(ctx)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, d := range definitions {
+		switch d.Name {
+		case from:
+			found = true
+		case to:
+			return false, errors.WithStack(herodot.ErrConflict.WithReasonf("The namespace %q already exists.", to))
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	nm, err := p.d.Config(ctx).ConfiguredNamespaceManager()
+	if err != nil {
+		return false, err
+	}
+	configured, err := nm.Namespaces(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range configured {
+		if n.Name == to {
+			return false, errors.WithStack(herodot.ErrConflict.WithReasonf("The namespace %q already exists.", to))
+		}
+	}
+
+	renamed := make([]*definition.Definition, len(definitions))
+	for i, d := range definitions {
+		renamed[i] = &definition.Definition{Name: d.Name, OPL: schema.Analyze(d.OPL).RenameNamespace(from, to)}
+		if d.Name == from {
+			renamed[i].Name = to
+		}
+	}
+	if _, errs := definition.Parse(configured, renamed); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return false, errors.WithStack(herodot.ErrBadRequest.
+			WithReason("The namespace definitions would be invalid after the rename.").
+			WithDetail("errors", msgs))
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, d := range definitions {
+		if renamed[i].OPL == d.OPL && renamed[i].Name == d.Name {
+			continue
+		}
+		if err := p.Connection(ctx).RawQuery(
+			"UPDATE keto_namespace_definitions SET name = ?, opl = ?, updated_at = ?, version = version + 1 WHERE nid = ? AND name = ?",
+			renamed[i].Name, renamed[i].OPL, now, p.NetworkID(ctx), d.Name,
+		).Exec(); err != nil {
+			return false, sqlcon.HandleError(err)
+		}
+	}
+	return true, nil
+}
+
+// RenameNamespaceBatch rewrites the next relation tuples that reference the
+// old name. The rewritten relation tuples do not match the query anymore, so
+// that every relation tuple is rewritten at most once.
+func (p *Persister) RenameNamespaceBatch(ctx context.Context, from string, batchSize int) (*ketoapi.NamespaceRename, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RenameNamespaceBatch")
+	defer span.End()
+
+	var res *namespaceRename
+	err := p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		var err error
+		res, err = p.activeNamespaceRename(ctx, from)
+		if err != nil {
+			return err
+		}
+		if res.State != string(ketoapi.NamespaceRenameRewriting) {
+			return nil
+		}
+		if now := time.Now().UTC(); now.Before(res.BatchesAllowedAt) {
+			return errors.WithStack(herodot.ErrConflict.WithReasonf(
+				"Not all instances know that %q is an alias of %q yet. Retry after %s.", res.From, res.To, res.BatchesAllowedAt.Format(time.RFC3339)))
+		}
+
+		referencing := func() *pop.Query {
+			return p.QueryWithNetwork(ctx).Where("(namespace = ? OR subject_set_namespace = ?)", from, from)
+		}
+		var page relationTuples
+		if err := referencing().Order("shard_id").Limit(batchSize).All(&page); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		var ins []*relationtuple.RelationTuple
+		for _, row := range page {
+			rt, err := row.toInternal()
+			if err != nil {
+				return err
+			}
+			renamed, _ := relationtuple.RenameNamespaceOf(rt, res.From, res.To)
+
+			q := p.QueryWithNetwork(ctx).Where("shard_id = ?", row.ID)
+			if err := p.recordDeletes(ctx, q); err != nil {
+				return err
+			}
+			if err := q.Delete(&RelationTuple{}); err != nil {
+				return sqlcon.HandleError(err)
+			}
+
+			q, err = p.queryTuple(ctx, renamed)
+			if err != nil {
+				return err
+			}
+			if exists, err := q.Exists(&RelationTuple{}); err != nil {
+				return sqlcon.HandleError(err)
+			} else if !exists {
+				if err := p.InsertRelationTuple(ctx, renamed); err != nil {
+					return err
+				}
+				ins = append(ins, renamed)
+			}
+		}
+		if err := p.checkCardinality(ctx, ins...); err != nil {
+			return err
+		}
+		res.Rewritten += len(page)
+
+		if remaining, err := referencing().Exists(&RelationTuple{}); err != nil {
+			return sqlcon.HandleError(err)
+		} else if !remaining {
+			res.State = string(ketoapi.NamespaceRenameRewritten)
+		}
+		res.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		return sqlcon.HandleError(p.Connection(ctx).RawQuery(
+			"UPDATE keto_namespace_renames SET state = ?, rewritten = ?, updated_at = ? WHERE id = ? AND nid = ?",
+			res.State, res.Rewritten, res.UpdatedAt, res.ID, p.NetworkID(ctx),
+		).Exec())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.toAPI(), nil
+}
+
+func (p *Persister) FinishNamespaceRename(ctx context.Context, from string) (*ketoapi.NamespaceRename, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FinishNamespaceRename")
+	defer span.End()
+
+	var res *namespaceRename
+	err := p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		var err error
+		res, err = p.activeNamespaceRename(ctx, from)
+		if err != nil {
+			return err
+		}
+		if res.State != string(ketoapi.NamespaceRenameRewritten) {
+			return errors.WithStack(herodot.ErrConflict.WithReasonf(
+				"The relation tuples of namespace %q are not rewritten yet. Rewrite all batches before finishing the rename.", from))
+		}
+
+		res.State = string(ketoapi.NamespaceRenameCompleted)
+		res.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		return sqlcon.HandleError(p.Connection(ctx).RawQuery(
+			"UPDATE keto_namespace_renames SET state = ?, updated_at = ? WHERE id = ? AND nid = ?",
+			res.State, res.UpdatedAt, res.ID, p.NetworkID(ctx),
+		).Exec())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.d.Config(ctx).ReloadNamespaceAliases()
+	return res.toAPI(), nil
+}
+
+func (p *Persister) ListNamespaceRenames(ctx context.Context) ([]*ketoapi.NamespaceRename, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListNamespaceRenames")
+	defer span.End()
+
+	var res namespaceRenames
+	if err := p.QueryWithNetwork(ctx).Order("created_at ASC").All(&res); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	renames := make([]*ketoapi.NamespaceRename, len(res))
+	for i, r := range res {
+		renames[i] = r.toAPI()
+	}
+	return renames, nil
+}
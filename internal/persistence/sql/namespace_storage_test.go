@@ -0,0 +1,105 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestNamespaceStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := dbx.GetSqlite(t, dbx.SQLiteMemory)
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "docs"}, {Name: "telemetry"}}),
+		driver.WithConfig(config.KeyNamespaceStorage, []config.NamespaceStorage{{DSN: storage.Conn, Namespaces: []string{"telemetry"}}}),
+	)
+	relationtuple.MapAndWriteTuples(t, reg,
+		&ketoapi.RelationTuple{Namespace: "docs", Object: "d", Relation: "viewer", SubjectID: x.Ptr("s")},
+		&ketoapi.RelationTuple{Namespace: "docs", Object: "e", Relation: "viewer", SubjectID: x.Ptr("s")},
+	)
+	relationtuple.MapAndWriteTuples(t, reg,
+		&ketoapi.RelationTuple{Namespace: "telemetry", Object: "t", Relation: "reader", SubjectID: x.Ptr("s")},
+	)
+
+	// A registry on the storage database sees only the relation tuples of the
+	// mapped namespace.
+	storageReg := driver.NewTestRegistry(t, storage,
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "docs"}, {Name: "telemetry"}}))
+	manager := reg.RelationTupleManager()
+
+	get := func(t *testing.T, m relationtuple.Manager, query *relationtuple.RelationQuery, opts ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string) {
+		res, next, err := m.GetRelationTuples(ctx, query, opts...)
+		require.NoError(t, err)
+		return res, next
+	}
+
+	t.Run("case=namespaces are read from their database", func(t *testing.T) {
+		res, _ := get(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("telemetry")})
+		assert.Len(t, res, 1)
+		res, _ = get(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")})
+		assert.Len(t, res, 2)
+
+		res, _ = get(t, storageReg.RelationTupleManager(), &relationtuple.RelationQuery{})
+		require.Len(t, res, 1)
+		assert.Equal(t, "telemetry", res[0].Namespace)
+		res, _ = get(t, reg.Persister(), &relationtuple.RelationQuery{})
+		assert.Len(t, res, 2)
+	})
+
+	t.Run("case=queries without namespace are answered by all databases", func(t *testing.T) {
+		var (
+			all   []*relationtuple.RelationTuple
+			token string
+		)
+		for i := 0; ; i++ {
+			require.Less(t, i, 10)
+			res, next := get(t, manager, &relationtuple.RelationQuery{}, x.WithSize(1), x.WithToken(token))
+			all = append(all, res...)
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		assert.Len(t, all, 3)
+
+		n, _, err := manager.CountRelationTuples(ctx, &relationtuple.RelationQuery{}, false)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		_, _, err = manager.GetRelationTuples(ctx, &relationtuple.RelationQuery{}, x.WithToken("5:"))
+		assert.Error(t, err)
+	})
+
+	t.Run("case=changes across databases are rejected", func(t *testing.T) {
+		docs, _ := get(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")})
+		telemetry, _ := get(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("telemetry")})
+
+		err := manager.TransactRelationTuples(ctx, docs[:1], telemetry)
+		assert.ErrorIs(t, err, herodot.ErrBadRequest)
+
+		require.NoError(t, manager.DeleteRelationTuples(ctx, telemetry...))
+		res, _ := get(t, storageReg.RelationTupleManager(), &relationtuple.RelationQuery{})
+		assert.Len(t, res, 0)
+	})
+
+	t.Run("case=namespaces can only be mapped once", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaceStorage, []config.NamespaceStorage{
+			{DSN: storage.Conn, Namespaces: []string{"telemetry"}},
+			{DSN: storage.Conn, Namespaces: []string{"telemetry"}},
+		}))
+		_, err := reg.Config(ctx).NamespaceStorage()
+		assert.ErrorContains(t, err, `namespace "telemetry" is mapped to more than one database`)
+	})
+}
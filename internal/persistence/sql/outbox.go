@@ -0,0 +1,200 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/cdc"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	outboxEntry struct {
+		ID                  int64          `db:"id"`
+		NetworkID           uuid.UUID      `db:"nid"`
+		Action              string         `db:"action"`
+		Namespace           string         `db:"namespace"`
+		Object              uuid.UUID      `db:"object"`
+		Relation            string         `db:"relation"`
+		SubjectID           uuid.NullUUID  `db:"subject_id"`
+		SubjectSetNamespace sql.NullString `db:"subject_set_namespace"`
+		SubjectSetObject    uuid.NullUUID  `db:"subject_set_object"`
+		SubjectSetRelation  sql.NullString `db:"subject_set_relation"`
+		CreatedAt           time.Time      `db:"created_at"`
+	}
+	outboxEntries []*outboxEntry
+)
+
+var _ cdc.Outbox = (*Persister)(nil)
+
+func (outboxEntries) TableName() string {
+	return "keto_relation_tuple_outbox"
+}
+
+func (outboxEntry) TableName() string {
+	return "keto_relation_tuple_outbox"
+}
+
+// recordChange adds the change to the outbox if change data capture is
+// enabled, and to the history if the relation tuple history is enabled, and
+// updates the closures. It has to be called in the transaction that applies
+// the change, deletes before the relation tuples are deleted.
+func (p *Persister) recordChange(ctx context.Context, action ketoapi.PatchAction, rt *RelationTuple) error {
+	return p.recordChanges(ctx, action, rt)
+}
+
+// recordChanges is like recordChange for many relation tuples, which are
+// inserted in batches.
+func (p *Persister) recordChanges(ctx context.Context, action ketoapi.PatchAction, rts ...*RelationTuple) error {
+	if err := p.updateClosure(ctx, action, rts...); err != nil {
+		return err
+	}
+
+	cdcEnabled, historyEnabled := p.d.Config(ctx).CDCEnabled(), p.d.Config(ctx).HistoryEnabled()
+	if (!cdcEnabled && !historyEnabled) || len(rts) == 0 {
+		return nil
+	}
+
+	nid, now := p.NetworkID(ctx), time.Now().UTC()
+	rows := make([][]interface{}, len(rts))
+	for i, rt := range rts {
+		rows[i] = []interface{}{nid, string(action), rt.Namespace, rt.Object, rt.Relation, rt.SubjectID, rt.SubjectSetNamespace, rt.SubjectSetObject, rt.SubjectSetRelation, now}
+	}
+	if historyEnabled {
+		if err := p.bulkInsert(ctx, historyEntry{}.TableName(), changeColumns, rows); err != nil {
+			return err
+		}
+	}
+	if cdcEnabled {
+		if err := p.bulkInsert(ctx, outboxEntry{}.TableName(), changeColumns, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordDeletes records the deletion of all relation tuples matched by q. It
+// has to be called before the tuples are deleted.
+func (p *Persister) recordDeletes(ctx context.Context, q *pop.Query) error {
+	if !p.d.Config(ctx).CDCEnabled() && !p.d.Config(ctx).HistoryEnabled() {
+		if c, err := p.closureRelations(ctx); err != nil || len(c) == 0 {
+			return err
+		}
+	}
+
+	return eachPage(q, func(page relationTuples) error {
+		return p.recordChanges(ctx, ketoapi.ActionDelete, page...)
+	})
+}
+
+// eachPage calls f for every page of the relation tuples matched by q, so that
+// queries that match many relation tuples are not loaded into memory at once.
+// The pages are ordered by shard ID, the order of q is ignored.
+func eachPage(q *pop.Query, f func(page relationTuples) error) error {
+	lastID := uuid.Nil
+	for {
+		pq := pop.Q(q.Connection)
+		q.Clone(pq)
+		pq.Paginator = nil
+
+		var page relationTuples
+		if err := pq.Where("shard_id > ?", lastID).Order("shard_id").Limit(walkPageSize).All(&page); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := f(page); err != nil {
+			return err
+		}
+		if len(page) < walkPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+func (e *outboxEntry) toInternal() (*relationtuple.RelationTuple, error) {
+	return (&RelationTuple{
+		Namespace:           e.Namespace,
+		Object:              e.Object,
+		Relation:            e.Relation,
+		SubjectID:           e.SubjectID,
+		SubjectSetNamespace: e.SubjectSetNamespace,
+		SubjectSetObject:    e.SubjectSetObject,
+		SubjectSetRelation:  e.SubjectSetRelation,
+	}).toInternal()
+}
+
+func (p *Persister) GetRelationTupleChanges(ctx context.Context, limit int) ([]*cdc.Change, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetRelationTupleChanges")
+	defer span.End()
+
+	var res outboxEntries
+	if err := p.QueryWithNetwork(ctx).Order("id").Limit(limit).All(&res); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	changes := make([]*cdc.Change, len(res))
+	for i, e := range res {
+		rt, err := e.toInternal()
+		if err != nil {
+			return nil, err
+		}
+		changes[i] = &cdc.Change{
+			ID:     e.ID,
+			Action: ketoapi.PatchAction(e.Action),
+			Tuple:  rt,
+			Time:   e.CreatedAt,
+		}
+	}
+	return changes, nil
+}
+
+func (p *Persister) DeleteRelationTupleChanges(ctx context.Context, ids ...int64) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteRelationTupleChanges")
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("id IN (?)", args...).Delete(&outboxEntries{}))
+}
+
+func (p *Persister) LeaseOutbox(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.LeaseOutbox")
+	defer span.End()
+
+	now := time.Now().UTC()
+	err := sqlcon.HandleError(p.Connection(ctx).RawQuery(
+		"INSERT INTO keto_relation_tuple_outbox_leases (nid, holder, expires_at) VALUES (?, ?, ?)",
+		p.NetworkID(ctx), holder, now.Add(ttl),
+	).Exec())
+	if err == nil {
+		return true, nil
+	} else if !errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return false, err
+	}
+
+	// The lease exists already, so it is only taken over once it expired.
+	n, err := p.Connection(ctx).RawQuery(
+		"UPDATE keto_relation_tuple_outbox_leases SET holder = ?, expires_at = ? WHERE nid = ? AND (holder = ? OR expires_at < ?)",
+		holder, now.Add(ttl), p.NetworkID(ctx), holder, now,
+	).ExecWithCount()
+	if err != nil {
+		return false, sqlcon.HandleError(err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,62 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/persistence"
+)
+
+func TestPartitionStatements(t *testing.T) {
+	t.Parallel()
+
+	constraints := []tableConstraint{
+		{Name: "chk_keto_rt_uuid_subject_type", Type: "c", Definition: "CHECK (subject_id IS NOT NULL)"},
+		{Name: "keto_relation_tuples_uuid_nid_fk", Type: "f", Definition: "FOREIGN KEY (nid) REFERENCES networks(id)"},
+		{Name: "keto_relation_tuples_uuid_pkey", Type: "p", Definition: "PRIMARY KEY (shard_id, nid)"},
+	}
+	indexes := []string{"CREATE INDEX keto_relation_tuples_uuid_full_idx ON public.keto_relation_tuples USING btree (nid, namespace)"}
+
+	t.Run("case=by namespace", func(t *testing.T) {
+		stmts, err := partitionStatements(persistence.PartitionByNamespace, 2, constraints, indexes)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"CREATE TABLE keto_relation_tuples_partitioned (LIKE keto_relation_tuples INCLUDING DEFAULTS) PARTITION BY HASH (namespace)",
+			"CREATE TABLE keto_relation_tuples_p0 PARTITION OF keto_relation_tuples_partitioned FOR VALUES WITH (MODULUS 2, REMAINDER 0)",
+			"CREATE TABLE keto_relation_tuples_p1 PARTITION OF keto_relation_tuples_partitioned FOR VALUES WITH (MODULUS 2, REMAINDER 1)",
+			"INSERT INTO keto_relation_tuples_partitioned SELECT * FROM keto_relation_tuples",
+			"DROP TABLE keto_relation_tuples",
+			"ALTER TABLE keto_relation_tuples_partitioned RENAME TO keto_relation_tuples",
+			"ALTER TABLE keto_relation_tuples ADD CONSTRAINT keto_relation_tuples_uuid_pkey PRIMARY KEY (shard_id, nid, namespace)",
+			"ALTER TABLE keto_relation_tuples ADD CONSTRAINT chk_keto_rt_uuid_subject_type CHECK (subject_id IS NOT NULL)",
+			"ALTER TABLE keto_relation_tuples ADD CONSTRAINT keto_relation_tuples_uuid_nid_fk FOREIGN KEY (nid) REFERENCES networks(id)",
+			indexes[0],
+		}, stmts)
+	})
+
+	t.Run("case=by shard ID keeps the primary key", func(t *testing.T) {
+		stmts, err := partitionStatements(persistence.PartitionByShardID, 4, constraints, indexes)
+		require.NoError(t, err)
+		assert.Contains(t, stmts, "CREATE TABLE keto_relation_tuples_partitioned (LIKE keto_relation_tuples INCLUDING DEFAULTS) PARTITION BY HASH (shard_id)")
+		assert.Contains(t, stmts, "ALTER TABLE keto_relation_tuples ADD CONSTRAINT keto_relation_tuples_uuid_pkey PRIMARY KEY (shard_id, nid)")
+	})
+
+	t.Run("case=by shard ID is not possible with unique indexes", func(t *testing.T) {
+		unique := append(indexes, "CREATE UNIQUE INDEX keto_relation_tuples_subject_ids_unique_idx ON public.keto_relation_tuples USING btree (nid, namespace, object, relation, subject_id)")
+		_, err := partitionStatements(persistence.PartitionByShardID, 4, constraints, unique)
+		assert.ErrorContains(t, err, "keto_relation_tuples_subject_ids_unique_idx")
+
+		stmts, err := partitionStatements(persistence.PartitionByNamespace, 4, constraints, unique)
+		require.NoError(t, err)
+		assert.Contains(t, stmts, unique[1])
+	})
+
+	t.Run("case=invalid options", func(t *testing.T) {
+		_, err := partitionStatements("object", 4, constraints, indexes)
+		assert.ErrorContains(t, err, "unknown partition key")
+		_, err = partitionStatements(persistence.PartitionByNamespace, 1, constraints, indexes)
+		assert.ErrorContains(t, err, "number of partitions")
+	})
+}
@@ -0,0 +1,145 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/persistence"
+)
+
+type tableConstraint struct {
+	Name       string `db:"name"`
+	Type       string `db:"type"`
+	Definition string `db:"definition"`
+}
+
+const (
+	partitionedTable = "keto_relation_tuples_partitioned"
+	maxPartitions    = 1024
+)
+
+var _ persistence.Partitioner = (*Persister)(nil)
+
+// partitionStatements returns the statements that recreate the relation tuple
+// table as a hash partitioned table with the same constraints and indexes, and
+// copy all rows. The primary key has to contain the partition key, so it is
+// extended by the namespace if the table is partitioned by namespace.
+func partitionStatements(key persistence.PartitionKey, partitions int, constraints []tableConstraint, indexes []string) ([]string, error) {
+	switch key {
+	case persistence.PartitionByNamespace, persistence.PartitionByShardID:
+	default:
+		return nil, errors.Errorf("unknown partition key %q, expected %q or %q", key, persistence.PartitionByNamespace, persistence.PartitionByShardID)
+	}
+	if partitions < 2 || partitions > maxPartitions {
+		return nil, errors.Errorf("the number of partitions has to be between 2 and %d, got %d", maxPartitions, partitions)
+	}
+	// Unique indexes of a partitioned table have to contain the partition
+	// key, which the indexes that keep relation tuples unique only do for
+	// the namespace.
+	if key == persistence.PartitionByShardID {
+		for _, idx := range indexes {
+			if strings.HasPrefix(idx, "CREATE UNIQUE INDEX") {
+				return nil, errors.Errorf("the relation tuples can not be partitioned by %s, because the unique index %q does not contain it; partition by %s instead", key, strings.Fields(idx)[3], persistence.PartitionByNamespace)
+			}
+		}
+	}
+
+	stmts := []string{
+		fmt.Sprintf("CREATE TABLE %s (LIKE keto_relation_tuples INCLUDING DEFAULTS) PARTITION BY HASH (%s)", partitionedTable, key),
+	}
+	for i := 0; i < partitions; i++ {
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE keto_relation_tuples_p%d PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d)", i, partitionedTable, partitions, i))
+	}
+	stmts = append(stmts,
+		fmt.Sprintf("INSERT INTO %s SELECT * FROM keto_relation_tuples", partitionedTable),
+		"DROP TABLE keto_relation_tuples",
+		fmt.Sprintf("ALTER TABLE %s RENAME TO keto_relation_tuples", partitionedTable),
+	)
+
+	// The primary key comes first, the indexes that back other constraints
+	// are created by them.
+	for _, c := range constraints {
+		if c.Type != "p" {
+			continue
+		}
+		def := "PRIMARY KEY (shard_id, nid)"
+		if key == persistence.PartitionByNamespace {
+			def = "PRIMARY KEY (shard_id, nid, namespace)"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE keto_relation_tuples ADD CONSTRAINT %s %s", c.Name, def))
+	}
+	for _, c := range constraints {
+		if c.Type == "p" {
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE keto_relation_tuples ADD CONSTRAINT %s %s", c.Name, c.Definition))
+	}
+	return append(stmts, indexes...), nil
+}
+
+// PartitionRelationTuplesPlan returns the statements that convert the
+// relation tuple table into a hash partitioned table. Only PostgreSQL supports
+// declarative partitioning.
+func (p *Persister) PartitionRelationTuplesPlan(ctx context.Context, key persistence.PartitionKey, partitions int) ([]string, error) {
+	return p.partitionPlan(ctx, p.Connection(ctx), key, partitions)
+}
+
+func (p *Persister) partitionPlan(ctx context.Context, c *pop.Connection, key persistence.PartitionKey, partitions int) ([]string, error) {
+	if d := c.Dialect.Name(); d != "postgres" {
+		return nil, errors.Errorf("partitioning is only supported on PostgreSQL, but the database is %s", d)
+	}
+
+	var partitioned bool
+	if err := c.Store.GetContext(ctx, &partitioned,
+		"SELECT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'keto_relation_tuples'::regclass)",
+	); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if partitioned {
+		return nil, errors.New("the relation tuple table is already partitioned")
+	}
+
+	var constraints []tableConstraint
+	if err := c.Store.SelectContext(ctx, &constraints,
+		"SELECT conname AS name, contype AS type, pg_get_constraintdef(oid) AS definition FROM pg_constraint WHERE conrelid = 'keto_relation_tuples'::regclass ORDER BY conname",
+	); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	var indexes []string
+	if err := c.Store.SelectContext(ctx, &indexes,
+		`SELECT pg_get_indexdef(i.indexrelid) FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+WHERE i.indrelid = 'keto_relation_tuples'::regclass
+  AND NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conindid = i.indexrelid)
+ORDER BY c.relname`,
+	); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return partitionStatements(key, partitions, constraints, indexes)
+}
+
+// PartitionRelationTuples converts the relation tuple table into a hash
+// partitioned table in one transaction. All rows are copied, so writes are
+// blocked until it completes.
+func (p *Persister) PartitionRelationTuples(ctx context.Context, key persistence.PartitionKey, partitions int) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.PartitionRelationTuples")
+	defer span.End()
+
+	return p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		stmts, err := p.partitionPlan(ctx, c, key, partitions)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range stmts {
+			if err := c.RawQuery(stmt).Exec(); err != nil {
+				return errors.Wrapf(sqlcon.HandleError(err), "could not execute %q", strings.SplitN(stmt, "(", 2)[0])
+			}
+		}
+		return nil
+	})
+}
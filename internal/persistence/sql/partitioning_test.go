@@ -0,0 +1,67 @@
+package sql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/persistence/sql"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestPartitioning(t *testing.T) {
+	t.Parallel()
+
+	for _, dsn := range dbx.GetDSNs(t, false) {
+		dsn := dsn
+		t.Run(fmt.Sprintf("dsn=%s", dsn.Name), func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			reg := driver.NewTestRegistry(t, dsn)
+			require.NoError(t, reg.MigrateUp(ctx))
+			p, ok := reg.Persister().(*sql.Persister)
+			require.True(t, ok)
+
+			if dsn.Name != "postgres" {
+				_, err := p.PartitionRelationTuplesPlan(ctx, persistence.PartitionByNamespace, 4)
+				assert.ErrorContains(t, err, "only supported on PostgreSQL")
+				return
+			}
+
+			rs := make([]*relationtuple.RelationTuple, 20)
+			for i := range rs {
+				rs[i] = &relationtuple.RelationTuple{
+					Namespace: fmt.Sprintf("n%d", i%5),
+					Object:    uuid.Must(uuid.NewV4()),
+					Relation:  "r",
+					Subject:   &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())},
+				}
+			}
+			require.NoError(t, p.WriteRelationTuples(ctx, rs...))
+
+			plan, err := p.PartitionRelationTuplesPlan(ctx, persistence.PartitionByNamespace, 4)
+			require.NoError(t, err)
+			assert.Contains(t, plan, "CREATE TABLE keto_relation_tuples_p3 PARTITION OF keto_relation_tuples_partitioned FOR VALUES WITH (MODULUS 4, REMAINDER 3)")
+
+			require.NoError(t, p.PartitionRelationTuples(ctx, persistence.PartitionByNamespace, 4))
+
+			_, err = p.PartitionRelationTuplesPlan(ctx, persistence.PartitionByNamespace, 4)
+			assert.ErrorContains(t, err, "already partitioned")
+
+			res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{}, x.WithSize(100))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, rs, res)
+
+			relationtuple.ManagerTest(t, p)
+		})
+	}
+}
@@ -4,22 +4,41 @@ import (
 	"context"
 	"embed"
 	"reflect"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
 	"github.com/ory/x/popx"
 	"github.com/pkg/errors"
 
+	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
 	"github.com/ory/keto/internal/x"
 	"github.com/ory/keto/ketoctx"
 )
 
 type (
 	Persister struct {
-		conn *pop.Connection
-		d    dependencies
-		nid  uuid.UUID
+		// conns holds the *connections, which are replaced when the
+		// database configuration changes.
+		conns atomic.Value
+		next  uint32
+		d     dependencies
+		nid   uuid.UUID
+		cache *mappingCache
+		// closures holds the *versionedClosureRelations of the last
+		// namespace version.
+		closures atomic.Value
+	}
+	connections struct {
+		primary  *pop.Connection
+		replicas []*pop.Connection
+		// writer queues the transactions of SQLite, which only has one
+		// writer at a time.
+		writer chan struct{}
 	}
 	internalPagination struct {
 		PerPage int
@@ -29,8 +48,10 @@ type (
 		x.LoggerProvider
 		x.TracingProvider
 		ketoctx.ContextualizerProvider
+		config.Provider
 
 		PopConnection(ctx context.Context) (*pop.Connection, error)
+		ReadReplicaConnections(ctx context.Context) ([]*pop.Connection, error)
 	}
 )
 
@@ -51,17 +72,49 @@ func NewPersister(ctx context.Context, reg dependencies, nid uuid.UUID) (*Persis
 		return nil, err
 	}
 
+	replicas, err := reg.ReadReplicaConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	p := &Persister{
-		d:    reg,
-		nid:  nid,
-		conn: conn,
+		d:     reg,
+		nid:   nid,
+		cache: newMappingCache(reg.Config(ctx).UUIDMappingCacheSize()),
 	}
+	p.SetConnections(conn, replicas)
 
 	return p, nil
 }
 
+// SetConnections replaces the connections to the primary database and the
+// read replicas. Running transactions keep using the old connections.
+func (p *Persister) SetConnections(conn *pop.Connection, replicas []*pop.Connection) {
+	conns := &connections{primary: conn, replicas: replicas}
+	if conn.Dialect.Name() == "sqlite3" {
+		conns.writer = make(chan struct{}, 1)
+	}
+	p.conns.Store(conns)
+}
+
+func (p *Persister) connections() *connections {
+	return p.conns.Load().(*connections)
+}
+
 func (p *Persister) Connection(ctx context.Context) *pop.Connection {
-	return popx.GetConnection(ctx, p.conn.WithContext(ctx))
+	return popx.GetConnection(ctx, p.connections().primary.WithContext(ctx))
+}
+
+// readConnection returns the connection for reads that do not need to be
+// strongly consistent. These are served by the read replicas in turn, unless
+// they are part of a transaction.
+func (p *Persister) readConnection(ctx context.Context) *pop.Connection {
+	c, replicas := p.Connection(ctx), p.connections().replicas
+	if len(replicas) == 0 || c.TX != nil || persistence.ConsistencyFromContext(ctx) != persistence.ConsistencyEventual {
+		return c
+	}
+	i := atomic.AddUint32(&p.next, 1)
+	return replicas[int(i)%len(replicas)].WithContext(ctx)
 }
 
 func (p *Persister) CreateWithNetwork(ctx context.Context, v interface{}) error {
@@ -86,8 +139,41 @@ func (p *Persister) QueryWithNetwork(ctx context.Context) *pop.Query {
 	return p.Connection(ctx).Where("nid = ?", p.NetworkID(ctx))
 }
 
+// readQueryWithNetwork is like QueryWithNetwork, but uses the readConnection.
+func (p *Persister) readQueryWithNetwork(ctx context.Context) *pop.Query {
+	return p.readConnection(ctx).Where("nid = ?", p.NetworkID(ctx))
+}
+
+// Transaction runs f in a transaction, or in the transaction of the context.
+// On SQLite, transactions are queued in the order they begin, and fail with a
+// conflict if the database stays locked by another process for longer than the
+// busy timeout.
 func (p *Persister) Transaction(ctx context.Context, f func(ctx context.Context, c *pop.Connection) error) error {
-	return popx.Transaction(ctx, p.conn.WithContext(ctx), f)
+	conns := p.connections()
+	if conns.writer == nil || p.Connection(ctx).TX != nil {
+		return popx.Transaction(ctx, conns.primary.WithContext(ctx), f)
+	}
+
+	select {
+	case conns.writer <- struct{}{}:
+		defer func() { <-conns.writer }()
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+	err := popx.Transaction(ctx, conns.primary.WithContext(ctx), f)
+	if err != nil && strings.Contains(err.Error(), "database is locked") {
+		return errors.WithStack(herodot.ErrConflict.WithReason("The database is locked by another writer, please retry.").WithDebug(err.Error()))
+	}
+	return err
+}
+
+var _ relationtuple.Transactor = (*Persister)(nil)
+
+// InTransaction implements relationtuple.Transactor.
+func (p *Persister) InTransaction(ctx context.Context, f func(ctx context.Context) error) error {
+	return p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		return f(ctx)
+	})
 }
 
 func (p *Persister) NetworkID(ctx context.Context) uuid.UUID {
@@ -0,0 +1,72 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/ory/x/popx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestReadReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	// The replica is a separate, empty database, so that reads from it are
+	// distinguishable from reads from the primary database.
+	replica := dbx.GetSqlite(t, dbx.SQLiteMemory)
+	driver.NewTestRegistry(t, replica)
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "n"}}),
+		driver.WithConfig(config.KeyReadReplicaDSNs, []string{replica.Conn}),
+	)
+	relationtuple.MapAndWriteTuples(t, reg, &ketoapi.RelationTuple{Namespace: "n", Object: "o", Relation: "r", SubjectID: x.Ptr("s")})
+
+	query := &relationtuple.RelationQuery{Namespace: x.Ptr("n")}
+	read := func(t *testing.T, ctx context.Context) []*relationtuple.RelationTuple {
+		res, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, query)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("case=strong consistency reads from the primary", func(t *testing.T) {
+		assert.Len(t, read(t, ctx), 1)
+		assert.Len(t, read(t, persistence.WithConsistency(ctx, persistence.ConsistencyStrong)), 1)
+	})
+
+	t.Run("case=eventual consistency reads from the replicas", func(t *testing.T) {
+		assert.Len(t, read(t, persistence.WithConsistency(ctx, persistence.ConsistencyEventual)), 0)
+
+		n, _, err := reg.RelationTupleManager().CountRelationTuples(persistence.WithConsistency(ctx, persistence.ConsistencyEventual), query, false)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("case=transactions read from the primary", func(t *testing.T) {
+		ctx := persistence.WithConsistency(ctx, persistence.ConsistencyEventual)
+		require.NoError(t, popx.Transaction(ctx, reg.Persister().Connection(ctx), func(ctx context.Context, _ *pop.Connection) error {
+			assert.Len(t, read(t, ctx), 1)
+			return nil
+		}))
+	})
+}
+
+func TestParseConsistency(t *testing.T) {
+	c, err := persistence.ParseConsistency("strong")
+	require.NoError(t, err)
+	assert.Equal(t, persistence.ConsistencyStrong, c)
+
+	_, err = persistence.ParseConsistency("linearizable")
+	assert.Error(t, err)
+}
@@ -3,12 +3,14 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"sort"
 	"time"
 
 	"github.com/ory/keto/ketoapi"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
 	"github.com/ory/x/sqlcon"
 	"github.com/pkg/errors"
 
@@ -101,24 +103,19 @@ func (p *Persister) InsertRelationTuple(ctx context.Context, rel *relationtuple.
 	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.InsertRelationTuple")
 	defer span.End()
 
-	if rel.Subject == nil {
-		return errors.WithStack(ketoapi.ErrNilSubject)
-	}
-
-	rt := &RelationTuple{
-		ID:         uuid.Must(uuid.NewV4()),
-		CommitTime: time.Now(),
+	aliases, err := p.namespaceAliases(ctx)
+	if err != nil {
+		return err
 	}
-	if err := rt.FromInternal(ctx, p, rel); err != nil {
+	rt, err := p.newRelationTuple(ctx, aliases, rel)
+	if err != nil {
 		return err
 	}
 
-	if err := sqlcon.HandleError(
-		p.CreateWithNetwork(ctx, rt),
-	); err != nil {
+	return p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		_, err := p.insertRows(ctx, []*RelationTuple{rt})
 		return err
-	}
-	return nil
+	})
 }
 
 func (p *Persister) whereSubject(_ context.Context, q *pop.Query, sub relationtuple.Subject) error {
@@ -143,9 +140,24 @@ func (p *Persister) whereSubject(_ context.Context, q *pop.Query, sub relationtu
 	return nil
 }
 
+// whereNamespace matches the namespace in the column by all its names, as
+// namespaces that are being renamed have two.
+func whereNamespace(q *pop.Query, column string, aliases namespaceAliases, name string) {
+	if names := aliases.names(name); len(names) > 1 {
+		q.Where(column+" IN (?)", names...)
+		return
+	}
+	q.Where(column+" = ?", name)
+}
+
 func (p *Persister) whereQuery(ctx context.Context, q *pop.Query, rq *relationtuple.RelationQuery) error {
+	aliases, err := p.namespaceAliases(ctx)
+	if err != nil {
+		return err
+	}
+
 	if rq.Namespace != nil {
-		q.Where("namespace = ?", rq.Namespace)
+		whereNamespace(q, "namespace", aliases, *rq.Namespace)
 	}
 	if rq.Object != nil {
 		q.Where("object = ?", rq.Object)
@@ -153,7 +165,13 @@ func (p *Persister) whereQuery(ctx context.Context, q *pop.Query, rq *relationtu
 	if rq.Relation != nil {
 		q.Where("relation = ?", rq.Relation)
 	}
-	if s := rq.Subject; s != nil {
+	if ss, ok := rq.Subject.(*relationtuple.SubjectSet); ok && len(aliases.names(ss.Namespace)) > 1 {
+		whereNamespace(q, "subject_set_namespace", aliases, ss.Namespace)
+		q.
+			Where("subject_set_object = ?", ss.Object).
+			Where("subject_set_relation = ?", ss.Relation).
+			Where("subject_id IS NULL")
+	} else if s := rq.Subject; s != nil {
 		if err := p.whereSubject(ctx, q, s); err != nil {
 			return err
 		}
@@ -161,22 +179,50 @@ func (p *Persister) whereQuery(ctx context.Context, q *pop.Query, rq *relationtu
 	return nil
 }
 
+// queryTuple returns a query matching exactly the given relation tuple.
+func (p *Persister) queryTuple(ctx context.Context, r *relationtuple.RelationTuple) (*pop.Query, error) {
+	q := p.QueryWithNetwork(ctx).
+		Where("namespace = ?", r.Namespace).
+		Where("object = ?", r.Object).
+		Where("relation = ?", r.Relation)
+	if err := p.whereSubject(ctx, q, r.Subject); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
 func (p *Persister) DeleteRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
 	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteRelationTuples")
 	defer span.End()
 
+	aliases, err := p.namespaceAliases(ctx)
+	if err != nil {
+		return err
+	}
+
 	return p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
 		for _, r := range rs {
-			q := p.QueryWithNetwork(ctx).
-				Where("namespace = ?", r.Namespace).
-				Where("object = ?", r.Object).
-				Where("relation = ?", r.Relation)
-			if err := p.whereSubject(ctx, q, r.Subject); err != nil {
-				return err
+			// The relation tuple might already be rewritten to the new name of
+			// a namespace that is being renamed.
+			variants := []*relationtuple.RelationTuple{r}
+			if resolved := aliases.resolve(r); resolved != r {
+				variants = append(variants, resolved)
 			}
-
-			if err := q.Delete(&RelationTuple{}); err != nil {
-				return err
+			for _, v := range variants {
+				q, err := p.queryTuple(ctx, v)
+				if err != nil {
+					return err
+				}
+
+				if err := p.recordDeletes(ctx, q); err != nil {
+					return err
+				}
+				if err := p.trashDeletes(ctx, q); err != nil {
+					return err
+				}
+				if err := q.Delete(&RelationTuple{}); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -194,12 +240,80 @@ func (p *Persister) DeleteAllRelationTuples(ctx context.Context, query *relation
 		if err != nil {
 			return err
 		}
+		if err := p.recordDeletes(ctx, sqlQuery); err != nil {
+			return err
+		}
+		if err := p.trashDeletes(ctx, sqlQuery); err != nil {
+			return err
+		}
 
 		var res relationTuples
 		return sqlQuery.Delete(&res)
 	})
 }
 
+func (p *Persister) DeleteRelationTuplesPage(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteRelationTuplesPage")
+	defer span.End()
+
+	pagination, err := internalPaginationFromOptions(options...)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var (
+		deleted       int
+		nextPageToken string
+	)
+	err = p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		sqlQuery := p.QueryWithNetwork(ctx).
+			Order("shard_id").
+			Where("shard_id > ?", pagination.LastID).
+			Limit(pagination.PerPage)
+		if err := p.whereQuery(ctx, sqlQuery, query); err != nil {
+			return err
+		}
+		var res relationTuples
+		if err := sqlQuery.All(&res); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if len(res) == 0 {
+			deleted, nextPageToken = 0, ""
+			return nil
+		}
+
+		ids := make([]interface{}, len(res))
+		for i, rt := range res {
+			ids[i] = rt.ID
+		}
+		// The conditions of the query let partitioned tables skip the
+		// partitions that cannot contain the page.
+		page := p.QueryWithNetwork(ctx).Where("shard_id IN (?)", ids...)
+		if err := p.whereQuery(ctx, page, query); err != nil {
+			return err
+		}
+		if err := p.recordDeletes(ctx, page); err != nil {
+			return err
+		}
+		if err := p.trashDeletes(ctx, page); err != nil {
+			return err
+		}
+		if err := page.Delete(&RelationTuple{}); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		deleted, nextPageToken = len(res), ""
+		if len(res) == pagination.PerPage {
+			nextPageToken = pagination.encodeNextPageToken(res[len(res)-1].ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return deleted, nextPageToken, nil
+}
+
 func (p *Persister) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, options ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
 	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetRelationTuples")
 	defer span.End()
@@ -209,7 +323,7 @@ func (p *Persister) GetRelationTuples(ctx context.Context, query *relationtuple.
 		return nil, "", err
 	}
 
-	sqlQuery := p.QueryWithNetwork(ctx).
+	sqlQuery := p.readQueryWithNetwork(ctx).
 		Order("shard_id, nid").
 		Where("shard_id > ?", pagination.LastID).
 		Limit(pagination.PerPage + 1)
@@ -248,10 +362,164 @@ func (p *Persister) WriteRelationTuples(ctx context.Context, rs ...*relationtupl
 	defer span.End()
 
 	return p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		if err := p.insertRelationTuples(ctx, rs); err != nil {
+			return err
+		}
+		return p.checkCardinality(ctx, rs...)
+	})
+}
+
+// checkCardinality returns a conflict error if any of the objects of the
+// relation tuples has more subjects for the relation than the namespace
+// configuration allows. It has to be called after all changes of a transaction
+// were applied, so that e.g. replacing the only subject of a relation is
+// possible.
+func (p *Persister) checkCardinality(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	type key struct {
+		namespace, relation string
+		object              uuid.UUID
+	}
+
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return err
+	}
+	// The relations are locked in a fixed order, so that concurrent writes
+	// of several relations can not deadlock.
+	checked := make(map[key]bool, len(rs))
+	limited := make([]*relationtuple.RelationTuple, 0, len(rs))
+	for _, r := range rs {
+		k := key{r.Namespace, r.Relation, r.Object}
+		if checked[k] {
+			continue
+		}
+		checked[k] = true
+		limited = append(limited, r)
+	}
+	sort.Slice(limited, func(i, j int) bool {
+		a, b := limited[i], limited[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Object != b.Object {
+			return a.Object.String() < b.Object.String()
+		}
+		return a.Relation < b.Relation
+	})
+
+	for _, r := range limited {
+		max, err := relationtuple.MaxSubjects(ctx, nm, r.Namespace, r.Relation)
+		if err != nil {
+			return err
+		}
+		if max == 0 {
+			continue
+		}
+		count, err := p.countSubjects(ctx, r)
+		if err != nil {
+			return err
+		}
+		if count > max {
+			return errors.WithStack(herodot.ErrConflict.WithReasonf(
+				"Relation %q in namespace %q allows at most %d subject(s) per object, but the object would have %d.",
+				r.Relation, r.Namespace, max, count))
+		}
+	}
+	return nil
+}
+
+// countSubjects counts the subjects of the relation of the object of r. It
+// first locks the row of the relation in the cardinality lock table until the
+// transaction ends, so that concurrent writes to the relation count one after
+// the other, and each count includes the committed writes of the others.
+// SQLite only has one writer at a time, so nothing is locked there.
+func (p *Persister) countSubjects(ctx context.Context, r *relationtuple.RelationTuple) (int, error) {
+	c := p.Connection(ctx)
+	nid := p.NetworkID(ctx)
+
+	countSuffix := ""
+	switch c.Dialect.Name() {
+	case "sqlite3":
+	case "mysql":
+		if err := c.RawQuery(
+			"INSERT INTO keto_relation_tuple_cardinality_locks (nid, namespace, object, relation) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE nid = nid",
+			nid, r.Namespace, r.Object, r.Relation,
+		).Exec(); err != nil {
+			return 0, sqlcon.HandleError(err)
+		}
+		// Plain reads use the snapshot of the transaction, which can
+		// be older than the lock.
+		countSuffix = " LOCK IN SHARE MODE"
+	default:
+		if err := c.RawQuery(
+			"INSERT INTO keto_relation_tuple_cardinality_locks (nid, namespace, object, relation) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			nid, r.Namespace, r.Object, r.Relation,
+		).Exec(); err != nil {
+			return 0, sqlcon.HandleError(err)
+		}
+		if err := c.RawQuery(
+			"SELECT nid FROM keto_relation_tuple_cardinality_locks WHERE nid = ? AND namespace = ? AND object = ? AND relation = ? FOR UPDATE",
+			nid, r.Namespace, r.Object, r.Relation,
+		).Exec(); err != nil {
+			return 0, sqlcon.HandleError(err)
+		}
+	}
+
+	var count int
+	if err := c.Store.GetContext(ctx, &count, c.Dialect.TranslateSQL(
+		"SELECT COUNT(*) FROM keto_relation_tuples WHERE nid = ? AND namespace = ? AND object = ? AND relation = ?"+countSuffix),
+		nid, r.Namespace, r.Object, r.Relation,
+	); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) TouchRelationTuples(ctx context.Context, rs ...*relationtuple.RelationTuple) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.TouchRelationTuples")
+	defer span.End()
+
+	aliases, err := p.namespaceAliases(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
 		for _, r := range rs {
-			if err := p.InsertRelationTuple(ctx, r); err != nil {
+			rt, err := p.newRelationTuple(ctx, aliases, r)
+			if err != nil {
+				return err
+			}
+			inserted, err := p.insertRows(ctx, []*RelationTuple{rt})
+			if err != nil {
+				return err
+			}
+			if len(inserted) > 0 {
+				if err := p.checkCardinality(ctx, r); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// The relation tuple exists already, so only its commit time is
+			// updated.
+			q, err := p.queryTuple(ctx, r)
+			if err != nil {
 				return err
 			}
+			var existing relationTuples
+			if err := q.All(&existing); err != nil {
+				return sqlcon.HandleError(err)
+			}
+			now := time.Now()
+			for _, e := range existing {
+				if err := c.RawQuery(
+					"UPDATE keto_relation_tuples SET commit_time = ? WHERE shard_id = ? AND nid = ? AND namespace = ?",
+					now, e.ID, e.NetworkID, e.Namespace,
+				).Exec(); err != nil {
+					return sqlcon.HandleError(err)
+				}
+			}
 		}
 		return nil
 	})
@@ -262,9 +530,12 @@ func (p *Persister) TransactRelationTuples(ctx context.Context, ins []*relationt
 	defer span.End()
 
 	return p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
-		if err := p.WriteRelationTuples(ctx, ins...); err != nil {
+		if err := p.insertRelationTuples(ctx, ins); err != nil {
+			return err
+		}
+		if err := p.DeleteRelationTuples(ctx, del...); err != nil {
 			return err
 		}
-		return p.DeleteRelationTuples(ctx, del...)
+		return p.checkCardinality(ctx, ins...)
 	})
 }
@@ -14,8 +14,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/persistence/sql"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
 	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
 )
 
 func rt(nw *networkx.Network, setSID, setNID, setO, setR bool) *sql.RelationTuple {
@@ -108,3 +112,100 @@ func TestRelationTupleSubjectTypeCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestBulkInsert(t *testing.T) {
+	t.Parallel()
+
+	for _, dsn := range dbx.GetDSNs(t, false) {
+		dsn := dsn
+		t.Run("dsn="+dsn.Name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			reg := driver.NewTestRegistry(t, dsn,
+				driver.WithConfig(config.KeyHistoryEnabled, true),
+				driver.WithConfig(config.KeyCDCEnabled, true),
+			)
+			p := reg.Persister()
+
+			// More relation tuples than fit into one statement.
+			rs := make([]*relationtuple.RelationTuple, 7000)
+			for i := range rs {
+				rs[i] = &relationtuple.RelationTuple{
+					Namespace: "n",
+					Object:    uuid.Must(uuid.NewV4()),
+					Relation:  "r",
+					Subject:   &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())},
+				}
+			}
+			rs[1].Subject = &relationtuple.SubjectSet{Namespace: "n", Object: rs[0].Object, Relation: "r"}
+			require.NoError(t, p.TransactRelationTuples(ctx, rs, nil))
+
+			n, _, err := p.CountRelationTuples(ctx, &relationtuple.RelationQuery{}, false)
+			require.NoError(t, err)
+			assert.Equal(t, len(rs), n)
+
+			res, _, err := p.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &rs[1].Namespace, Object: &rs[1].Object})
+			require.NoError(t, err)
+			assert.Equal(t, []*relationtuple.RelationTuple{rs[1]}, res)
+
+			history, _, err := p.GetRelationTupleHistory(ctx, &relationtuple.HistoryQuery{}, x.WithSize(1))
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+			assert.Equal(t, ketoapi.ActionInsert, history[0].Action)
+
+			changes, err := p.GetRelationTupleChanges(ctx, len(rs)+1)
+			require.NoError(t, err)
+			assert.Len(t, changes, len(rs))
+
+			// Failed transactions do not leave any of the relation tuples.
+			rs[len(rs)-1].Subject = nil
+			assert.ErrorIs(t, p.WriteRelationTuples(ctx, rs...), ketoapi.ErrNilSubject)
+			n, _, err = p.CountRelationTuples(ctx, &relationtuple.RelationQuery{}, false)
+			require.NoError(t, err)
+			assert.Equal(t, len(rs), n)
+		})
+	}
+}
+
+func TestReverseSubjectIndex(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory))
+	conn, err := reg.PopConnection(ctx)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name, where, index string
+		args               []interface{}
+	}{
+		{
+			name:  "subject id",
+			where: "subject_id = ? AND subject_set_namespace IS NULL AND subject_set_object IS NULL AND subject_set_relation IS NULL",
+			index: "keto_relation_tuples_subject_ids_reverse_idx",
+			args:  []interface{}{uuid.Must(uuid.NewV4())},
+		},
+		{
+			name:  "subject set",
+			where: "subject_set_namespace = ? AND subject_set_object = ? AND subject_set_relation = ? AND subject_id IS NULL",
+			index: "keto_relation_tuples_subject_sets_reverse_idx",
+			args:  []interface{}{"n", uuid.Must(uuid.NewV4()), "r"},
+		},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			// A page of the objects a subject is related to, it has to be read
+			// from the index without sorting.
+			args := append([]interface{}{uuid.Must(uuid.NewV4()), "n", "r"}, tc.args...)
+			args = append(args, uuid.Nil)
+
+			var plan []struct {
+				ID      int    `db:"id"`
+				Parent  int    `db:"parent"`
+				NotUsed int    `db:"notused"`
+				Detail  string `db:"detail"`
+			}
+			require.NoError(t, conn.RawQuery("EXPLAIN QUERY PLAN SELECT * FROM keto_relation_tuples WHERE nid = ? AND namespace = ? AND relation = ? AND "+tc.where+" AND shard_id > ? ORDER BY shard_id, nid LIMIT 101", args...).All(&plan))
+			require.Len(t, plan, 1)
+			assert.Contains(t, plan[0].Detail, "SEARCH keto_relation_tuples USING INDEX "+tc.index)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	schemaVersion struct {
+		NetworkID   uuid.UUID `db:"nid"`
+		Namespace   string    `db:"namespace"`
+		Version     int       `db:"version"`
+		Description string    `db:"description"`
+		Steps       string    `db:"steps"`
+		AppliedAt   time.Time `db:"applied_at"`
+	}
+	schemaVersions []*schemaVersion
+)
+
+var (
+	_ relationtuple.SchemaMigrationManager = (*Persister)(nil)
+
+	// errDryRun rolls back the transaction of a planned migration.
+	errDryRun = errors.New("dry run")
+)
+
+func (schemaVersions) TableName() string {
+	return "keto_namespace_schema_versions"
+}
+
+func (schemaVersion) TableName() string {
+	return "keto_namespace_schema_versions"
+}
+
+func (v *schemaVersion) toAPI() (*ketoapi.SchemaVersion, error) {
+	res := &ketoapi.SchemaVersion{
+		Namespace:   v.Namespace,
+		Version:     v.Version,
+		Description: v.Description,
+		AppliedAt:   v.AppliedAt,
+	}
+	if err := json.Unmarshal([]byte(v.Steps), &res.Steps); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+func (p *Persister) ListSchemaVersions(ctx context.Context, namespace string) (int, []*ketoapi.SchemaVersion, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListSchemaVersions")
+	defer span.End()
+
+	var res schemaVersions
+	if err := p.QueryWithNetwork(ctx).Where("namespace = ?", namespace).Order("version ASC").All(&res); err != nil {
+		return 0, nil, sqlcon.HandleError(err)
+	}
+
+	versions := make([]*ketoapi.SchemaVersion, len(res))
+	for i, v := range res {
+		var err error
+		if versions[i], err = v.toAPI(); err != nil {
+			return 0, nil, err
+		}
+	}
+	current := 0
+	if len(res) > 0 {
+		current = res[len(res)-1].Version
+	}
+	return current, versions, nil
+}
+
+func (p *Persister) currentSchemaVersion(ctx context.Context, namespace string) (int, error) {
+	var res schemaVersions
+	if err := p.QueryWithNetwork(ctx).Where("namespace = ?", namespace).Order("version DESC").Limit(1).All(&res); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	if len(res) == 0 {
+		return 0, nil
+	}
+	return res[0].Version, nil
+}
+
+func (p *Persister) MigrateSchema(ctx context.Context, m *ketoapi.SchemaMigration, dryRun bool) (*ketoapi.SchemaMigrationPlan, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.MigrateSchema")
+	defer span.End()
+
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := relationtuple.ValidateSchemaMigration(ctx, nm, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *ketoapi.SchemaMigrationPlan
+	err = p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		current, err := p.currentSchemaVersion(ctx, m.Namespace)
+		if err != nil {
+			return err
+		}
+		if current != m.FromVersion {
+			return errors.WithStack(herodot.ErrConflict.WithReasonf(
+				"The namespace %q is at schema version %d, but the migration applies to version %d.", m.Namespace, current, m.FromVersion))
+		}
+
+		plan = &ketoapi.SchemaMigrationPlan{
+			Namespace:   m.Namespace,
+			FromVersion: current,
+			ToVersion:   current + 1,
+			Steps:       make([]*ketoapi.SchemaMigrationStepPlan, len(m.Steps)),
+			Warnings:    warnings,
+			Applied:     !dryRun,
+		}
+		for i, step := range m.Steps {
+			if plan.Steps[i], err = p.migrateSchemaStep(ctx, m.Namespace, step); err != nil {
+				return err
+			}
+		}
+
+		steps, err := json.Marshal(m.Steps)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := p.Connection(ctx).RawQuery(
+			"INSERT INTO keto_namespace_schema_versions (nid, namespace, version, description, steps, applied_at) VALUES (?, ?, ?, ?, ?, ?)",
+			p.NetworkID(ctx), m.Namespace, plan.ToVersion, m.Description, string(steps), time.Now().UTC().Truncate(time.Second),
+		).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// migrateSchemaStep rewrites all relation tuples that the step applies to,
+// page by page. The rewritten relation tuples do not match the query anymore,
+// so that every relation tuple is rewritten at most once.
+func (p *Persister) migrateSchemaStep(ctx context.Context, namespace string, step *ketoapi.SchemaMigrationStep) (*ketoapi.SchemaMigrationStepPlan, error) {
+	from := relationtuple.SchemaMigrationStepSource(step)
+	res := &ketoapi.SchemaMigrationStepPlan{Description: relationtuple.DescribeSchemaMigrationStep(namespace, step)}
+
+	lastID := uuid.Nil
+	for {
+		var page relationTuples
+		if err := p.QueryWithNetwork(ctx).
+			Where("shard_id > ?", lastID).
+			Where("((namespace = ? AND relation = ?) OR (subject_set_namespace = ? AND subject_set_relation = ?))", namespace, from, namespace, from).
+			Order("shard_id").
+			Limit(defaultPageSize).
+			All(&page); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+		if len(page) == 0 {
+			return res, nil
+		}
+		lastID = page[len(page)-1].ID
+
+		var ins []*relationtuple.RelationTuple
+		for _, row := range page {
+			rt, err := row.toInternal()
+			if err != nil {
+				return nil, err
+			}
+			rewritten, ok := relationtuple.RewriteForSchemaMigration(namespace, step, rt)
+			if !ok {
+				res.Unmatched++
+				continue
+			}
+			res.Rewritten++
+
+			q := p.QueryWithNetwork(ctx).Where("shard_id = ?", row.ID)
+			if err := p.recordDeletes(ctx, q); err != nil {
+				return nil, err
+			}
+			if err := q.Delete(&RelationTuple{}); err != nil {
+				return nil, sqlcon.HandleError(err)
+			}
+			for _, r := range rewritten {
+				q, err := p.queryTuple(ctx, r)
+				if err != nil {
+					return nil, err
+				}
+				if exists, err := q.Exists(&RelationTuple{}); err != nil {
+					return nil, sqlcon.HandleError(err)
+				} else if exists {
+					continue
+				}
+				if err := p.InsertRelationTuple(ctx, r); err != nil {
+					return nil, err
+				}
+				ins = append(ins, r)
+			}
+		}
+		if err := p.checkCardinality(ctx, ins...); err != nil {
+			return nil, err
+		}
+	}
+}
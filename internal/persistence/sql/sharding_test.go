@@ -0,0 +1,130 @@
+package sql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/persistence"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestSharding(t *testing.T) {
+	ctx := context.Background()
+	namespaces := []*namespace.Namespace{{Name: "docs"}, {Name: "telemetry"}}
+	shards := []*dbx.DsnT{dbx.GetSqlite(t, dbx.SQLiteMemory), dbx.GetSqlite(t, dbx.SQLiteMemory), dbx.GetSqlite(t, dbx.SQLiteMemory)}
+	storage := dbx.GetSqlite(t, dbx.SQLiteMemory)
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithNamespaces(namespaces),
+		driver.WithConfig(config.KeyShardingDSNs, []string{shards[0].Conn, shards[1].Conn, shards[2].Conn}),
+		driver.WithConfig(config.KeyNamespaceStorage, []config.NamespaceStorage{{DSN: storage.Conn, Namespaces: []string{"telemetry"}}}),
+	)
+	tuples := make([]*ketoapi.RelationTuple, 30)
+	for i := range tuples {
+		tuples[i] = &ketoapi.RelationTuple{Namespace: "docs", Object: fmt.Sprintf("d%d", i), Relation: "viewer", SubjectID: x.Ptr("s")}
+	}
+	relationtuple.MapAndWriteTuples(t, reg, tuples...)
+	relationtuple.MapAndWriteTuples(t, reg,
+		&ketoapi.RelationTuple{Namespace: "telemetry", Object: "t", Relation: "reader", SubjectID: x.Ptr("s")},
+	)
+	manager := reg.RelationTupleManager()
+
+	count := func(t *testing.T, m relationtuple.Manager, query *relationtuple.RelationQuery) int {
+		n, _, err := m.CountRelationTuples(ctx, query, false)
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("case=relation tuples are distributed over the shards", func(t *testing.T) {
+		var total int
+		for _, s := range shards {
+			n := count(t, driver.NewTestRegistry(t, s, driver.WithNamespaces(namespaces)).RelationTupleManager(), &relationtuple.RelationQuery{})
+			assert.NotZero(t, n)
+			total += n
+		}
+		assert.Equal(t, len(tuples), total)
+
+		assert.Zero(t, count(t, reg.Persister(), &relationtuple.RelationQuery{}), "the primary database stores no relation tuples")
+		assert.Equal(t, 1, count(t, driver.NewTestRegistry(t, storage, driver.WithNamespaces(namespaces)).RelationTupleManager(), &relationtuple.RelationQuery{}))
+		assert.Equal(t, len(tuples)+1, count(t, manager, &relationtuple.RelationQuery{}))
+	})
+
+	t.Run("case=objects are read from their shard", func(t *testing.T) {
+		ids, err := reg.MappingManager().MapStringsToUUIDs(ctx, "d7")
+		require.NoError(t, err)
+		res, _, err := manager.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("docs"), Object: &ids[0]})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, ids[0], res[0].Object)
+	})
+
+	t.Run("case=list pages are gathered from all shards", func(t *testing.T) {
+		var (
+			token string
+			seen  = make(map[string]bool)
+		)
+		for i := 0; ; i++ {
+			require.Less(t, i, 50)
+			res, next, err := manager.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}, x.WithSize(4), x.WithToken(token))
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(res), 4)
+			for _, r := range res {
+				assert.False(t, seen[r.String()], "duplicate %s", r)
+				seen[r.String()] = true
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		assert.Len(t, seen, len(tuples))
+
+		_, _, err := manager.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}, x.WithToken("not a token"))
+		assert.ErrorIs(t, err, persistence.ErrMalformedPageToken)
+	})
+
+	t.Run("case=transactions are limited to one shard", func(t *testing.T) {
+		res, _, err := manager.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}, x.WithSize(10))
+		require.NoError(t, err)
+		assert.ErrorIs(t, manager.TransactRelationTuples(ctx, nil, res), herodot.ErrBadRequest)
+		assert.Equal(t, len(tuples), count(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}), "no shard was changed")
+
+		require.NoError(t, manager.TransactRelationTuples(ctx, nil, res[:1]))
+		assert.Equal(t, len(tuples)-1, count(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}))
+	})
+
+	t.Run("case=deletes span all shards", func(t *testing.T) {
+		res, _, err := manager.GetRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}, x.WithSize(10))
+		require.NoError(t, err)
+		require.NoError(t, manager.DeleteRelationTuples(ctx, res...))
+		assert.Equal(t, len(tuples)-1-len(res), count(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}))
+
+		require.NoError(t, manager.DeleteAllRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}))
+		assert.Zero(t, count(t, manager, &relationtuple.RelationQuery{Namespace: x.Ptr("docs")}))
+		assert.Equal(t, 1, count(t, manager, &relationtuple.RelationQuery{}))
+	})
+}
+
+func TestShardRouter(t *testing.T) {
+	var managers []relationtuple.Manager
+	for i := 0; i < 3; i++ {
+		managers = append(managers, driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory)).RelationTupleManager())
+	}
+
+	for _, key := range []persistence.ShardKey{persistence.ShardByObject, persistence.ShardByNamespace} {
+		t.Run("key="+string(key), func(t *testing.T) {
+			relationtuple.ManagerTest(t, persistence.NewShardRouter(key, managers...))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+)
+
+const walkPageSize = 1000
+
+func (p *Persister) WalkRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, f func(ctx context.Context, page []*relationtuple.RelationTuple) error) error {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.WalkRelationTuples")
+	defer span.End()
+
+	return p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		// MySQL defaults to repeatable read, while CockroachDB and SQLite are
+		// always serializable. Only PostgreSQL has to be told explicitly to
+		// read all pages from the same snapshot.
+		if c.Dialect.Name() == "postgres" {
+			if err := c.RawQuery("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY").Exec(); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+
+		var nextPage string
+		for {
+			page, next, err := p.GetRelationTuples(ctx, query, x.WithToken(nextPage), x.WithSize(walkPageSize))
+			if err != nil {
+				return err
+			}
+			if len(page) > 0 {
+				if err := f(ctx, page); err != nil {
+					return err
+				}
+			}
+			if next == "" {
+				return nil
+			}
+			nextPage = next
+		}
+	})
+}
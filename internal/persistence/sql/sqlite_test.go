@@ -0,0 +1,45 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+)
+
+func TestSQLiteFile(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteFile),
+		driver.WithNamespaces([]*namespace.Namespace{{Name: "n"}}))
+
+	t.Run("case=the database is in WAL mode", func(t *testing.T) {
+		var mode string
+		require.NoError(t, reg.Persister().Connection(ctx).Store.Get(&mode, "PRAGMA journal_mode"))
+		assert.Equal(t, "wal", mode)
+	})
+
+	t.Run("case=concurrent transactions do not fail", func(t *testing.T) {
+		obj := uuid.Must(uuid.NewV4())
+		var eg errgroup.Group
+		for i := 0; i < 50; i++ {
+			eg.Go(func() error {
+				rt := &relationtuple.RelationTuple{Namespace: "n", Object: obj, Relation: "r", Subject: &relationtuple.SubjectID{ID: uuid.Must(uuid.NewV4())}}
+				return reg.RelationTupleManager().TransactRelationTuples(ctx, []*relationtuple.RelationTuple{rt}, nil)
+			})
+		}
+		require.NoError(t, eg.Wait())
+
+		n, _, err := reg.RelationTupleManager().CountRelationTuples(ctx, &relationtuple.RelationQuery{Object: x.Ptr(obj)}, false)
+		require.NoError(t, err)
+		assert.Equal(t, 50, n)
+	})
+}
@@ -0,0 +1,263 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+type (
+	// trashedTuple is a soft deleted relation tuple. It is kept until the
+	// retention window of its namespace has passed and can be restored until
+	// then.
+	trashedTuple struct {
+		ID                  uuid.UUID      `db:"shard_id"`
+		NetworkID           uuid.UUID      `db:"nid"`
+		Namespace           string         `db:"namespace"`
+		Object              uuid.UUID      `db:"object"`
+		Relation            string         `db:"relation"`
+		SubjectID           uuid.NullUUID  `db:"subject_id"`
+		SubjectSetNamespace sql.NullString `db:"subject_set_namespace"`
+		SubjectSetObject    uuid.NullUUID  `db:"subject_set_object"`
+		SubjectSetRelation  sql.NullString `db:"subject_set_relation"`
+		CommitTime          time.Time      `db:"commit_time"`
+		DeletedAt           time.Time      `db:"deleted_at"`
+	}
+	trashedTuples []*trashedTuple
+)
+
+var _ relationtuple.SoftDeleteManager = (*Persister)(nil)
+
+func (trashedTuples) TableName() string {
+	return "keto_relation_tuple_trash"
+}
+
+func (trashedTuple) TableName() string {
+	return "keto_relation_tuple_trash"
+}
+
+func (t *trashedTuple) toRelationTuple() *RelationTuple {
+	return &RelationTuple{
+		ID:                  t.ID,
+		NetworkID:           t.NetworkID,
+		Namespace:           t.Namespace,
+		Object:              t.Object,
+		Relation:            t.Relation,
+		SubjectID:           t.SubjectID,
+		SubjectSetNamespace: t.SubjectSetNamespace,
+		SubjectSetObject:    t.SubjectSetObject,
+		SubjectSetRelation:  t.SubjectSetRelation,
+		CommitTime:          t.CommitTime,
+	}
+}
+
+// softDeleteRetentions returns the retention window of every namespace that
+// has soft deletes enabled.
+func (p *Persister) softDeleteRetentions(ctx context.Context) (map[string]time.Duration, error) {
+	nm, err := p.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+	nn, err := nm.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	retentions := make(map[string]time.Duration)
+	for _, n := range nn {
+		retention, enabled, err := n.SoftDeleteRetention()
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			retentions[n.Name] = retention
+		}
+	}
+	return retentions, nil
+}
+
+// trashDeletes moves all relation tuples matched by q that belong to a
+// namespace with soft deletes enabled to the trash. It has to be called before
+// the tuples are deleted.
+func (p *Persister) trashDeletes(ctx context.Context, q *pop.Query) error {
+	retentions, err := p.softDeleteRetentions(ctx)
+	if err != nil || len(retentions) == 0 {
+		return err
+	}
+
+	names := make([]interface{}, 0, len(retentions))
+	for n := range retentions {
+		names = append(names, n)
+	}
+	tq := pop.Q(q.Connection)
+	q.Clone(tq)
+
+	now := time.Now().UTC()
+	return eachPage(tq.Where("namespace IN (?)", names...), func(page relationTuples) error {
+		for _, rt := range page {
+			if err := sqlcon.HandleError(p.CreateWithNetwork(ctx, &trashedTuple{
+				ID:                  rt.ID,
+				Namespace:           rt.Namespace,
+				Object:              rt.Object,
+				Relation:            rt.Relation,
+				SubjectID:           rt.SubjectID,
+				SubjectSetNamespace: rt.SubjectSetNamespace,
+				SubjectSetObject:    rt.SubjectSetObject,
+				SubjectSetRelation:  rt.SubjectSetRelation,
+				CommitTime:          rt.CommitTime,
+				DeletedAt:           now,
+			})); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Persister) RestoreRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, deletedSince time.Time, allow func(ctx context.Context, restored map[string]int) error) (int, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RestoreRelationTuples")
+	defer span.End()
+
+	retentions, err := p.softDeleteRetentions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	err = p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+		restored = 0
+
+		q := p.QueryWithNetwork(ctx).Where("deleted_at >= ?", deletedSince.UTC())
+		if err := p.whereQuery(ctx, q, query); err != nil {
+			return err
+		}
+		var res trashedTuples
+		if err := q.All(&res); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		var (
+			now     = time.Now().UTC()
+			restore = make([]*RelationTuple, 0, len(res))
+			trashed = make([]*trashedTuple, 0, len(res))
+			counts  = make(map[string]int)
+			seen    = make(map[RelationTuple]struct{})
+		)
+		for _, t := range res {
+			retention, ok := retentions[t.Namespace]
+			if !ok || t.DeletedAt.Before(now.Add(-retention)) {
+				continue
+			}
+			trashed = append(trashed, t)
+
+			rt := t.toRelationTuple()
+			it, err := rt.toInternal()
+			if err != nil {
+				return err
+			}
+			live, err := p.queryTuple(ctx, it)
+			if err != nil {
+				return err
+			}
+			exists, err := live.Exists(&RelationTuple{})
+			if err != nil {
+				return sqlcon.HandleError(err)
+			}
+			// The relation tuple was written again after it was deleted, so
+			// there is nothing to restore.
+			if exists {
+				continue
+			}
+			// The relation tuple might have been deleted more than once.
+			key := *rt
+			key.ID, key.CommitTime = uuid.Nil, time.Time{}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			restore = append(restore, rt)
+			counts[rt.Namespace]++
+		}
+
+		if allow != nil {
+			if err := allow(ctx, counts); err != nil {
+				return err
+			}
+		}
+
+		inserted, err := p.insertRows(ctx, restore)
+		if err != nil {
+			return err
+		}
+		its := make([]*relationtuple.RelationTuple, len(inserted))
+		for i, rt := range inserted {
+			if its[i], err = rt.toInternal(); err != nil {
+				return err
+			}
+		}
+		if err := p.checkCardinality(ctx, its...); err != nil {
+			return err
+		}
+		for _, t := range trashed {
+			if err := p.QueryWithNetwork(ctx).Where("shard_id = ?", t.ID).Delete(&trashedTuples{}); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+		restored = len(inserted)
+		return nil
+	})
+	return restored, err
+}
+
+func (p *Persister) PurgeDeletedRelationTuples(ctx context.Context) (int, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.PurgeDeletedRelationTuples")
+	defer span.End()
+
+	retentions, err := p.softDeleteRetentions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	err = p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		purged = 0
+		now := time.Now().UTC()
+		nid := p.NetworkID(ctx)
+
+		names := make([]interface{}, 0, len(retentions))
+		for n, retention := range retentions {
+			names = append(names, n)
+
+			count, err := c.RawQuery(
+				"DELETE FROM keto_relation_tuple_trash WHERE nid = ? AND namespace = ? AND deleted_at < ?",
+				nid, n, now.Add(-retention),
+			).ExecWithCount()
+			if err != nil {
+				return sqlcon.HandleError(err)
+			}
+			purged += count
+		}
+
+		// Soft deletes were disabled for all other namespaces, so their
+		// relation tuples can not be restored anymore.
+		stmt, args := "DELETE FROM keto_relation_tuple_trash WHERE nid = ?", []interface{}{nid}
+		if len(names) > 0 {
+			stmt += " AND namespace NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ") + ")"
+			args = append(args, names...)
+		}
+		count, err := c.RawQuery(stmt, args...).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		purged += count
+		return nil
+	})
+	return purged, err
+}
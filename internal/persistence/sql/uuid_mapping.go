@@ -6,6 +6,7 @@ import (
 
 	"golang.org/x/exp/maps"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/ory/x/sqlcon"
 
@@ -33,36 +34,115 @@ func (p *Persister) batchToUUIDs(ctx context.Context, values []string) (uuids []
 		return
 	}
 
+	c, err := p.mappingCipher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.useCipher(c)
+
+	nid := p.NetworkID(ctx)
 	uuids = make([]uuid.UUID, len(values))
-	placeholderArray := make([]string, len(values))
-	args := make([]interface{}, 0, len(values)*2)
+	var added []int
+	seen := make(map[string]struct{}, len(values))
 	for i, val := range values {
-		uuids[i] = uuid.NewV5(p.NetworkID(ctx), val)
-		placeholderArray[i] = "(?, ?)"
-		args = append(args, uuids[i], val)
+		// Cached mappings are already stored, and a mapping can only be
+		// updated once per statement.
+		if id, ok := p.cache.stored(nid, val); ok {
+			uuids[i] = id
+			continue
+		}
+		uuids[i] = c.id(nid, val)
+		if _, ok := seen[val]; ok {
+			continue
+		}
+		seen[val] = struct{}{}
+		added = append(added, i)
+	}
+	if len(added) == 0 {
+		return uuids, nil
+	}
+	if err := p.keepUnencryptedIDs(ctx, c, nid, values, uuids, added); err != nil {
+		return nil, err
+	}
+
+	placeholderArray := make([]string, 0, len(added))
+	args := make([]interface{}, 0, len(added)*2)
+	for _, i := range added {
+		placeholderArray = append(placeholderArray, "(?, ?)")
+		rep := values[i]
+		if c != nil {
+			if rep, err = c.encrypt(rep); err != nil {
+				return nil, err
+			}
+		}
+		args = append(args, uuids[i], rep)
 	}
 	placeholders := strings.Join(placeholderArray, ", ")
 
-	p.d.Logger().WithField("values", values).WithField("UUIDs", uuids).Trace("adding UUID mappings")
+	p.d.Logger().WithField("UUIDs", uuids).Trace("adding UUID mappings")
 
 	// We need to write manual SQL here because the INSERT should not fail if
 	// the UUID already exists, but we still want to return an error if anything
-	// else goes wrong.
+	// else goes wrong. Existing mappings are used again, so they are not
+	// orphaned anymore.
 	var query string
 	switch d := p.Connection(ctx).Dialect.Name(); d {
 	case "mysql":
 		query = `
-			INSERT IGNORE INTO keto_uuid_mappings (id, string_representation) VALUES ` + placeholders
+			INSERT INTO keto_uuid_mappings (id, string_representation) VALUES ` + placeholders + `
+			ON DUPLICATE KEY UPDATE orphaned_at = NULL`
 	default:
 		query = `
 			INSERT INTO keto_uuid_mappings (id, string_representation)
 			VALUES ` + placeholders + `
-			ON CONFLICT (id) DO NOTHING`
+			ON CONFLICT (id) DO UPDATE SET orphaned_at = NULL WHERE keto_uuid_mappings.orphaned_at IS NOT NULL`
+	}
+
+	conn := p.Connection(ctx)
+	if err := sqlcon.HandleError(conn.RawQuery(query, args...).Exec()); err != nil {
+		return uuids, err
+	}
+	// The mappings of a transaction are only cached once it is committed,
+	// which we can not observe here, so they are not cached at all.
+	if conn.TX == nil {
+		for _, i := range added {
+			p.cache.addStored(nid, values[i], uuids[i])
+		}
+	}
+	return uuids, nil
+}
+
+// keepUnencryptedIDs uses the UUIDv5 of the added values that were mapped
+// before encryption was enabled, as relation tuples reference them.
+func (p *Persister) keepUnencryptedIDs(ctx context.Context, c *mappingCipher, nid uuid.UUID, values []string, uuids []uuid.UUID, added []int) error {
+	if c == nil {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(added))
+	byID := make(map[uuid.UUID]string, len(added))
+	for j, i := range added {
+		ids[j] = uuid.NewV5(nid, values[i])
+		byID[ids[j]] = values[i]
+	}
+	var existing []UUIDMapping
+	if err := sqlcon.HandleError(p.Connection(ctx).Select("id").Where("id IN (?)", ids).All(&existing)); err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
 	}
 
-	return uuids, sqlcon.HandleError(
-		p.Connection(ctx).RawQuery(query, args...).Exec(),
-	)
+	unencrypted := make(map[string]uuid.UUID, len(existing))
+	for _, m := range existing {
+		unencrypted[byID[m.ID]] = m.ID
+	}
+	for i, val := range values {
+		if id, ok := unencrypted[val]; ok {
+			uuids[i] = id
+		}
+	}
+	return nil
 }
 
 func (p *Persister) batchFromUUIDs(ctx context.Context, ids []uuid.UUID, opts ...x.PaginationOptionSetter) (res []string, err error) {
@@ -72,6 +152,12 @@ func (p *Persister) batchFromUUIDs(ctx context.Context, ids []uuid.UUID, opts ..
 
 	p.d.Logger().Trace("looking up UUIDs")
 
+	c, err := p.mappingCipher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.useCipher(c)
+
 	// We need to paginate on the ids, because we want to get the exact chunk of
 	// string representations for the given ids.
 	pagination, _ := internalPaginationFromOptions(opts...)
@@ -86,9 +172,17 @@ func (p *Persister) batchFromUUIDs(ctx context.Context, ids []uuid.UUID, opts ..
 			idIdx[id] = []int{i}
 		}
 	}
-	uniqueIDs := maps.Keys(idIdx)
-
 	res = make([]string, len(ids))
+	for id, idxs := range idIdx {
+		if rep, ok := p.cache.lookup(id); ok {
+			for _, idx := range idxs {
+				res[idx] = rep
+			}
+			delete(idIdx, id)
+		}
+	}
+	uniqueIDs := maps.Keys(idIdx)
+	cache := p.Connection(ctx).TX == nil
 
 	for i := 0; i < len(uniqueIDs); i += pageSize {
 		end := i + pageSize
@@ -97,15 +191,22 @@ func (p *Persister) batchFromUUIDs(ctx context.Context, ids []uuid.UUID, opts ..
 		}
 		idsToLookup := uniqueIDs[i:end]
 		var mappings []UUIDMapping
-		query := p.Connection(ctx).Where("id in (?)", idsToLookup)
+		query := p.readConnection(ctx).Where("id in (?)", idsToLookup)
 		if err := sqlcon.HandleError(query.All(&mappings)); err != nil {
 			return []string{}, err
 		}
 
 		// Write the representation to the correct index.
 		for _, m := range mappings {
+			rep, err := c.decrypt(m.StringRepresentation)
+			if err != nil {
+				return []string{}, err
+			}
 			for _, idx := range idIdx[m.ID] {
-				res[idx] = m.StringRepresentation
+				res[idx] = rep
+			}
+			if cache {
+				p.cache.addLookedUp(m.ID, rep)
 			}
 		}
 	}
@@ -120,3 +221,67 @@ func (p *Persister) MapStringsToUUIDs(ctx context.Context, s ...string) ([]uuid.
 func (p *Persister) MapUUIDsToStrings(ctx context.Context, u ...uuid.UUID) ([]string, error) {
 	return p.batchFromUUIDs(ctx, u)
 }
+
+func (p *Persister) mappingCipher(ctx context.Context) (*mappingCipher, error) {
+	c := p.d.Config(ctx)
+	return newMappingCipher(c.SecretsCipher(), c.SecretsMappingID())
+}
+
+// ReencryptMappings stores all UUID mappings as they would be written now,
+// i.e. encrypted with the first secret. Without secrets, encrypted mappings
+// can not be decrypted and the update fails. It returns the number of changed
+// mappings.
+func (p *Persister) ReencryptMappings(ctx context.Context) (int, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ReencryptMappings")
+	defer span.End()
+
+	c, err := p.mappingCipher(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	pagination, _ := internalPaginationFromOptions()
+	for lastID := uuid.Nil; ; {
+		var mappings []*UUIDMapping
+		if err := sqlcon.HandleError(p.Connection(ctx).Where("id > ?", lastID).Order("id ASC").Limit(pagination.PerPage).All(&mappings)); err != nil {
+			return changed, err
+		}
+		if len(mappings) == 0 {
+			return changed, nil
+		}
+		lastID = mappings[len(mappings)-1].ID
+
+		var stale []*UUIDMapping
+		for _, m := range mappings {
+			if c.current(m.StringRepresentation) {
+				continue
+			}
+			rep, err := c.decrypt(m.StringRepresentation)
+			if err != nil {
+				return changed, err
+			}
+			if c != nil {
+				if rep, err = c.encrypt(rep); err != nil {
+					return changed, err
+				}
+			}
+			stale = append(stale, &UUIDMapping{ID: m.ID, StringRepresentation: rep})
+		}
+		if len(stale) == 0 {
+			continue
+		}
+
+		if err := p.Transaction(ctx, func(ctx context.Context, conn *pop.Connection) error {
+			for _, m := range stale {
+				if err := conn.RawQuery("UPDATE keto_uuid_mappings SET string_representation = ? WHERE id = ?", m.StringRepresentation, m.ID).Exec(); err != nil {
+					return sqlcon.HandleError(err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return changed, err
+		}
+		changed += len(stale)
+	}
+}
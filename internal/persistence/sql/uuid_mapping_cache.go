@@ -0,0 +1,146 @@
+package sql
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/keto/internal/x"
+)
+
+type (
+	// mappingCache caches the UUID mappings in both directions. A string is
+	// only cached once its mapping is stored, so that it does not have to be
+	// written again. As orphaned mappings are deleted by other instances
+	// too, the strings expire before the grace period of the garbage
+	// collection.
+	mappingCache struct {
+		toUUID   *x.LRU[mappingCacheKey, uuid.UUID]
+		toString *x.LRU[uuid.UUID, string]
+
+		mx sync.Mutex
+		// keys identifies the cipher keys the cached mappings were read or
+		// written with.
+		keys string
+
+		toUUIDHits, toUUIDMisses     uint64
+		toStringHits, toStringMisses uint64
+	}
+	mappingCacheKey struct {
+		nid   uuid.UUID
+		value string
+	}
+	// MappingCacheStats are the statistics of one direction of the UUID
+	// mapping cache.
+	MappingCacheStats struct {
+		Hits, Misses, Evictions uint64
+		Entries, Size           int
+	}
+)
+
+// mappingCacheTTL is how long a string is known to be stored. Orphaned
+// mappings are deleted after a longer grace period.
+const mappingCacheTTL = 5 * time.Minute
+
+// newMappingCache returns nil if the size is not positive, which disables
+// the cache.
+func newMappingCache(size int) *mappingCache {
+	if size <= 0 {
+		return nil
+	}
+	return &mappingCache{
+		toUUID:   x.NewLRU[mappingCacheKey, uuid.UUID](size, mappingCacheTTL),
+		toString: x.NewLRU[uuid.UUID, string](size, 0),
+	}
+}
+
+// useCipher purges the cache if the cipher keys changed since it was last
+// used, so that encrypted mappings are only returned while their secret is
+// configured.
+func (c *mappingCache) useCipher(mc *mappingCipher) {
+	if c == nil {
+		return
+	}
+	keys := mc.keyIDs()
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.keys != keys {
+		c.purge()
+		c.keys = keys
+	}
+}
+
+// stored returns the UUID of the value if its mapping is known to be stored.
+func (c *mappingCache) stored(nid uuid.UUID, value string) (uuid.UUID, bool) {
+	if c == nil {
+		return uuid.Nil, false
+	}
+	if id, ok := c.toUUID.Get(mappingCacheKey{nid, value}); ok {
+		atomic.AddUint64(&c.toUUIDHits, 1)
+		return id, true
+	}
+	atomic.AddUint64(&c.toUUIDMisses, 1)
+	return uuid.Nil, false
+}
+
+func (c *mappingCache) lookup(id uuid.UUID) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if s, ok := c.toString.Get(id); ok {
+		atomic.AddUint64(&c.toStringHits, 1)
+		return s, true
+	}
+	atomic.AddUint64(&c.toStringMisses, 1)
+	return "", false
+}
+
+func (c *mappingCache) addStored(nid uuid.UUID, value string, id uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.toUUID.Add(mappingCacheKey{nid, value}, id)
+	c.toString.Add(id, value)
+}
+
+func (c *mappingCache) addLookedUp(id uuid.UUID, value string) {
+	if c == nil {
+		return
+	}
+	c.toString.Add(id, value)
+}
+
+// purge removes all mappings, e.g. after mappings were deleted.
+func (c *mappingCache) purge() {
+	if c == nil {
+		return
+	}
+	c.toUUID.Purge()
+	c.toString.Purge()
+}
+
+// MappingCacheStats returns the statistics of the UUID mapping cache from
+// strings to UUIDs and from UUIDs to strings. ok is false if the cache is
+// disabled.
+func (p *Persister) MappingCacheStats() (toUUID, toString MappingCacheStats, ok bool) {
+	c := p.cache
+	if c == nil {
+		return toUUID, toString, false
+	}
+	return lruStats(c.toUUID, &c.toUUIDHits, &c.toUUIDMisses),
+		lruStats(c.toString, &c.toStringHits, &c.toStringMisses),
+		true
+}
+
+func lruStats[K comparable, V any](lru *x.LRU[K, V], hits, misses *uint64) MappingCacheStats {
+	return MappingCacheStats{
+		Hits:      atomic.LoadUint64(hits),
+		Misses:    atomic.LoadUint64(misses),
+		Evictions: lru.Evictions(),
+		Entries:   lru.Len(),
+		Size:      lru.Size(),
+	}
+}
@@ -0,0 +1,162 @@
+package sql
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+type (
+	// mappingCipher encrypts the string representations of the UUID mappings
+	// with AES-GCM. The first key encrypts, all keys decrypt, so that keys
+	// can be rotated. Encrypted values are prefixed with the ID of their key,
+	// values without the prefix were written before encryption was enabled.
+	//
+	// The UUIDs of the values are derived with HMAC-SHA256 instead of UUIDv5,
+	// so that they do not reveal the values either. The relation tuples
+	// reference the UUIDs, so the HMAC key is derived from a dedicated secret
+	// that is never rotated, independent of the encryption secrets.
+	mappingCipher struct {
+		keys    []*mappingKey
+		idKey   []byte
+		idKeyID string
+	}
+	mappingKey struct {
+		id   string
+		aead cipher.AEAD
+	}
+)
+
+const (
+	encryptedMappingPrefix = "$aes-gcm$"
+	minCipherSecretLength  = 32
+	// mappingIDContext separates the HMAC key from the encryption keys.
+	mappingIDContext = "keto uuid mapping ids"
+)
+
+// newMappingCipher returns nil if no secrets are configured. The ID secret is
+// required with secrets.
+func newMappingCipher(secrets []string, idSecret string) (*mappingCipher, error) {
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	if len(idSecret) < minCipherSecretLength {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The identifiers are encrypted, so %s has to be set to a secret that is at least %d characters long.", config.KeySecretsMappingID, minCipherSecretLength))
+	}
+	c := &mappingCipher{keys: make([]*mappingKey, len(secrets))}
+	for i, s := range secrets {
+		if len(s) < minCipherSecretLength {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The secrets of %s have to be at least %d characters long.", config.KeySecretsCipher, minCipherSecretLength))
+		}
+		key := sha256.Sum256([]byte(s))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		id := sha256.Sum256(key[:])
+		c.keys[i] = &mappingKey{id: hex.EncodeToString(id[:4]), aead: aead}
+	}
+	mac := hmac.New(sha256.New, []byte(idSecret))
+	mac.Write([]byte(mappingIDContext))
+	c.idKey = mac.Sum(nil)
+	idKeyID := sha256.Sum256(c.idKey)
+	c.idKeyID = hex.EncodeToString(idKeyID[:4])
+	return c, nil
+}
+
+// id returns the UUID of the value in the network. Without a cipher, it is
+// the UUIDv5 of the value.
+func (c *mappingCipher) id(nid uuid.UUID, value string) uuid.UUID {
+	if c == nil {
+		return uuid.NewV5(nid, value)
+	}
+	mac := hmac.New(sha256.New, c.idKey)
+	mac.Write(nid.Bytes())
+	mac.Write([]byte(value))
+
+	var id uuid.UUID
+	copy(id[:], mac.Sum(nil))
+	id.SetVersion(8)
+	id.SetVariant(uuid.VariantRFC4122)
+	return id
+}
+
+func (c *mappingCipher) encrypt(s string) (string, error) {
+	k := c.keys[0]
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(s), []byte(k.id))
+	return encryptedMappingPrefix + k.id + "$" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns unencrypted values as they are.
+func (c *mappingCipher) decrypt(s string) (string, error) {
+	id, sealed, ok := splitEncryptedMapping(s)
+	if !ok {
+		return s, nil
+	}
+	if c != nil {
+		for _, k := range c.keys {
+			if k.id != id {
+				continue
+			}
+			raw, err := base64.RawStdEncoding.DecodeString(sealed)
+			if err != nil || len(raw) < k.aead.NonceSize() {
+				return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("A UUID mapping is not encrypted correctly."))
+			}
+			plain, err := k.aead.Open(nil, raw[:k.aead.NonceSize()], raw[k.aead.NonceSize():], []byte(k.id))
+			if err != nil {
+				return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("A UUID mapping could not be decrypted: %s", err))
+			}
+			return string(plain), nil
+		}
+	}
+	return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("A UUID mapping is encrypted with the unknown key %s. Add the secret it was encrypted with to %s.", id, config.KeySecretsCipher))
+}
+
+// current returns whether the value is stored as the cipher would store it
+// now, i.e. encrypted with the first key, or unencrypted without a cipher.
+func (c *mappingCipher) current(s string) bool {
+	id, _, ok := splitEncryptedMapping(s)
+	if c == nil {
+		return !ok
+	}
+	return ok && id == c.keys[0].id
+}
+
+// keyIDs identifies the keys of the cipher, including the HMAC key.
+func (c *mappingCipher) keyIDs() string {
+	if c == nil {
+		return ""
+	}
+	ids := make([]string, len(c.keys))
+	for i, k := range c.keys {
+		ids[i] = k.id
+	}
+	return strings.Join(ids, ",") + ";" + c.idKeyID
+}
+
+func splitEncryptedMapping(s string) (id, sealed string, ok bool) {
+	if !strings.HasPrefix(s, encryptedMappingPrefix) {
+		return "", "", false
+	}
+	return strings.Cut(s[len(encryptedMappingPrefix):], "$")
+}
@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/relationtuple"
+)
+
+type orphanCandidate struct {
+	ID         uuid.UUID  `db:"id"`
+	OrphanedAt *time.Time `db:"orphaned_at"`
+	Orphaned   bool       `db:"orphaned"`
+}
+
+// MinMappingGCGracePeriod is the shortest grace period of orphaned mappings,
+// as other instances cache the mappings as stored for mappingCacheTTL.
+const MinMappingGCGracePeriod = 2 * mappingCacheTTL
+
+var (
+	_ relationtuple.MappingGCManager = &Persister{}
+
+	// orphanedMappingCondition matches the UUID mappings that no relation
+	// tuple references, including the history, the soft deleted relation
+	// tuples, and the changes that are not published yet.
+	orphanedMappingCondition = func() string {
+		var conditions []string
+		for _, table := range []string{
+			"keto_relation_tuples",
+			"keto_relation_tuple_outbox",
+			"keto_relation_tuple_history",
+			"keto_relation_tuple_trash",
+			"keto_relation_tuple_closure",
+		} {
+			for _, column := range []string{"object", "subject_id", "subject_set_object"} {
+				conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = keto_uuid_mappings.id)", table, column))
+			}
+		}
+		return strings.Join(conditions, " AND ")
+	}()
+)
+
+// CollectOrphanedMappings pages through all UUID mappings. A mapping that
+// nothing references is marked as orphaned, and deleted once it was marked
+// for the grace period. Writing the mapping again removes the mark, so that a
+// mapping that is about to be referenced is not deleted.
+func (p *Persister) CollectOrphanedMappings(ctx context.Context, opts relationtuple.MappingGCOptions) (*relationtuple.MappingGCResult, error) {
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CollectOrphanedMappings")
+	defer span.End()
+
+	if opts.GracePeriod < MinMappingGCGracePeriod {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The grace period of orphaned UUID mappings has to be at least %s.", MinMappingGCGracePeriod))
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultPageSize
+	}
+
+	res := &relationtuple.MappingGCResult{}
+	for lastID := uuid.Nil; ; {
+		var page []*orphanCandidate
+		if err := sqlcon.HandleError(p.Connection(ctx).RawQuery(
+			"SELECT id, orphaned_at, CASE WHEN "+orphanedMappingCondition+" THEN 1 ELSE 0 END AS orphaned FROM keto_uuid_mappings WHERE id > ? ORDER BY id ASC LIMIT ?",
+			lastID, opts.BatchSize,
+		).All(&page)); err != nil {
+			return res, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		lastID = page[len(page)-1].ID
+
+		now := time.Now().UTC()
+		cutoff := now.Add(-opts.GracePeriod)
+		var mark, unmark, del []interface{}
+		for _, m := range page {
+			switch {
+			case m.Orphaned && m.OrphanedAt == nil:
+				mark = append(mark, m.ID)
+			case m.Orphaned && m.OrphanedAt.Before(cutoff):
+				del = append(del, m.ID)
+			case !m.Orphaned && m.OrphanedAt != nil:
+				unmark = append(unmark, m.ID)
+			}
+		}
+		if opts.DryRun {
+			res.Marked += len(mark)
+			res.Unmarked += len(unmark)
+			res.Deleted += len(del)
+			continue
+		}
+
+		var marked, unmarked, deleted int
+		if err := p.Transaction(ctx, func(ctx context.Context, conn *pop.Connection) (err error) {
+			// The conditions are checked again, as the mappings could have
+			// been written since the page was read.
+			if marked, err = execIn(conn, "UPDATE keto_uuid_mappings SET orphaned_at = ? WHERE orphaned_at IS NULL AND "+orphanedMappingCondition, []interface{}{now}, mark); err != nil {
+				return err
+			}
+			if unmarked, err = execIn(conn, "UPDATE keto_uuid_mappings SET orphaned_at = NULL WHERE orphaned_at IS NOT NULL", nil, unmark); err != nil {
+				return err
+			}
+			deleted, err = execIn(conn, "DELETE FROM keto_uuid_mappings WHERE orphaned_at < ? AND "+orphanedMappingCondition, []interface{}{cutoff}, del)
+			return err
+		}); err != nil {
+			return res, err
+		}
+		res.Marked += marked
+		res.Unmarked += unmarked
+		res.Deleted += deleted
+	}
+
+	if res.Deleted > 0 && !opts.DryRun {
+		p.cache.purge()
+	}
+	return res, nil
+}
+
+// execIn executes the statement for the mappings with the given IDs, and
+// returns the number of affected rows.
+func execIn(conn *pop.Connection, stmt string, args, ids []interface{}) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	stmt += " AND id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")"
+	n, err := conn.RawQuery(stmt, append(args, ids...)...).ExecWithCount()
+	return n, sqlcon.HandleError(err)
+}
@@ -5,13 +5,18 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/persistence/sql"
+	"github.com/ory/keto/internal/relationtuple"
 	"github.com/ory/keto/internal/x/dbx"
 )
 
@@ -84,3 +89,265 @@ func TestUUIDMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestUUIDMappingEncryption(t *testing.T) {
+	ctx := context.Background()
+	const (
+		oldSecret = "an-old-secret-that-is-long-enough"
+		newSecret = "a-new-secret-that-is-also-long-enough"
+	)
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+		driver.WithConfig(config.KeySecretsMappingID, "the-secret-of-the-mapping-uuids-of-the-test"))
+	p := reg.Persister()
+	cm := reg.MappingCipherManager()
+	require.NotNil(t, cm)
+	conn, err := reg.PopConnection(ctx)
+	require.NoError(t, err)
+
+	stored := func(t *testing.T, id uuid.UUID) string {
+		var m sql.UUIDMapping
+		require.NoError(t, conn.Find(&m, id))
+		return m.StringRepresentation
+	}
+	setSecrets := func(t *testing.T, secrets ...string) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeySecretsCipher, secrets))
+	}
+	assertReason := func(t *testing.T, err error, reason string, msgAndArgs ...interface{}) {
+		herodotErr := &herodot.DefaultError{}
+		require.ErrorAs(t, err, &herodotErr)
+		assert.Contains(t, herodotErr.Reason(), reason, msgAndArgs...)
+	}
+
+	plain, err := p.MapStringsToUUIDs(ctx, "written before encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "written before encryption", stored(t, plain[0]))
+
+	setSecrets(t, oldSecret)
+	ids, err := p.MapStringsToUUIDs(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.NotContains(t, stored(t, ids[0]), "alice")
+
+	all := []uuid.UUID{plain[0], ids[0]}
+	expected := []string{"written before encryption", "alice@example.com"}
+	actual, err := p.MapUUIDsToStrings(ctx, all...)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	t.Run("case=short secrets are rejected", func(t *testing.T) {
+		setSecrets(t, "short")
+		t.Cleanup(func() { setSecrets(t, oldSecret) })
+		_, err := p.MapStringsToUUIDs(ctx, "bob")
+		assertReason(t, err, config.KeySecretsCipher)
+	})
+
+	t.Run("case=the secret of the UUIDs is required", func(t *testing.T) {
+		reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory),
+			driver.WithConfig(config.KeySecretsCipher, []string{oldSecret}))
+		_, err := reg.Persister().MapStringsToUUIDs(ctx, "bob")
+		assertReason(t, err, config.KeySecretsMappingID)
+	})
+
+	t.Run("case=identifiers are derived with the dedicated secret", func(t *testing.T) {
+		assert.Equal(t, byte(5), plain[0].Version())
+		assert.Equal(t, byte(8), ids[0].Version(), "the UUIDv5 would reveal the identifier")
+
+		again, err := p.MapStringsToUUIDs(ctx, "written before encryption", "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, all, again, "identifiers mapped before encryption keep their UUID")
+
+		setSecrets(t, newSecret, oldSecret)
+		t.Cleanup(func() { setSecrets(t, oldSecret) })
+		again, err = p.MapStringsToUUIDs(ctx, "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, ids, again)
+
+		// The first secret is the current one, so prepending or removing
+		// old secrets keeps the UUIDs as well.
+		setSecrets(t, newSecret)
+		again, err = p.MapStringsToUUIDs(ctx, "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, ids, again)
+	})
+
+	t.Run("case=rotate the secret", func(t *testing.T) {
+		setSecrets(t, newSecret, oldSecret)
+		actual, err := p.MapUUIDsToStrings(ctx, all...)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		n, err := cm.ReencryptMappings(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.NotContains(t, stored(t, plain[0]), "encryption")
+		n, err = cm.ReencryptMappings(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		setSecrets(t, newSecret)
+		actual, err = p.MapUUIDsToStrings(ctx, all...)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		setSecrets(t, oldSecret)
+		_, err = p.MapUUIDsToStrings(ctx, all...)
+		assertReason(t, err, "unknown key")
+	})
+
+	t.Run("case=encrypted identifiers require the secret", func(t *testing.T) {
+		setSecrets(t, newSecret)
+		require.NoError(t, reg.Config(ctx).Set(config.KeySecretsCipher, []string{}))
+		_, err := p.MapUUIDsToStrings(ctx, all...)
+		assertReason(t, err, "unknown key")
+
+		setSecrets(t, newSecret)
+		actual, err := p.MapUUIDsToStrings(ctx, all...)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		require.NoError(t, reg.Config(ctx).Set(config.KeySecretsCipher, []string{}))
+		_, err = cm.ReencryptMappings(ctx)
+		assertReason(t, err, "unknown key", "the identifiers can only be decrypted with the secret")
+	})
+}
+
+func TestUUIDMappingCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=disabled", func(t *testing.T) {
+		reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithConfig(config.KeyUUIDMappingCacheSize, 0))
+		_, _, ok := reg.Persister().(*sql.Persister).MappingCacheStats()
+		assert.False(t, ok)
+	})
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithConfig(config.KeyUUIDMappingCacheSize, 2))
+	p := reg.Persister().(*sql.Persister)
+	stats := func(t *testing.T) (toUUID, toString sql.MappingCacheStats) {
+		toUUID, toString, ok := p.MappingCacheStats()
+		require.True(t, ok)
+		return toUUID, toString
+	}
+
+	ids, err := p.MapStringsToUUIDs(ctx, "a", "b")
+	require.NoError(t, err)
+	toUUID, toString := stats(t)
+	assert.Equal(t, sql.MappingCacheStats{Misses: 2, Entries: 2, Size: 2}, toUUID)
+	assert.Equal(t, sql.MappingCacheStats{Entries: 2, Size: 2}, toString)
+
+	again, err := p.MapStringsToUUIDs(ctx, "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, ids, again)
+	toUUID, _ = stats(t)
+	assert.EqualValues(t, 2, toUUID.Hits)
+
+	_, err = p.MapStringsToUUIDs(ctx, "c")
+	require.NoError(t, err)
+	toUUID, _ = stats(t)
+	assert.EqualValues(t, 1, toUUID.Evictions)
+	assert.Equal(t, 2, toUUID.Entries)
+
+	// "a" was evicted and is looked up in the database.
+	actual, err := p.MapUUIDsToStrings(ctx, ids[0], ids[1], ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "a"}, actual)
+	_, toString = stats(t)
+	assert.EqualValues(t, 1, toString.Hits)
+	assert.EqualValues(t, 1, toString.Misses)
+
+	actual, err = p.MapUUIDsToStrings(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, actual)
+	_, toString = stats(t)
+	assert.EqualValues(t, 2, toString.Hits)
+
+	t.Run("case=transactions are not cached", func(t *testing.T) {
+		require.NoError(t, p.Transaction(ctx, func(ctx context.Context, _ *pop.Connection) error {
+			_, err := p.MapStringsToUUIDs(ctx, "d")
+			return err
+		}))
+		before, _ := stats(t)
+		_, err := p.MapStringsToUUIDs(ctx, "d")
+		require.NoError(t, err)
+		after, _ := stats(t)
+		assert.Equal(t, before.Misses+1, after.Misses)
+	})
+
+	t.Run("case=changing the secrets purges the cache", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeySecretsMappingID, "a-mapping-secret-that-is-long-enough"))
+		require.NoError(t, reg.Config(ctx).Set(config.KeySecretsCipher, []string{"a-secret-that-is-definitely-long-enough"}))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeySecretsCipher, []string{})) })
+		_, err := p.MapStringsToUUIDs(ctx, "b")
+		require.NoError(t, err)
+		toUUID, toString := stats(t)
+		assert.Equal(t, 1, toUUID.Entries)
+		assert.Equal(t, 1, toString.Entries)
+	})
+}
+
+func TestUUIDMappingGC(t *testing.T) {
+	ctx := context.Background()
+	// Without the cache, every write of a mapping reaches the database.
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithConfig(config.KeyUUIDMappingCacheSize, 0))
+	p := reg.Persister()
+	gm := reg.MappingGCManager()
+	require.NotNil(t, gm)
+	conn, err := reg.PopConnection(ctx)
+	require.NoError(t, err)
+
+	ids, err := p.MapStringsToUUIDs(ctx, "doc", "alice", "orphan")
+	require.NoError(t, err)
+	tuple := &relationtuple.RelationTuple{Namespace: "docs", Object: ids[0], Relation: "viewer", Subject: &relationtuple.SubjectID{ID: ids[1]}}
+	require.NoError(t, p.WriteRelationTuples(ctx, tuple))
+
+	opts := relationtuple.MappingGCOptions{GracePeriod: sql.MinMappingGCGracePeriod, BatchSize: 2}
+	collect := func(t *testing.T, opts relationtuple.MappingGCOptions) relationtuple.MappingGCResult {
+		res, err := gm.CollectOrphanedMappings(ctx, opts)
+		require.NoError(t, err)
+		return *res
+	}
+	marked := func(t *testing.T) (n int) {
+		require.NoError(t, conn.RawQuery("SELECT COUNT(*) FROM keto_uuid_mappings WHERE orphaned_at IS NOT NULL").First(&n))
+		return n
+	}
+	backdate := func(t *testing.T) {
+		require.NoError(t, conn.RawQuery("UPDATE keto_uuid_mappings SET orphaned_at = ? WHERE orphaned_at IS NOT NULL", time.Now().UTC().Add(-time.Hour)).Exec())
+	}
+
+	_, err = gm.CollectOrphanedMappings(ctx, relationtuple.MappingGCOptions{GracePeriod: time.Minute})
+	assert.Error(t, err)
+
+	dryRun := opts
+	dryRun.DryRun = true
+	assert.Equal(t, relationtuple.MappingGCResult{Marked: 1}, collect(t, dryRun))
+	assert.Zero(t, marked(t))
+
+	assert.Equal(t, relationtuple.MappingGCResult{Marked: 1}, collect(t, opts))
+	assert.Equal(t, relationtuple.MappingGCResult{}, collect(t, opts), "the mapping is not orphaned for the grace period yet")
+
+	t.Run("case=writing the mapping removes the mark", func(t *testing.T) {
+		_, err := p.MapStringsToUUIDs(ctx, "orphan")
+		require.NoError(t, err)
+		assert.Zero(t, marked(t))
+		assert.Equal(t, relationtuple.MappingGCResult{Marked: 1}, collect(t, opts))
+	})
+
+	t.Run("case=orphaned mappings are deleted after the grace period", func(t *testing.T) {
+		backdate(t)
+		require.NoError(t, p.DeleteRelationTuples(ctx, tuple))
+		assert.Equal(t, relationtuple.MappingGCResult{Marked: 2, Deleted: 1}, collect(t, dryRun))
+		assert.Equal(t, relationtuple.MappingGCResult{Marked: 2, Deleted: 1}, collect(t, opts))
+
+		var n int
+		require.NoError(t, conn.RawQuery("SELECT COUNT(*) FROM keto_uuid_mappings").First(&n))
+		assert.Equal(t, 2, n)
+	})
+
+	t.Run("case=referenced mappings are unmarked", func(t *testing.T) {
+		backdate(t)
+		require.NoError(t, p.WriteRelationTuples(ctx, tuple))
+		assert.Equal(t, relationtuple.MappingGCResult{Unmarked: 2}, collect(t, opts))
+		assert.Zero(t, marked(t))
+
+		actual, err := p.MapUUIDsToStrings(ctx, ids[0], ids[1])
+		require.NoError(t, err)
+		assert.Equal(t, []string{"doc", "alice"}, actual)
+	})
+}
@@ -0,0 +1,37 @@
+package quota
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exports how often the quotas were exceeded, and the relation tuple
+// counts of the quotas.
+type Metrics struct {
+	exceeded       *prometheus.CounterVec
+	relationTuples *prometheus.GaugeVec
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		exceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "keto_quota_exceeded_total",
+			Help: "The number of requests that were rejected because they exceeded a quota.",
+		}, []string{"quota", "tenant", "namespace"}),
+		relationTuples: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "keto_quota_relation_tuples",
+			Help: "The number of relation tuples of the relation tuple quotas, as counted by the last write.",
+		}, []string{"tenant", "namespace"}),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.exceeded.Describe(ch)
+	m.relationTuples.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.exceeded.Collect(ch)
+	m.relationTuples.Collect(ch)
+}
@@ -0,0 +1,227 @@
+// Package quota enforces the configured quotas on the number of relation
+// tuples, and on the rates of writes and checks, per tenant and namespace.
+// The handlers call the enforcer before they write or check, like they record
+// the audit events afterwards.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/tenancy"
+	"github.com/ory/keto/internal/x/ratelimit"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	EnforcerProvider interface {
+		QuotaEnforcer() *Enforcer
+	}
+	// Counter counts the relation tuples of the tenant of the context in the
+	// namespace, or in all namespaces if it is empty.
+	Counter func(ctx context.Context, namespace string) (int, error)
+
+	Enforcer struct {
+		d     config.Provider
+		count Counter
+		m     *Metrics
+
+		mx       sync.Mutex
+		quotas   []config.Quota
+		limiters map[limiterKey]*ratelimit.Limiter
+	}
+	limiterKey struct {
+		quota config.Quota
+		kind  string
+	}
+)
+
+const (
+	kindRelationTuples = "relation_tuples"
+	kindWrites         = "writes"
+	kindChecks         = "checks"
+)
+
+var (
+	ErrRateExceeded = herodot.DefaultError{
+		CodeField:     http.StatusTooManyRequests,
+		StatusField:   http.StatusText(http.StatusTooManyRequests),
+		GRPCCodeField: codes.ResourceExhausted,
+		ErrorField:    "The quota was exceeded.",
+	}
+	ErrRelationTuplesExceeded = herodot.DefaultError{
+		CodeField:     http.StatusForbidden,
+		StatusField:   http.StatusText(http.StatusForbidden),
+		GRPCCodeField: codes.PermissionDenied,
+		ErrorField:    "The quota was exceeded.",
+	}
+)
+
+func NewEnforcer(d config.Provider, count Counter) *Enforcer {
+	return &Enforcer{d: d, count: count, m: NewMetrics()}
+}
+
+// Metrics returns the metrics of the enforced quotas.
+func (e *Enforcer) Metrics() *Metrics {
+	return e.m
+}
+
+// Check enforces the check quotas of the namespace of the relation tuple.
+func (e *Enforcer) Check(ctx context.Context, tuple *ketoapi.RelationTuple) error {
+	return e.rate(ctx, kindChecks, func(q config.Quota) bool {
+		return q.Checks.RequestsPerSecond > 0 && (q.Namespace == "" || q.Namespace == tuple.Namespace)
+	})
+}
+
+// Write enforces the write and relation tuple quotas of the namespaces that
+// the deltas change.
+func (e *Enforcer) Write(ctx context.Context, deltas []*ketoapi.PatchDelta) error {
+	added := make(map[string]int)
+	for _, d := range deltas {
+		switch d.Action {
+		case ketoapi.ActionInsert:
+			added[d.RelationTuple.Namespace]++
+		case ketoapi.ActionDelete:
+			added[d.RelationTuple.Namespace]--
+		}
+	}
+	if err := e.rate(ctx, kindWrites, func(q config.Quota) bool {
+		if q.Writes.RequestsPerSecond <= 0 {
+			return false
+		}
+		_, ok := added[q.Namespace]
+		return q.Namespace == "" || ok
+	}); err != nil {
+		return err
+	}
+	return e.Add(ctx, added)
+}
+
+// WriteAll enforces the write quotas of the namespace of the query, or of all
+// namespaces if it has none. It applies to writes of all relation tuples
+// matching the query, like deletes, restores and imports.
+func (e *Enforcer) WriteAll(ctx context.Context, query *ketoapi.RelationQuery) error {
+	return e.rate(ctx, kindWrites, func(q config.Quota) bool {
+		return q.Writes.RequestsPerSecond > 0 && (q.Namespace == "" || query.Namespace == nil || q.Namespace == *query.Namespace)
+	})
+}
+
+// applicable returns the quotas of the tenant.
+func (e *Enforcer) applicable(ctx context.Context, tenant string) ([]config.Quota, error) {
+	quotas, err := e.d.Config(ctx).Quotas()
+	if err != nil {
+		return nil, err
+	}
+	applicable := make([]config.Quota, 0, len(quotas))
+	for _, q := range quotas {
+		if q.Tenant == "" || q.Tenant == tenant {
+			applicable = append(applicable, q)
+		}
+	}
+	return applicable, nil
+}
+
+// rate takes a token from the buckets of the tenant for all quotas of the
+// kind that apply.
+func (e *Enforcer) rate(ctx context.Context, kind string, applies func(q config.Quota) bool) error {
+	tenant, _ := tenancy.FromContext(ctx)
+	quotas, err := e.applicable(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	for _, q := range quotas {
+		if !applies(q) {
+			continue
+		}
+		limit := q.Checks
+		if kind == kindWrites {
+			limit = q.Writes
+		}
+		if wait, ok := e.limiter(ctx, q, kind, limit).Take(tenant, time.Now()); !ok {
+			e.m.exceeded.WithLabelValues(kind, tenant, q.Namespace).Inc()
+			return errors.WithStack(ErrRateExceeded.WithReasonf(
+				"The %s quota of %s was exceeded, retry in %s.", kind, scope(tenant, q.Namespace), ratelimit.RetryAfter(wait)))
+		}
+	}
+	return nil
+}
+
+// limiter returns the limiter of the quota. All limiters are dropped when the
+// quotas change.
+func (e *Enforcer) limiter(ctx context.Context, q config.Quota, kind string, limit config.RateLimit) *ratelimit.Limiter {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if quotas, err := e.d.Config(ctx).Quotas(); err == nil && !slices.Equal(quotas, e.quotas) {
+		e.quotas, e.limiters = quotas, nil
+	}
+	if e.limiters == nil {
+		e.limiters = make(map[limiterKey]*ratelimit.Limiter)
+	}
+	key := limiterKey{quota: q, kind: kind}
+	l, ok := e.limiters[key]
+	if !ok {
+		l = ratelimit.NewLimiter(limit)
+		e.limiters[key] = l
+	}
+	return l
+}
+
+// Add enforces the relation tuple quotas of the tenant, given how many
+// relation tuples are added to every namespace. Writes that do not know the
+// added relation tuples up front call it in addition to WriteAll.
+func (e *Enforcer) Add(ctx context.Context, added map[string]int) error {
+	tenant, _ := tenancy.FromContext(ctx)
+	quotas, err := e.applicable(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	for _, q := range quotas {
+		if q.MaxRelationTuples <= 0 {
+			continue
+		}
+		n := 0
+		for namespace, a := range added {
+			if q.Namespace == "" || q.Namespace == namespace {
+				n += a
+			}
+		}
+		if n <= 0 {
+			continue
+		}
+
+		count, err := e.count(ctx, q.Namespace)
+		if err != nil {
+			return err
+		}
+		e.m.relationTuples.WithLabelValues(tenant, q.Namespace).Set(float64(count))
+		if count+n > q.MaxRelationTuples {
+			e.m.exceeded.WithLabelValues(kindRelationTuples, tenant, q.Namespace).Inc()
+			return errors.WithStack(ErrRelationTuplesExceeded.WithReasonf(
+				"The relation tuple quota of %s allows %d relation tuples, and there are %d already.", scope(tenant, q.Namespace), q.MaxRelationTuples, count))
+		}
+	}
+	return nil
+}
+
+// scope describes the tenant and namespace of a quota in error messages.
+func scope(tenant, namespace string) string {
+	switch {
+	case tenant != "" && namespace != "":
+		return fmt.Sprintf("the namespace %q of the tenant %q", namespace, tenant)
+	case tenant != "":
+		return fmt.Sprintf("the tenant %q", tenant)
+	case namespace != "":
+		return fmt.Sprintf("the namespace %q", namespace)
+	}
+	return "all namespaces"
+}
@@ -0,0 +1,150 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	BulkDeleteRoute = WriteRouteBase + "/bulk-delete"
+
+	defaultBulkDeleteBatchSize   = 1000
+	defaultBulkDeleteMaxDuration = 10 * time.Second
+)
+
+// bulkDeleteToken is the continuation token of a bulk delete. It carries the
+// progress, so that the total count survives across requests.
+type bulkDeleteToken struct {
+	PageToken    string `json:"p"`
+	TotalDeleted int    `json:"d"`
+}
+
+func (t *bulkDeleteToken) encode() string {
+	raw, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeBulkDeleteToken(s string) (*bulkDeleteToken, error) {
+	t := &bulkDeleteToken{}
+	if s == "" {
+		return t, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("malformed page token"))
+	}
+	if err := json.Unmarshal(raw, t); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("malformed page token"))
+	}
+	return t, nil
+}
+
+// swagger:route POST /admin/relation-tuples/bulk-delete write bulkDeleteRelationTuples
+//
+// # Delete Relation Tuples in Batches
+//
+// Use this endpoint to delete large amounts of relation tuples matching the
+// query. The relation tuples are deleted in batches until either all are
+// deleted or the maximum duration is exceeded. In the latter case, the
+// response contains a token to continue the deletion with. Relation tuples
+// written while the deletion is in progress might not be deleted.
+//
+//	Consumes:
+//	-  application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: bulkDeleteRelationTuplesResponse
+//	  400: genericError
+//	  500: genericError
+func (h *handler) bulkDeleteRelationTuples(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	start := time.Now()
+
+	q := r.URL.Query()
+	query, err := (&ketoapi.RelationQuery{}).FromURLQuery(q)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
+		return
+	}
+
+	token, err := decodeBulkDeleteToken(q.Get("page_token"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	batchSize := defaultBulkDeleteBatchSize
+	if raw := q.Get("batch_size"); raw != "" {
+		s, err := strconv.ParseInt(raw, 0, 0)
+		if err != nil || s <= 0 {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("batch_size has to be a positive integer, got %q", raw)))
+			return
+		}
+		batchSize = int(s)
+	}
+
+	maxDuration := defaultBulkDeleteMaxDuration
+	if raw := q.Get("max_duration"); raw != "" {
+		maxDuration, err = time.ParseDuration(raw)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not parse max_duration: %s", err)))
+			return
+		}
+	}
+
+	iq, err := h.d.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	if err := h.d.QuotaEnforcer().WriteAll(ctx, query); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	deleted := 0
+	for {
+		n, next, err := h.d.RelationTupleManager().DeleteRelationTuplesPage(ctx, iq, x.WithToken(token.PageToken), x.WithSize(batchSize))
+		if err != nil {
+			h.d.AuditLogger().Delete(ctx, query, err)
+			h.d.Writer().WriteError(w, r, err)
+			return
+		}
+		deleted += n
+		token.TotalDeleted += n
+		token.PageToken = next
+
+		if next == "" || time.Since(start) >= maxDuration {
+			break
+		}
+	}
+
+	h.d.AuditLogger().Delete(ctx, query, nil)
+	h.d.Logger().WithField("deleted", deleted).WithField("total_deleted", token.TotalDeleted).Debug("deleted relation tuples in batches")
+
+	resp := &ketoapi.BulkDeleteResponse{
+		Deleted:      deleted,
+		TotalDeleted: token.TotalDeleted,
+	}
+	if token.PageToken != "" {
+		resp.NextPageToken = token.encode()
+	}
+	h.d.Writer().Write(w, r, resp)
+}
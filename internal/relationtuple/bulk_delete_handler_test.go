@@ -0,0 +1,101 @@
+package relationtuple_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestBulkDeleteHandler(t *testing.T) {
+	ctx := context.Background()
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{{Name: "files"}, {Name: "groups"}}))
+	r := &x.WriteRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterWriteRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	bulkDelete := func(t *testing.T, q url.Values) (int, []byte) {
+		resp, err := ts.Client().Post(ts.URL+relationtuple.BulkDeleteRoute+"?"+q.Encode(), "", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, body
+	}
+
+	count := func(t *testing.T, nspace string) int {
+		n, _, err := reg.RelationTupleManager().CountRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &nspace}, false)
+		require.NoError(t, err)
+		return n
+	}
+
+	tuples := make([]*ketoapi.RelationTuple, 25)
+	for i := range tuples {
+		tuples[i] = &ketoapi.RelationTuple{Namespace: "files", Object: fmt.Sprintf("f%d", i), Relation: "viewer", SubjectID: x.Ptr("alice")}
+	}
+	relationtuple.MapAndWriteTuples(t, reg, tuples...)
+	relationtuple.MapAndWriteTuples(t, reg, &ketoapi.RelationTuple{Namespace: "groups", Object: "g", Relation: "member", SubjectID: x.Ptr("alice")})
+
+	t.Run("case=resumes with the page token", func(t *testing.T) {
+		q := url.Values{"namespace": {"files"}, "batch_size": {"10"}, "max_duration": {"0s"}}
+		var responses []ketoapi.BulkDeleteResponse
+		for {
+			status, body := bulkDelete(t, q)
+			require.Equal(t, http.StatusOK, status, "%s", body)
+
+			var res ketoapi.BulkDeleteResponse
+			require.NoError(t, json.Unmarshal(body, &res))
+			responses = append(responses, res)
+			if res.NextPageToken == "" {
+				break
+			}
+			q.Set("page_token", res.NextPageToken)
+		}
+
+		require.Len(t, responses, 3)
+		for i, expected := range []int{10, 10, 5} {
+			assert.Equal(t, expected, responses[i].Deleted)
+		}
+		assert.Equal(t, 25, responses[2].TotalDeleted)
+		assert.Zero(t, count(t, "files"))
+		assert.Equal(t, 1, count(t, "groups"))
+	})
+
+	t.Run("case=deletes everything within the max duration", func(t *testing.T) {
+		status, body := bulkDelete(t, url.Values{"namespace": {"groups"}})
+		require.Equal(t, http.StatusOK, status, "%s", body)
+
+		var res ketoapi.BulkDeleteResponse
+		require.NoError(t, json.Unmarshal(body, &res))
+		assert.Equal(t, ketoapi.BulkDeleteResponse{Deleted: 1, TotalDeleted: 1}, res)
+		assert.Zero(t, count(t, "groups"))
+	})
+
+	t.Run("case=rejects malformed parameters", func(t *testing.T) {
+		for _, q := range []url.Values{
+			{"page_token": {"not a token"}},
+			{"batch_size": {"0"}},
+			{"max_duration": {"forever"}},
+		} {
+			status, body := bulkDelete(t, q)
+			assert.Equal(t, http.StatusBadRequest, status, "%s", body)
+		}
+	})
+}
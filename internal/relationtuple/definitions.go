@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -16,12 +17,102 @@ type (
 	ManagerProvider interface {
 		RelationTupleManager() Manager
 	}
+	// Transactor is implemented by the relation tuple managers that can run
+	// several calls in one transaction.
+	Transactor interface {
+		// InTransaction runs f in one transaction, which all calls of the
+		// manager with the context of f are part of.
+		InTransaction(ctx context.Context, f func(ctx context.Context) error) error
+	}
+	// TransactionLimiter is implemented by the relation tuple managers that
+	// reject writes of more than a number of relation tuples.
+	TransactionLimiter interface {
+		// MaxTransactionSize returns how many relation tuples one write can
+		// change at most.
+		MaxTransactionSize() int
+	}
 	Manager interface {
 		GetRelationTuples(ctx context.Context, query *RelationQuery, options ...x.PaginationOptionSetter) ([]*RelationTuple, string, error)
 		WriteRelationTuples(ctx context.Context, rs ...*RelationTuple) error
 		DeleteRelationTuples(ctx context.Context, rs ...*RelationTuple) error
 		DeleteAllRelationTuples(ctx context.Context, query *RelationQuery) error
+		// DeleteRelationTuplesPage deletes one page of the relation tuples
+		// matching the query and returns how many were deleted. The returned
+		// token continues with the next page, it is empty after the last page.
+		DeleteRelationTuplesPage(ctx context.Context, query *RelationQuery, options ...x.PaginationOptionSetter) (deleted int, nextPageToken string, err error)
 		TransactRelationTuples(ctx context.Context, insert []*RelationTuple, delete []*RelationTuple) error
+		// TouchRelationTuples writes the relation tuples like
+		// WriteRelationTuples, but only updates the commit time of relation
+		// tuples that already exist instead of writing them again.
+		TouchRelationTuples(ctx context.Context, rs ...*RelationTuple) error
+		// WalkRelationTuples calls f for every page of relation tuples matching
+		// the query. All pages are read from the same consistent snapshot.
+		WalkRelationTuples(ctx context.Context, query *RelationQuery, f func(ctx context.Context, page []*RelationTuple) error) error
+		// CountRelationTuples returns the number of relation tuples matching
+		// the query. If estimate is set, the persister may return a cheaper
+		// estimate instead, which is reported through the returned bool.
+		CountRelationTuples(ctx context.Context, query *RelationQuery, estimate bool) (count int, estimated bool, err error)
+	}
+	HistoryManagerProvider interface {
+		RelationTupleHistoryManager() HistoryManager
+	}
+	HistoryManager interface {
+		// GetRelationTupleHistory returns the recorded inserts and deletes of
+		// relation tuples matching the query, oldest first.
+		GetRelationTupleHistory(ctx context.Context, query *HistoryQuery, options ...x.PaginationOptionSetter) ([]*HistoryEntry, string, error)
+		// LatestRelationTupleHistoryID returns the ID of the latest recorded
+		// change, or 0 if none was recorded. Formatted as decimal, an ID is the
+		// page token that continues the history after that change.
+		LatestRelationTupleHistoryID(ctx context.Context) (int64, error)
+	}
+	SoftDeleteManagerProvider interface {
+		RelationTupleSoftDeleteManager() SoftDeleteManager
+	}
+	SoftDeleteManager interface {
+		// RestoreRelationTuples restores the soft deleted relation tuples
+		// matching the query that were deleted at or after deletedSince and
+		// are still within their namespace's retention window. It returns the
+		// number of restored relation tuples. Before anything is restored,
+		// allow is called with the number of relation tuples that are restored
+		// in every namespace, and the restore is aborted if it fails.
+		RestoreRelationTuples(ctx context.Context, query *RelationQuery, deletedSince time.Time, allow func(ctx context.Context, restored map[string]int) error) (int, error)
+		// PurgeDeletedRelationTuples permanently deletes all soft deleted
+		// relation tuples that are past their retention window.
+		PurgeDeletedRelationTuples(ctx context.Context) (int, error)
+	}
+	ClosureManagerProvider interface {
+		// RelationTupleClosureManager returns nil if the relation tuples are
+		// stored without a closure.
+		RelationTupleClosureManager() ClosureManager
+	}
+	// ClosureManager maintains the transitive closure of the relations that
+	// are configured for it. The closure of an object's relation contains all
+	// subject IDs that are reachable through relation tuples of closure
+	// relations, and the subject sets of other relations where the search
+	// stops.
+	ClosureManager interface {
+		// HasClosure returns whether the relation of the namespace is closed.
+		HasClosure(ctx context.Context, namespace, relation string) (bool, error)
+		// CheckClosure returns whether the subject ID of the relation tuple is
+		// in the closure of its relation. Otherwise, members of the returned
+		// subject sets are also members of the relation.
+		CheckClosure(ctx context.Context, r *RelationTuple) (isMember bool, open []*SubjectSet, err error)
+		// RebuildClosure recomputes the closure of all closure relations and
+		// returns the number of entries.
+		RebuildClosure(ctx context.Context) (int, error)
+	}
+	HistoryQuery struct {
+		RelationQuery
+		// Since and Until limit the changes to the given time range. The zero
+		// value means no limit.
+		Since, Until time.Time
+	}
+	HistoryEntry struct {
+		// ID increases with every recorded change.
+		ID     int64
+		Action ketoapi.PatchAction
+		Tuple  *RelationTuple
+		Time   time.Time
 	}
 	SubjectID struct {
 		ID uuid.UUID `json:"id"`
@@ -164,6 +255,22 @@ func (t *ManagerWrapper) TransactRelationTuples(ctx context.Context, insert []*R
 	return t.Reg.RelationTupleManager().TransactRelationTuples(ctx, insert, delete)
 }
 
+func (t *ManagerWrapper) DeleteRelationTuplesPage(ctx context.Context, query *RelationQuery, options ...x.PaginationOptionSetter) (int, string, error) {
+	return t.Reg.RelationTupleManager().DeleteRelationTuplesPage(ctx, query, options...)
+}
+
+func (t *ManagerWrapper) TouchRelationTuples(ctx context.Context, rs ...*RelationTuple) error {
+	return t.Reg.RelationTupleManager().TouchRelationTuples(ctx, rs...)
+}
+
+func (t *ManagerWrapper) WalkRelationTuples(ctx context.Context, query *RelationQuery, f func(ctx context.Context, page []*RelationTuple) error) error {
+	return t.Reg.RelationTupleManager().WalkRelationTuples(ctx, query, f)
+}
+
+func (t *ManagerWrapper) CountRelationTuples(ctx context.Context, query *RelationQuery, estimate bool) (int, bool, error) {
+	return t.Reg.RelationTupleManager().CountRelationTuples(ctx, query, estimate)
+}
+
 func (t *ManagerWrapper) RelationTupleManager() Manager {
 	return t
 }
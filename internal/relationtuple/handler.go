@@ -1,3 +1,5 @@
+//go:build !js
+
 package relationtuple
 
 import (
@@ -5,15 +7,25 @@ import (
 
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 
+	"github.com/ory/keto/internal/audit"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/quota"
 	"github.com/ory/keto/internal/x"
 )
 
 type (
 	handlerDeps interface {
+		audit.LoggerProvider
+		quota.EnforcerProvider
 		ManagerProvider
+		HistoryManagerProvider
+		SoftDeleteManagerProvider
+		SchemaMigrationManagerProvider
+		NamespaceRenameManagerProvider
 		MapperProvider
 		x.LoggerProvider
 		x.WriterProvider
+		config.Provider
 	}
 	handler struct {
 		d handlerDeps
@@ -23,6 +35,7 @@ type (
 const (
 	ReadRouteBase  = "/relation-tuples"
 	WriteRouteBase = "/admin/relation-tuples"
+	CountRoute     = ReadRouteBase + "/count"
 )
 
 func NewHandler(d handlerDeps) *handler {
@@ -33,12 +46,29 @@ func NewHandler(d handlerDeps) *handler {
 
 func (h *handler) RegisterReadRoutes(r *x.ReadRouter) {
 	r.GET(ReadRouteBase, h.getRelations)
+	r.GET(CountRoute, h.countRelations)
 }
 
 func (h *handler) RegisterWriteRoutes(r *x.WriteRouter) {
 	r.PUT(WriteRouteBase, h.createRelation)
 	r.DELETE(WriteRouteBase, h.deleteRelations)
 	r.PATCH(WriteRouteBase, h.patchRelationTuples)
+	r.GET(HistoryRoute, h.getHistory)
+	r.GET(WatchRoute, h.watchRelationTuples)
+	r.POST(RestoreRoute, h.restoreRelationTuples)
+	r.POST(BulkDeleteRoute, h.bulkDeleteRelationTuples)
+}
+
+func (h *handler) RegisterAdminRoutes(r *x.AdminRouter) {
+	r.GET(SnapshotRoute, h.exportSnapshot)
+	r.PUT(SnapshotRoute, h.importSnapshot)
+	r.POST(SchemaMigrationPlanRoute, h.planSchemaMigration)
+	r.POST(SchemaMigrationApplyRoute, h.applySchemaMigration)
+	r.GET(SchemaVersionsRoute, h.listSchemaVersions)
+	r.GET(NamespaceRenameRouteBase, h.listNamespaceRenames)
+	r.POST(NamespaceRenameRouteBase, h.startNamespaceRename)
+	r.POST(NamespaceRenameBatchRoute, h.renameNamespaceBatch)
+	r.POST(NamespaceRenameFinishRoute, h.finishNamespaceRename)
 }
 
 func (h *handler) RegisterReadGRPC(s *grpc.Server) {
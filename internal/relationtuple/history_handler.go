@@ -0,0 +1,131 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+const HistoryRoute = WriteRouteBase + "/history"
+
+// swagger:route GET /admin/relation-tuples/history write getRelationTupleHistory
+//
+// # Query the Relation Tuple History
+//
+// Use this endpoint to list the recorded inserts and deletes of relation tuples,
+// oldest first. The history has to be enabled with `history.enabled`. Changes
+// can be filtered by the relation tuple fields and a time range, which allows
+// reconstructing the relation tuples at any point in the past.
+//
+//	Consumes:
+//	-  application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: getRelationTupleHistoryResponse
+//	  400: genericError
+//	  404: genericError
+//	  500: genericError
+func (h *handler) getHistory(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	if !h.d.Config(ctx).HistoryEnabled() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The relation tuple history is not enabled.")))
+		return
+	}
+
+	q := r.URL.Query()
+	query, err := (&ketoapi.RelationQuery{}).FromURLQuery(q)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
+		return
+	}
+
+	hq := &HistoryQuery{}
+	for key, t := range map[string]*time.Time{"since": &hq.Since, "until": &hq.Until} {
+		if raw := q.Get(key); raw != "" {
+			if *t, err = time.Parse(time.RFC3339, raw); err != nil {
+				h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not parse %s: %s", key, err)))
+				return
+			}
+		}
+	}
+
+	var paginationOpts []x.PaginationOptionSetter
+	if pageToken := q.Get("page_token"); pageToken != "" {
+		paginationOpts = append(paginationOpts, x.WithToken(pageToken))
+	}
+	var pageSize int64
+	if raw := q.Get("page_size"); raw != "" {
+		pageSize, err = strconv.ParseInt(raw, 0, 0)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
+			return
+		}
+	}
+	paginationOpts = append(paginationOpts, x.WithSize(h.d.Config(ctx).PageSize(int(pageSize))))
+
+	iq, err := h.d.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	hq.RelationQuery = *iq
+
+	entries, nextPage, err := h.d.RelationTupleHistoryManager().GetRelationTupleHistory(ctx, hq, paginationOpts...)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	changes, err := h.historyChanges(ctx, entries)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &ketoapi.GetHistoryResponse{
+		Changes:       changes,
+		NextPageToken: nextPage,
+	})
+}
+
+// historyChanges maps the history entries to the relation tuple changes of the
+// API.
+func (h *handler) historyChanges(ctx context.Context, entries []*HistoryEntry) ([]*ketoapi.RelationTupleChange, error) {
+	its := make([]*RelationTuple, len(entries))
+	for i, e := range entries {
+		its[i] = e.Tuple
+	}
+	tuples, err := h.d.Mapper().ToTuple(ctx, its...)
+	if err != nil {
+		return nil, err
+	}
+	if len(tuples) != len(entries) {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("expected %d mapped relation tuples, got %d", len(entries), len(tuples)))
+	}
+
+	changes := make([]*ketoapi.RelationTupleChange, len(entries))
+	for i, e := range entries {
+		changes[i] = &ketoapi.RelationTupleChange{
+			Action:        e.Action,
+			RelationTuple: tuples[i],
+			Time:          e.Time,
+		}
+	}
+	return changes, nil
+}
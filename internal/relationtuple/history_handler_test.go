@@ -0,0 +1,114 @@
+package relationtuple_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	ctx := context.Background()
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{{Name: "files"}}))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, true))
+	r := &x.WriteRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterWriteRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	getHistory := func(t *testing.T, q url.Values) *ketoapi.GetHistoryResponse {
+		resp, err := ts.Client().Get(ts.URL + relationtuple.HistoryRoute + "?" + q.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var res ketoapi.GetHistoryResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return &res
+	}
+
+	alice := &ketoapi.RelationTuple{Namespace: "files", Object: "a", Relation: "viewer", SubjectID: x.Ptr("alice")}
+	bob := &ketoapi.RelationTuple{Namespace: "files", Object: "b", Relation: "viewer", SubjectID: x.Ptr("bob")}
+	its, err := reg.Mapper().FromTuple(ctx, alice, bob)
+	require.NoError(t, err)
+
+	require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, its...))
+	afterInsert := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, reg.RelationTupleManager().DeleteRelationTuples(ctx, its[0]))
+
+	t.Run("case=all changes in order", func(t *testing.T) {
+		res := getHistory(t, url.Values{})
+		require.Len(t, res.Changes, 3)
+		assert.Equal(t, ketoapi.ActionInsert, res.Changes[0].Action)
+		assert.Equal(t, alice, res.Changes[0].RelationTuple)
+		assert.Equal(t, ketoapi.ActionInsert, res.Changes[1].Action)
+		assert.Equal(t, bob, res.Changes[1].RelationTuple)
+		assert.Equal(t, ketoapi.ActionDelete, res.Changes[2].Action)
+		assert.Equal(t, alice, res.Changes[2].RelationTuple)
+		assert.Empty(t, res.NextPageToken)
+	})
+
+	t.Run("case=filter by object", func(t *testing.T) {
+		res := getHistory(t, url.Values{"namespace": {"files"}, "object": {"a"}})
+		require.Len(t, res.Changes, 2)
+		for _, c := range res.Changes {
+			assert.Equal(t, alice, c.RelationTuple)
+		}
+	})
+
+	t.Run("case=filter by subject", func(t *testing.T) {
+		res := getHistory(t, url.Values{"subject_id": {"bob"}})
+		require.Len(t, res.Changes, 1)
+		assert.Equal(t, bob, res.Changes[0].RelationTuple)
+	})
+
+	t.Run("case=filter by time range", func(t *testing.T) {
+		res := getHistory(t, url.Values{"since": {afterInsert.Format(time.RFC3339Nano)}})
+		require.Len(t, res.Changes, 1)
+		assert.Equal(t, ketoapi.ActionDelete, res.Changes[0].Action)
+
+		res = getHistory(t, url.Values{"until": {afterInsert.Format(time.RFC3339Nano)}})
+		assert.Len(t, res.Changes, 2)
+	})
+
+	t.Run("case=paginates", func(t *testing.T) {
+		var changes []*ketoapi.RelationTupleChange
+		q := url.Values{"page_size": {"2"}}
+		for {
+			res := getHistory(t, q)
+			changes = append(changes, res.Changes...)
+			if res.NextPageToken == "" {
+				break
+			}
+			q.Set("page_token", res.NextPageToken)
+		}
+		assert.Len(t, changes, 3)
+	})
+
+	t.Run("case=disabled", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, false))
+		t.Cleanup(func() { _ = reg.Config(ctx).Set(config.KeyHistoryEnabled, true) })
+
+		resp, err := ts.Client().Get(ts.URL + relationtuple.HistoryRoute)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
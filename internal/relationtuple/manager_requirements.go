@@ -52,6 +52,25 @@ func ManagerTest(t *testing.T, m Manager) {
 			assert.Equal(t, "", nextPage)
 			assert.ElementsMatch(t, tuples, resp)
 		})
+
+		t.Run("case=stores existing tuples once", func(t *testing.T) {
+			nspace := strconv.Itoa(rand.Int()) // nolint
+
+			tuple := &RelationTuple{
+				Namespace: nspace,
+				Object:    uuid.Must(uuid.NewV4()),
+				Relation:  "rel",
+				Subject:   &SubjectID{ID: uuid.Must(uuid.NewV4())},
+			}
+			require.NoError(t, m.WriteRelationTuples(ctx, tuple, tuple))
+			require.NoError(t, m.WriteRelationTuples(ctx, tuple))
+
+			resp, _, err := m.GetRelationTuples(ctx, &RelationQuery{
+				Namespace: x.Ptr(nspace),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []*RelationTuple{tuple}, resp)
+		})
 	})
 
 	t.Run("method=Get", func(t *testing.T) {
@@ -361,10 +380,12 @@ func ManagerTest(t *testing.T, m Manager) {
 				sIDs[i] = uuid.Must(uuid.NewV4())
 			}
 
+			// All relation tuples share the object, as sharded managers only
+			// support transactions within one shard.
 			for i := range rs {
 				rs[i] = &RelationTuple{
 					Namespace: nspace,
-					Object:    oIDs[i],
+					Object:    oIDs[0],
 					Relation:  "r" + strconv.Itoa(i),
 					Subject:   &SubjectID{ID: sIDs[i]},
 				}
@@ -441,4 +462,159 @@ func ManagerTest(t *testing.T, m Manager) {
 			assert.Equal(t, []*RelationTuple{rs[0]}, res)
 		})
 	})
+
+	t.Run("method=Walk", func(t *testing.T) {
+		t.Run("case=visits all pages", func(t *testing.T) {
+			nspace := strconv.Itoa(rand.Int()) // nolint
+
+			rs := make([]*RelationTuple, 2500)
+			for i := range rs {
+				rs[i] = &RelationTuple{
+					Namespace: nspace,
+					Object:    uuid.Must(uuid.NewV4()),
+					Relation:  "r",
+					Subject:   &SubjectID{ID: uuid.Must(uuid.NewV4())},
+				}
+			}
+			batch := len(rs)
+			if l, ok := m.(TransactionLimiter); ok {
+				batch = l.MaxTransactionSize()
+			}
+			for i := 0; i < len(rs); i += batch {
+				end := i + batch
+				if end > len(rs) {
+					end = len(rs)
+				}
+				require.NoError(t, m.WriteRelationTuples(ctx, rs[i:end]...))
+			}
+
+			var walked []*RelationTuple
+			require.NoError(t, m.WalkRelationTuples(ctx, &RelationQuery{Namespace: &nspace}, func(_ context.Context, page []*RelationTuple) error {
+				assert.NotEmpty(t, page)
+				walked = append(walked, page...)
+				return nil
+			}))
+			assert.ElementsMatch(t, rs, walked)
+		})
+
+		t.Run("case=stops on error", func(t *testing.T) {
+			nspace := strconv.Itoa(rand.Int()) // nolint
+
+			require.NoError(t, m.WriteRelationTuples(ctx, &RelationTuple{
+				Namespace: nspace,
+				Object:    uuid.Must(uuid.NewV4()),
+				Relation:  "r",
+				Subject:   &SubjectID{ID: uuid.Must(uuid.NewV4())},
+			}))
+
+			expectedErr := fmt.Errorf("stop walking")
+			assert.ErrorIs(t, m.WalkRelationTuples(ctx, &RelationQuery{Namespace: &nspace}, func(context.Context, []*RelationTuple) error {
+				return expectedErr
+			}), expectedErr)
+		})
+	})
+
+	t.Run("method=Count", func(t *testing.T) {
+		nspace := strconv.Itoa(rand.Int()) // nolint
+
+		rs := make([]*RelationTuple, 7)
+		obj := uuid.Must(uuid.NewV4())
+		for i := range rs {
+			rs[i] = &RelationTuple{
+				Namespace: nspace,
+				Object:    obj,
+				Relation:  strconv.Itoa(i % 2),
+				Subject:   &SubjectID{ID: uuid.Must(uuid.NewV4())},
+			}
+		}
+		require.NoError(t, m.WriteRelationTuples(ctx, rs...))
+
+		for _, tc := range []struct {
+			name     string
+			query    *RelationQuery
+			expected int
+		}{
+			{name: "namespace", query: &RelationQuery{Namespace: &nspace}, expected: 7},
+			{name: "relation", query: &RelationQuery{Namespace: &nspace, Relation: x.Ptr("0")}, expected: 4},
+			{name: "subject", query: &RelationQuery{Namespace: &nspace, Subject: rs[2].Subject}, expected: 1},
+			{name: "no match", query: &RelationQuery{Namespace: x.Ptr(nspace + "other")}, expected: 0},
+		} {
+			t.Run("case="+tc.name, func(t *testing.T) {
+				n, estimated, err := m.CountRelationTuples(ctx, tc.query, false)
+				require.NoError(t, err)
+				assert.False(t, estimated)
+				assert.Equal(t, tc.expected, n)
+			})
+		}
+
+		t.Run("case=estimate", func(t *testing.T) {
+			n, estimated, err := m.CountRelationTuples(ctx, &RelationQuery{Namespace: &nspace}, true)
+			require.NoError(t, err)
+			if !estimated {
+				assert.Equal(t, 7, n)
+			}
+		})
+	})
+
+	t.Run("method=Touch", func(t *testing.T) {
+		nspace := strconv.Itoa(rand.Int()) // nolint
+
+		rs := []*RelationTuple{{
+			Namespace: nspace,
+			Object:    uuid.Must(uuid.NewV4()),
+			Relation:  "r",
+			Subject:   &SubjectID{ID: uuid.Must(uuid.NewV4())},
+		}, {
+			Namespace: nspace,
+			Object:    uuid.Must(uuid.NewV4()),
+			Relation:  "r",
+			Subject:   &SubjectSet{Namespace: nspace, Object: uuid.Must(uuid.NewV4()), Relation: "r"},
+		}}
+		require.NoError(t, m.WriteRelationTuples(ctx, rs[0]))
+
+		require.NoError(t, m.TouchRelationTuples(ctx, rs...))
+		require.NoError(t, m.TouchRelationTuples(ctx, rs...))
+
+		res, _, err := m.GetRelationTuples(ctx, &RelationQuery{Namespace: &nspace})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, rs, res)
+
+		t.Run("case=rejects nil subject", func(t *testing.T) {
+			assert.ErrorIs(t, m.TouchRelationTuples(ctx, &RelationTuple{Namespace: nspace, Relation: "r"}), ketoapi.ErrNilSubject)
+		})
+	})
+
+	t.Run("method=DeleteRelationTuplesPage", func(t *testing.T) {
+		nspace := strconv.Itoa(rand.Int()) // nolint
+
+		rs := make([]*RelationTuple, 12)
+		for i := range rs {
+			rs[i] = &RelationTuple{
+				Namespace: nspace,
+				Object:    uuid.Must(uuid.NewV4()),
+				Relation:  "r",
+				Subject:   &SubjectID{ID: uuid.Must(uuid.NewV4())},
+			}
+		}
+		require.NoError(t, m.WriteRelationTuples(ctx, rs...))
+
+		var (
+			deleted   []int
+			pageToken string
+		)
+		for {
+			n, next, err := m.DeleteRelationTuplesPage(ctx, &RelationQuery{Namespace: &nspace}, x.WithSize(5), x.WithToken(pageToken))
+			require.NoError(t, err)
+			deleted = append(deleted, n)
+			if next == "" {
+				break
+			}
+			pageToken = next
+		}
+		assert.Equal(t, []int{5, 5, 2}, deleted)
+
+		res, _, err := m.GetRelationTuples(ctx, &RelationQuery{Namespace: &nspace})
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	})
 }
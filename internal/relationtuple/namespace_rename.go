@@ -0,0 +1,54 @@
+package relationtuple
+
+import (
+	"context"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	NamespaceRenameManagerProvider interface {
+		NamespaceRenameManager() NamespaceRenameManager
+	}
+	NamespaceRenameManager interface {
+		// StartNamespaceRename records the rename, and renames the managed
+		// namespace definition if there is one. From then on, the old name
+		// is an alias of the new one.
+		StartNamespaceRename(ctx context.Context, from, to string) (*ketoapi.NamespaceRename, error)
+		// RenameNamespaceBatch rewrites up to batchSize relation tuples that
+		// reference the old name. The rename is rewritten once no relation
+		// tuple references the old name anymore.
+		RenameNamespaceBatch(ctx context.Context, from string, batchSize int) (*ketoapi.NamespaceRename, error)
+		// FinishNamespaceRename removes the alias of a rewritten rename.
+		FinishNamespaceRename(ctx context.Context, from string) (*ketoapi.NamespaceRename, error)
+		// ListNamespaceRenames returns all renames, oldest first.
+		ListNamespaceRenames(ctx context.Context) ([]*ketoapi.NamespaceRename, error)
+	}
+)
+
+// ValidateNamespaceRename checks that the rename is well-formed.
+func ValidateNamespaceRename(from, to string) error {
+	if from == "" || to == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithError("the old and the new name of the namespace are required"))
+	}
+	if from == to {
+		return errors.WithStack(herodot.ErrBadRequest.WithErrorf("cannot rename namespace %q to itself", from))
+	}
+	return nil
+}
+
+// RenameNamespaceOf returns rt with all references to the namespace from
+// replaced by to, and whether there was any.
+func RenameNamespaceOf(rt *RelationTuple, from, to string) (*RelationTuple, bool) {
+	renamed := *rt
+	if rt.Namespace == from {
+		renamed.Namespace = to
+	}
+	if ss, ok := rt.Subject.(*SubjectSet); ok && ss.Namespace == from {
+		renamed.Subject = &SubjectSet{Namespace: to, Object: ss.Object, Relation: ss.Relation}
+	}
+	return &renamed, renamed.Namespace != rt.Namespace || renamed.Subject != rt.Subject
+}
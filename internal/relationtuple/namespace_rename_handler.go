@@ -0,0 +1,159 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	NamespaceRenameRouteBase   = "/admin/namespace-renames"
+	NamespaceRenameBatchRoute  = NamespaceRenameRouteBase + "/:from/batch"
+	NamespaceRenameFinishRoute = NamespaceRenameRouteBase + "/:from/finish"
+
+	defaultNamespaceRenameBatchSize = 100
+)
+
+// swagger:route POST /admin/namespace-renames write startNamespaceRename
+//
+// # Start a Namespace Rename
+//
+// Use this endpoint to rename a namespace. A namespace definition managed
+// through the namespace administration API is renamed right away, together
+// with the references to it in the other managed definitions. Namespaces from
+// the configuration have to be declared with the new name first.
+//
+// From then on, the old name is an alias of the new one. Relation tuples are
+// rewritten to the new name in batches by subsequent requests.
+//
+//	Consumes:
+//	-  application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  201: namespaceRename
+//	  400: genericError
+//	  404: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) startNamespaceRename(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body ketoapi.StartNamespaceRename
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+
+	h.d.Logger().
+		WithField("from", body.From).
+		WithField("to", body.To).
+		Debug("starting namespace rename")
+
+	rename, err := h.d.NamespaceRenameManager().StartNamespaceRename(r.Context(), body.From, body.To)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.Writer().WriteCreated(w, r, NamespaceRenameRouteBase, rename)
+}
+
+// swagger:route POST /admin/namespace-renames/{from}/batch write renameNamespaceBatch
+//
+// # Rewrite a Batch of Relation Tuples of a Namespace Rename
+//
+// Use this endpoint to rewrite the next batch of relation tuples that still
+// reference the old name of the namespace. Once there are none left, the
+// rename is in the state "rewritten".
+//
+//	Consumes:
+//	-  application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: namespaceRename
+//	  400: genericError
+//	  404: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) renameNamespaceBatch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	body := ketoapi.NamespaceRenameBatch{BatchSize: defaultNamespaceRenameBatchSize}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+			return
+		}
+	}
+	if body.BatchSize <= 0 {
+		body.BatchSize = defaultNamespaceRenameBatchSize
+	}
+
+	rename, err := h.d.NamespaceRenameManager().RenameNamespaceBatch(r.Context(), ps.ByName("from"), body.BatchSize)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.Writer().Write(w, r, rename)
+}
+
+// swagger:route POST /admin/namespace-renames/{from}/finish write finishNamespaceRename
+//
+// # Finish a Namespace Rename
+//
+// Use this endpoint to remove the alias of a rewritten namespace rename, once
+// all clients use the new name. Afterwards, the old name is not known anymore.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: namespaceRename
+//	  404: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) finishNamespaceRename(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rename, err := h.d.NamespaceRenameManager().FinishNamespaceRename(r.Context(), ps.ByName("from"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.Writer().Write(w, r, rename)
+}
+
+// swagger:route GET /admin/namespace-renames write listNamespaceRenames
+//
+// # List Namespace Renames
+//
+// Use this endpoint to get the progress of all namespace renames.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listNamespaceRenamesResponse
+//	  500: genericError
+func (h *handler) listNamespaceRenames(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	renames, err := h.d.NamespaceRenameManager().ListNamespaceRenames(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.Writer().Write(w, r, &ketoapi.ListNamespaceRenamesResponse{Renames: renames})
+}
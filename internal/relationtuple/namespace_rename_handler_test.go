@@ -0,0 +1,155 @@
+package relationtuple_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestNamespaceRenames(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{
+		{Name: "Document"}, {Name: "Doc"}, {Name: "Group"}, {Name: "Team"},
+	}))
+	r := &x.AdminRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterAdminRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	post := func(t *testing.T, route string, body interface{}) (int, *ketoapi.NamespaceRename, []byte) {
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		resp, err := ts.Client().Post(ts.URL+route, "application/json", bytes.NewReader(raw))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		resBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var res ketoapi.NamespaceRename
+		_ = json.Unmarshal(resBody, &res)
+		return resp.StatusCode, &res, resBody
+	}
+	route := func(r, from string) string {
+		return strings.Replace(r, ":from", from, 1)
+	}
+	list := func(t *testing.T, nspace string) []string {
+		iq, err := reg.Mapper().FromQuery(ctx, &ketoapi.RelationQuery{Namespace: &nspace})
+		require.NoError(t, err)
+		its, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, iq)
+		require.NoError(t, err)
+		tuples, err := reg.Mapper().ToTuple(ctx, its...)
+		require.NoError(t, err)
+		res := make([]string, len(tuples))
+		for i, rt := range tuples {
+			res[i] = rt.String()
+		}
+		return res
+	}
+
+	relationtuple.MapAndWriteTuples(t, reg,
+		&ketoapi.RelationTuple{Namespace: "Document", Object: "d", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "g", Relation: "members"}},
+		&ketoapi.RelationTuple{Namespace: "Group", Object: "g", Relation: "members", SubjectID: x.Ptr("alice")},
+		&ketoapi.RelationTuple{Namespace: "Group", Object: "g", Relation: "members", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "h", Relation: "members"}},
+		&ketoapi.RelationTuple{Namespace: "Group", Object: "h", Relation: "members", SubjectID: x.Ptr("bob")},
+	)
+
+	t.Run("case=batches wait for the refresh interval", func(t *testing.T) {
+		code, rename, body := post(t, relationtuple.NamespaceRenameRouteBase, &ketoapi.StartNamespaceRename{From: "Document", To: "Doc"})
+		require.Equal(t, http.StatusCreated, code, "%s", body)
+		assert.Equal(t, ketoapi.NamespaceRenameRewriting, rename.State)
+		assert.False(t, rename.DefinitionRenamed)
+		assert.True(t, rename.BatchesAllowedAt.After(rename.CreatedAt))
+
+		code, _, body = post(t, route(relationtuple.NamespaceRenameBatchRoute, "Document"), &ketoapi.NamespaceRenameBatch{})
+		assert.Equal(t, http.StatusConflict, code, "%s", body)
+
+		t.Run("case=both names resolve to the relation tuples", func(t *testing.T) {
+			expected := []string{"Document:d#viewers@(Group:g#members)"}
+			assert.Equal(t, expected, list(t, "Document"))
+			assert.Equal(t, expected, list(t, "Doc"))
+		})
+	})
+
+	require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaceAPIRefreshInterval, "0s"))
+
+	t.Run("case=rewrites relation tuples and subject sets in batches", func(t *testing.T) {
+		code, _, body := post(t, relationtuple.NamespaceRenameRouteBase, &ketoapi.StartNamespaceRename{From: "Group", To: "Team"})
+		require.Equal(t, http.StatusCreated, code, "%s", body)
+
+		relationtuple.MapAndWriteTuples(t, reg,
+			&ketoapi.RelationTuple{Namespace: "Group", Object: "h", Relation: "members", SubjectID: x.Ptr("carol")},
+		)
+		assert.Contains(t, list(t, "Group"), "Team:h#members@carol", "writes with the old name use the new one")
+
+		code, rename, body := post(t, route(relationtuple.NamespaceRenameBatchRoute, "Group"), &ketoapi.NamespaceRenameBatch{BatchSize: 2})
+		require.Equal(t, http.StatusOK, code, "%s", body)
+		assert.Equal(t, ketoapi.NamespaceRenameRewriting, rename.State)
+		assert.Equal(t, 2, rename.Rewritten)
+
+		code, _, body = post(t, route(relationtuple.NamespaceRenameFinishRoute, "Group"), nil)
+		assert.Equal(t, http.StatusConflict, code, "%s", body)
+
+		for rename.State == ketoapi.NamespaceRenameRewriting {
+			code, rename, body = post(t, route(relationtuple.NamespaceRenameBatchRoute, "Group"), &ketoapi.NamespaceRenameBatch{BatchSize: 2})
+			require.Equal(t, http.StatusOK, code, "%s", body)
+		}
+		assert.Equal(t, 4, rename.Rewritten)
+
+		assert.ElementsMatch(t, []string{
+			"Team:g#members@alice",
+			"Team:g#members@(Team:h#members)",
+			"Team:h#members@bob",
+			"Team:h#members@carol",
+		}, list(t, "Team"))
+		// The batch writes with the new names of all namespaces that are being renamed.
+		assert.Equal(t, []string{"Doc:d#viewers@(Team:g#members)"}, list(t, "Document"))
+
+		code, rename, body = post(t, route(relationtuple.NamespaceRenameFinishRoute, "Group"), nil)
+		require.Equal(t, http.StatusOK, code, "%s", body)
+		assert.Equal(t, ketoapi.NamespaceRenameCompleted, rename.State)
+
+		assert.Equal(t, []string{"Doc:d#viewers@(Team:g#members)"}, list(t, "Doc"))
+	})
+
+	t.Run("case=conflicts with active renames", func(t *testing.T) {
+		code, _, body := post(t, relationtuple.NamespaceRenameRouteBase, &ketoapi.StartNamespaceRename{From: "Doc", To: "Group"})
+		assert.Equal(t, http.StatusConflict, code, "%s", body)
+	})
+
+	t.Run("case=unknown rename", func(t *testing.T) {
+		code, _, body := post(t, route(relationtuple.NamespaceRenameBatchRoute, "Unknown"), nil)
+		assert.Equal(t, http.StatusNotFound, code, "%s", body)
+	})
+
+	t.Run("case=undeclared target namespace", func(t *testing.T) {
+		code, _, body := post(t, relationtuple.NamespaceRenameRouteBase, &ketoapi.StartNamespaceRename{From: "Team", To: "Unknown"})
+		assert.Equal(t, http.StatusBadRequest, code, "%s", body)
+	})
+
+	t.Run("case=lists renames", func(t *testing.T) {
+		resp, err := ts.Client().Get(ts.URL + relationtuple.NamespaceRenameRouteBase)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var res ketoapi.ListNamespaceRenamesResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		require.Len(t, res.Renames, 2)
+		assert.Equal(t, "Document", res.Renames[0].From)
+		assert.Equal(t, ketoapi.NamespaceRenameCompleted, res.Renames[1].State)
+	})
+}
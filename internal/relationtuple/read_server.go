@@ -1,3 +1,5 @@
+//go:build !js
+
 package relationtuple
 
 import (
@@ -74,12 +76,20 @@ func (h *handler) ListRelationTuples(ctx context.Context, req *rts.ListRelationT
 		return nil, herodot.ErrBadRequest.WithError("you must provide a query")
 	}
 
+	if err := x.ValidateProtoFieldMask(req.ExpandMask, &rts.RelationTuple{}); err != nil {
+		return nil, err
+	}
+
+	if req.Snaptoken != "" {
+		ctx = WithSnaptoken(ctx, req.Snaptoken)
+	}
+
 	iq, err := h.d.Mapper().FromQuery(ctx, &q)
 	if err != nil {
 		return nil, err
 	}
 	ir, nextPage, err := h.d.RelationTupleManager().GetRelationTuples(ctx, iq,
-		x.WithSize(int(req.PageSize)),
+		x.WithSize(h.d.Config(ctx).PageSize(int(req.PageSize))),
 		x.WithToken(req.PageToken),
 	)
 	if err != nil {
@@ -96,11 +106,66 @@ func (h *handler) ListRelationTuples(ctx context.Context, req *rts.ListRelationT
 	}
 	for i, r := range relations {
 		resp.RelationTuples[i] = r.ToProto()
+		x.ApplyProtoFieldMask(req.ExpandMask, resp.RelationTuples[i])
 	}
 
 	return resp, nil
 }
 
+// StreamRelationTuples sends the relation tuples page by page. As sending
+// blocks while the flow control window of the client is full, the next page is
+// only read once the client received the previous one.
+func (h *handler) StreamRelationTuples(req *rts.StreamRelationTuplesRequest, stream rts.ReadService_StreamRelationTuplesServer) error {
+	ctx := stream.Context()
+
+	if req.RelationQuery == nil {
+		return herodot.ErrBadRequest.WithError("you must provide a query")
+	}
+	var q ketoapi.RelationQuery
+	q.FromDataProvider(&queryWrapper{req.RelationQuery})
+
+	if err := x.ValidateProtoFieldMask(req.ExpandMask, &rts.RelationTuple{}); err != nil {
+		return err
+	}
+
+	iq, err := h.d.Mapper().FromQuery(ctx, &q)
+	if err != nil {
+		return err
+	}
+
+	pageToken := req.PageToken
+	for {
+		ir, nextPage, err := h.d.RelationTupleManager().GetRelationTuples(ctx, iq,
+			x.WithSize(h.d.Config(ctx).PageSize(int(req.BatchSize))),
+			x.WithToken(pageToken),
+		)
+		if err != nil {
+			return err
+		}
+		relations, err := h.d.Mapper().ToTuple(ctx, ir...)
+		if err != nil {
+			return err
+		}
+
+		resp := &rts.StreamRelationTuplesResponse{
+			RelationTuples: make([]*rts.RelationTuple, len(relations)),
+			NextPageToken:  nextPage,
+		}
+		for i, r := range relations {
+			resp.RelationTuples[i] = r.ToProto()
+			x.ApplyProtoFieldMask(req.ExpandMask, resp.RelationTuples[i])
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+
+		if nextPage == "" {
+			return nil
+		}
+		pageToken = nextPage
+	}
+}
+
 // swagger:route GET /relation-tuples read getRelationTuples
 //
 // # Query relation tuples
@@ -129,6 +194,12 @@ func (h *handler) getRelations(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
+	mask, err := x.FieldMaskFromURLQuery(q, ketoapi.RelationTuple{})
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	l := h.d.Logger()
 	for k := range q {
 		l = l.WithField(k, q.Get(k))
@@ -140,14 +211,15 @@ func (h *handler) getRelations(w http.ResponseWriter, r *http.Request, _ httprou
 		paginationOpts = append(paginationOpts, x.WithToken(pageToken))
 	}
 
-	if pageSize := q.Get("page_size"); pageSize != "" {
-		s, err := strconv.ParseInt(pageSize, 0, 0)
+	var pageSize int64
+	if raw := q.Get("page_size"); raw != "" {
+		pageSize, err = strconv.ParseInt(raw, 0, 0)
 		if err != nil {
 			h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
 			return
 		}
-		paginationOpts = append(paginationOpts, x.WithSize(int(s)))
 	}
+	paginationOpts = append(paginationOpts, x.WithSize(h.d.Config(ctx).PageSize(int(pageSize))))
 
 	iq, err := h.d.Mapper().FromQuery(ctx, query)
 	if err != nil {
@@ -166,6 +238,21 @@ func (h *handler) getRelations(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
+	if mask != nil {
+		tuples := make([]interface{}, len(relations))
+		for i, rt := range relations {
+			if tuples[i], err = mask.Apply(rt); err != nil {
+				h.d.Writer().WriteError(w, r, err)
+				return
+			}
+		}
+		h.d.Writer().Write(w, r, map[string]interface{}{
+			"relation_tuples": tuples,
+			"next_page_token": nextPage,
+		})
+		return
+	}
+
 	resp := &ketoapi.GetResponse{
 		RelationTuples: relations,
 		NextPageToken:  nextPage,
@@ -173,3 +260,58 @@ func (h *handler) getRelations(w http.ResponseWriter, r *http.Request, _ httprou
 
 	h.d.Writer().Write(w, r, resp)
 }
+
+// swagger:route GET /relation-tuples/count read getRelationTupleCount
+//
+// # Count relation tuples
+//
+// Get the number of relation tuples that match the query without listing them.
+//
+//	Consumes:
+//	-  application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: getRelationTupleCountResponse
+//	  400: genericError
+//	  404: genericError
+//	  500: genericError
+func (h *handler) countRelations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+	query, err := (&ketoapi.RelationQuery{}).FromURLQuery(q)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
+		return
+	}
+
+	var estimate bool
+	if raw := q.Get("estimate"); raw != "" {
+		estimate, err = strconv.ParseBool(raw)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithErrorf("could not parse estimate: %s", err))
+			return
+		}
+	}
+
+	iq, err := h.d.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	count, estimated, err := h.d.RelationTupleManager().CountRelationTuples(ctx, iq, estimate)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &ketoapi.CountResponse{
+		Count:     count,
+		Estimated: estimated,
+	})
+}
@@ -3,6 +3,7 @@ package relationtuple_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -12,7 +13,11 @@ import (
 	"testing"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 
@@ -137,6 +142,41 @@ func TestReadHandlers(t *testing.T) {
 			assert.Equal(t, "", respMsg.NextPageToken)
 		})
 
+		t.Run("case=returns only the selected fields", func(t *testing.T) {
+			nspace := newNamespace(t)
+			relationtuple.MapAndWriteTuples(t, reg,
+				&ketoapi.RelationTuple{Namespace: nspace.Name, Object: "o1", Relation: "r1", SubjectID: x.Ptr("s1")},
+				&ketoapi.RelationTuple{Namespace: nspace.Name, Object: "o2", Relation: "r2", SubjectSet: &ketoapi.SubjectSet{
+					Namespace: nspace.Name, Object: "o1", Relation: "r1",
+				}},
+			)
+
+			resp, err := ts.Client().Get(ts.URL + relationtuple.ReadRouteBase + "?" + url.Values{
+				"namespace":         {nspace.Name},
+				x.FieldMaskQueryKey: {"subject_id,subject_set.object", "object"},
+			}.Encode())
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			var tuples []map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(gjson.GetBytes(body, "relation_tuples").Raw), &tuples))
+			assert.ElementsMatch(t, []map[string]interface{}{
+				{"object": "o1", "subject_id": "s1"},
+				{"object": "o2", "subject_set": map[string]interface{}{"object": "o1"}},
+			}, tuples)
+			assert.Equal(t, "", gjson.GetBytes(body, "next_page_token").Str)
+		})
+
+		t.Run("case=returns bad request on unknown field", func(t *testing.T) {
+			resp, err := ts.Client().Get(ts.URL + relationtuple.ReadRouteBase + "?" + url.Values{
+				x.FieldMaskQueryKey: {"subject_id.nested"},
+			}.Encode())
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+
 		t.Run("case=returns bad request on malformed subject", func(t *testing.T) {
 			resp, err := ts.Client().Get(ts.URL + relationtuple.ReadRouteBase + "?" + url.Values{
 				"subject": {"not#a valid subject"},
@@ -209,6 +249,53 @@ func TestReadHandlers(t *testing.T) {
 		})
 	})
 
+	t.Run("method=count", func(t *testing.T) {
+		count := func(t *testing.T, q url.Values) (int, ketoapi.CountResponse) {
+			resp, err := ts.Client().Get(ts.URL + relationtuple.CountRoute + "?" + q.Encode())
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var respMsg ketoapi.CountResponse
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&respMsg))
+			}
+			return resp.StatusCode, respMsg
+		}
+
+		nspace := newNamespace(t)
+		tuples := make([]*ketoapi.RelationTuple, 5)
+		for i := range tuples {
+			tuples[i] = &ketoapi.RelationTuple{
+				Namespace: nspace.Name,
+				Object:    fmt.Sprintf("o%d", i%2),
+				Relation:  "r",
+				SubjectID: x.Ptr(fmt.Sprintf("s%d", i)),
+			}
+		}
+		relationtuple.MapAndWriteTuples(t, reg, tuples...)
+
+		t.Run("case=counts matching tuples", func(t *testing.T) {
+			status, res := count(t, url.Values{"namespace": {nspace.Name}})
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, ketoapi.CountResponse{Count: 5}, res)
+
+			status, res = count(t, url.Values{"namespace": {nspace.Name}, "object": {"o0"}})
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, ketoapi.CountResponse{Count: 3}, res)
+		})
+
+		t.Run("case=falls back to exact count for estimates", func(t *testing.T) {
+			status, res := count(t, url.Values{"namespace": {nspace.Name}, "estimate": {"true"}})
+			require.Equal(t, http.StatusOK, status)
+			assert.Equal(t, ketoapi.CountResponse{Count: 5}, res)
+		})
+
+		t.Run("case=returns bad request on invalid estimate", func(t *testing.T) {
+			status, _ := count(t, url.Values{"estimate": {"foo"}})
+			assert.Equal(t, http.StatusBadRequest, status)
+		})
+	})
+
 	t.Run("method=grpc", func(t *testing.T) {
 		type requestEnhancer = func(req *rts.ListRelationTuplesRequest, query *ketoapi.RelationQuery)
 		withRelationQuery := func(req *rts.ListRelationTuplesRequest, query *ketoapi.RelationQuery) {
@@ -298,6 +385,36 @@ func TestReadHandlers(t *testing.T) {
 						assert.ElementsMatch(t, tuples, apiTuplesFromProto(t, resp.RelationTuples...))
 					})
 
+					t.Run("case=applies the field mask", func(t *testing.T) {
+						nspace := newNamespace(t)
+						relationtuple.MapAndWriteTuples(t, reg, &ketoapi.RelationTuple{
+							Namespace: nspace.Name,
+							Object:    "o1",
+							Relation:  "rel",
+							SubjectSet: &ketoapi.SubjectSet{
+								Namespace: nspace.Name,
+								Object:    "o2",
+								Relation:  "r2",
+							},
+						})
+
+						req := &rts.ListRelationTuplesRequest{ExpandMask: &fieldmaskpb.FieldMask{Paths: []string{"object", "subject.set.object"}}}
+						enhancer(req, &ketoapi.RelationQuery{
+							Namespace: &nspace.Name,
+						})
+						resp, err := client.ListRelationTuples(ctx, req)
+						require.NoError(t, err)
+						require.Len(t, resp.RelationTuples, 1)
+						assert.True(t, proto.Equal(&rts.RelationTuple{
+							Object:  "o1",
+							Subject: rts.NewSubjectSet("", "o2", ""),
+						}, resp.RelationTuples[0]), "%v", resp.RelationTuples[0])
+
+						req.ExpandMask.Paths = []string{"unknown"}
+						_, err = client.ListRelationTuples(ctx, req)
+						assert.Equal(t, codes.FailedPrecondition, status.Code(err), "%+v", err)
+					})
+
 					t.Run("case=paginates", func(t *testing.T) {
 						nspace := newNamespace(t)
 						tuples := []*ketoapi.RelationTuple{
@@ -349,5 +466,78 @@ func TestReadHandlers(t *testing.T) {
 				})
 			}
 		})
+
+		t.Run("method=stream", func(t *testing.T) {
+			client := rts.NewReadServiceClient(con)
+			receiveAll := func(t *testing.T, req *rts.StreamRelationTuplesRequest) (messages []*rts.StreamRelationTuplesResponse, err error) {
+				stream, err := client.StreamRelationTuples(ctx, req)
+				require.NoError(t, err)
+				for {
+					msg, err := stream.Recv()
+					if errors.Is(err, io.EOF) {
+						return messages, nil
+					} else if err != nil {
+						return messages, err
+					}
+					messages = append(messages, msg)
+				}
+			}
+
+			nspace := newNamespace(t)
+			tuples := make([]*ketoapi.RelationTuple, 5)
+			for i := range tuples {
+				tuples[i] = &ketoapi.RelationTuple{
+					Namespace: nspace.Name,
+					Object:    fmt.Sprintf("o%d", i),
+					Relation:  "rel",
+					SubjectID: x.Ptr(fmt.Sprintf("s%d", i)),
+				}
+			}
+			relationtuple.MapAndWriteTuples(t, reg, tuples...)
+
+			t.Run("case=streams all tuples in batches", func(t *testing.T) {
+				messages, err := receiveAll(t, &rts.StreamRelationTuplesRequest{
+					RelationQuery: (&ketoapi.RelationQuery{Namespace: &nspace.Name}).ToProto(),
+					BatchSize:     2,
+				})
+				require.NoError(t, err)
+				require.Len(t, messages, 3)
+
+				var received []*rts.RelationTuple
+				for i, msg := range messages {
+					received = append(received, msg.RelationTuples...)
+					assert.Equal(t, i == len(messages)-1, msg.NextPageToken == "")
+				}
+				assert.ElementsMatch(t, tuples, apiTuplesFromProto(t, received...))
+			})
+
+			t.Run("case=resumes after a message", func(t *testing.T) {
+				query := (&ketoapi.RelationQuery{Namespace: &nspace.Name}).ToProto()
+				first, err := receiveAll(t, &rts.StreamRelationTuplesRequest{RelationQuery: query, BatchSize: 3})
+				require.NoError(t, err)
+				require.Len(t, first, 2)
+
+				resumed, err := receiveAll(t, &rts.StreamRelationTuplesRequest{RelationQuery: query, BatchSize: 3, PageToken: first[0].NextPageToken})
+				require.NoError(t, err)
+				require.Len(t, resumed, 1)
+				assert.True(t, proto.Equal(first[1], resumed[0]))
+			})
+
+			t.Run("case=applies the expand mask", func(t *testing.T) {
+				messages, err := receiveAll(t, &rts.StreamRelationTuplesRequest{
+					RelationQuery: (&ketoapi.RelationQuery{Namespace: &nspace.Name, Object: x.Ptr("o1")}).ToProto(),
+					ExpandMask:    &fieldmaskpb.FieldMask{Paths: []string{"object"}},
+				})
+				require.NoError(t, err)
+				require.Len(t, messages, 1)
+				require.Len(t, messages[0].RelationTuples, 1)
+				assert.True(t, proto.Equal(&rts.RelationTuple{Object: "o1"}, messages[0].RelationTuples[0]))
+			})
+
+			t.Run("case=requires a query", func(t *testing.T) {
+				_, err := receiveAll(t, &rts.StreamRelationTuplesRequest{})
+				assert.Equal(t, codes.FailedPrecondition, status.Code(err), "%+v", err)
+			})
+		})
 	})
 }
@@ -0,0 +1,74 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+const RestoreRoute = WriteRouteBase + "/restore"
+
+// swagger:route POST /admin/relation-tuples/restore write restoreRelationTuples
+//
+// # Restore Deleted Relation Tuples
+//
+// Use this endpoint to restore soft deleted relation tuples that match the
+// query. Only relation tuples of namespaces with soft deletes enabled that are
+// still within the retention window can be restored.
+//
+//	Consumes:
+//	-  application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: restoreRelationTuplesResponse
+//	  400: genericError
+//	  500: genericError
+func (h *handler) restoreRelationTuples(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+	query, err := (&ketoapi.RelationQuery{}).FromURLQuery(q)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
+		return
+	}
+
+	var deletedSince time.Time
+	if raw := q.Get("deleted_since"); raw != "" {
+		if deletedSince, err = time.Parse(time.RFC3339, raw); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not parse deleted_since: %s", err)))
+			return
+		}
+	}
+
+	h.d.Logger().WithField("deleted_since", deletedSince).Debug("restoring relation tuples")
+
+	iq, err := h.d.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	if err := h.d.QuotaEnforcer().WriteAll(ctx, query); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	n, err := h.d.RelationTupleSoftDeleteManager().RestoreRelationTuples(ctx, iq, deletedSince, h.d.QuotaEnforcer().Add)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &ketoapi.RestoreResponse{Restored: n})
+}
@@ -0,0 +1,99 @@
+package relationtuple_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	nspaces := []*namespace.Namespace{
+		{Name: "files", Config: json.RawMessage(`{"soft_delete":{"enabled":true}}`)},
+		{Name: "expiring", Config: json.RawMessage(`{"soft_delete":{"enabled":true,"retention":"1ns"}}`)},
+		{Name: "groups"},
+	}
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces(nspaces))
+	r := &x.WriteRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterWriteRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	restore := func(t *testing.T, q url.Values) int {
+		resp, err := ts.Client().Post(ts.URL+relationtuple.RestoreRoute+"?"+q.Encode(), "", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var res ketoapi.RestoreResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res.Restored
+	}
+
+	list := func(t *testing.T, nspace string) []*ketoapi.RelationTuple {
+		iq, err := reg.Mapper().FromQuery(ctx, &ketoapi.RelationQuery{Namespace: &nspace})
+		require.NoError(t, err)
+		its, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, iq)
+		require.NoError(t, err)
+		tuples, err := reg.Mapper().ToTuple(ctx, its...)
+		require.NoError(t, err)
+		return tuples
+	}
+
+	tuples := []*ketoapi.RelationTuple{
+		{Namespace: "files", Object: "a", Relation: "viewer", SubjectID: x.Ptr("alice")},
+		{Namespace: "files", Object: "b", Relation: "viewer", SubjectSet: &ketoapi.SubjectSet{Namespace: "groups", Object: "g", Relation: "member"}},
+		{Namespace: "expiring", Object: "c", Relation: "viewer", SubjectID: x.Ptr("alice")},
+		{Namespace: "groups", Object: "g", Relation: "member", SubjectID: x.Ptr("bob")},
+	}
+	relationtuple.MapAndWriteTuples(t, reg, tuples...)
+
+	beforeDelete := time.Now().Add(-time.Second)
+	for _, n := range nspaces {
+		require.NoError(t, reg.RelationTupleManager().DeleteAllRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: &n.Name}))
+		assert.Empty(t, list(t, n.Name))
+	}
+
+	t.Run("case=nothing deleted in the future", func(t *testing.T) {
+		assert.Zero(t, restore(t, url.Values{"deleted_since": {time.Now().Add(time.Hour).Format(time.RFC3339)}}))
+	})
+
+	t.Run("case=restores within the retention window", func(t *testing.T) {
+		assert.Equal(t, 1, restore(t, url.Values{"namespace": {"files"}, "object": {"a"}, "deleted_since": {beforeDelete.Format(time.RFC3339)}}))
+		assert.Equal(t, tuples[:1], list(t, "files"))
+
+		assert.Equal(t, 1, restore(t, url.Values{}))
+		assert.ElementsMatch(t, tuples[:2], list(t, "files"))
+		assert.Empty(t, list(t, "expiring"))
+		assert.Empty(t, list(t, "groups"))
+
+		assert.Zero(t, restore(t, url.Values{}))
+	})
+
+	t.Run("case=purges expired relation tuples", func(t *testing.T) {
+		relationtuple.MapAndWriteTuples(t, reg, tuples[0])
+		require.NoError(t, reg.RelationTupleManager().DeleteAllRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("files")}))
+
+		n, err := reg.RelationTupleSoftDeleteManager().PurgeDeletedRelationTuples(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n, "only the expired relation tuple should be purged")
+
+		assert.Equal(t, 2, restore(t, url.Values{}))
+	})
+}
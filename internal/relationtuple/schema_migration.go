@@ -0,0 +1,192 @@
+package relationtuple
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	SchemaMigrationManagerProvider interface {
+		SchemaMigrationManager() SchemaMigrationManager
+	}
+	SchemaMigrationManager interface {
+		// ListSchemaVersions returns the current schema version of the
+		// namespace and the applied migrations, oldest first.
+		ListSchemaVersions(ctx context.Context, namespace string) (current int, versions []*ketoapi.SchemaVersion, err error)
+		// MigrateSchema rewrites the relation tuples according to the
+		// migration and records the new schema version. If dryRun is set,
+		// all changes are rolled back and only the plan is returned.
+		MigrateSchema(ctx context.Context, m *ketoapi.SchemaMigration, dryRun bool) (*ketoapi.SchemaMigrationPlan, error)
+	}
+)
+
+// ValidateSchemaMigration checks that the migration is well-formed and that
+// the namespace exists. It returns warnings for target relations that the
+// namespace does not declare.
+func ValidateSchemaMigration(ctx context.Context, nm namespace.Manager, m *ketoapi.SchemaMigration) (warnings []string, err error) {
+	if m.Namespace == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithError("the namespace is required"))
+	}
+	if len(m.Steps) == 0 {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithError("a migration needs at least one step"))
+	}
+	n, err := nm.GetNamespaceByName(ctx, m.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]bool, len(n.Relations))
+	for _, r := range n.Relations {
+		declared[r.Name] = true
+	}
+	targets := func(relations ...string) {
+		if len(n.Relations) == 0 {
+			return
+		}
+		for _, r := range relations {
+			if !declared[r] {
+				warnings = append(warnings, fmt.Sprintf("relation %q is not declared in namespace %q", r, m.Namespace))
+			}
+		}
+	}
+
+	for i, s := range m.Steps {
+		switch {
+		case s.Rename != nil && s.Split == nil:
+			if s.Rename.From == "" || s.Rename.To == "" {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("step %d: rename needs a source and a target relation", i+1))
+			}
+			if s.Rename.From == s.Rename.To {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("step %d: cannot rename relation %q to itself", i+1, s.Rename.From))
+			}
+			targets(s.Rename.To)
+		case s.Split != nil && s.Rename == nil:
+			if s.Split.From == "" || len(s.Split.Into) == 0 {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("step %d: split needs a source relation and at least one target", i+1))
+			}
+			for _, t := range s.Split.Into {
+				if t.Relation == "" || t.Relation == s.Split.From {
+					return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("step %d: every split target needs a relation other than %q", i+1, s.Split.From))
+				}
+				targets(t.Relation)
+			}
+		default:
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("step %d: exactly one of rename or split has to be set", i+1))
+		}
+	}
+	return warnings, nil
+}
+
+// SchemaMigrationStepSource returns the relation that the step rewrites.
+func SchemaMigrationStepSource(s *ketoapi.SchemaMigrationStep) string {
+	if s.Rename != nil {
+		return s.Rename.From
+	}
+	return s.Split.From
+}
+
+// DescribeSchemaMigrationStep returns a human readable description of the
+// step.
+func DescribeSchemaMigrationStep(nspace string, s *ketoapi.SchemaMigrationStep) string {
+	if s.Rename != nil {
+		return fmt.Sprintf("rename %s#%s to %s#%s", nspace, s.Rename.From, nspace, s.Rename.To)
+	}
+	into := make([]string, len(s.Split.Into))
+	for i, t := range s.Split.Into {
+		switch {
+		case t.SubjectIDs:
+			into[i] = fmt.Sprintf("%s (subject IDs)", t.Relation)
+		case t.SubjectSetNamespace != "":
+			into[i] = fmt.Sprintf("%s (subject sets of %s)", t.Relation, t.SubjectSetNamespace)
+		default:
+			into[i] = fmt.Sprintf("%s (all other subjects)", t.Relation)
+		}
+	}
+	return fmt.Sprintf("split %s#%s into %s", nspace, s.Split.From, strings.Join(into, ", "))
+}
+
+// RewriteForSchemaMigration returns the relation tuples that replace rt after
+// applying the step to the namespace. It returns false if the step does not
+// apply to rt, e.g. because no split target matches its subject.
+func RewriteForSchemaMigration(nspace string, s *ketoapi.SchemaMigrationStep, rt *RelationTuple) ([]*RelationTuple, bool) {
+	from := SchemaMigrationStepSource(s)
+	relations := []string{rt.Relation}
+	if rt.Namespace == nspace && rt.Relation == from {
+		relation, ok := migratedRelation(s, rt.Subject)
+		if !ok {
+			return nil, false
+		}
+		relations = []string{relation}
+	}
+
+	var subjects []Subject
+	if ss, ok := rt.Subject.(*SubjectSet); ok && ss.Namespace == nspace && ss.Relation == from {
+		for _, relation := range migratedSubjectRelations(s) {
+			subjects = append(subjects, &SubjectSet{Namespace: ss.Namespace, Object: ss.Object, Relation: relation})
+		}
+	} else {
+		subjects = []Subject{rt.Subject}
+	}
+
+	if relations[0] == rt.Relation && len(subjects) == 1 && subjects[0] == rt.Subject {
+		return nil, false
+	}
+
+	rewritten := make([]*RelationTuple, 0, len(relations)*len(subjects))
+	for _, relation := range relations {
+		for _, subject := range subjects {
+			rewritten = append(rewritten, &RelationTuple{
+				Namespace: rt.Namespace,
+				Object:    rt.Object,
+				Relation:  relation,
+				Subject:   subject,
+			})
+		}
+	}
+	return rewritten, true
+}
+
+// migratedRelation returns the relation that relation tuples with the subject
+// are moved to.
+func migratedRelation(s *ketoapi.SchemaMigrationStep, subject Subject) (string, bool) {
+	if s.Rename != nil {
+		return s.Rename.To, true
+	}
+	for _, t := range s.Split.Into {
+		switch sub := subject.(type) {
+		case *SubjectID:
+			if t.SubjectIDs || t.SubjectSetNamespace == "" {
+				return t.Relation, true
+			}
+		case *SubjectSet:
+			if t.SubjectSetNamespace == sub.Namespace || (!t.SubjectIDs && t.SubjectSetNamespace == "") {
+				return t.Relation, true
+			}
+		}
+	}
+	return "", false
+}
+
+// migratedSubjectRelations returns the relations that replace references to
+// the source relation in subject sets.
+func migratedSubjectRelations(s *ketoapi.SchemaMigrationStep) []string {
+	if s.Rename != nil {
+		return []string{s.Rename.To}
+	}
+	relations := make([]string, 0, len(s.Split.Into))
+	seen := make(map[string]bool, len(s.Split.Into))
+	for _, t := range s.Split.Into {
+		if !seen[t.Relation] {
+			relations = append(relations, t.Relation)
+			seen[t.Relation] = true
+		}
+	}
+	return relations
+}
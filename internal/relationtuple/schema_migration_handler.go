@@ -0,0 +1,127 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	SchemaMigrationRouteBase  = "/admin/schema-migrations"
+	SchemaMigrationPlanRoute  = SchemaMigrationRouteBase + "/plan"
+	SchemaMigrationApplyRoute = SchemaMigrationRouteBase + "/apply"
+	SchemaVersionsRoute       = "/admin/schema-versions"
+)
+
+// swagger:route POST /admin/schema-migrations/plan write planSchemaMigration
+//
+// # Plan a Schema Migration
+//
+// Use this endpoint to preview a schema migration. The migration is run in a
+// transaction that is rolled back, so the plan reports exactly which relation
+// tuples would be rewritten by applying it.
+//
+//	Consumes:
+//	-  application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: schemaMigrationPlan
+//	  400: genericError
+//	  404: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) planSchemaMigration(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.migrateSchema(w, r, true)
+}
+
+// swagger:route POST /admin/schema-migrations/apply write applySchemaMigration
+//
+// # Apply a Schema Migration
+//
+// Use this endpoint to rewrite the relation tuples of a namespace after a
+// relation was renamed or split. All relation tuples are rewritten in one
+// transaction, and the schema version of the namespace is incremented. The
+// migration is rejected if the namespace is not at the version the migration
+// applies to.
+//
+//	Consumes:
+//	-  application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: schemaMigrationPlan
+//	  400: genericError
+//	  404: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) applySchemaMigration(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.migrateSchema(w, r, false)
+}
+
+func (h *handler) migrateSchema(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	var m ketoapi.SchemaMigration
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+
+	h.d.Logger().
+		WithField("namespace", m.Namespace).
+		WithField("from_version", m.FromVersion).
+		WithField("dry_run", dryRun).
+		Debug("migrating namespace schema")
+
+	plan, err := h.d.SchemaMigrationManager().MigrateSchema(r.Context(), &m, dryRun)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.Writer().Write(w, r, plan)
+}
+
+// swagger:route GET /admin/schema-versions write listSchemaVersions
+//
+// # List Schema Versions
+//
+// Use this endpoint to get the current schema version of a namespace and the
+// migrations that were applied to it.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listSchemaVersionsResponse
+//	  400: genericError
+//	  500: genericError
+func (h *handler) listSchemaVersions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	nspace := r.URL.Query().Get("namespace")
+	if nspace == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError("the namespace query parameter is required")))
+		return
+	}
+
+	current, versions, err := h.d.SchemaMigrationManager().ListSchemaVersions(r.Context(), nspace)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.Writer().Write(w, r, &ketoapi.ListSchemaVersionsResponse{Current: current, Versions: versions})
+}
@@ -0,0 +1,179 @@
+package relationtuple_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestSchemaMigrations(t *testing.T) {
+	ctx := context.Background()
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{
+		{Name: "Document"}, {Name: "Group"},
+	}))
+	r := &x.AdminRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterAdminRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	migrate := func(t *testing.T, route string, m *ketoapi.SchemaMigration) (int, []byte) {
+		raw, err := json.Marshal(m)
+		require.NoError(t, err)
+		resp, err := ts.Client().Post(ts.URL+route, "application/json", bytes.NewReader(raw))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, body
+	}
+	plan := func(t *testing.T, route string, m *ketoapi.SchemaMigration) *ketoapi.SchemaMigrationPlan {
+		code, body := migrate(t, route, m)
+		require.Equal(t, http.StatusOK, code, "%s", body)
+		var res ketoapi.SchemaMigrationPlan
+		require.NoError(t, json.Unmarshal(body, &res))
+		return &res
+	}
+	list := func(t *testing.T, nspace string) []string {
+		iq, err := reg.Mapper().FromQuery(ctx, &ketoapi.RelationQuery{Namespace: &nspace})
+		require.NoError(t, err)
+		its, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, iq)
+		require.NoError(t, err)
+		tuples, err := reg.Mapper().ToTuple(ctx, its...)
+		require.NoError(t, err)
+		res := make([]string, len(tuples))
+		for i, rt := range tuples {
+			res[i] = rt.String()
+		}
+		return res
+	}
+
+	relationtuple.MapAndWriteTuples(t, reg,
+		&ketoapi.RelationTuple{Namespace: "Document", Object: "d", Relation: "viewers", SubjectID: x.Ptr("alice")},
+		&ketoapi.RelationTuple{Namespace: "Document", Object: "d", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "g", Relation: "members"}},
+		&ketoapi.RelationTuple{Namespace: "Group", Object: "g", Relation: "members", SubjectID: x.Ptr("bob")},
+		&ketoapi.RelationTuple{Namespace: "Group", Object: "g", Relation: "members", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "h", Relation: "members"}},
+	)
+
+	rename := &ketoapi.SchemaMigration{
+		Namespace:   "Group",
+		Description: "rename members to member",
+		Steps:       []*ketoapi.SchemaMigrationStep{{Rename: &ketoapi.RenameRelation{From: "members", To: "member"}}},
+	}
+
+	t.Run("case=plan does not change anything", func(t *testing.T) {
+		res := plan(t, relationtuple.SchemaMigrationPlanRoute, rename)
+		assert.False(t, res.Applied)
+		assert.Equal(t, 0, res.FromVersion)
+		assert.Equal(t, 1, res.ToVersion)
+		require.Len(t, res.Steps, 1)
+		assert.Equal(t, "rename Group#members to Group#member", res.Steps[0].Description)
+		assert.Equal(t, 3, res.Steps[0].Rewritten)
+
+		assert.Contains(t, list(t, "Group"), "Group:g#members@bob")
+	})
+
+	t.Run("case=apply renames relations and subject sets", func(t *testing.T) {
+		res := plan(t, relationtuple.SchemaMigrationApplyRoute, rename)
+		assert.True(t, res.Applied)
+		assert.Equal(t, 3, res.Steps[0].Rewritten)
+
+		assert.ElementsMatch(t, []string{"Group:g#member@bob", "Group:g#member@(Group:h#member)"}, list(t, "Group"))
+		assert.ElementsMatch(t, []string{"Document:d#viewers@alice", "Document:d#viewers@(Group:g#member)"}, list(t, "Document"))
+	})
+
+	t.Run("case=rejects outdated versions", func(t *testing.T) {
+		code, body := migrate(t, relationtuple.SchemaMigrationApplyRoute, rename)
+		assert.Equal(t, http.StatusConflict, code, "%s", body)
+	})
+
+	t.Run("case=split by subject type", func(t *testing.T) {
+		res := plan(t, relationtuple.SchemaMigrationApplyRoute, &ketoapi.SchemaMigration{
+			Namespace: "Document",
+			Steps: []*ketoapi.SchemaMigrationStep{{Split: &ketoapi.SplitRelation{
+				From: "viewers",
+				Into: []*ketoapi.SplitTarget{
+					{Relation: "user_viewers", SubjectIDs: true},
+					{Relation: "group_viewers", SubjectSetNamespace: "Group"},
+				},
+			}}},
+		})
+		assert.Equal(t, 2, res.Steps[0].Rewritten)
+		assert.Zero(t, res.Steps[0].Unmatched)
+		assert.ElementsMatch(t, []string{"Document:d#user_viewers@alice", "Document:d#group_viewers@(Group:g#member)"}, list(t, "Document"))
+	})
+
+	t.Run("case=lists versions", func(t *testing.T) {
+		resp, err := ts.Client().Get(ts.URL + relationtuple.SchemaVersionsRoute + "?namespace=Group")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var res ketoapi.ListSchemaVersionsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		assert.Equal(t, 1, res.Current)
+		require.Len(t, res.Versions, 1)
+		assert.Equal(t, rename.Description, res.Versions[0].Description)
+		assert.Equal(t, rename.Steps, res.Versions[0].Steps)
+	})
+
+	t.Run("case=rejects invalid migrations", func(t *testing.T) {
+		for _, m := range []*ketoapi.SchemaMigration{
+			{Namespace: "Group"},
+			{Namespace: "Group", FromVersion: 1, Steps: []*ketoapi.SchemaMigrationStep{{}}},
+			{Namespace: "Group", FromVersion: 1, Steps: []*ketoapi.SchemaMigrationStep{{Rename: &ketoapi.RenameRelation{From: "member", To: "member"}}}},
+			{Namespace: "Group", FromVersion: 1, Steps: []*ketoapi.SchemaMigrationStep{{Split: &ketoapi.SplitRelation{From: "member"}}}},
+		} {
+			code, body := migrate(t, relationtuple.SchemaMigrationPlanRoute, m)
+			assert.Equal(t, http.StatusBadRequest, code, "%s", body)
+		}
+
+		code, body := migrate(t, relationtuple.SchemaMigrationPlanRoute, &ketoapi.SchemaMigration{
+			Namespace: "Unknown",
+			Steps:     rename.Steps,
+		})
+		assert.Equal(t, http.StatusNotFound, code, "%s", body)
+	})
+}
+
+func TestRewriteForSchemaMigration(t *testing.T) {
+	split := &ketoapi.SchemaMigrationStep{Split: &ketoapi.SplitRelation{
+		From: "viewers",
+		Into: []*ketoapi.SplitTarget{
+			{Relation: "user_viewers", SubjectIDs: true},
+			{Relation: "group_viewers", SubjectSetNamespace: "Group"},
+		},
+	}}
+
+	t.Run("case=unmatched subject", func(t *testing.T) {
+		_, ok := relationtuple.RewriteForSchemaMigration("Doc", split, &relationtuple.RelationTuple{
+			Namespace: "Doc", Relation: "viewers", Subject: &relationtuple.SubjectSet{Namespace: "Team", Relation: "members"},
+		})
+		assert.False(t, ok)
+	})
+
+	t.Run("case=references to a split relation are expanded", func(t *testing.T) {
+		rts, ok := relationtuple.RewriteForSchemaMigration("Doc", split, &relationtuple.RelationTuple{
+			Namespace: "Folder", Relation: "viewers", Subject: &relationtuple.SubjectSet{Namespace: "Doc", Relation: "viewers"},
+		})
+		require.True(t, ok)
+		require.Len(t, rts, 2)
+		assert.Equal(t, "viewers", rts[0].Relation)
+		assert.Equal(t, "user_viewers", rts[0].Subject.(*relationtuple.SubjectSet).Relation)
+		assert.Equal(t, "group_viewers", rts[1].Subject.(*relationtuple.SubjectSet).Relation)
+	})
+}
@@ -0,0 +1,72 @@
+package relationtuple
+
+import (
+	"context"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+// ValidateSchema checks that the relation tuple conforms to the namespace's Ory
+// Permission Language schema: the relation has to be declared as a relation
+// (not a permission) and a subject set has to be one of the allowed subject
+// types of that relation.
+func ValidateSchema(ctx context.Context, nm namespace.Manager, t *RelationTuple) error {
+	n, err := nm.GetNamespaceByName(ctx, t.Namespace)
+	if err != nil {
+		return err
+	}
+
+	rel := findRelation(n, t.Relation)
+	if rel == nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Relation %q is not declared in namespace %q.", t.Relation, t.Namespace))
+	}
+	if rel.SubjectSetRewrite != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("%q in namespace %q is a permission, relation tuples can only be written for relations.", t.Relation, t.Namespace))
+	}
+
+	s, ok := t.Subject.(*SubjectSet)
+	if !ok || len(rel.Types) == 0 {
+		return nil
+	}
+	for _, typ := range rel.Types {
+		if typ.Namespace == s.Namespace && typ.Relation == s.Relation {
+			return nil
+		}
+	}
+
+	subjectType := s.Namespace
+	if s.Relation != "" {
+		subjectType += "#" + s.Relation
+	}
+	return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Subject type %q is not allowed for relation %q in namespace %q.", subjectType, t.Relation, t.Namespace))
+}
+
+func findRelation(n *namespace.Namespace, name string) *ast.Relation {
+	for i := range n.Relations {
+		if n.Relations[i].Name == name {
+			return &n.Relations[i]
+		}
+	}
+	return nil
+}
+
+// MaxSubjects returns the maximum number of subjects that an object can have
+// for the relation, as declared in the namespace configuration. Zero means that
+// the number is not limited, which is also the case for unknown namespaces and
+// relations.
+func MaxSubjects(ctx context.Context, nm namespace.Manager, namespace, relation string) (int, error) {
+	n, err := nm.GetNamespaceByName(ctx, namespace)
+	if errors.Is(err, herodot.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if rel := findRelation(n, relation); rel != nil {
+		return rel.MaxSubjects, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,206 @@
+package relationtuple_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestStrictMode(t *testing.T) {
+	ctx := context.Background()
+	const opl = `
+class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: (User | SubjectSet<Group, "members">)[]
+  }
+}
+
+class Document implements Namespace {
+  related: {
+    viewers: (User | SubjectSet<Group, "members">)[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+  }
+}`
+
+	newReg := func(t *testing.T, strict bool) *driver.RegistryDefault {
+		fn := filepath.Join(t.TempDir(), "namespaces.keto.ts")
+		require.NoError(t, os.WriteFile(fn, []byte(opl), 0600))
+
+		reg := driver.NewSqliteTestRegistry(t, false)
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, map[string]interface{}{
+			"location":                 "file://" + fn,
+			"experimental_strict_mode": strict,
+		}))
+		return reg
+	}
+
+	write := func(t *testing.T, reg *driver.RegistryDefault, tuple *ketoapi.RelationTuple) error {
+		its, err := reg.Mapper().FromTuple(ctx, tuple)
+		require.NoError(t, err)
+		return reg.RelationTupleManager().WriteRelationTuples(ctx, its...)
+	}
+
+	valid := []*ketoapi.RelationTuple{
+		{Namespace: "Document", Object: "d", Relation: "viewers", SubjectID: x.Ptr("alice")},
+		{Namespace: "Document", Object: "d", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "User", Object: "alice"}},
+		{Namespace: "Document", Object: "d", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "g", Relation: "members"}},
+		{Namespace: "Group", Object: "g", Relation: "members", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "h", Relation: "members"}},
+	}
+	invalid := map[string]*ketoapi.RelationTuple{
+		"unknown relation":     {Namespace: "Document", Object: "d", Relation: "viewer", SubjectID: x.Ptr("alice")},
+		"permission":           {Namespace: "Document", Object: "d", Relation: "view", SubjectID: x.Ptr("alice")},
+		"wrong subject type":   {Namespace: "Document", Object: "d", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "Document", Object: "e"}},
+		"wrong subject rel":    {Namespace: "Document", Object: "d", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "g", Relation: "owners"}},
+		"no relations defined": {Namespace: "User", Object: "u", Relation: "manager", SubjectID: x.Ptr("bob")},
+	}
+
+	t.Run("case=strict mode", func(t *testing.T) {
+		reg := newReg(t, true)
+
+		for _, tuple := range valid {
+			assert.NoError(t, write(t, reg, tuple), "%s", tuple)
+		}
+		for name, tuple := range invalid {
+			t.Run("invalid="+name, func(t *testing.T) {
+				err := write(t, reg, tuple)
+				assert.ErrorIs(t, err, herodot.ErrBadRequest)
+			})
+		}
+
+		res, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, &relationtuple.RelationQuery{})
+		require.NoError(t, err)
+		assert.Len(t, res, len(valid))
+	})
+
+	t.Run("case=lenient mode", func(t *testing.T) {
+		reg := newReg(t, false)
+
+		for _, tuple := range invalid {
+			assert.NoError(t, write(t, reg, tuple), "%s", tuple)
+		}
+	})
+}
+
+func TestCardinalityConstraints(t *testing.T) {
+	ctx := context.Background()
+	const opl = `
+class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owner: User
+    viewers: User[]
+  }
+}`
+
+	fn := filepath.Join(t.TempDir(), "namespaces.keto.ts")
+	require.NoError(t, os.WriteFile(fn, []byte(opl), 0600))
+	reg := driver.NewSqliteTestRegistry(t, false)
+	require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, map[string]interface{}{
+		"location": "file://" + fn,
+	}))
+	m := reg.RelationTupleManager()
+
+	mapped := func(t *testing.T, tuples ...*ketoapi.RelationTuple) []*relationtuple.RelationTuple {
+		its, err := reg.Mapper().FromTuple(ctx, tuples...)
+		require.NoError(t, err)
+		return its
+	}
+	owner := func(doc, user string) *ketoapi.RelationTuple {
+		return &ketoapi.RelationTuple{Namespace: "Document", Object: doc, Relation: "owner", SubjectID: x.Ptr(user)}
+	}
+	owners := func(t *testing.T, doc string) []string {
+		res, _, err := m.GetRelationTuples(ctx, &relationtuple.RelationQuery{
+			Namespace: x.Ptr("Document"),
+			Object:    &mapped(t, owner(doc, "-"))[0].Object,
+			Relation:  x.Ptr("owner"),
+		})
+		require.NoError(t, err)
+		tuples, err := reg.Mapper().ToTuple(ctx, res...)
+		require.NoError(t, err)
+		names := make([]string, len(tuples))
+		for i, tuple := range tuples {
+			names[i] = *tuple.SubjectID
+		}
+		return names
+	}
+
+	t.Run("case=single subject is allowed", func(t *testing.T) {
+		require.NoError(t, m.WriteRelationTuples(ctx, mapped(t, owner("a", "alice"))...))
+		assert.Equal(t, []string{"alice"}, owners(t, "a"))
+	})
+
+	t.Run("case=second subject is rejected", func(t *testing.T) {
+		require.NoError(t, m.WriteRelationTuples(ctx, mapped(t, owner("b", "alice"))...))
+
+		err := m.WriteRelationTuples(ctx, mapped(t, owner("b", "bob"))...)
+		assert.ErrorIs(t, err, herodot.ErrConflict)
+		assert.Equal(t, []string{"alice"}, owners(t, "b"))
+
+		err = m.WriteRelationTuples(ctx, mapped(t, owner("c", "alice"), owner("c", "bob"))...)
+		assert.ErrorIs(t, err, herodot.ErrConflict)
+		assert.Empty(t, owners(t, "c"))
+	})
+
+	t.Run("case=subject can be replaced in a transaction", func(t *testing.T) {
+		require.NoError(t, m.WriteRelationTuples(ctx, mapped(t, owner("d", "alice"))...))
+
+		require.NoError(t, m.TransactRelationTuples(ctx, mapped(t, owner("d", "bob")), mapped(t, owner("d", "alice"))))
+		assert.Equal(t, []string{"bob"}, owners(t, "d"))
+	})
+
+	t.Run("case=touching the subject is allowed", func(t *testing.T) {
+		require.NoError(t, m.WriteRelationTuples(ctx, mapped(t, owner("e", "alice"))...))
+		require.NoError(t, m.TouchRelationTuples(ctx, mapped(t, owner("e", "alice"))...))
+
+		err := m.TouchRelationTuples(ctx, mapped(t, owner("e", "bob"))...)
+		assert.ErrorIs(t, err, herodot.ErrConflict)
+	})
+
+	t.Run("case=restoring a second subject is rejected", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{
+			Name:      "Document",
+			Config:    json.RawMessage(`{"soft_delete":{"enabled":true}}`),
+			Relations: []ast.Relation{{Name: "owner", MaxSubjects: 1}},
+		}}))
+		t.Cleanup(func() {
+			require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, map[string]interface{}{
+				"location": "file://" + fn,
+			}))
+		})
+
+		require.NoError(t, m.WriteRelationTuples(ctx, mapped(t, owner("g", "alice"))...))
+		require.NoError(t, m.TransactRelationTuples(ctx, mapped(t, owner("g", "bob")), mapped(t, owner("g", "alice"))))
+
+		_, err := reg.RelationTupleSoftDeleteManager().RestoreRelationTuples(ctx, &relationtuple.RelationQuery{Namespace: x.Ptr("Document")}, time.Time{}, nil)
+		assert.ErrorIs(t, err, herodot.ErrConflict)
+		assert.Equal(t, []string{"bob"}, owners(t, "g"))
+	})
+
+	t.Run("case=array relations are not limited", func(t *testing.T) {
+		require.NoError(t, m.WriteRelationTuples(ctx, mapped(t,
+			&ketoapi.RelationTuple{Namespace: "Document", Object: "f", Relation: "viewers", SubjectID: x.Ptr("alice")},
+			&ketoapi.RelationTuple{Namespace: "Document", Object: "f", Relation: "viewers", SubjectID: x.Ptr("bob")},
+		)...))
+	})
+}
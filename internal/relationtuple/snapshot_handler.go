@@ -0,0 +1,254 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	SnapshotRoute = WriteRouteBase + "/snapshot"
+
+	snapshotImportBatchSize = 1000
+)
+
+// swagger:route GET /admin/relation-tuples/snapshot write exportSnapshot
+//
+// # Export a Snapshot
+//
+// Use this endpoint to export all relation tuples from a consistent snapshot.
+// The response is a snapshot header followed by one relation tuple per line,
+// and a trailer with their count and checksum. A response without the trailer
+// was cut off by an error.
+//
+//	Produces:
+//	- application/x-ndjson
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: snapshotHeader
+//	  400: genericError
+//	  500: genericError
+func (h *handler) exportSnapshot(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	header := &ketoapi.SnapshotHeader{
+		Version:   ketoapi.SnapshotFormatVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if raw := r.URL.Query().Get("include_namespaces"); raw != "" {
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not parse include_namespaces: %s", err)))
+			return
+		}
+		if include {
+			nm, err := h.d.Config(ctx).NamespaceManager()
+			if err != nil {
+				h.d.Writer().WriteError(w, r, err)
+				return
+			}
+			namespaces, err := nm.Namespaces(ctx)
+			if err != nil {
+				h.d.Writer().WriteError(w, r, err)
+				return
+			}
+			for _, n := range namespaces {
+				header.Namespaces = append(header.Namespaces, &ketoapi.SnapshotNamespace{Name: n.Name, Config: n.Config})
+			}
+		}
+	}
+
+	// The header is only written once the first page was read, so that errors
+	// that occur early can still be reported with a proper status code.
+	enc := json.NewEncoder(w)
+	headerWritten := false
+	writeHeader := func() error {
+		if headerWritten {
+			return nil
+		}
+		headerWritten = true
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		return errors.WithStack(enc.Encode(header))
+	}
+
+	sum := ketoapi.NewSnapshotChecksum()
+	writeLine := func(line []byte) error {
+		_, err := w.Write(append(line, '\n'))
+		return errors.WithStack(err)
+	}
+	err := h.d.RelationTupleManager().WalkRelationTuples(ctx, &RelationQuery{}, func(ctx context.Context, page []*RelationTuple) error {
+		tuples, err := h.d.Mapper().ToTuple(ctx, page...)
+		if err != nil {
+			return err
+		}
+		if err := writeHeader(); err != nil {
+			return err
+		}
+		for _, t := range tuples {
+			line, err := json.Marshal(t)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			sum.Add(line)
+			if err := writeLine(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = writeHeader()
+	}
+	if err == nil {
+		var trailer []byte
+		if trailer, err = ketoapi.EncodeSnapshotTrailer(sum.Trailer()); err == nil {
+			err = writeLine(trailer)
+		}
+	}
+	if err != nil {
+		h.d.Logger().WithError(err).Error("could not export snapshot")
+		if !headerWritten {
+			h.d.Writer().WriteError(w, r, err)
+		}
+	}
+}
+
+// swagger:route PUT /admin/relation-tuples/snapshot write importSnapshot
+//
+// # Import a Snapshot
+//
+// Use this endpoint to restore a snapshot into an instance without any
+// relation tuples. All namespaces the snapshot refers to have to be
+// configured. The snapshot is imported in one transaction, and only if its
+// trailer matches the relation tuples.
+//
+//	Consumes:
+//	- application/x-ndjson
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: snapshotImportResult
+//	  400: genericError
+//	  409: genericError
+//	  500: genericError
+func (h *handler) importSnapshot(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	snapshot := ketoapi.NewSnapshotReader(r.Body)
+
+	header, err := snapshot.Header()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if len(header.Namespaces) > 0 {
+		nm, err := h.d.Config(ctx).NamespaceManager()
+		if err != nil {
+			h.d.Writer().WriteError(w, r, err)
+			return
+		}
+		for _, n := range header.Namespaces {
+			if _, err := nm.GetNamespaceByName(ctx, n.Name); err != nil {
+				h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("namespace %q of the snapshot is not configured", n.Name)))
+				return
+			}
+		}
+	}
+
+	if err := h.d.QuotaEnforcer().WriteAll(ctx, &ketoapi.RelationQuery{}); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var result ketoapi.SnapshotImportResult
+	err = h.inTransaction(ctx, func(ctx context.Context) error {
+		result.Imported = 0
+
+		existing, _, err := h.d.RelationTupleManager().GetRelationTuples(ctx, &RelationQuery{}, x.WithSize(1))
+		if err != nil {
+			return err
+		}
+		if len(existing) != 0 {
+			return errors.WithStack(herodot.ErrConflict.WithReason("snapshots can only be imported into an instance without relation tuples"))
+		}
+
+		batchSize := snapshotImportBatchSize
+		if l, ok := h.d.RelationTupleManager().(TransactionLimiter); ok && l.MaxTransactionSize() < batchSize {
+			batchSize = l.MaxTransactionSize()
+		}
+		batch := make([]*ketoapi.RelationTuple, 0, batchSize)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			added := make(map[string]int)
+			for _, rt := range batch {
+				added[rt.Namespace]++
+			}
+			if err := h.d.QuotaEnforcer().Add(ctx, added); err != nil {
+				return err
+			}
+			its, err := h.d.Mapper().FromTuple(ctx, batch...)
+			if err != nil {
+				return err
+			}
+			if err := h.d.RelationTupleManager().WriteRelationTuples(ctx, its...); err != nil {
+				return err
+			}
+			result.Imported += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+
+		for {
+			rt, err := snapshot.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			if batch = append(batch, rt); len(batch) == batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		return flush()
+	})
+	if err != nil {
+		h.d.Logger().WithError(err).Error("could not import snapshot")
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &result)
+}
+
+// inTransaction runs f in one transaction of the relation tuple manager, or
+// directly if it does not support transactions.
+func (h *handler) inTransaction(ctx context.Context, f func(ctx context.Context) error) error {
+	if t, ok := h.d.RelationTupleManager().(Transactor); ok {
+		return t.InTransaction(ctx, f)
+	}
+	return f(ctx)
+}
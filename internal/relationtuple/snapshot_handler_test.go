@@ -0,0 +1,161 @@
+package relationtuple_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestSnapshotHandlers(t *testing.T) {
+	ctx := context.Background()
+	nspaces := []*namespace.Namespace{{Name: "files"}, {Name: "groups"}}
+
+	newServer := func(t *testing.T) (*driver.RegistryDefault, *httptest.Server) {
+		reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces(nspaces))
+		r := &x.AdminRouter{Router: httprouter.New()}
+		relationtuple.NewHandler(reg).RegisterAdminRoutes(r)
+		ts := httptest.NewServer(r)
+		t.Cleanup(ts.Close)
+		return reg, ts
+	}
+
+	export := func(t *testing.T, ts *httptest.Server, query string) []byte {
+		resp, err := ts.Client().Get(ts.URL + relationtuple.SnapshotRoute + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "%s", body)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		return body
+	}
+
+	importSnapshot := func(t *testing.T, ts *httptest.Server, snapshot []byte) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, ts.URL+relationtuple.SnapshotRoute, bytes.NewReader(snapshot))
+		require.NoError(t, err)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("case=roundtrip", func(t *testing.T) {
+		srcReg, src := newServer(t)
+		dstReg, dst := newServer(t)
+
+		tuples := make([]*ketoapi.RelationTuple, 2500)
+		for i := range tuples {
+			tuples[i] = &ketoapi.RelationTuple{
+				Namespace: "files",
+				Object:    fmt.Sprintf("file %d", i),
+				Relation:  "viewer",
+				SubjectSet: &ketoapi.SubjectSet{
+					Namespace: "groups",
+					Object:    fmt.Sprintf("group %d", i%7),
+					Relation:  "member",
+				},
+			}
+		}
+		tuples[0].SubjectSet, tuples[0].SubjectID = nil, x.Ptr("user")
+		its, err := srcReg.Mapper().FromTuple(ctx, tuples...)
+		require.NoError(t, err)
+		require.NoError(t, srcReg.RelationTupleManager().WriteRelationTuples(ctx, its...))
+
+		snapshot := export(t, src, "?include_namespaces=true")
+
+		lines := strings.Split(strings.TrimSpace(string(snapshot)), "\n")
+		require.Len(t, lines, len(tuples)+2)
+		trailer, ok := ketoapi.DecodeSnapshotTrailer([]byte(lines[len(lines)-1]))
+		require.True(t, ok)
+		assert.Equal(t, len(tuples), trailer.Count)
+		var header ketoapi.SnapshotHeader
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+		assert.Equal(t, ketoapi.SnapshotFormatVersion, header.Version)
+		require.Len(t, header.Namespaces, 2)
+		assert.Equal(t, "files", header.Namespaces[0].Name)
+
+		resp := importSnapshot(t, dst, snapshot)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var result ketoapi.SnapshotImportResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, len(tuples), result.Imported)
+
+		var imported []*relationtuple.RelationTuple
+		require.NoError(t, dstReg.RelationTupleManager().WalkRelationTuples(ctx, &relationtuple.RelationQuery{}, func(_ context.Context, page []*relationtuple.RelationTuple) error {
+			imported = append(imported, page...)
+			return nil
+		}))
+		actual, err := dstReg.Mapper().ToTuple(ctx, imported...)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, tuples, actual)
+
+		t.Run("case=rejects non-empty instance", func(t *testing.T) {
+			resp := importSnapshot(t, dst, snapshot)
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		})
+
+		for _, tc := range []struct {
+			name     string
+			snapshot string
+		}{
+			{name: "truncated", snapshot: strings.Join(lines[:len(lines)-10], "\n")},
+			{name: "without trailer", snapshot: strings.Join(lines[:len(lines)-1], "\n")},
+			{name: "altered", snapshot: strings.Replace(string(snapshot), `"file 42"`, `"file 43"`, 1)},
+		} {
+			t.Run("case=rejects "+tc.name+" snapshot", func(t *testing.T) {
+				dstReg, dst := newServer(t)
+
+				resp := importSnapshot(t, dst, []byte(tc.snapshot))
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+				// Nothing is imported, so that the import can be retried.
+				its, _, err := dstReg.RelationTupleManager().GetRelationTuples(ctx, &relationtuple.RelationQuery{})
+				require.NoError(t, err)
+				assert.Len(t, its, 0)
+			})
+		}
+	})
+
+	t.Run("case=empty snapshot only has a header and trailer", func(t *testing.T) {
+		_, ts := newServer(t)
+
+		snapshot := export(t, ts, "")
+		lines := strings.Split(strings.TrimSpace(string(snapshot)), "\n")
+		require.Len(t, lines, 2)
+
+		var header ketoapi.SnapshotHeader
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+		assert.Len(t, header.Namespaces, 0)
+	})
+
+	t.Run("case=rejects unknown version", func(t *testing.T) {
+		_, ts := newServer(t)
+
+		resp := importSnapshot(t, ts, []byte(`{"version":42,"created_at":"2022-01-01T00:00:00Z"}`))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("case=rejects unknown namespace", func(t *testing.T) {
+		_, ts := newServer(t)
+
+		resp := importSnapshot(t, ts, []byte(fmt.Sprintf(`{"version":%d,"created_at":"2022-01-01T00:00:00Z","namespaces":[{"name":"unknown"}]}`, ketoapi.SnapshotFormatVersion)))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
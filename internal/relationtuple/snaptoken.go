@@ -0,0 +1,46 @@
+package relationtuple
+
+import "context"
+
+type (
+	// Snapshotter is implemented by the relation tuple managers that can read
+	// at consistent snapshots. Snaptokens identify the snapshots and are opaque
+	// to clients.
+	Snapshotter interface {
+		// Snaptoken returns the token of the latest snapshot.
+		Snaptoken(ctx context.Context) (string, error)
+	}
+
+	snaptokenKey         struct{}
+	snaptokenRecorderKey struct{}
+)
+
+// WithSnaptoken returns a context in which reads are evaluated at a snapshot
+// no earlier than the one of the token. Managers that are not Snapshotters
+// ignore it.
+func WithSnaptoken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, snaptokenKey{}, token)
+}
+
+// SnaptokenFromContext returns the token of the snapshot that reads have to
+// see, or the empty string.
+func SnaptokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(snaptokenKey{}).(string)
+	return token
+}
+
+// WithSnaptokenRecorder returns a context in which Snapshotters record the
+// snaptoken of their writes, and a function that returns the token of the last
+// write, or the empty string.
+func WithSnaptokenRecorder(ctx context.Context) (context.Context, func() string) {
+	token := new(string)
+	return context.WithValue(ctx, snaptokenRecorderKey{}, token), func() string { return *token }
+}
+
+// RecordSnaptoken records the snaptoken of a write, if the context has a
+// recorder.
+func RecordSnaptoken(ctx context.Context, token string) {
+	if rec, ok := ctx.Value(snaptokenRecorderKey{}).(*string); ok {
+		*rec = token
+	}
+}
@@ -10,6 +10,13 @@ var (
 	_ = (*getRelationsParams)(nil)
 	_ = (*bodyRelationTuple)(nil)
 	_ = (*queryRelationTuple)(nil)
+	_ = (*exportSnapshotParams)(nil)
+	_ = (*importSnapshotParams)(nil)
+	_ = (*getHistoryParams)(nil)
+	_ = (*getCountParams)(nil)
+	_ = (*createRelationTupleParams)(nil)
+	_ = (*restoreParams)(nil)
+	_ = (*bulkDeleteParams)(nil)
 )
 
 // The patch request payload
@@ -61,6 +68,13 @@ type getRelationsParams struct {
 	// Either subject_set.* or subject_id are required.
 	SRelation string `json:"subject_set.relation"`
 
+	// Fields of the relation tuples to return
+	//
+	// in: query
+	// The JSON field names, with nested fields separated by dots, e.g.
+	// `subject_id` or `subject_set.object`. All fields are returned if empty.
+	Fields []string `json:"fields"`
+
 	// swagger:allOf
 	x.PaginationOptions
 }
@@ -73,6 +87,15 @@ type bodyRelationTuple struct {
 	Payload ketoapi.RelationQuery
 }
 
+// swagger:parameters createRelationTuple
+type createRelationTupleParams struct {
+	// Only update the commit time if the relation tuple already exists,
+	// instead of writing it again.
+	//
+	// in: query
+	Touch bool `json:"touch"`
+}
+
 // The basic ACL relation tuple
 //
 // swagger:parameters getCheck deleteRelationTuples
@@ -116,3 +139,163 @@ type queryRelationTuple struct {
 	// Either subject_set.* or subject_id are required.
 	SRelation string `json:"subject_set.relation"`
 }
+
+// swagger:parameters exportSnapshot
+type exportSnapshotParams struct {
+	// Whether to include the configured namespaces in the snapshot header.
+	//
+	// in: query
+	IncludeNamespaces bool `json:"include_namespaces"`
+}
+
+// swagger:parameters importSnapshot
+type importSnapshotParams struct {
+	// The snapshot as returned by the export endpoint.
+	//
+	// in: body
+	Payload *ketoapi.SnapshotHeader
+}
+
+// swagger:parameters getRelationTupleHistory
+type getHistoryParams struct {
+	// Only return changes at or after this time (RFC 3339).
+	//
+	// in: query
+	Since string `json:"since"`
+
+	// Only return changes before this time (RFC 3339).
+	//
+	// in: query
+	Until string `json:"until"`
+
+	// swagger:allOf
+	getRelationsParams
+}
+
+// swagger:parameters getRelationTupleCount
+type getCountParams struct {
+	// Return an estimate instead of an exact count. Estimates are much cheaper
+	// for large sets of relation tuples, but only supported on PostgreSQL.
+	//
+	// in: query
+	Estimate bool `json:"estimate"`
+
+	// swagger:allOf
+	relationQueryParams
+}
+
+type relationQueryParams struct {
+	// Namespace of the Relation Tuple
+	//
+	// in: query
+	Namespace string `json:"namespace"`
+
+	// Object of the Relation Tuple
+	//
+	// in: query
+	Object string `json:"object"`
+
+	// Relation of the Relation Tuple
+	//
+	// in: query
+	Relation string `json:"relation"`
+
+	// SubjectID of the Relation Tuple
+	//
+	// in: query
+	// Either subject_set.* or subject_id are required.
+	SubjectID string `json:"subject_id"`
+
+	// Namespace of the Subject Set
+	//
+	// in: query
+	// Either subject_set.* or subject_id are required.
+	SNamespace string `json:"subject_set.namespace"`
+
+	// Object of the Subject Set
+	//
+	// in: query
+	// Either subject_set.* or subject_id are required.
+	SObject string `json:"subject_set.object"`
+
+	// Relation of the Subject Set
+	//
+	// in: query
+	// Either subject_set.* or subject_id are required.
+	SRelation string `json:"subject_set.relation"`
+}
+
+// swagger:parameters restoreRelationTuples
+type restoreParams struct {
+	// Only restore relation tuples deleted at or after this time (RFC 3339).
+	//
+	// in: query
+	DeletedSince string `json:"deleted_since"`
+
+	// swagger:allOf
+	relationQueryParams
+}
+
+// swagger:parameters bulkDeleteRelationTuples
+type bulkDeleteParams struct {
+	// The number of relation tuples deleted per transaction. Defaults to 1000.
+	//
+	// in: query
+	BatchSize int `json:"batch_size"`
+
+	// How long to keep deleting before returning a continuation token, e.g.
+	// `30s`. Defaults to 10s.
+	//
+	// in: query
+	MaxDuration string `json:"max_duration"`
+
+	// The token returned by the previous request to continue deleting.
+	//
+	// in: query
+	PageToken string `json:"page_token"`
+
+	// swagger:allOf
+	relationQueryParams
+}
+
+// swagger:parameters planSchemaMigration applySchemaMigration
+type schemaMigrationBody struct {
+	// in: body
+	Payload ketoapi.SchemaMigration
+}
+
+// swagger:parameters listSchemaVersions
+type listSchemaVersionsParams struct {
+	// The namespace to list the schema versions of.
+	//
+	// required: true
+	// in: query
+	Namespace string `json:"namespace"`
+}
+
+// swagger:parameters startNamespaceRename
+type startNamespaceRenameBody struct {
+	// in: body
+	Payload ketoapi.StartNamespaceRename
+}
+
+// swagger:parameters renameNamespaceBatch
+type renameNamespaceBatchParams struct {
+	// The old name of the namespace.
+	//
+	// required: true
+	// in: path
+	From string `json:"from"`
+
+	// in: body
+	Payload ketoapi.NamespaceRenameBatch
+}
+
+// swagger:parameters finishNamespaceRename
+type finishNamespaceRenameParams struct {
+	// The old name of the namespace.
+	//
+	// required: true
+	// in: path
+	From string `json:"from"`
+}
@@ -1,9 +1,12 @@
+//go:build !js
+
 package relationtuple
 
 import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/ory/keto/ketoapi"
 
@@ -47,14 +50,24 @@ func (h *handler) TransactRelationTuples(ctx context.Context, req *rts.TransactR
 		return nil, err
 	}
 
+	deltas := patchDeltas(insertTuples, deleteTuples)
+	if err := h.d.QuotaEnforcer().Write(ctx, deltas); err != nil {
+		return nil, err
+	}
+	ctx, written := WithSnaptokenRecorder(ctx)
 	err = h.d.RelationTupleManager().TransactRelationTuples(ctx, its[:len(insertTuples)], its[len(insertTuples):])
+	h.d.AuditLogger().Write(ctx, deltas, err)
 	if err != nil {
 		return nil, err
 	}
 
+	snaptoken := "not yet implemented"
+	if _, ok := h.d.RelationTupleManager().(Snapshotter); ok {
+		snaptoken = written()
+	}
 	snaptokens := make([]string, len(insertTuples))
 	for i := range insertTuples {
-		snaptokens[i] = "not yet implemented"
+		snaptokens[i] = snaptoken
 	}
 	return &rts.TransactRelationTuplesResponse{
 		Snaptokens: snaptokens,
@@ -77,7 +90,12 @@ func (h *handler) DeleteRelationTuples(ctx context.Context, req *rts.DeleteRelat
 	if err != nil {
 		return nil, err
 	}
-	if err := h.d.RelationTupleManager().DeleteAllRelationTuples(ctx, iq); err != nil {
+	if err := h.d.QuotaEnforcer().WriteAll(ctx, &q); err != nil {
+		return nil, err
+	}
+	err = h.d.RelationTupleManager().DeleteAllRelationTuples(ctx, iq)
+	h.d.AuditLogger().Delete(ctx, &q, err)
+	if err != nil {
 		return nil, errors.WithStack(herodot.ErrInternalServerError.WithError(err.Error()))
 	}
 
@@ -88,7 +106,8 @@ func (h *handler) DeleteRelationTuples(ctx context.Context, req *rts.DeleteRelat
 //
 // # Create a Relation Tuple
 //
-// Use this endpoint to create a relation tuple.
+// Use this endpoint to create a relation tuple. If `touch` is set, writing a
+// relation tuple that already exists only updates its commit time.
 //
 //	Consumes:
 //	-  application/json
@@ -111,6 +130,16 @@ func (h *handler) createRelation(w http.ResponseWriter, r *http.Request, _ httpr
 		return
 	}
 
+	var touch bool
+	if raw := r.URL.Query().Get("touch"); raw != "" {
+		var err error
+		touch, err = strconv.ParseBool(raw)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not parse touch: %s", err)))
+			return
+		}
+	}
+
 	h.d.Logger().WithFields(rt.ToLoggerFields()).Debug("creating relation tuple")
 
 	it, err := h.d.Mapper().FromTuple(ctx, &rt)
@@ -119,7 +148,18 @@ func (h *handler) createRelation(w http.ResponseWriter, r *http.Request, _ httpr
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
-	if err := h.d.RelationTupleManager().WriteRelationTuples(ctx, it...); err != nil {
+	deltas := patchDeltas([]*ketoapi.RelationTuple{&rt}, nil)
+	if err := h.d.QuotaEnforcer().Write(ctx, deltas); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	write := h.d.RelationTupleManager().WriteRelationTuples
+	if touch {
+		write = h.d.RelationTupleManager().TouchRelationTuples
+	}
+	err = write(ctx, it...)
+	h.d.AuditLogger().Write(ctx, deltas, err)
+	if err != nil {
 		h.d.Logger().WithError(err).WithFields(rt.ToLoggerFields()).Errorf("got an error while creating the relation tuple")
 		h.d.Writer().WriteError(w, r, err)
 		return
@@ -171,7 +211,13 @@ func (h *handler) deleteRelations(w http.ResponseWriter, r *http.Request, _ http
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
-	if err := h.d.RelationTupleManager().DeleteAllRelationTuples(ctx, iq); err != nil {
+	if err := h.d.QuotaEnforcer().WriteAll(ctx, query); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	err = h.d.RelationTupleManager().DeleteAllRelationTuples(ctx, iq)
+	h.d.AuditLogger().Delete(ctx, query, err)
+	if err != nil {
 		l.WithError(err).Errorf("got an error while deleting relation tuples")
 		h.d.Writer().WriteError(w, r, herodot.ErrInternalServerError.WithError(err.Error()))
 		return
@@ -189,6 +235,19 @@ func internalTuplesWithAction(deltas []*ketoapi.PatchDelta, action ketoapi.Patch
 	return
 }
 
+// patchDeltas returns the inserted and deleted relation tuples as deltas, for
+// the audit log.
+func patchDeltas(insert, delete []*ketoapi.RelationTuple) []*ketoapi.PatchDelta {
+	deltas := make([]*ketoapi.PatchDelta, 0, len(insert)+len(delete))
+	for _, t := range insert {
+		deltas = append(deltas, &ketoapi.PatchDelta{Action: ketoapi.ActionInsert, RelationTuple: t})
+	}
+	for _, t := range delete {
+		deltas = append(deltas, &ketoapi.PatchDelta{Action: ketoapi.ActionDelete, RelationTuple: t})
+	}
+	return deltas
+}
+
 // swagger:route PATCH /admin/relation-tuples write patchRelationTuples
 //
 // # Patch Multiple Relation Tuples
@@ -238,12 +297,18 @@ func (h *handler) patchRelationTuples(w http.ResponseWriter, r *http.Request, _
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
-	if err := h.d.RelationTupleManager().
+	deltas = patchDeltas(insertTuples, deleteTuples)
+	if err := h.d.QuotaEnforcer().Write(ctx, deltas); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	err = h.d.RelationTupleManager().
 		TransactRelationTuples(
 			ctx,
 			its[:len(insertTuples)],
-			its[len(insertTuples):]); err != nil {
-
+			its[len(insertTuples):])
+	h.d.AuditLogger().Write(ctx, deltas, err)
+	if err != nil {
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
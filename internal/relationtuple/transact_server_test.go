@@ -103,6 +103,33 @@ func TestWriteHandlers(t *testing.T) {
 			})
 		})
 
+		t.Run("case=touch does not duplicate tuples", func(t *testing.T) {
+			nspace := addNamespace(t)
+
+			rt := &ketoapi.RelationTuple{
+				Namespace: nspace.Name,
+				Object:    "obj",
+				Relation:  "rel",
+				SubjectID: x.Ptr("subj"),
+			}
+			payload, err := json.Marshal(rt)
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(http.MethodPut, ts.URL+relationtuple.WriteRouteBase+"?touch=true", bytes.NewReader(payload))
+				require.NoError(t, err)
+				resp, err := ts.Client().Do(req)
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusCreated, resp.StatusCode)
+			}
+
+			iq, err := reg.Mapper().FromQuery(ctx, &ketoapi.RelationQuery{Namespace: &nspace.Name})
+			require.NoError(t, err)
+			actual, _, err := reg.RelationTupleManager().GetRelationTuples(ctx, iq)
+			require.NoError(t, err)
+			assert.Len(t, actual, 1)
+		})
+
 		t.Run("case=returns bad request on JSON parse error", func(t *testing.T) {
 			resp := doCreate([]byte("foo"))
 			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
@@ -1,8 +1,11 @@
+//go:build !js
+
 package relationtuple
 
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/ory/keto/internal/driver/config"
 	"github.com/ory/keto/internal/x"
@@ -25,6 +28,44 @@ type (
 		MapStringsToUUIDs(ctx context.Context, s ...string) ([]uuid.UUID, error)
 		MapUUIDsToStrings(ctx context.Context, u ...uuid.UUID) ([]string, error)
 	}
+	MappingCipherManagerProvider interface {
+		// MappingCipherManager returns nil if the UUID mappings are not
+		// stored in the SQL database.
+		MappingCipherManager() MappingCipherManager
+	}
+	MappingCipherManager interface {
+		// ReencryptMappings encrypts all UUID mappings with the current
+		// secret, including the ones that were written before encryption was
+		// enabled. It returns the number of changed mappings.
+		ReencryptMappings(ctx context.Context) (int, error)
+	}
+	MappingGCManagerProvider interface {
+		// MappingGCManager returns nil if the UUID mappings are not stored in
+		// the SQL database, or the relation tuples are sharded or stored in
+		// more than one database, as the mappings are referenced from all of
+		// them.
+		MappingGCManager() MappingGCManager
+	}
+	MappingGCManager interface {
+		// CollectOrphanedMappings marks the UUID mappings that nothing
+		// references, and deletes the ones that were marked for at least the
+		// grace period.
+		CollectOrphanedMappings(ctx context.Context, opts MappingGCOptions) (*MappingGCResult, error)
+	}
+	MappingGCOptions struct {
+		GracePeriod time.Duration
+		BatchSize   int
+		// DryRun only counts the mappings that would be changed.
+		DryRun bool
+	}
+	MappingGCResult struct {
+		// Marked is the number of newly orphaned mappings.
+		Marked int
+		// Unmarked is the number of marked mappings that are referenced
+		// again.
+		Unmarked int
+		Deleted  int
+	}
 	MapperProvider interface {
 		Mapper() *Mapper
 	}
@@ -0,0 +1,283 @@
+//go:build !js
+
+package relationtuple
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/graceful"
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"github.com/rs/cors"
+
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	WatchRoute = WriteRouteBase + "/watch"
+
+	// watchHeartbeat is how often idle connections are kept alive.
+	watchHeartbeat = 15 * time.Second
+	// watchRetry is the reconnection delay in milliseconds that is suggested
+	// to clients of server-sent events.
+	watchRetry = 100
+)
+
+// watchStreamDuration ends server-sent event streams before the write timeout
+// of the server. Browsers reconnect right away and resume after the last
+// event ID.
+var watchStreamDuration = graceful.DefaultWriteTimeout - 2*time.Second
+
+// watchStream sends the events of the watch endpoint to one client.
+type watchStream interface {
+	send(e *ketoapi.WatchEvent) error
+	ping() error
+}
+
+// watchRelationTuples streams the relation tuple changes and namespace reloads
+// over WebSocket, or as server-sent events otherwise. The relation tuples can
+// be filtered like in the history, which has to be enabled. Changes after a
+// previous event are resumed with the "after" query parameter or the
+// Last-Event-ID header.
+func (h *handler) watchRelationTuples(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	if !h.d.Config(ctx).HistoryEnabled() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The relation tuple history is not enabled, which is required to watch the relation tuples.")))
+		return
+	}
+
+	q := r.URL.Query()
+	query, err := (&ketoapi.RelationQuery{}).FromURLQuery(q)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()))
+		return
+	}
+	iq, err := h.d.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	after := q.Get("after")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		after = id
+	}
+	var cursor int64
+	if after != "" {
+		if cursor, err = strconv.ParseInt(after, 10, 64); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not parse the cursor: %s", err)))
+			return
+		}
+	} else if cursor, err = h.d.RelationTupleHistoryManager().LatestRelationTupleHistoryID(ctx); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	hq := &HistoryQuery{RelationQuery: *iq}
+	if websocket.IsWebSocketUpgrade(r) {
+		h.watchWebSocket(w, r, hq, cursor)
+		return
+	}
+	h.watchEventStream(w, r, hq, cursor)
+}
+
+func (h *handler) watchWebSocket(w http.ResponseWriter, r *http.Request, query *HistoryQuery, cursor int64) {
+	// Without CORS, only connections from the same origin are accepted.
+	upgrader := websocket.Upgrader{}
+	if options, enabled := h.d.Config(r.Context()).CORS("write"); enabled {
+		upgrader.CheckOrigin = cors.New(options).OriginAllowed
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already responded with an error.
+		h.d.Logger().WithError(err).Debug("could not upgrade the watch request to WebSocket")
+		return
+	}
+	defer conn.Close()
+
+	// The deadlines of the server do not apply to the upgraded connection.
+	_ = conn.SetReadDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		// Clients do not send messages, but the control messages have to be
+		// read. Watching stops once the client closed the connection.
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := h.watch(ctx, &webSocketStream{conn: conn}, query, cursor); err != nil {
+		h.d.Logger().WithError(err).Error("could not watch the relation tuples")
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "could not watch the relation tuples"), time.Now().Add(time.Second))
+		return
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+}
+
+func (h *handler) watchEventStream(w http.ResponseWriter, r *http.Request, query *HistoryQuery, cursor int64) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReason("The response cannot be streamed.")))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// The cursor is sent without an event, so that browsers resume after it
+	// even if the stream ends before the first change.
+	if _, err := fmt.Fprintf(w, "retry: %d\nid: %d\n\n", watchRetry, cursor); err != nil {
+		return
+	}
+	f.Flush()
+
+	ctx, cancel := context.WithTimeout(r.Context(), watchStreamDuration)
+	defer cancel()
+	if err := h.watch(ctx, &eventStream{w: w, f: f}, query, cursor); err != nil {
+		h.d.Logger().WithError(err).Error("could not watch the relation tuples")
+	}
+}
+
+// watch sends the namespaces, then the relation tuple changes after the
+// cursor and the namespaces whenever they are reloaded, until the context is
+// done. Errors of the stream mean that the client is gone, so they are not
+// returned.
+func (h *handler) watch(ctx context.Context, s watchStream, query *HistoryQuery, cursor int64) error {
+	ticker := time.NewTicker(h.d.Config(ctx).HistoryWatchInterval())
+	defer ticker.Stop()
+
+	var namespaces string
+	lastSent := time.Now()
+	for {
+		digest, names, err := h.namespaceNames(ctx)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if digest != namespaces {
+			if err := s.send(&ketoapi.WatchEvent{Type: ketoapi.WatchEventNamespaces, ID: strconv.FormatInt(cursor, 10), Namespaces: names}); err != nil {
+				return nil
+			}
+			namespaces, lastSent = digest, time.Now()
+		}
+
+		for {
+			entries, nextPage, err := h.d.RelationTupleHistoryManager().GetRelationTupleHistory(ctx, query,
+				x.WithToken(strconv.FormatInt(cursor, 10)), x.WithSize(h.d.Config(ctx).PageSize(0)))
+			if ctx.Err() != nil {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			changes, err := h.historyChanges(ctx, entries)
+			if err != nil {
+				return err
+			}
+			for i, e := range entries {
+				cursor = e.ID
+				if err := s.send(&ketoapi.WatchEvent{Type: ketoapi.WatchEventRelationTuple, ID: strconv.FormatInt(cursor, 10), Change: changes[i]}); err != nil {
+					return nil
+				}
+				lastSent = time.Now()
+			}
+			if nextPage == "" {
+				break
+			}
+		}
+
+		if time.Since(lastSent) >= watchHeartbeat {
+			if err := s.ping(); err != nil {
+				return nil
+			}
+			lastSent = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// namespaceNames returns the sorted names of the namespaces, and a digest that
+// changes whenever the namespaces are reloaded with a different
+// configuration.
+func (h *handler) namespaceNames(ctx context.Context) (string, []string, error) {
+	nm, err := h.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return "", nil, err
+	}
+	nn, err := nm.Namespaces(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	names := make([]string, len(nn))
+	for i, n := range nn {
+		names[i] = n.Name
+	}
+	sort.Strings(names)
+	digest, err := namespace.Digest(nn)
+	if err != nil {
+		return "", nil, err
+	}
+	return digest, names, nil
+}
+
+type webSocketStream struct {
+	conn *websocket.Conn
+}
+
+func (s *webSocketStream) send(e *ketoapi.WatchEvent) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(graceful.DefaultWriteTimeout))
+	return s.conn.WriteJSON(e)
+}
+
+func (s *webSocketStream) ping() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(graceful.DefaultWriteTimeout))
+}
+
+type eventStream struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func (s *eventStream) send(e *ketoapi.WatchEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(s.w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+		return err
+	}
+	s.f.Flush()
+	return nil
+}
+
+func (s *eventStream) ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	s.f.Flush()
+	return nil
+}
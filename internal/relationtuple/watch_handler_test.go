@@ -0,0 +1,138 @@
+package relationtuple_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestWatchHandler(t *testing.T) {
+	ctx := context.Background()
+
+	reg := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{{Name: "files"}, {Name: "groups"}}))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, true))
+	require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryWatchInterval, "10ms"))
+	r := &x.WriteRouter{Router: httprouter.New()}
+	relationtuple.NewHandler(reg).RegisterWriteRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	write := func(t *testing.T, tuples ...*ketoapi.RelationTuple) {
+		its, err := reg.Mapper().FromTuple(ctx, tuples...)
+		require.NoError(t, err)
+		require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, its...))
+	}
+	alice := &ketoapi.RelationTuple{Namespace: "files", Object: "a", Relation: "viewer", SubjectID: x.Ptr("alice")}
+	bob := &ketoapi.RelationTuple{Namespace: "files", Object: "b", Relation: "viewer", SubjectID: x.Ptr("bob")}
+	editors := &ketoapi.RelationTuple{Namespace: "groups", Object: "editors", Relation: "member", SubjectID: x.Ptr("bob")}
+
+	t.Run("case=websocket", func(t *testing.T) {
+		// Changes before watching are not sent.
+		write(t, alice)
+
+		conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+relationtuple.WatchRoute+"?namespace=files", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		t.Cleanup(func() { _ = conn.Close() })
+
+		read := func(t *testing.T) *ketoapi.WatchEvent {
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			var e ketoapi.WatchEvent
+			require.NoError(t, conn.ReadJSON(&e))
+			return &e
+		}
+
+		e := read(t)
+		assert.Equal(t, ketoapi.WatchEventNamespaces, e.Type)
+		assert.Equal(t, []string{"files", "groups"}, e.Namespaces)
+		assert.NotEmpty(t, e.ID)
+
+		write(t, editors, bob)
+		e = read(t)
+		assert.Equal(t, ketoapi.WatchEventRelationTuple, e.Type)
+		assert.Equal(t, ketoapi.ActionInsert, e.Change.Action)
+		assert.Equal(t, bob, e.Change.RelationTuple)
+
+		require.NoError(t, reg.Config(ctx).Set(config.KeyNamespaces, []*namespace.Namespace{{Name: "files"}, {Name: "groups"}, {Name: "users"}}))
+		e = read(t)
+		assert.Equal(t, ketoapi.WatchEventNamespaces, e.Type)
+		assert.Equal(t, []string{"files", "groups", "users"}, e.Namespaces)
+	})
+
+	t.Run("case=server-sent events resume after the last event", func(t *testing.T) {
+		// Both were written before, so they are deleted to be written again.
+		its, err := reg.Mapper().FromTuple(ctx, alice, bob)
+		require.NoError(t, err)
+		require.NoError(t, reg.RelationTupleManager().DeleteRelationTuples(ctx, its...))
+
+		latest, err := reg.RelationTupleHistoryManager().LatestRelationTupleHistoryID(ctx)
+		require.NoError(t, err)
+		write(t, alice, bob)
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+relationtuple.WatchRoute+"?namespace=files", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(latest+1, 10))
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		var lines []string
+		var data []string
+		scanner := bufio.NewScanner(resp.Body)
+		for len(data) < 2 && scanner.Scan() {
+			lines = append(lines, scanner.Text())
+			if strings.HasPrefix(scanner.Text(), "data: ") {
+				data = append(data, strings.TrimPrefix(scanner.Text(), "data: "))
+			}
+		}
+		require.NoError(t, scanner.Err())
+		assert.Equal(t, []string{"retry: 100", "id: " + strconv.FormatInt(latest+1, 10), ""}, lines[:3])
+		assert.Contains(t, lines, "event: namespaces")
+
+		var e ketoapi.WatchEvent
+		require.NoError(t, json.Unmarshal([]byte(data[1]), &e))
+		assert.Equal(t, ketoapi.WatchEventRelationTuple, e.Type)
+		assert.Equal(t, bob, e.Change.RelationTuple)
+		assert.Equal(t, strconv.FormatInt(latest+2, 10), e.ID)
+		assert.Contains(t, lines, "id: "+e.ID)
+	})
+
+	t.Run("case=malformed cursor", func(t *testing.T) {
+		resp, err := ts.Client().Get(ts.URL + relationtuple.WatchRoute + "?" + url.Values{"after": {"not-a-number"}}.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("case=history disabled", func(t *testing.T) {
+		require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, false))
+		t.Cleanup(func() { require.NoError(t, reg.Config(ctx).Set(config.KeyHistoryEnabled, true)) })
+
+		resp, err := ts.Client().Get(ts.URL + relationtuple.WatchRoute)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -0,0 +1,531 @@
+package schema
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+type (
+	// Document is an analyzed Ory Permission Language file. It answers the
+	// questions of editor integrations, such as where the symbol at an offset
+	// is declared. The analysis is based on the tokens of the file, so that it
+	// also works for files that do not parse while they are edited. All
+	// offsets are byte offsets into the input.
+	Document struct {
+		input      string
+		items      []item      // tokens up to the first lexer error, without comments
+		namespaces []namespace // merged model, including imported namespaces
+		errors     []error     // parse and type errors of the file
+		symbols    []symbol    // declarations and references in the file
+		decls      map[relationRef]Range
+		declared   []relationRef            // declarations in the order of the file
+		permits    map[relationRef]bool     // declarations that are permissions
+		types      map[relationRef][]string // declared subject namespaces of relations
+		classes    []classSpan
+	}
+
+	// Range is a range of byte offsets in the input.
+	Range struct {
+		Start, End int
+	}
+
+	// Diagnostic is a problem in a document.
+	Diagnostic struct {
+		Range    Range
+		Severity Severity
+		Message  string
+	}
+
+	// CompletionKind is the kind of symbol a completion inserts.
+	CompletionKind int
+
+	// Completion is a suggestion to complete the input at an offset.
+	Completion struct {
+		Label  string
+		Kind   CompletionKind
+		Detail string
+	}
+
+	// symbol is a declaration of or a reference to a namespace or relation.
+	// The relation is empty for namespaces. References through the variable
+	// of a traversal refer to the relation in all types of the traversed
+	// relation.
+	symbol struct {
+		Range
+		ref relationRef
+		via *relationRef
+	}
+
+	classSpan struct {
+		Range
+		name string
+	}
+)
+
+const (
+	CompletionNamespace CompletionKind = iota + 1
+	CompletionRelation
+	CompletionPermission
+	CompletionKeyword
+)
+
+// Analyze analyzes a single Ory Permission Language input. As with Parse,
+// imports of other files can not be resolved.
+func Analyze(input string) *Document {
+	p := parseSingle(input)
+	return newDocument(input, p.namespaces, p.errors)
+}
+
+// AnalyzeFile analyzes the input as the content of the file with the given
+// name in fsys. Imports are resolved in fsys.
+func AnalyzeFile(fsys fs.FS, name, input string) *Document {
+	name = path.Clean(name)
+	namespaces, parsers, errs := fileSet{fsys: fsys, overlays: map[string]string{name: input}}.parse(name)
+	if p, ok := parsers[name]; ok {
+		errs = p.errors
+	}
+	return newDocument(input, namespaces, errs)
+}
+
+// AnalyzeLocalFile analyzes the input as the content of the local file fn.
+// Imports are resolved on the local file system.
+func AnalyzeLocalFile(fn, input string) *Document {
+	fsys, names, err := localFiles(fn)
+	if err != nil {
+		return Analyze(input)
+	}
+	return AnalyzeFile(fsys, names[0], input)
+}
+
+func newDocument(input string, namespaces []namespace, errs []error) *Document {
+	d := &Document{
+		input:      input,
+		namespaces: namespaces,
+		errors:     errs,
+		decls:      make(map[relationRef]Range),
+		permits:    make(map[relationRef]bool),
+		types:      make(map[relationRef][]string),
+	}
+	l := Lex("input", input)
+	for i := l.nextItem(); i.Typ != itemEOF && i.Typ != itemError; i = l.nextItem() {
+		if i.Typ != itemComment {
+			d.items = append(d.items, i)
+		}
+	}
+	d.scan()
+	return d
+}
+
+// at returns the token at index i, or an EOF token if there is none.
+func (d *Document) at(i int) item {
+	if i < 0 || i >= len(d.items) {
+		return item{Typ: itemEOF}
+	}
+	return d.items[i]
+}
+
+// scan collects the declarations and references of the document.
+func (d *Document) scan() {
+	var (
+		depth, parens          int
+		class, block, relation string
+	)
+	for i, it := range d.items {
+		switch it.Typ {
+		case itemKeywordClass:
+			if name := d.at(i + 1); name.Typ == itemIdentifier {
+				class = name.Val
+				d.declare(relationRef{namespace: class}, name)
+				d.classes = append(d.classes, classSpan{Range{it.Start, len(d.input)}, class})
+			}
+		case itemBraceLeft:
+			depth++
+			if depth == 2 {
+				block = d.at(i - 2).Val
+			}
+		case itemBraceRight:
+			depth--
+			if depth < 2 {
+				block = ""
+			}
+			if depth <= 0 && class != "" {
+				d.classes[len(d.classes)-1].End = it.End
+				depth, class = 0, ""
+			}
+		case itemParenLeft:
+			parens++
+		case itemParenRight:
+			parens--
+		case itemStringLiteral:
+			// The relation of SubjectSet<Namespace, "relation">.
+			if block == "related" && d.at(i-1).Typ == itemOperatorComma && d.at(i-3).Typ == itemAngledLeft && d.at(i-4).Val == "SubjectSet" {
+				d.refer(it, relationRef{d.at(i - 2).Val, it.Val}, nil)
+			}
+		case itemIdentifier:
+			if class == "" {
+				continue
+			}
+			if ref, via, ok := d.relationAccess(i, class); ok {
+				d.refer(it, ref, via)
+				continue
+			}
+			next := d.at(i + 1)
+			switch {
+			case block == "related" && depth == 2 && next.Typ == itemOperatorColon:
+				d.declare(relationRef{class, it.Val}, it)
+				relation = it.Val
+			case block == "related" && depth == 2 && it.Val != "SubjectSet" && it.Val != "Conditional":
+				d.refer(it, relationRef{namespace: it.Val}, nil)
+				ref := relationRef{class, relation}
+				d.types[ref] = append(d.types[ref], it.Val)
+			case block == "permits" && depth == 2 && parens == 0 && next.Typ == itemOperatorColon:
+				d.declare(relationRef{class, it.Val}, it)
+				d.permits[relationRef{class, it.Val}] = true
+			}
+		}
+	}
+}
+
+func (d *Document) declare(ref relationRef, it item) {
+	r := Range{it.Start, it.End}
+	if _, ok := d.decls[ref]; !ok {
+		d.decls[ref] = r
+		d.declared = append(d.declared, ref)
+	}
+	d.symbols = append(d.symbols, symbol{Range: r, ref: ref})
+}
+
+func (d *Document) refer(it item, ref relationRef, via *relationRef) {
+	d.symbols = append(d.symbols, symbol{Range: Range{it.Start, it.End}, ref: ref, via: via})
+}
+
+// relationAccess returns the relation that the identifier at index i refers
+// to, if it is accessed as `this.related.name`, `this.permits.name`, or
+// through the variable of a traversal.
+func (d *Document) relationAccess(i int, class string) (ref relationRef, via *relationRef, ok bool) {
+	if d.at(i-1).Typ != itemOperatorDot || d.at(i-3).Typ != itemOperatorDot {
+		return ref, nil, false
+	}
+	if verb := d.at(i - 2).Val; verb != "related" && verb != "permits" {
+		return ref, nil, false
+	}
+	switch obj := d.at(i - 4); obj.Typ {
+	case itemKeywordThis:
+		return relationRef{class, d.items[i].Val}, nil, true
+	case itemIdentifier:
+		if traversed, ok := d.traversal(i-4, class); ok {
+			return relationRef{relation: d.items[i].Val}, &traversed, true
+		}
+	}
+	return ref, nil, false
+}
+
+// traversal returns the traversed relation if the identifier at index i is
+// the variable of a traversal, e.g. `p` in
+// `this.related.parents.traverse((p) => p.related.viewers...)`.
+func (d *Document) traversal(i int, class string) (relationRef, bool) {
+	name := d.items[i].Val
+	for j := i - 1; j >= 0; j-- {
+		if d.items[j].Val != name || d.items[j].Typ != itemIdentifier {
+			continue
+		}
+		open := j - 1
+		switch {
+		case d.at(j+1).Typ == itemOperatorArrow:
+		case d.at(j+1).Typ == itemParenRight && d.at(j+2).Typ == itemOperatorArrow && d.at(j-1).Typ == itemParenLeft:
+			open = j - 2
+		default:
+			continue
+		}
+		if d.at(open).Typ == itemParenLeft && d.at(open-1).Val == "traverse" &&
+			d.at(open-2).Typ == itemOperatorDot && d.at(open-4).Typ == itemOperatorDot &&
+			d.at(open-5).Val == "related" && d.at(open-7).Typ == itemKeywordThis {
+			return relationRef{class, d.at(open - 3).Val}, true
+		}
+		return relationRef{}, false
+	}
+	return relationRef{}, false
+}
+
+// targets returns the namespaces or relations that the symbol refers to.
+func (d *Document) targets(s symbol) []relationRef {
+	if s.via == nil {
+		return []relationRef{s.ref}
+	}
+	// Use the declared types if the namespace does not parse.
+	types := d.types[*s.via]
+	if r, ok := namespaceQuery(d.namespaces).findRelation(s.via.namespace, s.via.relation); ok {
+		types = make([]string, len(r.Types))
+		for i, t := range r.Types {
+			types[i] = t.Namespace
+		}
+	}
+	var refs []relationRef
+	seen := make(map[string]bool)
+	for _, t := range types {
+		if !seen[t] {
+			seen[t] = true
+			refs = append(refs, relationRef{t, s.ref.relation})
+		}
+	}
+	return refs
+}
+
+func (d *Document) symbolAt(offset int) (symbol, bool) {
+	for _, s := range d.symbols {
+		if s.Start <= offset && offset <= s.End {
+			return s, true
+		}
+	}
+	return symbol{}, false
+}
+
+// classAt returns the name of the namespace declared around the offset.
+func (d *Document) classAt(offset int) string {
+	for _, c := range d.classes {
+		if c.Start <= offset && offset <= c.End {
+			return c.name
+		}
+	}
+	return ""
+}
+
+// Diagnostics returns the parse and type errors of the document. If there are
+// none, the findings of the linter for the namespaces declared in the document
+// are returned.
+func (d *Document) Diagnostics() []Diagnostic {
+	diags := make([]Diagnostic, 0, len(d.errors))
+	for _, err := range d.errors {
+		diag := Diagnostic{Severity: SeverityError, Message: err.Error()}
+		if e, ok := err.(*ParseError); ok {
+			diag.Range = Range{e.item.Start, e.item.End}
+			diag.Message = e.msg
+		}
+		diags = append(diags, diag)
+	}
+	if len(d.errors) > 0 {
+		return diags
+	}
+
+	for _, f := range Lint(d.namespaces) {
+		r, ok := d.decls[relationRef{f.Namespace, f.Relation}]
+		if !ok {
+			// The namespace is declared in an imported file.
+			continue
+		}
+		diags = append(diags, Diagnostic{Range: r, Severity: f.Severity, Message: f.Message})
+	}
+	return diags
+}
+
+// Definition returns the range of the declaration of the symbol at the offset.
+// Only declarations in the document itself are found.
+func (d *Document) Definition(offset int) (Range, bool) {
+	s, ok := d.symbolAt(offset)
+	if !ok {
+		return Range{}, false
+	}
+	for _, t := range d.targets(s) {
+		if r, ok := d.decls[t]; ok {
+			return r, true
+		}
+	}
+	return Range{}, false
+}
+
+// RenameNamespace returns the input with the declaration of and all
+// references to the namespace renamed. Everything else, including comments and
+// formatting, is kept.
+func (d *Document) RenameNamespace(from, to string) string {
+	var (
+		b    strings.Builder
+		last int
+	)
+	for _, s := range d.symbols {
+		if s.ref != (relationRef{namespace: from}) || s.via != nil {
+			continue
+		}
+		b.WriteString(d.input[last:s.Start])
+		b.WriteString(to)
+		last = s.End
+	}
+	b.WriteString(d.input[last:])
+	return b.String()
+}
+
+// Hover returns a Markdown description of the symbol at the offset and its
+// range.
+func (d *Document) Hover(offset int) (string, Range, bool) {
+	s, ok := d.symbolAt(offset)
+	if !ok {
+		return "", Range{}, false
+	}
+	var descriptions []string
+	for _, t := range d.targets(s) {
+		if t.relation == "" {
+			if n, ok := namespaceQuery(d.namespaces).find(t.namespace); ok {
+				descriptions = append(descriptions, describeNamespace(n))
+			}
+		} else if r, ok := namespaceQuery(d.namespaces).findRelation(t.namespace, t.relation); ok {
+			descriptions = append(descriptions, describeRelation(t.namespace, r))
+		}
+	}
+	if len(descriptions) == 0 {
+		return "", Range{}, false
+	}
+	return strings.Join(descriptions, "\n\n---\n\n"), s.Range, true
+}
+
+func describeNamespace(n *namespace) string {
+	var relations, permissions []string
+	for _, r := range n.Relations {
+		if r.SubjectSetRewrite == nil {
+			relations = append(relations, "`"+r.Name+"`")
+		} else {
+			permissions = append(permissions, "`"+r.Name+"`")
+		}
+	}
+	s := fmt.Sprintf("```ts\nclass %s implements Namespace\n```", n.Name)
+	if len(relations) > 0 {
+		s += "\n\nRelations: " + strings.Join(relations, ", ")
+	}
+	if len(permissions) > 0 {
+		s += "\n\nPermissions: " + strings.Join(permissions, ", ")
+	}
+	return s
+}
+
+func describeRelation(namespace string, r *ast.Relation) string {
+	if r.SubjectSetRewrite != nil {
+		return fmt.Sprintf("```ts\n%s.permits.%s(ctx: Context): boolean\n```\n\nPermission of namespace `%s`.", namespace, r.Name, namespace)
+	}
+	s := fmt.Sprintf("```ts\n%s.related.%s: %s\n```\n\nRelation of namespace `%s`.", namespace, r.Name, typeString(r), namespace)
+	if r.MaxSubjects > 0 {
+		s += fmt.Sprintf(" An object can have at most %d subject(s).", r.MaxSubjects)
+	}
+	return s
+}
+
+// typeString returns the type of the relation as it is declared.
+func typeString(r *ast.Relation) string {
+	types := make([]string, len(r.Types))
+	for i, t := range r.Types {
+		if t.Relation != "" {
+			types[i] = fmt.Sprintf("SubjectSet<%s, %q>", t.Namespace, t.Relation)
+		} else {
+			types[i] = t.Namespace
+		}
+	}
+	s := strings.Join(types, " | ")
+	if len(types) > 1 {
+		s = "(" + s + ")"
+	}
+	if r.Condition != nil {
+		s = "Conditional<" + s + ", {...}>"
+	}
+	if r.MaxSubjects == 0 {
+		s += "[]"
+	}
+	return s
+}
+
+// Completions returns the suggestions for the identifier at the offset. After
+// `this.related.` or `this.permits.`, the relations or permissions of the
+// namespace are suggested, and of the traversed namespaces after the variable
+// of a traversal. Otherwise, namespaces are suggested.
+func (d *Document) Completions(offset int) []Completion {
+	// The index of the last token before the identifier that is completed.
+	last := -1
+	for i, it := range d.items {
+		if it.End > offset || it.End == offset && it.Typ == itemIdentifier {
+			break
+		}
+		last = i
+	}
+
+	if d.at(last).Typ == itemOperatorDot {
+		if d.at(last-1).Typ == itemKeywordThis {
+			return []Completion{
+				{Label: "related", Kind: CompletionKeyword},
+				{Label: "permits", Kind: CompletionKeyword},
+			}
+		}
+		if verb := d.at(last - 1).Val; (verb == "related" || verb == "permits") && d.at(last-2).Typ == itemOperatorDot {
+			class := d.classAt(offset)
+			var namespaces []string
+			switch obj := d.at(last - 3); obj.Typ {
+			case itemKeywordThis:
+				namespaces = []string{class}
+			case itemIdentifier:
+				if traversed, ok := d.traversal(last-3, class); ok {
+					for _, t := range d.targets(symbol{ref: relationRef{}, via: &traversed}) {
+						namespaces = append(namespaces, t.namespace)
+					}
+				}
+			}
+			return d.relationCompletions(namespaces, verb == "permits")
+		}
+	}
+
+	var completions []Completion
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			completions = append(completions, Completion{Label: name, Kind: CompletionNamespace, Detail: "namespace"})
+		}
+	}
+	for _, ref := range d.declared {
+		if ref.relation == "" {
+			add(ref.namespace)
+		}
+	}
+	for _, n := range d.namespaces {
+		add(n.Name)
+	}
+	return completions
+}
+
+// relationCompletions suggests the relations or permissions of the
+// namespaces. The declarations of the document are used for namespaces that
+// do not parse, e.g. because they are currently edited.
+func (d *Document) relationCompletions(namespaces []string, permissions bool) []Completion {
+	var completions []Completion
+	seen := make(map[string]bool)
+	add := func(name string, r *ast.Relation) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if permissions {
+			completions = append(completions, Completion{Label: name, Kind: CompletionPermission, Detail: "permission"})
+			return
+		}
+		c := Completion{Label: name, Kind: CompletionRelation, Detail: "relation"}
+		if r != nil {
+			c.Detail = typeString(r)
+		}
+		completions = append(completions, c)
+	}
+
+	for _, name := range namespaces {
+		if n, ok := namespaceQuery(d.namespaces).find(name); ok {
+			for i := range n.Relations {
+				if r := &n.Relations[i]; (r.SubjectSetRewrite != nil) == permissions {
+					add(r.Name, r)
+				}
+			}
+			continue
+		}
+		for _, ref := range d.declared {
+			if ref.namespace == name && ref.relation != "" && d.permits[ref] == permissions {
+				add(ref.relation, nil)
+			}
+		}
+	}
+	return completions
+}
@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const analysisInput = `class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: (User | SubjectSet<Group, "members">)[]
+  }
+}
+
+class Folder implements Namespace {
+  related: {
+    owner: User
+    viewers: SubjectSet<Group, "members">[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.owner.includes(ctx.subject) ||
+      this.related.viewers.includes(ctx.subject),
+  }
+}
+
+class File implements Namespace {
+  related: {
+    parents: Folder[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.parents.traverse((p) => p.permits.view(ctx)),
+  }
+}
+`
+
+// offset returns the offset of the nth occurrence of substr in the input.
+func offset(t *testing.T, input, substr string, n int) int {
+	t.Helper()
+	idx := -1
+	for i := 0; i < n; i++ {
+		next := strings.Index(input[idx+1:], substr)
+		require.NotEqual(t, -1, next, "%q has less than %d occurrences", substr, n)
+		idx += next + 1
+	}
+	return idx
+}
+
+func TestDocument(t *testing.T) {
+	d := Analyze(analysisInput)
+
+	t.Run("method=Definition", func(t *testing.T) {
+		for _, tc := range []struct {
+			name       string
+			from, to   string
+			fromN, toN int
+		}{
+			{name: "namespace in relation type", from: "User |", fromN: 1, to: "User", toN: 1},
+			{name: "subject set relation", from: `members">)[]`, fromN: 1, to: "members:", toN: 1},
+			{name: "subject set namespace", from: `Group, "members">[]`, fromN: 1, to: "Group", toN: 1},
+			{name: "this.related", from: "viewers.includes", fromN: 1, to: "viewers:", toN: 1},
+			{name: "traversed relation", from: "parents.traverse", fromN: 1, to: "parents:", toN: 1},
+			{name: "traversal variable", from: "view(ctx)", fromN: 1, to: "view:", toN: 1},
+			{name: "declaration", from: "owner", fromN: 1, to: "owner", toN: 1},
+		} {
+			t.Run("case="+tc.name, func(t *testing.T) {
+				r, ok := d.Definition(offset(t, analysisInput, tc.from, tc.fromN) + 1)
+				require.True(t, ok)
+				start := offset(t, analysisInput, tc.to, tc.toN)
+				assert.Equal(t, start, r.Start)
+			})
+		}
+
+		_, ok := d.Definition(offset(t, analysisInput, "implements", 1))
+		assert.False(t, ok)
+	})
+
+	t.Run("method=Hover", func(t *testing.T) {
+		s, r, ok := d.Hover(offset(t, analysisInput, "Folder[]", 1))
+		require.True(t, ok)
+		assert.Contains(t, s, "class Folder implements Namespace")
+		assert.Contains(t, s, "Relations: `owner`, `viewers`")
+		assert.Contains(t, s, "Permissions: `view`")
+		assert.Equal(t, "Folder", analysisInput[r.Start:r.End])
+
+		s, _, ok = d.Hover(offset(t, analysisInput, "owner", 1))
+		require.True(t, ok)
+		assert.Contains(t, s, "Folder.related.owner: User\n")
+		assert.Contains(t, s, "at most 1 subject")
+
+		s, _, ok = d.Hover(offset(t, analysisInput, "members:", 1))
+		require.True(t, ok)
+		assert.Contains(t, s, `Group.related.members: (User | SubjectSet<Group, "members">)[]`)
+
+		s, _, ok = d.Hover(offset(t, analysisInput, "view(ctx)", 1))
+		require.True(t, ok)
+		assert.Contains(t, s, "Folder.permits.view(ctx: Context): boolean")
+	})
+
+	t.Run("method=Completions", func(t *testing.T) {
+		labels := func(cs []Completion) (ls []string) {
+			for _, c := range cs {
+				ls = append(ls, c.Label)
+			}
+			return
+		}
+
+		// The cursor is at the position of "<|>".
+		for _, tc := range []struct {
+			name     string
+			old, new string
+			expected []string
+		}{
+			{
+				name:     "relations",
+				old:      "this.related.viewers.includes",
+				new:      "this.related.v<|>",
+				expected: []string{"owner", "viewers"},
+			},
+			{
+				name:     "permissions of traversed namespace",
+				old:      "p.permits.view(ctx)",
+				new:      "p.permits.<|>",
+				expected: []string{"view"},
+			},
+			{
+				name:     "relations of traversed namespace",
+				old:      "p.permits.view(ctx)",
+				new:      "p.related.<|>",
+				expected: []string{"owner", "viewers"},
+			},
+			{
+				name:     "this",
+				old:      "this.related.viewers.includes",
+				new:      "this.<|>",
+				expected: []string{"related", "permits"},
+			},
+			{
+				name:     "namespaces",
+				old:      "parents: Folder[]",
+				new:      "parents: F<|>[]",
+				expected: []string{"User", "Group", "Folder", "File"},
+			},
+		} {
+			t.Run("case="+tc.name, func(t *testing.T) {
+				input := strings.Replace(analysisInput, tc.old, tc.new, 1)
+				cursor := strings.Index(input, "<|>")
+				input = strings.Replace(input, "<|>", "", 1)
+				assert.Equal(t, tc.expected, labels(Analyze(input).Completions(cursor)))
+			})
+		}
+	})
+
+	t.Run("method=Diagnostics", func(t *testing.T) {
+		assert.Empty(t, d.Diagnostics())
+
+		input := strings.Replace(analysisInput, "this.related.viewers", "this.related.viewer", 1)
+		diags := Analyze(input).Diagnostics()
+		require.Len(t, diags, 1)
+		assert.Equal(t, SeverityError, diags[0].Severity)
+		assert.Equal(t, `namespace "Folder" did not declare relation "viewer", did you mean "viewers"?`, diags[0].Message)
+		assert.Equal(t, "viewer", input[diags[0].Range.Start:diags[0].Range.End])
+
+		input = strings.Replace(analysisInput, "owner: User", "owner: User\n    unused: User[]", 1)
+		diags = Analyze(input).Diagnostics()
+		require.Len(t, diags, 1)
+		assert.Equal(t, SeverityWarning, diags[0].Severity)
+		assert.Equal(t, "unused", input[diags[0].Range.Start:diags[0].Range.End])
+	})
+
+	t.Run("method=RenameNamespace", func(t *testing.T) {
+		renamed := d.RenameNamespace("Group", "Team")
+		assert.Equal(t, strings.NewReplacer(
+			"class Group", "class Team",
+			"SubjectSet<Group,", "SubjectSet<Team,",
+		).Replace(analysisInput), renamed)
+		assert.Empty(t, Analyze(renamed).Diagnostics())
+
+		assert.Equal(t, analysisInput, d.RenameNamespace("Unknown", "Other"))
+	})
+
+	t.Run("case=resolves imports", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"users.ts": {Data: []byte("class User implements Namespace {}")},
+		}
+		input := `import { User } from "./users"
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+  }
+
+  permits = {
+    edit: (ctx: Context): boolean => this.related.owners.includes(ctx.subject),
+  }
+}`
+		d := AnalyzeFile(fsys, "main.ts", input)
+		assert.Empty(t, d.Diagnostics())
+
+		s, _, ok := d.Hover(offset(t, input, "User[]", 1))
+		require.True(t, ok)
+		assert.Contains(t, s, "class User implements Namespace")
+
+		_, ok = d.Definition(offset(t, input, "User[]", 1))
+		assert.False(t, ok, "declarations in other files are not found")
+	})
+}
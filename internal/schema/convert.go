@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+// legacyConfig is the part of the configuration of a legacy namespace that
+// is converted. The relations are written in the JSON form of the syntax
+// tree, as printed by `keto namespace export --ast`.
+type legacyConfig struct {
+	Relations []ast.Relation `json:"relations"`
+}
+
+// ConvertLegacy converts namespaces of the legacy `namespaces` configuration
+// to an Ory Permission Language model. The relations are taken from the
+// "relations" key of the namespace configuration, if any. Namespace IDs have
+// no equivalent in the Ory Permission Language and are dropped. The output is
+// parsed again, so that it is guaranteed to be a valid model.
+func ConvertLegacy(legacy []*namespace) (string, []error) {
+	var errs []error
+	converted := make([]*namespace, 0, len(legacy))
+	for _, l := range legacy {
+		if !isIdentifier(l.Name) {
+			errs = append(errs, fmt.Errorf("namespace %q: the name is not a valid identifier, rename the namespace first", l.Name))
+			continue
+		}
+		n := &namespace{Name: l.Name}
+		if IsZanzibarConfig(l.Config) {
+			errs = append(errs, fmt.Errorf("namespace %q: relations in the format of the Zanzibar paper declare no subject types, declare the relations under \"relations\" instead", l.Name))
+			continue
+		}
+		if len(l.Config) > 0 {
+			var c legacyConfig
+			if err := json.Unmarshal(l.Config, &c); err != nil {
+				errs = append(errs, fmt.Errorf("namespace %q: could not decode the relations of the config: %w", l.Name, err))
+				continue
+			}
+			n.Relations = c.Relations
+		}
+		for _, r := range n.Relations {
+			switch {
+			case !isIdentifier(r.Name):
+				errs = append(errs, fmt.Errorf("namespace %q: the name of relation %q is not a valid identifier", l.Name, r.Name))
+			case r.SubjectSetRewrite == nil && len(r.Types) == 0:
+				errs = append(errs, fmt.Errorf("namespace %q: relation %q declares neither subject types nor a rewrite", l.Name, r.Name))
+			}
+		}
+		converted = append(converted, n)
+	}
+	if len(errs) > 0 {
+		return "", errs
+	}
+
+	opl, _, errs := printChecked(converted)
+	return opl, errs
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || strings.ContainsAny(s[:1], digits) {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(letters+digits, r) {
+			return false
+		}
+	}
+	return true
+}
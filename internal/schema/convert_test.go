@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertLegacy(t *testing.T) {
+	t.Run("case=namespaces without config", func(t *testing.T) {
+		opl, errs := ConvertLegacy([]*namespace{{ID: 0, Name: "User"}, {ID: 1, Name: "Document"}})
+		require.Empty(t, errs)
+		assert.Equal(t, "class User implements Namespace {}\n\nclass Document implements Namespace {}\n", opl)
+	})
+
+	t.Run("suite=keeps the relations of the config", func(t *testing.T) {
+		for _, tc := range parserTestCases {
+			t.Run(tc.name, func(t *testing.T) {
+				expected, errs := Parse(tc.input)
+				require.Empty(t, errs)
+
+				legacy := make([]*namespace, len(expected))
+				for i, n := range expected {
+					relations, err := json.Marshal(n.Relations)
+					require.NoError(t, err)
+					legacy[i] = &namespace{ID: int32(i), Name: n.Name, Config: json.RawMessage(fmt.Sprintf(`{"relations": %s}`, relations))}
+				}
+
+				opl, errs := ConvertLegacy(legacy)
+				require.Empty(t, errs)
+				actual, errs := Parse(opl)
+				require.Empty(t, errs, opl)
+				assert.Equal(t, expected, actual)
+			})
+		}
+	})
+
+	t.Run("case=errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			legacy  []*namespace
+			message string
+		}{{
+			name:    "invalid namespace name",
+			legacy:  []*namespace{{Name: "my-videos"}},
+			message: `namespace "my-videos": the name is not a valid identifier`,
+		}, {
+			name:    "relation without types",
+			legacy:  []*namespace{{Name: "Video", Config: json.RawMessage(`{"relations": [{"name": "owners"}]}`)}},
+			message: `relation "owners" declares neither subject types nor a rewrite`,
+		}, {
+			name:    "unknown operator",
+			legacy:  []*namespace{{Name: "Video", Config: json.RawMessage(`{"relations": [{"name": "view", "rewrite": {"operator": "xor", "children": []}}]}`)}},
+			message: `unknown operator "xor"`,
+		}, {
+			name:    "zanzibar relations",
+			legacy:  []*namespace{{Name: "Video", Config: json.RawMessage(`{"relation": [{"name": "owners"}]}`)}},
+			message: `relations in the format of the Zanzibar paper declare no subject types`,
+		}, {
+			name:    "unknown subject type",
+			legacy:  []*namespace{{Name: "Video", Config: json.RawMessage(`{"relations": [{"name": "owners", "types": [{"namespace": "User"}]}]}`)}},
+			message: `namespace "User" was not declared`,
+		}} {
+			t.Run(tc.name, func(t *testing.T) {
+				_, errs := ConvertLegacy(tc.legacy)
+				require.NotEmpty(t, errs)
+				assert.Contains(t, errs[0].Error(), tc.message)
+			})
+		}
+	})
+}
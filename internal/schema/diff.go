@@ -0,0 +1,224 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+type (
+	// ChangeKind is the kind of a change between two versions of an Ory
+	// Permission Language model.
+	ChangeKind string
+
+	// Change is a difference between two versions of a model. Breaking
+	// changes can invalidate existing relation tuples or change the meaning
+	// of checks that clients rely on.
+	Change struct {
+		Kind      ChangeKind `json:"kind"`
+		Breaking  bool       `json:"breaking"`
+		Namespace string     `json:"namespace"`
+		Relation  string     `json:"relation,omitempty"`
+		Message   string     `json:"message"`
+	}
+
+	// TupleCounter returns the number of relation tuples in the namespace,
+	// with the relation if it is not empty.
+	TupleCounter func(namespace, relation string) (int, error)
+)
+
+const (
+	ChangeNamespaceAdded     ChangeKind = "namespace-added"
+	ChangeNamespaceRemoved   ChangeKind = "namespace-removed"
+	ChangeRelationAdded      ChangeKind = "relation-added"
+	ChangeRelationRemoved    ChangeKind = "relation-removed"
+	ChangePermissionAdded    ChangeKind = "permission-added"
+	ChangePermissionRemoved  ChangeKind = "permission-removed"
+	ChangePermissionModified ChangeKind = "permission-modified"
+	ChangeKindModified       ChangeKind = "relation-kind-modified"
+	ChangeSubjectTypeAdded   ChangeKind = "subject-type-added"
+	ChangeSubjectTypeRemoved ChangeKind = "subject-type-removed"
+	ChangeCardinality        ChangeKind = "cardinality-modified"
+	ChangeCondition          ChangeKind = "condition-modified"
+)
+
+// Diff compares two versions of a model. The changes are ordered by the
+// declaration of their namespace and relation, removed ones first.
+//
+// Removing a namespace or relation is classified as breaking, because
+// existing relation tuples would not match the schema anymore. Use
+// ClassifyWithTuples to only report them as breaking if relation tuples exist.
+func Diff(old, new []namespace) []Change {
+	var changes []Change
+	add := func(kind ChangeKind, breaking bool, nspace, relation, format string, a ...interface{}) {
+		changes = append(changes, Change{
+			Kind:      kind,
+			Breaking:  breaking,
+			Namespace: nspace,
+			Relation:  relation,
+			Message:   fmt.Sprintf(format, a...),
+		})
+	}
+
+	newByName := make(map[string]*namespace, len(new))
+	for i := range new {
+		newByName[new[i].Name] = &new[i]
+	}
+	for i := range old {
+		if _, ok := newByName[old[i].Name]; !ok {
+			add(ChangeNamespaceRemoved, true, old[i].Name, "", "namespace %q was removed", old[i].Name)
+		}
+	}
+
+	oldByName := make(map[string]*namespace, len(old))
+	for i := range old {
+		oldByName[old[i].Name] = &old[i]
+	}
+	for i := range new {
+		n := &new[i]
+		o, ok := oldByName[n.Name]
+		if !ok {
+			add(ChangeNamespaceAdded, false, n.Name, "", "namespace %q was added", n.Name)
+			continue
+		}
+
+		for j := range o.Relations {
+			or := &o.Relations[j]
+			if findRelation(n, or.Name) == nil {
+				if isPermission(or) {
+					add(ChangePermissionRemoved, true, n.Name, or.Name, "permission %q was removed", or.Name)
+				} else {
+					add(ChangeRelationRemoved, true, n.Name, or.Name, "relation %q was removed", or.Name)
+				}
+			}
+		}
+
+		for j := range n.Relations {
+			nr := &n.Relations[j]
+			or := findRelation(o, nr.Name)
+			switch {
+			case or == nil && isPermission(nr):
+				add(ChangePermissionAdded, false, n.Name, nr.Name, "permission %q was added", nr.Name)
+			case or == nil:
+				add(ChangeRelationAdded, false, n.Name, nr.Name, "relation %q was added", nr.Name)
+			case isPermission(or) != isPermission(nr):
+				add(ChangeKindModified, true, n.Name, nr.Name, "%q changed from a %s to a %s", nr.Name, relationKind(or), relationKind(nr))
+			case isPermission(nr):
+				if !reflect.DeepEqual(or.SubjectSetRewrite, nr.SubjectSetRewrite) {
+					add(ChangePermissionModified, false, n.Name, nr.Name, "the definition of permission %q changed", nr.Name)
+				}
+			default:
+				for _, t := range or.Types {
+					if !hasType(nr, t) {
+						add(ChangeSubjectTypeRemoved, true, n.Name, nr.Name, "relation %q does not allow subjects of type %s anymore", nr.Name, relationTypeString(t))
+					}
+				}
+				for _, t := range nr.Types {
+					if !hasType(or, t) {
+						add(ChangeSubjectTypeAdded, false, n.Name, nr.Name, "relation %q now allows subjects of type %s", nr.Name, relationTypeString(t))
+					}
+				}
+				if or.MaxSubjects != nr.MaxSubjects {
+					add(ChangeCardinality, nr.MaxSubjects != 0 && (or.MaxSubjects == 0 || nr.MaxSubjects < or.MaxSubjects),
+						n.Name, nr.Name, "the type of relation %q changed from %s to %s", nr.Name, typeString(or), typeString(nr))
+				}
+				if !reflect.DeepEqual(or.Condition, nr.Condition) {
+					added := addedParameters(or.Condition, nr.Condition)
+					msg := fmt.Sprintf("the condition of relation %q changed", nr.Name)
+					if len(added) > 0 {
+						msg += fmt.Sprintf(", existing relation tuples lack the parameters %s", strings.Join(added, ", "))
+					}
+					add(ChangeCondition, len(added) > 0, n.Name, nr.Name, "%s", msg)
+				}
+			}
+		}
+	}
+
+	return changes
+}
+
+// ClassifyWithTuples only keeps removals of namespaces and relations as
+// breaking if there are relation tuples that would be orphaned by them.
+func ClassifyWithTuples(changes []Change, count TupleCounter) error {
+	for i := range changes {
+		c := &changes[i]
+		var relation string
+		switch c.Kind {
+		case ChangeNamespaceRemoved:
+		case ChangeRelationRemoved:
+			relation = c.Relation
+		default:
+			continue
+		}
+
+		n, err := count(c.Namespace, relation)
+		if err != nil {
+			return err
+		}
+		c.Breaking = n > 0
+		if n > 0 {
+			c.Message += fmt.Sprintf(", but %d relation tuples exist", n)
+		} else {
+			c.Message += ", no relation tuples exist"
+		}
+	}
+	return nil
+}
+
+func findRelation(n *namespace, name string) *ast.Relation {
+	for i := range n.Relations {
+		if n.Relations[i].Name == name {
+			return &n.Relations[i]
+		}
+	}
+	return nil
+}
+
+func isPermission(r *ast.Relation) bool {
+	return r.SubjectSetRewrite != nil
+}
+
+func relationKind(r *ast.Relation) string {
+	if isPermission(r) {
+		return "permission"
+	}
+	return "relation"
+}
+
+func hasType(r *ast.Relation, t ast.RelationType) bool {
+	for _, rt := range r.Types {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+func relationTypeString(t ast.RelationType) string {
+	if t.Relation != "" {
+		return fmt.Sprintf("SubjectSet<%s, %q>", t.Namespace, t.Relation)
+	}
+	return t.Namespace
+}
+
+// addedParameters returns the condition parameters of new that old does not
+// declare with the same type.
+func addedParameters(old, new *ast.Condition) (added []string) {
+	if new == nil {
+		return nil
+	}
+	for _, np := range new.Parameters {
+		found := false
+		if old != nil {
+			for _, op := range old.Parameters {
+				found = found || op == np
+			}
+		}
+		if !found {
+			added = append(added, fmt.Sprintf("%q", np.Name))
+		}
+	}
+	return added
+}
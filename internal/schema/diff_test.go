@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	parse := func(t *testing.T, input string) []namespace {
+		ns, errs := Parse(input)
+		require.Empty(t, errs)
+		return ns
+	}
+
+	old := parse(t, `
+class User implements Namespace {}
+
+class Bot implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}
+
+class Tag implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owner: User
+    editors: User[]
+    viewers: (User | SubjectSet<Group, "members">)[]
+    auditors: User[]
+    shared: Conditional<User, {}>[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+    edit: (ctx: Context): boolean => this.related.editors.includes(ctx.subject),
+    audit: (ctx: Context): boolean => this.related.auditors.includes(ctx.subject),
+  }
+}`)
+
+	t.Run("case=no changes", func(t *testing.T) {
+		assert.Empty(t, Diff(old, old))
+	})
+
+	t.Run("case=reports and classifies changes", func(t *testing.T) {
+		changes := Diff(old, parse(t, `
+class User implements Namespace {}
+
+class Bot implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: (User | Bot)[]
+  }
+}
+
+class Folder implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owner: User[]
+    editors: User
+    viewers: User[]
+    shared: Conditional<User, { expires_at: Date }>[]
+    view: User[]
+  }
+
+  permits = {
+    edit: (ctx: Context): boolean => this.related.editors.includes(ctx.subject) || this.related.owner.includes(ctx.subject),
+    audit: (ctx: Context): boolean => this.related.owner.includes(ctx.subject),
+    share: (ctx: Context): boolean => this.related.owner.includes(ctx.subject),
+  }
+}`))
+
+		assert.Equal(t, []Change{
+			{Kind: ChangeNamespaceRemoved, Breaking: true, Namespace: "Tag", Message: `namespace "Tag" was removed`},
+			{Kind: ChangeSubjectTypeAdded, Namespace: "Group", Relation: "members", Message: `relation "members" now allows subjects of type Bot`},
+			{Kind: ChangeNamespaceAdded, Namespace: "Folder", Message: `namespace "Folder" was added`},
+			{Kind: ChangeRelationRemoved, Breaking: true, Namespace: "Document", Relation: "auditors", Message: `relation "auditors" was removed`},
+			{Kind: ChangeCardinality, Namespace: "Document", Relation: "owner", Message: `the type of relation "owner" changed from User to User[]`},
+			{Kind: ChangeCardinality, Breaking: true, Namespace: "Document", Relation: "editors", Message: `the type of relation "editors" changed from User[] to User`},
+			{Kind: ChangeSubjectTypeRemoved, Breaking: true, Namespace: "Document", Relation: "viewers", Message: `relation "viewers" does not allow subjects of type SubjectSet<Group, "members"> anymore`},
+			{Kind: ChangeCondition, Breaking: true, Namespace: "Document", Relation: "shared", Message: `the condition of relation "shared" changed, existing relation tuples lack the parameters "expires_at"`},
+			{Kind: ChangeKindModified, Breaking: true, Namespace: "Document", Relation: "view", Message: `"view" changed from a permission to a relation`},
+			{Kind: ChangePermissionModified, Namespace: "Document", Relation: "edit", Message: `the definition of permission "edit" changed`},
+			{Kind: ChangePermissionModified, Namespace: "Document", Relation: "audit", Message: `the definition of permission "audit" changed`},
+			{Kind: ChangePermissionAdded, Namespace: "Document", Relation: "share", Message: `permission "share" was added`},
+		}, changes)
+	})
+
+	t.Run("case=classifies removals by existing relation tuples", func(t *testing.T) {
+		changes := Diff(old, parse(t, `
+class User implements Namespace {}
+
+class Bot implements Namespace {}
+
+class Group implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owner: User
+    editors: User[]
+    viewers: User[]
+    shared: Conditional<User, {}>[]
+  }
+}`))
+		counts := map[string]int{"Group#members": 3}
+		require.NoError(t, ClassifyWithTuples(changes, func(namespace, relation string) (int, error) {
+			return counts[namespace+"#"+relation], nil
+		}))
+
+		breaking := map[string]bool{}
+		for _, c := range changes {
+			breaking[c.Namespace+"#"+c.Relation] = c.Breaking
+		}
+		assert.Equal(t, map[string]bool{
+			"Tag#":              false,
+			"Group#members":     true,
+			"Document#viewers":  true,
+			"Document#auditors": false,
+			"Document#view":     true,
+			"Document#edit":     true,
+			"Document#audit":    true,
+		}, breaking)
+		assert.Contains(t, changes[1].Message, "but 3 relation tuples exist")
+	})
+}
@@ -0,0 +1,350 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	formatter struct {
+		input    string
+		items    []item
+		out      strings.Builder
+		indent   int
+		newlines int     // newlines to write before the next token
+		groups   []group // open brackets, innermost last
+		prev     item    // previous token that is not a comment
+		before   item    // token that is not a comment before prev
+		// multiline is true while formatting a permission whose operands are
+		// written on separate lines.
+		multiline bool
+	}
+	group struct {
+		open  itemType
+		kind  blockKind
+		empty bool
+	}
+	blockKind int
+)
+
+const (
+	inline blockKind = iota
+	classBlock
+	relatedBlock
+	permitsBlock
+)
+
+const indentation = "  "
+
+// Format formats the Ory Permission Language input canonically, so that
+// formatting the same model always results in the same output. Only
+// whitespace, quotes of string literals, and trailing commas are changed,
+// comments are kept. The input has to be syntactically valid.
+func Format(input string) (string, []error) {
+	p := &parser{lexer: Lex("input", input)}
+	p.parseInput()
+	if len(p.errors) > 0 {
+		return "", p.errors
+	}
+
+	f := &formatter{input: input}
+	l := Lex("input", input)
+	for i := l.nextItem(); i.Typ != itemEOF; i = l.nextItem() {
+		if i.Typ == itemError {
+			return "", []error{errors.New(i.Val)}
+		}
+		f.items = append(f.items, i)
+	}
+
+	for idx, i := range f.items {
+		f.format(idx, i)
+	}
+	if f.out.Len() == 0 {
+		return "", nil
+	}
+	return f.out.String() + "\n", nil
+}
+
+func (f *formatter) format(idx int, i item) {
+	if i.Typ == itemComment {
+		f.formatComment(idx, i)
+		return
+	}
+	if i.Typ == itemOperatorComma && !f.atBlockLevel() {
+		// Trailing commas in brackets are dropped.
+		if next := f.nextSignificant(idx + 1); next.Typ == itemParenRight || next.Typ == itemBraceRight {
+			return
+		}
+	}
+
+	defer func() { f.before, f.prev = f.prev, i }()
+
+	switch i.Typ {
+	case itemBraceLeft:
+		g := group{open: itemBraceLeft, kind: f.blockKind()}
+		g.empty = idx+1 < len(f.items) && f.items[idx+1].Typ == itemBraceRight
+		f.write("{", g.kind != inline || f.spaceBefore(i))
+		f.groups = append(f.groups, g)
+		if g.kind != inline && !g.empty {
+			f.indent++
+			f.requireNewlines(1)
+		}
+		return
+
+	case itemBraceRight:
+		g := f.pop()
+		if g.kind == inline || g.empty {
+			f.write("}", g.kind == inline && !g.empty)
+			return
+		}
+		if g.kind == permitsBlock {
+			f.endPermission()
+			if f.prev.Typ != itemOperatorComma && f.prev.Typ != itemBraceLeft {
+				f.write(",", false)
+			}
+		}
+		f.indent--
+		f.requireNewlines(1)
+		f.write("}", false)
+		if len(f.groups) == 0 {
+			f.requireNewlines(1)
+		}
+		return
+
+	case itemParenLeft, itemBracketLeft, itemAngledLeft:
+		f.write(i.Val, f.spaceBefore(i))
+		f.groups = append(f.groups, group{open: i.Typ})
+		return
+
+	case itemParenRight, itemBracketRight, itemAngledRight:
+		f.pop()
+		f.write(i.Val, false)
+		return
+	}
+
+	if f.atTopLevel() {
+		switch {
+		case i.Typ == itemKeywordClass:
+			if idx > 0 && f.items[idx-1].Typ == itemComment && f.sourceNewlines(idx, 1) == 1 {
+				// Keep a comment directly above the class.
+				f.requireNewlines(1)
+			} else {
+				f.requireNewlines(2)
+			}
+		case i.Typ == itemIdentifier && i.Val == "import":
+			f.requireNewlines(f.sourceNewlines(idx, 1))
+		}
+	} else if f.atBlockLevel() {
+		switch {
+		case f.startsEntry(idx):
+			f.requireNewlines(f.sourceNewlines(idx, 1))
+		case i.Typ == itemOperatorArrow && f.currentBlock() == permitsBlock:
+			f.write("=>", true)
+			if f.hasOperands(idx + 1) {
+				f.multiline = true
+				f.indent++
+				f.requireNewlines(1)
+			}
+			return
+		case (i.Typ == itemOperatorAnd || i.Typ == itemOperatorOr) && f.multiline:
+			f.write(i.Val, true)
+			f.requireNewlines(1)
+			return
+		case i.Typ == itemOperatorComma && f.currentBlock() == permitsBlock:
+			f.write(",", false)
+			f.endPermission()
+			return
+		}
+	}
+
+	f.write(f.render(i), f.spaceBefore(i))
+}
+
+func (f *formatter) formatComment(idx int, i item) {
+	if f.out.Len() > 0 && f.sourceNewlines(idx, 0) == 0 {
+		// The comment trails the previous token on the same line.
+		f.out.WriteString(" " + i.Val)
+	} else {
+		min := 1
+		if f.atTopLevel() && f.prev.Typ == itemBraceRight {
+			// Separate the comment from the previous class.
+			min = 2
+		}
+		f.requireNewlines(f.sourceNewlines(idx, min))
+		f.write(i.Val, false)
+	}
+	if strings.HasPrefix(i.Val, "//") {
+		f.requireNewlines(1)
+	}
+}
+
+// write writes the token, preceded by the pending newlines or a space.
+func (f *formatter) write(s string, space bool) {
+	switch {
+	case f.out.Len() == 0:
+	case f.newlines > 0:
+		f.out.WriteString(strings.Repeat("\n", f.newlines))
+		f.out.WriteString(strings.Repeat(indentation, f.indent))
+	case space:
+		f.out.WriteString(" ")
+	}
+	f.newlines = 0
+	f.out.WriteString(s)
+}
+
+func (f *formatter) requireNewlines(n int) {
+	if f.newlines < n {
+		f.newlines = n
+	}
+}
+
+// sourceNewlines returns how many newlines precede the token at idx in the
+// input, at most two and at least min. Blank lines after an opening brace are
+// not kept.
+func (f *formatter) sourceNewlines(idx, min int) int {
+	start := 0
+	if idx > 0 {
+		start = f.items[idx-1].End
+	}
+	n := strings.Count(f.input[start:f.items[idx].Start], "\n")
+	if idx > 0 && f.items[idx-1].Typ == itemBraceLeft && n > 1 {
+		n = 1
+	}
+	if n > 2 {
+		n = 2
+	}
+	if n < min {
+		n = min
+	}
+	return n
+}
+
+func (f *formatter) pop() (g group) {
+	if len(f.groups) == 0 {
+		return g
+	}
+	g = f.groups[len(f.groups)-1]
+	f.groups = f.groups[:len(f.groups)-1]
+	return g
+}
+
+func (f *formatter) atTopLevel() bool {
+	return len(f.groups) == 0
+}
+
+// atBlockLevel returns true if the innermost open bracket is a class, related,
+// or permits block.
+func (f *formatter) atBlockLevel() bool {
+	return len(f.groups) > 0 && f.currentBlock() != inline
+}
+
+func (f *formatter) currentBlock() blockKind {
+	if len(f.groups) == 0 {
+		return inline
+	}
+	return f.groups[len(f.groups)-1].kind
+}
+
+// blockKind determines the kind of block that the next "{" opens.
+func (f *formatter) blockKind() blockKind {
+	switch {
+	case f.prev.Typ == itemIdentifier && f.prev.Val == "Namespace" && f.atTopLevel():
+		return classBlock
+	case f.currentBlock() != classBlock || !f.atBlockLevel():
+		return inline
+	case f.prev.Typ == itemOperatorColon && f.before.Val == "related":
+		return relatedBlock
+	case f.prev.Typ == itemOperatorAssign && f.before.Val == "permits":
+		return permitsBlock
+	}
+	return inline
+}
+
+// nextSignificant returns the first token at or after idx that is not a
+// comment.
+func (f *formatter) nextSignificant(idx int) item {
+	for ; idx < len(f.items); idx++ {
+		if f.items[idx].Typ != itemComment {
+			return f.items[idx]
+		}
+	}
+	return item{Typ: itemEOF}
+}
+
+// startsEntry returns true if the token at idx is the name of a relation, a
+// permission, or the "related" and "permits" declarations.
+func (f *formatter) startsEntry(idx int) bool {
+	i := f.items[idx]
+	if i.Typ != itemIdentifier {
+		return false
+	}
+	next := f.nextSignificant(idx + 1)
+	return next.Typ == itemOperatorColon || next.Typ == itemOperatorAssign
+}
+
+// hasOperands returns true if the permission expression starting at idx
+// contains a top-level "&&" or "||".
+func (f *formatter) hasOperands(idx int) bool {
+	depth := 0
+	for ; idx < len(f.items); idx++ {
+		switch f.items[idx].Typ {
+		case itemParenLeft, itemBracketLeft, itemAngledLeft, itemBraceLeft:
+			depth++
+		case itemParenRight, itemBracketRight, itemAngledRight, itemBraceRight:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case itemOperatorComma:
+			if depth == 0 {
+				return false
+			}
+		case itemOperatorAnd, itemOperatorOr:
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *formatter) endPermission() {
+	if f.multiline {
+		f.multiline = false
+		f.indent--
+	}
+}
+
+// spaceBefore returns true if a space separates the previous token and i on
+// the same line.
+func (f *formatter) spaceBefore(i item) bool {
+	switch f.prev.Typ {
+	case itemOperatorDot, itemOperatorNot, itemParenLeft, itemBracketLeft, itemAngledLeft:
+		return false
+	case itemBraceLeft:
+		return true
+	}
+	switch i.Typ {
+	case itemOperatorDot, itemOperatorComma, itemOperatorColon, itemBracketLeft, itemAngledLeft:
+		return false
+	case itemParenLeft:
+		switch f.prev.Typ {
+		case itemIdentifier, itemKeywordThis, itemKeywordCtx:
+			return false
+		}
+	}
+	return true
+}
+
+// render returns the token as it is written to the output. String literals
+// are quoted with double quotes, unless they contain one.
+func (f *formatter) render(i item) string {
+	if i.Typ != itemStringLiteral {
+		return i.Val
+	}
+	if strings.Contains(i.Val, `"`) {
+		return "'" + i.Val + "'"
+	}
+	return `"` + i.Val + `"`
+}
@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Run("case=canonical output", func(t *testing.T) {
+		input := `import {Namespace,Context} from '@ory/keto-namespace-types'
+class User implements Namespace{}
+class Group implements Namespace {
+
+
+  related: { members: (User|SubjectSet<Group,'members'>)[] } // members of the group
+}
+  /* Documents */
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: Conditional<User,{ip:string,}>[]
+  }
+  permits = {
+    view: (ctx:Context):boolean=>this.related.viewers.includes(ctx.subject)||this.related.owners.includes(ctx.subject)||
+      this.related.viewers.traverse((u)=>u.related.manager.includes(ctx.subject),),
+
+    edit:(ctx: Context) => !this.related.owners.includes(ctx.subject)
+  }
+}`
+		expected := `import { Namespace, Context } from "@ory/keto-namespace-types"
+
+class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: (User | SubjectSet<Group, "members">)[]
+  } // members of the group
+}
+
+/* Documents */
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: Conditional<User, { ip: string }>[]
+  }
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      this.related.owners.includes(ctx.subject) ||
+      this.related.viewers.traverse((u) => u.related.manager.includes(ctx.subject)),
+
+    edit: (ctx: Context) => !this.related.owners.includes(ctx.subject),
+  }
+}
+`
+		actual, errs := Format(input)
+		require.Empty(t, errs)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("suite=keeps the model", func(t *testing.T) {
+		for _, tc := range parserTestCases {
+			t.Run(tc.name, func(t *testing.T) {
+				formatted, errs := Format(tc.input)
+				require.Empty(t, errs)
+
+				again, errs := Format(formatted)
+				require.Empty(t, errs)
+				assert.Equal(t, formatted, again, "formatting must be idempotent")
+
+				expected, errs := Parse(tc.input)
+				require.Empty(t, errs)
+				actual, errs := Parse(formatted)
+				require.Empty(t, errs)
+				assert.Equal(t, expected, actual)
+			})
+		}
+	})
+
+	t.Run("case=invalid input", func(t *testing.T) {
+		_, errs := Format(`class User implements Namespace {`)
+		assert.NotEmpty(t, errs)
+	})
+}
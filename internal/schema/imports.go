@@ -0,0 +1,287 @@
+package schema
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type oplImport struct {
+	path  item   // the imported path as written in the input
+	names []item // the imported namespaces, if any
+}
+
+// parseImport parses an import statement. The "import" token was already
+// consumed. Both `import { A, B } from "./file"` and `import "./file"` are
+// supported. Imports of packages, such as "@ory/keto-namespace-types", only
+// provide types to TypeScript tooling and are ignored.
+func (p *parser) parseImport() {
+	var (
+		names []item
+		path  item
+	)
+	if p.matchIf(is(itemBraceLeft), "{") {
+	loop:
+		for !p.fatal {
+			switch item := p.next(); item.Typ {
+			case itemBraceRight:
+				break loop
+			case itemIdentifier:
+				names = append(names, item)
+				p.matchIf(is(itemOperatorComma), ",")
+			default:
+				p.addFatal(item, "expected identifier or '}', got %s", got(item))
+				return
+			}
+		}
+		p.match("from")
+	}
+	if !p.match(&path) {
+		return
+	}
+	if path.Typ != itemStringLiteral {
+		p.addFatal(path, "expected import path, got %s", got(path))
+		return
+	}
+	if !isFileImport(path.Val) {
+		return
+	}
+	p.imports = append(p.imports, oplImport{path: path, names: names})
+}
+
+func isFileImport(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../")
+}
+
+// ParseFiles parses the given Ory Permission Language files and all files they
+// import from fsys. The namespaces of all files are merged into one model, so
+// that namespaces can reference namespaces declared in other files.
+func ParseFiles(fsys fs.FS, names ...string) ([]namespace, []error) {
+	namespaces, _, errs := fileSet{fsys: fsys}.parse(names...)
+	return namespaces, errs
+}
+
+// ParseLocalFiles parses the Ory Permission Language files from the local file
+// system and all files they import into one model.
+func ParseLocalFiles(fns ...string) ([]namespace, []error) {
+	fsys, names, err := localFiles(fns...)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return ParseFiles(fsys, names...)
+}
+
+// localFiles returns the file system of the volume of the local files, and the
+// names of the files in it.
+func localFiles(fns ...string) (fs.FS, []string, error) {
+	var (
+		vol   string
+		names = make([]string, len(fns))
+	)
+	for i, fn := range fns {
+		abs, err := filepath.Abs(fn)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		vol = filepath.VolumeName(abs)
+		names[i] = strings.TrimPrefix(filepath.ToSlash(abs[len(vol):]), "/")
+	}
+	return os.DirFS(vol + string(filepath.Separator)), names, nil
+}
+
+// fileSet is the set of files that imports are resolved in. The overlays
+// replace the content of files in fsys, e.g. of files with unsaved changes in
+// an editor. The known namespaces are declared outside of the files, but can be
+// referenced from them.
+type fileSet struct {
+	fsys     fs.FS
+	overlays map[string]string
+	known    []namespace
+}
+
+func (s fileSet) read(name string) (string, error) {
+	if content, ok := s.overlays[name]; ok {
+		return content, nil
+	}
+	raw, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(raw), nil
+}
+
+func (s fileSet) isFile(name string) bool {
+	if _, ok := s.overlays[name]; ok {
+		return true
+	}
+	info, err := fs.Stat(s.fsys, name)
+	return err == nil && !info.IsDir()
+}
+
+// parse parses the files and all files they import, and type checks them
+// against the merged model. It returns the parser of every file by name.
+func (s fileSet) parse(names ...string) ([]namespace, map[string]*parser, []error) {
+	var (
+		parsers []*parser
+		byFile  = make(map[string]*parser)
+		errs    []error
+		queue   = make([]string, len(names))
+	)
+	for i, name := range names {
+		queue[i] = path.Clean(name)
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := byFile[name]; ok {
+			continue
+		}
+
+		content, err := s.read(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p := &parser{
+			lexer: Lex(name, content),
+			file:  name,
+		}
+		p.parseInput()
+		parsers = append(parsers, p)
+		byFile[name] = p
+
+		for _, imp := range p.imports {
+			file, ok := s.resolveImport(name, imp.path.Val)
+			if !ok {
+				p.addErr(imp.path, "could not resolve import %q", imp.path.Val)
+				continue
+			}
+			queue = append(queue, file)
+		}
+	}
+
+	var namespaces []namespace
+	declaredIn := make(map[string]string)
+	for _, n := range s.known {
+		declaredIn[n.Name] = ""
+	}
+	for _, p := range parsers {
+		for _, class := range p.classes {
+			if file, ok := declaredIn[class.Val]; ok && file == "" {
+				p.addErr(class, "namespace %q was already declared in the configuration", class.Val)
+				continue
+			} else if ok {
+				p.addErr(class, "namespace %q was already declared in %s", class.Val, file)
+				continue
+			}
+			declaredIn[class.Val] = p.file
+		}
+		namespaces = append(namespaces, p.namespaces...)
+	}
+
+	for _, p := range parsers {
+		for _, imp := range p.imports {
+			file, ok := s.resolveImport(p.file, imp.path.Val)
+			if !ok {
+				continue
+			}
+			for _, name := range imp.names {
+				if declaredIn[name.Val] != file {
+					p.addErr(name, "namespace %q is not declared in %q", name.Val, imp.path.Val)
+				}
+			}
+		}
+
+		// Type check every file against the merged model.
+		own := p.namespaces
+		p.namespaces = append(append([]namespace{}, s.known...), namespaces...)
+		p.typeCheck()
+		p.namespaces = own
+
+		errs = append(errs, p.errors...)
+	}
+
+	return namespaces, byFile, errs
+}
+
+// ParseDefinitions parses namespace definitions that are managed at runtime,
+// keyed by the name of the namespace. Every definition has to declare exactly
+// the namespace it is named after. It can import other definitions by name,
+// e.g. `import { User } from "./User"`, and reference the known namespaces
+// without importing them. Only the namespaces of the definitions are returned.
+func ParseDefinitions(known []namespace, definitions map[string]string) ([]namespace, []error) {
+	s := fileSet{
+		fsys:     emptyFS{},
+		overlays: make(map[string]string, len(definitions)),
+		known:    known,
+	}
+	names := make([]string, 0, len(definitions))
+	for name, opl := range definitions {
+		s.overlays[name+".ts"] = opl
+		names = append(names, name+".ts")
+	}
+	sort.Strings(names)
+
+	namespaces, byFile, errs := s.parse(names...)
+	for _, name := range names {
+		p := byFile[name]
+		want := strings.TrimSuffix(name, ".ts")
+		if len(p.classes) == 1 && p.classes[0].Val == want {
+			continue
+		}
+		at := item{Typ: itemEOF}
+		if len(p.classes) > 0 {
+			at = p.classes[len(p.classes)-1]
+		}
+		p.addErr(at, "the definition of namespace %q has to declare exactly that namespace", want)
+		errs = append(errs, p.errors[len(p.errors)-1])
+	}
+	return namespaces, errs
+}
+
+// ParseContents parses Ory Permission Language files that were not read from a
+// file system, keyed by their slash-separated path, into one model. The files
+// can import each other by their relative paths.
+func ParseContents(files map[string]string) ([]namespace, []error) {
+	s := fileSet{
+		fsys:     emptyFS{},
+		overlays: make(map[string]string, len(files)),
+	}
+	names := make([]string, 0, len(files))
+	for name, content := range files {
+		name = path.Clean(name)
+		s.overlays[name] = content
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	namespaces, _, errs := s.parse(names...)
+	return namespaces, errs
+}
+
+type emptyFS struct{}
+
+func (emptyFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// resolveImport resolves the import path relative to the importing file. As in
+// TypeScript, the ".ts" extension can be omitted.
+func (s fileSet) resolveImport(from, imp string) (string, bool) {
+	file := path.Join(path.Dir(from), imp)
+	if !fs.ValidPath(file) {
+		return "", false
+	}
+	for _, candidate := range []string{file, file + ".ts"} {
+		if s.isFile(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
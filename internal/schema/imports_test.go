@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users.ts": {Data: []byte(`
+import { Namespace } from "@ory/keto-namespace-types"
+
+class User implements Namespace {}`)},
+		"groups/groups.ts": {Data: []byte(`
+import { User } from "../users"
+
+class Group implements Namespace {
+  related: {
+    members: (User | SubjectSet<Group, "members">)[]
+  }
+}`)},
+		"main.ts": {Data: []byte(`
+import { Namespace, Context } from "@ory/keto-namespace-types"
+import { User } from "./users.ts"
+import { Group } from "./groups/groups"
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+    viewers: SubjectSet<Group, "members">[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+  }
+}`)},
+	}
+
+	t.Run("case=resolves imports", func(t *testing.T) {
+		ns, errs := ParseFiles(fsys, "main.ts")
+		for _, err := range errs {
+			t.Error(err)
+		}
+		names := make([]string, len(ns))
+		for i, n := range ns {
+			names[i] = n.Name
+		}
+		assert.ElementsMatch(t, []string{"Document", "User", "Group"}, names)
+	})
+
+	for _, tc := range []struct{ name, input, file, msg string }{
+		{"unresolved import", `import { User } from "./people"`, "main.ts", `could not resolve import "./people"`},
+		{"name not declared in import", `import { Group } from "./users"`, "main.ts", `namespace "Group" is not declared in "./users"`},
+		{"missing import", `class Document implements Namespace {
+  related: {
+    owners: Organization[]
+  }
+}`, "main.ts", `namespace "Organization" was not declared`},
+		{"duplicate namespace", `import "./users"
+
+class User implements Namespace {}`, "users.ts", `namespace "User" was already declared in main.ts`},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			files := fstest.MapFS{"main.ts": {Data: []byte(tc.input)}}
+			for k, v := range fsys {
+				if k != "main.ts" {
+					files[k] = v
+				}
+			}
+			_, errs := ParseFiles(files, "main.ts")
+			require.Len(t, errs, 1)
+			assert.Contains(t, errs[0].Error(), tc.file+":")
+			assert.Contains(t, errs[0].Error(), tc.msg)
+		})
+	}
+
+	t.Run("case=Parse does not resolve imports", func(t *testing.T) {
+		_, errs := Parse(`import { User } from "./users"`)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "imports are only supported when parsing files")
+	})
+}
+
+func TestParseDefinitions(t *testing.T) {
+	known := []namespace{{Name: "User"}}
+
+	t.Run("case=definitions reference each other and known namespaces", func(t *testing.T) {
+		nn, errs := ParseDefinitions(known, map[string]string{
+			"Group": `class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}`,
+			"Document": `import { Group } from "./Group"
+
+class Document implements Namespace {
+  related: {
+    viewers: SubjectSet<Group, "members">[]
+  }
+}`,
+		})
+		require.Empty(t, errs)
+		names := make([]string, len(nn))
+		for i, n := range nn {
+			names[i] = n.Name
+		}
+		assert.ElementsMatch(t, []string{"Group", "Document"}, names)
+	})
+
+	for _, tc := range []struct {
+		name, input, msg string
+	}{
+		{"declares another namespace", "class Group implements Namespace {}", `the definition of namespace "Folder" has to declare exactly that namespace`},
+		{"declares no namespace", "", `the definition of namespace "Folder" has to declare exactly that namespace`},
+		{"redeclares a known namespace", "class Folder implements Namespace {}\nclass User implements Namespace {}", `namespace "User" was already declared in the configuration`},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			_, errs := ParseDefinitions(known, map[string]string{"Folder": tc.input})
+			require.NotEmpty(t, errs)
+			assert.Contains(t, errs[0].Error(), tc.msg)
+		})
+	}
+}
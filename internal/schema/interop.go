@@ -0,0 +1,33 @@
+package schema
+
+import "fmt"
+
+// Untranslatable is a construct of another authorization schema language
+// that has no equivalent in the Ory Permission Language, or the other way
+// around. The construct is dropped from the translation.
+type Untranslatable struct {
+	Namespace string `json:"namespace"`
+	Relation  string `json:"relation,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (u Untranslatable) String() string {
+	if u.Relation == "" {
+		return fmt.Sprintf("%s: %s", u.Namespace, u.Message)
+	}
+	return fmt.Sprintf("%s.%s: %s", u.Namespace, u.Relation, u.Message)
+}
+
+// printChecked prints the translated namespaces, and parses the output again,
+// so that the translation is guaranteed to be a valid model.
+func printChecked(translated []*namespace) (string, []namespace, []error) {
+	opl, err := Print(translated)
+	if err != nil {
+		return "", nil, []error{err}
+	}
+	nn, errs := Parse(opl)
+	if len(errs) > 0 {
+		return "", nil, errs
+	}
+	return opl, nn, nil
+}
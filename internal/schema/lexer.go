@@ -303,13 +303,16 @@ func lexBlockComment(l *lexer) stateFn {
 	}
 }
 
-// lexStringLiteral scans a string literal.
+// lexStringLiteral scans a string literal. String literals can not span
+// multiple lines.
 func lexStringLiteral(l *lexer) stateFn {
 	r := l.next()
 	l.ignore()
-	l.acceptRun(digits + letters)
-	if l.peek() != r {
-		return l.errorf("unclosed string literal")
+	for c := l.peek(); c != r; c = l.peek() {
+		if c == eof || c == '\n' {
+			return l.errorf("unclosed string literal")
+		}
+		l.next()
 	}
 	l.emit(itemStringLiteral)
 	l.next()
@@ -127,3 +127,28 @@ func TestLexer(t *testing.T) {
 
 	})
 }
+
+func FuzzLexer(f *testing.F) {
+	for _, tc := range lexableTestCases {
+		f.Add(tc.input)
+	}
+	for _, tc := range lexingErrorTestCases {
+		f.Add(tc.input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := Lex("fuzz", input)
+		// Every item but EOF consumes input, so the lexer has to stop after
+		// at most one item per byte.
+		for i := 0; i <= len(input)+1; i++ {
+			item := l.nextItem()
+			if item.Typ == itemEOF || item.Typ == itemError {
+				return
+			}
+			if item.Start < 0 || item.Start > item.End || item.End > len(input) {
+				t.Fatalf("item %s has invalid position %d-%d", item, item.Start, item.End)
+			}
+		}
+		t.Fatal("the lexer did not reach EOF")
+	})
+}
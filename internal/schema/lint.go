@@ -0,0 +1,361 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+type (
+	// Severity is the severity of a lint finding.
+	Severity string
+
+	// Finding is a semantic problem in an Ory Permission Language model that
+	// the parser and type checker do not report.
+	Finding struct {
+		Severity  Severity `json:"severity"`
+		Rule      string   `json:"rule"`
+		Namespace string   `json:"namespace"`
+		Relation  string   `json:"relation,omitempty"`
+		Message   string   `json:"message"`
+	}
+
+	relationRef struct {
+		namespace, relation string
+	}
+
+	linter struct {
+		namespaces []namespace
+		findings   []Finding
+	}
+)
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+const (
+	RuleUnusedRelation        = "unused-relation"
+	RuleUnreachablePermission = "unreachable-permission"
+	RuleUnusedNamespace       = "unused-namespace"
+	RuleRecursiveDefinition   = "recursive-definition"
+)
+
+// Rule describes a lint rule and how to fix its findings.
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Hint        string `json:"hint"`
+}
+
+// Rules lists all lint rules.
+var Rules = []Rule{
+	{
+		ID:          RuleUnusedNamespace,
+		Description: "a namespace declares no relations and is never allowed as a subject type",
+		Hint:        "Remove the namespace, or allow it as a subject type of a relation.",
+	},
+	{
+		ID:          RuleUnusedRelation,
+		Description: "a relation is not used by any permission and is never allowed as a subject set",
+		Hint:        "Remove the relation, or use it in a permission or as a subject set.",
+	},
+	{
+		ID:          RuleUnreachablePermission,
+		Description: "a permission can never be granted, e.g. because it recurses without a base case",
+		Hint:        "Add a relation to the permission that grants it without recursion, e.g. this.related.owners.includes(ctx.subject).",
+	},
+	{
+		ID:          RuleRecursiveDefinition,
+		Description: "a permission includes itself on the same object",
+		Hint:        "Break the cycle by referencing a relation instead of one of the permissions in the cycle.",
+	},
+}
+
+// RuleByID returns the lint rule with the given ID.
+func RuleByID(id string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Severities lists all severities, from the most to the least severe.
+var Severities = []Severity{SeverityError, SeverityWarning, SeverityInfo}
+
+// AtLeast returns true if s is at least as severe as other.
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() <= other.rank()
+}
+
+func (s Severity) rank() int {
+	for i, sev := range Severities {
+		if sev == s {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// Lint checks the type checked namespaces for semantic problems, such as
+// unused relations or permissions that can never be granted. The findings are
+// ordered by namespace and relation as they are declared.
+func Lint(namespaces []namespace) []Finding {
+	l := &linter{namespaces: namespaces}
+	l.unusedNamespaces()
+	l.unusedRelations()
+	l.unreachablePermissions()
+	l.recursiveDefinitions()
+	return l.sorted()
+}
+
+func (l *linter) add(severity Severity, rule, namespace, relation, format string, a ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Severity:  severity,
+		Rule:      rule,
+		Namespace: namespace,
+		Relation:  relation,
+		Message:   fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *linter) find(namespace, relation string) *ast.Relation {
+	for i := range l.namespaces {
+		if l.namespaces[i].Name != namespace {
+			continue
+		}
+		for j := range l.namespaces[i].Relations {
+			if l.namespaces[i].Relations[j].Name == relation {
+				return &l.namespaces[i].Relations[j]
+			}
+		}
+	}
+	return nil
+}
+
+// references calls fn for every relation that the rewrite of the relation in
+// the namespace uses.
+func (l *linter) references(namespace string, rewrite ast.Child, fn func(ref relationRef, computed bool)) {
+	switch c := rewrite.(type) {
+	case *ast.SubjectSetRewrite:
+		for _, child := range c.Children {
+			l.references(namespace, child, fn)
+		}
+	case *ast.InvertResult:
+		l.references(namespace, c.Child, fn)
+	case *ast.ComputedSubjectSet:
+		fn(relationRef{namespace, c.Relation}, true)
+	case *ast.TupleToSubjectSet:
+		fn(relationRef{namespace, c.Relation}, false)
+		if r := l.find(namespace, c.Relation); r != nil {
+			for _, t := range r.Types {
+				fn(relationRef{t.Namespace, c.ComputedSubjectSetRelation}, false)
+			}
+		}
+	}
+}
+
+// unusedNamespaces reports namespaces that declare no relations and can not
+// be referenced by any relation tuple.
+func (l *linter) unusedNamespaces() {
+	used := make(map[string]bool)
+	for _, n := range l.namespaces {
+		for _, r := range n.Relations {
+			for _, t := range r.Types {
+				used[t.Namespace] = true
+			}
+		}
+	}
+	for _, n := range l.namespaces {
+		if len(n.Relations) == 0 && !used[n.Name] {
+			l.add(SeverityWarning, RuleUnusedNamespace, n.Name, "",
+				"namespace %q declares no relations and is never allowed as a subject type", n.Name)
+		}
+	}
+}
+
+// unusedRelations reports relations that are neither used by a permission nor
+// allowed as a subject set.
+func (l *linter) unusedRelations() {
+	used := make(map[relationRef]bool)
+	for _, n := range l.namespaces {
+		for _, r := range n.Relations {
+			for _, t := range r.Types {
+				if t.Relation != "" {
+					used[relationRef{t.Namespace, t.Relation}] = true
+				}
+			}
+			if r.SubjectSetRewrite != nil {
+				l.references(n.Name, r.SubjectSetRewrite, func(ref relationRef, _ bool) {
+					used[ref] = true
+				})
+			}
+		}
+	}
+	for _, n := range l.namespaces {
+		// Namespaces without permissions are usually checked directly, so
+		// their relations are likely used by clients.
+		severity := SeverityInfo
+		for _, r := range n.Relations {
+			if r.SubjectSetRewrite != nil {
+				severity = SeverityWarning
+			}
+		}
+		for _, r := range n.Relations {
+			if r.SubjectSetRewrite == nil && !used[relationRef{n.Name, r.Name}] {
+				l.add(severity, RuleUnusedRelation, n.Name, r.Name,
+					"relation %q is not used by any permission and is never allowed as a subject set", r.Name)
+			}
+		}
+	}
+}
+
+// unreachablePermissions reports permissions that can never be granted,
+// because no relation tuples can satisfy them. This is the case for
+// intersections with such permissions, or recursive permissions without a base
+// case.
+func (l *linter) unreachablePermissions() {
+	granted := make(map[relationRef]bool)
+	var canGrant func(namespace string, rewrite ast.Child) bool
+	canGrant = func(namespace string, rewrite ast.Child) bool {
+		switch c := rewrite.(type) {
+		case *ast.SubjectSetRewrite:
+			if len(c.Children) == 0 {
+				return false
+			}
+			for _, child := range c.Children {
+				ok := canGrant(namespace, child)
+				if c.Operation == ast.OperatorAnd && !ok {
+					return false
+				}
+				if c.Operation != ast.OperatorAnd && ok {
+					return true
+				}
+			}
+			return c.Operation == ast.OperatorAnd
+		case *ast.InvertResult:
+			return true
+		case *ast.ComputedSubjectSet:
+			return l.canGrant(granted, relationRef{namespace, c.Relation})
+		case *ast.TupleToSubjectSet:
+			r := l.find(namespace, c.Relation)
+			if r == nil || r.SubjectSetRewrite != nil {
+				return false
+			}
+			for _, t := range r.Types {
+				if l.canGrant(granted, relationRef{t.Namespace, c.ComputedSubjectSetRelation}) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	// Compute the least fixpoint: a permission can only be granted if it can
+	// be granted based on the permissions known to be grantable so far.
+	for changed := true; changed; {
+		changed = false
+		for _, n := range l.namespaces {
+			for _, r := range n.Relations {
+				ref := relationRef{n.Name, r.Name}
+				if r.SubjectSetRewrite == nil || granted[ref] {
+					continue
+				}
+				if canGrant(n.Name, r.SubjectSetRewrite) {
+					granted[ref] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, n := range l.namespaces {
+		for _, r := range n.Relations {
+			if r.SubjectSetRewrite != nil && !granted[relationRef{n.Name, r.Name}] {
+				l.add(SeverityError, RuleUnreachablePermission, n.Name, r.Name,
+					"permission %q can never be granted, because no relation tuples can satisfy it", r.Name)
+			}
+		}
+	}
+}
+
+// canGrant returns true if the relation can hold relation tuples, or if it is
+// a permission that is known to be grantable.
+func (l *linter) canGrant(granted map[relationRef]bool, ref relationRef) bool {
+	r := l.find(ref.namespace, ref.relation)
+	if r == nil {
+		return false
+	}
+	if r.SubjectSetRewrite == nil {
+		return true
+	}
+	return granted[ref]
+}
+
+// recursiveDefinitions reports permissions that include themselves on the
+// same object, which the check engine can only resolve by running into the
+// maximum depth.
+func (l *linter) recursiveDefinitions() {
+	for _, n := range l.namespaces {
+		for _, r := range n.Relations {
+			if r.SubjectSetRewrite == nil {
+				continue
+			}
+			if cycle := l.cycle(n.Name, []string{r.Name}, make(map[string]bool)); cycle != nil {
+				l.add(SeverityWarning, RuleRecursiveDefinition, n.Name, r.Name,
+					"permission %q includes itself on the same object: %s", r.Name, strings.Join(cycle, " -> "))
+			}
+		}
+	}
+}
+
+// cycle returns the path of computed subject sets from the first to the first
+// relation again, or nil if there is none.
+func (l *linter) cycle(namespace string, path []string, visited map[string]bool) (cycle []string) {
+	current := l.find(namespace, path[len(path)-1])
+	if current == nil || current.SubjectSetRewrite == nil {
+		return nil
+	}
+	visited[current.Name] = true
+
+	l.references(namespace, current.SubjectSetRewrite, func(ref relationRef, computed bool) {
+		if cycle != nil || !computed {
+			return
+		}
+		next := append(append([]string{}, path...), ref.relation)
+		if ref.relation == path[0] {
+			cycle = next
+			return
+		}
+		if !visited[ref.relation] {
+			cycle = l.cycle(namespace, next, visited)
+		}
+	})
+	return cycle
+}
+
+// sorted returns the findings ordered by the declaration of their namespace
+// and relation.
+func (l *linter) sorted() []Finding {
+	var sorted []Finding
+	for _, n := range l.namespaces {
+		for _, f := range l.findings {
+			if f.Namespace == n.Name && f.Relation == "" {
+				sorted = append(sorted, f)
+			}
+		}
+		for _, r := range n.Relations {
+			for _, f := range l.findings {
+				if f.Namespace == n.Name && f.Relation == r.Name {
+					sorted = append(sorted, f)
+				}
+			}
+		}
+	}
+	return sorted
+}
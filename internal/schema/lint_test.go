@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Run("case=reports findings", func(t *testing.T) {
+		ns, errs := Parse(`
+class User implements Namespace {}
+
+class Bot implements Namespace {}
+
+class Tag implements Namespace {
+  related: {
+    owners: User[]
+  }
+}
+
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}
+
+class Folder implements Namespace {
+  related: {
+    parents: Folder[]
+    viewers: (User | SubjectSet<Group, "members">)[]
+    auditors: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      this.related.parents.traverse((p) => p.permits.view(ctx)),
+    inherit: (ctx: Context): boolean => this.related.parents.traverse((p) => p.permits.inherit(ctx)),
+    edit: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject) && this.related.inherit.includes(ctx.subject),
+    read: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject) || this.related.write.includes(ctx.subject),
+    write: (ctx: Context): boolean => this.related.read.includes(ctx.subject),
+  }
+}`)
+		require.Empty(t, errs)
+
+		assert.Equal(t, []Finding{
+			{Severity: SeverityWarning, Rule: RuleUnusedNamespace, Namespace: "Bot",
+				Message: `namespace "Bot" declares no relations and is never allowed as a subject type`},
+			{Severity: SeverityInfo, Rule: RuleUnusedRelation, Namespace: "Tag", Relation: "owners",
+				Message: `relation "owners" is not used by any permission and is never allowed as a subject set`},
+			{Severity: SeverityWarning, Rule: RuleUnusedRelation, Namespace: "Folder", Relation: "auditors",
+				Message: `relation "auditors" is not used by any permission and is never allowed as a subject set`},
+			{Severity: SeverityError, Rule: RuleUnreachablePermission, Namespace: "Folder", Relation: "inherit",
+				Message: `permission "inherit" can never be granted, because no relation tuples can satisfy it`},
+			{Severity: SeverityError, Rule: RuleUnreachablePermission, Namespace: "Folder", Relation: "edit",
+				Message: `permission "edit" can never be granted, because no relation tuples can satisfy it`},
+			{Severity: SeverityWarning, Rule: RuleRecursiveDefinition, Namespace: "Folder", Relation: "read",
+				Message: `permission "read" includes itself on the same object: read -> write -> read`},
+			{Severity: SeverityWarning, Rule: RuleRecursiveDefinition, Namespace: "Folder", Relation: "write",
+				Message: `permission "write" includes itself on the same object: write -> read -> write`},
+		}, Lint(ns))
+	})
+
+	t.Run("case=clean model", func(t *testing.T) {
+		ns, errs := Parse(`
+class User implements Namespace {}
+
+class Folder implements Namespace {
+  related: {
+    parents: Folder[]
+    viewers: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      this.related.parents.traverse((p) => p.permits.view(ctx)),
+  }
+}`)
+		require.Empty(t, errs)
+		assert.Empty(t, Lint(ns))
+	})
+
+	t.Run("case=severities", func(t *testing.T) {
+		assert.True(t, SeverityError.AtLeast(SeverityWarning))
+		assert.True(t, SeverityWarning.AtLeast(SeverityWarning))
+		assert.False(t, SeverityInfo.AtLeast(SeverityWarning))
+	})
+}
@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"encoding/json"
+)
+
+// The subset of the Language Server Protocol that the server implements, see
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/.
+
+type (
+	message struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id,omitempty"`
+		Method  string           `json:"method,omitempty"`
+		Params  json.RawMessage  `json:"params,omitempty"`
+		Result  interface{}      `json:"result,omitempty"`
+		Error   *responseError   `json:"error,omitempty"`
+	}
+
+	responseError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	position struct {
+		Line      int `json:"line"`
+		Character int `json:"character"`
+	}
+
+	lspRange struct {
+		Start position `json:"start"`
+		End   position `json:"end"`
+	}
+
+	location struct {
+		URI   string   `json:"uri"`
+		Range lspRange `json:"range"`
+	}
+
+	textDocumentIdentifier struct {
+		URI string `json:"uri"`
+	}
+
+	textDocumentItem struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	}
+
+	textDocumentPositionParams struct {
+		TextDocument textDocumentIdentifier `json:"textDocument"`
+		Position     position               `json:"position"`
+	}
+
+	didOpenTextDocumentParams struct {
+		TextDocument textDocumentItem `json:"textDocument"`
+	}
+
+	didChangeTextDocumentParams struct {
+		TextDocument   textDocumentIdentifier `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+
+	didCloseTextDocumentParams struct {
+		TextDocument textDocumentIdentifier `json:"textDocument"`
+	}
+
+	initializeResult struct {
+		Capabilities serverCapabilities `json:"capabilities"`
+		ServerInfo   serverInfo         `json:"serverInfo"`
+	}
+
+	serverCapabilities struct {
+		TextDocumentSync   textDocumentSyncOptions `json:"textDocumentSync"`
+		HoverProvider      bool                    `json:"hoverProvider"`
+		DefinitionProvider bool                    `json:"definitionProvider"`
+		CompletionProvider completionOptions       `json:"completionProvider"`
+	}
+
+	textDocumentSyncOptions struct {
+		OpenClose bool `json:"openClose"`
+		Change    int  `json:"change"`
+	}
+
+	completionOptions struct {
+		TriggerCharacters []string `json:"triggerCharacters"`
+	}
+
+	serverInfo struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	hover struct {
+		Contents markupContent `json:"contents"`
+		Range    lspRange      `json:"range"`
+	}
+
+	markupContent struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	}
+
+	completionItem struct {
+		Label  string `json:"label"`
+		Kind   int    `json:"kind"`
+		Detail string `json:"detail,omitempty"`
+	}
+
+	publishDiagnosticsParams struct {
+		URI         string       `json:"uri"`
+		Diagnostics []diagnostic `json:"diagnostics"`
+	}
+
+	diagnostic struct {
+		Range    lspRange `json:"range"`
+		Severity int      `json:"severity"`
+		Source   string   `json:"source"`
+		Message  string   `json:"message"`
+	}
+)
+
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+
+	textDocumentSyncFull = 1
+
+	severityError       = 1
+	severityWarning     = 2
+	severityInformation = 3
+
+	completionKindMethod  = 2
+	completionKindField   = 5
+	completionKindClass   = 7
+	completionKindKeyword = 14
+)
@@ -0,0 +1,316 @@
+// Package lsp implements a language server for the Ory Permission Language.
+// It communicates with the editor through the Language Server Protocol over a
+// pair of streams, usually stdin and stdout.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/schema"
+)
+
+type (
+	// Server is a language server for Ory Permission Language files. It keeps
+	// the content of the documents that are open in the editor.
+	Server struct {
+		out       io.Writer
+		documents map[string]*document
+		shutdown  bool
+	}
+
+	document struct {
+		text     string
+		analysis *schema.Document
+	}
+)
+
+func NewServer() *Server {
+	return &Server{documents: make(map[string]*document)}
+}
+
+// Serve handles the messages read from r and writes the responses to w, until
+// the client sends the exit notification, r is closed, or the context is
+// canceled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.out = w
+	in := bufio.NewReader(r)
+	for ctx.Err() == nil {
+		raw, err := readMessage(in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			if err := s.respond(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(&msg); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// readMessage reads the content of the next message, which is preceded by
+// HTTP-like headers.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if len(headers) == 0 && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, errors.WithStack(err)
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, errors.Errorf("invalid Content-Length header %q", headers.Get("Content-Length"))
+	}
+	raw := make([]byte, length)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return raw, nil
+}
+
+func (s *Server) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(raw), raw)
+	return errors.WithStack(err)
+}
+
+func (s *Server) respond(id *json.RawMessage, result interface{}, rErr *responseError) error {
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	if result == nil && rErr == nil {
+		// The result of a successful request has to be set, even if it is
+		// null.
+		result = json.RawMessage("null")
+	}
+	return s.write(&message{ID: id, Result: result, Error: rErr})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.write(&message{Method: method, Params: raw})
+}
+
+func (s *Server) handle(msg *message) error {
+	isRequest := msg.ID != nil
+	if s.shutdown && isRequest {
+		return s.respond(msg.ID, nil, &responseError{Code: codeInvalidRequest, Message: "the server is shutting down"})
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch msg.Method {
+	case "initialize":
+		result = initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncOptions{OpenClose: true, Change: textDocumentSyncFull},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+				CompletionProvider: completionOptions{TriggerCharacters: []string{"."}},
+			},
+			ServerInfo: serverInfo{Name: "keto", Version: config.Version},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			return s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params didChangeTextDocumentParams
+		if err = json.Unmarshal(msg.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			// The server only supports full synchronization, so the last
+			// change is the whole document.
+			return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params didCloseTextDocumentParams
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			delete(s.documents, params.TextDocument.URI)
+			return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []diagnostic{},
+			})
+		}
+	case "textDocument/hover":
+		result, err = s.positionRequest(msg, s.hover)
+	case "textDocument/definition":
+		result, err = s.positionRequest(msg, s.definition)
+	case "textDocument/completion":
+		result, err = s.positionRequest(msg, s.completion)
+	default:
+		if isRequest {
+			return s.respond(msg.ID, nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q is not supported", msg.Method)})
+		}
+		// Unknown notifications are ignored.
+		return nil
+	}
+
+	if !isRequest {
+		return nil
+	}
+	if err != nil {
+		return s.respond(msg.ID, nil, &responseError{Code: codeInvalidParams, Message: err.Error()})
+	}
+	return s.respond(msg.ID, result, nil)
+}
+
+// update analyzes the new content of the document and publishes its
+// diagnostics.
+func (s *Server) update(uri, text string) error {
+	d := &document{text: text}
+	if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+		d.analysis = schema.AnalyzeLocalFile(u.Path, text)
+	} else {
+		d.analysis = schema.Analyze(text)
+	}
+	s.documents[uri] = d
+
+	diags := make([]diagnostic, 0)
+	for _, diag := range d.analysis.Diagnostics() {
+		severity := severityError
+		switch diag.Severity {
+		case schema.SeverityWarning:
+			severity = severityWarning
+		case schema.SeverityInfo:
+			severity = severityInformation
+		}
+		diags = append(diags, diagnostic{
+			Range:    d.toRange(diag.Range),
+			Severity: severity,
+			Source:   "keto",
+			Message:  diag.Message,
+		})
+	}
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+func (s *Server) positionRequest(msg *message, handler func(uri string, d *document, offset int) interface{}) (interface{}, error) {
+	var params textDocumentPositionParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, errors.Errorf("document %q is not open", params.TextDocument.URI)
+	}
+	return handler(params.TextDocument.URI, d, d.toOffset(params.Position)), nil
+}
+
+func (s *Server) hover(_ string, d *document, offset int) interface{} {
+	contents, r, ok := d.analysis.Hover(offset)
+	if !ok {
+		return nil
+	}
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: contents},
+		Range:    d.toRange(r),
+	}
+}
+
+func (s *Server) definition(uri string, d *document, offset int) interface{} {
+	r, ok := d.analysis.Definition(offset)
+	if !ok {
+		return nil
+	}
+	return location{URI: uri, Range: d.toRange(r)}
+}
+
+func (s *Server) completion(_ string, d *document, offset int) interface{} {
+	items := make([]completionItem, 0)
+	for _, c := range d.analysis.Completions(offset) {
+		kind := completionKindClass
+		switch c.Kind {
+		case schema.CompletionRelation:
+			kind = completionKindField
+		case schema.CompletionPermission:
+			kind = completionKindMethod
+		case schema.CompletionKeyword:
+			kind = completionKindKeyword
+		}
+		items = append(items, completionItem{Label: c.Label, Kind: kind, Detail: c.Detail})
+	}
+	return items
+}
+
+// toOffset converts the position to a byte offset in the text. The character
+// of a position counts UTF-16 code units.
+func (d *document) toOffset(pos position) int {
+	lineStart := 0
+	for line := 0; line < pos.Line; line++ {
+		next := strings.IndexByte(d.text[lineStart:], '\n')
+		if next == -1 {
+			return len(d.text)
+		}
+		lineStart += next + 1
+	}
+	character := 0
+	for i, r := range d.text[lineStart:] {
+		if character >= pos.Character || r == '\n' {
+			return lineStart + i
+		}
+		character += utf16Len(r)
+	}
+	return len(d.text)
+}
+
+// toPosition converts the byte offset in the text to a position.
+func (d *document) toPosition(offset int) (pos position) {
+	for i, r := range d.text {
+		if i >= offset {
+			break
+		}
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else {
+			pos.Character += utf16Len(r)
+		}
+	}
+	return pos
+}
+
+func (d *document) toRange(r schema.Range) lspRange {
+	return lspRange{Start: d.toPosition(r.Start), End: d.toPosition(r.End)}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
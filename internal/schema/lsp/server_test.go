@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+const testDocument = `class User implements Namespace {}
+
+class Document implements Namespace {
+  related: {
+    owners: User[]
+  }
+
+  permits = {
+    edit: (ctx: Context): boolean => this.related.owners.includes(ctx.subject),
+  }
+}
+`
+
+func TestServer(t *testing.T) {
+	const uri = "untitled:namespaces.keto.ts"
+
+	var (
+		in bytes.Buffer
+		id int
+	)
+	send := func(method string, params interface{}, isRequest bool) {
+		msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+		if isRequest {
+			id++
+			msg["id"] = id
+		}
+		raw, err := json.Marshal(msg)
+		require.NoError(t, err)
+		_, _ = fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(raw), raw)
+	}
+	at := func(line, character int) map[string]interface{} {
+		return map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri},
+			"position":     map[string]interface{}{"line": line, "character": character},
+		}
+	}
+
+	send("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, true)
+	send("initialized", map[string]interface{}{}, false)
+	send("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "typescript", "version": 1, "text": testDocument},
+	}, false)
+	send("textDocument/hover", at(4, 13), true)      // User in "owners: User[]"
+	send("textDocument/definition", at(8, 53), true) // owners in "this.related.owners"
+	send("textDocument/completion", at(8, 50), true) // after "this.related."
+	send("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "class Document implements Namespace {\n  related: {\n    owners: Userr[]\n  }\n}\n"}},
+	}, false)
+	send("workspace/symbol", map[string]interface{}{"query": ""}, true)
+	send("shutdown", nil, true)
+	send("exit", nil, false)
+
+	var out bytes.Buffer
+	require.NoError(t, NewServer().Serve(context.Background(), &in, &out))
+
+	var msgs []string
+	r := bufio.NewReader(&out)
+	for {
+		raw, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		msgs = append(msgs, string(raw))
+	}
+	require.Len(t, msgs, 8, "%v", msgs)
+
+	t.Run("method=initialize", func(t *testing.T) {
+		assert.True(t, gjson.Get(msgs[0], "result.capabilities.hoverProvider").Bool())
+		assert.True(t, gjson.Get(msgs[0], "result.capabilities.definitionProvider").Bool())
+		assert.Equal(t, int64(1), gjson.Get(msgs[0], "result.capabilities.textDocumentSync.change").Int())
+	})
+
+	t.Run("method=publishDiagnostics", func(t *testing.T) {
+		assert.Equal(t, "textDocument/publishDiagnostics", gjson.Get(msgs[1], "method").String())
+		assert.Equal(t, "[]", gjson.Get(msgs[1], "params.diagnostics").Raw)
+
+		assert.Equal(t, "textDocument/publishDiagnostics", gjson.Get(msgs[5], "method").String())
+		assert.Equal(t, `namespace "Userr" was not declared`, gjson.Get(msgs[5], "params.diagnostics.0.message").String())
+		assert.Equal(t, int64(severityError), gjson.Get(msgs[5], "params.diagnostics.0.severity").Int())
+		assert.JSONEq(t, `{"start":{"line":2,"character":12},"end":{"line":2,"character":17}}`, gjson.Get(msgs[5], "params.diagnostics.0.range").Raw)
+	})
+
+	t.Run("method=hover", func(t *testing.T) {
+		assert.Contains(t, gjson.Get(msgs[2], "result.contents.value").String(), "class User implements Namespace")
+		assert.JSONEq(t, `{"start":{"line":4,"character":12},"end":{"line":4,"character":16}}`, gjson.Get(msgs[2], "result.range").Raw)
+	})
+
+	t.Run("method=definition", func(t *testing.T) {
+		assert.Equal(t, uri, gjson.Get(msgs[3], "result.uri").String())
+		assert.JSONEq(t, `{"start":{"line":4,"character":4},"end":{"line":4,"character":10}}`, gjson.Get(msgs[3], "result.range").Raw)
+	})
+
+	t.Run("method=completion", func(t *testing.T) {
+		assert.Equal(t, "owners", gjson.Get(msgs[4], "result.0.label").String())
+		assert.Equal(t, "User[]", gjson.Get(msgs[4], "result.0.detail").String())
+		assert.Equal(t, int64(1), gjson.Get(msgs[4], "result.#").Int())
+	})
+
+	t.Run("case=unsupported method", func(t *testing.T) {
+		assert.Equal(t, int64(codeMethodNotFound), gjson.Get(msgs[6], "error.code").Int())
+	})
+
+	t.Run("method=shutdown", func(t *testing.T) {
+		assert.True(t, gjson.Get(msgs[7], "result").Exists())
+		assert.Equal(t, "null", gjson.Get(msgs[7], "result").Raw)
+	})
+}
+
+func TestPositions(t *testing.T) {
+	d := &document{text: "a\nb😀c\n"}
+	for _, tc := range []struct {
+		pos    position
+		offset int
+	}{
+		{position{0, 0}, 0},
+		{position{0, 1}, 1},
+		{position{1, 0}, 2},
+		{position{1, 1}, 3},
+		{position{1, 3}, 7},
+		{position{2, 0}, 9},
+	} {
+		assert.Equal(t, tc.offset, d.toOffset(tc.pos), "%+v", tc.pos)
+		assert.Equal(t, tc.pos, d.toPosition(tc.offset), "%d", tc.offset)
+	}
+}
@@ -0,0 +1,414 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ory/keto/internal/namespace/ast"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// fgaModel is an OpenFGA authorization model, parsed from either the
+	// JSON or the DSL representation.
+	fgaModel struct {
+		types []fgaType
+	}
+	fgaType struct {
+		name      string
+		relations []fgaRelation
+	}
+	fgaRelation struct {
+		name    string
+		direct  []fgaTypeRef // the directly related user types
+		rewrite *fgaUserset
+	}
+	fgaTypeRef struct {
+		Type      string    `json:"type"`
+		Relation  string    `json:"relation,omitempty"`
+		Wildcard  *struct{} `json:"wildcard,omitempty"`
+		Condition string    `json:"condition,omitempty"`
+	}
+	// fgaUserset is a userset rewrite. Exactly one of the fields is set,
+	// matching the JSON representation of OpenFGA.
+	fgaUserset struct {
+		This            *struct{}      `json:"this,omitempty"`
+		ComputedUserset *fgaObjectRel  `json:"computedUserset,omitempty"`
+		TupleToUserset  *fgaTTU        `json:"tupleToUserset,omitempty"`
+		Union           *fgaUsersets   `json:"union,omitempty"`
+		Intersection    *fgaUsersets   `json:"intersection,omitempty"`
+		Difference      *fgaDifference `json:"difference,omitempty"`
+	}
+	fgaObjectRel struct {
+		Relation string `json:"relation"`
+	}
+	fgaTTU struct {
+		Tupleset        fgaObjectRel `json:"tupleset"`
+		ComputedUserset fgaObjectRel `json:"computedUserset"`
+	}
+	fgaUsersets struct {
+		Child []*fgaUserset `json:"child"`
+	}
+	fgaDifference struct {
+		Base     *fgaUserset `json:"base"`
+		Subtract *fgaUserset `json:"subtract"`
+	}
+
+	// fgaTranslator translates an OpenFGA model. It records the relation
+	// that stores the relation tuples of every OpenFGA relation.
+	fgaTranslator struct {
+		namespaces     []*namespace
+		direct         map[string]map[string]string // type -> relation -> relation of the relation tuples
+		untranslatable []Untranslatable
+		errs           []error
+	}
+)
+
+// openFGADirectSuffix is appended to the name of an OpenFGA relation that is
+// both directly assignable and computed, for the relation that stores the
+// relation tuples.
+const openFGADirectSuffix = "_direct"
+
+// ImportOpenFGA translates an OpenFGA authorization model, written in JSON or
+// in the DSL, to namespaces. Types become namespaces. Directly related user
+// types become relations, and usersets become permissions. A relation that is
+// both directly assignable and computed is split into a relation with the
+// suffix "_direct" and a permission. Wildcards and conditions have no
+// equivalent, and are reported as untranslatable.
+func ImportOpenFGA(model []byte) ([]namespace, []Untranslatable, []error) {
+	t, errs := translateOpenFGA(model)
+	if len(errs) > 0 {
+		return nil, t.untranslatable, errs
+	}
+	_, nn, errs := printChecked(t.namespaces)
+	return nn, t.untranslatable, errs
+}
+
+// ImportOpenFGATuples translates OpenFGA relation tuples to relation tuples of
+// the namespaces that ImportOpenFGA translates the model to. The tuples are
+// a JSON list of tuple keys, optionally wrapped in an object with the key
+// "tuple_keys". Tuples with wildcards are dropped, and conditions are ignored.
+func ImportOpenFGATuples(model, tuples []byte) ([]*ketoapi.RelationTuple, []Untranslatable, error) {
+	t, errs := translateOpenFGA(model)
+	if len(errs) > 0 {
+		return nil, nil, errs[0]
+	}
+
+	type tupleKey struct {
+		User      string          `json:"user"`
+		Relation  string          `json:"relation"`
+		Object    string          `json:"object"`
+		Condition json.RawMessage `json:"condition,omitempty"`
+	}
+	var keys []tupleKey
+	if trimmed := bytes.TrimSpace(tuples); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped struct {
+			TupleKeys []tupleKey `json:"tuple_keys"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapped); err != nil {
+			return nil, nil, fmt.Errorf("could not decode the tuples: %w", err)
+		}
+		keys = wrapped.TupleKeys
+	} else if err := json.Unmarshal(trimmed, &keys); err != nil {
+		return nil, nil, fmt.Errorf("could not decode the tuples: %w", err)
+	}
+
+	var (
+		translated     []*ketoapi.RelationTuple
+		untranslatable []Untranslatable
+	)
+	for _, k := range keys {
+		objectType, objectID, ok := strings.Cut(k.Object, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("tuple %s#%s@%s: the object has to be written as type:id", k.Object, k.Relation, k.User)
+		}
+		objectType = fgaName(objectType)
+		relation, ok := t.direct[objectType][k.Relation]
+		if !ok {
+			return nil, nil, fmt.Errorf("tuple %s#%s@%s: the relation can not be written directly", k.Object, k.Relation, k.User)
+		}
+
+		user, userRelation, _ := strings.Cut(k.User, "#")
+		userType, userID, ok := strings.Cut(user, ":")
+		switch {
+		case !ok:
+			return nil, nil, fmt.Errorf("tuple %s#%s@%s: the user has to be written as type:id or type:id#relation", k.Object, k.Relation, k.User)
+		case userID == "*":
+			untranslatable = append(untranslatable, Untranslatable{Namespace: objectType, Relation: k.Relation, Message: fmt.Sprintf("the tuple %s#%s@%s was dropped, wildcards are not supported", k.Object, k.Relation, k.User)})
+			continue
+		case len(k.Condition) > 0:
+			untranslatable = append(untranslatable, Untranslatable{Namespace: objectType, Relation: k.Relation, Message: fmt.Sprintf("the condition of the tuple %s#%s@%s was dropped", k.Object, k.Relation, k.User)})
+		}
+		translated = append(translated, &ketoapi.RelationTuple{
+			Namespace:  objectType,
+			Object:     objectID,
+			Relation:   relation,
+			SubjectSet: &ketoapi.SubjectSet{Namespace: fgaName(userType), Object: userID, Relation: userRelation},
+		})
+	}
+	return translated, untranslatable, nil
+}
+
+func translateOpenFGA(input []byte) (*fgaTranslator, []error) {
+	t := &fgaTranslator{direct: make(map[string]map[string]string)}
+
+	var (
+		m   *fgaModel
+		err error
+	)
+	if trimmed := bytes.TrimSpace(input); len(trimmed) > 0 && trimmed[0] == '{' {
+		m, err = parseOpenFGAJSON(trimmed)
+	} else {
+		m, err = parseOpenFGADSL(string(input))
+	}
+	if err != nil {
+		return t, []error{err}
+	}
+
+	for _, typ := range m.types {
+		t.translateType(typ)
+	}
+	return t, t.errs
+}
+
+func (t *fgaTranslator) report(n, r, format string, a ...interface{}) {
+	t.untranslatable = append(t.untranslatable, Untranslatable{Namespace: n, Relation: r, Message: fmt.Sprintf(format, a...)})
+}
+
+// fgaName converts an OpenFGA type or relation name to an identifier. OpenFGA
+// allows e.g. dashes in names.
+func fgaName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(letters+digits, r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (t *fgaTranslator) translateType(typ fgaType) {
+	n := &namespace{Name: fgaName(typ.name)}
+	if n.Name != typ.name {
+		t.report(n.Name, "", "the type %q was renamed to %q", typ.name, n.Name)
+	}
+	t.direct[n.Name] = make(map[string]string)
+
+	// The relation tuples of split relations are stored in the relation
+	// with the suffix, also if they are traversed.
+	for _, r := range typ.relations {
+		if r.rewrite.This != nil {
+			t.direct[n.Name][r.name] = fgaName(r.name)
+		} else if r.rewrite.containsThis() {
+			t.direct[n.Name][r.name] = fgaName(r.name) + openFGADirectSuffix
+		}
+	}
+
+	for _, r := range typ.relations {
+		name := fgaName(r.name)
+		if name != r.name {
+			t.report(n.Name, name, "the relation %q was renamed to %q", r.name, name)
+		}
+
+		var types []ast.RelationType
+		for _, ref := range r.direct {
+			switch {
+			case ref.Wildcard != nil:
+				t.report(n.Name, name, "the wildcard %s:* was dropped, relation tuples can not grant access to all subjects of a type", ref.Type)
+				continue
+			case ref.Condition != "":
+				t.report(n.Name, name, "the condition %s of %s was dropped, condition expressions are not evaluated", ref.Condition, ref.Type)
+			}
+			types = append(types, ast.RelationType{Namespace: fgaName(ref.Type), Relation: fgaName(ref.Relation)})
+		}
+
+		direct, isDirect := t.direct[n.Name][r.name]
+		if isDirect && len(types) == 0 {
+			if len(r.direct) == 0 {
+				t.errs = append(t.errs, fmt.Errorf("type %s, relation %s: the directly related user types are missing, only models with schema 1.1 can be translated", typ.name, r.name))
+				return
+			}
+			t.report(n.Name, name, "the relation was dropped, because none of its directly related user types can be translated")
+			delete(t.direct[n.Name], r.name)
+			continue
+		}
+		if r.rewrite.This != nil {
+			n.Relations = append(n.Relations, ast.Relation{Name: name, Types: types})
+			continue
+		}
+		if isDirect {
+			n.Relations = append(n.Relations, ast.Relation{Name: direct, Types: types})
+			t.report(n.Name, name, "relation tuples are written to %q, and %q became a permission", direct, name)
+		}
+		n.Relations = append(n.Relations, ast.Relation{Name: name, SubjectSetRewrite: t.translateUserset(n.Name, r.name, r.rewrite).AsRewrite()})
+	}
+	t.namespaces = append(t.namespaces, n)
+}
+
+func (t *fgaTranslator) translateUserset(n, relation string, u *fgaUserset) ast.Child {
+	switch {
+	case u.This != nil:
+		return &ast.ComputedSubjectSet{Relation: t.direct[n][relation]}
+	case u.ComputedUserset != nil:
+		return &ast.ComputedSubjectSet{Relation: fgaName(u.ComputedUserset.Relation)}
+	case u.TupleToUserset != nil:
+		tupleset := fgaName(u.TupleToUserset.Tupleset.Relation)
+		if direct, ok := t.direct[n][u.TupleToUserset.Tupleset.Relation]; ok {
+			tupleset = direct
+		}
+		return &ast.TupleToSubjectSet{Relation: tupleset, ComputedSubjectSetRelation: fgaName(u.TupleToUserset.ComputedUserset.Relation)}
+	case u.Union != nil, u.Intersection != nil:
+		rewrite := &ast.SubjectSetRewrite{Operation: ast.OperatorOr}
+		children := u.Union
+		if u.Intersection != nil {
+			rewrite.Operation, children = ast.OperatorAnd, u.Intersection
+		}
+		for _, c := range children.Child {
+			rewrite.Children = append(rewrite.Children, t.translateUserset(n, relation, c))
+		}
+		return rewrite
+	case u.Difference != nil:
+		return &ast.SubjectSetRewrite{
+			Operation: ast.OperatorAnd,
+			Children: ast.Children{
+				t.translateUserset(n, relation, u.Difference.Base),
+				&ast.InvertResult{Child: t.translateUserset(n, relation, u.Difference.Subtract)},
+			},
+		}
+	}
+	panic("not reached")
+}
+
+func (u *fgaUserset) containsThis() bool {
+	switch {
+	case u.This != nil:
+		return true
+	case u.Union != nil:
+		return anyContainsThis(u.Union.Child)
+	case u.Intersection != nil:
+		return anyContainsThis(u.Intersection.Child)
+	case u.Difference != nil:
+		return u.Difference.Base.containsThis() || u.Difference.Subtract.containsThis()
+	}
+	return false
+}
+
+func anyContainsThis(uu []*fgaUserset) bool {
+	for _, u := range uu {
+		if u.containsThis() {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *fgaUserset) validate() error {
+	set := 0
+	for _, isSet := range []bool{u.This != nil, u.ComputedUserset != nil, u.TupleToUserset != nil, u.Union != nil, u.Intersection != nil, u.Difference != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("a userset has to be exactly one of this, computedUserset, tupleToUserset, union, intersection, or difference")
+	}
+	switch {
+	case u.Union != nil:
+		return validateAll(u.Union.Child)
+	case u.Intersection != nil:
+		return validateAll(u.Intersection.Child)
+	case u.Difference != nil:
+		if u.Difference.Base == nil || u.Difference.Subtract == nil {
+			return fmt.Errorf("a difference needs a base and a subtract userset")
+		}
+		return validateAll([]*fgaUserset{u.Difference.Base, u.Difference.Subtract})
+	}
+	return nil
+}
+
+func validateAll(uu []*fgaUserset) error {
+	for _, u := range uu {
+		if u == nil {
+			return fmt.Errorf("a userset must not be null")
+		}
+		if err := u.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseOpenFGAJSON parses the JSON representation of a model, also if it is
+// wrapped in "authorization_model" as returned by the OpenFGA API. The order
+// of the relations is kept.
+func parseOpenFGAJSON(input []byte) (*fgaModel, error) {
+	var raw struct {
+		AuthorizationModel *json.RawMessage `json:"authorization_model"`
+		TypeDefinitions    []struct {
+			Type      string          `json:"type"`
+			Relations json.RawMessage `json:"relations"`
+			Metadata  struct {
+				Relations map[string]struct {
+					DirectlyRelatedUserTypes []fgaTypeRef `json:"directly_related_user_types"`
+				} `json:"relations"`
+			} `json:"metadata"`
+		} `json:"type_definitions"`
+	}
+	if err := json.Unmarshal(input, &raw); err != nil {
+		return nil, fmt.Errorf("could not decode the model: %w", err)
+	}
+	if raw.AuthorizationModel != nil {
+		return parseOpenFGAJSON(*raw.AuthorizationModel)
+	}
+
+	m := &fgaModel{}
+	for _, td := range raw.TypeDefinitions {
+		typ := fgaType{name: td.Type}
+		names, rewrites, err := orderedObject(td.Relations)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: could not decode the relations: %w", td.Type, err)
+		}
+		for _, name := range names {
+			r := fgaRelation{name: name, rewrite: &fgaUserset{}, direct: td.Metadata.Relations[name].DirectlyRelatedUserTypes}
+			if err := json.Unmarshal(rewrites[name], r.rewrite); err != nil {
+				return nil, fmt.Errorf("type %s, relation %s: %w", td.Type, name, err)
+			}
+			if err := r.rewrite.validate(); err != nil {
+				return nil, fmt.Errorf("type %s, relation %s: %w", td.Type, name, err)
+			}
+			typ.relations = append(typ.relations, r)
+		}
+		m.types = append(m.types, typ)
+	}
+	return m, nil
+}
+
+// orderedObject decodes a JSON object, and returns its keys in order.
+func orderedObject(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil, values, nil
+	}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, nil, err
+		}
+	}
+	return keys, values, nil
+}
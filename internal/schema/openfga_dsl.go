@@ -0,0 +1,260 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fgaDSLParser parses a relation definition of the OpenFGA DSL, e.g.
+// "[user, group#member] or owner or viewer from parent".
+type fgaDSLParser struct {
+	line   int
+	tokens []string
+	pos    int
+	direct []fgaTypeRef
+}
+
+// parseOpenFGADSL parses the DSL representation of a model. Conditions are
+// skipped, modular models are not supported.
+func parseOpenFGADSL(input string) (*fgaModel, error) {
+	var (
+		m              = &fgaModel{}
+		typ            *fgaType
+		conditionDepth int
+	)
+	for i, line := range strings.Split(input, "\n") {
+		lineNo := i + 1
+		if conditionDepth > 0 {
+			conditionDepth += strings.Count(line, "{") - strings.Count(line, "}")
+			continue
+		}
+		line = strings.TrimSpace(stripFGAComment(line))
+		if line == "" {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch keyword {
+		case "model", "relations":
+		case "schema":
+			if rest != "1.1" && rest != "1.2" {
+				return nil, fmt.Errorf("%d: only models with schema 1.1 can be translated, got schema %s", lineNo, rest)
+			}
+		case "module", "extend":
+			return nil, fmt.Errorf("%d: modular models are not supported, use the combined model of `fga model write` instead", lineNo)
+		case "type":
+			m.types = append(m.types, fgaType{name: rest})
+			typ = &m.types[len(m.types)-1]
+		case "condition":
+			conditionDepth = strings.Count(line, "{") - strings.Count(line, "}")
+		case "define":
+			if typ == nil {
+				return nil, fmt.Errorf("%d: relations have to be defined within a type", lineNo)
+			}
+			name, definition, ok := strings.Cut(rest, ":")
+			if !ok {
+				return nil, fmt.Errorf("%d: expected \"define <relation>: <definition>\"", lineNo)
+			}
+			p := &fgaDSLParser{line: lineNo, tokens: tokenizeFGA(definition)}
+			rewrite, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			typ.relations = append(typ.relations, fgaRelation{name: strings.TrimSpace(name), direct: p.direct, rewrite: rewrite})
+		default:
+			return nil, fmt.Errorf("%d: unexpected %q", lineNo, keyword)
+		}
+	}
+	return m, nil
+}
+
+// stripFGAComment removes a comment, which starts with "#" at the beginning
+// of the line or after whitespace. Subject sets such as "group#member" also
+// contain "#".
+func stripFGAComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	for _, sep := range []string{" #", "\t#"} {
+		if i := strings.Index(line, sep); i >= 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+func tokenizeFGA(definition string) []string {
+	var (
+		tokens []string
+		word   strings.Builder
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range definition {
+		switch {
+		case strings.ContainsRune("[](),", r):
+			flush()
+			tokens = append(tokens, string(r))
+		case r == ' ' || r == '\t':
+			flush()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func (p *fgaDSLParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *fgaDSLParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *fgaDSLParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+func (p *fgaDSLParser) expect(token string) error {
+	if got := p.next(); got != token {
+		return p.errorf("expected %q, got %s", token, fgaGot(got))
+	}
+	return nil
+}
+
+func (p *fgaDSLParser) parse() (*fgaUserset, error) {
+	u, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return u, nil
+}
+
+// parseExpression parses operands combined with either "or" or "and", and
+// an optional "but not". Mixing "or" and "and" needs parentheses.
+func (p *fgaDSLParser) parseExpression() (*fgaUserset, error) {
+	first, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		op       string
+		children = []*fgaUserset{first}
+	)
+	for p.peek() == "or" || p.peek() == "and" {
+		if op != "" && p.peek() != op {
+			return nil, p.errorf("\"or\" and \"and\" can not be mixed without parentheses")
+		}
+		op = p.next()
+		child, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+
+	u := first
+	switch op {
+	case "or":
+		u = &fgaUserset{Union: &fgaUsersets{Child: children}}
+	case "and":
+		u = &fgaUserset{Intersection: &fgaUsersets{Child: children}}
+	}
+
+	if p.peek() == "but" {
+		p.next()
+		if err := p.expect("not"); err != nil {
+			return nil, err
+		}
+		subtract, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		u = &fgaUserset{Difference: &fgaDifference{Base: u, Subtract: subtract}}
+	}
+	return u, nil
+}
+
+func (p *fgaDSLParser) parseOperand() (*fgaUserset, error) {
+	switch t := p.next(); t {
+	case "(":
+		u, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return u, p.expect(")")
+	case "[":
+		if err := p.parseTypeRestrictions(); err != nil {
+			return nil, err
+		}
+		return &fgaUserset{This: &struct{}{}}, nil
+	case "", ")", "]", ",", "or", "and", "but", "not", "from":
+		return nil, p.errorf("expected relation, \"[\", or \"(\", got %s", fgaGot(t))
+	default:
+		if p.peek() != "from" {
+			return &fgaUserset{ComputedUserset: &fgaObjectRel{Relation: t}}, nil
+		}
+		p.next()
+		tupleset := p.next()
+		if tupleset == "" {
+			return nil, p.errorf("expected relation after \"from\", got end of definition")
+		}
+		return &fgaUserset{TupleToUserset: &fgaTTU{
+			Tupleset:        fgaObjectRel{Relation: tupleset},
+			ComputedUserset: fgaObjectRel{Relation: t},
+		}}, nil
+	}
+}
+
+// parseTypeRestrictions parses the directly related user types after "[",
+// e.g. "user, user:*, group#member, user with condition]".
+func (p *fgaDSLParser) parseTypeRestrictions() error {
+	for {
+		t := p.next()
+		if t == "" || strings.ContainsAny(t, "[](),") {
+			return p.errorf("expected type, got %s", fgaGot(t))
+		}
+		ref := fgaTypeRef{Type: t}
+		switch typ, rel, ok := strings.Cut(t, "#"); {
+		case ok:
+			ref.Type, ref.Relation = typ, rel
+		case strings.HasSuffix(t, ":*"):
+			ref.Type, ref.Wildcard = strings.TrimSuffix(t, ":*"), &struct{}{}
+		}
+		if p.peek() == "with" {
+			p.next()
+			ref.Condition = p.next()
+		}
+		p.direct = append(p.direct, ref)
+
+		switch t := p.next(); t {
+		case ",":
+		case "]":
+			return nil
+		default:
+			return p.errorf("expected \",\" or \"]\", got %s", fgaGot(t))
+		}
+	}
+}
+
+func fgaGot(token string) string {
+	if token == "" {
+		return "end of definition"
+	}
+	return fmt.Sprintf("%q", token)
+}
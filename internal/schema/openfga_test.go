@@ -0,0 +1,213 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+const (
+	openFGADSL = `model
+  schema 1.1
+
+# the users
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type folder
+  relations
+    define viewer: [user, group#member] # also the members of groups
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define blocked: [user]
+    define viewer: [user, group#member] or owner or viewer from parent
+    define can_share: owner and (viewer but not blocked)
+`
+
+	openFGAJSON = `{
+  "schema_version": "1.1",
+  "type_definitions": [
+    {"type": "user"},
+    {
+      "type": "group",
+      "relations": {"member": {"this": {}}},
+      "metadata": {"relations": {"member": {"directly_related_user_types": [{"type": "user"}, {"type": "group", "relation": "member"}]}}}
+    },
+    {
+      "type": "folder",
+      "relations": {"viewer": {"this": {}}},
+      "metadata": {"relations": {"viewer": {"directly_related_user_types": [{"type": "user"}, {"type": "group", "relation": "member"}]}}}
+    },
+    {
+      "type": "document",
+      "relations": {
+        "parent": {"this": {}},
+        "owner": {"this": {}},
+        "blocked": {"this": {}},
+        "viewer": {"union": {"child": [
+          {"this": {}},
+          {"computedUserset": {"object": "", "relation": "owner"}},
+          {"tupleToUserset": {"tupleset": {"object": "", "relation": "parent"}, "computedUserset": {"object": "", "relation": "viewer"}}}
+        ]}},
+        "can_share": {"intersection": {"child": [
+          {"computedUserset": {"relation": "owner"}},
+          {"difference": {"base": {"computedUserset": {"relation": "viewer"}}, "subtract": {"computedUserset": {"relation": "blocked"}}}}
+        ]}}
+      },
+      "metadata": {"relations": {
+        "parent": {"directly_related_user_types": [{"type": "folder"}]},
+        "owner": {"directly_related_user_types": [{"type": "user"}]},
+        "blocked": {"directly_related_user_types": [{"type": "user"}]},
+        "viewer": {"directly_related_user_types": [{"type": "user"}, {"type": "group", "relation": "member"}]}
+      }}
+    }
+  ]
+}`
+)
+
+func TestImportOpenFGA(t *testing.T) {
+	expected, errs := Parse(`
+class user implements Namespace {}
+
+class group implements Namespace {
+  related: {
+    member: (user | SubjectSet<group, "member">)[]
+  }
+}
+
+class folder implements Namespace {
+  related: {
+    viewer: (user | SubjectSet<group, "member">)[]
+  }
+}
+
+class document implements Namespace {
+  related: {
+    parent: folder[]
+    owner: user[]
+    blocked: user[]
+    viewer_direct: (user | SubjectSet<group, "member">)[]
+  }
+
+  permits = {
+    viewer: (ctx: Context): boolean =>
+      this.related.viewer_direct.includes(ctx.subject) ||
+      this.related.owner.includes(ctx.subject) ||
+      this.related.parent.traverse((f) => f.related.viewer.includes(ctx.subject)),
+    can_share: (ctx: Context): boolean =>
+      this.related.owner.includes(ctx.subject) &&
+      this.related.viewer.includes(ctx.subject) &&
+      !this.related.blocked.includes(ctx.subject),
+  }
+}`)
+	require.Empty(t, errs)
+
+	for name, model := range map[string]string{"DSL": openFGADSL, "JSON": openFGAJSON, "API response": `{"authorization_model": ` + openFGAJSON + `}`} {
+		t.Run("format="+name, func(t *testing.T) {
+			nn, untranslatable, errs := ImportOpenFGA([]byte(model))
+			require.Empty(t, errs)
+			assert.Equal(t, []Untranslatable{{
+				Namespace: "document",
+				Relation:  "viewer",
+				Message:   `relation tuples are written to "viewer_direct", and "viewer" became a permission`,
+			}}, untranslatable)
+			assert.Equal(t, expected, nn)
+		})
+	}
+}
+
+func TestImportOpenFGAUntranslatable(t *testing.T) {
+	nn, untranslatable, errs := ImportOpenFGA([]byte(`model
+  schema 1.1
+
+type user
+
+type service-account
+
+type document
+  relations
+    define public: [user:*]
+    define viewer: [user, user:*, service-account with non_expired]
+
+condition non_expired(expires_at: timestamp, now: timestamp) {
+  now < expires_at
+}
+`))
+	require.Empty(t, errs)
+	assert.Equal(t, []Untranslatable{
+		{Namespace: "service_account", Message: `the type "service-account" was renamed to "service_account"`},
+		{Namespace: "document", Relation: "public", Message: "the wildcard user:* was dropped, relation tuples can not grant access to all subjects of a type"},
+		{Namespace: "document", Relation: "public", Message: "the relation was dropped, because none of its directly related user types can be translated"},
+		{Namespace: "document", Relation: "viewer", Message: "the wildcard user:* was dropped, relation tuples can not grant access to all subjects of a type"},
+		{Namespace: "document", Relation: "viewer", Message: "the condition non_expired of service-account was dropped, condition expressions are not evaluated"},
+	}, untranslatable)
+	require.Len(t, nn, 3)
+	assert.Equal(t, []string{"viewer"}, relationNames(nn[2]))
+}
+
+func TestImportOpenFGAErrors(t *testing.T) {
+	for _, tc := range []struct{ name, model, err string }{
+		{"schema 1.0", "model\n  schema 1.0\n", "2: only models with schema 1.1 can be translated, got schema 1.0"},
+		{"modules", "module documents\n", "1: modular models are not supported, use the combined model of `fga model write` instead"},
+		{"mixed operators", "type user\n  relations\n    define a: [user]\n    define b: a or a and a\n", `4: "or" and "and" can not be mixed without parentheses`},
+		{"missing operand", "type user\n  relations\n    define a: [user] or\n", `3: expected relation, "[", or "(", got end of definition`},
+		{"unknown relation", "type user\n  relations\n    define a: b\n", `namespace "user" did not declare relation "b"`},
+		{"missing types", `{"type_definitions": [{"type": "user", "relations": {"a": {"this": {}}}}]}`, "type user, relation a: the directly related user types are missing, only models with schema 1.1 can be translated"},
+		{"invalid userset", `{"type_definitions": [{"type": "user", "relations": {"a": {}}}]}`, "type user, relation a: a userset has to be exactly one of this, computedUserset, tupleToUserset, union, intersection, or difference"},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			_, _, errs := ImportOpenFGA([]byte(tc.model))
+			require.NotEmpty(t, errs)
+			assert.Contains(t, errs[0].Error(), tc.err)
+		})
+	}
+}
+
+func TestImportOpenFGATuples(t *testing.T) {
+	subject := func(namespace, object, relation string) *ketoapi.SubjectSet {
+		return &ketoapi.SubjectSet{Namespace: namespace, Object: object, Relation: relation}
+	}
+
+	for name, tuples := range map[string]string{
+		"list": `[
+  {"user": "user:anne", "relation": "owner", "object": "document:readme"},
+  {"user": "group:eng#member", "relation": "viewer", "object": "document:readme"},
+  {"user": "user:*", "relation": "viewer", "object": "document:readme"},
+  {"user": "user:bob", "relation": "viewer", "object": "folder:docs", "condition": {"name": "non_expired"}}
+]`,
+		"tuple keys": `{"tuple_keys": [
+  {"user": "user:anne", "relation": "owner", "object": "document:readme"},
+  {"user": "group:eng#member", "relation": "viewer", "object": "document:readme"},
+  {"user": "user:*", "relation": "viewer", "object": "document:readme"},
+  {"user": "user:bob", "relation": "viewer", "object": "folder:docs", "condition": {"name": "non_expired"}}
+]}`,
+	} {
+		t.Run("format="+name, func(t *testing.T) {
+			translated, untranslatable, err := ImportOpenFGATuples([]byte(openFGADSL), []byte(tuples))
+			require.NoError(t, err)
+			assert.Equal(t, []*ketoapi.RelationTuple{
+				{Namespace: "document", Object: "readme", Relation: "owner", SubjectSet: subject("user", "anne", "")},
+				{Namespace: "document", Object: "readme", Relation: "viewer_direct", SubjectSet: subject("group", "eng", "member")},
+				{Namespace: "folder", Object: "docs", Relation: "viewer", SubjectSet: subject("user", "bob", "")},
+			}, translated)
+			assert.Equal(t, []Untranslatable{
+				{Namespace: "document", Relation: "viewer", Message: "the tuple document:readme#viewer@user:* was dropped, wildcards are not supported"},
+				{Namespace: "folder", Relation: "viewer", Message: "the condition of the tuple folder:docs#viewer@user:bob was dropped"},
+			}, untranslatable)
+		})
+	}
+
+	t.Run("case=computed relation", func(t *testing.T) {
+		_, _, err := ImportOpenFGATuples([]byte(openFGADSL), []byte(`[{"user": "user:anne", "relation": "can_share", "object": "document:readme"}]`))
+		assert.EqualError(t, err, "tuple document:readme#can_share@user:anne: the relation can not be written directly")
+	})
+}
@@ -11,8 +11,11 @@ type ParseError struct {
 	item item
 	p    *parser
 }
-type sourcePosition struct {
-	line, col int
+
+// SourcePosition is a position in the input. Lines and columns start at 1,
+// columns count characters.
+type SourcePosition struct {
+	Line, Column int
 }
 
 func max(a, b int) int {
@@ -22,68 +25,109 @@ func max(a, b int) int {
 	return b
 }
 
+// Message returns the error message without the source position.
+func (e *ParseError) Message() string {
+	return e.msg
+}
+
+// File returns the name of the file that the error occurred in, if any.
+func (e *ParseError) File() string {
+	return e.p.file
+}
+
+// Start returns the position of the first character of the erroneous token.
+func (e *ParseError) Start() SourcePosition {
+	return e.toSrcPos(e.item.Start)
+}
+
+// End returns the position after the last character of the erroneous token.
+func (e *ParseError) End() SourcePosition {
+	return e.toSrcPos(e.item.End)
+}
+
 func (e *ParseError) Error() string {
 	var s strings.Builder
-	start := e.toSrcPos(e.item.Start)
-	end := e.toSrcPos(e.item.End)
+	start, end := e.Start(), e.End()
 	rows := e.rows()
-	startLineIdx := max(start.line-2, 0)
-	errorLineIdx := max(start.line-1, 0)
 
-	s.WriteString(fmt.Sprintf("error from %d:%d to %d:%d: %s\n\n",
-		start.line, start.col,
-		end.line, end.col,
+	if e.p.file != "" {
+		s.WriteString(e.p.file + ":")
+	}
+	s.WriteString(fmt.Sprintf("%d:%d-%d:%d: error: %s\n\n",
+		start.Line, start.Column,
+		end.Line, end.Column,
 		e.msg))
 
-	if len(rows) < start.line {
+	if len(rows) < start.Line {
 		s.WriteString("meta error: could not find source position in input\n")
 		return s.String()
 	}
 
-	for line := startLineIdx; line <= errorLineIdx; line++ {
-		s.WriteString(fmt.Sprintf("%4d | %s\n", line, rows[line]))
+	// Print the line of the error and the one before it, with the erroneous
+	// token underlined.
+	for line := max(start.Line-1, 1); line <= start.Line; line++ {
+		s.WriteString(fmt.Sprintf("%4d | %s\n", line, rows[line-1]))
 	}
-	s.WriteString("       ")
-	for i, r := range rows[errorLineIdx] {
+	var marker strings.Builder
+	last := end.Column - 1
+	if end.Line > start.Line {
+		last = len([]rune(rows[start.Line-1]))
+	}
+	column := 0
+	for _, r := range rows[start.Line-1] {
+		column++
 		switch {
-		case start.col == i:
-			s.WriteRune('^')
-		case start.col <= i && i <= end.col-1:
-			s.WriteRune('~')
+		case column == start.Column:
+			marker.WriteRune('^')
+		case start.Column < column && column <= last:
+			marker.WriteRune('~')
 		case unicode.IsSpace(r):
-			s.WriteRune(r)
+			marker.WriteRune(r)
 		default:
-			s.WriteRune(' ')
+			marker.WriteRune(' ')
 		}
 	}
+	if column < start.Column {
+		// The error is at the end of the line, e.g. at the end of the input.
+		marker.WriteString(strings.Repeat(" ", start.Column-column-1) + "^")
+	}
+	s.WriteString("       " + strings.TrimRightFunc(marker.String(), unicode.IsSpace))
 	s.WriteRune('\n')
 
-	if errorLineIdx+1 < len(rows) {
-		s.WriteString(fmt.Sprintf("%4d | %s\n", errorLineIdx, rows[errorLineIdx+1]))
+	if start.Line < len(rows) {
+		s.WriteString(fmt.Sprintf("%4d | %s\n", start.Line+1, rows[start.Line]))
 		s.WriteRune('\n')
 	}
 
 	return s.String()
 }
 
-// toSrcPos converts the given position in the input to a line and column
+// toSrcPos converts the given byte offset in the input to a line and column
 // number.
-func (e *ParseError) toSrcPos(pos int) (srcPos sourcePosition) {
-	srcPos.line = 1
-	for _, c := range e.p.lexer.input {
-		srcPos.col++
-		pos--
-		if pos == 0 {
-			return
+func (e *ParseError) toSrcPos(pos int) SourcePosition {
+	srcPos := SourcePosition{Line: 1, Column: 1}
+	for i, c := range e.p.lexer.input {
+		if i >= pos {
+			break
 		}
 		if c == '\n' {
-			srcPos.line++
-			srcPos.col = 0
+			srcPos.Line++
+			srcPos.Column = 1
+		} else {
+			srcPos.Column++
 		}
 	}
-	return sourcePosition{0, 0}
+	return srcPos
 }
 
 func (e *ParseError) rows() []string {
 	return strings.Split(e.p.lexer.input, "\n")
 }
+
+// got describes the unexpected token for an error message.
+func got(i item) string {
+	if i.Typ == itemEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", i.Val)
+}
@@ -18,14 +18,29 @@ type (
 		fatal      bool        // parser encountered a fatal error
 		lookahead  *item       // lookahead token
 		checks     []typeCheck // checks to perform on the namespace
+		classes    []item      // name tokens of the parsed namespaces
+		imports    []oplImport // file imports of the input
+		file       string      // name of the parsed file, if any
 	}
 )
 
+// Parse parses a single Ory Permission Language input. Imports of other files
+// can not be resolved and are reported as errors, use ParseFiles instead.
 func Parse(input string) ([]namespace, []error) {
+	p := parseSingle(input)
+	return p.namespaces, p.errors
+}
+
+func parseSingle(input string) *parser {
 	p := &parser{
 		lexer: Lex("input", input),
 	}
-	return p.parse()
+	p.parseInput()
+	for _, imp := range p.imports {
+		p.addErr(imp.path, "could not resolve import %q, imports are only supported when parsing files", imp.path.Val)
+	}
+	p.typeCheck()
+	return p
 }
 
 func (p *parser) next() (item item) {
@@ -49,7 +64,9 @@ func (p *parser) peek() item {
 	return *p.lookahead
 }
 
-func (p *parser) parse() ([]namespace, []error) {
+// parseInput parses all namespaces and imports of the input. The type checks
+// are run separately, because they might need namespaces from other files.
+func (p *parser) parseInput() {
 loop:
 	for !p.fatal {
 		switch item := p.next(); item.Typ {
@@ -59,12 +76,12 @@ loop:
 			p.addFatal(item, "fatal: %s", item.Val)
 		case itemKeywordClass:
 			p.parseClass()
+		case itemIdentifier:
+			if item.Val == "import" {
+				p.parseImport()
+			}
 		}
 	}
-
-	p.typeCheck()
-
-	return p.namespaces, p.errors
 }
 
 func (p *parser) addFatal(item item, format string, a ...interface{}) {
@@ -96,7 +113,7 @@ func optional(tokens ...string) matcher {
 			for _, token := range tokens[1:] {
 				i := p.next()
 				if i.Val != token {
-					p.addFatal(i, "expected %q, got %q", token, i.Val)
+					p.addFatal(i, "expected %q, got %s", token, got(i))
 					return false
 				}
 			}
@@ -122,13 +139,13 @@ func (p *parser) match(tokens ...interface{}) (matched bool) {
 		case string:
 			i := p.next()
 			if i.Val != token {
-				p.addFatal(i, "expected %q, got %q", token, i.Val)
+				p.addFatal(i, "expected %q, got %s", token, got(i))
 				return false
 			}
 		case *string:
 			i := p.next()
 			if i.Typ != itemIdentifier && i.Typ != itemStringLiteral {
-				p.addFatal(i, "expected identifier, got %s", i.Typ)
+				p.addFatal(i, "expected identifier, got %s", got(i))
 				return false
 			}
 			*token = i.Val
@@ -165,6 +182,7 @@ func (p *parser) matchIf(predicate itemPredicate, tokens ...interface{}) (matche
 // parseClass parses a class. The "class" token was already consumed.
 func (p *parser) parseClass() {
 	var name string
+	p.classes = append(p.classes, p.peek())
 	p.match(&name, "implements", "Namespace", "{")
 	p.namespace = namespace{Name: name}
 
@@ -178,7 +196,7 @@ func (p *parser) parseClass() {
 		case item.Val == "permits":
 			p.parsePermits()
 		default:
-			p.addFatal(item, "expected 'permits' or 'related', got %q", item.Val)
+			p.addFatal(item, "expected 'permits' or 'related', got %s%s", got(item), didYouMean(item.Val, []string{"permits", "related"}))
 			return
 		}
 	}
@@ -194,24 +212,35 @@ func (p *parser) parseRelated() {
 			relation := item.Val
 			var types []ast.RelationType
 			p.match(":")
+			var condition *ast.Condition
 			switch item := p.next(); item.Typ {
 			case itemIdentifier:
-				if item.Val == "SubjectSet" {
+				switch item.Val {
+				case "SubjectSet":
 					types = append(types, p.matchSubjectSet())
-				} else {
+				case "Conditional":
+					types, condition = p.parseConditional()
+				default:
 					types = append(types, ast.RelationType{Namespace: item.Val})
 					p.addCheck(checkNamespaceExists(item))
 				}
 			case itemParenLeft:
-				types = append(types, p.parseTypeUnion()...)
+				types = append(types, p.parseTypeUnion(itemParenRight)...)
+			}
+			// A relation that is not declared as an array can have at most
+			// one subject per object.
+			maxSubjects := 1
+			if p.matchIf(is(itemBracketLeft), "[", "]") {
+				maxSubjects = 0
 			}
-			p.match("[", "]")
 			p.namespace.Relations = append(p.namespace.Relations, ast.Relation{
-				Name:  relation,
-				Types: types,
+				Name:        relation,
+				Types:       types,
+				Condition:   condition,
+				MaxSubjects: maxSubjects,
 			})
 		default:
-			p.addFatal(item, "expected identifier or '}', got %q", item.Val)
+			p.addFatal(item, "expected identifier or '}', got %s", got(item))
 			return
 		}
 	}
@@ -224,7 +253,9 @@ func (p *parser) matchSubjectSet() ast.RelationType {
 	return ast.RelationType{Namespace: namespace.Val, Relation: relation.Val}
 }
 
-func (p *parser) parseTypeUnion() (types []ast.RelationType) {
+// parseTypeUnion parses types separated by '|' up to and including the end
+// token.
+func (p *parser) parseTypeUnion(end itemType) (types []ast.RelationType) {
 	for !p.fatal {
 		var identifier item
 		p.match(&identifier)
@@ -235,16 +266,89 @@ func (p *parser) parseTypeUnion() (types []ast.RelationType) {
 			p.addCheck(checkNamespaceExists(identifier))
 		}
 		switch item := p.next(); item.Typ {
-		case itemParenRight:
+		case end:
 			return
 		case itemTypeUnion:
 		default:
-			p.addFatal(item, "expected '|', got %q", item.Val)
+			p.addFatal(item, "expected '|', got %s", got(item))
 		}
 	}
 	return
 }
 
+// parseConditional parses the types and condition parameters of a conditional
+// relation, e.g. `Conditional<User | Group, { expires_at: Date }>`. The
+// "Conditional" token was already consumed.
+func (p *parser) parseConditional() (types []ast.RelationType, condition *ast.Condition) {
+	if !p.match("<") {
+		return nil, nil
+	}
+	if p.matchIf(is(itemParenLeft), "(") {
+		types = p.parseTypeUnion(itemParenRight)
+		p.match(",")
+	} else {
+		types = p.parseTypeUnion(itemOperatorComma)
+	}
+	condition = p.parseConditionParameters()
+	p.match(">")
+	return types, condition
+}
+
+var conditionParameterTypes = map[string]ast.ConditionParameterType{
+	"string":  ast.ConditionParameterString,
+	"number":  ast.ConditionParameterNumber,
+	"boolean": ast.ConditionParameterBoolean,
+	"Date":    ast.ConditionParameterTimestamp,
+}
+
+// parseConditionParameters parses an object type of condition parameters,
+// e.g. `{ ip: string, expires_at: Date }`.
+func (p *parser) parseConditionParameters() *ast.Condition {
+	if !p.match("{") {
+		return nil
+	}
+	condition := &ast.Condition{Parameters: []ast.ConditionParameter{}}
+	for !p.fatal {
+		switch name := p.next(); name.Typ {
+		case itemBraceRight:
+			return condition
+		case itemIdentifier:
+			var typ item
+			if !p.match(":", &typ) {
+				return nil
+			}
+			list := p.matchIf(is(itemBracketLeft), "[", "]")
+
+			paramType, ok := conditionParameterTypes[typ.Val]
+			if !ok {
+				p.addErr(typ, "unknown condition parameter type %q, expected one of string, number, boolean, or Date", typ.Val)
+			}
+			for _, param := range condition.Parameters {
+				if param.Name == name.Val {
+					p.addErr(name, "condition parameter %q was declared more than once", name.Val)
+				}
+			}
+			condition.Parameters = append(condition.Parameters, ast.ConditionParameter{
+				Name: name.Val,
+				Type: paramType,
+				List: list,
+			})
+
+			switch next := p.peek(); next.Typ {
+			case itemOperatorComma:
+				p.next()
+			case itemBraceRight:
+			default:
+				p.addFatal(next, "expected ',' or '}', got %s", got(next))
+			}
+		default:
+			p.addFatal(name, "expected identifier or '}', got %s", got(name))
+			return nil
+		}
+	}
+	return nil
+}
+
 func (p *parser) parsePermits() {
 	p.match("=", "{")
 	for !p.fatal {
@@ -271,7 +375,7 @@ func (p *parser) parsePermits() {
 				})
 
 		default:
-			p.addFatal(item, "expected identifier or '}', got %q", item.Val)
+			p.addFatal(item, "expected identifier or '}', got %s", got(item))
 			return
 		}
 	}
@@ -284,7 +388,20 @@ func (p *parser) parsePermissionExpressions(finalToken itemType, depth int) *ast
 			expressionNestingMaxDepth)
 		return nil
 	}
-	var root *ast.SubjectSetRewrite
+	var (
+		root *ast.SubjectSetRewrite
+		// and is the intersection that is currently parsed as an operand of
+		// the union in root, because "&&" binds stronger than "||".
+		and     *ast.SubjectSetRewrite
+		afterOr bool
+	)
+	add := func(child ast.Child) {
+		if and != nil {
+			and.Children = append(and.Children, child)
+		} else {
+			root = addChild(root, child)
+		}
+	}
 
 	// We only expect an expression in the beginning and after a binary
 	// operator.
@@ -302,7 +419,7 @@ func (p *parser) parsePermissionExpressions(finalToken itemType, depth int) *ast
 			if child == nil {
 				return nil
 			}
-			root = addChild(root, child)
+			add(child)
 			expectExpression = false
 
 		case item.Typ == finalToken:
@@ -315,23 +432,46 @@ func (p *parser) parsePermissionExpressions(finalToken itemType, depth int) *ast
 			return root
 
 		case item.Typ == itemOperatorAnd, item.Typ == itemOperatorOr:
+			if expectExpression {
+				p.addFatal(item, "expected expression, got %s", got(item))
+				return nil
+			}
 			p.next() // consume operator
-			newRoot := &ast.SubjectSetRewrite{
-				Operation: setOperation(item.Typ),
-				Children:  []ast.Child{root},
+			switch {
+			case item.Typ == itemOperatorAnd && and != nil:
+				// Continue the current intersection.
+			case item.Typ == itemOperatorAnd && afterOr:
+				// Intersect the last operand of the union with the following
+				// expressions.
+				last := len(root.Children) - 1
+				and = &ast.SubjectSetRewrite{
+					Operation: ast.OperatorAnd,
+					Children:  []ast.Child{root.Children[last]},
+				}
+				root.Children[last] = and
+			default:
+				and = nil
+				root = &ast.SubjectSetRewrite{
+					Operation: setOperation(item.Typ),
+					Children:  []ast.Child{root},
+				}
 			}
-			root = newRoot
+			afterOr = item.Typ == itemOperatorOr
 			expectExpression = true
 
 		// A "not" creates an AST node where the children are either a
 		// single expression, or a list of expressions grouped by "()".
 		case item.Typ == itemOperatorNot:
+			if !expectExpression {
+				p.addFatal(item, "did not expect another expression")
+				return nil
+			}
 			p.next() // consume operator
 			child := p.parseNotExpression(depth - 1)
 			if child == nil {
 				return nil
 			}
-			root = addChild(root, child)
+			add(child)
 			expectExpression = false
 
 		default:
@@ -345,8 +485,8 @@ func (p *parser) parsePermissionExpressions(finalToken itemType, depth int) *ast
 			if child == nil {
 				return nil
 			}
-			root = addChild(root, child)
-			expectExpression = true
+			add(child)
+			expectExpression = false
 		}
 	}
 	return nil
@@ -361,10 +501,14 @@ func (p *parser) parseNotExpression(depth int) ast.Child {
 	}
 
 	var child ast.Child
-	if item := p.peek(); item.Typ == itemParenLeft {
+	switch item := p.peek(); item.Typ {
+	case itemParenLeft:
 		p.next() // consume paren
 		child = p.parsePermissionExpressions(itemParenRight, depth-1)
-	} else {
+	case itemOperatorNot:
+		p.next() // consume operator
+		child = p.parseNotExpression(depth - 1)
+	default:
 		child = p.parsePermissionExpression()
 	}
 	if child == nil {
@@ -404,7 +548,7 @@ func (p *parser) parsePermissionExpression() (child ast.Child) {
 	case "includes":
 		child = p.parseComputedSubjectSet(name)
 	default:
-		p.addFatal(item, "expected 'traverse' or 'includes', got %q", item.Val)
+		p.addFatal(item, "expected 'traverse' or 'includes', got %s%s", got(item), didYouMean(item.Val, []string{"traverse", "includes"}))
 	}
 
 	return
@@ -425,7 +569,12 @@ func (p *parser) parseTupleToSubjectSet(relation item) (rewrite ast.Child) {
 	default:
 		return nil
 	}
-	p.match("=>", arg.Val, ".", &verb)
+	p.match("=>")
+	if item := p.peek(); item.Typ == itemOperatorNot {
+		p.addFatal(item, "negation inside of a traversal is not supported, negate the traversal instead: !this.related.%s.traverse(...)", relation.Val)
+		return nil
+	}
+	p.match(arg.Val, ".", &verb)
 
 	switch verb.Val {
 	case "related":
@@ -442,7 +591,7 @@ func (p *parser) parseTupleToSubjectSet(relation item) (rewrite ast.Child) {
 			&p.namespace, relation, subjectSetRel,
 		))
 	default:
-		p.addFatal(verb, "expected 'related' or 'permits', got %q", verb)
+		p.addFatal(verb, "expected 'related' or 'permits', got %s%s", got(verb), didYouMean(verb.Val, []string{"related", "permits"}))
 		return nil
 	}
 	p.addCheck(checkCurrentNamespaceHasRelation(&p.namespace, relation))
@@ -468,9 +617,9 @@ func simplifyExpression(root *ast.SubjectSetRewrite) *ast.SubjectSetRewrite {
 	}
 	var newChildren []ast.Child
 	for _, child := range root.Children {
+		child = simplifyChild(child)
 		if ch, ok := child.(*ast.SubjectSetRewrite); ok && ch.Operation == root.Operation {
 			// merge child and root
-			simplifyExpression(ch)
 			newChildren = append(newChildren, ch.Children...)
 		} else {
 			// can't merge, just copy
@@ -481,3 +630,20 @@ func simplifyExpression(root *ast.SubjectSetRewrite) *ast.SubjectSetRewrite {
 
 	return root
 }
+
+// simplifyChild simplifies nested expressions. A rewrite with a single child is
+// replaced by that child, so that e.g. an intersection directly contains the
+// traversals it intersects.
+func simplifyChild(child ast.Child) ast.Child {
+	switch c := child.(type) {
+	case *ast.SubjectSetRewrite:
+		c = simplifyExpression(c)
+		if len(c.Children) == 1 {
+			return c.Children[0]
+		}
+		return c
+	case *ast.InvertResult:
+		c.Child = simplifyChild(c.Child)
+	}
+	return child
+}
@@ -1,16 +1,70 @@
 package schema
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/ory/x/snapshotx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ory/keto/internal/namespace/ast"
 )
 
 var parserErrorTestCases = []struct{ name, input string }{
 	{"lexer error", "/* unclosed comment"},
+	{"unknown condition parameter type", `
+  class User implements Namespace {}
+  class File implements Namespace {
+	related: {
+	  viewers: Conditional<User, { ip: Address }>[]
+	}
+  }`},
+	{"duplicate condition parameter", `
+  class User implements Namespace {}
+  class File implements Namespace {
+	related: {
+	  viewers: Conditional<User, { ip: string, ip: number }>[]
+	}
+  }`},
+	{"negation inside of traversal", `
+  class Folder implements Namespace {
+	related: {
+	  viewers: Folder[]
+	}
+  }
+  class File implements Namespace {
+	related: {
+	  parents: Folder[]
+	}
+	permits = {
+	  view: (ctx: Context) => this.related.parents.traverse(p => !p.related.viewers.includes(ctx.subject)),
+	}
+  }`},
+	{"missing operator before negation", `
+  class File implements Namespace {
+	related: {
+	  viewers: File[]
+	}
+	permits = {
+	  view: (ctx: Context) => this.related.viewers.includes(ctx.subject) !this.related.viewers.includes(ctx.subject),
+	}
+  }`},
+	{"missing expression before operator", `
+  class File implements Namespace {
+	related: {
+	  viewers: File[]
+	}
+	permits = {
+	  view: (ctx: Context) => this.related.viewers.includes(ctx.subject) || && this.related.viewers.includes(ctx.subject),
+	}
+  }`},
+	{"unknown conditional subject type", `
+  class File implements Namespace {
+	related: {
+	  viewers: Conditional<User, { ip: string }>[]
+	}
+  }`},
 }
 
 var parserTestCases = []struct {
@@ -69,6 +123,119 @@ var parserTestCases = []struct {
 		this.related.siblings.traverse(s => s.permits.edit(ctx)),
 	}
   }
+`},
+	{"negation", `
+  class User implements Namespace {}
+
+  class Folder implements Namespace {
+	related: {
+	  blocked: User[]
+	  viewers: User[]
+	}
+
+	permits = {
+	  view: (ctx: Context): boolean => !this.related.blocked.includes(ctx.subject),
+	}
+  }
+
+  class File implements Namespace {
+	related: {
+	  parents: Folder[]
+	  viewers: User[]
+	}
+
+	permits = {
+	  view: (ctx: Context): boolean =>
+		this.related.viewers.includes(ctx.subject) &&
+		!this.related.parents.traverse((p) => p.related.blocked.includes(ctx.subject)),
+	  notInherited: (ctx: Context): boolean =>
+		!(this.related.parents.traverse(p => p.permits.view(ctx)) || !!this.related.viewers.includes(ctx.subject)),
+	}
+  }
+`},
+	{"intersections", `
+  class User implements Namespace {}
+
+  class Label implements Namespace {
+	related: {
+	  members: User[]
+	}
+  }
+
+  class Folder implements Namespace {
+	related: {
+	  viewers: User[]
+	}
+
+	permits = {
+	  view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+	}
+  }
+
+  class File implements Namespace {
+	related: {
+	  parents: Folder[]
+	  labels: Label[]
+	  owners: User[]
+	}
+
+	permits = {
+	  view: (ctx: Context): boolean =>
+		this.related.parents.traverse(p => p.permits.view(ctx)) &&
+		this.related.labels.traverse(l => l.related.members.includes(ctx.subject)),
+	  edit: (ctx: Context): boolean =>
+		this.related.owners.includes(ctx.subject) ||
+		this.related.parents.traverse(p => p.permits.view(ctx)) &&
+		this.related.labels.traverse(l => l.related.members.includes(ctx.subject)) ||
+		this.related.parents.traverse(p => p.related.viewers.includes(ctx.subject)),
+	}
+  }
+`},
+	{"conditions", `
+  class User implements Namespace {}
+
+  class Group implements Namespace {
+	related: {
+	  members: User[]
+	}
+  }
+
+  class File implements Namespace {
+	related: {
+	  owners: Conditional<User, { expires_at: Date }>[]
+	  viewers: Conditional<User | SubjectSet<Group, "members">, {
+		ip: string,
+		allowed_networks: string[],
+		max_views: number,
+		internal: boolean,
+	  }>[]
+	  editors: Conditional<(User | Group), {}>[]
+	}
+
+	permits = {
+	  view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+	}
+  }
+`},
+	{"cardinality", `
+  class User implements Namespace {}
+
+  class Folder implements Namespace {}
+
+  class File implements Namespace {
+	related: {
+	  owner: User
+	  parent: (Folder)
+	  expiring_owner: Conditional<User, { expires_at: Date }>
+	  viewers: User[]
+	}
+
+	permits = {
+	  view: (ctx: Context): boolean =>
+		this.related.owner.includes(ctx.subject) ||
+		this.related.viewers.includes(ctx.subject),
+	}
+  }
 `},
 }
 
@@ -104,6 +271,99 @@ func TestParser(t *testing.T) {
 	})
 }
 
+func TestParseErrorDiagnostics(t *testing.T) {
+	const model = `class User implements Namespace {}
+
+class Folder implements Namespace {
+  related: {
+    viewers: User[]
+  }
+}
+
+class File implements Namespace {
+  related: {
+    parents: (Folder | File)[]
+    owners: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => %s,
+  }
+}`
+
+	for _, tc := range []struct {
+		name, input, expression, msg string
+		start, end                   SourcePosition
+	}{
+		{
+			name:       "misspelled relation",
+			expression: "this.related.owner.includes(ctx.subject)",
+			msg:        `namespace "File" did not declare relation "owner", did you mean "owners"?`,
+			start:      SourcePosition{16, 51}, end: SourcePosition{16, 56},
+		},
+		{
+			name:       "unrelated relation",
+			expression: "this.related.editors.includes(ctx.subject)",
+			msg:        `namespace "File" did not declare relation "editors"`,
+			start:      SourcePosition{16, 51}, end: SourcePosition{16, 58},
+		},
+		{
+			name:       "traversal with a type that misses the relation",
+			expression: "this.related.parents.traverse(p => p.related.viewers.includes(ctx.subject))",
+			msg:        `traversal of "parents" requires relation "viewers" in every subject type, but namespace "File" does not declare it (type of "parents": (Folder | File)[])`,
+			start:      SourcePosition{16, 51}, end: SourcePosition{16, 58},
+		},
+		{
+			name:       "misspelled method",
+			expression: "this.related.owners.include(ctx.subject)",
+			msg:        `expected 'traverse' or 'includes', got "include", did you mean "includes"?`,
+			start:      SourcePosition{16, 58}, end: SourcePosition{16, 65},
+		},
+		{
+			name:  "end of input",
+			input: "class File implements Namespace {\n  permits = {\n    view: (ctx: Context): boolean => this.",
+			msg:   `expected "related", got end of input`,
+			start: SourcePosition{3, 43}, end: SourcePosition{3, 43},
+		},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			input := tc.input
+			if input == "" {
+				input = fmt.Sprintf(model, tc.expression)
+			}
+			_, errs := Parse(input)
+			require.Len(t, errs, 1, "%v", errs)
+			var perr *ParseError
+			require.ErrorAs(t, errs[0], &perr)
+			assert.Equal(t, tc.msg, perr.Message())
+			assert.Equal(t, tc.start, perr.Start())
+			assert.Equal(t, tc.end, perr.End())
+		})
+	}
+
+	t.Run("case=error string", func(t *testing.T) {
+		_, errs := Parse("class User implements Namespace {}\nclass File implements Namespace {\n  related: {\n    owners: Usr[]\n  }\n}")
+		require.Len(t, errs, 1)
+		assert.Equal(t, `4:13-4:16: error: namespace "Usr" was not declared, did you mean "User"?
+
+   3 |   related: {
+   4 |     owners: Usr[]
+                   ^~~
+   5 |   }
+
+`, errs[0].Error())
+	})
+}
+
+func TestDidYouMean(t *testing.T) {
+	candidates := []string{"viewers", "owners", "parents"}
+	assert.Equal(t, `, did you mean "viewers"?`, didYouMean("viewer", candidates))
+	assert.Equal(t, `, did you mean "owners"?`, didYouMean("Owners", candidates))
+	assert.Equal(t, `, did you mean "parents"?`, didYouMean("prents", candidates))
+	assert.Equal(t, "", didYouMean("editors", candidates))
+	assert.Equal(t, "", didYouMean("x", candidates))
+}
+
 func FuzzParser(f *testing.F) {
 	for _, tc := range lexableTestCases {
 		f.Add(tc.input)
@@ -117,6 +377,23 @@ func FuzzParser(f *testing.F) {
 	})
 }
 
+func FuzzParseContents(f *testing.F) {
+	f.Add(`import { User } from "./user"
+
+class Document implements Namespace {
+  related: {
+    viewers: User[]
+  }
+}`, `class User implements Namespace {}`)
+	for _, tc := range parserTestCases {
+		f.Add(tc.input, `import { Document } from "./main"`)
+	}
+
+	f.Fuzz(func(_ *testing.T, main, user string) {
+		ParseContents(map[string]string{"main.ts": main, "user.ts": user})
+	})
+}
+
 func Test_simplify(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -155,6 +432,33 @@ func Test_simplify(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "unwrap rewrites with one child",
+			input: &ast.SubjectSetRewrite{
+				Operation: ast.OperatorAnd,
+				Children: ast.Children{
+					&ast.SubjectSetRewrite{
+						Children: ast.Children{
+							&ast.TupleToSubjectSet{Relation: "parents", ComputedSubjectSetRelation: "view"},
+						},
+					},
+					&ast.InvertResult{
+						Child: &ast.SubjectSetRewrite{
+							Children: ast.Children{
+								&ast.ComputedSubjectSet{Relation: "blocked"},
+							},
+						},
+					},
+				},
+			},
+			expected: &ast.SubjectSetRewrite{
+				Operation: ast.OperatorAnd,
+				Children: ast.Children{
+					&ast.TupleToSubjectSet{Relation: "parents", ComputedSubjectSetRelation: "view"},
+					&ast.InvertResult{Child: &ast.ComputedSubjectSet{Relation: "blocked"}},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
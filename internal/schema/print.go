@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+var conditionParameterTypeNames = func() map[ast.ConditionParameterType]string {
+	names := make(map[ast.ConditionParameterType]string, len(conditionParameterTypes))
+	for name, t := range conditionParameterTypes {
+		names[t] = name
+	}
+	return names
+}()
+
+// Print renders the namespaces as an Ory Permission Language model. Parsing
+// the output results in the same namespaces, but comments, the order of
+// relations and permissions within a namespace, and the choice between
+// equivalent expressions are not kept. The output is formatted canonically.
+func Print(namespaces []*namespace) (string, error) {
+	byName := make(map[string]*namespace, len(namespaces))
+	for _, n := range namespaces {
+		byName[n.Name] = n
+	}
+
+	var b strings.Builder
+	for _, n := range namespaces {
+		var related, permits []ast.Relation
+		for _, r := range n.Relations {
+			if isPermission(&r) {
+				permits = append(permits, r)
+			} else {
+				related = append(related, r)
+			}
+		}
+
+		fmt.Fprintf(&b, "class %s implements Namespace {\n", n.Name)
+		if len(related) > 0 {
+			b.WriteString("related: {\n")
+			for _, r := range related {
+				fmt.Fprintf(&b, "%s: %s\n", r.Name, declaredType(&r))
+			}
+			b.WriteString("}\n")
+		}
+		if len(permits) > 0 {
+			b.WriteString("permits = {\n")
+			for _, r := range permits {
+				fmt.Fprintf(&b, "%s: (ctx: Context): boolean => %s,\n", r.Name, printRewrite(n, byName, r.SubjectSetRewrite, false))
+			}
+			b.WriteString("}\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	formatted, errs := Format(b.String())
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+	return formatted, nil
+}
+
+// declaredType is the inverse of typeString, including the condition
+// parameters.
+func declaredType(r *ast.Relation) string {
+	types := make([]string, len(r.Types))
+	for i, t := range r.Types {
+		types[i] = relationTypeString(t)
+	}
+	s := strings.Join(types, " | ")
+	switch {
+	case r.Condition != nil:
+		params := make([]string, len(r.Condition.Parameters))
+		for i, p := range r.Condition.Parameters {
+			params[i] = p.Name + ": " + conditionParameterTypeNames[p.Type]
+			if p.List {
+				params[i] += "[]"
+			}
+		}
+		obj := "{}"
+		if len(params) > 0 {
+			obj = "{ " + strings.Join(params, ", ") + " }"
+		}
+		s = fmt.Sprintf("Conditional<%s, %s>", s, obj)
+	case len(types) > 1:
+		s = "(" + s + ")"
+	}
+	if r.MaxSubjects == 0 {
+		s += "[]"
+	}
+	return s
+}
+
+func printRewrite(n *namespace, byName map[string]*namespace, rewrite *ast.SubjectSetRewrite, nested bool) string {
+	op := " || "
+	if rewrite.Operation == ast.OperatorAnd {
+		op = " && "
+	}
+	children := make([]string, len(rewrite.Children))
+	for i, c := range rewrite.Children {
+		children[i] = printChild(n, byName, c)
+	}
+	s := strings.Join(children, op)
+	if nested && len(children) > 1 {
+		s = "(" + s + ")"
+	}
+	return s
+}
+
+func printChild(n *namespace, byName map[string]*namespace, child ast.Child) string {
+	switch c := child.(type) {
+	case *ast.SubjectSetRewrite:
+		return printRewrite(n, byName, c, true)
+	case *ast.ComputedSubjectSet:
+		return fmt.Sprintf("this.related.%s.includes(ctx.subject)", c.Relation)
+	case *ast.TupleToSubjectSet:
+		if traversesPermission(n, byName, c) {
+			return fmt.Sprintf("this.related.%s.traverse((s) => s.permits.%s(ctx))", c.Relation, c.ComputedSubjectSetRelation)
+		}
+		return fmt.Sprintf("this.related.%s.traverse((s) => s.related.%s.includes(ctx.subject))", c.Relation, c.ComputedSubjectSetRelation)
+	case *ast.InvertResult:
+		return "!" + printChild(n, byName, c.Child)
+	}
+	panic(fmt.Sprintf("unknown subject-set rewrite child %T", child))
+}
+
+// traversesPermission returns whether the traversal is written with
+// "permits", because the computed relation is a permission of the traversed
+// namespaces. Both ways of writing it result in the same rewrite.
+func traversesPermission(n *namespace, byName map[string]*namespace, t *ast.TupleToSubjectSet) bool {
+	r := findRelation(n, t.Relation)
+	if r == nil {
+		return false
+	}
+	for _, typ := range r.Types {
+		if target, ok := byName[typ.Namespace]; ok {
+			if computed := findRelation(target, t.ComputedSubjectSetRelation); computed != nil {
+				return isPermission(computed)
+			}
+		}
+	}
+	return false
+}
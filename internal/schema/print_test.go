@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrint(t *testing.T) {
+	t.Run("case=canonical output", func(t *testing.T) {
+		nn, errs := Parse(`
+class User implements Namespace {}
+
+class Folder implements Namespace {
+  related: {
+    parents: Folder[]
+    owner: User
+    viewers: Conditional<User | SubjectSet<Folder, "owner">, { expires_at: Date, ips: string[] }>[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      (this.related.parents.traverse((p) => p.permits.view(ctx)) && !this.related.owner.includes(ctx.subject)),
+    list: (ctx: Context): boolean => this.related.parents.traverse((p) => p.related.viewers.includes(ctx.subject)),
+  }
+}`)
+		require.Empty(t, errs)
+
+		actual, err := Print(pointers(nn))
+		require.NoError(t, err)
+		assert.Equal(t, `class User implements Namespace {}
+
+class Folder implements Namespace {
+  related: {
+    parents: Folder[]
+    owner: User
+    viewers: Conditional<User | SubjectSet<Folder, "owner">, { expires_at: Date, ips: string[] }>[]
+  }
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      (this.related.parents.traverse((s) => s.permits.view(ctx)) && !this.related.owner.includes(ctx.subject)),
+    list: (ctx: Context): boolean => this.related.parents.traverse((s) => s.related.viewers.includes(ctx.subject)),
+  }
+}
+`, actual)
+	})
+
+	t.Run("suite=keeps the model", func(t *testing.T) {
+		for _, tc := range parserTestCases {
+			t.Run(tc.name, func(t *testing.T) {
+				expected, errs := Parse(tc.input)
+				require.Empty(t, errs)
+
+				printed, err := Print(pointers(expected))
+				require.NoError(t, err)
+				actual, errs := Parse(printed)
+				require.Empty(t, errs, printed)
+
+				require.Len(t, actual, len(expected))
+				for i := range expected {
+					assert.Equal(t, expected[i].Name, actual[i].Name)
+					assert.ElementsMatch(t, expected[i].Relations, actual[i].Relations, printed)
+				}
+			})
+		}
+	})
+}
+
+func pointers(nn []namespace) []*namespace {
+	res := make([]*namespace, len(nn))
+	for i := range nn {
+		res[i] = &nn[i]
+	}
+	return res
+}
@@ -0,0 +1,616 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+type (
+	zedToken struct {
+		val       string
+		line, col int
+	}
+
+	// zedParser parses the SpiceDB schema language. Only the constructs that
+	// are needed for the translation are kept, the bodies of caveats are
+	// skipped.
+	zedParser struct {
+		tokens []zedToken
+		pos    int
+
+		namespaces     []*namespace
+		current        *namespace
+		untranslatable []Untranslatable
+		errs           []error
+	}
+
+	// zedExpr is a permission expression of the SpiceDB schema language.
+	zedExpr struct {
+		op       string // "+", "&", "-", "->", ".all", or "" for an identifier
+		name     string // the identifier, or the computed relation of an arrow
+		children []*zedExpr
+	}
+)
+
+// ImportSpiceDB translates a SpiceDB schema to namespaces. Unions,
+// intersections, exclusions, and arrows are translated to the equivalent
+// permission expressions. Wildcards, caveats, expiration, nil, and arrows
+// with ".all()" have no equivalent, and are reported as untranslatable.
+func ImportSpiceDB(input string) ([]namespace, []Untranslatable, []error) {
+	tokens, err := lexZed(input)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	p := &zedParser{tokens: tokens}
+	p.parseSchema()
+	if len(p.errs) > 0 {
+		return nil, p.untranslatable, p.errs
+	}
+	_, nn, errs := printChecked(p.namespaces)
+	return nn, p.untranslatable, errs
+}
+
+func lexZed(input string) ([]zedToken, error) {
+	var (
+		tokens    []zedToken
+		line, col = 1, 1
+		runes     = []rune(input)
+	)
+	advance := func(n int) {
+		for _, r := range runes[:n] {
+			if r == '\n' {
+				line, col = line+1, 1
+			} else {
+				col++
+			}
+		}
+		runes = runes[n:]
+	}
+	for len(runes) > 0 {
+		r, rest := runes[0], string(runes)
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexRune(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			advance(len([]rune(rest[:end])))
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest, "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%d:%d: unterminated comment", line, col)
+			}
+			advance(len([]rune(rest[:end+2])))
+		case strings.HasPrefix(rest, "->"):
+			tokens = append(tokens, zedToken{"->", line, col})
+			advance(2)
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			n := 0
+			for n < len(runes) && (runes[n] == '_' || runes[n] == '/' || unicode.IsLetter(runes[n]) || unicode.IsDigit(runes[n])) {
+				n++
+			}
+			tokens = append(tokens, zedToken{string(runes[:n]), line, col})
+			advance(n)
+		default:
+			tokens = append(tokens, zedToken{string(r), line, col})
+			advance(1)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *zedParser) peek() zedToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	if len(p.tokens) == 0 {
+		return zedToken{val: "", line: 1, col: 1}
+	}
+	last := p.tokens[len(p.tokens)-1]
+	return zedToken{line: last.line, col: last.col + len(last.val)}
+}
+
+func (p *zedParser) next() zedToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *zedParser) is(val string) bool {
+	return p.peek().val == val
+}
+
+func (p *zedParser) errorf(t zedToken, format string, a ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf("%d:%d: %s", t.line, t.col, fmt.Sprintf(format, a...)))
+}
+
+func (p *zedParser) expect(val string) bool {
+	if t := p.next(); t.val != val {
+		p.errorf(t, "expected %q, got %s", val, zedGot(t))
+		return false
+	}
+	return true
+}
+
+func (p *zedParser) identifier() (zedToken, bool) {
+	t := p.next()
+	if !isZedIdentifier(t.val) {
+		p.errorf(t, "expected identifier, got %s", zedGot(t))
+		return t, false
+	}
+	return t, true
+}
+
+func (p *zedParser) untranslatablef(relation, format string, a ...interface{}) {
+	n := ""
+	if p.current != nil {
+		n = p.current.Name
+	}
+	p.untranslatable = append(p.untranslatable, Untranslatable{Namespace: n, Relation: relation, Message: fmt.Sprintf(format, a...)})
+}
+
+func (p *zedParser) parseSchema() {
+	for p.pos < len(p.tokens) && len(p.errs) == 0 {
+		switch t := p.next(); t.val {
+		case "definition":
+			p.parseDefinition()
+		case "caveat":
+			p.skipCaveat()
+		case "use":
+			if flag, ok := p.identifier(); ok {
+				p.untranslatable = append(p.untranslatable, Untranslatable{Namespace: "use " + flag.val, Message: "schema flags are not supported"})
+			}
+		default:
+			p.errorf(t, "expected \"definition\" or \"caveat\", got %s", zedGot(t))
+		}
+	}
+}
+
+func (p *zedParser) parseDefinition() {
+	name, ok := p.identifier()
+	if !ok {
+		return
+	}
+	p.current = &namespace{Name: p.namespaceName(name.val)}
+	defer func() { p.current = nil }()
+	if !p.expect("{") {
+		return
+	}
+	for len(p.errs) == 0 {
+		switch t := p.next(); t.val {
+		case "}":
+			p.namespaces = append(p.namespaces, p.current)
+			return
+		case "relation":
+			p.parseRelation()
+		case "permission":
+			p.parsePermission()
+		default:
+			p.errorf(t, "expected \"relation\", \"permission\", or \"}\", got %s", zedGot(t))
+		}
+	}
+}
+
+// namespaceName returns the name of the namespace for the definition. Object
+// definitions can be prefixed, e.g. "org/document", but namespace names can
+// not contain a slash.
+func (p *zedParser) namespaceName(definition string) string {
+	name := zedTypeName(definition)
+	if name != definition {
+		p.untranslatable = append(p.untranslatable, Untranslatable{
+			Namespace: name,
+			Message:   fmt.Sprintf("the prefixed definition %q was renamed to %q", definition, name),
+		})
+	}
+	return name
+}
+
+func (p *zedParser) parseRelation() {
+	name, ok := p.identifier()
+	if !ok || !p.expect(":") {
+		return
+	}
+	r := ast.Relation{Name: name.val}
+	for len(p.errs) == 0 {
+		subject, ok := p.identifier()
+		if !ok {
+			return
+		}
+		t := ast.RelationType{Namespace: zedTypeName(subject.val)}
+		wildcard := false
+		switch {
+		case p.is(":"):
+			p.next()
+			if !p.expect("*") {
+				return
+			}
+			wildcard = true
+			p.untranslatablef(r.Name, "the wildcard %s:* was dropped, relation tuples can not grant access to all subjects of a type", subject.val)
+		case p.is("#"):
+			p.next()
+			rel, ok := p.identifier()
+			if !ok {
+				return
+			}
+			t.Relation = rel.val
+		}
+		// Traits are written as "with caveat", "with expiration", or "with
+		// caveat and expiration".
+		for traits := p.is("with"); traits; traits = p.is("and") {
+			p.next()
+			trait, ok := p.identifier()
+			if !ok {
+				return
+			}
+			if trait.val == "expiration" {
+				p.untranslatablef(r.Name, "the expiration of %s was dropped, relation tuples do not expire", subject.val)
+			} else {
+				p.untranslatablef(r.Name, "the caveat %s of %s was dropped, caveat expressions are not evaluated", trait.val, subject.val)
+			}
+		}
+		if !wildcard {
+			r.Types = append(r.Types, t)
+		}
+		if !p.is("|") {
+			break
+		}
+		p.next()
+	}
+
+	if len(r.Types) == 0 {
+		p.untranslatablef(r.Name, "the relation was dropped, because none of its subject types can be translated")
+		return
+	}
+	p.current.Relations = append(p.current.Relations, r)
+}
+
+func zedTypeName(definition string) string {
+	return strings.ReplaceAll(definition, "/", "_")
+}
+
+func (p *zedParser) parsePermission() {
+	name, ok := p.identifier()
+	if !ok || !p.expect("=") {
+		return
+	}
+	e := p.parseExclusion()
+	if e == nil {
+		return
+	}
+	child, ok := p.translate(name.val, e)
+	if !ok {
+		p.untranslatablef(name.val, "the permission was dropped")
+		return
+	}
+	p.current.Relations = append(p.current.Relations, ast.Relation{Name: name.val, SubjectSetRewrite: child.AsRewrite()})
+}
+
+// The operators of the SpiceDB schema language are left-associative, and
+// exclusion binds the loosest, union the tightest.
+
+func (p *zedParser) parseExclusion() *zedExpr {
+	return p.parseBinary("-", p.parseIntersection)
+}
+
+func (p *zedParser) parseIntersection() *zedExpr {
+	return p.parseBinary("&", p.parseUnion)
+}
+
+func (p *zedParser) parseUnion() *zedExpr {
+	return p.parseBinary("+", p.parseArrow)
+}
+
+func (p *zedParser) parseBinary(op string, operand func() *zedExpr) *zedExpr {
+	left := operand()
+	for left != nil && p.is(op) {
+		p.next()
+		right := operand()
+		if right == nil {
+			return nil
+		}
+		left = &zedExpr{op: op, children: []*zedExpr{left, right}}
+	}
+	return left
+}
+
+func (p *zedParser) parseArrow() *zedExpr {
+	left := p.parsePrimary()
+	for left != nil && (p.is("->") || p.is(".")) {
+		var (
+			op       = "->"
+			computed zedToken
+			ok       bool
+		)
+		if p.next().val == "->" {
+			if computed, ok = p.identifier(); !ok {
+				return nil
+			}
+		} else {
+			// The arrow functions "left.any(computed)" and
+			// "left.all(computed)".
+			fn, ok := p.identifier()
+			if !ok || !p.expect("(") {
+				return nil
+			}
+			if computed, ok = p.identifier(); !ok || !p.expect(")") {
+				return nil
+			}
+			switch fn.val {
+			case "any":
+			case "all":
+				op = ".all"
+			default:
+				p.errorf(fn, "expected \"any\" or \"all\", got %s", zedGot(fn))
+				return nil
+			}
+		}
+		left = &zedExpr{op: op, name: computed.val, children: []*zedExpr{left}}
+	}
+	return left
+}
+
+func (p *zedParser) parsePrimary() *zedExpr {
+	switch t := p.peek(); {
+	case t.val == "(":
+		p.next()
+		e := p.parseExclusion()
+		if e == nil || !p.expect(")") {
+			return nil
+		}
+		return e
+	case isZedIdentifier(t.val):
+		p.next()
+		return &zedExpr{name: t.val}
+	default:
+		p.next()
+		p.errorf(t, "expected identifier or \"(\", got %s", zedGot(t))
+		return nil
+	}
+}
+
+// translate translates the expression of the permission. Untranslatable
+// expressions are reported, and ok is false.
+func (p *zedParser) translate(permission string, e *zedExpr) (ast.Child, bool) {
+	switch e.op {
+	case "":
+		if e.name == "nil" {
+			p.untranslatablef(permission, "nil can not be expressed, a permission has to be granted by some relation")
+			return nil, false
+		}
+		return &ast.ComputedSubjectSet{Relation: e.name}, true
+	case "->", ".all":
+		if e.op == ".all" {
+			p.untranslatablef(permission, "%s.all(%s) can not be expressed, a traversal is granted by any of the subjects", e.children[0].name, e.name)
+			return nil, false
+		}
+		if e.children[0].op != "" || e.children[0].name == "nil" {
+			p.untranslatablef(permission, "only relations can be traversed, not expressions")
+			return nil, false
+		}
+		return &ast.TupleToSubjectSet{Relation: e.children[0].name, ComputedSubjectSetRelation: e.name}, true
+	}
+
+	left, ok := p.translate(permission, e.children[0])
+	if !ok {
+		return nil, false
+	}
+	right, ok := p.translate(permission, e.children[1])
+	if !ok {
+		return nil, false
+	}
+	switch e.op {
+	case "+":
+		return &ast.SubjectSetRewrite{Operation: ast.OperatorOr, Children: ast.Children{left, right}}, true
+	case "&":
+		return &ast.SubjectSetRewrite{Operation: ast.OperatorAnd, Children: ast.Children{left, right}}, true
+	default:
+		return &ast.SubjectSetRewrite{Operation: ast.OperatorAnd, Children: ast.Children{left, &ast.InvertResult{Child: right}}}, true
+	}
+}
+
+// skipCaveat skips the parameters and the body of a caveat, which is a CEL
+// expression.
+func (p *zedParser) skipCaveat() {
+	name, ok := p.identifier()
+	if !ok {
+		return
+	}
+	p.untranslatable = append(p.untranslatable, Untranslatable{Namespace: "caveat " + name.val, Message: "caveats are not supported"})
+	for _, delims := range [][2]string{{"(", ")"}, {"{", "}"}} {
+		if !p.expect(delims[0]) {
+			return
+		}
+		for depth := 1; depth > 0; {
+			switch t := p.next(); t.val {
+			case delims[0]:
+				depth++
+			case delims[1]:
+				depth--
+			case "":
+				p.errorf(t, "unexpected end of input in caveat %s", name.val)
+				return
+			}
+		}
+	}
+}
+
+func isZedIdentifier(s string) bool {
+	return s != "" && (s[0] == '_' || unicode.IsLetter([]rune(s)[0]))
+}
+
+func zedGot(t zedToken) string {
+	if t.val == "" {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// ExportSpiceDB translates the namespaces to a SpiceDB schema. Names are
+// converted to snake case, because SpiceDB only allows lowercase names.
+// Conditions, cardinalities, and negations outside of an intersection have no
+// equivalent, and are reported as untranslatable.
+func ExportSpiceDB(namespaces []namespace) (string, []Untranslatable) {
+	var (
+		b              strings.Builder
+		untranslatable []Untranslatable
+	)
+	report := func(n, r, format string, a ...interface{}) {
+		untranslatable = append(untranslatable, Untranslatable{Namespace: n, Relation: r, Message: fmt.Sprintf(format, a...)})
+	}
+
+	for i, n := range namespaces {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		name := zedName(n.Name)
+		if name != n.Name {
+			report(n.Name, "", "renamed to %q, relation tuples have to use the new name", name)
+		}
+		if !isValidZedName(name) {
+			report(n.Name, "", "%q is not a valid SpiceDB definition name", name)
+		}
+
+		var relations, permissions []string
+		for _, r := range n.Relations {
+			rName := zedName(r.Name)
+			if rName != r.Name {
+				report(n.Name, r.Name, "renamed to %q, relation tuples have to use the new name", rName)
+			}
+			if !isValidZedName(rName) {
+				report(n.Name, r.Name, "%q is not a valid SpiceDB relation name", rName)
+			}
+
+			if r.SubjectSetRewrite != nil {
+				expr, ok := zedRewrite(r.SubjectSetRewrite, false)
+				if !ok {
+					report(n.Name, r.Name, "negations are only supported within intersections, e.g. a && !b, the permission was replaced by nil")
+					expr = "nil"
+				}
+				permissions = append(permissions, fmt.Sprintf("\tpermission %s = %s\n", rName, expr))
+				continue
+			}
+
+			types := make([]string, len(r.Types))
+			for i, t := range r.Types {
+				types[i] = zedName(t.Namespace)
+				if t.Relation != "" {
+					types[i] += "#" + zedName(t.Relation)
+				}
+			}
+			if r.Condition != nil {
+				report(n.Name, r.Name, "the condition was dropped, SpiceDB caveats need an expression")
+			}
+			if r.MaxSubjects > 0 {
+				report(n.Name, r.Name, "the cardinality was dropped, SpiceDB does not limit the number of subjects")
+			}
+			relations = append(relations, fmt.Sprintf("\trelation %s: %s\n", rName, strings.Join(types, " | ")))
+		}
+
+		if len(relations)+len(permissions) == 0 {
+			fmt.Fprintf(&b, "definition %s {}\n", name)
+			continue
+		}
+		fmt.Fprintf(&b, "definition %s {\n", name)
+		b.WriteString(strings.Join(relations, ""))
+		if len(relations) > 0 && len(permissions) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.Join(permissions, ""))
+		b.WriteString("}\n")
+	}
+	return b.String(), untranslatable
+}
+
+func zedRewrite(rewrite *ast.SubjectSetRewrite, nested bool) (string, bool) {
+	var (
+		operands, excluded []string
+		op                 = " + "
+	)
+	if rewrite.Operation == ast.OperatorAnd {
+		op = " & "
+	}
+	for _, c := range rewrite.Children {
+		inverted, isInverted := c.(*ast.InvertResult)
+		if isInverted {
+			if rewrite.Operation != ast.OperatorAnd {
+				return "", false
+			}
+			c = inverted.Child
+		}
+		s, ok := zedChild(c)
+		if !ok {
+			return "", false
+		}
+		if isInverted {
+			excluded = append(excluded, s)
+		} else {
+			operands = append(operands, s)
+		}
+	}
+	if len(operands) == 0 {
+		return "", false
+	}
+
+	s := strings.Join(operands, op)
+	if len(excluded) > 0 {
+		if len(operands) > 1 {
+			s = "(" + s + ")"
+		}
+		s += " - " + strings.Join(excluded, " - ")
+	}
+	if nested && len(operands)+len(excluded) > 1 {
+		s = "(" + s + ")"
+	}
+	return s, true
+}
+
+func zedChild(child ast.Child) (string, bool) {
+	switch c := child.(type) {
+	case *ast.SubjectSetRewrite:
+		return zedRewrite(c, true)
+	case *ast.ComputedSubjectSet:
+		return zedName(c.Relation), true
+	case *ast.TupleToSubjectSet:
+		return zedName(c.Relation) + "->" + zedName(c.ComputedSubjectSetRelation), true
+	}
+	return "", false
+}
+
+// zedName converts the name to snake case, e.g. "DocumentFolder" to
+// "document_folder".
+func zedName(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isValidZedName reports whether the name is allowed by SpiceDB: lowercase
+// letters, digits, and underscores, 3 to 64 characters long, starting with a
+// letter and not ending with an underscore.
+func isValidZedName(name string) bool {
+	if len(name) < 3 || len(name) > 64 || name[0] < 'a' || name[0] > 'z' || name[len(name)-1] == '_' {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,249 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const spiceDBSchema = `definition user {}
+
+definition group {
+	relation member: user | group#member
+}
+
+definition folder {
+	relation parent: folder
+	relation viewer: user | group#member
+
+	permission view = viewer + parent->view
+}
+
+definition document {
+	relation folder: folder
+	relation owner: user
+	relation viewer: user | group#member
+	relation banned: user
+
+	permission edit = owner
+	permission view = (viewer + edit + folder->view) - banned
+	permission share = edit & viewer
+}
+`
+
+func TestImportSpiceDB(t *testing.T) {
+	t.Run("case=translates all supported constructs", func(t *testing.T) {
+		nn, untranslatable, errs := ImportSpiceDB(spiceDBSchema)
+		require.Empty(t, errs)
+		assert.Empty(t, untranslatable)
+
+		opl, err := Print(pointers(nn))
+		require.NoError(t, err)
+		assert.Equal(t, `class user implements Namespace {}
+
+class group implements Namespace {
+  related: {
+    member: (user | SubjectSet<group, "member">)[]
+  }
+}
+
+class folder implements Namespace {
+  related: {
+    parent: folder[]
+    viewer: (user | SubjectSet<group, "member">)[]
+  }
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewer.includes(ctx.subject) ||
+      this.related.parent.traverse((s) => s.permits.view(ctx)),
+  }
+}
+
+class document implements Namespace {
+  related: {
+    folder: folder[]
+    owner: user[]
+    viewer: (user | SubjectSet<group, "member">)[]
+    banned: user[]
+  }
+  permits = {
+    edit: (ctx: Context): boolean => this.related.owner.includes(ctx.subject),
+    view: (ctx: Context): boolean =>
+      (this.related.viewer.includes(ctx.subject) || this.related.edit.includes(ctx.subject) || this.related.folder.traverse((s) => s.permits.view(ctx))) &&
+      !this.related.banned.includes(ctx.subject),
+    share: (ctx: Context): boolean =>
+      this.related.edit.includes(ctx.subject) &&
+      this.related.viewer.includes(ctx.subject),
+  }
+}
+`, opl)
+	})
+
+	t.Run("case=operator precedence", func(t *testing.T) {
+		nn, _, errs := ImportSpiceDB(`definition user {}
+definition doc {
+	relation a: user
+	relation b: user
+	relation c: user
+	permission p = a - b & c + a
+}`)
+		require.Empty(t, errs)
+		expected, errs := Parse(`class user implements Namespace {}
+class doc implements Namespace {
+  related: {
+    a: user[]
+    b: user[]
+    c: user[]
+  }
+  permits = {
+    p: (ctx: Context): boolean =>
+      this.related.a.includes(ctx.subject) &&
+      !(this.related.b.includes(ctx.subject) && (this.related.c.includes(ctx.subject) || this.related.a.includes(ctx.subject))),
+  }
+}`)
+		require.Empty(t, errs)
+		assert.Equal(t, expected, nn)
+	})
+
+	t.Run("case=reports untranslatable constructs", func(t *testing.T) {
+		nn, untranslatable, errs := ImportSpiceDB(`
+use expiration
+
+caveat ip_allowlist(user_ip ipaddress, cidr string) {
+	user_ip.in_cidr(cidr) && {"nested": true}.nested
+}
+
+/** a user */
+definition org/user {}
+
+definition document {
+	// everyone can read public documents
+	relation reader: org/user | org/user:* | org/user with ip_allowlist and expiration
+	relation public: org/user:*
+	relation parent: document
+
+	permission none = nil
+	permission read = reader + parent.all(read)
+	permission view = reader + parent.any(view)
+}`)
+		require.Empty(t, errs)
+		assert.Equal(t, []Untranslatable{
+			{Namespace: "use expiration", Message: "schema flags are not supported"},
+			{Namespace: "caveat ip_allowlist", Message: "caveats are not supported"},
+			{Namespace: "org_user", Message: `the prefixed definition "org/user" was renamed to "org_user"`},
+			{Namespace: "document", Relation: "reader", Message: "the wildcard org/user:* was dropped, relation tuples can not grant access to all subjects of a type"},
+			{Namespace: "document", Relation: "reader", Message: "the caveat ip_allowlist of org/user was dropped, caveat expressions are not evaluated"},
+			{Namespace: "document", Relation: "reader", Message: "the expiration of org/user was dropped, relation tuples do not expire"},
+			{Namespace: "document", Relation: "public", Message: "the wildcard org/user:* was dropped, relation tuples can not grant access to all subjects of a type"},
+			{Namespace: "document", Relation: "public", Message: "the relation was dropped, because none of its subject types can be translated"},
+			{Namespace: "document", Relation: "none", Message: "nil can not be expressed, a permission has to be granted by some relation"},
+			{Namespace: "document", Relation: "none", Message: "the permission was dropped"},
+			{Namespace: "document", Relation: "read", Message: "parent.all(read) can not be expressed, a traversal is granted by any of the subjects"},
+			{Namespace: "document", Relation: "read", Message: "the permission was dropped"},
+		}, untranslatable)
+
+		require.Len(t, nn, 2)
+		assert.Equal(t, "org_user", nn[0].Name)
+		assert.Equal(t, []string{"reader", "parent", "view"}, relationNames(nn[1]))
+	})
+
+	t.Run("case=syntax errors", func(t *testing.T) {
+		for _, tc := range []struct{ input, err string }{
+			{"definition user {", `1:18: expected "relation", "permission", or "}", got end of input`},
+			{"definition user {\n  relation owner user\n}", `2:18: expected ":", got "user"`},
+			{"definition user {\n  permission view = + owner\n}", `2:21: expected identifier or "(", got "+"`},
+			{"definition user {\n  permission view = owner.some(x)\n}", `2:27: expected "any" or "all", got "some"`},
+			{"schema user {}", `1:1: expected "definition" or "caveat", got "schema"`},
+		} {
+			t.Run(tc.input, func(t *testing.T) {
+				_, _, errs := ImportSpiceDB(tc.input)
+				require.Len(t, errs, 1)
+				assert.EqualError(t, errs[0], tc.err)
+			})
+		}
+	})
+
+	t.Run("case=type errors", func(t *testing.T) {
+		_, _, errs := ImportSpiceDB("definition document {\n  relation owner: user\n}")
+		require.NotEmpty(t, errs)
+		assert.Contains(t, errs[0].Error(), `namespace "user" was not declared`)
+	})
+}
+
+func TestExportSpiceDB(t *testing.T) {
+	t.Run("case=translates the model", func(t *testing.T) {
+		nn, errs := Parse(`
+class User implements Namespace {}
+
+class UserGroup implements Namespace {
+  related: {
+    members: (User | SubjectSet<UserGroup, "members">)[]
+  }
+}
+
+class Document implements Namespace {
+  related: {
+    owner: User
+    viewers: (User | SubjectSet<UserGroup, "members">)[]
+    parents: Document[]
+    banned: Conditional<User, { until: Date }>[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      (this.related.viewers.includes(ctx.subject) || this.related.parents.traverse((p) => p.permits.view(ctx))) &&
+      !this.related.banned.includes(ctx.subject),
+    edit: (ctx: Context): boolean => this.related.owner.includes(ctx.subject),
+    notBanned: (ctx: Context): boolean => !this.related.banned.includes(ctx.subject),
+  }
+}`)
+		require.Empty(t, errs)
+
+		schema, untranslatable := ExportSpiceDB(nn)
+		assert.Equal(t, `definition user {}
+
+definition user_group {
+	relation members: user | user_group#members
+}
+
+definition document {
+	relation owner: user
+	relation viewers: user | user_group#members
+	relation parents: document
+	relation banned: user
+
+	permission view = (viewers + parents->view) - banned
+	permission edit = owner
+	permission not_banned = nil
+}
+`, schema)
+		assert.Equal(t, []Untranslatable{
+			{Namespace: "User", Message: `renamed to "user", relation tuples have to use the new name`},
+			{Namespace: "UserGroup", Message: `renamed to "user_group", relation tuples have to use the new name`},
+			{Namespace: "Document", Message: `renamed to "document", relation tuples have to use the new name`},
+			{Namespace: "Document", Relation: "owner", Message: "the cardinality was dropped, SpiceDB does not limit the number of subjects"},
+			{Namespace: "Document", Relation: "banned", Message: "the condition was dropped, SpiceDB caveats need an expression"},
+			{Namespace: "Document", Relation: "notBanned", Message: `renamed to "not_banned", relation tuples have to use the new name`},
+			{Namespace: "Document", Relation: "notBanned", Message: "negations are only supported within intersections, e.g. a && !b, the permission was replaced by nil"},
+		}, untranslatable)
+	})
+
+	t.Run("case=round trip", func(t *testing.T) {
+		nn, untranslatable, errs := ImportSpiceDB(spiceDBSchema)
+		require.Empty(t, errs)
+		require.Empty(t, untranslatable)
+
+		schema, untranslatable := ExportSpiceDB(nn)
+		assert.Empty(t, untranslatable)
+		assert.Equal(t, spiceDBSchema, schema)
+	})
+}
+
+func relationNames(n namespace) []string {
+	names := make([]string, len(n.Relations))
+	for i, r := range n.Relations {
+		names[i] = r.Name
+	}
+	return names
+}
@@ -39,6 +39,11 @@ func (rs relationQuery) find(name string) (*ast.Relation, bool) {
 }
 
 func (p *parser) typeCheck() {
+	if p.fatal {
+		// The namespaces are incomplete, so the checks would report follow-up
+		// errors of the fatal error.
+		return
+	}
 	for _, check := range p.checks {
 		check(p)
 	}
@@ -48,13 +53,30 @@ func (p *parser) addCheck(check typeCheck) {
 	p.checks = append(p.checks, check)
 }
 
+func (ns namespaceQuery) names() []string {
+	names := make([]string, len(ns))
+	for i, n := range ns {
+		names[i] = n.Name
+	}
+	return names
+}
+
+func (rs relationQuery) names() []string {
+	names := make([]string, len(rs))
+	for i, r := range rs {
+		names[i] = r.Name
+	}
+	return names
+}
+
 // checkNamespace checks that the there exists a namespace with the given name.
 func checkNamespaceExists(namespace item) typeCheck {
 	return func(p *parser) {
 		if _, ok := namespaceQuery(p.namespaces).find(namespace.Val); ok {
 			return
 		}
-		p.addErr(namespace, "namespace %q was not declared", namespace.Val)
+		p.addErr(namespace, "namespace %q was not declared%s",
+			namespace.Val, didYouMean(namespace.Val, p.query().names()))
 	}
 }
 
@@ -67,11 +89,12 @@ func checkNamespaceHasRelation(namespace, relation item) typeCheck {
 				return
 			}
 			p.addErr(relation,
-				"namespace %q did not declare relation %q",
-				namespace.Val, relation.Val)
+				"namespace %q did not declare relation %q%s",
+				namespace.Val, relation.Val, didYouMean(relation.Val, relationQuery(n.Relations).names()))
 			return
 		}
-		p.addErr(namespace, "namespace %q was not declared", namespace.Val)
+		p.addErr(namespace, "namespace %q was not declared%s",
+			namespace.Val, didYouMean(namespace.Val, p.query().names()))
 	}
 }
 
@@ -85,43 +108,55 @@ func checkCurrentNamespaceHasRelation(current *namespace, relation item) typeChe
 				return
 			}
 			p.addErr(relation,
-				"namespace %q did not declare relation %q",
-				namespace, relation.Val)
+				"namespace %q did not declare relation %q%s",
+				namespace, relation.Val, didYouMean(relation.Val, relationQuery(n.Relations).names()))
 			return
 		}
 		p.addErr(relation, "namespace %q was not declared", namespace)
 	}
 }
 
+// checkAllRelationsTypesHaveRelation checks that every subject type of the
+// traversed relation declares the relation that the traversal uses.
 func checkAllRelationsTypesHaveRelation(current *namespace, relationType item, relation string) typeCheck {
 	namespace := current.Name
 	return func(p *parser) {
-		recursiveCheckAllRelationsTypesHaveRelation(p, relationType, namespace, relationType.Val, relation, tupleToSubjectSetTypeCheckMaxDepth)
+		r, ok := p.query().findRelation(namespace, relationType.Val)
+		if !ok {
+			// Reported by checkCurrentNamespaceHasRelation.
+			return
+		}
+		recursiveCheckAllRelationsTypesHaveRelation(p, relationType, r, relation, tupleToSubjectSetTypeCheckMaxDepth)
 	}
 }
 
-func recursiveCheckAllRelationsTypesHaveRelation(p *parser, item item, namespace string, relationType string, relation string, depth int) {
+func recursiveCheckAllRelationsTypesHaveRelation(p *parser, item item, r *ast.Relation, relation string, depth int) {
 	if depth < 0 {
 		p.addErr(item, "could not typecheck deeply nested SubjectSet further")
 		return
 	}
-	r, ok := namespaceQuery(p.namespaces).findRelation(namespace, relationType)
-	if !ok {
-		p.addErr(item, "relation %q was not declared in namespace %q",
-			relationType, namespace)
-		return
-	}
 	for _, t := range r.Types {
 		if t.Relation == "" {
-			if _, ok := p.query().findRelation(t.Namespace, relation); !ok {
-				p.addErr(item, "relation %q was not declared in namespace %q",
-					relation, t.Namespace)
+			n, ok := p.query().find(t.Namespace)
+			if !ok {
+				// Reported by checkNamespaceExists.
+				continue
+			}
+			if _, ok := relationQuery(n.Relations).find(relation); !ok {
+				p.addErr(item,
+					"traversal of %q requires relation %q in every subject type, but namespace %q does not declare it (type of %q: %s)%s",
+					item.Val, relation, t.Namespace, r.Name, typeString(r),
+					didYouMean(relation, relationQuery(n.Relations).names()))
 			}
 		} else {
 			// Type is a subject set, we need to recursively check if the type has
 			// the required relation.
-			recursiveCheckAllRelationsTypesHaveRelation(
-				p, item, t.Namespace, t.Relation, relation, depth-1)
+			sr, ok := p.query().findRelation(t.Namespace, t.Relation)
+			if !ok {
+				// Reported by checkNamespaceHasRelation.
+				continue
+			}
+			recursiveCheckAllRelationsTypesHaveRelation(p, item, sr, relation, depth-1)
 		}
 	}
 }
@@ -0,0 +1,502 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+type (
+	// zanzibarConfig is a namespace configuration as described in the
+	// Zanzibar paper, e.g.
+	//
+	//	name: "doc"
+	//	relation { name: "owner" }
+	//	relation {
+	//	  name: "viewer"
+	//	  userset_rewrite {
+	//	    union {
+	//	      child { _this {} }
+	//	      child { computed_userset { relation: "owner" } }
+	//	    }
+	//	  }
+	//	}
+	zanzibarConfig struct {
+		Name      string             `json:"name"`
+		Relations []zanzibarRelation `json:"relation"`
+	}
+
+	zanzibarRelation struct {
+		Name           string           `json:"name"`
+		UsersetRewrite *zanzibarUserset `json:"userset_rewrite"`
+	}
+
+	// zanzibarUserset is a userset rewrite, or a child of a set operation.
+	// Exactly one of the fields has to be set.
+	zanzibarUserset struct {
+		This            *struct{}               `json:"_this"`
+		ComputedUserset *zanzibarObjectRelation `json:"computed_userset"`
+		TupleToUserset  *zanzibarTupleToUserset `json:"tuple_to_userset"`
+		UsersetRewrite  *zanzibarUserset        `json:"userset_rewrite"`
+		Union           *zanzibarSetOperation   `json:"union"`
+		Intersection    *zanzibarSetOperation   `json:"intersection"`
+		Exclusion       *zanzibarSetOperation   `json:"exclusion"`
+	}
+
+	// zanzibarSetOperation combines the children. An exclusion subtracts all
+	// but the first child from the first child, or the subtract userset from
+	// the base userset.
+	zanzibarSetOperation struct {
+		Child    []*zanzibarUserset `json:"child"`
+		Base     *zanzibarUserset   `json:"base"`
+		Subtract *zanzibarUserset   `json:"subtract"`
+	}
+
+	zanzibarObjectRelation struct {
+		Object   string `json:"object"`
+		Relation string `json:"relation"`
+	}
+
+	zanzibarTupleToUserset struct {
+		Tupleset        zanzibarObjectRelation `json:"tupleset"`
+		ComputedUserset zanzibarObjectRelation `json:"computed_userset"`
+	}
+
+	// zanzibarTextParser parses the protocol buffer text format into the
+	// JSON representation of the message.
+	zanzibarTextParser struct {
+		tokens []zanzibarToken
+		pos    int
+	}
+
+	zanzibarToken struct {
+		val       string
+		quoted    bool
+		line, col int
+	}
+)
+
+// zanzibarTupleUsersetObject is the placeholder for the object of the
+// relation tuples found by the tupleset.
+const zanzibarTupleUsersetObject = "$TUPLE_USERSET_OBJECT"
+
+// IsZanzibarConfig returns whether the namespace configuration declares
+// relations in the format of the Zanzibar paper.
+func IsZanzibarConfig(config json.RawMessage) bool {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(config, &keys); err != nil {
+		return false
+	}
+	_, ok := keys["relation"]
+	return ok
+}
+
+// ZanzibarTextToJSON converts a namespace configuration in the protocol
+// buffer text format to its JSON representation.
+func ZanzibarTextToJSON(input []byte) ([]byte, error) {
+	tokens, err := lexZanzibarText(string(input))
+	if err != nil {
+		return nil, err
+	}
+	p := &zanzibarTextParser{tokens: tokens}
+	msg, err := p.parseFields("")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(msg)
+}
+
+// ConvertZanzibar converts the relations of a namespace configuration in the
+// format of the Zanzibar paper, encoded as JSON, to relations with
+// subject-set rewrites. Field names can be written in snake case or, as
+// printed by protojson, in lower camel case.
+//
+// Relation tuples are always considered by checks, so "_this" is dropped
+// from the top-level union of a rewrite. As a consequence, relation tuples
+// of relations without "_this" are considered as well. "_this" can not be
+// translated within intersections and exclusions.
+func ConvertZanzibar(config json.RawMessage) (name string, _ []ast.Relation, _ error) {
+	var generic map[string]interface{}
+	if err := json.Unmarshal(config, &generic); err != nil {
+		return "", nil, err
+	}
+	// Other keys, such as the soft delete configuration, are left alone.
+	c := zanzibarConfig{}
+	if n, ok := generic["name"].(string); ok {
+		c.Name = n
+	}
+	normalized, err := json.Marshal(normalizeZanzibarKeys(generic["relation"]))
+	if err != nil {
+		return "", nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(normalized))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c.Relations); err != nil {
+		return "", nil, fmt.Errorf("could not decode the Zanzibar relations: %w", err)
+	}
+
+	declared := make(map[string]bool, len(c.Relations))
+	for _, r := range c.Relations {
+		if r.Name == "" {
+			return "", nil, fmt.Errorf("a relation is missing its name")
+		}
+		if declared[r.Name] {
+			return "", nil, fmt.Errorf("relation %q is declared more than once", r.Name)
+		}
+		declared[r.Name] = true
+	}
+
+	relations := make([]ast.Relation, len(c.Relations))
+	for i, r := range c.Relations {
+		relations[i].Name = r.Name
+		if r.UsersetRewrite == nil {
+			continue
+		}
+		rewrite, err := convertZanzibarRewrite(r.UsersetRewrite, declared)
+		if err != nil {
+			return "", nil, fmt.Errorf("relation %q: %w", r.Name, err)
+		}
+		relations[i].SubjectSetRewrite = rewrite
+	}
+	return c.Name, relations, nil
+}
+
+// convertZanzibarRewrite converts the top-level userset rewrite, dropping
+// "_this" from it.
+func convertZanzibarRewrite(u *zanzibarUserset, declared map[string]bool) (*ast.SubjectSetRewrite, error) {
+	if err := u.validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case u.This != nil:
+		return nil, nil
+	case u.UsersetRewrite != nil:
+		return convertZanzibarRewrite(u.UsersetRewrite, declared)
+	case u.Union != nil:
+		if len(u.Union.Child) == 0 || u.Union.Base != nil || u.Union.Subtract != nil {
+			return nil, fmt.Errorf("a union needs at least one child, and no base or subtract")
+		}
+		rewrite := &ast.SubjectSetRewrite{Operation: ast.OperatorOr}
+		for _, child := range u.Union.Child {
+			if err := child.validate(); err != nil {
+				return nil, err
+			}
+			if child.This != nil {
+				continue
+			}
+			c, err := convertZanzibarChild(child, declared)
+			if err != nil {
+				return nil, err
+			}
+			rewrite.Children = append(rewrite.Children, c)
+		}
+		if len(rewrite.Children) == 0 {
+			return nil, nil
+		}
+		return rewrite, nil
+	}
+
+	c, err := convertZanzibarChild(u, declared)
+	if err != nil {
+		return nil, err
+	}
+	return c.AsRewrite(), nil
+}
+
+func convertZanzibarChild(u *zanzibarUserset, declared map[string]bool) (ast.Child, error) {
+	if err := u.validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case u.This != nil:
+		return nil, fmt.Errorf(`"_this" can only be translated at the top level of a rewrite, or as a child of its top-level union`)
+
+	case u.ComputedUserset != nil:
+		if o := u.ComputedUserset.Object; o != "" {
+			return nil, fmt.Errorf("the object %q of a computed userset can not be translated", o)
+		}
+		if !declared[u.ComputedUserset.Relation] {
+			return nil, fmt.Errorf("the computed userset references the undeclared relation %q", u.ComputedUserset.Relation)
+		}
+		return &ast.ComputedSubjectSet{Relation: u.ComputedUserset.Relation}, nil
+
+	case u.TupleToUserset != nil:
+		ttu := u.TupleToUserset
+		if o := ttu.Tupleset.Object; o != "" {
+			return nil, fmt.Errorf("the object %q of a tupleset can not be translated", o)
+		}
+		if !declared[ttu.Tupleset.Relation] {
+			return nil, fmt.Errorf("the tupleset references the undeclared relation %q", ttu.Tupleset.Relation)
+		}
+		if o := ttu.ComputedUserset.Object; o != "" && o != zanzibarTupleUsersetObject {
+			return nil, fmt.Errorf("the object of the computed userset of a tuple to userset has to be %s, got %q", zanzibarTupleUsersetObject, o)
+		}
+		if ttu.ComputedUserset.Relation == "" {
+			return nil, fmt.Errorf("the computed userset of a tuple to userset is missing its relation")
+		}
+		return &ast.TupleToSubjectSet{
+			Relation:                   ttu.Tupleset.Relation,
+			ComputedSubjectSetRelation: ttu.ComputedUserset.Relation,
+		}, nil
+
+	case u.UsersetRewrite != nil:
+		return convertZanzibarChild(u.UsersetRewrite, declared)
+
+	case u.Union != nil:
+		return convertZanzibarSetOperation(ast.OperatorOr, u.Union.Child, declared)
+
+	case u.Intersection != nil:
+		if u.Intersection.Base != nil || u.Intersection.Subtract != nil {
+			return nil, fmt.Errorf("only exclusions have a base and subtract")
+		}
+		return convertZanzibarSetOperation(ast.OperatorAnd, u.Intersection.Child, declared)
+
+	default:
+		children := u.Exclusion.Child
+		switch {
+		case u.Exclusion.Base != nil && u.Exclusion.Subtract != nil && len(children) == 0:
+			children = []*zanzibarUserset{u.Exclusion.Base, u.Exclusion.Subtract}
+		case u.Exclusion.Base != nil || u.Exclusion.Subtract != nil || len(children) < 2:
+			return nil, fmt.Errorf("an exclusion needs either a base and subtract, or at least two children")
+		}
+		base, err := convertZanzibarChild(children[0], declared)
+		if err != nil {
+			return nil, err
+		}
+		rewrite := &ast.SubjectSetRewrite{Operation: ast.OperatorAnd, Children: ast.Children{base}}
+		for _, child := range children[1:] {
+			c, err := convertZanzibarChild(child, declared)
+			if err != nil {
+				return nil, err
+			}
+			rewrite.Children = append(rewrite.Children, &ast.InvertResult{Child: c})
+		}
+		return rewrite, nil
+	}
+}
+
+func convertZanzibarSetOperation(op ast.Operator, children []*zanzibarUserset, declared map[string]bool) (ast.Child, error) {
+	if len(children) == 0 {
+		return nil, fmt.Errorf("a set operation needs at least one child")
+	}
+	rewrite := &ast.SubjectSetRewrite{Operation: op}
+	for _, child := range children {
+		c, err := convertZanzibarChild(child, declared)
+		if err != nil {
+			return nil, err
+		}
+		rewrite.Children = append(rewrite.Children, c)
+	}
+	return rewrite, nil
+}
+
+func (u *zanzibarUserset) validate() error {
+	if u == nil {
+		return fmt.Errorf("a userset is missing")
+	}
+	set := 0
+	for _, isSet := range []bool{
+		u.This != nil,
+		u.ComputedUserset != nil,
+		u.TupleToUserset != nil,
+		u.UsersetRewrite != nil,
+		u.Union != nil,
+		u.Intersection != nil,
+		u.Exclusion != nil,
+	} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("a userset has to be exactly one of _this, computed_userset, tuple_to_userset, userset_rewrite, union, intersection, or exclusion")
+	}
+	return nil
+}
+
+// normalizeZanzibarKeys converts the lower camel case keys of protojson to
+// the snake case keys of the protocol buffer definition.
+func normalizeZanzibarKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			normalized[zanzibarFieldName(key)] = normalizeZanzibarKeys(val)
+		}
+		return normalized
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeZanzibarKeys(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func zanzibarFieldName(key string) string {
+	if key == "this" || key == "This" {
+		return "_this"
+	}
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func lexZanzibarText(input string) ([]zanzibarToken, error) {
+	var (
+		tokens    []zanzibarToken
+		line, col = 1, 1
+		runes     = []rune(input)
+	)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := zanzibarToken{line: line, col: col}
+		advance := func(n int) {
+			for ; n > 0; n-- {
+				if runes[i] == '\n' {
+					line, col = line+1, 1
+				} else {
+					col++
+				}
+				i++
+			}
+		}
+
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				advance(1)
+			}
+		case strings.ContainsRune("{}<>:;,[]", r):
+			start.val = string(r)
+			tokens = append(tokens, start)
+			advance(1)
+		case r == '"' || r == '\'':
+			end := i + 1
+			for ; end < len(runes) && runes[end] != r && runes[end] != '\n'; end++ {
+				if runes[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(runes) || runes[end] != r {
+				return nil, fmt.Errorf("%d:%d: unterminated string", line, col)
+			}
+			raw := string(runes[i+1 : end])
+			if r == '\'' {
+				raw = strings.ReplaceAll(raw, `"`, `\"`)
+			}
+			val, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("%d:%d: invalid string: %s", line, col, err)
+			}
+			start.val, start.quoted = val, true
+			tokens = append(tokens, start)
+			advance(end + 1 - i)
+		case r == '_' || r == '$' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (runes[end] == '_' || runes[end] == '$' || runes[end] == '-' || runes[end] == '.' || unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+				end++
+			}
+			start.val = string(runes[i:end])
+			tokens = append(tokens, start)
+			advance(end - i)
+		default:
+			return nil, fmt.Errorf("%d:%d: unexpected %q", line, col, r)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *zanzibarTextParser) peek() *zanzibarToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *zanzibarTextParser) errorf(t *zanzibarToken, format string, a ...interface{}) error {
+	if t == nil {
+		return fmt.Errorf("end of input: %s", fmt.Sprintf(format, a...))
+	}
+	return fmt.Errorf("%d:%d: %s", t.line, t.col, fmt.Sprintf(format, a...))
+}
+
+// parseFields parses the fields of a message until the closing delimiter,
+// or the end of the input for the top-level message.
+func (p *zanzibarTextParser) parseFields(closing string) (map[string]interface{}, error) {
+	msg := make(map[string]interface{})
+	for {
+		t := p.peek()
+		switch {
+		case t == nil && closing == "":
+			return msg, nil
+		case t == nil:
+			return nil, p.errorf(t, "expected %q", closing)
+		case !t.quoted && t.val == closing:
+			p.pos++
+			return msg, nil
+		case t.quoted || strings.ContainsAny(t.val, "{}<>:;,[]"):
+			return nil, p.errorf(t, "expected field name, got %q", t.val)
+		}
+		p.pos++
+		name := t.val
+
+		hasColon := false
+		if next := p.peek(); next != nil && !next.quoted && next.val == ":" {
+			hasColon = true
+			p.pos++
+		}
+
+		var value interface{}
+		switch next := p.peek(); {
+		case next == nil:
+			return nil, p.errorf(next, "expected value of field %q", name)
+		case !next.quoted && (next.val == "{" || next.val == "<"):
+			p.pos++
+			closing := map[string]string{"{": "}", "<": ">"}[next.val]
+			sub, err := p.parseFields(closing)
+			if err != nil {
+				return nil, err
+			}
+			value = sub
+		case !hasColon:
+			return nil, p.errorf(next, "expected \":\" after field %q", name)
+		case !next.quoted && strings.ContainsAny(next.val, "{}<>:;,[]"):
+			return nil, p.errorf(next, "expected value of field %q, got %q", name, next.val)
+		default:
+			p.pos++
+			value = next.val
+		}
+
+		// The relations of the namespace configuration and the children of
+		// set operations are repeated fields.
+		if name == "child" || (name == "relation" && closing == "") {
+			values, _ := msg[name].([]interface{})
+			msg[name] = append(values, value)
+		} else if _, ok := msg[name]; ok {
+			return nil, p.errorf(t, "field %q is set more than once", name)
+		} else {
+			msg[name] = value
+		}
+
+		if next := p.peek(); next != nil && !next.quoted && (next.val == ";" || next.val == ",") {
+			p.pos++
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/namespace/ast"
+)
+
+const zanzibarDocText = `# the example of the Zanzibar paper
+name: "doc"
+
+relation { name: "owner" }
+
+relation {
+  name: "editor"
+  userset_rewrite {
+    union {
+      child { _this {} }
+      child { computed_userset { relation: "owner" } }
+    }
+  }
+}
+
+relation {
+  name: "viewer"
+  userset_rewrite {
+    union {
+      child { _this {} }
+      child { computed_userset { relation: "editor" } }
+      child { tuple_to_userset {
+        tupleset { relation: "parent" }
+        computed_userset {
+          object: $TUPLE_USERSET_OBJECT  # parent folder
+          relation: "viewer"
+        }
+      } }
+    }
+  }
+}
+
+relation { name: "parent" }
+relation { name: "banned" }
+
+relation {
+  name: "commenter"
+  userset_rewrite <
+    exclusion {
+      base: { computed_userset { relation: 'viewer' } }
+      subtract: { computed_userset { relation: "banned" } }
+    }
+  >
+}
+`
+
+func TestConvertZanzibar(t *testing.T) {
+	expected := []ast.Relation{
+		{Name: "owner"},
+		{Name: "editor", SubjectSetRewrite: &ast.SubjectSetRewrite{Children: ast.Children{
+			&ast.ComputedSubjectSet{Relation: "owner"},
+		}}},
+		{Name: "viewer", SubjectSetRewrite: &ast.SubjectSetRewrite{Children: ast.Children{
+			&ast.ComputedSubjectSet{Relation: "editor"},
+			&ast.TupleToSubjectSet{Relation: "parent", ComputedSubjectSetRelation: "viewer"},
+		}}},
+		{Name: "parent"},
+		{Name: "banned"},
+		{Name: "commenter", SubjectSetRewrite: &ast.SubjectSetRewrite{Operation: ast.OperatorAnd, Children: ast.Children{
+			&ast.ComputedSubjectSet{Relation: "viewer"},
+			&ast.InvertResult{Child: &ast.ComputedSubjectSet{Relation: "banned"}},
+		}}},
+	}
+
+	t.Run("format=textproto", func(t *testing.T) {
+		config, err := ZanzibarTextToJSON([]byte(zanzibarDocText))
+		require.NoError(t, err)
+		assert.True(t, IsZanzibarConfig(config))
+
+		name, relations, err := ConvertZanzibar(config)
+		require.NoError(t, err)
+		assert.Equal(t, "doc", name)
+		assert.Equal(t, expected, relations)
+	})
+
+	t.Run("format=protojson", func(t *testing.T) {
+		name, relations, err := ConvertZanzibar(json.RawMessage(`{
+  "name": "doc",
+  "relation": [
+    {"name": "owner"},
+    {"name": "editor", "usersetRewrite": {"union": {"child": [{"this": {}}, {"computedUserset": {"relation": "owner"}}]}}},
+    {"name": "viewer", "usersetRewrite": {"union": {"child": [
+      {"_this": {}},
+      {"computedUserset": {"relation": "editor"}},
+      {"tupleToUserset": {"tupleset": {"relation": "parent"}, "computedUserset": {"object": "$TUPLE_USERSET_OBJECT", "relation": "viewer"}}}
+    ]}}},
+    {"name": "parent"},
+    {"name": "banned"},
+    {"name": "commenter", "usersetRewrite": {"exclusion": {"child": [{"computedUserset": {"relation": "viewer"}}, {"computedUserset": {"relation": "banned"}}]}}}
+  ]
+}`))
+		require.NoError(t, err)
+		assert.Equal(t, "doc", name)
+		assert.Equal(t, expected, relations)
+	})
+
+	t.Run("case=keeps other config keys", func(t *testing.T) {
+		_, relations, err := ConvertZanzibar(json.RawMessage(`{"relation": [{"name": "owner", "userset_rewrite": {"_this": {}}}], "soft_delete": {"enabled": true}}`))
+		require.NoError(t, err)
+		assert.Equal(t, []ast.Relation{{Name: "owner"}}, relations)
+	})
+
+	t.Run("case=errors", func(t *testing.T) {
+		for _, tc := range []struct{ name, config, err string }{
+			{"this in intersection", `{"relation": [{"name": "a", "userset_rewrite": {"intersection": {"child": [{"_this": {}}]}}}]}`, `relation "a": "_this" can only be translated at the top level`},
+			{"undeclared relation", `{"relation": [{"name": "a", "userset_rewrite": {"computed_userset": {"relation": "b"}}}]}`, `relation "a": the computed userset references the undeclared relation "b"`},
+			{"ambiguous userset", `{"relation": [{"name": "a", "userset_rewrite": {"_this": {}, "union": {"child": []}}}]}`, "a userset has to be exactly one of"},
+			{"unknown field", `{"relation": [{"name": "a", "rewrite": {}}]}`, `unknown field "rewrite"`},
+			{"duplicate relation", `{"relation": [{"name": "a"}, {"name": "a"}]}`, `relation "a" is declared more than once`},
+			{"exclusion with one child", `{"relation": [{"name": "a", "userset_rewrite": {"exclusion": {"child": [{"computed_userset": {"relation": "a"}}]}}}]}`, "an exclusion needs either a base and subtract, or at least two children"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				_, _, err := ConvertZanzibar(json.RawMessage(tc.config))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+func TestZanzibarTextToJSON(t *testing.T) {
+	for _, tc := range []struct{ name, input, err string }{
+		{"unterminated string", `name: "doc`, "1:7: unterminated string"},
+		{"unclosed message", "relation {\n  name: \"a\"", `end of input: expected "}"`},
+		{"missing colon", `name "doc"`, `1:6: expected ":" after field "name"`},
+		{"repeated field", "name: \"a\"\nname: \"b\"", `2:1: field "name" is set more than once`},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			_, err := ZanzibarTextToJSON([]byte(tc.input))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+
+	t.Run("case=separators and scalars", func(t *testing.T) {
+		config, err := ZanzibarTextToJSON([]byte(`name: "a\"b"; relation: { name: 'c' }, relation < name: "d" >`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "a\"b", "relation": [{"name": "c"}, {"name": "d"}]}`, string(config))
+	})
+}
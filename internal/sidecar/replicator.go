@@ -0,0 +1,412 @@
+// Package sidecar replicates the relation tuples of a primary cluster into the
+// local database, so that a sidecar can answer checks without a round trip to
+// the primary. The replica loads a snapshot of the primary and then follows
+// its changes through the watch endpoint of the write API.
+package sidecar
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	ReplicatorDependencies interface {
+		config.Provider
+		x.LoggerProvider
+		relationtuple.ManagerProvider
+		relationtuple.MapperProvider
+	}
+	ReplicatorProvider interface {
+		SidecarReplicator() *Replicator
+	}
+	// Replicator keeps the relation tuples of the replicated namespaces in
+	// sync with the primary.
+	Replicator struct {
+		d      ReplicatorDependencies
+		client *http.Client
+		synced chan struct{}
+	}
+
+	// event is a server-sent event of the watch endpoint.
+	event struct {
+		id, typ, data string
+	}
+	// namespaceSet contains the replicated namespaces, and all namespaces
+	// that are known locally.
+	namespaceSet struct {
+		replicated, local map[string]struct{}
+	}
+)
+
+const snapshotBatchSize = 1000
+
+var (
+	// ErrReadOnly is returned for all writes of relation tuples to a sidecar.
+	ErrReadOnly = herodot.ErrForbidden.WithReason("This instance is a read-only sidecar, relation tuples can only be changed on the primary.")
+	// ErrNotSynced is reported by the ready check until the snapshot of the
+	// primary was loaded.
+	ErrNotSynced = errors.New("the snapshot of the primary was not loaded yet")
+)
+
+func NewReplicator(d ReplicatorDependencies) *Replicator {
+	return &Replicator{
+		d:      d,
+		client: http.DefaultClient,
+		synced: make(chan struct{}),
+	}
+}
+
+// Synced returns whether the snapshot of the primary was loaded.
+func (r *Replicator) Synced() bool {
+	select {
+	case <-r.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run loads the snapshot of the primary and then applies its changes until
+// the context is canceled. Failed requests are retried after the retry
+// interval, resuming after the last applied change.
+func (r *Replicator) Run(ctx context.Context) error {
+	var cursor string
+	for {
+		var err error
+		if cursor == "" {
+			cursor, err = r.loadSnapshot(ctx)
+		}
+		if err == nil {
+			cursor, err = r.follow(ctx, cursor)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			// The primary ends event streams regularly.
+			continue
+		}
+		r.d.Logger().WithError(err).Error("could not replicate the relation tuples of the primary, will retry")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.d.Config(ctx).Sidecar().RetryInterval):
+		}
+	}
+}
+
+// loadSnapshot replaces the local relation tuples of the replicated
+// namespaces with the ones of a snapshot of the primary, and returns the
+// cursor to follow the changes after. The relation tuples are replaced in one
+// transaction if the manager supports it, so that a failed load keeps the
+// previous ones.
+//
+// The cursor is read before the snapshot is taken, so changes between both
+// are applied again. This is safe, as applying a change is idempotent.
+func (r *Replicator) loadSnapshot(ctx context.Context) (string, error) {
+	cursor, err := r.latestCursor(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	namespaces, err := r.namespaces(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.get(ctx, r.d.Config(ctx).Sidecar().PrimaryAdminURL, relationtuple.SnapshotRoute, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	snapshot := ketoapi.NewSnapshotReader(resp.Body)
+	header, err := snapshot.Header()
+	if err != nil {
+		return "", err
+	}
+
+	count := 0
+	err = r.inTransaction(ctx, func(ctx context.Context) error {
+		count = 0
+		for n := range namespaces.replicated {
+			n := n
+			query, err := r.d.Mapper().FromQuery(ctx, &ketoapi.RelationQuery{Namespace: &n})
+			if err != nil {
+				return err
+			}
+			if err := r.d.RelationTupleManager().DeleteAllRelationTuples(ctx, query); err != nil {
+				return err
+			}
+		}
+
+		batch := make([]*ketoapi.RelationTuple, 0, snapshotBatchSize)
+		write := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			its, err := r.d.Mapper().FromTuple(ctx, batch...)
+			if err != nil {
+				return err
+			}
+			count += len(its)
+			batch = batch[:0]
+			return r.d.RelationTupleManager().TouchRelationTuples(ctx, its...)
+		}
+		for {
+			t, err := snapshot.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			if !namespaces.includes(t) {
+				continue
+			}
+			if batch = append(batch, t); len(batch) == snapshotBatchSize {
+				if err := write(); err != nil {
+					return err
+				}
+			}
+		}
+		return write()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	r.d.Logger().
+		WithField("relation_tuples", count).
+		WithField("snapshot_created_at", header.CreatedAt).
+		Info("Loaded the snapshot of the primary")
+	select {
+	case <-r.synced:
+	default:
+		close(r.synced)
+	}
+	return cursor, nil
+}
+
+// inTransaction runs f in one transaction of the relation tuple manager, or
+// directly if it does not support transactions.
+func (r *Replicator) inTransaction(ctx context.Context, f func(ctx context.Context) error) error {
+	if t, ok := r.d.RelationTupleManager().(relationtuple.Transactor); ok {
+		return t.InTransaction(ctx, f)
+	}
+	return f(ctx)
+}
+
+// latestCursor returns the cursor of the latest change of the primary, which
+// the watch endpoint sends first.
+func (r *Replicator) latestCursor(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := r.get(ctx, r.d.Config(ctx).Sidecar().PrimaryURL, relationtuple.WatchRoute, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	e, err := nextEvent(newScanner(resp.Body))
+	if err != nil {
+		return "", err
+	}
+	if e.id == "" {
+		return "", errors.New("the primary did not send the cursor of its latest change")
+	}
+	return e.id, nil
+}
+
+// follow applies the changes of the primary after the cursor until the event
+// stream ends, and returns the cursor of the last applied change.
+func (r *Replicator) follow(ctx context.Context, cursor string) (string, error) {
+	namespaces, err := r.namespaces(ctx)
+	if err != nil {
+		return cursor, err
+	}
+
+	resp, err := r.get(ctx, r.d.Config(ctx).Sidecar().PrimaryURL, relationtuple.WatchRoute, url.Values{"after": {cursor}})
+	if err != nil {
+		return cursor, err
+	}
+	defer resp.Body.Close()
+
+	s := newScanner(resp.Body)
+	for {
+		e, err := nextEvent(s)
+		if errors.Is(err, io.EOF) {
+			return cursor, nil
+		} else if err != nil {
+			return cursor, err
+		}
+		if ketoapi.WatchEventType(e.typ) != ketoapi.WatchEventRelationTuple {
+			continue
+		}
+
+		var we ketoapi.WatchEvent
+		if err := json.Unmarshal([]byte(e.data), &we); err != nil {
+			return cursor, errors.WithStack(err)
+		}
+		if we.Change == nil || we.Change.RelationTuple == nil {
+			return cursor, errors.Errorf("the primary sent the event %s without a relation tuple change", e.id)
+		}
+		if namespaces.includes(we.Change.RelationTuple) {
+			if err := r.apply(ctx, we.Change); err != nil {
+				return cursor, err
+			}
+		}
+		cursor = e.id
+	}
+}
+
+func (r *Replicator) apply(ctx context.Context, change *ketoapi.RelationTupleChange) error {
+	its, err := r.d.Mapper().FromTuple(ctx, change.RelationTuple)
+	if err != nil {
+		return err
+	}
+	switch change.Action {
+	case ketoapi.ActionInsert:
+		return r.d.RelationTupleManager().TouchRelationTuples(ctx, its...)
+	case ketoapi.ActionDelete:
+		return r.d.RelationTupleManager().DeleteRelationTuples(ctx, its...)
+	default:
+		return errors.Errorf("unknown relation tuple change action %q", change.Action)
+	}
+}
+
+// namespaces returns the replicated namespaces, which are the configured ones
+// that are also known locally.
+func (r *Replicator) namespaces(ctx context.Context) (*namespaceSet, error) {
+	nm, err := r.d.Config(ctx).NamespaceManager()
+	if err != nil {
+		return nil, err
+	}
+	local, err := nm.Namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := r.d.Config(ctx).Sidecar().Namespaces
+	set := &namespaceSet{local: make(map[string]struct{}, len(local))}
+	for _, n := range local {
+		set.local[n.Name] = struct{}{}
+	}
+	if len(selected) == 0 {
+		set.replicated = set.local
+		return set, nil
+	}
+
+	set.replicated = make(map[string]struct{}, len(selected))
+	for _, n := range selected {
+		if _, ok := set.local[n]; !ok {
+			r.d.Logger().WithField("namespace", n).Warn("The replicated namespace is not configured locally, ignoring it.")
+			continue
+		}
+		set.replicated[n] = struct{}{}
+	}
+	return set, nil
+}
+
+// includes returns whether the relation tuple is replicated. Relation tuples
+// with subject sets of namespaces that are unknown locally are skipped, as
+// they cannot be stored.
+func (s *namespaceSet) includes(t *ketoapi.RelationTuple) bool {
+	if _, ok := s.replicated[t.Namespace]; !ok {
+		return false
+	}
+	if t.SubjectSet != nil {
+		_, ok := s.local[t.SubjectSet.Namespace]
+		return ok
+	}
+	return true
+}
+
+// get requests the path from the API of the primary at the base URL.
+func (r *Replicator) get(ctx context.Context, base, path string, query url.Values) (*http.Response, error) {
+	c := r.d.Config(ctx).Sidecar()
+	u := strings.TrimSuffix(base, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("the primary responded to %s with status %d: %s", path, resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+func newScanner(body io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(body)
+	// Relation tuples can be larger than the default limit of a line.
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return s
+}
+
+// nextEvent reads the next server-sent event of the stream, skipping
+// comments. It returns io.EOF once the stream ended.
+func nextEvent(s *bufio.Scanner) (*event, error) {
+	var (
+		e    event
+		data []string
+		seen bool
+	)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case line == "":
+			if seen {
+				e.data = strings.Join(data, "\n")
+				return &e, nil
+			}
+			continue
+		case strings.HasPrefix(line, ":"):
+			continue
+		}
+
+		seen = true
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			e.id = value
+		case "event":
+			e.typ = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,171 @@
+package sidecar_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/internal/x/dbx"
+	"github.com/ory/keto/ketoapi"
+)
+
+func TestReplicator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	namespaces := []*namespace.Namespace{{Name: "n"}, {Name: "m"}}
+
+	primary := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces(namespaces))
+	require.NoError(t, primary.Config(ctx).Set(config.KeyHistoryEnabled, true))
+	require.NoError(t, primary.Config(ctx).Set(config.KeyHistoryWatchInterval, "10ms"))
+	require.NoError(t, primary.Config(ctx).Set(config.KeyAdminAPIEnabled, true))
+	ts := httptest.NewServer(primary.WriteRouter(ctx))
+	t.Cleanup(ts.Close)
+	// The snapshot is only served on the admin API of the primary.
+	admin := httptest.NewServer(primary.AdminRouter(ctx))
+	t.Cleanup(admin.Close)
+
+	replica := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces(namespaces))
+	require.NoError(t, replica.Config(ctx).Set(config.KeySidecarPrimaryURL, ts.URL))
+	require.NoError(t, replica.Config(ctx).Set(config.KeySidecarPrimaryAdminURL, admin.URL))
+	require.NoError(t, replica.Config(ctx).Set(config.KeySidecarNamespaces, []string{"n"}))
+	require.NoError(t, replica.Config(ctx).Set(config.KeySidecarRetryInterval, "10ms"))
+
+	transact := func(t *testing.T, insert, delete []*ketoapi.RelationTuple) {
+		its, err := primary.Mapper().FromTuple(ctx, append(append([]*ketoapi.RelationTuple{}, insert...), delete...)...)
+		require.NoError(t, err)
+		require.NoError(t, primary.RelationTupleManager().TransactRelationTuples(ctx, its[:len(insert)], its[len(insert):]))
+	}
+	replicated := func(t *testing.T) []string {
+		its, _, err := replica.RelationTupleManager().GetRelationTuples(ctx, &relationtuple.RelationQuery{}, x.WithSize(100))
+		require.NoError(t, err)
+		tuples, err := replica.Mapper().ToTuple(ctx, its...)
+		require.NoError(t, err)
+		res := make([]string, len(tuples))
+		for i, rt := range tuples {
+			res[i] = rt.String()
+		}
+		sort.Strings(res)
+		return res
+	}
+	tuple := func(s string) *ketoapi.RelationTuple {
+		rt, err := (&ketoapi.RelationTuple{}).FromString(s)
+		require.NoError(t, err)
+		return rt
+	}
+
+	transact(t, []*ketoapi.RelationTuple{tuple("n:a#r@s1"), tuple("m:a#r@s1")}, nil)
+
+	// Relation tuples that the replica has before the snapshot are replaced.
+	stale, err := replica.Mapper().FromTuple(ctx, tuple("n:stale#r@s1"))
+	require.NoError(t, err)
+	require.NoError(t, replica.RelationTupleManager().WriteRelationTuples(ctx, stale...))
+
+	require.Error(t, replica.HealthHandler().ReadyChecks["sidecar"](httptest.NewRequest("GET", "/", nil)))
+
+	done := make(chan error)
+	go func() {
+		done <- replica.SidecarReplicator().Run(ctx)
+	}()
+
+	t.Run("case=loads the snapshot of the replicated namespaces", func(t *testing.T) {
+		require.Eventually(t, replica.SidecarReplicator().Synced, 5*time.Second, 10*time.Millisecond)
+		assert.ElementsMatch(t, []string{"n:a#r@s1"}, replicated(t))
+		assert.NoError(t, replica.HealthHandler().ReadyChecks["sidecar"](httptest.NewRequest("GET", "/", nil)))
+	})
+
+	t.Run("case=applies the changes of the primary", func(t *testing.T) {
+		transact(t, []*ketoapi.RelationTuple{tuple("n:b#r@s2"), tuple("m:b#r@s2")}, nil)
+		transact(t, []*ketoapi.RelationTuple{tuple("n:c#r@n:b#r")}, []*ketoapi.RelationTuple{tuple("n:a#r@s1")})
+
+		expected := []string{"n:b#r@s2", "n:c#r@(n:b#r)"}
+		require.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual(expected, replicated(t))
+		}, 5*time.Second, 10*time.Millisecond)
+
+		it, err := replica.Mapper().FromTuple(ctx, tuple("n:c#r@s2"))
+		require.NoError(t, err)
+		allowed, err := replica.PermissionEngine().CheckIsMember(ctx, it[0], 0)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("case=rejects writes", func(t *testing.T) {
+		ts := httptest.NewServer(replica.WriteRouter(ctx))
+		t.Cleanup(ts.Close)
+
+		req, err := http.NewRequest(http.MethodPut, ts.URL+relationtuple.WriteRouteBase, strings.NewReader(`{"namespace":"n","object":"o","relation":"r","subject_id":"s"}`))
+		require.NoError(t, err)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp, err = ts.Client().Get(ts.URL + relationtuple.SnapshotRoute)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestReplicatorFailedSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var snapshots int32
+	mux := http.NewServeMux()
+	mux.HandleFunc(relationtuple.WatchRoute, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("id: 1\n\n"))
+	})
+	mux.HandleFunc(relationtuple.SnapshotRoute, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&snapshots, 1)
+		_, _ = fmt.Fprintf(w, "{\"version\":%d}\n", ketoapi.SnapshotFormatVersion)
+		_, _ = w.Write([]byte(`{"namespace":"n","object":"a","relation":"r","subject_id":"s1"}` + "\n{"))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	replica := driver.NewTestRegistry(t, dbx.GetSqlite(t, dbx.SQLiteMemory), driver.WithNamespaces([]*namespace.Namespace{{Name: "n"}}))
+	require.NoError(t, replica.Config(ctx).Set(config.KeySidecarPrimaryURL, ts.URL))
+	require.NoError(t, replica.Config(ctx).Set(config.KeySidecarRetryInterval, "10ms"))
+
+	rt, err := (&ketoapi.RelationTuple{}).FromString("n:stale#r@s1")
+	require.NoError(t, err)
+	stale, err := replica.Mapper().FromTuple(ctx, rt)
+	require.NoError(t, err)
+	require.NoError(t, replica.RelationTupleManager().WriteRelationTuples(ctx, stale...))
+
+	done := make(chan error)
+	go func() {
+		done <- replica.SidecarReplicator().Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&snapshots) >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.False(t, replica.SidecarReplicator().Synced())
+	its, _, err := replica.RelationTupleManager().GetRelationTuples(context.Background(), &relationtuple.RelationQuery{})
+	require.NoError(t, err)
+	tuples, err := replica.Mapper().ToTuple(context.Background(), its...)
+	require.NoError(t, err)
+	require.Len(t, tuples, 1)
+	assert.Equal(t, "n:stale#r@s1", tuples[0].String(), "a failed snapshot keeps the previous relation tuples")
+}
@@ -0,0 +1,91 @@
+// Package tenancy partitions the data of one deployment by tenant. Every
+// tenant gets its own network, which all persister queries are scoped by, so
+// that tenants can neither see nor change the relation tuples, managed
+// namespaces, and UUID mappings of each other.
+package tenancy
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+
+	"github.com/ory/keto/ketoctx"
+)
+
+type (
+	// Contextualizer derives the network of the tenant of the context from
+	// the network of the wrapped contextualizer.
+	Contextualizer struct {
+		ketoctx.Contextualizer
+	}
+	// ContextsProvider lists a context for the deployment and for every known
+	// tenant, so that background jobs can cover the networks of all tenants.
+	ContextsProvider interface {
+		TenantContexts(ctx context.Context) ([]context.Context, error)
+	}
+
+	contextKey struct{}
+)
+
+// AnyTenant is the tenant of clients that can access all tenants. They choose
+// the tenant of a request by the tenant header.
+const AnyTenant = "*"
+
+var (
+	pattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+	ErrMissingTenant = herodot.ErrBadRequest.WithReason("A tenant has to be provided.")
+	ErrInvalidTenant = herodot.ErrBadRequest.WithReason("The tenant has to consist of at most 64 letters, digits, dots, dashes, and underscores, and start with a letter or digit.")
+	ErrForeignTenant = herodot.ErrForbidden.WithReason("The client is not allowed to access the tenant.")
+	ErrUnboundClient = herodot.ErrForbidden.WithReason("The client is not bound to a tenant.")
+	ErrUnknownTenant = herodot.ErrForbidden.WithReason("The tenant is not allowed.")
+)
+
+var _ ketoctx.Contextualizer = (*Contextualizer)(nil)
+
+// NewContextualizer wraps the contextualizer, which is used for requests
+// without a tenant.
+func NewContextualizer(c ketoctx.Contextualizer) *Contextualizer {
+	return &Contextualizer{Contextualizer: c}
+}
+
+func (c *Contextualizer) Network(ctx context.Context, network uuid.UUID) uuid.UUID {
+	network = c.Contextualizer.Network(ctx, network)
+	if tenant, ok := FromContext(ctx); ok {
+		return Network(network, tenant)
+	}
+	return network
+}
+
+// Network returns the network of the tenant within the network of the
+// deployment.
+func Network(network uuid.UUID, tenant string) uuid.UUID {
+	return uuid.NewV5(network, "tenant:"+tenant)
+}
+
+// Valid returns whether the tenant is a valid tenant name.
+func Valid(tenant string) bool {
+	return pattern.MatchString(tenant)
+}
+
+// NewContext returns a context that carries the tenant.
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant of the request, if there is one.
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// Contexts returns the contexts that background jobs run in. Without a
+// ContextsProvider, it is only the given context.
+func Contexts(ctx context.Context, d interface{}) ([]context.Context, error) {
+	if p, ok := d.(ContextsProvider); ok {
+		return p.TenantContexts(ctx)
+	}
+	return []context.Context{ctx}, nil
+}
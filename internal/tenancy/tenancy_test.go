@@ -0,0 +1,35 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/keto/ketoctx"
+)
+
+func TestContextualizer(t *testing.T) {
+	ctx := context.Background()
+	ctxer := NewContextualizer(&ketoctx.DefaultContextualizer{})
+	network := uuid.Must(uuid.NewV4())
+
+	assert.Equal(t, network, ctxer.Network(ctx, network))
+	assert.Equal(t, network, ctxer.Network(NewContext(ctx, ""), network))
+
+	a := ctxer.Network(NewContext(ctx, "a"), network)
+	assert.NotEqual(t, network, a)
+	assert.Equal(t, a, ctxer.Network(NewContext(ctx, "a"), network))
+	assert.NotEqual(t, a, ctxer.Network(NewContext(ctx, "b"), network))
+	assert.NotEqual(t, a, ctxer.Network(NewContext(ctx, "a"), uuid.Must(uuid.NewV4())))
+}
+
+func TestValid(t *testing.T) {
+	for _, tenant := range []string{"a", "acme", "acme-corp.eu_1", "0"} {
+		assert.True(t, Valid(tenant), tenant)
+	}
+	for _, tenant := range []string{"", "-a", ".a", "../a", "a/b", "a b", string(make([]byte, 65))} {
+		assert.False(t, Valid(tenant), tenant)
+	}
+}
@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// File is a validation file. It contains a permission model, the
+	// relation tuples to load into it, and the expected results of checks
+	// and expands.
+	File struct {
+		// Schema is the Ory Permission Language source of the namespaces.
+		Schema string `json:"schema"`
+		// SchemaFile is the Ory Permission Language file of the namespaces,
+		// relative to the validation file. It is used if Schema is empty.
+		SchemaFile string `json:"schema_file"`
+		// Relationships are the relation tuples, one per line in the form
+		// namespace:object#relation@subject. Comments (starting with //)
+		// and blank lines are ignored.
+		Relationships string `json:"relationships"`
+		// Assertions are the relation tuples that are expected to be allowed
+		// or denied.
+		Assertions Assertions `json:"assertions"`
+		// ExpectedExpansions are the subjects that the expansion of a subject
+		// set (namespace:object#relation) is expected to contain, either
+		// subject IDs or subject sets that are not expanded any further.
+		ExpectedExpansions map[string][]string `json:"expected_expansions"`
+	}
+	Assertions struct {
+		Allowed []string `json:"allowed"`
+		Denied  []string `json:"denied"`
+	}
+)
+
+// ReadFile reads and decodes the validation file. The schema file, if any, is
+// read as well.
+func ReadFile(fn string) (*File, error) {
+	raw, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file %s: %w", fn, err)
+	}
+	f, err := Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode file %s: %w", fn, err)
+	}
+
+	if f.Schema == "" && f.SchemaFile != "" {
+		schemaFile := f.SchemaFile
+		if !filepath.IsAbs(schemaFile) {
+			schemaFile = filepath.Join(filepath.Dir(fn), schemaFile)
+		}
+		schema, err := os.ReadFile(schemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the schema file of %s: %w", fn, err)
+		}
+		f.Schema = string(schema)
+	}
+	return f, nil
+}
+
+// Decode decodes a validation file in YAML or JSON. Unknown keys are
+// rejected, so that typos do not silently skip assertions.
+func Decode(raw []byte) (*File, error) {
+	j, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	var f File
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Schema == "" && f.SchemaFile == "" {
+		return nil, fmt.Errorf("either schema or schema_file is required")
+	}
+	return &f, nil
+}
+
+// relationships parses the relation tuples of the file.
+func (f *File) relationships() ([]*ketoapi.RelationTuple, error) {
+	var tuples []*ketoapi.RelationTuple
+	s := bufio.NewScanner(strings.NewReader(f.Relationships))
+	for i := 1; s.Scan(); i++ {
+		row := strings.TrimSpace(s.Text())
+		if row == "" || strings.HasPrefix(row, "//") {
+			continue
+		}
+		t, err := (&ketoapi.RelationTuple{}).FromString(row)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode relationships line %d\n  %s\n\n%w", i, row, err)
+		}
+		tuples = append(tuples, t)
+	}
+	return tuples, s.Err()
+}
@@ -0,0 +1,255 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketoctx"
+)
+
+type (
+	// Result are the results of all assertions and expected expansions of a
+	// validation file.
+	Result struct {
+		Assertions []*AssertionResult `json:"assertions"`
+		Expansions []*ExpansionResult `json:"expansions"`
+	}
+	AssertionResult struct {
+		Tuple    string `json:"tuple"`
+		Expected bool   `json:"expected"`
+		Allowed  bool   `json:"allowed"`
+		Error    string `json:"error,omitempty"`
+	}
+	ExpansionResult struct {
+		SubjectSet string   `json:"subject_set"`
+		Expected   []string `json:"expected"`
+		Actual     []string `json:"actual"`
+		Error      string   `json:"error,omitempty"`
+	}
+	// SchemaError is returned if the schema of the validation file can not
+	// be parsed.
+	SchemaError struct {
+		Errors []error
+	}
+)
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "could not parse the schema:\n" + strings.Join(msgs, "\n")
+}
+
+// Run loads the schema and the relationships of the file into a new in-memory
+// instance, then checks every assertion and expands every subject set with
+// expected subjects. An error is only returned if the file could not be
+// loaded, failed assertions are part of the result.
+func Run(ctx context.Context, f *File, opts ...ketoctx.Option) (*Result, error) {
+	parsed, errs := schema.Parse(f.Schema)
+	if len(errs) > 0 {
+		return nil, &SchemaError{Errors: errs}
+	}
+	namespaces := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		namespaces[i] = &parsed[i]
+	}
+
+	tuples, err := f.relationships()
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := driver.NewInMemoryRegistry(ctx, namespaces, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not start the in-memory instance: %w", err)
+	}
+	for _, t := range tuples {
+		its, err := reg.Mapper().FromTuple(ctx, t)
+		if err == nil {
+			err = reg.RelationTupleManager().WriteRelationTuples(ctx, its...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not load the relationship %s: %s", t, errorMessage(err))
+		}
+	}
+
+	res := &Result{Assertions: []*AssertionResult{}, Expansions: []*ExpansionResult{}}
+	for _, a := range []struct {
+		tuples   []string
+		expected bool
+	}{{f.Assertions.Allowed, true}, {f.Assertions.Denied, false}} {
+		for _, raw := range a.tuples {
+			r := &AssertionResult{Tuple: raw, Expected: a.expected}
+			if err := checkAssertion(ctx, reg, r); err != nil {
+				r.Error = errorMessage(err)
+			}
+			res.Assertions = append(res.Assertions, r)
+		}
+	}
+
+	sets := make([]string, 0, len(f.ExpectedExpansions))
+	for s := range f.ExpectedExpansions {
+		sets = append(sets, s)
+	}
+	sort.Strings(sets)
+	for _, s := range sets {
+		r := &ExpansionResult{SubjectSet: s, Expected: sortedUnique(f.ExpectedExpansions[s]), Actual: []string{}}
+		if err := expandSubjectSet(ctx, reg, r); err != nil {
+			r.Error = errorMessage(err)
+		}
+		res.Expansions = append(res.Expansions, r)
+	}
+	return res, nil
+}
+
+func checkAssertion(ctx context.Context, reg driver.Registry, r *AssertionResult) error {
+	t, err := (&ketoapi.RelationTuple{}).FromString(r.Tuple)
+	if err != nil {
+		return err
+	}
+	its, err := reg.Mapper().FromTuple(ctx, t)
+	if err != nil {
+		return err
+	}
+	r.Allowed, err = reg.PermissionEngine().CheckIsMember(ctx, its[0], 0)
+	return err
+}
+
+// expandSubjectSet sets the subjects of the leaves of the expand tree.
+func expandSubjectSet(ctx context.Context, reg driver.Registry, r *ExpansionResult) error {
+	set, err := (&ketoapi.SubjectSet{}).FromString(r.SubjectSet)
+	if err != nil {
+		return err
+	}
+	internal, err := reg.Mapper().FromSubjectSet(ctx, set)
+	if err != nil {
+		return err
+	}
+	tree, err := reg.ExpandEngine().BuildTree(ctx, internal, 0)
+	if err != nil || tree == nil {
+		return err
+	}
+	apiTree, err := reg.Mapper().ToTree(ctx, tree)
+	if err != nil {
+		return err
+	}
+
+	var leaves []string
+	var walk func(t *ketoapi.Tree[*ketoapi.RelationTuple])
+	walk = func(t *ketoapi.Tree[*ketoapi.RelationTuple]) {
+		if len(t.Children) == 0 {
+			if t.Tuple.SubjectID != nil {
+				leaves = append(leaves, *t.Tuple.SubjectID)
+			} else if t.Tuple.SubjectSet != nil {
+				leaves = append(leaves, t.Tuple.SubjectSet.String())
+			}
+		}
+		for _, c := range t.Children {
+			walk(c)
+		}
+	}
+	walk(apiTree)
+	r.Actual = sortedUnique(leaves)
+	return nil
+}
+
+func (r *Result) Passed() bool {
+	for _, a := range r.Assertions {
+		if !a.Passed() {
+			return false
+		}
+	}
+	for _, e := range r.Expansions {
+		if !e.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary counts the assertions and expansions that passed, failed, or could
+// not be evaluated.
+func (r *Result) Summary() (passed, failed, errored int) {
+	count := func(ok bool, err string) {
+		switch {
+		case err != "":
+			errored++
+		case ok:
+			passed++
+		default:
+			failed++
+		}
+	}
+	for _, a := range r.Assertions {
+		count(a.Passed(), a.Error)
+	}
+	for _, e := range r.Expansions {
+		count(e.Passed(), e.Error)
+	}
+	return passed, failed, errored
+}
+
+func (r *AssertionResult) Passed() bool {
+	return r.Error == "" && r.Allowed == r.Expected
+}
+
+func (r *ExpansionResult) Passed() bool {
+	return r.Error == "" && len(r.Missing()) == 0 && len(r.Unexpected()) == 0
+}
+
+// Missing are the expected subjects that are not part of the expansion.
+func (r *ExpansionResult) Missing() []string {
+	return difference(r.Expected, r.Actual)
+}
+
+// Unexpected are the subjects of the expansion that were not expected.
+func (r *ExpansionResult) Unexpected() []string {
+	return difference(r.Actual, r.Expected)
+}
+
+func difference(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		in[s] = struct{}{}
+	}
+	var res []string
+	for _, s := range a {
+		if _, ok := in[s]; !ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func sortedUnique(ss []string) []string {
+	res := make([]string, 0, len(ss))
+	seen := make(map[string]struct{}, len(ss))
+	for _, s := range ss {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			res = append(res, s)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// errorMessage adds the reason of API errors, e.g. which namespace is
+// unknown.
+func errorMessage(err error) string {
+	var rc herodot.ReasonCarrier
+	if errors.As(err, &rc) && rc.Reason() != "" {
+		return err.Error() + ": " + rc.Reason()
+	}
+	return err.Error()
+}
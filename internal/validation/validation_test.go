@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validationFile = `
+schema_file: namespaces.keto.ts
+relationships: |
+  // alice is in dev, which can view the readme
+  Group:dev#members@alice
+  Document:readme#viewers@Group:dev#members
+  Document:readme#owners@carol
+assertions:
+  allowed:
+    - Document:readme#view@alice
+    - Document:readme#view@carol
+  denied:
+    - Document:readme#view@bob
+expected_expansions:
+  Document:readme#viewers:
+    - alice
+`
+
+const testSchema = `import { Namespace, SubjectSet, Context } from "@ory/keto-namespace-types"
+
+class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}
+
+class Document implements Namespace {
+  related: {
+    viewers: (User | SubjectSet<Group, "members">)[]
+    owners: User[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean =>
+      this.related.viewers.includes(ctx.subject) ||
+      this.related.owners.includes(ctx.subject),
+  }
+}
+`
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "namespaces.keto.ts"), []byte(testSchema), 0600))
+	fn := filepath.Join(dir, "validation.yml")
+	require.NoError(t, os.WriteFile(fn, []byte(validationFile), 0600))
+
+	t.Run("case=all hold", func(t *testing.T) {
+		f, err := ReadFile(fn)
+		require.NoError(t, err)
+
+		res, err := Run(ctx, f)
+		require.NoError(t, err)
+		assert.True(t, res.Passed(), "%+v", res)
+		require.Len(t, res.Assertions, 3)
+		assert.False(t, res.Assertions[2].Expected)
+		require.Len(t, res.Expansions, 1)
+		assert.Equal(t, []string{"alice"}, res.Expansions[0].Actual)
+
+		passed, failed, errored := res.Summary()
+		assert.Equal(t, [3]int{4, 0, 0}, [3]int{passed, failed, errored})
+	})
+
+	t.Run("case=failures", func(t *testing.T) {
+		f, err := ReadFile(fn)
+		require.NoError(t, err)
+		f.Assertions.Denied = append(f.Assertions.Denied, "Document:readme#view@alice", "Unknown:readme#view@alice")
+		f.ExpectedExpansions["Document:readme#viewers"] = []string{"bob", "alice"}
+
+		res, err := Run(ctx, f)
+		require.NoError(t, err)
+		assert.False(t, res.Passed())
+		passed, failed, errored := res.Summary()
+		assert.Equal(t, [3]int{3, 2, 1}, [3]int{passed, failed, errored})
+		assert.Contains(t, res.Assertions[4].Error, "Unknown")
+
+		e := res.Expansions[0]
+		assert.Equal(t, []string{"alice", "bob"}, e.Expected)
+		assert.Equal(t, []string{"bob"}, e.Missing())
+		assert.Empty(t, e.Unexpected())
+	})
+
+	t.Run("case=invalid schema", func(t *testing.T) {
+		_, err := Run(ctx, &File{Schema: "class Document implements Namespace { related: { viewers: Unknown[] } }"})
+		var schemaErr *SchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.NotEmpty(t, schemaErr.Errors)
+	})
+
+	t.Run("case=unknown relationship namespace", func(t *testing.T) {
+		_, err := Run(ctx, &File{Schema: testSchema, Relationships: "Folder:root#viewers@alice"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not load the relationship Folder:root#viewers@alice")
+	})
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name, raw, err string
+	}{
+		{name: "unknown key", raw: "schema: x\nassertion:\n  allowed: []", err: `unknown field "assertion"`},
+		{name: "no schema", raw: "relationships: ''", err: "either schema or schema_file is required"},
+		{name: "invalid yaml", raw: "schema: [", err: "yaml"},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			_, err := Decode([]byte(tc.raw))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -16,26 +17,24 @@ func GetSqlite(t testing.TB, mode sqliteMode) *DsnT {
 	}
 
 	fn := fmt.Sprintf("TestDB_%s_%d.sqlite", t.Name(), rand.Int31())
-	// init switch
 	switch mode {
 	case SQLiteMemory:
 		dsn.Name = "memory"
 		dsn.Conn = fmt.Sprintf("sqlite://file:%s?_fk=true&cache=shared&mode=memory", fn)
 		t.Cleanup(func() {
-			_ = os.Remove(fn)
+			// Database files are in WAL mode, see driver.tuneSQLiteDSN.
+			for _, f := range []string{fn, fn + "-wal", fn + "-shm"} {
+				_ = os.Remove(f)
+			}
 		})
-	case SQLiteFile, SQLiteDebug:
+	case SQLiteFile:
+		dsn.Name = "sqlite"
+		// The database files are removed with the temporary directory.
+		dsn.Conn = fmt.Sprintf("sqlite://file:%s?_fk=true", filepath.Join(t.TempDir(), "keto.sqlite"))
+	case SQLiteDebug:
 		dsn.Name = "sqlite"
 		dsn.Conn = fmt.Sprintf("sqlite://file:%s?_fk=true", fn)
 	}
-	// cleanup switch
-	switch mode {
-	case SQLiteMemory, SQLiteFile:
-		t.Cleanup(func() {
-			_ = os.Remove(fn)
-		})
-	}
-
 	return dsn
 }
 
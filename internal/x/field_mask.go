@@ -0,0 +1,161 @@
+package x
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// FieldMaskQueryKey is the URL query parameter that selects the fields of a
+// response. It can be repeated, or hold comma-separated paths.
+const FieldMaskQueryKey = "fields"
+
+// FieldMask selects the fields of a JSON response by dot-separated paths of
+// the JSON field names, e.g. "subject_id" or "subject_set.object". A nil mask
+// selects all fields.
+type FieldMask struct {
+	paths [][]string
+}
+
+// FieldMaskFromURLQuery parses the field mask of the URL query, and validates
+// it against the JSON fields of the type of v. It returns nil if the query does
+// not have a field mask.
+func FieldMaskFromURLQuery(q url.Values, v interface{}) (*FieldMask, error) {
+	var paths [][]string
+	for _, value := range q[FieldMaskQueryKey] {
+		for _, p := range strings.Split(value, ",") {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			path := strings.Split(p, ".")
+			if !validJSONPath(reflect.TypeOf(v), path) {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The field %q does not exist.", p))
+			}
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return &FieldMask{paths: paths}, nil
+}
+
+func validJSONPath(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if f.IsExported() && name == path[0] {
+			return validJSONPath(f.Type, path[1:])
+		}
+	}
+	return false
+}
+
+// Apply returns v with only the selected fields, as it would be encoded to
+// JSON.
+func (m *FieldMask) Apply(v interface{}) (interface{}, error) {
+	if m == nil {
+		return v, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pruneJSON(decoded, m.paths), nil
+}
+
+func pruneJSON(v interface{}, paths [][]string) interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	full, nested := splitPaths(paths)
+	for k, v := range obj {
+		switch {
+		case full[k]:
+		case len(nested[k]) > 0:
+			obj[k] = pruneJSON(v, nested[k])
+		default:
+			delete(obj, k)
+		}
+	}
+	return obj
+}
+
+// splitPaths returns the fields that are selected completely, and the paths
+// of the fields that are selected partially.
+func splitPaths(paths [][]string) (map[string]bool, map[string][][]string) {
+	full, nested := make(map[string]bool), make(map[string][][]string)
+	for _, p := range paths {
+		if len(p) == 1 {
+			full[p[0]] = true
+		} else {
+			nested[p[0]] = append(nested[p[0]], p[1:])
+		}
+	}
+	return full, nested
+}
+
+// ValidateProtoFieldMask returns an error if the mask has paths that are not
+// fields of the message. A nil mask is valid.
+func ValidateProtoFieldMask(mask *fieldmaskpb.FieldMask, m proto.Message) error {
+	if mask != nil && !mask.IsValid(m) {
+		return herodot.ErrBadRequest.WithReasonf("The field mask %v has fields that do not exist.", mask.GetPaths())
+	}
+	return nil
+}
+
+// ApplyProtoFieldMask clears all fields of the message that are not selected
+// by the mask. An empty mask selects all fields.
+func ApplyProtoFieldMask(mask *fieldmaskpb.FieldMask, m proto.Message) {
+	if len(mask.GetPaths()) == 0 {
+		return
+	}
+	paths := make([][]string, len(mask.GetPaths()))
+	for i, p := range mask.GetPaths() {
+		paths[i] = strings.Split(p, ".")
+	}
+	pruneProto(m.ProtoReflect(), paths)
+}
+
+func pruneProto(m protoreflect.Message, paths [][]string) {
+	full, nested := splitPaths(paths)
+	var cleared []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := string(fd.Name())
+		switch {
+		case full[name]:
+		case len(nested[name]) > 0 && fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			pruneProto(v.Message(), nested[name])
+		default:
+			cleared = append(cleared, fd)
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		m.Clear(fd)
+	}
+}
@@ -0,0 +1,82 @@
+// Package gcpx authorizes requests to the Google Cloud APIs with the token of
+// the service account from the metadata server, so that they can be called
+// without the Google Cloud client libraries.
+package gcpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TokenSource caches the access token of the service account.
+type TokenSource struct {
+	sync.Mutex
+	metadataHost string // empty if no token is used
+	token        string
+	expires      time.Time
+}
+
+// NewTokenSource returns a token source for the metadata server at
+// GCE_METADATA_HOST, or metadata.google.internal.
+func NewTokenSource() *TokenSource {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	return &TokenSource{metadataHost: host}
+}
+
+// Authorize sets the token of the service account on the request, if there is
+// one.
+func (s *TokenSource) Authorize(req *http.Request) {
+	if s == nil {
+		return
+	}
+	if token := s.Token(req.Context()); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// Token returns the cached token of the service account, and requests a new
+// one from the metadata server before it expires. If there is no metadata
+// server, it returns the empty string from then on.
+func (s *TokenSource) Token(ctx context.Context) string {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.metadataHost == "" {
+		return ""
+	}
+	if s.token != "" && time.Until(s.expires) > time.Minute {
+		return s.token
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+s.metadataHost+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.metadataHost = ""
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&token) != nil {
+		s.metadataHost = ""
+		return ""
+	}
+	s.token, s.expires = token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn)*time.Second)
+	return s.token
+}
@@ -48,3 +48,20 @@ func CheckAndAddVisited(ctx context.Context, current relationtuple.Subject) (con
 
 	return ctx, set.addNoDuplicate(current.UniqueID())
 }
+
+// CopyVisited returns a context with a copy of the visited subjects, so that
+// the subjects that are visited with it are not visible to other branches.
+func CopyVisited(ctx context.Context) context.Context {
+	set, ok := ctx.Value(visitedMapKey).(*stringSet)
+	if !ok {
+		return ctx
+	}
+
+	set.l.Lock()
+	defer set.l.Unlock()
+	cp := &stringSet{m: make(map[string]struct{}, len(set.m))}
+	for el := range set.m {
+		cp.m[el] = struct{}{}
+	}
+	return context.WithValue(ctx, visitedMapKey, cp)
+}
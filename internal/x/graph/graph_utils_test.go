@@ -121,4 +121,22 @@ func TestEngineUtilsProvider_CheckVisited(t *testing.T) {
 			assert.Equal(t, aCtx, bCtx)
 		}
 	})
+
+	t.Run("case=copies do not share subjects", func(t *testing.T) {
+		visited := &relationtuple.SubjectSet{Namespace: "1", Object: a, Relation: "connected"}
+		subject := &relationtuple.SubjectSet{Namespace: "1", Object: b, Relation: "connected"}
+
+		ctx, _ := CheckAndAddVisited(context.Background(), visited)
+		aCtx, bCtx := CopyVisited(ctx), CopyVisited(ctx)
+
+		_, isVisited := CheckAndAddVisited(aCtx, visited)
+		assert.True(t, isVisited, "the copy has the subjects of the original")
+
+		_, isVisited = CheckAndAddVisited(aCtx, subject)
+		assert.False(t, isVisited)
+		_, isVisited = CheckAndAddVisited(bCtx, subject)
+		assert.False(t, isVisited, "the subjects of one copy are not visible to the other")
+		_, isVisited = CheckAndAddVisited(ctx, subject)
+		assert.False(t, isVisited, "the subjects of a copy are not visible to the original")
+	})
 }
@@ -0,0 +1,95 @@
+// Package kafkax produces records to a Kafka topic through a Confluent REST
+// Proxy (v2 API), so that Kafka can be used without a client library. It does
+// not speak the Kafka protocol, a REST proxy has to run in front of the brokers.
+package kafkax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Producer produces JSON records to one topic.
+	Producer struct {
+		endpoint string
+		c        *http.Client
+	}
+	// Record is produced to the partition of its key. Records without a key
+	// are distributed by the proxy.
+	Record struct {
+		Key   string      `json:"key,omitempty"`
+		Value interface{} `json:"value"`
+	}
+
+	produceRequest struct {
+		Records []*Record `json:"records"`
+	}
+	produceResponse struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+)
+
+func NewProducer(proxyURL, topic string) (*Producer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return nil, errors.Errorf("unsupported Kafka REST proxy URL scheme %q, expected http or https", u.Scheme)
+	}
+	return &Producer{
+		endpoint: strings.TrimSuffix(u.String(), "/") + "/topics/" + url.PathEscape(topic),
+		c:        http.DefaultClient,
+	}, nil
+}
+
+// Produce produces the records in one request. It fails if any record could
+// not be produced.
+func (p *Producer) Produce(ctx context.Context, records []*Record) error {
+	enc, err := json.Marshal(produceRequest{Records: records})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(enc))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.c.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("Kafka REST proxy responded with status code %d: %s", resp.StatusCode, msg)
+	}
+
+	var res produceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return errors.WithStack(err)
+	}
+	for i, o := range res.Offsets {
+		if o.ErrorCode != nil {
+			return errors.WithStack(fmt.Errorf("could not produce record %d: %s (code %d)", i, o.Error, *o.ErrorCode))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package x
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type (
+	// LRU caches up to a fixed number of entries, and evicts the least
+	// recently used entry when it is full. It is safe for concurrent use.
+	LRU[K comparable, V any] struct {
+		mx        sync.Mutex
+		size      int
+		ttl       time.Duration
+		entries   map[K]*list.Element
+		order     *list.List
+		evictions uint64
+	}
+	lruEntry[K comparable, V any] struct {
+		key     K
+		value   V
+		expires time.Time
+	}
+)
+
+// NewLRU returns a cache of the given size. Entries expire the given time
+// after they were added, or never if it is 0.
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[K]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return value, false
+	}
+	entry := e.Value.(*lruEntry[K, V])
+	if c.ttl > 0 && time.Now().After(entry.expires) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return value, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+func (c *LRU[K, V]) Add(key K, value V) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*lruEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+		c.evictions++
+	}
+}
+
+func (c *LRU[K, V]) Remove(key K) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// Purge removes all entries.
+func (c *LRU[K, V]) Purge() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.entries = make(map[K]*list.Element, c.size)
+	c.order.Init()
+}
+
+func (c *LRU[K, V]) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) Size() int { return c.size }
+
+// Evictions returns how many entries were evicted because the cache was full.
+func (c *LRU[K, V]) Evictions() uint64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.evictions
+}
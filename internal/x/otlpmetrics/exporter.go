@@ -0,0 +1,268 @@
+// Package otlpmetrics pushes the metrics of a Prometheus registry to an
+// OpenTelemetry collector with the OpenTelemetry protocol (OTLP) over HTTP, so
+// that the metrics do not have to be scraped.
+package otlpmetrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultPath = "/v1/metrics"
+	scopeName   = "github.com/ory/keto"
+)
+
+var posInf = math.Inf(1)
+
+// Exporter converts the gathered metrics to OTLP metrics and pushes them.
+// Counters and histograms are cumulative since the exporter was created.
+type Exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource *resourcepb.Resource
+	g        prometheus.Gatherer
+	c        *http.Client
+	start    time.Time
+}
+
+// NewExporter returns an exporter that pushes the metrics of g to the endpoint.
+// The resource attributes describe the service, e.g. service.name.
+func NewExporter(endpoint string, headers, resource map[string]string, g prometheus.Gatherer) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return nil, errors.Errorf("unsupported OTLP endpoint scheme %q, expected http or https", u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultPath
+	}
+
+	return &Exporter{
+		endpoint: u.String(),
+		headers:  headers,
+		resource: &resourcepb.Resource{Attributes: attributes(resource)},
+		g:        g,
+		c:        http.DefaultClient,
+		start:    time.Now(),
+	}, nil
+}
+
+// Run pushes the metrics every interval until the context is canceled, and
+// once more before it returns.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			defer cancel()
+			if err := e.Export(ctx); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := e.Export(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Export gathers and pushes the metrics once.
+func (e *Exporter) Export(ctx context.Context) error {
+	families, err := e.g.Gather()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	now := time.Now()
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	for _, f := range families {
+		if m := e.convert(f, now); m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	body, err := proto.Marshal(&colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: e.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+				Metrics: metrics,
+			}},
+		}},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.c.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("OTLP endpoint responded with status code %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// convert returns the OTLP metric of the Prometheus metric family, or nil if
+// the type is not supported.
+func (e *Exporter) convert(f *dto.MetricFamily, now time.Time) *metricspb.Metric {
+	var (
+		start = uint64(e.start.UnixNano())
+		ts    = uint64(now.UnixNano())
+		m     = &metricspb.Metric{Name: f.GetName(), Description: f.GetHelp()}
+	)
+
+	switch f.GetType() {
+	case dto.MetricType_COUNTER:
+		points := make([]*metricspb.NumberDataPoint, len(f.Metric))
+		for i, pm := range f.Metric {
+			points[i] = &metricspb.NumberDataPoint{
+				Attributes:        labels(pm.Label),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      ts,
+				Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: pm.GetCounter().GetValue()},
+			}
+		}
+		m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		points := make([]*metricspb.NumberDataPoint, len(f.Metric))
+		for i, pm := range f.Metric {
+			v := pm.GetGauge().GetValue()
+			if f.GetType() == dto.MetricType_UNTYPED {
+				v = pm.GetUntyped().GetValue()
+			}
+			points[i] = &metricspb.NumberDataPoint{
+				Attributes:   labels(pm.Label),
+				TimeUnixNano: ts,
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: v},
+			}
+		}
+		m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+
+	case dto.MetricType_HISTOGRAM:
+		points := make([]*metricspb.HistogramDataPoint, len(f.Metric))
+		for i, pm := range f.Metric {
+			points[i] = histogramDataPoint(pm.GetHistogram())
+			points[i].Attributes = labels(pm.Label)
+			points[i].StartTimeUnixNano = start
+			points[i].TimeUnixNano = ts
+		}
+		m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}
+
+	case dto.MetricType_SUMMARY:
+		points := make([]*metricspb.SummaryDataPoint, len(f.Metric))
+		for i, pm := range f.Metric {
+			s := pm.GetSummary()
+			points[i] = &metricspb.SummaryDataPoint{
+				Attributes:        labels(pm.Label),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      ts,
+				Count:             s.GetSampleCount(),
+				Sum:               s.GetSampleSum(),
+			}
+			for _, q := range s.Quantile {
+				points[i].QuantileValues = append(points[i].QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+					Quantile: q.GetQuantile(),
+					Value:    q.GetValue(),
+				})
+			}
+		}
+		m.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: points}}
+
+	default:
+		return nil
+	}
+	return m
+}
+
+// histogramDataPoint converts the cumulative buckets of Prometheus to the
+// buckets of OTLP, which count the observations between two bounds.
+func histogramDataPoint(h *dto.Histogram) *metricspb.HistogramDataPoint {
+	var (
+		sum    = h.GetSampleSum()
+		p      = &metricspb.HistogramDataPoint{Count: h.GetSampleCount(), Sum: &sum}
+		seen   uint64
+		bounds = h.Bucket
+	)
+	// The +Inf bucket is implicit in OTLP.
+	if n := len(bounds); n > 0 && bounds[n-1].GetUpperBound() == posInf {
+		bounds = bounds[:n-1]
+	}
+	for _, b := range bounds {
+		p.ExplicitBounds = append(p.ExplicitBounds, b.GetUpperBound())
+		p.BucketCounts = append(p.BucketCounts, b.GetCumulativeCount()-seen)
+		seen = b.GetCumulativeCount()
+	}
+	p.BucketCounts = append(p.BucketCounts, p.Count-seen)
+	return p
+}
+
+func labels(pairs []*dto.LabelPair) []*commonpb.KeyValue {
+	kv := make([]*commonpb.KeyValue, len(pairs))
+	for i, l := range pairs {
+		kv[i] = stringAttribute(l.GetName(), l.GetValue())
+	}
+	return kv
+}
+
+func attributes(m map[string]string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kv := make([]*commonpb.KeyValue, len(keys))
+	for i, k := range keys {
+		kv[i] = stringAttribute(k, m[k])
+	}
+	return kv
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
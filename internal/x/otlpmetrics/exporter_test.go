@@ -0,0 +1,102 @@
+package otlpmetrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ory/keto/internal/x/otlpmetrics"
+)
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests."}, []string{"code"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_connections", Help: "Connections."})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_duration_seconds", Help: "Duration.", Buckets: []float64{1, 2}})
+	reg.MustRegister(counter, gauge, histogram)
+
+	counter.WithLabelValues("200").Add(3)
+	gauge.Set(7)
+	for _, v := range []float64{0.5, 1.5, 1.5, 5} {
+		histogram.Observe(v)
+	}
+
+	var received colmetricspb.ExportMetricsServiceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, proto.Unmarshal(body, &received))
+	}))
+	defer srv.Close()
+
+	e, err := otlpmetrics.NewExporter(srv.URL, map[string]string{"Authorization": "Bearer secret"}, map[string]string{"service.name": "keto"}, reg)
+	require.NoError(t, err)
+	require.NoError(t, e.Export(ctx))
+
+	require.Len(t, received.ResourceMetrics, 1)
+	rm := received.ResourceMetrics[0]
+	require.Len(t, rm.Resource.Attributes, 1)
+	assert.Equal(t, "service.name", rm.Resource.Attributes[0].Key)
+	assert.Equal(t, "keto", rm.Resource.Attributes[0].Value.GetStringValue())
+
+	metrics := map[string]*metricspb.Metric{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	require.Len(t, metrics, 3)
+
+	sum := metrics["test_requests_total"].GetSum()
+	require.NotNil(t, sum)
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.AggregationTemporality)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, 3.0, sum.DataPoints[0].GetAsDouble())
+	assert.Equal(t, "code", sum.DataPoints[0].Attributes[0].Key)
+	assert.Equal(t, "200", sum.DataPoints[0].Attributes[0].Value.GetStringValue())
+
+	g := metrics["test_connections"].GetGauge()
+	require.NotNil(t, g)
+	assert.Equal(t, 7.0, g.DataPoints[0].GetAsDouble())
+
+	h := metrics["test_duration_seconds"].GetHistogram()
+	require.NotNil(t, h)
+	require.Len(t, h.DataPoints, 1)
+	assert.Equal(t, uint64(4), h.DataPoints[0].Count)
+	assert.Equal(t, 8.5, h.DataPoints[0].GetSum())
+	assert.Equal(t, []float64{1, 2}, h.DataPoints[0].ExplicitBounds)
+	assert.Equal(t, []uint64{1, 2, 1}, h.DataPoints[0].BucketCounts, "the buckets are not cumulative")
+}
+
+func TestExporterErrors(t *testing.T) {
+	t.Run("case=unsupported scheme", func(t *testing.T) {
+		_, err := otlpmetrics.NewExporter("grpc://localhost:4317", nil, nil, prometheus.NewRegistry())
+		assert.Error(t, err)
+	})
+
+	t.Run("case=error response", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "nope", http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		e, err := otlpmetrics.NewExporter(srv.URL+"/custom", nil, nil, prometheus.NewRegistry())
+		require.NoError(t, err)
+		err = e.Export(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nope")
+	})
+}
@@ -0,0 +1,105 @@
+// Package ratelimit limits the rate of requests per key with token buckets.
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+const (
+	// cleanupInterval is how often the buckets of keys that have not been
+	// used for a while are dropped.
+	cleanupInterval = time.Minute
+	// maxKeys is the number of keys a limiter tracks at most. When it is
+	// reached, the bucket of the least recently used key is dropped, so
+	// that clients with many keys can not exhaust the memory.
+	maxKeys = 100_000
+)
+
+type (
+	// Limiter is a token bucket per key. Buckets that are full again are
+	// dropped, so that idle keys take no memory.
+	Limiter struct {
+		sync.Mutex
+		limit       config.RateLimit
+		maxKeys     int
+		buckets     map[string]*list.Element
+		lru         *list.List
+		lastCleanup time.Time
+	}
+	tokenBucket struct {
+		key     string
+		tokens  float64
+		updated time.Time
+	}
+)
+
+func NewLimiter(limit config.RateLimit) *Limiter {
+	return &Limiter{
+		limit:       limit,
+		maxKeys:     maxKeys,
+		buckets:     make(map[string]*list.Element),
+		lru:         list.New(),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Limit returns the limit of every key.
+func (l *Limiter) Limit() config.RateLimit {
+	return l.limit
+}
+
+func (b *tokenBucket) refill(now time.Time, limit config.RateLimit) {
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.RequestsPerSecond)
+	b.updated = now
+}
+
+func (l *Limiter) drop(e *list.Element) {
+	delete(l.buckets, e.Value.(*tokenBucket).key)
+	l.lru.Remove(e)
+}
+
+// Take takes a token from the bucket of the key. If there is none left, it
+// returns how long to wait for the next one.
+func (l *Limiter) Take(key string, now time.Time) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if now.Sub(l.lastCleanup) > cleanupInterval {
+		for _, e := range l.buckets {
+			b := e.Value.(*tokenBucket)
+			if b.refill(now, l.limit); b.tokens >= float64(l.limit.Burst) {
+				l.drop(e)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	e, ok := l.buckets[key]
+	if ok {
+		l.lru.MoveToFront(e)
+	} else {
+		if len(l.buckets) >= l.maxKeys {
+			l.drop(l.lru.Back())
+		}
+		e = l.lru.PushFront(&tokenBucket{key: key, tokens: float64(l.limit.Burst), updated: now})
+		l.buckets[key] = e
+	}
+	b := e.Value.(*tokenBucket)
+	b.refill(now, l.limit)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / l.limit.RequestsPerSecond * float64(time.Second)), false
+}
+
+// RetryAfter rounds the time to wait up to whole seconds, as sent in
+// Retry-After headers.
+func RetryAfter(wait time.Duration) time.Duration {
+	return time.Duration(math.Ceil(wait.Seconds())) * time.Second
+}
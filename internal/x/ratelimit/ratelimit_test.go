@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/keto/internal/driver/config"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	limit := config.RateLimit{RequestsPerSecond: 0.001, Burst: 1}
+
+	t.Run("case=limits per key", func(t *testing.T) {
+		l := NewLimiter(limit)
+		_, ok := l.Take("a", now)
+		assert.True(t, ok)
+		wait, ok := l.Take("a", now)
+		assert.False(t, ok)
+		assert.Positive(t, wait)
+		_, ok = l.Take("b", now)
+		assert.True(t, ok)
+	})
+
+	t.Run("case=drops the least recently used key", func(t *testing.T) {
+		l := NewLimiter(limit)
+		l.maxKeys = 3
+		for i := 0; i < 3; i++ {
+			_, ok := l.Take(strconv.Itoa(i), now)
+			assert.True(t, ok)
+		}
+		// 0 is used again, so 1 is the least recently used key.
+		_, ok := l.Take("0", now)
+		assert.False(t, ok)
+		_, ok = l.Take("3", now)
+		assert.True(t, ok)
+
+		assert.Len(t, l.buckets, 3)
+		assert.NotContains(t, l.buckets, "1")
+		_, ok = l.Take("0", now)
+		assert.False(t, ok)
+	})
+}
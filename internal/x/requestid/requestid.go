@@ -0,0 +1,95 @@
+// Package requestid correlates everything that happens for one request: the
+// ID is accepted from or returned in the X-Request-ID header, and attached to
+// log lines, traces, error responses, and SQL queries.
+package requestid
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Header is the HTTP header that carries the request ID.
+	Header = "X-Request-ID"
+	// MetadataKey is the gRPC metadata key that carries the request ID.
+	MetadataKey = "x-request-id"
+	// LogField is the field of the log lines and the attribute of the spans.
+	LogField = "request_id"
+
+	maxLength = 128
+)
+
+type ctxKey struct{}
+
+// NewContext returns a context that carries the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID of the context, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// New generates a request ID.
+func New() string {
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// Valid returns whether an ID sent by the client can be used. It is limited to
+// a safe set of characters, because the ID ends up in headers, logs, and SQL
+// comments.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FromClient returns the ID sent by the client if it is valid, or a new one.
+func FromClient(id string) string {
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// Start returns a context that carries the request ID, and records it on the
+// span of the context.
+func Start(ctx context.Context, id string) context.Context {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String(LogField, id))
+	return NewContext(ctx, id)
+}
+
+// LogHook adds the request ID to all log lines that are logged with a context
+// carrying one.
+type LogHook struct{}
+
+var _ logrus.Hook = LogHook{}
+
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (LogHook) Fire(e *logrus.Entry) error {
+	if e.Context == nil {
+		return nil
+	}
+	if id := FromContext(e.Context); id != "" {
+		e.Data[LogField] = id
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package requestid
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValid(t *testing.T) {
+	for id, valid := range map[string]bool{
+		New():                    true,
+		"trace:1234/abc+def==":   true,
+		"":                       false,
+		"with space":             false,
+		"*/ DROP TABLE x; --":    false,
+		"line\nbreak":            false,
+		strings.Repeat("a", 128): true,
+		strings.Repeat("a", 129): false,
+	} {
+		assert.Equal(t, valid, Valid(id), "%q", id)
+	}
+	assert.Equal(t, "client-id", FromClient("client-id"))
+	assert.True(t, Valid(FromClient("not valid")))
+}
+
+func TestLogHook(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	l.AddHook(LogHook{})
+
+	l.WithContext(NewContext(context.Background(), "the-id")).Info("with ID")
+	l.WithContext(context.Background()).Info("without ID")
+	l.Info("without context")
+
+	require.Len(t, hook.AllEntries(), 3)
+	assert.Equal(t, logrus.Fields{LogField: "the-id"}, hook.AllEntries()[0].Data)
+	assert.Empty(t, hook.AllEntries()[1].Data)
+	assert.Empty(t, hook.AllEntries()[2].Data)
+}
+
+type recordingConn struct {
+	driver.Conn
+	queries []string
+}
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.queries = append(c.queries, query)
+	return nil, nil
+}
+
+func TestCommentConn(t *testing.T) {
+	rc := &recordingConn{}
+	c := &commentConn{Conn: rc}
+
+	_, err := c.QueryContext(NewContext(context.Background(), "the-id"), "SELECT 1", nil)
+	require.NoError(t, err)
+	_, err = c.QueryContext(context.Background(), "SELECT 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/* request_id=the-id */ SELECT 1", "SELECT 2"}, rc.queries)
+
+	_, err = c.ExecContext(context.Background(), "DELETE FROM x", nil)
+	assert.ErrorIs(t, err, driver.ErrSkip, "the driver does not support ExecContext")
+}
@@ -0,0 +1,161 @@
+package requestid
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/luna-duclos/instrumentedsql"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	registerMx sync.Mutex
+
+	// baseDrivers maps the canonical pop dialects to the SQL drivers pop uses
+	// for them.
+	baseDrivers = map[string]string{
+		"postgres":  "pgx",
+		"cockroach": "pgx",
+		"mysql":     "mysql",
+		"sqlite3":   "sqlite3",
+	}
+)
+
+// Comment prefixes the query with an SQL comment that carries the request ID
+// of the context, so that it shows up in the query logs of the database.
+func Comment(ctx context.Context, query string) string {
+	id := FromContext(ctx)
+	if id == "" {
+		return query
+	}
+	return "/* " + LogField + "=" + id + " */ " + query
+}
+
+// RegisterSQLDriver registers an SQL driver that comments all queries with the
+// request ID, wrapping the driver pop uses for the dialect. The driver is
+// instrumented with the options, if any. It returns the name of the driver.
+func RegisterSQLDriver(dialect string, opts ...instrumentedsql.Opt) (string, error) {
+	base, ok := baseDrivers[dialect]
+	if !ok {
+		return "", errors.Errorf("SQL comments are not supported for the dialect %q", dialect)
+	}
+	name := "keto-request-id-" + base
+	if len(opts) > 0 {
+		name += "-instrumented"
+	}
+
+	registerMx.Lock()
+	defer registerMx.Unlock()
+	if slices.Contains(sql.Drivers(), name) {
+		return name, nil
+	}
+
+	db, err := sql.Open(base, "")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	var d driver.Driver = &commentDriver{Driver: db.Driver()}
+	if err := db.Close(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(opts) > 0 {
+		d = instrumentedsql.WrapDriver(d, opts...)
+	}
+	sql.Register(name, d)
+	sqlx.BindDriver(name, sqlx.BindType(base))
+	return name, nil
+}
+
+type (
+	commentDriver struct {
+		driver.Driver
+	}
+	commentConn struct {
+		driver.Conn
+	}
+)
+
+var (
+	_ driver.ConnPrepareContext = (*commentConn)(nil)
+	_ driver.ConnBeginTx        = (*commentConn)(nil)
+	_ driver.ExecerContext      = (*commentConn)(nil)
+	_ driver.QueryerContext     = (*commentConn)(nil)
+	_ driver.Pinger             = (*commentConn)(nil)
+	_ driver.SessionResetter    = (*commentConn)(nil)
+	_ driver.Validator          = (*commentConn)(nil)
+	_ driver.NamedValueChecker  = (*commentConn)(nil)
+)
+
+func (d *commentDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &commentConn{Conn: c}, nil
+}
+
+func (c *commentConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, Comment(ctx, query))
+	}
+	return c.Conn.Prepare(Comment(ctx, query))
+}
+
+func (c *commentConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		return nil, errors.New("the SQL driver does not support transaction options")
+	}
+	return c.Conn.Begin() //nolint:staticcheck // the fallback of database/sql
+}
+
+// ExecContext and QueryContext return driver.ErrSkip if the driver does not
+// support them, so that database/sql prepares the statement instead.
+
+func (c *commentConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, Comment(ctx, query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *commentConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, Comment(ctx, query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *commentConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *commentConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *commentConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *commentConn) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
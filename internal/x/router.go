@@ -11,4 +11,9 @@ type (
 	WriteRouter struct {
 		*httprouter.Router
 	}
+	// AdminRouter serves the schema and operational endpoints, either on the
+	// admin API or, if it is disabled, on the write API.
+	AdminRouter struct {
+		*httprouter.Router
+	}
 )
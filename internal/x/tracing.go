@@ -0,0 +1,15 @@
+//go:build !js
+
+package x
+
+import (
+	"context"
+
+	"github.com/ory/x/otelx"
+)
+
+// TracingProvider is not available in WebAssembly builds, because the
+// exporters of otelx do not build for js.
+type TracingProvider interface {
+	Tracer(ctx context.Context) *otelx.Tracer
+}
@@ -0,0 +1,65 @@
+package ketoapi
+
+import "time"
+
+// RelationTupleChange describes a single insert or delete of a relation tuple.
+//
+// swagger:model relationTupleChange
+type RelationTupleChange struct {
+	// Whether the relation tuple was inserted or deleted.
+	//
+	// required: true
+	Action PatchAction `json:"action"`
+
+	// The relation tuple that was changed.
+	//
+	// required: true
+	RelationTuple *RelationTuple `json:"relation_tuple"`
+
+	// The time the change was committed.
+	//
+	// required: true
+	Time time.Time `json:"time"`
+
+	// The tenant of the relation tuple. It is only set for published changes
+	// if multi-tenancy is enabled.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// swagger:model getRelationTupleHistoryResponse
+type GetHistoryResponse struct {
+	// The recorded changes, oldest first.
+	//
+	// required: true
+	Changes []*RelationTupleChange `json:"changes"`
+	// The opaque token to provide in a subsequent request
+	// to get the next page. It is the empty string iff this is
+	// the last page.
+	NextPageToken string `json:"next_page_token"`
+}
+
+const (
+	WatchEventRelationTuple WatchEventType = "relation_tuple"
+	WatchEventNamespaces    WatchEventType = "namespaces"
+)
+
+// WatchEventType is the type of an event sent by the watch endpoint.
+type WatchEventType string
+
+// WatchEvent is sent by the watch endpoint over WebSocket or as server-sent
+// event, either for a relation tuple change or for loaded namespaces.
+type WatchEvent struct {
+	// The type of the event.
+	Type WatchEventType `json:"type"`
+
+	// The cursor to resume watching after this change with. It is only set
+	// for relation tuple changes.
+	ID string `json:"id,omitempty"`
+
+	// The relation tuple change of a relation_tuple event.
+	Change *RelationTupleChange `json:"change,omitempty"`
+
+	// The names of the namespaces of a namespaces event. It is sent when
+	// watching starts and whenever the namespaces are reloaded.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
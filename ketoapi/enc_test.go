@@ -348,3 +348,59 @@ func TestRelationQuery(t *testing.T) {
 		}
 	})
 }
+
+func FuzzRelationTupleFromString(f *testing.F) {
+	for _, s := range []string{
+		"n:o#r@s",
+		"n:o#r@n:o#r",
+		"n:o#r@(n:o#r)",
+		"n:o#r@(n:o#)",
+		"n:o:o#r#r@s@s",
+		"n:o#r@",
+		":#@",
+		"n:o#r@n#r",
+		"no separators",
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		rt, err := (&RelationTuple{}).FromString(s)
+		if err != nil {
+			return
+		}
+		// The string representation of a parsed tuple parses to the same tuple.
+		again, err := (&RelationTuple{}).FromString(rt.String())
+		require.NoError(t, err)
+		assert.Equal(t, rt, again)
+	})
+}
+
+func FuzzRelationTupleFromURLQuery(f *testing.F) {
+	for _, s := range []string{
+		"namespace=n&object=o&relation=r&subject_id=s",
+		"namespace=n&object=o&relation=r&subject_set.namespace=n&subject_set.object=o&subject_set.relation=r",
+		"namespace=n&subject_id=s&subject_set.namespace=n",
+		"subject=n:o#r",
+		"namespace=%zz",
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		query, err := url.ParseQuery(s)
+		if err != nil {
+			return
+		}
+		if q, err := (&RelationQuery{}).FromURLQuery(query); err == nil {
+			again, err := (&RelationQuery{}).FromURLQuery(q.ToURLQuery())
+			require.NoError(t, err)
+			assert.Equal(t, q, again)
+		}
+		if rt, err := (&RelationTuple{}).FromURLQuery(query); err == nil {
+			again, err := (&RelationTuple{}).FromURLQuery(rt.ToURLQuery())
+			require.NoError(t, err)
+			assert.Equal(t, rt, again)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 
@@ -120,6 +121,283 @@ type GetResponse struct {
 	NextPageToken string `json:"next_page_token"`
 }
 
+// swagger:model getRelationTupleCountResponse
+type CountResponse struct {
+	// The number of relation tuples matching the query.
+	//
+	// required: true
+	Count int `json:"count"`
+	// Whether the count is an estimate of the database's query planner rather
+	// than an exact count.
+	//
+	// required: true
+	Estimated bool `json:"estimated"`
+}
+
+// swagger:model restoreRelationTuplesResponse
+type RestoreResponse struct {
+	// The number of restored relation tuples.
+	//
+	// required: true
+	Restored int `json:"restored"`
+}
+
+// swagger:model bulkDeleteRelationTuplesResponse
+type BulkDeleteResponse struct {
+	// The number of relation tuples deleted by this request.
+	//
+	// required: true
+	Deleted int `json:"deleted"`
+	// The number of relation tuples deleted since the first request of this
+	// bulk delete.
+	//
+	// required: true
+	TotalDeleted int `json:"total_deleted"`
+	// The opaque token to provide in a subsequent request to continue
+	// deleting. It is the empty string iff all relation tuples were deleted.
+	NextPageToken string `json:"next_page_token"`
+}
+
+// A namespace definition managed through the namespace administration API.
+//
+// swagger:model namespaceDefinition
+type NamespaceDefinition struct {
+	// The name of the namespace.
+	//
+	// required: true
+	Name string `json:"name"`
+	// The Ory Permission Language source of the namespace. It has to declare
+	// exactly the namespace with the given name.
+	//
+	// required: true
+	OPL string `json:"opl"`
+	// The version of the definition, which is incremented whenever it
+	// changes. It is also returned as the ETag header, and updates have to
+	// send it in the If-Match header.
+	Version int64 `json:"version,omitempty"`
+	// When the definition was created.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// When the definition was last updated.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// swagger:model listNamespaceDefinitionsResponse
+type ListNamespaceDefinitionsResponse struct {
+	// The namespace definitions, ordered by name.
+	//
+	// required: true
+	Definitions []*NamespaceDefinition `json:"definitions"`
+}
+
+// A schema migration rewrites the relation tuples of a namespace after its
+// schema changed, and bumps the schema version of the namespace.
+//
+// swagger:model schemaMigration
+type SchemaMigration struct {
+	// The namespace to migrate.
+	//
+	// required: true
+	Namespace string `json:"namespace"`
+	// The current schema version of the namespace. The migration is rejected
+	// if the namespace is at a different version, e.g. because another
+	// migration was applied in the meantime.
+	FromVersion int `json:"from_version"`
+	// A description of the change, which is recorded with the new version.
+	Description string `json:"description,omitempty"`
+	// The steps of the migration, which are applied in order.
+	//
+	// required: true
+	Steps []*SchemaMigrationStep `json:"steps"`
+}
+
+// A single step of a schema migration. Exactly one of the fields has to be
+// set.
+//
+// swagger:model schemaMigrationStep
+type SchemaMigrationStep struct {
+	Rename *RenameRelation `json:"rename,omitempty"`
+	Split  *SplitRelation  `json:"split,omitempty"`
+}
+
+// Renames a relation. Relation tuples with the relation and subject sets
+// referencing it are rewritten.
+//
+// swagger:model renameRelation
+type RenameRelation struct {
+	// required: true
+	From string `json:"from"`
+	// required: true
+	To string `json:"to"`
+}
+
+// Splits a relation into several relations by the type of the subjects.
+// Every relation tuple with the relation is moved to the first target that
+// matches its subject. Subject sets referencing the relation are replaced by
+// subject sets referencing every target relation.
+//
+// swagger:model splitRelation
+type SplitRelation struct {
+	// required: true
+	From string `json:"from"`
+	// required: true
+	Into []*SplitTarget `json:"into"`
+}
+
+// swagger:model splitTarget
+type SplitTarget struct {
+	// The relation to move the matching relation tuples to.
+	//
+	// required: true
+	Relation string `json:"relation"`
+	// Match relation tuples with a subject ID.
+	SubjectIDs bool `json:"subject_ids,omitempty"`
+	// Match relation tuples with a subject set in this namespace.
+	SubjectSetNamespace string `json:"subject_set_namespace,omitempty"`
+}
+
+// The result of planning or applying a schema migration.
+//
+// swagger:model schemaMigrationPlan
+type SchemaMigrationPlan struct {
+	// required: true
+	Namespace string `json:"namespace"`
+	// required: true
+	FromVersion int `json:"from_version"`
+	// required: true
+	ToVersion int `json:"to_version"`
+	// required: true
+	Steps []*SchemaMigrationStepPlan `json:"steps"`
+	// Issues that do not prevent the migration, e.g. target relations that
+	// are not declared in the namespace yet.
+	Warnings []string `json:"warnings,omitempty"`
+	// Whether the migration was applied, or only planned.
+	//
+	// required: true
+	Applied bool `json:"applied"`
+}
+
+// swagger:model schemaMigrationStepPlan
+type SchemaMigrationStepPlan struct {
+	// required: true
+	Description string `json:"description"`
+	// The number of relation tuples that are replaced.
+	//
+	// required: true
+	Rewritten int `json:"rewritten"`
+	// The number of relation tuples with the relation that no split target
+	// matches. They are left unchanged.
+	//
+	// required: true
+	Unmatched int `json:"unmatched"`
+}
+
+// swagger:model schemaVersion
+type SchemaVersion struct {
+	// required: true
+	Namespace string `json:"namespace"`
+	// required: true
+	Version int `json:"version"`
+	// required: true
+	Description string `json:"description"`
+	// required: true
+	Steps []*SchemaMigrationStep `json:"steps"`
+	// required: true
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// swagger:model listSchemaVersionsResponse
+type ListSchemaVersionsResponse struct {
+	// The current schema version of the namespace. It is 0 if no migration
+	// was applied yet.
+	//
+	// required: true
+	Current int `json:"current"`
+	// The applied migrations, oldest first.
+	//
+	// required: true
+	Versions []*SchemaVersion `json:"versions"`
+}
+
+// The state of a namespace rename.
+//
+// swagger:enum NamespaceRenameState
+type NamespaceRenameState string
+
+const (
+	// The relation tuples are being rewritten. Both names refer to the
+	// namespace.
+	NamespaceRenameRewriting NamespaceRenameState = "rewriting"
+	// All relation tuples use the new name. Both names still refer to the
+	// namespace until the rename is finished.
+	NamespaceRenameRewritten NamespaceRenameState = "rewritten"
+	// The rename is finished, and the old name is not known anymore.
+	NamespaceRenameCompleted NamespaceRenameState = "completed"
+)
+
+// A namespace rename moves the relation tuples of a namespace to its new name
+// in batches. Until it is finished, the old name is an alias of the new one,
+// so that checks and writes using either name keep working.
+//
+// swagger:model namespaceRename
+type NamespaceRename struct {
+	// The old name of the namespace.
+	//
+	// required: true
+	From string `json:"from"`
+	// The new name of the namespace.
+	//
+	// required: true
+	To string `json:"to"`
+	// required: true
+	State NamespaceRenameState `json:"state"`
+	// Whether the namespace definition managed through the namespace
+	// administration API was renamed, including the references to it in the
+	// other managed definitions.
+	//
+	// required: true
+	DefinitionRenamed bool `json:"definition_renamed"`
+	// The number of relation tuples that were rewritten so far.
+	//
+	// required: true
+	Rewritten int `json:"rewritten"`
+	// Batches are rejected before this time, so that all instances know the
+	// alias before the first relation tuple is rewritten.
+	//
+	// required: true
+	BatchesAllowedAt time.Time `json:"batches_allowed_at"`
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+	// required: true
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// swagger:model startNamespaceRename
+type StartNamespaceRename struct {
+	// The namespace to rename.
+	//
+	// required: true
+	From string `json:"from"`
+	// The new name of the namespace. Namespaces from the configuration have
+	// to be declared with the new name before they can be renamed.
+	//
+	// required: true
+	To string `json:"to"`
+}
+
+// swagger:model namespaceRenameBatch
+type NamespaceRenameBatch struct {
+	// The maximum number of relation tuples to rewrite. Defaults to 100.
+	BatchSize int `json:"batch_size,omitempty"`
+}
+
+// swagger:model listNamespaceRenamesResponse
+type ListNamespaceRenamesResponse struct {
+	// All namespace renames, oldest first.
+	//
+	// required: true
+	Renames []*NamespaceRename `json:"renames"`
+}
+
 func (r *RelationTuple) ToLoggerFields() logrus.Fields {
 	fields := make(logrus.Fields, 7)
 	q := r.ToURLQuery()
@@ -0,0 +1,182 @@
+package ketoapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+)
+
+// SnapshotFormatVersion is the version of the snapshot format written by this
+// version of Keto. Snapshots with a different version are rejected on import.
+const SnapshotFormatVersion = 2
+
+var (
+	ErrSnapshotTruncated        = herodot.ErrBadRequest.WithError("the snapshot ends without a trailer, it might be truncated")
+	ErrSnapshotChecksumMismatch = herodot.ErrBadRequest.WithError("the snapshot does not match its trailer")
+)
+
+// SnapshotHeader is the first line of a snapshot. It is followed by one JSON
+// encoded RelationTuple per line, and a SnapshotTrailer as last line.
+//
+// swagger:model snapshotHeader
+type SnapshotHeader struct {
+	// The version of the snapshot format.
+	//
+	// required: true
+	Version int `json:"version"`
+
+	// The time the snapshot was taken.
+	//
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+
+	// The namespaces that were configured when the snapshot was taken. Only
+	// set if the snapshot was requested to include namespaces.
+	Namespaces []*SnapshotNamespace `json:"namespaces,omitempty"`
+}
+
+// swagger:model snapshotNamespace
+type SnapshotNamespace struct {
+	// The name of the namespace.
+	//
+	// required: true
+	Name string `json:"name"`
+
+	// The configuration of the namespace.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// swagger:model snapshotImportResult
+type SnapshotImportResult struct {
+	// The number of imported relation tuples.
+	//
+	// required: true
+	Imported int `json:"imported"`
+}
+
+// SnapshotTrailer is the last line of a snapshot, wrapped in a "trailer"
+// object. It allows to detect truncated or altered snapshots.
+//
+// swagger:model snapshotTrailer
+type SnapshotTrailer struct {
+	// The number of relation tuples in the snapshot.
+	//
+	// required: true
+	Count int `json:"count"`
+
+	// The hex encoded SHA-256 checksum of all relation tuple lines, including
+	// their line breaks.
+	//
+	// required: true
+	Checksum string `json:"checksum"`
+}
+
+type snapshotTrailerLine struct {
+	Trailer *SnapshotTrailer `json:"trailer"`
+}
+
+// SnapshotChecksum computes the trailer of a snapshot from its relation tuple
+// lines.
+type SnapshotChecksum struct {
+	h     hash.Hash
+	count int
+}
+
+func NewSnapshotChecksum() *SnapshotChecksum {
+	return &SnapshotChecksum{h: sha256.New()}
+}
+
+// Add adds the JSON encoded relation tuple line, without its line break.
+func (c *SnapshotChecksum) Add(line []byte) {
+	_, _ = c.h.Write(bytes.TrimSpace(line))
+	_, _ = c.h.Write([]byte{'\n'})
+	c.count++
+}
+
+func (c *SnapshotChecksum) Trailer() *SnapshotTrailer {
+	return &SnapshotTrailer{Count: c.count, Checksum: hex.EncodeToString(c.h.Sum(nil))}
+}
+
+// Verify returns an error if the trailer does not match the added lines.
+func (c *SnapshotChecksum) Verify(t *SnapshotTrailer) error {
+	if expected := c.Trailer(); *expected != *t {
+		return errors.WithStack(ErrSnapshotChecksumMismatch.WithDetail("expected_count", t.Count).WithDetail("actual_count", expected.Count))
+	}
+	return nil
+}
+
+// EncodeSnapshotTrailer returns the trailer line, without its line break.
+func EncodeSnapshotTrailer(t *SnapshotTrailer) ([]byte, error) {
+	b, err := json.Marshal(&snapshotTrailerLine{Trailer: t})
+	return b, errors.WithStack(err)
+}
+
+// DecodeSnapshotTrailer returns the trailer if the line is one.
+func DecodeSnapshotTrailer(line []byte) (*SnapshotTrailer, bool) {
+	var l snapshotTrailerLine
+	if err := json.Unmarshal(line, &l); err != nil || l.Trailer == nil {
+		return nil, false
+	}
+	return l.Trailer, true
+}
+
+// SnapshotReader reads the relation tuples of a snapshot, and verifies them
+// against its trailer.
+type SnapshotReader struct {
+	dec *json.Decoder
+	sum *SnapshotChecksum
+}
+
+func NewSnapshotReader(r io.Reader) *SnapshotReader {
+	return &SnapshotReader{dec: json.NewDecoder(r), sum: NewSnapshotChecksum()}
+}
+
+// Header reads the header of the snapshot. It has to be called first, and
+// rejects snapshots of other versions.
+func (r *SnapshotReader) Header() (*SnapshotHeader, error) {
+	var header SnapshotHeader
+	if err := r.dec.Decode(&header); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not decode snapshot header: %s", err))
+	}
+	if header.Version != SnapshotFormatVersion {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf(
+			"unsupported snapshot version %d, expected %d", header.Version, SnapshotFormatVersion))
+	}
+	return &header, nil
+}
+
+// Next returns the next relation tuple. It returns io.EOF once the trailer was
+// read and matches the relation tuples, and an error if it does not match or
+// the snapshot ends without one.
+func (r *SnapshotReader) Next() (*RelationTuple, error) {
+	var line json.RawMessage
+	if err := r.dec.Decode(&line); errors.Is(err, io.EOF) {
+		return nil, errors.WithStack(ErrSnapshotTruncated)
+	} else if err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not decode line %d of the snapshot: %s", r.sum.count+2, err))
+	}
+
+	if t, ok := DecodeSnapshotTrailer(line); ok {
+		if err := r.sum.Verify(t); err != nil {
+			return nil, err
+		}
+		if r.dec.More() {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithError("the snapshot continues after its trailer"))
+		}
+		return nil, io.EOF
+	}
+
+	var rt RelationTuple
+	if err := json.Unmarshal(line, &rt); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithErrorf("could not decode relation tuple %d: %s", r.sum.count+1, err))
+	}
+	r.sum.Add(line)
+	return &rt, nil
+}
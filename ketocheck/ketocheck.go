@@ -0,0 +1,193 @@
+// Package ketocheck evaluates checks with the permission engine of Ory Keto,
+// without a database or a server. The namespaces are compiled from an Ory
+// Permission Language source, and the relation tuples come from a pluggable
+// TupleSource, e.g. a local replica of the relation tuples of a cluster.
+//
+// Unlike the rest of Keto, this package builds for WebAssembly
+// (GOOS=js GOARCH=wasm), so that permission models can be evaluated in
+// browsers and edge workers. contrib/wasm exposes it to JavaScript.
+package ketocheck
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/logrusx"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// Checker checks relation tuples against the namespaces and the relation
+	// tuples of its source. It is safe for concurrent use.
+	Checker struct {
+		e          *check.Engine
+		m          *manager
+		namespaces namespaces
+	}
+	Option func(d *dependencies)
+
+	// dependencies are the dependencies of the engine, which the registry
+	// provides in the server.
+	dependencies struct {
+		l        *logrusx.Logger
+		m        *manager
+		maxDepth int
+	}
+	// config is the configuration of the engine.
+	config struct {
+		namespaces namespaces
+		maxDepth   int
+	}
+	namespaces map[string]*namespace.Namespace
+)
+
+// DefaultMaxDepth is the maximum depth of checks by default, like the
+// limit.max_read_depth of the server.
+const DefaultMaxDepth = 5
+
+var (
+	_ check.EngineDependencies = (*dependencies)(nil)
+	_ check.Config             = (*config)(nil)
+	_ namespace.Manager        = (namespaces)(nil)
+)
+
+// WithMaxDepth sets the maximum depth of checks, which requests can only
+// lower.
+func WithMaxDepth(depth int) Option {
+	return func(d *dependencies) {
+		d.maxDepth = depth
+	}
+}
+
+// WithLogger sets the logger, which only logs errors by default.
+func WithLogger(l *logrusx.Logger) Option {
+	return func(d *dependencies) {
+		d.l = l
+	}
+}
+
+// Compile compiles the Ory Permission Language source and returns the names
+// of its namespaces, or all errors in the source.
+func Compile(opl string) ([]string, []error) {
+	nn, errs := compile(opl)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	names := make([]string, 0, len(nn))
+	for name := range nn {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// NewChecker returns a checker of the namespaces of the Ory Permission
+// Language source against the relation tuples of the source. It fails with the
+// first error in the source.
+func NewChecker(opl string, source TupleSource, opts ...Option) (*Checker, error) {
+	nn, errs := compile(opl)
+	if len(errs) > 0 {
+		return nil, errors.Wrap(errs[0], "could not parse the Ory Permission Language source")
+	}
+
+	d := &dependencies{maxDepth: DefaultMaxDepth, m: newManager(source)}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.l == nil {
+		d.l = logrusx.New("Ory Keto", "", logrusx.ForceLevel(logrus.ErrorLevel))
+	}
+	c := &config{namespaces: nn, maxDepth: d.maxDepth}
+	return &Checker{
+		e:          check.NewEngine(d, check.WithConfig(func(context.Context) check.Config { return c })),
+		m:          d.m,
+		namespaces: nn,
+	}, nil
+}
+
+// Check returns whether the subject of the relation tuple is related to the
+// object, either directly or through the rewrites of the namespace. Relation
+// tuples of unknown namespaces are never allowed, like in the check API of
+// the server. A maximum depth of 0 means the configured one.
+func (c *Checker) Check(ctx context.Context, tuple *ketoapi.RelationTuple, maxDepth int) (bool, error) {
+	if _, ok := c.namespaces[tuple.Namespace]; !ok {
+		return false, nil
+	}
+	switch {
+	case tuple.SubjectID == nil && tuple.SubjectSet == nil:
+		return false, errors.WithStack(ketoapi.ErrNilSubject)
+	case tuple.SubjectID != nil && tuple.SubjectSet != nil:
+		return false, errors.WithStack(ketoapi.ErrDuplicateSubject)
+	}
+	return c.e.CheckIsMember(ctx, c.m.fromTuple(tuple), maxDepth)
+}
+
+func compile(opl string) (namespaces, []error) {
+	parsed, errs := schema.Parse(opl)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	nn := make(namespaces, len(parsed))
+	for i := range parsed {
+		nn[parsed[i].Name] = &parsed[i]
+	}
+	return nn, nil
+}
+
+func (d *dependencies) Logger() *logrusx.Logger {
+	return d.l
+}
+
+func (d *dependencies) RelationTupleManager() relationtuple.Manager {
+	return d.m
+}
+
+func (c *config) MaxReadDepth() int {
+	return c.maxDepth
+}
+
+func (c *config) NamespaceManager() (namespace.Manager, error) {
+	return c.namespaces, nil
+}
+
+// SlowChecksThreshold is 0, as there is no operator that reads the logs.
+func (c *config) SlowChecksThreshold() time.Duration {
+	return 0
+}
+
+func (c *config) SlowChecksIncludeTree() bool {
+	return false
+}
+
+func (nn namespaces) GetNamespaceByName(_ context.Context, name string) (*namespace.Namespace, error) {
+	n, ok := nn[name]
+	if !ok {
+		return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("Unknown namespace with name %q.", name))
+	}
+	return n, nil
+}
+
+func (nn namespaces) GetNamespaceByConfigID(_ context.Context, id int32) (*namespace.Namespace, error) {
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("Unknown namespace with id %d.", id))
+}
+
+func (nn namespaces) Namespaces(context.Context) ([]*namespace.Namespace, error) {
+	res := make([]*namespace.Namespace, 0, len(nn))
+	for _, n := range nn {
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+func (nn namespaces) ShouldReload(interface{}) bool {
+	return false
+}
@@ -0,0 +1,85 @@
+package ketocheck_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketocheck"
+	"github.com/ory/keto/ketodataset"
+)
+
+// funcSource is a TupleSource that calls the function.
+type funcSource func(q *ketoapi.RelationQuery) ([]*ketoapi.RelationTuple, error)
+
+func (f funcSource) RelationTuples(_ context.Context, q *ketoapi.RelationQuery) ([]*ketoapi.RelationTuple, error) {
+	return f(q)
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=dataset", func(t *testing.T) {
+		d, err := ketodataset.Generate(ketodataset.Config{Seed: 42, Documents: 20, Users: 20, Groups: 3, Depth: 2, FanOut: 3, WildcardRatio: 0.2})
+		require.NoError(t, err)
+		c, err := ketocheck.NewChecker(d.OPL(), ketocheck.Tuples(d.Tuples), ketocheck.WithMaxDepth(10))
+		require.NoError(t, err)
+
+		for _, check := range d.Checks {
+			allowed, err := c.Check(ctx, check.Tuple, 0)
+			require.NoError(t, err)
+			assert.Equal(t, check.Allowed, allowed, check.Tuple.String())
+		}
+	})
+
+	t.Run("case=pluggable source", func(t *testing.T) {
+		tuple := &ketoapi.RelationTuple{Namespace: "Group", Object: "dev", Relation: "members", SubjectID: x.Ptr("alice")}
+		var (
+			mx      sync.Mutex
+			queries []*ketoapi.RelationQuery
+		)
+		c, err := ketocheck.NewChecker(`class User implements Namespace {}
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}`, funcSource(func(q *ketoapi.RelationQuery) ([]*ketoapi.RelationTuple, error) {
+			mx.Lock()
+			queries = append(queries, q)
+			mx.Unlock()
+			return ketocheck.Tuples{tuple}.RelationTuples(ctx, q)
+		}))
+		require.NoError(t, err)
+
+		allowed, err := c.Check(ctx, tuple, 0)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		mx.Lock()
+		require.NotEmpty(t, queries)
+		assert.Equal(t, "dev", *queries[0].Object)
+		mx.Unlock()
+
+		allowed, err = c.Check(ctx, &ketoapi.RelationTuple{Namespace: "Unknown", Object: "o", Relation: "r", SubjectID: x.Ptr("alice")}, 0)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		_, err = c.Check(ctx, &ketoapi.RelationTuple{Namespace: "Group", Object: "dev", Relation: "members"}, 0)
+		assert.ErrorIs(t, err, ketoapi.ErrNilSubject)
+	})
+
+	t.Run("case=compile", func(t *testing.T) {
+		names, errs := ketocheck.Compile("class User implements Namespace {}\nclass Group implements Namespace {}")
+		assert.Empty(t, errs)
+		assert.Equal(t, []string{"Group", "User"}, names)
+
+		_, errs = ketocheck.Compile("class {")
+		assert.NotEmpty(t, errs)
+		_, err := ketocheck.NewChecker("class {", ketocheck.Tuples(nil))
+		assert.ErrorContains(t, err, "could not parse the Ory Permission Language source")
+	})
+}
@@ -0,0 +1,145 @@
+package ketocheck
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/keto/internal/relationtuple"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+type (
+	// TupleSource provides the relation tuples that checks are evaluated
+	// against. It returns all relation tuples that match the query, where
+	// fields that are nil match every value.
+	TupleSource interface {
+		RelationTuples(ctx context.Context, query *ketoapi.RelationQuery) ([]*ketoapi.RelationTuple, error)
+	}
+	// Tuples is a TupleSource of relation tuples in memory.
+	Tuples []*ketoapi.RelationTuple
+
+	// manager reads the relation tuples of the engine from the source. The
+	// engine only reads, so the other methods of the interface are not
+	// implemented.
+	manager struct {
+		relationtuple.Manager
+		source TupleSource
+
+		// names maps the UUIDs of the engine back to the names of objects
+		// and subjects.
+		mx    sync.RWMutex
+		names map[uuid.UUID]string
+	}
+)
+
+// ErrReadOnly is returned when the engine would write relation tuples.
+var ErrReadOnly = errors.New("the relation tuples of ketocheck are read-only")
+
+var _ TupleSource = Tuples(nil)
+
+func (tt Tuples) RelationTuples(_ context.Context, query *ketoapi.RelationQuery) ([]*ketoapi.RelationTuple, error) {
+	var res []*ketoapi.RelationTuple
+	for _, t := range tt {
+		if matches(query, t) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+func matches(q *ketoapi.RelationQuery, t *ketoapi.RelationTuple) bool {
+	switch {
+	case q.Namespace != nil && *q.Namespace != t.Namespace,
+		q.Object != nil && *q.Object != t.Object,
+		q.Relation != nil && *q.Relation != t.Relation,
+		q.SubjectID != nil && (t.SubjectID == nil || *q.SubjectID != *t.SubjectID),
+		q.SubjectSet != nil && (t.SubjectSet == nil || *q.SubjectSet != *t.SubjectSet):
+		return false
+	}
+	return true
+}
+
+func newManager(source TupleSource) *manager {
+	return &manager{source: source, names: make(map[uuid.UUID]string)}
+}
+
+// GetRelationTuples returns all relation tuples of the source that match the
+// query on one page.
+func (m *manager) GetRelationTuples(ctx context.Context, query *relationtuple.RelationQuery, _ ...x.PaginationOptionSetter) ([]*relationtuple.RelationTuple, string, error) {
+	tuples, err := m.source.RelationTuples(ctx, m.toQuery(query))
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]*relationtuple.RelationTuple, 0, len(tuples))
+	for _, t := range tuples {
+		if t.SubjectID == nil && t.SubjectSet == nil {
+			return nil, "", errors.WithStack(ketoapi.ErrNilSubject)
+		}
+		res = append(res, m.fromTuple(t))
+	}
+	return res, "", nil
+}
+
+func (m *manager) WriteRelationTuples(context.Context, ...*relationtuple.RelationTuple) error {
+	return errors.WithStack(ErrReadOnly)
+}
+
+func (m *manager) TransactRelationTuples(context.Context, []*relationtuple.RelationTuple, []*relationtuple.RelationTuple) error {
+	return errors.WithStack(ErrReadOnly)
+}
+
+// id returns the UUID of the name, which the engine uses instead of the name.
+func (m *manager) id(name string) uuid.UUID {
+	id := uuid.NewV5(uuid.Nil, name)
+	m.mx.RLock()
+	_, ok := m.names[id]
+	m.mx.RUnlock()
+	if !ok {
+		m.mx.Lock()
+		m.names[id] = name
+		m.mx.Unlock()
+	}
+	return id
+}
+
+func (m *manager) name(id uuid.UUID) string {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+	return m.names[id]
+}
+
+func (m *manager) fromTuple(t *ketoapi.RelationTuple) *relationtuple.RelationTuple {
+	res := &relationtuple.RelationTuple{
+		Namespace: t.Namespace,
+		Object:    m.id(t.Object),
+		Relation:  t.Relation,
+	}
+	if t.SubjectID != nil {
+		res.Subject = &relationtuple.SubjectID{ID: m.id(*t.SubjectID)}
+	} else {
+		res.Subject = &relationtuple.SubjectSet{
+			Namespace: t.SubjectSet.Namespace,
+			Object:    m.id(t.SubjectSet.Object),
+			Relation:  t.SubjectSet.Relation,
+		}
+	}
+	return res
+}
+
+func (m *manager) toQuery(q *relationtuple.RelationQuery) *ketoapi.RelationQuery {
+	res := &ketoapi.RelationQuery{Namespace: q.Namespace, Relation: q.Relation}
+	if q.Object != nil {
+		res.Object = x.Ptr(m.name(*q.Object))
+	}
+	switch s := q.Subject.(type) {
+	case *relationtuple.SubjectID:
+		res.SubjectID = x.Ptr(m.name(s.ID))
+	case *relationtuple.SubjectSet:
+		res.SubjectSet = &ketoapi.SubjectSet{Namespace: s.Namespace, Object: m.name(s.Object), Relation: s.Relation}
+	}
+	return res
+}
@@ -0,0 +1,73 @@
+package ketoctx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+type (
+	// GRPCInterceptors are added to the gRPC server of an API after the
+	// request was authenticated, and before the meta-permissions are checked.
+	// They can, for example, extract the tenant of the authenticated subject
+	// or reject requests.
+	GRPCInterceptors struct {
+		Unary  []grpc.UnaryServerInterceptor
+		Stream []grpc.StreamServerInterceptor
+	}
+
+	// GRPCInterceptorPlugin returns the interceptors of the API, either
+	// "read" or "write". The config is the plugin configuration of
+	// serve.grpc.interceptors, or nil if it has none.
+	GRPCInterceptorPlugin func(ctx context.Context, api string, config json.RawMessage) (*GRPCInterceptors, error)
+)
+
+var (
+	grpcInterceptorPluginsMx sync.RWMutex
+	grpcInterceptorPlugins   = make(map[string]GRPCInterceptorPlugin)
+)
+
+// RegisterGRPCInterceptorPlugin makes the plugin available under the name, so
+// that it can be enabled in serve.grpc.interceptors without embedding Ory
+// Keto. It is usually called in an init function of a package that a custom
+// build imports. It panics if a plugin with the same name was registered
+// already.
+func RegisterGRPCInterceptorPlugin(name string, plugin GRPCInterceptorPlugin) {
+	grpcInterceptorPluginsMx.Lock()
+	defer grpcInterceptorPluginsMx.Unlock()
+
+	if plugin == nil {
+		panic("ketoctx: the gRPC interceptor plugin is nil")
+	}
+	if _, ok := grpcInterceptorPlugins[name]; ok {
+		panic(fmt.Sprintf("ketoctx: the gRPC interceptor plugin %q is registered twice", name))
+	}
+	grpcInterceptorPlugins[name] = plugin
+}
+
+// LookupGRPCInterceptorPlugin returns the plugin registered under the name.
+func LookupGRPCInterceptorPlugin(name string) (GRPCInterceptorPlugin, bool) {
+	grpcInterceptorPluginsMx.RLock()
+	defer grpcInterceptorPluginsMx.RUnlock()
+
+	p, ok := grpcInterceptorPlugins[name]
+	return p, ok
+}
+
+// GRPCInterceptorPluginNames returns the sorted names of the registered
+// plugins.
+func GRPCInterceptorPluginNames() []string {
+	grpcInterceptorPluginsMx.RLock()
+	defer grpcInterceptorPluginsMx.RUnlock()
+
+	names := make([]string, 0, len(grpcInterceptorPlugins))
+	for n := range grpcInterceptorPlugins {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,26 @@
+package ketoctx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGRPCInterceptorPlugins(t *testing.T) {
+	plugin := func(context.Context, string, json.RawMessage) (*GRPCInterceptors, error) {
+		return &GRPCInterceptors{}, nil
+	}
+
+	_, ok := LookupGRPCInterceptorPlugin("ketoctx-test")
+	assert.False(t, ok)
+
+	RegisterGRPCInterceptorPlugin("ketoctx-test", plugin)
+	_, ok = LookupGRPCInterceptorPlugin("ketoctx-test")
+	assert.True(t, ok)
+	assert.Contains(t, GRPCInterceptorPluginNames(), "ketoctx-test")
+
+	assert.Panics(t, func() { RegisterGRPCInterceptorPlugin("ketoctx-test", plugin) })
+	assert.Panics(t, func() { RegisterGRPCInterceptorPlugin("ketoctx-nil", nil) })
+}
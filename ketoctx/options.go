@@ -14,6 +14,7 @@ type (
 		httpMiddlewares        []func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc)
 		grpcUnaryInterceptors  []grpc.UnaryServerInterceptor
 		grpcStreamInterceptors []grpc.StreamServerInterceptor
+		grpcAPIInterceptors    map[string]GRPCInterceptors
 	}
 	Option func(o *opts)
 )
@@ -48,6 +49,28 @@ func WithGRPCStreamInterceptors(i ...grpc.StreamServerInterceptor) Option {
 	}
 }
 
+// WithGRPCInterceptors adds interceptors to the gRPC servers of the given
+// APIs, "read" and "write", or of both if none are given. Unlike the
+// interceptors of WithGRPCUnaryInterceptors and WithGRPCStreamInterceptors,
+// they are called after the request was authenticated. The option can be
+// given more than once.
+func WithGRPCInterceptors(i GRPCInterceptors, apis ...string) Option {
+	return func(o *opts) {
+		if len(apis) == 0 {
+			apis = []string{"read", "write"}
+		}
+		if o.grpcAPIInterceptors == nil {
+			o.grpcAPIInterceptors = make(map[string]GRPCInterceptors)
+		}
+		for _, api := range apis {
+			is := o.grpcAPIInterceptors[api]
+			is.Unary = append(is.Unary, i.Unary...)
+			is.Stream = append(is.Stream, i.Stream...)
+			o.grpcAPIInterceptors[api] = is
+		}
+	}
+}
+
 func (o *opts) Logger() *logrusx.Logger {
 	return o.logger
 }
@@ -68,6 +91,12 @@ func (o *opts) GRPCStreamInterceptors() []grpc.StreamServerInterceptor {
 	return o.grpcStreamInterceptors
 }
 
+// GRPCInterceptors returns the interceptors added to the gRPC server of the
+// API with WithGRPCInterceptors.
+func (o *opts) GRPCInterceptors(api string) GRPCInterceptors {
+	return o.grpcAPIInterceptors[api]
+}
+
 func Options(options ...Option) *opts {
 	o := &opts{
 		contextualizer: &DefaultContextualizer{},
@@ -1,9 +1,11 @@
 package ketoctx
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 )
 
 func TestOptions(t *testing.T) {
@@ -20,4 +22,25 @@ func TestOptions(t *testing.T) {
 		opts := Options(WithContextualizer(ctxer))
 		assert.Equal(t, ctxer, opts.Contextualizer())
 	})
+
+	t.Run("case=adds gRPC interceptors by API", func(t *testing.T) {
+		var called []string
+		unary := func(name string) grpc.UnaryServerInterceptor {
+			return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				called = append(called, name)
+				return handler(ctx, req)
+			}
+		}
+
+		opts := Options(
+			WithGRPCInterceptors(GRPCInterceptors{Unary: []grpc.UnaryServerInterceptor{unary("both")}}),
+			WithGRPCInterceptors(GRPCInterceptors{Unary: []grpc.UnaryServerInterceptor{unary("write")}}, "write"),
+		)
+		for _, i := range opts.GRPCInterceptors("write").Unary {
+			_, _ = i(context.Background(), nil, nil, func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+		}
+		assert.Equal(t, []string{"both", "write"}, called)
+		assert.Len(t, opts.GRPCInterceptors("read").Unary, 1)
+		assert.Empty(t, opts.GRPCInterceptors("read").Stream)
+	})
 }
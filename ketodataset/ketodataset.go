@@ -0,0 +1,294 @@
+// Package ketodataset deterministically generates synthetic permission models
+// of documents, nested groups, and users, together with the expected results
+// of checks against them. The same configuration always results in the same
+// namespaces, relation tuples, and checks, independent of the machine, so
+// that performance regressions can be reproduced exactly.
+package ketodataset
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+)
+
+// Everyone is the group that contains all users. Keto has no wildcard
+// subjects, so documents that are visible to everyone have its members as
+// viewers.
+const Everyone = "everyone"
+
+type (
+	// Config describes a dataset. Every group has FanOut users as members,
+	// and groups up to Depth levels below the Groups top-level groups also
+	// have FanOut nested groups. Every document has FanOut viewers, each
+	// either a user or the members of a group.
+	Config struct {
+		// Seed is the seed of the random source.
+		Seed int64
+		// Documents is the number of documents, at least 1.
+		Documents int
+		// Users is the number of users the members and viewers are chosen
+		// from, at least 1.
+		Users int
+		// Groups is the number of top-level groups.
+		Groups int
+		// Depth is the number of levels of nested groups below every
+		// top-level group.
+		Depth int
+		// FanOut is the number of users and nested groups of every group, and
+		// of viewers of every document, at least 1.
+		FanOut int
+		// WildcardRatio is the share of documents that are visible to
+		// everyone, between 0 and 1.
+		WildcardRatio float64
+
+		// DocumentNamespace, GroupNamespace, and UserNamespace are the
+		// namespaces of the objects, "Document", "Group", and "User" by
+		// default. Documents and groups can share a namespace.
+		DocumentNamespace, GroupNamespace, UserNamespace string
+		// ViewersRelation and MembersRelation are the relations of the
+		// documents and groups, "viewers" and "members" by default.
+		ViewersRelation, MembersRelation string
+	}
+	// Dataset is a generated dataset.
+	Dataset struct {
+		// Namespaces are the namespaces of the model, in the order they have
+		// to be created.
+		Namespaces []*Namespace
+		Tuples     []*ketoapi.RelationTuple
+		// Checks are one allowed and one denied check per document. The
+		// denied check is left out if the document is visible to all users.
+		Checks []*Check
+
+		// classes are the class declarations of the namespaces.
+		classes []string
+	}
+	// Namespace is a namespace of the model and its Ory Permission Language
+	// source, which imports the other namespaces by their relative paths.
+	Namespace struct {
+		Name string
+		OPL  string
+	}
+	// Check is a relation tuple and whether it is expected to be allowed.
+	Check struct {
+		Tuple   *ketoapi.RelationTuple
+		Allowed bool
+	}
+
+	// groupNode is a generated group and its direct members.
+	groupNode struct {
+		name     string
+		users    []int
+		children []*groupNode
+	}
+)
+
+func Document(i int) string { return "doc-" + strconv.Itoa(i) }
+func Group(i int) string    { return "group-" + strconv.Itoa(i) }
+func User(i int) string     { return "user-" + strconv.Itoa(i) }
+
+// WithDefaults returns the configuration with the default namespaces and
+// relations set.
+func (c Config) WithDefaults() Config {
+	for _, d := range []struct {
+		field *string
+		value string
+	}{
+		{&c.DocumentNamespace, "Document"},
+		{&c.GroupNamespace, "Group"},
+		{&c.UserNamespace, "User"},
+		{&c.ViewersRelation, "viewers"},
+		{&c.MembersRelation, "members"},
+	} {
+		if *d.field == "" {
+			*d.field = d.value
+		}
+	}
+	return c
+}
+
+// Validate returns an error if the configuration does not describe a
+// dataset.
+func (c Config) Validate() error {
+	switch {
+	case c.Documents < 1:
+		return fmt.Errorf("the number of documents must be at least 1")
+	case c.Users < 1:
+		return fmt.Errorf("the number of users must be at least 1")
+	case c.Groups < 0:
+		return fmt.Errorf("the number of groups must not be negative")
+	case c.Depth < 0:
+		return fmt.Errorf("the depth must not be negative")
+	case c.FanOut < 1:
+		return fmt.Errorf("the fan-out must be at least 1")
+	case c.WildcardRatio < 0 || c.WildcardRatio > 1:
+		return fmt.Errorf("the wildcard ratio must be between 0 and 1")
+	}
+	c = c.WithDefaults()
+	if c.UserNamespace == c.DocumentNamespace || c.UserNamespace == c.GroupNamespace {
+		return fmt.Errorf("the user namespace must differ from the document and group namespaces")
+	}
+	if c.DocumentNamespace == c.GroupNamespace && c.ViewersRelation != c.MembersRelation {
+		return fmt.Errorf("documents and groups in the same namespace must have the same relation")
+	}
+	return nil
+}
+
+// Generate generates the dataset. Only the standard library's seeded random
+// source is used, and maps are never iterated in random order, so the result
+// only depends on the configuration.
+func Generate(c Config) (*Dataset, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	c = c.WithDefaults()
+
+	r := rand.New(rand.NewSource(c.Seed))
+	d := &Dataset{}
+	d.Namespaces, d.classes = c.namespaces()
+	var groups []*groupNode
+
+	var nest func(name string, level int) *groupNode
+	nest = func(name string, level int) *groupNode {
+		gr := &groupNode{name: name}
+		groups = append(groups, gr)
+		for j := 0; j < c.FanOut; j++ {
+			u := r.Intn(c.Users)
+			gr.users = append(gr.users, u)
+			d.Tuples = append(d.Tuples, c.member(name, u))
+		}
+		if level < c.Depth {
+			for j := 0; j < c.FanOut; j++ {
+				child := name + "." + strconv.Itoa(j)
+				d.Tuples = append(d.Tuples, &ketoapi.RelationTuple{Namespace: c.GroupNamespace, Object: name, Relation: c.MembersRelation, SubjectSet: c.membersOf(child)})
+				gr.children = append(gr.children, nest(child, level+1))
+			}
+		}
+		return gr
+	}
+	for i := 0; i < c.Groups; i++ {
+		nest(Group(i), 0)
+	}
+
+	public := false
+	for i := 0; i < c.Documents; i++ {
+		doc := Document(i)
+		viewers := make(map[int]struct{})
+		for j := 0; j < c.FanOut; j++ {
+			if len(groups) > 0 && r.Intn(2) == 0 {
+				gr := groups[r.Intn(len(groups))]
+				d.Tuples = append(d.Tuples, &ketoapi.RelationTuple{Namespace: c.DocumentNamespace, Object: doc, Relation: c.ViewersRelation, SubjectSet: c.membersOf(gr.name)})
+				gr.addMembers(viewers)
+				continue
+			}
+			u := r.Intn(c.Users)
+			d.Tuples = append(d.Tuples, c.viewer(doc, u))
+			viewers[u] = struct{}{}
+		}
+
+		if r.Float64() < c.WildcardRatio {
+			public = true
+			d.Tuples = append(d.Tuples, &ketoapi.RelationTuple{Namespace: c.DocumentNamespace, Object: doc, Relation: c.ViewersRelation, SubjectSet: c.membersOf(Everyone)})
+			d.Checks = append(d.Checks, &Check{Tuple: c.viewer(doc, r.Intn(c.Users)), Allowed: true})
+			continue
+		}
+
+		// The viewers are sorted so that the checks do not depend on the
+		// iteration order of the map.
+		allowed := make([]int, 0, len(viewers))
+		for u := range viewers {
+			allowed = append(allowed, u)
+		}
+		sort.Ints(allowed)
+		d.Checks = append(d.Checks, &Check{Tuple: c.viewer(doc, allowed[r.Intn(len(allowed))]), Allowed: true})
+		if len(viewers) < c.Users {
+			u := r.Intn(c.Users)
+			for ; ; u = (u + 1) % c.Users {
+				if _, ok := viewers[u]; !ok {
+					break
+				}
+			}
+			d.Checks = append(d.Checks, &Check{Tuple: c.viewer(doc, u), Allowed: false})
+		}
+	}
+
+	if public {
+		for u := 0; u < c.Users; u++ {
+			d.Tuples = append(d.Tuples, c.member(Everyone, u))
+		}
+	}
+	return d, nil
+}
+
+// OPL returns the Ory Permission Language source of all namespaces in one
+// file.
+func (d *Dataset) OPL() string {
+	var sb strings.Builder
+	sb.WriteString(`import { Namespace, SubjectSet } from "@ory/keto-namespace-types"` + "\n")
+	for _, class := range d.classes {
+		sb.WriteString("\n")
+		sb.WriteString(class)
+	}
+	return sb.String()
+}
+
+// namespaces returns the namespaces with their imports, and their class
+// declarations.
+func (c Config) namespaces() ([]*Namespace, []string) {
+	members := fmt.Sprintf(`(%s | SubjectSet<%s, "%s">)[]`, c.UserNamespace, c.GroupNamespace, c.MembersRelation)
+	class := func(name, relation string) string {
+		return fmt.Sprintf("class %s implements Namespace {\n  related: {\n    %s: %s\n  }\n}\n", name, relation, members)
+	}
+
+	names := []string{c.UserNamespace}
+	classes := []string{fmt.Sprintf("class %s implements Namespace {}\n", c.UserNamespace)}
+	imports := [][]string{nil}
+	if c.GroupNamespace != c.DocumentNamespace {
+		names = append(names, c.GroupNamespace)
+		classes = append(classes, class(c.GroupNamespace, c.MembersRelation))
+		imports = append(imports, []string{c.UserNamespace})
+	}
+	names = append(names, c.DocumentNamespace)
+	classes = append(classes, class(c.DocumentNamespace, c.ViewersRelation))
+	imports = append(imports, names[:len(names)-1])
+
+	namespaces := make([]*Namespace, len(names))
+	for i, name := range names {
+		var sb strings.Builder
+		for _, imp := range imports[i] {
+			_, _ = fmt.Fprintf(&sb, "import { %[1]s } from \"./%[1]s\"\n", imp)
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(classes[i])
+		namespaces[i] = &Namespace{Name: name, OPL: sb.String()}
+	}
+	return namespaces, classes
+}
+
+// addMembers adds the users of the group and of all nested groups.
+func (gr *groupNode) addMembers(into map[int]struct{}) {
+	for _, u := range gr.users {
+		into[u] = struct{}{}
+	}
+	for _, c := range gr.children {
+		c.addMembers(into)
+	}
+}
+
+func (c Config) membersOf(object string) *ketoapi.SubjectSet {
+	return &ketoapi.SubjectSet{Namespace: c.GroupNamespace, Object: object, Relation: c.MembersRelation}
+}
+
+func (c Config) member(group string, u int) *ketoapi.RelationTuple {
+	return &ketoapi.RelationTuple{Namespace: c.GroupNamespace, Object: group, Relation: c.MembersRelation, SubjectID: x.Ptr(User(u))}
+}
+
+func (c Config) viewer(doc string, u int) *ketoapi.RelationTuple {
+	return &ketoapi.RelationTuple{Namespace: c.DocumentNamespace, Object: doc, Relation: c.ViewersRelation, SubjectID: x.Ptr(User(u))}
+}
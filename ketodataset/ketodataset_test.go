@@ -0,0 +1,89 @@
+package ketodataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/schema"
+)
+
+// hash returns a digest of the tuples and checks, in order.
+func hash(d *Dataset) string {
+	h := sha256.New()
+	for _, t := range d.Tuples {
+		_, _ = fmt.Fprintln(h, t)
+	}
+	for _, c := range d.Checks {
+		_, _ = fmt.Fprintln(h, c.Tuple, c.Allowed)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestGenerate(t *testing.T) {
+	config := Config{Seed: 42, Documents: 50, Users: 40, Groups: 3, Depth: 2, FanOut: 3, WildcardRatio: 0.2}
+
+	t.Run("case=the dataset only depends on the configuration", func(t *testing.T) {
+		d, err := Generate(config)
+		require.NoError(t, err)
+		again, err := Generate(config)
+		require.NoError(t, err)
+		assert.Equal(t, d, again)
+
+		// The digest is pinned, so that changes to the generator that change
+		// existing datasets are noticed.
+		assert.Equal(t, "455c379bd0557f0448cad7c55bd0ca9d36213277e9e09a48fc5c50c94b810245", hash(d))
+
+		other := config
+		other.Seed++
+		d, err = Generate(other)
+		require.NoError(t, err)
+		assert.NotEqual(t, hash(again), hash(d))
+	})
+
+	t.Run("case=dataset size", func(t *testing.T) {
+		d, err := Generate(Config{Documents: 3, Users: 10, Groups: 2, Depth: 1, FanOut: 2})
+		require.NoError(t, err)
+		// Every top-level group has 2 users and 2 nested groups, which have
+		// 2 users each. Every document has 2 viewers.
+		assert.Len(t, d.Tuples, 2*(4+2*2)+3*2)
+		assert.Len(t, d.Checks, 3*2)
+	})
+
+	t.Run("case=the model is valid", func(t *testing.T) {
+		for _, c := range []Config{
+			config,
+			{Documents: 1, Users: 1, FanOut: 1, DocumentNamespace: "Files", GroupNamespace: "Files", UserNamespace: "Accounts", ViewersRelation: "access", MembersRelation: "access"},
+		} {
+			d, err := Generate(c)
+			require.NoError(t, err)
+
+			_, errs := schema.Parse(d.OPL())
+			assert.Empty(t, errs, d.OPL())
+
+			definitions := make(map[string]string, len(d.Namespaces))
+			for _, n := range d.Namespaces {
+				definitions[n.Name] = n.OPL
+			}
+			namespaces, errs := schema.ParseDefinitions(nil, definitions)
+			assert.Empty(t, errs, definitions)
+			assert.Len(t, namespaces, len(d.Namespaces))
+		}
+	})
+
+	t.Run("case=invalid configuration", func(t *testing.T) {
+		for _, c := range []Config{
+			{Users: 1, FanOut: 1},
+			{Documents: 1, Users: 1, FanOut: 1, WildcardRatio: 1.5},
+			{Documents: 1, Users: 1, FanOut: 1, UserNamespace: "Group"},
+			{Documents: 1, Users: 1, FanOut: 1, GroupNamespace: "Document"},
+		} {
+			_, err := Generate(c)
+			assert.Error(t, err, "%+v", c)
+		}
+	})
+}
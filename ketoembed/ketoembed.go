@@ -0,0 +1,233 @@
+// Package ketoembed runs Ory Keto in-process, without the gRPC and REST
+// servers. It loads the namespaces from Ory Permission Language sources,
+// writes and reads relation tuples, and checks and expands permissions
+// directly against the permission engine.
+//
+// This package is the supported API to embed Keto in a Go service. It only
+// exposes the types of the ketoapi and ketoctx packages, so that the internal
+// packages can change between releases.
+//
+// The default database is an in-memory SQLite database, so services that use
+// it have to be built with the sqlite build tag, e.g.
+// `go build -tags sqlite ./...`.
+package ketoembed
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/ory/herodot"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/dbal"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/driver/config"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketoctx"
+)
+
+type (
+	// Keto is an embedded Ory Keto. It is safe for concurrent use.
+	Keto struct {
+		r      driver.Registry
+		cancel context.CancelFunc
+	}
+	options struct {
+		dsn     string
+		migrate bool
+		config  map[string]interface{}
+		keto    []ketoctx.Option
+	}
+	Option func(o *options)
+)
+
+// WithDSN stores the relation tuples in the database of the data source name
+// instead of an in-memory SQLite database.
+func WithDSN(dsn string) Option {
+	return func(o *options) {
+		o.dsn = dsn
+	}
+}
+
+// WithMigrations applies the migrations to the database of WithDSN first.
+// In-memory databases are always migrated.
+func WithMigrations() Option {
+	return func(o *options) {
+		o.migrate = true
+	}
+}
+
+// WithConfig sets the configuration key, e.g. "limit.max_read_depth".
+func WithConfig(key string, value interface{}) Option {
+	return func(o *options) {
+		o.config[key] = value
+	}
+}
+
+// WithKetoOptions passes the options to the registry, e.g. a logger or a
+// contextualizer.
+func WithKetoOptions(opts ...ketoctx.Option) Option {
+	return func(o *options) {
+		o.keto = append(o.keto, opts...)
+	}
+}
+
+// New returns a Keto with the namespaces of the Ory Permission Language
+// source. It runs until Close is called or the context is canceled.
+func New(ctx context.Context, opl string, opts ...Option) (*Keto, error) {
+	o := &options{config: make(map[string]interface{})}
+	for _, opt := range opts {
+		opt(o)
+	}
+	namespaces, err := parse(opl)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{
+		config.KeyDSN: dbal.NewSQLiteInMemoryDatabase("keto-embedded-" + uuid.Must(uuid.NewV4()).String()),
+		"log.level":   "error",
+		// The in-memory database is either available right away or not at
+		// all, e.g. because SQLite was not built in. WithConfig can raise the
+		// timeout for the database of WithDSN.
+		config.KeyDatabasePoolConnectTimeout: "1s",
+	}
+	if o.dsn != "" {
+		values[config.KeyDSN] = o.dsn
+	}
+	for key, value := range o.config {
+		values[key] = value
+	}
+	values[config.KeyNamespaces] = namespaces
+
+	ctx, cancel := context.WithCancel(ctx)
+	ctx = configx.ContextWithConfigOptions(ctx, configx.WithValues(values))
+	r, err := driver.NewDefaultRegistry(ctx, pflag.NewFlagSet("ketoembed", pflag.ContinueOnError), true, o.keto...)
+	if err == nil {
+		if o.migrate {
+			err = r.MigrateUp(ctx)
+		} else {
+			err = r.Init(ctx)
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Keto{r: r, cancel: cancel}, nil
+}
+
+// Close stops the background work and closes the database connection.
+func (k *Keto) Close() error {
+	k.cancel()
+	return k.r.Persister().Connection(context.Background()).Close()
+}
+
+// SetOPL replaces the namespaces with the ones of the Ory Permission Language
+// source. The relation tuples of removed namespaces are kept, but can not be
+// read or checked until the namespaces are added again.
+func (k *Keto) SetOPL(ctx context.Context, opl string) error {
+	namespaces, err := parse(opl)
+	if err != nil {
+		return err
+	}
+	return k.r.Config(ctx).Set(config.KeyNamespaces, namespaces)
+}
+
+// WriteRelationTuples inserts the relation tuples.
+func (k *Keto) WriteRelationTuples(ctx context.Context, tuples ...*ketoapi.RelationTuple) error {
+	return k.TransactRelationTuples(ctx, tuples, nil)
+}
+
+// DeleteRelationTuples deletes the relation tuples.
+func (k *Keto) DeleteRelationTuples(ctx context.Context, tuples ...*ketoapi.RelationTuple) error {
+	return k.TransactRelationTuples(ctx, nil, tuples)
+}
+
+// TransactRelationTuples inserts and deletes the relation tuples in one
+// transaction.
+func (k *Keto) TransactRelationTuples(ctx context.Context, insert, delete []*ketoapi.RelationTuple) error {
+	its, err := k.r.Mapper().FromTuple(ctx, append(append([]*ketoapi.RelationTuple{}, insert...), delete...)...)
+	if err != nil {
+		return err
+	}
+	return k.r.RelationTupleManager().TransactRelationTuples(ctx, its[:len(insert)], its[len(insert):])
+}
+
+// DeleteAllRelationTuples deletes all relation tuples that match the query.
+func (k *Keto) DeleteAllRelationTuples(ctx context.Context, query *ketoapi.RelationQuery) error {
+	iq, err := k.r.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	return k.r.RelationTupleManager().DeleteAllRelationTuples(ctx, iq)
+}
+
+// RelationTuples returns a page of the relation tuples that match the query,
+// and the token of the next page, which is empty on the last page. A page
+// size of 0 means the configured default.
+func (k *Keto) RelationTuples(ctx context.Context, query *ketoapi.RelationQuery, pageToken string, pageSize int) ([]*ketoapi.RelationTuple, string, error) {
+	iq, err := k.r.Mapper().FromQuery(ctx, query)
+	if err != nil {
+		return nil, "", err
+	}
+	its, nextPage, err := k.r.RelationTupleManager().GetRelationTuples(ctx, iq,
+		x.WithSize(k.r.Config(ctx).PageSize(pageSize)),
+		x.WithToken(pageToken),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	tuples, err := k.r.Mapper().ToTuple(ctx, its...)
+	if err != nil {
+		return nil, "", err
+	}
+	return tuples, nextPage, nil
+}
+
+// Check returns whether the subject of the relation tuple is related to the
+// object, either directly or through the rewrites of the namespace. Relation
+// tuples of unknown namespaces are never allowed, like in the check API. A
+// maximum depth of 0 means the configured default.
+func (k *Keto) Check(ctx context.Context, tuple *ketoapi.RelationTuple, maxDepth int) (bool, error) {
+	it, err := k.r.Mapper().FromTuple(ctx, tuple)
+	// herodot.ErrNotFound occurs when the namespace is unknown
+	if errors.Is(err, herodot.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return k.r.PermissionEngine().CheckIsMember(ctx, it[0], maxDepth)
+}
+
+// Expand returns the tree of the subjects of the subject set, or nil if it
+// has none. A maximum depth of 0 means the configured default.
+func (k *Keto) Expand(ctx context.Context, subject *ketoapi.SubjectSet, maxDepth int) (*ketoapi.Tree[*ketoapi.RelationTuple], error) {
+	internal, err := k.r.Mapper().FromSubjectSet(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	res, err := k.r.ExpandEngine().BuildTree(ctx, internal, maxDepth)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	return k.r.Mapper().ToTree(ctx, res)
+}
+
+func parse(opl string) ([]*namespace.Namespace, error) {
+	parsed, errs := schema.Parse(opl)
+	if len(errs) > 0 {
+		return nil, pkgerrors.Wrap(errs[0], "could not parse the Ory Permission Language source")
+	}
+	namespaces := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		namespaces[i] = &parsed[i]
+	}
+	return namespaces, nil
+}
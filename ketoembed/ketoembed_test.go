@@ -0,0 +1,105 @@
+package ketoembed_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketoembed"
+)
+
+const opl = `import { Namespace, SubjectSet, Context } from "@ory/keto-namespace-types"
+
+class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}
+
+class Document implements Namespace {
+  related: {
+    viewers: (User | SubjectSet<Group, "members">)[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+  }
+}
+`
+
+func TestKeto(t *testing.T) {
+	ctx := context.Background()
+	alice := &ketoapi.RelationTuple{Namespace: "Group", Object: "dev", Relation: "members", SubjectID: x.Ptr("alice")}
+	devs := &ketoapi.SubjectSet{Namespace: "Group", Object: "dev", Relation: "members"}
+	readme := &ketoapi.RelationTuple{Namespace: "Document", Object: "readme", Relation: "viewers", SubjectSet: devs}
+	view := func(user string) *ketoapi.RelationTuple {
+		return &ketoapi.RelationTuple{Namespace: "Document", Object: "readme", Relation: "view", SubjectID: x.Ptr(user)}
+	}
+
+	k, err := ketoembed.New(ctx, opl, ketoembed.WithConfig("limit.max_read_depth", 3))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, k.Close()) })
+
+	t.Run("case=checks", func(t *testing.T) {
+		require.NoError(t, k.WriteRelationTuples(ctx, alice, readme))
+
+		for user, allowed := range map[string]bool{"alice": true, "bob": false} {
+			res, err := k.Check(ctx, view(user), 0)
+			require.NoError(t, err)
+			assert.Equal(t, allowed, res, user)
+		}
+		res, err := k.Check(ctx, &ketoapi.RelationTuple{Namespace: "Unknown", Object: "o", Relation: "r", SubjectID: x.Ptr("alice")}, 0)
+		require.NoError(t, err)
+		assert.False(t, res)
+	})
+
+	t.Run("case=reads", func(t *testing.T) {
+		tuples, next, err := k.RelationTuples(ctx, &ketoapi.RelationQuery{Namespace: x.Ptr("Document")}, "", 0)
+		require.NoError(t, err)
+		assert.Empty(t, next)
+		assert.Equal(t, []*ketoapi.RelationTuple{readme}, tuples)
+
+		tree, err := k.Expand(ctx, devs, 0)
+		require.NoError(t, err)
+		require.NotNil(t, tree)
+		require.Len(t, tree.Children, 1)
+		assert.Equal(t, "alice", *tree.Children[0].Tuple.SubjectID)
+	})
+
+	t.Run("case=deletes", func(t *testing.T) {
+		bob := &ketoapi.RelationTuple{Namespace: "Group", Object: "dev", Relation: "members", SubjectID: x.Ptr("bob")}
+		require.NoError(t, k.TransactRelationTuples(ctx, []*ketoapi.RelationTuple{bob}, []*ketoapi.RelationTuple{alice}))
+		res, err := k.Check(ctx, view("alice"), 0)
+		require.NoError(t, err)
+		assert.False(t, res)
+
+		require.NoError(t, k.DeleteAllRelationTuples(ctx, &ketoapi.RelationQuery{Namespace: x.Ptr("Group")}))
+		res, err = k.Check(ctx, view("bob"), 0)
+		require.NoError(t, err)
+		assert.False(t, res)
+	})
+
+	t.Run("case=namespaces", func(t *testing.T) {
+		folder := &ketoapi.RelationTuple{Namespace: "Folder", Object: "root", Relation: "owners", SubjectID: x.Ptr("alice")}
+		assert.Error(t, k.WriteRelationTuples(ctx, folder))
+
+		require.NoError(t, k.SetOPL(ctx, opl+"\nclass Folder implements Namespace {\n  related: {\n    owners: User[]\n  }\n}\n"))
+		require.NoError(t, k.WriteRelationTuples(ctx, folder))
+		res, err := k.Check(ctx, folder, 0)
+		require.NoError(t, err)
+		assert.True(t, res)
+
+		assert.Error(t, k.SetOPL(ctx, "class {"))
+	})
+
+	t.Run("case=invalid source", func(t *testing.T) {
+		_, err := ketoembed.New(ctx, "class {")
+		assert.ErrorContains(t, err, "could not parse the Ory Permission Language source")
+	})
+}
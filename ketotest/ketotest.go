@@ -0,0 +1,233 @@
+// Package ketotest starts a fully wired, in-memory Ory Keto for tests. The
+// read, write, and admin APIs are served on random local ports, or on
+// in-process connections with WithBufconn. The server is stopped when the
+// test ends.
+//
+// The in-memory database is SQLite, so tests that use this package have to be
+// built with the sqlite build tag, e.g. `go test -tags sqlite ./...`.
+package ketotest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ory/keto/internal/driver"
+	"github.com/ory/keto/internal/namespace"
+	"github.com/ory/keto/internal/schema"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketoctx"
+	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
+)
+
+type (
+	// Server is an in-memory Ory Keto.
+	Server struct {
+		// ReadConn and WriteConn are the gRPC connections to the read and
+		// write APIs.
+		ReadConn, WriteConn *grpc.ClientConn
+		// ReadURL, WriteURL, and AdminURL are the base URLs of the REST APIs.
+		// Requests have to be sent with HTTPClient.
+		ReadURL, WriteURL, AdminURL string
+		// HTTPClient sends requests to the REST APIs.
+		HTTPClient *http.Client
+	}
+	options struct {
+		bufconn bool
+		config  map[string]interface{}
+		tuples  []*ketoapi.RelationTuple
+		keto    []ketoctx.Option
+	}
+	Option func(o *options)
+)
+
+// bufconnSize is the buffer size of the in-process connections.
+const bufconnSize = 1 << 20
+
+// WithBufconn serves the APIs on in-process connections instead of local
+// ports.
+func WithBufconn() Option {
+	return func(o *options) {
+		o.bufconn = true
+	}
+}
+
+// WithConfig sets the configuration key, e.g. "limit.max_read_depth".
+func WithConfig(key string, value interface{}) Option {
+	return func(o *options) {
+		o.config[key] = value
+	}
+}
+
+// WithTuples writes the relation tuples before the server starts.
+func WithTuples(tuples ...*ketoapi.RelationTuple) Option {
+	return func(o *options) {
+		o.tuples = append(o.tuples, tuples...)
+	}
+}
+
+// WithKetoOptions passes the options to the registry, e.g. interceptors.
+func WithKetoOptions(opts ...ketoctx.Option) Option {
+	return func(o *options) {
+		o.keto = append(o.keto, opts...)
+	}
+}
+
+// New starts a server with the namespaces of the Ory Permission Language
+// source. The test fails if the source can not be parsed or the server can
+// not be started.
+func New(t testing.TB, opl string, opts ...Option) *Server {
+	t.Helper()
+	o := &options{config: make(map[string]interface{})}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	parsed, errs := schema.Parse(opl)
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		t.Fatalf("could not parse the Ory Permission Language source:\n%s", strings.Join(msgs, "\n"))
+	}
+	namespaces := make([]*namespace.Namespace, len(parsed))
+	for i := range parsed {
+		namespaces[i] = &parsed[i]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	reg, err := driver.NewInMemoryRegistry(ctx, namespaces, o.keto...)
+	require.NoError(t, err)
+	for key, value := range o.config {
+		require.NoError(t, reg.Config(ctx).Set(key, value))
+	}
+
+	its, err := reg.Mapper().FromTuple(ctx, o.tuples...)
+	require.NoError(t, err)
+	require.NoError(t, reg.RelationTupleManager().WriteRelationTuples(ctx, its...))
+
+	s := &Server{HTTPClient: &http.Client{Timeout: 10 * time.Second}}
+	var listen listenFunc = listenLocal
+	if o.bufconn {
+		listen, s.HTTPClient.Transport = newBufconnNetwork()
+	}
+
+	for _, api := range []struct {
+		name   string
+		grpc   *grpc.Server
+		router http.Handler
+		conn   **grpc.ClientConn
+		url    *string
+	}{
+		{name: "read", grpc: reg.ReadGRPCServer(ctx), router: reg.ReadRouter(ctx), conn: &s.ReadConn, url: &s.ReadURL},
+		{name: "write", grpc: reg.WriteGRPCServer(ctx), router: reg.WriteRouter(ctx), conn: &s.WriteConn, url: &s.WriteURL},
+		{name: "admin", router: reg.AdminRouter(ctx), url: &s.AdminURL},
+	} {
+		if api.grpc != nil {
+			l, dial := listen(t, api.name+"-grpc")
+			go func(s *grpc.Server) { _ = s.Serve(l) }(api.grpc)
+			t.Cleanup(api.grpc.Stop)
+
+			conn, err := grpc.DialContext(ctx, "passthrough:///"+api.name,
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return dial(ctx) }),
+			)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = conn.Close() })
+			*api.conn = conn
+		}
+
+		l, _ := listen(t, api.name)
+		hs := &http.Server{Handler: api.router, ReadHeaderTimeout: 10 * time.Second}
+		go func() { _ = hs.Serve(l) }()
+		t.Cleanup(func() { _ = hs.Close() })
+		*api.url = "http://" + l.Addr().String()
+	}
+
+	return s
+}
+
+// WriteTuples writes the relation tuples through the write API.
+func (s *Server) WriteTuples(t testing.TB, tuples ...*ketoapi.RelationTuple) {
+	t.Helper()
+	protos := make([]*rts.RelationTuple, len(tuples))
+	for i, rt := range tuples {
+		protos[i] = rt.ToProto()
+	}
+	_, err := rts.NewWriteServiceClient(s.WriteConn).TransactRelationTuples(context.Background(), &rts.TransactRelationTuplesRequest{
+		RelationTupleDeltas: rts.RelationTupleToDeltas(protos, rts.RelationTupleDelta_ACTION_INSERT),
+	})
+	require.NoError(t, err)
+}
+
+// Check checks the relation tuple through the read API.
+func (s *Server) Check(t testing.TB, tuple *ketoapi.RelationTuple) bool {
+	t.Helper()
+	req := &rts.CheckRequest{Tuple: tuple.ToProto()}
+	resp, err := rts.NewCheckServiceClient(s.ReadConn).Check(context.Background(), req)
+	require.NoError(t, err)
+	return resp.Allowed
+}
+
+// listenFunc returns a listener and a function to connect to it.
+type listenFunc func(t testing.TB, name string) (net.Listener, func(ctx context.Context) (net.Conn, error))
+
+func listenLocal(t testing.TB, _ string) (net.Listener, func(ctx context.Context) (net.Conn, error)) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return l, func(ctx context.Context) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", l.Addr().String())
+	}
+}
+
+// bufconnAddr is the address of a bufconn listener, so that the REST API URLs
+// name the API.
+type bufconnAddr string
+
+func (a bufconnAddr) Network() string { return "bufconn" }
+func (a bufconnAddr) String() string  { return string(a) }
+
+type bufconnListener struct {
+	*bufconn.Listener
+	addr bufconnAddr
+}
+
+func (l *bufconnListener) Addr() net.Addr { return l.addr }
+
+// newBufconnNetwork returns a listenFunc for in-process listeners, and a
+// transport that connects to them by the host of the URL.
+func newBufconnNetwork() (listenFunc, *http.Transport) {
+	listeners := make(map[string]*bufconnListener)
+	listen := func(t testing.TB, name string) (net.Listener, func(ctx context.Context) (net.Conn, error)) {
+		l := &bufconnListener{Listener: bufconn.Listen(bufconnSize), addr: bufconnAddr(name)}
+		t.Cleanup(func() { _ = l.Close() })
+		listeners[name] = l
+		return l, l.DialContext
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			l, ok := listeners[host]
+			if !ok {
+				return nil, &net.AddrError{Err: "unknown in-process API", Addr: addr}
+			}
+			return l.DialContext(ctx)
+		},
+	}
+	return listen, transport
+}
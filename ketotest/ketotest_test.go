@@ -0,0 +1,79 @@
+package ketotest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/keto/internal/check"
+	"github.com/ory/keto/internal/namespace/namespacehandler"
+	"github.com/ory/keto/internal/x"
+	"github.com/ory/keto/ketoapi"
+	"github.com/ory/keto/ketotest"
+)
+
+const opl = `import { Namespace, SubjectSet, Context } from "@ory/keto-namespace-types"
+
+class User implements Namespace {}
+
+class Group implements Namespace {
+  related: {
+    members: User[]
+  }
+}
+
+class Document implements Namespace {
+  related: {
+    viewers: (User | SubjectSet<Group, "members">)[]
+  }
+
+  permits = {
+    view: (ctx: Context): boolean => this.related.viewers.includes(ctx.subject),
+  }
+}
+`
+
+func TestServer(t *testing.T) {
+	alice := &ketoapi.RelationTuple{Namespace: "Group", Object: "dev", Relation: "members", SubjectID: x.Ptr("alice")}
+	readme := &ketoapi.RelationTuple{Namespace: "Document", Object: "readme", Relation: "viewers", SubjectSet: &ketoapi.SubjectSet{Namespace: "Group", Object: "dev", Relation: "members"}}
+	view := func(user string) *ketoapi.RelationTuple {
+		return &ketoapi.RelationTuple{Namespace: "Document", Object: "readme", Relation: "view", SubjectID: x.Ptr(user)}
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []ketotest.Option
+	}{
+		{name: "local ports"},
+		{name: "bufconn", opts: []ketotest.Option{ketotest.WithBufconn()}},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			s := ketotest.New(t, opl, append(tc.opts, ketotest.WithTuples(alice))...)
+			s.WriteTuples(t, readme)
+
+			assert.True(t, s.Check(t, view("alice")))
+			assert.False(t, s.Check(t, view("bob")))
+
+			resp, err := s.HTTPClient.Get(s.ReadURL + check.RouteBase + "?" + view("alice").ToURLQuery().Encode())
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var body struct {
+				Allowed bool `json:"allowed"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.True(t, body.Allowed)
+
+			resp, err = s.HTTPClient.Get(s.AdminURL + namespacehandler.RouteBase + "?" + url.Values{"format": {namespacehandler.FormatJSON}}.Encode())
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			var nn namespacehandler.EffectiveNamespaces
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&nn))
+			assert.Len(t, nn.Namespaces, 3)
+		})
+	}
+}
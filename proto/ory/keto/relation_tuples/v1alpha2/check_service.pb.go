@@ -49,7 +49,9 @@ type CheckRequest struct {
 	// Deprecated: Do not use.
 	Subject *Subject       `protobuf:"bytes,4,opt,name=subject,proto3" json:"subject,omitempty"`
 	Tuple   *RelationTuple `protobuf:"bytes,8,opt,name=tuple,proto3" json:"tuple,omitempty"`
-	// This field is not implemented yet and has no effect.
+	// Set this field to `true` to evaluate the check on the primary
+	// database instead of a read replica, which might lag behind.
+	// Snaptokens are not implemented yet.
 	// <!--
 	// Set this field to `true` in case your application
 	// needs to authorize depending on up to date ACLs,
@@ -24,6 +24,11 @@ const _ = grpc.SupportPackageIsVersion7
 type ReadServiceClient interface {
 	// Lists ACL relation tuples.
 	ListRelationTuples(ctx context.Context, in *ListRelationTuplesRequest, opts ...grpc.CallOption) (*ListRelationTuplesResponse, error)
+	// Streams all relation tuples matching the query, without pagination
+	// round trips. The relation tuples are only read from the database as fast
+	// as the client receives them, so that exporting large namespaces does not
+	// buffer them in the server.
+	StreamRelationTuples(ctx context.Context, in *StreamRelationTuplesRequest, opts ...grpc.CallOption) (ReadService_StreamRelationTuplesClient, error)
 }
 
 type readServiceClient struct {
@@ -43,12 +48,49 @@ func (c *readServiceClient) ListRelationTuples(ctx context.Context, in *ListRela
 	return out, nil
 }
 
+func (c *readServiceClient) StreamRelationTuples(ctx context.Context, in *StreamRelationTuplesRequest, opts ...grpc.CallOption) (ReadService_StreamRelationTuplesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ReadService_ServiceDesc.Streams[0], "/ory.keto.relation_tuples.v1alpha2.ReadService/StreamRelationTuples", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &readServiceStreamRelationTuplesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ReadService_StreamRelationTuplesClient interface {
+	Recv() (*StreamRelationTuplesResponse, error)
+	grpc.ClientStream
+}
+
+type readServiceStreamRelationTuplesClient struct {
+	grpc.ClientStream
+}
+
+func (x *readServiceStreamRelationTuplesClient) Recv() (*StreamRelationTuplesResponse, error) {
+	m := new(StreamRelationTuplesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ReadServiceServer is the server API for ReadService service.
 // All implementations should embed UnimplementedReadServiceServer
 // for forward compatibility
 type ReadServiceServer interface {
 	// Lists ACL relation tuples.
 	ListRelationTuples(context.Context, *ListRelationTuplesRequest) (*ListRelationTuplesResponse, error)
+	// Streams all relation tuples matching the query, without pagination
+	// round trips. The relation tuples are only read from the database as fast
+	// as the client receives them, so that exporting large namespaces does not
+	// buffer them in the server.
+	StreamRelationTuples(*StreamRelationTuplesRequest, ReadService_StreamRelationTuplesServer) error
 }
 
 // UnimplementedReadServiceServer should be embedded to have forward compatible implementations.
@@ -58,6 +100,9 @@ type UnimplementedReadServiceServer struct {
 func (UnimplementedReadServiceServer) ListRelationTuples(context.Context, *ListRelationTuplesRequest) (*ListRelationTuplesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListRelationTuples not implemented")
 }
+func (UnimplementedReadServiceServer) StreamRelationTuples(*StreamRelationTuplesRequest, ReadService_StreamRelationTuplesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRelationTuples not implemented")
+}
 
 // UnsafeReadServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to ReadServiceServer will
@@ -88,6 +133,27 @@ func _ReadService_ListRelationTuples_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ReadService_StreamRelationTuples_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRelationTuplesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReadServiceServer).StreamRelationTuples(m, &readServiceStreamRelationTuplesServer{stream})
+}
+
+type ReadService_StreamRelationTuplesServer interface {
+	Send(*StreamRelationTuplesResponse) error
+	grpc.ServerStream
+}
+
+type readServiceStreamRelationTuplesServer struct {
+	grpc.ServerStream
+}
+
+func (x *readServiceStreamRelationTuplesServer) Send(m *StreamRelationTuplesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ReadService_ServiceDesc is the grpc.ServiceDesc for ReadService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -100,6 +166,12 @@ var ReadService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ReadService_ListRelationTuples_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRelationTuples",
+			Handler:       _ReadService_StreamRelationTuples_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "ory/keto/relation_tuples/v1alpha2/read_service.proto",
 }
@@ -24,6 +24,10 @@ const _ = grpc.SupportPackageIsVersion7
 type VersionServiceClient interface {
 	// Returns the version of the Ory Keto instance.
 	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
+	// Returns the version of the Ory Keto instance, hashes of its namespaces
+	// and configuration, and the version of the last applied migration, for
+	// example to detect instances with a different configuration.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
 }
 
 type versionServiceClient struct {
@@ -43,12 +47,25 @@ func (c *versionServiceClient) GetVersion(ctx context.Context, in *GetVersionReq
 	return out, nil
 }
 
+func (c *versionServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, "/ory.keto.relation_tuples.v1alpha2.VersionService/GetStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // VersionServiceServer is the server API for VersionService service.
 // All implementations should embed UnimplementedVersionServiceServer
 // for forward compatibility
 type VersionServiceServer interface {
 	// Returns the version of the Ory Keto instance.
 	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
+	// Returns the version of the Ory Keto instance, hashes of its namespaces
+	// and configuration, and the version of the last applied migration, for
+	// example to detect instances with a different configuration.
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
 }
 
 // UnimplementedVersionServiceServer should be embedded to have forward compatible implementations.
@@ -58,6 +75,9 @@ type UnimplementedVersionServiceServer struct {
 func (UnimplementedVersionServiceServer) GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
 }
+func (UnimplementedVersionServiceServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
 
 // UnsafeVersionServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to VersionServiceServer will
@@ -88,6 +108,24 @@ func _VersionService_GetVersion_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VersionService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VersionServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ory.keto.relation_tuples.v1alpha2.VersionService/GetStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VersionServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // VersionService_ServiceDesc is the grpc.ServiceDesc for VersionService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +137,10 @@ var VersionService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetVersion",
 			Handler:    _VersionService_GetVersion_Handler,
 		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _VersionService_GetStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "ory/keto/relation_tuples/v1alpha2/version.proto",
@@ -0,0 +1,95 @@
+package spec
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type document struct {
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+	} `json:"paths"`
+}
+
+var routeAnnotation = regexp.MustCompile(`(?m)^\s*// swagger:route (\S+) (\S+) .*?(\S+)$`)
+
+// TestSpecCoversHandlers makes sure that the spec is regenerated when an
+// annotated handler is added or changed.
+func TestSpecCoversHandlers(t *testing.T) {
+	type route struct{ method, path, operationID string }
+	var routes []route
+	for _, dir := range []string{"../internal", "../ketoapi"} {
+		require.NoError(t, filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == "httpclient" {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, m := range routeAnnotation.FindAllStringSubmatch(string(src), -1) {
+				routes = append(routes, route{method: strings.ToLower(m[1]), path: m[2], operationID: m[3]})
+			}
+			return nil
+		}))
+	}
+	require.NotEmpty(t, routes)
+
+	for _, file := range []string{"api.json", "swagger.json"} {
+		t.Run("file="+file, func(t *testing.T) {
+			raw, err := os.ReadFile(file)
+			require.NoError(t, err)
+			var doc document
+			require.NoError(t, json.Unmarshal(raw, &doc))
+
+			for _, r := range routes {
+				op, ok := doc.Paths[r.path][r.method]
+				if assert.Truef(t, ok, "%s %s is missing, regenerate the spec with `make sdk`", r.method, r.path) {
+					assert.Equal(t, r.operationID, op.OperationID, "%s %s", r.method, r.path)
+				}
+			}
+		})
+	}
+}
+
+func TestSpecReferences(t *testing.T) {
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(API, &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	var check func(v interface{})
+	check = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				target := interface{}(doc)
+				for _, p := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					target = target.(map[string]interface{})[p]
+				}
+				assert.NotNil(t, target, "%s is not defined", ref)
+			}
+			for _, x := range v {
+				check(x)
+			}
+		case []interface{}:
+			for _, x := range v {
+				check(x)
+			}
+		}
+	}
+	check(doc)
+}